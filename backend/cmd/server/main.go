@@ -3,29 +3,35 @@ package main
 import (
 	"context"
 	"encoding/json"
+	"fmt"
 	"log"
 	"net/http"
 	"os"
 	"os/signal"
+	"strconv"
 	"syscall"
 	"time"
 
 	"cargomax-api/internal/auth"
 	"cargomax-api/internal/config"
 	"cargomax-api/internal/database"
+	"cargomax-api/internal/geocode"
 	"cargomax-api/internal/graph"
 	"cargomax-api/internal/graph/resolvers"
+	"cargomax-api/internal/jobs"
 	"cargomax-api/internal/middleware"
 	"cargomax-api/internal/models"
 	"cargomax-api/internal/repository"
 	"cargomax-api/internal/rest"
 	"cargomax-api/internal/seed"
+	"cargomax-api/internal/storage"
 	"cargomax-api/internal/workers"
 
 	"github.com/go-chi/chi/v5"
 	chiMiddleware "github.com/go-chi/chi/v5/middleware"
 	"github.com/go-chi/cors"
 	"github.com/graphql-go/handler"
+	"github.com/jackc/pgx/v5/pgxpool"
 )
 
 // The ResponseWriter context key is defined in models.CtxResponseWriter.
@@ -43,11 +49,20 @@ func main() {
 	defer database.Close(pool)
 	log.Println("Connected to PostgreSQL")
 
-	// Run database migrations.
-	if err := database.RunMigrations(pool); err != nil {
+	// "migrate up|down|status" manages the schema explicitly and exits.
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		if err := runMigrateCommand(pool, os.Args[2:]); err != nil {
+			log.Fatalf("migrate: %v", err)
+		}
+		return
+	}
+
+	// Apply pending database migrations.
+	applied, err := database.MigrateUp(context.Background(), pool, 0)
+	if err != nil {
 		log.Fatalf("Failed to run migrations: %v", err)
 	}
-	log.Println("Migrations complete")
+	log.Printf("Migrations complete (%d applied)", len(applied))
 
 	// Check for --seed flag: populate database with sample data and exit.
 	for _, arg := range os.Args[1:] {
@@ -61,7 +76,7 @@ func main() {
 		}
 	}
 
-	// Create all 18 repositories.
+	// Create the core repositories.
 	userRepo := repository.NewUserRepo(pool)
 	tenantRepo := repository.NewTenantRepo(pool)
 	shipmentRepo := repository.NewShipmentRepo(pool)
@@ -80,6 +95,7 @@ func main() {
 	settingRepo := repository.NewSettingRepo(pool)
 	roleRepo := repository.NewRoleRepo(pool)
 	activityRepo := repository.NewActivityRepo(pool)
+	routeRepo := repository.NewRouteRepo(pool)
 
 	// Create tracking repositories.
 	shiftRepo := repository.NewShiftRepo(pool)
@@ -87,34 +103,91 @@ func main() {
 	alertRepo := repository.NewAlertRepo(pool)
 	zoneRepo := repository.NewZoneRepo(pool)
 
+	// Create import, pricing and invoicing repositories.
+	importRepo := repository.NewImportRepo(pool)
+	rateCardRepo := repository.NewRateCardRepo(pool)
+	invoiceRepo := repository.NewInvoiceRepo(pool)
+
+	// Create order fulfilment, returns, stock and purchasing repositories.
+	fulfillmentRepo := repository.NewFulfillmentRepo(pool)
+	orderScheduleRepo := repository.NewOrderScheduleRepo(pool)
+	rmaRepo := repository.NewRMARepo(pool)
+	stockMovementRepo := repository.NewStockMovementRepo(pool)
+	productRepo := repository.NewProductRepo(pool)
+	transferRepo := repository.NewTransferRepo(pool)
+	supplierProductRepo := repository.NewSupplierProductRepo(pool)
+	purchaseOrderRepo := repository.NewPurchaseOrderRepo(pool)
+
+	// Create the background job scheduler.
+	jobRepo := repository.NewJobRepo(pool)
+	scheduler := jobs.NewScheduler(jobRepo)
+
 	// Create WebSocket hub and tracking handler.
 	wsHub := rest.NewHub(cfg)
 	go wsHub.Run()
 
-	trackingHandler := rest.NewTrackingHandler(cfg, driverRepo, vehicleRepo, shiftRepo, pingRepo, alertRepo, zoneRepo, wsHub)
-	managerHandler := rest.NewManagerHandler(cfg, driverRepo, vehicleRepo, shiftRepo, pingRepo, alertRepo, zoneRepo)
+	// Uploaded files such as proof-of-delivery signatures and photos.
+	fileStore, err := storage.NewLocalDisk(cfg.StorageDir)
+	if err != nil {
+		log.Fatalf("Failed to open file storage: %v", err)
+	}
+
+	// Offline geocoding from the configured gazetteer, if any.
+	var geocoder geocode.Geocoder
+	if cfg.GazetteerPath != "" {
+		gazetteer, err := geocode.LoadGazetteer(cfg.GazetteerPath)
+		if err != nil {
+			log.Fatalf("Failed to load gazetteer: %v", err)
+		}
+		log.Printf("Gazetteer loaded: %d postcodes", gazetteer.Len())
+		geocoder = gazetteer
+	}
+
+	trackingHandler := rest.NewTrackingHandler(cfg, driverRepo, vehicleRepo, shiftRepo, pingRepo, alertRepo, zoneRepo, shipmentRepo, fileStore, wsHub)
+	managerHandler := rest.NewManagerHandler(cfg, driverRepo, vehicleRepo, shiftRepo, pingRepo, alertRepo, zoneRepo, shipmentRepo, warehouseRepo, tenantRepo, importRepo, invoiceRepo, fileStore)
+
+	// Public tracking is unauthenticated; REST and GraphQL share one per-IP budget.
+	trackingLimiter := middleware.NewRateLimiter(resolvers.PublicTrackingLimit, resolvers.PublicTrackingWindow)
+	publicTrackingHandler := rest.NewPublicTrackingHandler(shipmentRepo, trackingLimiter)
 
 	// Build the unified resolver that every GraphQL field delegates to.
 	resolver := &resolvers.Resolver{
-		UserRepo:         userRepo,
-		TenantRepo:       tenantRepo,
-		ShipmentRepo:     shipmentRepo,
-		VehicleRepo:      vehicleRepo,
-		DriverRepo:       driverRepo,
-		MaintenanceRepo:  maintenanceRepo,
-		WarehouseRepo:    warehouseRepo,
-		InventoryRepo:    inventoryRepo,
-		OrderRepo:        orderRepo,
-		VendorRepo:       vendorRepo,
-		ClientRepo:       clientRepo,
-		FeedbackRepo:     feedbackRepo,
-		DashboardRepo:    dashboardRepo,
-		ReportRepo:       reportRepo,
-		NotificationRepo: notificationRepo,
-		SettingRepo:      settingRepo,
-		RoleRepo:         roleRepo,
-		ActivityRepo:     activityRepo,
-		Config:           cfg,
+		UserRepo:            userRepo,
+		TenantRepo:          tenantRepo,
+		ShipmentRepo:        shipmentRepo,
+		VehicleRepo:         vehicleRepo,
+		DriverRepo:          driverRepo,
+		MaintenanceRepo:     maintenanceRepo,
+		WarehouseRepo:       warehouseRepo,
+		InventoryRepo:       inventoryRepo,
+		OrderRepo:           orderRepo,
+		VendorRepo:          vendorRepo,
+		ClientRepo:          clientRepo,
+		FeedbackRepo:        feedbackRepo,
+		DashboardRepo:       dashboardRepo,
+		ReportRepo:          reportRepo,
+		NotificationRepo:    notificationRepo,
+		SettingRepo:         settingRepo,
+		RoleRepo:            roleRepo,
+		ActivityRepo:        activityRepo,
+		RouteRepo:           routeRepo,
+		ZoneRepo:            zoneRepo,
+		ImportRepo:          importRepo,
+		RateCardRepo:        rateCardRepo,
+		InvoiceRepo:         invoiceRepo,
+		FulfillmentRepo:     fulfillmentRepo,
+		OrderScheduleRepo:   orderScheduleRepo,
+		RMARepo:             rmaRepo,
+		StockMovementRepo:   stockMovementRepo,
+		ProductRepo:         productRepo,
+		TransferRepo:        transferRepo,
+		SupplierProductRepo: supplierProductRepo,
+		PurchaseOrderRepo:   purchaseOrderRepo,
+		Config:              cfg,
+		TrackingLimiter:     trackingLimiter,
+		Storage:             fileStore,
+		Geocoder:            geocoder,
+		Scheduler:           scheduler,
 	}
 
 	// Assemble the GraphQL schema from all domain resolvers.
@@ -175,6 +248,7 @@ func main() {
 	// GraphQL endpoint wrapped with optional auth and ResponseWriter injection.
 	r.Route("/graphql", func(sub chi.Router) {
 		sub.Use(optionalAuth)
+		sub.Use(resolver.LoaderMiddleware)
 		sub.Handle("/*", injectResponseWriter(gqlHandler))
 		sub.Handle("/", injectResponseWriter(gqlHandler))
 	})
@@ -185,6 +259,9 @@ func main() {
 	// REST API routes for manager dashboard.
 	r.Mount("/api/v1/manager", managerHandler.Routes())
 
+	// Unauthenticated shipment tracking for end customers.
+	r.Mount("/api/v1/public", publicTrackingHandler.Routes())
+
 	// WebSocket endpoints for live dashboard.
 	r.Get("/ws/tracking/live", wsHub.HandleTrackingWS)
 	r.Get("/ws/alerts", wsHub.HandleAlertsWS)
@@ -206,11 +283,35 @@ func main() {
 		}
 	}()
 
-	// Start alert detection worker.
+	// Register background workers and start the job scheduler.
 	alertWorker := workers.NewAlertWorker(shiftRepo, pingRepo, alertRepo, zoneRepo, wsHub)
-	workerCtx, workerCancel := context.WithCancel(context.Background())
-	defer workerCancel()
-	go alertWorker.Start(workerCtx)
+	if err := alertWorker.Register(scheduler); err != nil {
+		log.Fatalf("Failed to register alert worker: %v", err)
+	}
+	etaWorker := workers.NewETAWorker(shiftRepo, pingRepo, shipmentRepo, wsHub)
+	if err := etaWorker.Register(scheduler); err != nil {
+		log.Fatalf("Failed to register ETA worker: %v", err)
+	}
+	routeWorker := workers.NewRouteWorker(shiftRepo, pingRepo, routeRepo, wsHub)
+	if err := routeWorker.Register(scheduler); err != nil {
+		log.Fatalf("Failed to register route worker: %v", err)
+	}
+	importWorker := workers.NewImportWorker(resolver)
+	if err := importWorker.Register(scheduler); err != nil {
+		log.Fatalf("Failed to register import worker: %v", err)
+	}
+	orderScheduleWorker := workers.NewOrderScheduleWorker(orderScheduleRepo, orderRepo, fulfillmentRepo, notificationRepo)
+	if err := orderScheduleWorker.Register(scheduler); err != nil {
+		log.Fatalf("Failed to register order schedule worker: %v", err)
+	}
+	reorderWorker := workers.NewReorderWorker(purchaseOrderRepo, notificationRepo)
+	if err := reorderWorker.Register(scheduler); err != nil {
+		log.Fatalf("Failed to register reorder worker: %v", err)
+	}
+	if err := scheduler.RegisterPrune(7 * 24 * time.Hour); err != nil {
+		log.Fatalf("Failed to register job pruning: %v", err)
+	}
+	scheduler.Start()
 
 	// Graceful shutdown on SIGINT or SIGTERM.
 	quit := make(chan os.Signal, 1)
@@ -221,10 +322,80 @@ func main() {
 	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
 	defer cancel()
 
+	// A failed HTTP shutdown must not skip draining the jobs below.
 	if err := srv.Shutdown(ctx); err != nil {
-		log.Fatalf("Graceful shutdown failed: %v", err)
+		log.Printf("Graceful shutdown failed: %v", err)
+	} else {
+		log.Println("Server stopped")
+	}
+
+	// Drain in-flight jobs; anything unfinished is reclaimed after restart.
+	// The drain gets its own deadline in case the HTTP shutdown used it up.
+	jobCtx, jobCancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer jobCancel()
+	if err := scheduler.Shutdown(jobCtx); err != nil {
+		log.Printf("Job scheduler shutdown: %v", err)
+	}
+}
+
+// runMigrateCommand implements the migrate subcommand:
+//
+//	migrate up [version]   apply pending migrations (optionally up to version)
+//	migrate down [steps]   revert the last migration (or the last N)
+//	migrate status         list migrations and whether they are applied
+func runMigrateCommand(pool *pgxpool.Pool, args []string) error {
+	ctx := context.Background()
+	if len(args) == 0 {
+		return fmt.Errorf("usage: migrate up [version] | down [steps] | status")
+	}
+
+	n := 0
+	if len(args) > 1 {
+		v, err := strconv.Atoi(args[1])
+		if err != nil || v < 0 {
+			return fmt.Errorf("invalid number %q", args[1])
+		}
+		n = v
+	}
+
+	switch args[0] {
+	case "up":
+		applied, err := database.MigrateUp(ctx, pool, n)
+		for _, m := range applied {
+			log.Printf("Applied %04d_%s", m.Version, m.Name)
+		}
+		if err == nil && len(applied) == 0 {
+			log.Println("Schema is up to date")
+		}
+		return err
+	case "down":
+		reverted, err := database.MigrateDown(ctx, pool, n)
+		for _, m := range reverted {
+			log.Printf("Reverted %04d_%s", m.Version, m.Name)
+		}
+		return err
+	case "status":
+		status, err := database.GetMigrationStatus(ctx, pool)
+		if err != nil {
+			return err
+		}
+		for _, s := range status {
+			state := "pending"
+			if s.Applied {
+				state = "applied " + s.AppliedAt.Format(time.RFC3339)
+			}
+			if s.Modified {
+				state += " (MODIFIED)"
+			}
+			if s.Missing {
+				state += " (missing from build)"
+			}
+			fmt.Printf("%04d  %-30s %s\n", s.Version, s.Name, state)
+		}
+		return nil
+	default:
+		return fmt.Errorf("unknown migrate command %q", args[0])
 	}
-	log.Println("Server stopped")
 }
 
 // optionalAuthMiddleware returns middleware that attempts JWT validation from
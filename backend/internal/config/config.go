@@ -26,6 +26,14 @@ type Config struct {
 	SMTPUser      string
 	SMTPPass      string
 	FrontendURL   string
+
+	// StorageDir is where uploaded files such as proof-of-delivery
+	// signatures and photos are kept.
+	StorageDir string
+
+	// GazetteerPath is a postcode/city centroid CSV for offline geocoding.
+	// Addresses are not geocoded when it is empty.
+	GazetteerPath string
 }
 
 func Load() *Config {
@@ -76,6 +84,8 @@ func Load() *Config {
 		SMTPUser:      getEnv("SMTP_USER", ""),
 		SMTPPass:      getEnv("SMTP_PASS", ""),
 		FrontendURL:   frontendURL,
+		StorageDir:    getEnv("STORAGE_DIR", "data/uploads"),
+		GazetteerPath: getEnv("GAZETTEER_PATH", ""),
 	}
 
 	log.Printf("Config: APP_HOST=%s, FrontendURL=%s, CookieDomain=%q, CookieSecure=%v",
@@ -2,398 +2,315 @@ package database
 
 import (
 	"context"
+	"crypto/sha256"
+	"embed"
+	"encoding/hex"
 	"fmt"
+	"io/fs"
+	"regexp"
+	"sort"
+	"strconv"
+	"time"
 
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
-// RunMigrations creates all tables and indexes if they do not already exist.
-func RunMigrations(pool *pgxpool.Pool) error {
-	ctx := context.Background()
+// Migration files live in migrations/ as NNNN_name.up.sql and
+// NNNN_name.down.sql and are embedded into the binary. Versions must be
+// unique; never edit a migration after it has been applied anywhere -- add a
+// new one instead (the checksum check will refuse to run otherwise).
+//
+//go:embed migrations/*.sql
+var migrationFiles embed.FS
 
-	migrations := []string{
-		// 1. tenants
-		`CREATE TABLE IF NOT EXISTS tenants (
-			id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
-			name VARCHAR(255) NOT NULL,
-			domain VARCHAR(255) UNIQUE,
-			plan VARCHAR(50) DEFAULT 'starter',
-			created_at TIMESTAMPTZ DEFAULT NOW(),
-			updated_at TIMESTAMPTZ DEFAULT NOW()
-		)`,
+// migrationLockKey is the pg_advisory_lock key held while migrating so that
+// replicas booting concurrently apply migrations one at a time.
+const migrationLockKey int64 = 0x436172676F4D6178 // "CargoMax"
 
-		// 2. users
-		`CREATE TABLE IF NOT EXISTS users (
-			id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
-			tenant_id UUID NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
-			email VARCHAR(255) NOT NULL,
-			password_hash VARCHAR(255) NOT NULL,
-			first_name VARCHAR(100),
-			last_name VARCHAR(100),
-			role VARCHAR(50) DEFAULT 'viewer',
-			email_verified BOOLEAN DEFAULT FALSE,
-			email_verify_token VARCHAR(255),
-			avatar_url VARCHAR(500),
-			created_at TIMESTAMPTZ DEFAULT NOW(),
-			updated_at TIMESTAMPTZ DEFAULT NOW(),
-			UNIQUE(tenant_id, email)
-		)`,
+var migrationFileRe = regexp.MustCompile(`^(\d+)_([a-z0-9_]+)\.(up|down)\.sql$`)
 
-		// 3. shipments
-		`CREATE TABLE IF NOT EXISTS shipments (
-			id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
-			tenant_id UUID NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
-			tracking_number VARCHAR(50) NOT NULL,
-			origin VARCHAR(255),
-			destination VARCHAR(255),
-			status VARCHAR(50) DEFAULT 'pending',
-			carrier VARCHAR(100),
-			weight DECIMAL(10,2),
-			dimensions VARCHAR(100),
-			estimated_delivery TIMESTAMPTZ,
-			actual_delivery TIMESTAMPTZ,
-			customer_name VARCHAR(255),
-			customer_email VARCHAR(255),
-			notes TEXT,
-			created_at TIMESTAMPTZ DEFAULT NOW(),
-			updated_at TIMESTAMPTZ DEFAULT NOW(),
-			UNIQUE(tenant_id, tracking_number)
-		)`,
-
-		// 4. vehicles
-		`CREATE TABLE IF NOT EXISTS vehicles (
-			id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
-			tenant_id UUID NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
-			vehicle_id VARCHAR(50) NOT NULL,
-			name VARCHAR(255),
-			type VARCHAR(50),
-			status VARCHAR(50) DEFAULT 'available',
-			fuel_level INTEGER DEFAULT 100,
-			mileage INTEGER DEFAULT 0,
-			last_service TIMESTAMPTZ,
-			next_service TIMESTAMPTZ,
-			license_plate VARCHAR(50),
-			year INTEGER,
-			created_at TIMESTAMPTZ DEFAULT NOW(),
-			updated_at TIMESTAMPTZ DEFAULT NOW(),
-			UNIQUE(tenant_id, vehicle_id)
-		)`,
+// Migration is a single versioned schema change.
+type Migration struct {
+	Version  int
+	Name     string
+	Up       string
+	Down     string
+	Checksum string
+}
 
-		// 5. drivers
-		`CREATE TABLE IF NOT EXISTS drivers (
-			id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
-			tenant_id UUID NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
-			employee_id VARCHAR(50) NOT NULL,
-			first_name VARCHAR(100),
-			last_name VARCHAR(100),
-			email VARCHAR(255),
-			phone VARCHAR(50),
-			license_number VARCHAR(100),
-			license_expiry TIMESTAMPTZ,
-			status VARCHAR(50) DEFAULT 'available',
-			rating DECIMAL(3,2) DEFAULT 0,
-			total_deliveries INTEGER DEFAULT 0,
-			vehicle_id UUID REFERENCES vehicles(id),
-			created_at TIMESTAMPTZ DEFAULT NOW(),
-			updated_at TIMESTAMPTZ DEFAULT NOW(),
-			UNIQUE(tenant_id, employee_id)
-		)`,
+// MigrationStatus describes a migration and whether it has been applied.
+type MigrationStatus struct {
+	Version   int
+	Name      string
+	Applied   bool
+	AppliedAt *time.Time
+	// Modified is true when the applied checksum differs from the embedded file.
+	Modified bool
+	// Missing is true for versions recorded in the database but absent from the binary.
+	Missing bool
+}
 
-		// 6. maintenance_records
-		`CREATE TABLE IF NOT EXISTS maintenance_records (
-			id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
-			tenant_id UUID NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
-			vehicle_id UUID NOT NULL REFERENCES vehicles(id),
-			type VARCHAR(100),
-			description TEXT,
-			status VARCHAR(50) DEFAULT 'scheduled',
-			scheduled_date TIMESTAMPTZ,
-			completed_date TIMESTAMPTZ,
-			cost DECIMAL(10,2),
-			mechanic VARCHAR(255),
-			created_at TIMESTAMPTZ DEFAULT NOW(),
-			updated_at TIMESTAMPTZ DEFAULT NOW()
-		)`,
+type appliedMigration struct {
+	Version   int
+	Name      string
+	Checksum  string
+	AppliedAt time.Time
+}
 
-		// 7. warehouses
-		`CREATE TABLE IF NOT EXISTS warehouses (
-			id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
-			tenant_id UUID NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
-			name VARCHAR(255) NOT NULL,
-			location VARCHAR(255),
-			address TEXT,
-			capacity INTEGER DEFAULT 0,
-			used_capacity INTEGER DEFAULT 0,
-			manager VARCHAR(255),
-			phone VARCHAR(50),
-			status VARCHAR(50) DEFAULT 'active',
-			created_at TIMESTAMPTZ DEFAULT NOW(),
-			updated_at TIMESTAMPTZ DEFAULT NOW()
-		)`,
+// LoadMigrations parses the embedded migration files ordered by version.
+func LoadMigrations() ([]Migration, error) {
+	entries, err := fs.ReadDir(migrationFiles, "migrations")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read migrations: %w", err)
+	}
 
-		// 8. inventory_items
-		`CREATE TABLE IF NOT EXISTS inventory_items (
-			id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
-			tenant_id UUID NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
-			warehouse_id UUID NOT NULL REFERENCES warehouses(id),
-			sku VARCHAR(100) NOT NULL,
-			name VARCHAR(255),
-			category VARCHAR(100),
-			quantity INTEGER DEFAULT 0,
-			min_quantity INTEGER DEFAULT 0,
-			unit_price DECIMAL(10,2),
-			weight DECIMAL(10,2),
-			status VARCHAR(50) DEFAULT 'in_stock',
-			created_at TIMESTAMPTZ DEFAULT NOW(),
-			updated_at TIMESTAMPTZ DEFAULT NOW(),
-			UNIQUE(tenant_id, sku)
-		)`,
+	byVersion := map[int]*Migration{}
+	for _, e := range entries {
+		m := migrationFileRe.FindStringSubmatch(e.Name())
+		if m == nil {
+			return nil, fmt.Errorf("invalid migration file name %q", e.Name())
+		}
+		version, _ := strconv.Atoi(m[1])
+		body, err := migrationFiles.ReadFile("migrations/" + e.Name())
+		if err != nil {
+			return nil, fmt.Errorf("failed to read migration %s: %w", e.Name(), err)
+		}
 
-		// 9. orders
-		`CREATE TABLE IF NOT EXISTS orders (
-			id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
-			tenant_id UUID NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
-			order_number VARCHAR(50) NOT NULL,
-			customer_name VARCHAR(255),
-			customer_email VARCHAR(255),
-			status VARCHAR(50) DEFAULT 'pending',
-			type VARCHAR(50) DEFAULT 'standard',
-			total_amount DECIMAL(10,2),
-			shipment_id UUID REFERENCES shipments(id),
-			scheduled_date TIMESTAMPTZ,
-			return_reason TEXT,
-			cancellation_reason TEXT,
-			created_at TIMESTAMPTZ DEFAULT NOW(),
-			updated_at TIMESTAMPTZ DEFAULT NOW(),
-			UNIQUE(tenant_id, order_number)
-		)`,
+		mig, ok := byVersion[version]
+		if !ok {
+			mig = &Migration{Version: version, Name: m[2]}
+			byVersion[version] = mig
+		} else if mig.Name != m[2] {
+			return nil, fmt.Errorf("migration %d has conflicting names %q and %q", version, mig.Name, m[2])
+		}
+		if m[3] == "up" {
+			mig.Up = string(body)
+			sum := sha256.Sum256(body)
+			mig.Checksum = hex.EncodeToString(sum[:])
+		} else {
+			mig.Down = string(body)
+		}
+	}
 
-		// 10. vendors
-		`CREATE TABLE IF NOT EXISTS vendors (
-			id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
-			tenant_id UUID NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
-			name VARCHAR(255) NOT NULL,
-			contact_person VARCHAR(255),
-			email VARCHAR(255),
-			phone VARCHAR(50),
-			address TEXT,
-			category VARCHAR(100),
-			rating DECIMAL(3,2) DEFAULT 0,
-			contract_start TIMESTAMPTZ,
-			contract_end TIMESTAMPTZ,
-			status VARCHAR(50) DEFAULT 'active',
-			created_at TIMESTAMPTZ DEFAULT NOW(),
-			updated_at TIMESTAMPTZ DEFAULT NOW()
-		)`,
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, mig := range byVersion {
+		if mig.Up == "" {
+			return nil, fmt.Errorf("migration %d_%s has no up file", mig.Version, mig.Name)
+		}
+		migrations = append(migrations, *mig)
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+	return migrations, nil
+}
 
-		// 11. clients
-		`CREATE TABLE IF NOT EXISTS clients (
-			id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
-			tenant_id UUID NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
-			company_name VARCHAR(255) NOT NULL,
-			contact_person VARCHAR(255),
-			email VARCHAR(255),
-			phone VARCHAR(50),
-			address TEXT,
-			industry VARCHAR(100),
-			total_shipments INTEGER DEFAULT 0,
-			total_spent DECIMAL(12,2) DEFAULT 0,
-			satisfaction_rating DECIMAL(3,2) DEFAULT 0,
-			status VARCHAR(50) DEFAULT 'active',
-			created_at TIMESTAMPTZ DEFAULT NOW(),
-			updated_at TIMESTAMPTZ DEFAULT NOW()
-		)`,
+// MigrateUp applies every pending migration up to and including target
+// (0 means all). Each migration runs in its own transaction together with its
+// schema_migrations row. It returns the migrations that were applied.
+func MigrateUp(ctx context.Context, pool *pgxpool.Pool, target int) ([]Migration, error) {
+	migrations, err := LoadMigrations()
+	if err != nil {
+		return nil, err
+	}
 
-		// 12. client_feedback
-		`CREATE TABLE IF NOT EXISTS client_feedback (
-			id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
-			tenant_id UUID NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
-			client_id UUID NOT NULL REFERENCES clients(id),
-			rating INTEGER CHECK (rating >= 1 AND rating <= 5),
-			comment TEXT,
-			category VARCHAR(100),
-			created_at TIMESTAMPTZ DEFAULT NOW()
-		)`,
+	var applied []Migration
+	err = withMigrationLock(ctx, pool, func(conn *pgxpool.Conn) error {
+		done, err := loadApplied(ctx, conn)
+		if err != nil {
+			return err
+		}
+		if err := verifyChecksums(migrations, done); err != nil {
+			return err
+		}
 
-		// 13. notifications
-		`CREATE TABLE IF NOT EXISTS notifications (
-			id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
-			tenant_id UUID NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
-			user_id UUID NOT NULL REFERENCES users(id),
-			title VARCHAR(255),
-			message TEXT,
-			type VARCHAR(50),
-			read BOOLEAN DEFAULT FALSE,
-			created_at TIMESTAMPTZ DEFAULT NOW()
-		)`,
+		for _, m := range migrations {
+			if target > 0 && m.Version > target {
+				break
+			}
+			if _, ok := done[m.Version]; ok {
+				continue
+			}
+			if err := runInTx(ctx, conn, m.Up, func(tx pgx.Tx) error {
+				_, err := tx.Exec(ctx,
+					`INSERT INTO schema_migrations (version, name, checksum, applied_at) VALUES ($1, $2, $3, NOW())`,
+					m.Version, m.Name, m.Checksum,
+				)
+				return err
+			}); err != nil {
+				return fmt.Errorf("migration %d_%s failed: %w", m.Version, m.Name, err)
+			}
+			applied = append(applied, m)
+		}
+		return nil
+	})
+	return applied, err
+}
 
-		// 14. roles
-		`CREATE TABLE IF NOT EXISTS roles (
-			id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
-			tenant_id UUID NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
-			name VARCHAR(100) NOT NULL,
-			permissions JSONB DEFAULT '{}',
-			created_at TIMESTAMPTZ DEFAULT NOW(),
-			updated_at TIMESTAMPTZ DEFAULT NOW(),
-			UNIQUE(tenant_id, name)
-		)`,
+// MigrateDown reverts the most recently applied migrations, newest first.
+// steps defaults to 1 when zero or negative.
+func MigrateDown(ctx context.Context, pool *pgxpool.Pool, steps int) ([]Migration, error) {
+	if steps <= 0 {
+		steps = 1
+	}
+	migrations, err := LoadMigrations()
+	if err != nil {
+		return nil, err
+	}
+	known := make(map[int]Migration, len(migrations))
+	for _, m := range migrations {
+		known[m.Version] = m
+	}
 
-		// 15. settings
-		`CREATE TABLE IF NOT EXISTS settings (
-			id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
-			tenant_id UUID NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
-			key VARCHAR(255) NOT NULL,
-			value TEXT,
-			category VARCHAR(100),
-			updated_by UUID REFERENCES users(id),
-			updated_at TIMESTAMPTZ DEFAULT NOW(),
-			UNIQUE(tenant_id, key)
-		)`,
+	var reverted []Migration
+	err = withMigrationLock(ctx, pool, func(conn *pgxpool.Conn) error {
+		done, err := loadApplied(ctx, conn)
+		if err != nil {
+			return err
+		}
+		versions := make([]int, 0, len(done))
+		for v := range done {
+			versions = append(versions, v)
+		}
+		sort.Sort(sort.Reverse(sort.IntSlice(versions)))
 
-		// 16. activity_log
-		`CREATE TABLE IF NOT EXISTS activity_log (
-			id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
-			tenant_id UUID NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
-			user_id UUID REFERENCES users(id),
-			action VARCHAR(255),
-			entity_type VARCHAR(100),
-			entity_id UUID,
-			details JSONB,
-			ip_address VARCHAR(45),
-			created_at TIMESTAMPTZ DEFAULT NOW()
-		)`,
+		for i := 0; i < steps && i < len(versions); i++ {
+			m, ok := known[versions[i]]
+			if !ok {
+				return fmt.Errorf("migration %d is applied but not present in this build", versions[i])
+			}
+			if m.Down == "" {
+				return fmt.Errorf("migration %d_%s is not reversible", m.Version, m.Name)
+			}
+			if err := runInTx(ctx, conn, m.Down, func(tx pgx.Tx) error {
+				_, err := tx.Exec(ctx, `DELETE FROM schema_migrations WHERE version = $1`, m.Version)
+				return err
+			}); err != nil {
+				return fmt.Errorf("revert of migration %d_%s failed: %w", m.Version, m.Name, err)
+			}
+			reverted = append(reverted, m)
+		}
+		return nil
+	})
+	return reverted, err
+}
 
-		// 17. notification_preferences
-		`CREATE TABLE IF NOT EXISTS notification_preferences (
-			id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
-			tenant_id UUID NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
-			user_id UUID NOT NULL REFERENCES users(id),
-			event_type VARCHAR(100),
-			email_enabled BOOLEAN DEFAULT TRUE,
-			sms_enabled BOOLEAN DEFAULT FALSE,
-			push_enabled BOOLEAN DEFAULT FALSE,
-			UNIQUE(tenant_id, user_id, event_type)
-		)`,
+// GetMigrationStatus lists every known or applied migration with its state.
+func GetMigrationStatus(ctx context.Context, pool *pgxpool.Pool) ([]MigrationStatus, error) {
+	migrations, err := LoadMigrations()
+	if err != nil {
+		return nil, err
+	}
 
-		// Indexes on tenant_id for all tables
-		`CREATE INDEX IF NOT EXISTS idx_users_tenant_id ON users(tenant_id)`,
-		`CREATE INDEX IF NOT EXISTS idx_shipments_tenant_id ON shipments(tenant_id)`,
-		`CREATE INDEX IF NOT EXISTS idx_vehicles_tenant_id ON vehicles(tenant_id)`,
-		`CREATE INDEX IF NOT EXISTS idx_drivers_tenant_id ON drivers(tenant_id)`,
-		`CREATE INDEX IF NOT EXISTS idx_maintenance_records_tenant_id ON maintenance_records(tenant_id)`,
-		`CREATE INDEX IF NOT EXISTS idx_warehouses_tenant_id ON warehouses(tenant_id)`,
-		`CREATE INDEX IF NOT EXISTS idx_inventory_items_tenant_id ON inventory_items(tenant_id)`,
-		`CREATE INDEX IF NOT EXISTS idx_orders_tenant_id ON orders(tenant_id)`,
-		`CREATE INDEX IF NOT EXISTS idx_vendors_tenant_id ON vendors(tenant_id)`,
-		`CREATE INDEX IF NOT EXISTS idx_clients_tenant_id ON clients(tenant_id)`,
-		`CREATE INDEX IF NOT EXISTS idx_client_feedback_tenant_id ON client_feedback(tenant_id)`,
-		`CREATE INDEX IF NOT EXISTS idx_notifications_tenant_id ON notifications(tenant_id)`,
-		`CREATE INDEX IF NOT EXISTS idx_notification_preferences_tenant_id ON notification_preferences(tenant_id)`,
-		`CREATE INDEX IF NOT EXISTS idx_roles_tenant_id ON roles(tenant_id)`,
-		`CREATE INDEX IF NOT EXISTS idx_settings_tenant_id ON settings(tenant_id)`,
-		`CREATE INDEX IF NOT EXISTS idx_activity_log_tenant_id ON activity_log(tenant_id)`,
+	conn, err := pool.Acquire(Privileged(ctx))
+	if err != nil {
+		return nil, fmt.Errorf("failed to acquire connection: %w", err)
+	}
+	defer conn.Release()
 
-		// 18. Add pin_hash column to drivers table
-		`ALTER TABLE drivers ADD COLUMN IF NOT EXISTS pin_hash VARCHAR(255)`,
+	if err := ensureMigrationsTable(ctx, conn); err != nil {
+		return nil, err
+	}
+	done, err := loadApplied(ctx, conn)
+	if err != nil {
+		return nil, err
+	}
 
-		// 19. gps_pings (high-volume)
-		`CREATE TABLE IF NOT EXISTS gps_pings (
-			id BIGSERIAL PRIMARY KEY,
-			tenant_id UUID NOT NULL REFERENCES tenants(id),
-			driver_id UUID NOT NULL REFERENCES drivers(id),
-			truck_id UUID NOT NULL,
-			shift_id UUID NOT NULL,
-			latitude DECIMAL(10,7) NOT NULL,
-			longitude DECIMAL(10,7) NOT NULL,
-			speed_kmh DECIMAL(5,1) DEFAULT 0,
-			heading SMALLINT DEFAULT 0 CHECK (heading >= 0 AND heading <= 360),
-			accuracy DECIMAL(5,1) DEFAULT 0,
-			battery_level SMALLINT DEFAULT 0 CHECK (battery_level >= 0 AND battery_level <= 100),
-			is_moving BOOLEAN DEFAULT false,
-			recorded_at TIMESTAMPTZ NOT NULL,
-			received_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
-			is_delayed BOOLEAN DEFAULT false,
-			created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
-		)`,
+	var status []MigrationStatus
+	for _, m := range migrations {
+		s := MigrationStatus{Version: m.Version, Name: m.Name}
+		if a, ok := done[m.Version]; ok {
+			appliedAt := a.AppliedAt
+			s.Applied = true
+			s.AppliedAt = &appliedAt
+			s.Modified = a.Checksum != m.Checksum
+			delete(done, m.Version)
+		}
+		status = append(status, s)
+	}
+	for _, a := range done {
+		appliedAt := a.AppliedAt
+		status = append(status, MigrationStatus{Version: a.Version, Name: a.Name, Applied: true, AppliedAt: &appliedAt, Missing: true})
+	}
+	sort.Slice(status, func(i, j int) bool { return status[i].Version < status[j].Version })
+	return status, nil
+}
 
-		// 20. shifts
-		`CREATE TABLE IF NOT EXISTS shifts (
-			id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
-			tenant_id UUID NOT NULL REFERENCES tenants(id),
-			driver_id UUID NOT NULL REFERENCES drivers(id),
-			truck_id UUID NOT NULL,
-			started_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
-			ended_at TIMESTAMPTZ,
-			status VARCHAR(20) NOT NULL DEFAULT 'active' CHECK (status IN ('active', 'completed')),
-			total_km DECIMAL(8,2) DEFAULT 0,
-			created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
-			updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
-		)`,
+// withMigrationLock runs fn on a dedicated connection holding the migration
+// advisory lock, running as the privileged login role (DDL needs table
+// ownership). Session-level locks are tied to the connection, so every
+// statement of the run must go through conn.
+func withMigrationLock(ctx context.Context, pool *pgxpool.Pool, fn func(conn *pgxpool.Conn) error) error {
+	conn, err := pool.Acquire(Privileged(ctx))
+	if err != nil {
+		return fmt.Errorf("failed to acquire connection: %w", err)
+	}
+	defer conn.Release()
 
-		// 21. alerts
-		`CREATE TABLE IF NOT EXISTS alerts (
-			id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
-			tenant_id UUID NOT NULL REFERENCES tenants(id),
-			driver_id UUID NOT NULL REFERENCES drivers(id),
-			shift_id UUID,
-			type VARCHAR(30) NOT NULL CHECK (type IN ('unauthorized_stop', 'driver_offline', 'speed_exceeded')),
-			status VARCHAR(20) NOT NULL DEFAULT 'triggered' CHECK (status IN ('triggered', 'notified', 'acknowledged', 'resolved', 'false_alarm')),
-			stop_latitude DECIMAL(10,7),
-			stop_longitude DECIMAL(10,7),
-			stop_duration_seconds INT DEFAULT 0,
-			nearest_zone_id UUID,
-			nearest_zone_distance_meters DECIMAL(10,2),
-			manager_notes TEXT,
-			triggered_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
-			notified_at TIMESTAMPTZ,
-			acknowledged_at TIMESTAMPTZ,
-			resolved_at TIMESTAMPTZ,
-			created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
-		)`,
+	if _, err := conn.Exec(ctx, `SELECT pg_advisory_lock($1)`, migrationLockKey); err != nil {
+		return fmt.Errorf("failed to acquire migration lock: %w", err)
+	}
+	defer conn.Exec(context.Background(), `SELECT pg_advisory_unlock($1)`, migrationLockKey)
 
-		// 22. approved_zones
-		`CREATE TABLE IF NOT EXISTS approved_zones (
-			id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
-			tenant_id UUID NOT NULL REFERENCES tenants(id),
-			label VARCHAR(255) NOT NULL,
-			latitude DECIMAL(10,7) NOT NULL,
-			longitude DECIMAL(10,7) NOT NULL,
-			radius_meters INT NOT NULL DEFAULT 500,
-			type VARCHAR(30) DEFAULT 'other' CHECK (type IN ('warehouse', 'client_site', 'gas_station', 'rest_area', 'other')),
-			created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
-			updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
-		)`,
+	if err := ensureMigrationsTable(ctx, conn); err != nil {
+		return err
+	}
+	return fn(conn)
+}
 
-		// 23. alert_config (one per company)
-		`CREATE TABLE IF NOT EXISTS alert_config (
-			id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
-			tenant_id UUID NOT NULL UNIQUE REFERENCES tenants(id),
-			max_stop_duration_minutes INT NOT NULL DEFAULT 5,
-			alert_on_driver_offline BOOLEAN NOT NULL DEFAULT true,
-			offline_threshold_minutes INT NOT NULL DEFAULT 3,
-			notify_via_push BOOLEAN NOT NULL DEFAULT true,
-			notify_via_email BOOLEAN NOT NULL DEFAULT true,
-			notify_via_sms BOOLEAN NOT NULL DEFAULT false,
-			updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
-		)`,
+func ensureMigrationsTable(ctx context.Context, conn *pgxpool.Conn) error {
+	_, err := conn.Exec(ctx, `CREATE TABLE IF NOT EXISTS schema_migrations (
+		version BIGINT PRIMARY KEY,
+		name VARCHAR(255) NOT NULL,
+		checksum CHAR(64) NOT NULL,
+		applied_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+	)`)
+	if err != nil {
+		return fmt.Errorf("failed to create schema_migrations: %w", err)
+	}
+	return nil
+}
 
-		// Indexes for new tables
-		`CREATE INDEX IF NOT EXISTS idx_gps_pings_driver_time ON gps_pings(tenant_id, driver_id, recorded_at DESC)`,
-		`CREATE INDEX IF NOT EXISTS idx_gps_pings_company_time ON gps_pings(tenant_id, recorded_at DESC)`,
-		`CREATE INDEX IF NOT EXISTS idx_gps_pings_shift ON gps_pings(shift_id, recorded_at DESC)`,
-		`CREATE INDEX IF NOT EXISTS idx_shifts_tenant ON shifts(tenant_id)`,
-		`CREATE INDEX IF NOT EXISTS idx_shifts_driver ON shifts(tenant_id, driver_id, started_at DESC)`,
-		`CREATE INDEX IF NOT EXISTS idx_shifts_active ON shifts(tenant_id, status) WHERE status = 'active'`,
-		`CREATE INDEX IF NOT EXISTS idx_alerts_tenant ON alerts(tenant_id, triggered_at DESC)`,
-		`CREATE INDEX IF NOT EXISTS idx_alerts_status ON alerts(tenant_id, status)`,
-		`CREATE INDEX IF NOT EXISTS idx_zones_tenant ON approved_zones(tenant_id)`,
+func loadApplied(ctx context.Context, conn *pgxpool.Conn) (map[int]appliedMigration, error) {
+	rows, err := conn.Query(ctx, `SELECT version, name, checksum, applied_at FROM schema_migrations`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read schema_migrations: %w", err)
 	}
+	defer rows.Close()
 
-	for i, migration := range migrations {
-		if _, err := pool.Exec(ctx, migration); err != nil {
-			return fmt.Errorf("migration %d failed: %w", i+1, err)
+	applied := map[int]appliedMigration{}
+	for rows.Next() {
+		var a appliedMigration
+		if err := rows.Scan(&a.Version, &a.Name, &a.Checksum, &a.AppliedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan schema_migrations: %w", err)
 		}
+		applied[a.Version] = a
 	}
+	return applied, rows.Err()
+}
 
+// verifyChecksums refuses to continue when an applied migration's file was
+// edited after the fact, since the live schema would no longer match it.
+func verifyChecksums(migrations []Migration, applied map[int]appliedMigration) error {
+	for _, m := range migrations {
+		if a, ok := applied[m.Version]; ok && a.Checksum != m.Checksum {
+			return fmt.Errorf("checksum mismatch for applied migration %d_%s: the file was modified after it ran", m.Version, m.Name)
+		}
+	}
 	return nil
 }
+
+func runInTx(ctx context.Context, conn *pgxpool.Conn, sql string, record func(tx pgx.Tx) error) error {
+	tx, err := conn.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, sql); err != nil {
+		return err
+	}
+	if err := record(tx); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
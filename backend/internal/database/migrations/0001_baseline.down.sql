@@ -0,0 +1,23 @@
+-- Drops the entire baseline schema in reverse dependency order.
+DROP TABLE IF EXISTS alert_config;
+DROP TABLE IF EXISTS approved_zones;
+DROP TABLE IF EXISTS alerts;
+DROP TABLE IF EXISTS shifts;
+DROP TABLE IF EXISTS gps_pings;
+DROP TABLE IF EXISTS notification_preferences;
+DROP TABLE IF EXISTS activity_log;
+DROP TABLE IF EXISTS settings;
+DROP TABLE IF EXISTS roles;
+DROP TABLE IF EXISTS notifications;
+DROP TABLE IF EXISTS client_feedback;
+DROP TABLE IF EXISTS clients;
+DROP TABLE IF EXISTS vendors;
+DROP TABLE IF EXISTS orders;
+DROP TABLE IF EXISTS inventory_items;
+DROP TABLE IF EXISTS warehouses;
+DROP TABLE IF EXISTS maintenance_records;
+DROP TABLE IF EXISTS drivers;
+DROP TABLE IF EXISTS vehicles;
+DROP TABLE IF EXISTS shipments;
+DROP TABLE IF EXISTS users;
+DROP TABLE IF EXISTS tenants;
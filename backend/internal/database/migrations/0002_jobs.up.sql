@@ -0,0 +1,21 @@
+-- jobs (background scheduler queue; tenant_id is NULL for system jobs)
+CREATE TABLE IF NOT EXISTS jobs (
+	id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+	tenant_id UUID REFERENCES tenants(id) ON DELETE CASCADE,
+	type VARCHAR(100) NOT NULL,
+	payload JSONB NOT NULL DEFAULT '{}',
+	status VARCHAR(20) NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'running', 'completed', 'dead')),
+	attempts INT NOT NULL DEFAULT 0,
+	max_attempts INT NOT NULL DEFAULT 5,
+	run_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+	locked_at TIMESTAMPTZ,
+	locked_by VARCHAR(255),
+	last_error TEXT,
+	dedupe_key VARCHAR(255) UNIQUE,
+	completed_at TIMESTAMPTZ,
+	created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+	updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+CREATE INDEX IF NOT EXISTS idx_jobs_due ON jobs(run_at) WHERE status = 'pending';
+CREATE INDEX IF NOT EXISTS idx_jobs_running ON jobs(locked_at) WHERE status = 'running';
+CREATE INDEX IF NOT EXISTS idx_jobs_tenant ON jobs(tenant_id, created_at DESC);
@@ -0,0 +1,35 @@
+-- The cargomax_app role is cluster-wide and may be shared with other
+-- databases, so it is left in place.
+
+SELECT disable_tenant_rls('jobs');
+SELECT disable_tenant_rls('alert_config');
+SELECT disable_tenant_rls('approved_zones');
+SELECT disable_tenant_rls('alerts');
+SELECT disable_tenant_rls('shifts');
+SELECT disable_tenant_rls('gps_pings');
+SELECT disable_tenant_rls('activity_log');
+SELECT disable_tenant_rls('settings');
+SELECT disable_tenant_rls('roles');
+SELECT disable_tenant_rls('notification_preferences');
+SELECT disable_tenant_rls('notifications');
+SELECT disable_tenant_rls('client_feedback');
+SELECT disable_tenant_rls('clients');
+SELECT disable_tenant_rls('vendors');
+SELECT disable_tenant_rls('orders');
+SELECT disable_tenant_rls('inventory_items');
+SELECT disable_tenant_rls('warehouses');
+SELECT disable_tenant_rls('maintenance_records');
+SELECT disable_tenant_rls('drivers');
+SELECT disable_tenant_rls('vehicles');
+SELECT disable_tenant_rls('shipments');
+SELECT disable_tenant_rls('users');
+
+DROP FUNCTION IF EXISTS enable_tenant_rls(regclass);
+DROP FUNCTION IF EXISTS disable_tenant_rls(regclass);
+DROP FUNCTION IF EXISTS current_tenant_id();
+
+ALTER DEFAULT PRIVILEGES IN SCHEMA public REVOKE SELECT, INSERT, UPDATE, DELETE ON TABLES FROM cargomax_app;
+ALTER DEFAULT PRIVILEGES IN SCHEMA public REVOKE USAGE, SELECT ON SEQUENCES FROM cargomax_app;
+REVOKE ALL ON ALL TABLES IN SCHEMA public FROM cargomax_app;
+REVOKE ALL ON ALL SEQUENCES IN SCHEMA public FROM cargomax_app;
+REVOKE USAGE ON SCHEMA public FROM cargomax_app;
@@ -0,0 +1,79 @@
+-- Row-level security for tenant isolation. Pooled connections switch to the
+-- unprivileged cargomax_app role and set app.tenant_id on every checkout (see
+-- database.prepareConn); the policies below then hide every row belonging to
+-- another tenant even if a query forgets its tenant_id filter. The login role
+-- owns the tables and bypasses RLS; the application only uses it through the
+-- explicit database.Privileged path.
+
+DO $$
+BEGIN
+	IF NOT EXISTS (SELECT 1 FROM pg_roles WHERE rolname = 'cargomax_app') THEN
+		CREATE ROLE cargomax_app NOLOGIN NOBYPASSRLS;
+	END IF;
+END
+$$;
+
+GRANT cargomax_app TO CURRENT_USER;
+GRANT USAGE ON SCHEMA public TO cargomax_app;
+GRANT SELECT, INSERT, UPDATE, DELETE ON ALL TABLES IN SCHEMA public TO cargomax_app;
+GRANT USAGE, SELECT ON ALL SEQUENCES IN SCHEMA public TO cargomax_app;
+ALTER DEFAULT PRIVILEGES IN SCHEMA public GRANT SELECT, INSERT, UPDATE, DELETE ON TABLES TO cargomax_app;
+ALTER DEFAULT PRIVILEGES IN SCHEMA public GRANT USAGE, SELECT ON SEQUENCES TO cargomax_app;
+
+-- The migration bookkeeping table is not application data.
+REVOKE ALL ON schema_migrations FROM cargomax_app;
+
+-- current_tenant_id returns the tenant bound to this session, or NULL.
+CREATE OR REPLACE FUNCTION current_tenant_id() RETURNS UUID
+LANGUAGE sql STABLE AS $$
+	SELECT NULLIF(current_setting('app.tenant_id', true), '')::uuid
+$$;
+
+-- enable_tenant_rls turns on RLS for a table with a tenant_id column and
+-- installs the tenant_isolation policy. Later migrations that add tenant
+-- tables must call it as well.
+CREATE OR REPLACE FUNCTION enable_tenant_rls(tbl regclass) RETURNS void
+LANGUAGE plpgsql AS $$
+BEGIN
+	EXECUTE format('ALTER TABLE %s ENABLE ROW LEVEL SECURITY', tbl);
+	EXECUTE format('DROP POLICY IF EXISTS tenant_isolation ON %s', tbl);
+	EXECUTE format(
+		'CREATE POLICY tenant_isolation ON %s USING (tenant_id = current_tenant_id()) WITH CHECK (tenant_id = current_tenant_id())',
+		tbl
+	);
+END
+$$;
+
+-- disable_tenant_rls reverses enable_tenant_rls (used by down migrations).
+CREATE OR REPLACE FUNCTION disable_tenant_rls(tbl regclass) RETURNS void
+LANGUAGE plpgsql AS $$
+BEGIN
+	EXECUTE format('DROP POLICY IF EXISTS tenant_isolation ON %s', tbl);
+	EXECUTE format('ALTER TABLE %s DISABLE ROW LEVEL SECURITY', tbl);
+END
+$$;
+
+-- tenants is the root of the hierarchy: registration creates a tenant before
+-- any tenant context exists, so it is not covered by a policy.
+SELECT enable_tenant_rls('users');
+SELECT enable_tenant_rls('shipments');
+SELECT enable_tenant_rls('vehicles');
+SELECT enable_tenant_rls('drivers');
+SELECT enable_tenant_rls('maintenance_records');
+SELECT enable_tenant_rls('warehouses');
+SELECT enable_tenant_rls('inventory_items');
+SELECT enable_tenant_rls('orders');
+SELECT enable_tenant_rls('vendors');
+SELECT enable_tenant_rls('clients');
+SELECT enable_tenant_rls('client_feedback');
+SELECT enable_tenant_rls('notifications');
+SELECT enable_tenant_rls('notification_preferences');
+SELECT enable_tenant_rls('roles');
+SELECT enable_tenant_rls('settings');
+SELECT enable_tenant_rls('activity_log');
+SELECT enable_tenant_rls('gps_pings');
+SELECT enable_tenant_rls('shifts');
+SELECT enable_tenant_rls('alerts');
+SELECT enable_tenant_rls('approved_zones');
+SELECT enable_tenant_rls('alert_config');
+SELECT enable_tenant_rls('jobs');
@@ -0,0 +1,3 @@
+ALTER TABLE shipments DROP CONSTRAINT IF EXISTS shipments_status_check;
+SELECT disable_tenant_rls('shipment_events');
+DROP TABLE IF EXISTS shipment_events;
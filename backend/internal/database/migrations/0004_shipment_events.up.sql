@@ -0,0 +1,30 @@
+-- shipment_events is the append-only tracking timeline of a shipment. Every
+-- status change goes through ShipmentRepo.Transition, which validates the move
+-- against models.CanTransitionShipment and records one row here.
+CREATE TABLE IF NOT EXISTS shipment_events (
+	id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+	tenant_id UUID NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+	shipment_id UUID NOT NULL REFERENCES shipments(id) ON DELETE CASCADE,
+	status VARCHAR(50) NOT NULL,
+	location VARCHAR(255),
+	note TEXT,
+	actor_type VARCHAR(20) NOT NULL DEFAULT 'system' CHECK (actor_type IN ('user', 'driver', 'system')),
+	actor_id UUID,
+	occurred_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+	created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+CREATE INDEX IF NOT EXISTS idx_shipment_events_shipment ON shipment_events(tenant_id, shipment_id, occurred_at);
+
+SELECT enable_tenant_rls('shipment_events');
+
+-- Restrict shipments.status to the state machine's vocabulary. NOT VALID
+-- leaves any legacy free-form values in place while enforcing new writes.
+ALTER TABLE shipments ADD CONSTRAINT shipments_status_check CHECK (status IN (
+	'pending', 'processing', 'picked_up', 'in_transit', 'out_for_delivery',
+	'delayed', 'delivered', 'cancelled', 'returned'
+)) NOT VALID;
+
+-- Give existing shipments a starting point on their timeline.
+INSERT INTO shipment_events (tenant_id, shipment_id, status, note, actor_type, occurred_at)
+SELECT tenant_id, id, status, 'Status at timeline migration', 'system', COALESCE(actual_delivery, updated_at, created_at, NOW())
+FROM shipments;
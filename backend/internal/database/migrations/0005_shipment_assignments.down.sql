@@ -0,0 +1,2 @@
+SELECT disable_tenant_rls('shipment_assignments');
+DROP TABLE IF EXISTS shipment_assignments;
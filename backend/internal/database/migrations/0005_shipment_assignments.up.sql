@@ -0,0 +1,21 @@
+-- shipment_assignments records which driver and vehicle carry a shipment. A
+-- reassignment closes the open row (unassigned_at) and opens a new one, so the
+-- table is the shipment's full assignment history. Starting a shift re-opens
+-- the driver's open assignments against that shift and its truck.
+CREATE TABLE IF NOT EXISTS shipment_assignments (
+	id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+	tenant_id UUID NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+	shipment_id UUID NOT NULL REFERENCES shipments(id) ON DELETE CASCADE,
+	driver_id UUID REFERENCES drivers(id) ON DELETE SET NULL,
+	vehicle_id UUID REFERENCES vehicles(id) ON DELETE SET NULL,
+	shift_id UUID REFERENCES shifts(id) ON DELETE SET NULL,
+	assigned_by UUID REFERENCES users(id) ON DELETE SET NULL,
+	assigned_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+	unassigned_at TIMESTAMPTZ,
+	created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_shipment_assignments_open ON shipment_assignments(shipment_id) WHERE unassigned_at IS NULL;
+CREATE INDEX IF NOT EXISTS idx_shipment_assignments_driver ON shipment_assignments(tenant_id, driver_id) WHERE unassigned_at IS NULL;
+CREATE INDEX IF NOT EXISTS idx_shipment_assignments_history ON shipment_assignments(tenant_id, shipment_id, assigned_at);
+
+SELECT enable_tenant_rls('shipment_assignments');
@@ -0,0 +1,11 @@
+SELECT disable_tenant_rls('lane_speeds');
+SELECT disable_tenant_rls('shipment_etas');
+DROP TABLE IF EXISTS lane_speeds;
+DROP TABLE IF EXISTS shipment_etas;
+DROP INDEX IF EXISTS idx_shipments_predicted_late;
+ALTER TABLE shipments
+	DROP COLUMN IF EXISTS eta_updated_at,
+	DROP COLUMN IF EXISTS predicted_late,
+	DROP COLUMN IF EXISTS predicted_delivery,
+	DROP COLUMN IF EXISTS destination_longitude,
+	DROP COLUMN IF EXISTS destination_latitude;
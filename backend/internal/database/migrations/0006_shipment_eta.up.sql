@@ -0,0 +1,42 @@
+-- Live ETA. destination_latitude/longitude locate the drop-off; the ETA worker
+-- keeps the latest prediction on the shipment itself and flags it
+-- predicted_late when it lands after the promised estimated_delivery.
+ALTER TABLE shipments
+	ADD COLUMN IF NOT EXISTS destination_latitude DECIMAL(10,7),
+	ADD COLUMN IF NOT EXISTS destination_longitude DECIMAL(10,7),
+	ADD COLUMN IF NOT EXISTS predicted_delivery TIMESTAMPTZ,
+	ADD COLUMN IF NOT EXISTS predicted_late BOOLEAN NOT NULL DEFAULT false,
+	ADD COLUMN IF NOT EXISTS eta_updated_at TIMESTAMPTZ;
+CREATE INDEX IF NOT EXISTS idx_shipments_predicted_late ON shipments(tenant_id) WHERE predicted_late;
+
+-- shipment_etas keeps every prediction that moved the ETA, next to the time
+-- promised when it was made, so accuracy can be reviewed after delivery.
+CREATE TABLE IF NOT EXISTS shipment_etas (
+	id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+	tenant_id UUID NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+	shipment_id UUID NOT NULL REFERENCES shipments(id) ON DELETE CASCADE,
+	shift_id UUID REFERENCES shifts(id) ON DELETE SET NULL,
+	predicted_delivery TIMESTAMPTZ NOT NULL,
+	promised_delivery TIMESTAMPTZ,
+	predicted_late BOOLEAN NOT NULL DEFAULT false,
+	remaining_km DECIMAL(8,2) NOT NULL,
+	speed_kmh DECIMAL(5,1) NOT NULL,
+	computed_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+CREATE INDEX IF NOT EXISTS idx_shipment_etas_shipment ON shipment_etas(tenant_id, shipment_id, computed_at);
+
+-- lane_speeds is the historical average pace between an origin and a
+-- destination, stops included. It is folded in on every delivery and serves as
+-- the prior before a shift has enough recent pings.
+CREATE TABLE IF NOT EXISTS lane_speeds (
+	tenant_id UUID NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+	origin VARCHAR(255) NOT NULL,
+	destination VARCHAR(255) NOT NULL,
+	avg_speed_kmh DECIMAL(5,1) NOT NULL,
+	samples INTEGER NOT NULL DEFAULT 1,
+	updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+	PRIMARY KEY (tenant_id, origin, destination)
+);
+
+SELECT enable_tenant_rls('shipment_etas');
+SELECT enable_tenant_rls('lane_speeds');
@@ -0,0 +1,4 @@
+SELECT disable_tenant_rls('proof_of_delivery_files');
+SELECT disable_tenant_rls('proof_of_delivery');
+DROP TABLE IF EXISTS proof_of_delivery_files;
+DROP TABLE IF EXISTS proof_of_delivery;
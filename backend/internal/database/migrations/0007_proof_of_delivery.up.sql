@@ -0,0 +1,35 @@
+-- Electronic proof of delivery. One record per shipment, written in the same
+-- transaction that moves it to delivered. latitude/longitude/captured_at come
+-- from the driver's latest ping on the active shift, and distance_m is how far
+-- that position was from the shipment's destination.
+CREATE TABLE IF NOT EXISTS proof_of_delivery (
+	id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+	tenant_id UUID NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+	shipment_id UUID NOT NULL UNIQUE REFERENCES shipments(id) ON DELETE CASCADE,
+	driver_id UUID REFERENCES drivers(id) ON DELETE SET NULL,
+	shift_id UUID REFERENCES shifts(id) ON DELETE SET NULL,
+	recipient_name VARCHAR(255) NOT NULL,
+	latitude DECIMAL(10,7) NOT NULL,
+	longitude DECIMAL(10,7) NOT NULL,
+	accuracy_m DECIMAL(7,1) NOT NULL DEFAULT 0,
+	distance_m DECIMAL(10,1) NOT NULL,
+	captured_at TIMESTAMPTZ NOT NULL,
+	created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+CREATE INDEX IF NOT EXISTS idx_proof_of_delivery_tenant ON proof_of_delivery(tenant_id);
+
+-- The signature and photos live in file storage; only their keys are kept.
+CREATE TABLE IF NOT EXISTS proof_of_delivery_files (
+	id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+	tenant_id UUID NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+	pod_id UUID NOT NULL REFERENCES proof_of_delivery(id) ON DELETE CASCADE,
+	kind VARCHAR(20) NOT NULL CHECK (kind IN ('signature', 'photo')),
+	storage_key TEXT NOT NULL,
+	content_type VARCHAR(100) NOT NULL,
+	size_bytes BIGINT NOT NULL,
+	created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+CREATE INDEX IF NOT EXISTS idx_proof_of_delivery_files_pod ON proof_of_delivery_files(tenant_id, pod_id);
+
+SELECT enable_tenant_rls('proof_of_delivery');
+SELECT enable_tenant_rls('proof_of_delivery_files');
@@ -0,0 +1,4 @@
+SELECT disable_tenant_rls('route_stops');
+SELECT disable_tenant_rls('route_plans');
+DROP TABLE IF EXISTS route_stops;
+DROP TABLE IF EXISTS route_plans;
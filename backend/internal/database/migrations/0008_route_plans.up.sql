@@ -0,0 +1,56 @@
+-- Route plans: a driver's ordered stops for one day. A plan is built as a
+-- draft, assigned to a driver and vehicle, linked to the driver's shift once
+-- it starts and completed when every stop is done or skipped.
+CREATE TABLE IF NOT EXISTS route_plans (
+	id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+	tenant_id UUID NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+	plan_date DATE NOT NULL,
+	name VARCHAR(255) NOT NULL,
+	warehouse_id UUID REFERENCES warehouses(id) ON DELETE SET NULL,
+	driver_id UUID REFERENCES drivers(id) ON DELETE SET NULL,
+	vehicle_id UUID REFERENCES vehicles(id) ON DELETE SET NULL,
+	shift_id UUID REFERENCES shifts(id) ON DELETE SET NULL,
+	status VARCHAR(20) NOT NULL DEFAULT 'draft' CHECK (status IN ('draft', 'assigned', 'in_progress', 'completed', 'cancelled')),
+	planned_start TIMESTAMPTZ NOT NULL,
+	notes TEXT,
+	created_by UUID REFERENCES users(id) ON DELETE SET NULL,
+	created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+	updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+CREATE INDEX IF NOT EXISTS idx_route_plans_date ON route_plans(tenant_id, plan_date);
+CREATE INDEX IF NOT EXISTS idx_route_plans_driver ON route_plans(tenant_id, driver_id, plan_date) WHERE status IN ('assigned', 'in_progress');
+
+-- route_stops are a plan's stops in visiting order. A stop is a pickup or a
+-- delivery of a shipment, or a visit to a warehouse or client site; its
+-- coordinates are copied from whichever it points at. planned_arrival is
+-- recomputed whenever the sequence changes; actual_arrival/actual_departure
+-- are filled from the driver's pings entering and leaving the stop.
+CREATE TABLE IF NOT EXISTS route_stops (
+	id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+	tenant_id UUID NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+	route_id UUID NOT NULL REFERENCES route_plans(id) ON DELETE CASCADE,
+	sequence INT NOT NULL,
+	type VARCHAR(20) NOT NULL CHECK (type IN ('pickup', 'delivery', 'depot', 'visit')),
+	shipment_id UUID REFERENCES shipments(id) ON DELETE SET NULL,
+	warehouse_id UUID REFERENCES warehouses(id) ON DELETE SET NULL,
+	zone_id UUID REFERENCES approved_zones(id) ON DELETE SET NULL,
+	label VARCHAR(255) NOT NULL,
+	latitude DECIMAL(10,7) NOT NULL,
+	longitude DECIMAL(10,7) NOT NULL,
+	radius_meters INT NOT NULL DEFAULT 200,
+	window_start TIMESTAMPTZ,
+	window_end TIMESTAMPTZ,
+	service_minutes INT NOT NULL DEFAULT 10 CHECK (service_minutes >= 0),
+	planned_arrival TIMESTAMPTZ,
+	actual_arrival TIMESTAMPTZ,
+	actual_departure TIMESTAMPTZ,
+	status VARCHAR(20) NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'arrived', 'completed', 'skipped')),
+	created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+	updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+	UNIQUE (route_id, sequence) DEFERRABLE INITIALLY DEFERRED,
+	CHECK (window_start IS NULL OR window_end IS NULL OR window_start <= window_end)
+);
+CREATE INDEX IF NOT EXISTS idx_route_stops_route ON route_stops(tenant_id, route_id, sequence);
+
+SELECT enable_tenant_rls('route_plans');
+SELECT enable_tenant_rls('route_stops');
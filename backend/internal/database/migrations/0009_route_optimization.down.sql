@@ -0,0 +1,5 @@
+ALTER TABLE warehouses
+	DROP COLUMN IF EXISTS longitude,
+	DROP COLUMN IF EXISTS latitude;
+ALTER TABLE vehicles
+	DROP COLUMN IF EXISTS capacity_kg;
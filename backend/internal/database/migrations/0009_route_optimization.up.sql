@@ -0,0 +1,8 @@
+-- Route optimization. capacity_kg is the payload a vehicle may carry (NULL for
+-- no limit); latitude/longitude place a warehouse as the depot routes start
+-- and end at.
+ALTER TABLE vehicles
+	ADD COLUMN IF NOT EXISTS capacity_kg DECIMAL(10,2);
+ALTER TABLE warehouses
+	ADD COLUMN IF NOT EXISTS latitude DECIMAL(10,7),
+	ADD COLUMN IF NOT EXISTS longitude DECIMAL(10,7);
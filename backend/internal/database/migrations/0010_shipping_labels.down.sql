@@ -0,0 +1,5 @@
+SELECT disable_tenant_rls('tenant_sequences');
+DROP TABLE IF EXISTS tenant_sequences;
+DROP INDEX IF EXISTS idx_shipments_warehouse;
+ALTER TABLE shipments
+	DROP COLUMN IF EXISTS warehouse_id;
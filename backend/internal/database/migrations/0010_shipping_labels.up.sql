@@ -0,0 +1,17 @@
+-- Shipping labels. warehouse_id is the warehouse a shipment leaves from, so a
+-- day's labels can be printed there in one batch. tenant_sequences hands out
+-- per-tenant serial numbers, starting with the generated tracking numbers;
+-- a row is advanced under its own row lock so concurrent callers never see
+-- the same value.
+ALTER TABLE shipments
+	ADD COLUMN IF NOT EXISTS warehouse_id UUID REFERENCES warehouses(id) ON DELETE SET NULL;
+CREATE INDEX IF NOT EXISTS idx_shipments_warehouse ON shipments(tenant_id, warehouse_id, created_at) WHERE warehouse_id IS NOT NULL;
+
+CREATE TABLE IF NOT EXISTS tenant_sequences (
+	tenant_id UUID NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+	name VARCHAR(50) NOT NULL,
+	last_value BIGINT NOT NULL DEFAULT 0,
+	PRIMARY KEY (tenant_id, name)
+);
+
+SELECT enable_tenant_rls('tenant_sequences');
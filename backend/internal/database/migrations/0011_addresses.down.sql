@@ -0,0 +1,15 @@
+DROP INDEX IF EXISTS idx_zones_client;
+DROP INDEX IF EXISTS idx_zones_warehouse;
+ALTER TABLE approved_zones
+	DROP COLUMN IF EXISTS client_id,
+	DROP COLUMN IF EXISTS warehouse_id;
+
+ALTER TABLE clients
+	DROP COLUMN IF EXISTS longitude,
+	DROP COLUMN IF EXISTS latitude,
+	DROP COLUMN IF EXISTS address_details;
+ALTER TABLE warehouses
+	DROP COLUMN IF EXISTS address_details;
+ALTER TABLE shipments
+	DROP COLUMN IF EXISTS destination_address,
+	DROP COLUMN IF EXISTS origin_address;
@@ -0,0 +1,20 @@
+-- Structured addresses. The JSONB columns hold an address broken into line1,
+-- line2, city, region, postal_code and country; the text columns next to them
+-- keep the one-line form for display and search. Clients get coordinates like
+-- warehouses, and an approved zone created for a warehouse or client site
+-- points back at it, so it moves with the record and goes when it is deleted.
+ALTER TABLE shipments
+	ADD COLUMN IF NOT EXISTS origin_address JSONB,
+	ADD COLUMN IF NOT EXISTS destination_address JSONB;
+ALTER TABLE warehouses
+	ADD COLUMN IF NOT EXISTS address_details JSONB;
+ALTER TABLE clients
+	ADD COLUMN IF NOT EXISTS address_details JSONB,
+	ADD COLUMN IF NOT EXISTS latitude DECIMAL(10,7),
+	ADD COLUMN IF NOT EXISTS longitude DECIMAL(10,7);
+
+ALTER TABLE approved_zones
+	ADD COLUMN IF NOT EXISTS warehouse_id UUID REFERENCES warehouses(id) ON DELETE CASCADE,
+	ADD COLUMN IF NOT EXISTS client_id UUID REFERENCES clients(id) ON DELETE CASCADE;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_zones_warehouse ON approved_zones(warehouse_id) WHERE warehouse_id IS NOT NULL;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_zones_client ON approved_zones(client_id) WHERE client_id IS NOT NULL;
@@ -0,0 +1,2 @@
+SELECT disable_tenant_rls('import_batches');
+DROP TABLE IF EXISTS import_batches;
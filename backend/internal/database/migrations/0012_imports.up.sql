@@ -0,0 +1,29 @@
+-- Bulk imports. A batch records an uploaded CSV or XLSX file (kept in file
+-- storage under storage_key), the header row read from it, how its columns
+-- map onto the entity's fields and the outcome of the last dry run or commit.
+-- errors holds the first row errors as [{row, field, message}].
+CREATE TABLE IF NOT EXISTS import_batches (
+	id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+	tenant_id UUID NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+	entity VARCHAR(20) NOT NULL CHECK (entity IN ('shipments', 'orders', 'inventory', 'drivers', 'vehicles')),
+	filename VARCHAR(255) NOT NULL,
+	storage_key TEXT NOT NULL,
+	status VARCHAR(20) NOT NULL DEFAULT 'uploaded' CHECK (status IN ('uploaded', 'validated', 'queued', 'completed', 'failed')),
+	mode VARCHAR(10) NOT NULL DEFAULT 'insert' CHECK (mode IN ('insert', 'upsert', 'skip')),
+	header JSONB NOT NULL DEFAULT '[]',
+	mapping JSONB NOT NULL DEFAULT '[]',
+	total_rows INTEGER NOT NULL DEFAULT 0,
+	created_count INTEGER NOT NULL DEFAULT 0,
+	updated_count INTEGER NOT NULL DEFAULT 0,
+	skipped_count INTEGER NOT NULL DEFAULT 0,
+	failed_count INTEGER NOT NULL DEFAULT 0,
+	errors JSONB NOT NULL DEFAULT '[]',
+	error TEXT,
+	created_by UUID REFERENCES users(id) ON DELETE SET NULL,
+	created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+	updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+	completed_at TIMESTAMPTZ
+);
+CREATE INDEX IF NOT EXISTS idx_import_batches_tenant ON import_batches(tenant_id, created_at DESC);
+
+SELECT enable_tenant_rls('import_batches');
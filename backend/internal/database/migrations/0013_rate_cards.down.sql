@@ -0,0 +1,6 @@
+ALTER TABLE orders
+	DROP COLUMN IF EXISTS quote,
+	DROP COLUMN IF EXISTS client_id;
+
+SELECT disable_tenant_rls('rate_cards');
+DROP TABLE IF EXISTS rate_cards;
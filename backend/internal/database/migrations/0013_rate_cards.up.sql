@@ -0,0 +1,29 @@
+-- Rate cards. A card's zones, lanes with their weight breaks and surcharges
+-- are edited and priced as a whole, so they are kept in JSONB next to it. A
+-- tenant has at most one active default card (no client) and one active card
+-- per client, which overrides the default for that client's orders.
+CREATE TABLE IF NOT EXISTS rate_cards (
+	id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+	tenant_id UUID NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+	name VARCHAR(255) NOT NULL,
+	client_id UUID REFERENCES clients(id) ON DELETE CASCADE,
+	currency CHAR(3) NOT NULL DEFAULT 'USD',
+	volumetric_divisor DECIMAL(10,2) NOT NULL DEFAULT 5000 CHECK (volumetric_divisor > 0),
+	min_charge DECIMAL(10,2) NOT NULL DEFAULT 0 CHECK (min_charge >= 0),
+	active BOOLEAN NOT NULL DEFAULT true,
+	zones JSONB NOT NULL DEFAULT '[]',
+	lanes JSONB NOT NULL DEFAULT '[]',
+	surcharges JSONB NOT NULL DEFAULT '[]',
+	created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+	updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_rate_cards_default ON rate_cards(tenant_id) WHERE client_id IS NULL AND active;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_rate_cards_client ON rate_cards(tenant_id, client_id) WHERE client_id IS NOT NULL AND active;
+
+SELECT enable_tenant_rls('rate_cards');
+
+-- Orders name the client they are billed to and keep the itemised quote their
+-- total was worked out from.
+ALTER TABLE orders
+	ADD COLUMN IF NOT EXISTS client_id UUID REFERENCES clients(id) ON DELETE SET NULL,
+	ADD COLUMN IF NOT EXISTS quote JSONB;
@@ -0,0 +1,10 @@
+DROP INDEX IF EXISTS idx_orders_uninvoiced;
+ALTER TABLE orders
+	DROP COLUMN IF EXISTS credit_note_id,
+	DROP COLUMN IF EXISTS invoice_id;
+
+SELECT disable_tenant_rls('payments');
+DROP TABLE IF EXISTS payments;
+
+SELECT disable_tenant_rls('invoices');
+DROP TABLE IF EXISTS invoices;
@@ -0,0 +1,65 @@
+-- Invoices and credit notes. An invoice bills one client for its delivered
+-- orders over a billing period; a credit note takes an amount off one
+-- invoice. Both are numbered from the tenant's sequences when issued and are
+-- never deleted: a mistaken invoice is voided. lines holds
+-- [{order_id, description, amount}] and taxes [{name, rate, amount}]; the
+-- bill-to columns keep the client's details as they were on the day.
+CREATE TABLE IF NOT EXISTS invoices (
+	id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+	tenant_id UUID NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+	client_id UUID NOT NULL REFERENCES clients(id) ON DELETE RESTRICT,
+	kind VARCHAR(20) NOT NULL DEFAULT 'invoice' CHECK (kind IN ('invoice', 'credit_note')),
+	invoice_number VARCHAR(30) NOT NULL,
+	status VARCHAR(20) NOT NULL DEFAULT 'issued' CHECK (status IN ('issued', 'partially_paid', 'paid', 'void')),
+	credited_invoice_id UUID REFERENCES invoices(id) ON DELETE RESTRICT,
+	bill_to_name VARCHAR(255) NOT NULL,
+	bill_to_address TEXT,
+	period_start DATE,
+	period_end DATE,
+	issue_date DATE NOT NULL,
+	due_date DATE NOT NULL,
+	currency CHAR(3) NOT NULL DEFAULT 'USD',
+	lines JSONB NOT NULL DEFAULT '[]',
+	taxes JSONB NOT NULL DEFAULT '[]',
+	subtotal DECIMAL(12,2) NOT NULL DEFAULT 0,
+	tax_total DECIMAL(12,2) NOT NULL DEFAULT 0,
+	total DECIMAL(12,2) NOT NULL DEFAULT 0,
+	amount_paid DECIMAL(12,2) NOT NULL DEFAULT 0,
+	amount_credited DECIMAL(12,2) NOT NULL DEFAULT 0,
+	reason TEXT,
+	created_by UUID REFERENCES users(id) ON DELETE SET NULL,
+	created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+	updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+	UNIQUE(tenant_id, invoice_number),
+	CHECK ((kind = 'credit_note') = (credited_invoice_id IS NOT NULL))
+);
+CREATE INDEX IF NOT EXISTS idx_invoices_client ON invoices(tenant_id, client_id, issue_date DESC);
+CREATE INDEX IF NOT EXISTS idx_invoices_open ON invoices(tenant_id, due_date) WHERE kind = 'invoice' AND status IN ('issued', 'partially_paid');
+CREATE INDEX IF NOT EXISTS idx_invoices_credited ON invoices(credited_invoice_id) WHERE credited_invoice_id IS NOT NULL;
+
+SELECT enable_tenant_rls('invoices');
+
+-- Payments received against invoices. Recording one also adds it to the
+-- client's total_spent.
+CREATE TABLE IF NOT EXISTS payments (
+	id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+	tenant_id UUID NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+	invoice_id UUID NOT NULL REFERENCES invoices(id) ON DELETE RESTRICT,
+	client_id UUID NOT NULL REFERENCES clients(id) ON DELETE RESTRICT,
+	amount DECIMAL(12,2) NOT NULL CHECK (amount > 0),
+	method VARCHAR(30),
+	reference VARCHAR(100),
+	paid_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+	created_by UUID REFERENCES users(id) ON DELETE SET NULL,
+	created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+CREATE INDEX IF NOT EXISTS idx_payments_invoice ON payments(invoice_id);
+CREATE INDEX IF NOT EXISTS idx_payments_tenant ON payments(tenant_id, paid_at);
+
+SELECT enable_tenant_rls('payments');
+
+-- An order is billed on one invoice and credited on at most one credit note.
+ALTER TABLE orders
+	ADD COLUMN IF NOT EXISTS invoice_id UUID REFERENCES invoices(id) ON DELETE SET NULL,
+	ADD COLUMN IF NOT EXISTS credit_note_id UUID REFERENCES invoices(id) ON DELETE SET NULL;
+CREATE INDEX IF NOT EXISTS idx_orders_uninvoiced ON orders(tenant_id, client_id) WHERE invoice_id IS NULL AND status = 'delivered';
@@ -0,0 +1,6 @@
+SELECT disable_tenant_rls('order_lines');
+DROP TABLE IF EXISTS order_lines;
+
+ALTER TABLE inventory_items
+	DROP CONSTRAINT IF EXISTS inventory_items_reserved_check,
+	DROP COLUMN IF EXISTS reserved;
@@ -0,0 +1,38 @@
+-- Stock held for open orders. quantity is what is on hand; reserved is the
+-- part of it promised to orders that have not shipped, so quantity -
+-- reserved is what can still be sold. The check is the last guard against
+-- overselling: a reservation that would exceed the stock on hand fails.
+ALTER TABLE inventory_items
+	ADD COLUMN IF NOT EXISTS reserved INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE inventory_items
+	ADD CONSTRAINT inventory_items_reserved_check CHECK (reserved >= 0 AND reserved <= GREATEST(quantity, 0));
+
+-- The items an order is for. sku, warehouse_id and description keep what was
+-- ordered should the inventory item later change or go. reserved_quantity is
+-- held in stock for the line, shipped_quantity has left the warehouse and
+-- returned_quantity has been put back after a return.
+CREATE TABLE IF NOT EXISTS order_lines (
+	id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+	tenant_id UUID NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+	order_id UUID NOT NULL REFERENCES orders(id) ON DELETE CASCADE,
+	line_number INTEGER NOT NULL,
+	inventory_item_id UUID REFERENCES inventory_items(id) ON DELETE SET NULL,
+	sku VARCHAR(100) NOT NULL,
+	warehouse_id UUID REFERENCES warehouses(id) ON DELETE SET NULL,
+	description VARCHAR(255),
+	quantity INTEGER NOT NULL CHECK (quantity > 0),
+	unit_price DECIMAL(10,2) NOT NULL CHECK (unit_price >= 0),
+	line_total DECIMAL(12,2) NOT NULL,
+	reserved_quantity INTEGER NOT NULL DEFAULT 0,
+	shipped_quantity INTEGER NOT NULL DEFAULT 0,
+	returned_quantity INTEGER NOT NULL DEFAULT 0,
+	created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+	updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+	UNIQUE(order_id, line_number),
+	CHECK (reserved_quantity >= 0 AND shipped_quantity >= 0 AND reserved_quantity + shipped_quantity <= quantity),
+	CHECK (returned_quantity >= 0 AND returned_quantity <= shipped_quantity)
+);
+CREATE INDEX IF NOT EXISTS idx_order_lines_item ON order_lines(inventory_item_id) WHERE inventory_item_id IS NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_order_lines_tenant ON order_lines(tenant_id, order_id);
+
+SELECT enable_tenant_rls('order_lines');
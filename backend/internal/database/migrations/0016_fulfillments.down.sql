@@ -0,0 +1,4 @@
+SELECT disable_tenant_rls('fulfillment_lines');
+SELECT disable_tenant_rls('fulfillments');
+DROP TABLE IF EXISTS fulfillment_lines;
+DROP TABLE IF EXISTS fulfillments;
@@ -0,0 +1,38 @@
+-- Fulfillment of orders with lines. A fulfillment is the part of an order
+-- picked at one warehouse and packed into one parcel: its pick list is the
+-- fulfillment_lines. Packing creates the shipment that carries it, so an
+-- order picked at two warehouses, or picked again for what was short, goes
+-- out in several shipments.
+CREATE TABLE IF NOT EXISTS fulfillments (
+	id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+	tenant_id UUID NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+	order_id UUID NOT NULL REFERENCES orders(id) ON DELETE CASCADE,
+	warehouse_id UUID REFERENCES warehouses(id) ON DELETE SET NULL,
+	status VARCHAR(20) NOT NULL DEFAULT 'picking' CHECK (status IN ('picking', 'picked', 'shipped', 'cancelled')),
+	shipment_id UUID REFERENCES shipments(id) ON DELETE SET NULL,
+	weight DECIMAL(10,2),
+	dimensions VARCHAR(100),
+	created_by UUID REFERENCES users(id) ON DELETE SET NULL,
+	picked_at TIMESTAMPTZ,
+	packed_at TIMESTAMPTZ,
+	created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+	updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+CREATE INDEX IF NOT EXISTS idx_fulfillments_order ON fulfillments(tenant_id, order_id);
+CREATE INDEX IF NOT EXISTS idx_fulfillments_open ON fulfillments(tenant_id, warehouse_id) WHERE status IN ('picking', 'picked');
+CREATE INDEX IF NOT EXISTS idx_fulfillments_shipment ON fulfillments(shipment_id) WHERE shipment_id IS NOT NULL;
+
+-- quantity is what is to be picked for the order line; picking short lowers
+-- it, leaving the rest for another pick list.
+CREATE TABLE IF NOT EXISTS fulfillment_lines (
+	id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+	tenant_id UUID NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+	fulfillment_id UUID NOT NULL REFERENCES fulfillments(id) ON DELETE CASCADE,
+	order_line_id UUID NOT NULL REFERENCES order_lines(id) ON DELETE CASCADE,
+	quantity INTEGER NOT NULL CHECK (quantity >= 0),
+	UNIQUE(fulfillment_id, order_line_id)
+);
+CREATE INDEX IF NOT EXISTS idx_fulfillment_lines_order_line ON fulfillment_lines(order_line_id);
+
+SELECT enable_tenant_rls('fulfillments');
+SELECT enable_tenant_rls('fulfillment_lines');
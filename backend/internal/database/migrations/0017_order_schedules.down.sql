@@ -0,0 +1,7 @@
+DROP INDEX IF EXISTS idx_orders_scheduled_due;
+ALTER TABLE orders
+	DROP COLUMN IF EXISTS schedule_failure,
+	DROP COLUMN IF EXISTS order_schedule_id;
+
+SELECT disable_tenant_rls('order_schedules');
+DROP TABLE IF EXISTS order_schedules;
@@ -0,0 +1,42 @@
+-- Standing orders. A schedule repeats an order every `every` days, weeks or
+-- months from start_date until end_date, leaving out skip_dates. next_date
+-- is the next occurrence still to be created; the order for it is created
+-- lead_days ahead as a scheduled order and becomes pending on the day.
+-- lines is the order's template: [{inventoryItemId, sku, warehouseId,
+-- quantity, unitPrice}], unitPrice null for the item's price on the day.
+CREATE TABLE IF NOT EXISTS order_schedules (
+	id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+	tenant_id UUID NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+	name VARCHAR(255) NOT NULL,
+	order_prefix VARCHAR(30) NOT NULL,
+	client_id UUID REFERENCES clients(id) ON DELETE CASCADE,
+	customer_name VARCHAR(255),
+	customer_email VARCHAR(255),
+	order_type VARCHAR(50) NOT NULL DEFAULT 'standard',
+	lines JSONB NOT NULL DEFAULT '[]',
+	frequency VARCHAR(10) NOT NULL CHECK (frequency IN ('daily', 'weekly', 'monthly')),
+	every INTEGER NOT NULL DEFAULT 1 CHECK (every BETWEEN 1 AND 365),
+	start_date DATE NOT NULL,
+	end_date DATE,
+	skip_dates DATE[] NOT NULL DEFAULT '{}',
+	lead_days INTEGER NOT NULL DEFAULT 0 CHECK (lead_days BETWEEN 0 AND 60),
+	next_date DATE,
+	auto_fulfill BOOLEAN NOT NULL DEFAULT false,
+	active BOOLEAN NOT NULL DEFAULT true,
+	created_by UUID REFERENCES users(id) ON DELETE SET NULL,
+	created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+	updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+	UNIQUE(tenant_id, order_prefix),
+	CHECK (end_date IS NULL OR end_date >= start_date)
+);
+CREATE INDEX IF NOT EXISTS idx_order_schedules_due ON order_schedules(next_date) WHERE active AND next_date IS NOT NULL;
+
+-- order_schedule_id is the schedule an order was created from.
+-- schedule_failure is why the worker could not move a scheduled order to
+-- pending on its day, e.g. a stock shortage; it is retried until it can.
+ALTER TABLE orders
+	ADD COLUMN IF NOT EXISTS order_schedule_id UUID REFERENCES order_schedules(id) ON DELETE SET NULL,
+	ADD COLUMN IF NOT EXISTS schedule_failure TEXT;
+CREATE INDEX IF NOT EXISTS idx_orders_scheduled_due ON orders(scheduled_date) WHERE status = 'scheduled';
+
+SELECT enable_tenant_rls('order_schedules');
@@ -0,0 +1,4 @@
+SELECT disable_tenant_rls('rma_lines');
+SELECT disable_tenant_rls('rmas');
+DROP TABLE IF EXISTS rma_lines;
+DROP TABLE IF EXISTS rmas;
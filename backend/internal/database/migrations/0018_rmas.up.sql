@@ -0,0 +1,55 @@
+-- Return merchandise authorizations. An RMA is a customer's request to send
+-- back part of a shipped order. It is approved line by line, optionally
+-- comes back on a return shipment, is inspected on receipt, where each line
+-- is graded and restocked or written off, and is closed with a refund or a
+-- credit note.
+CREATE TABLE IF NOT EXISTS rmas (
+	id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+	tenant_id UUID NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+	rma_number VARCHAR(50) NOT NULL,
+	order_id UUID NOT NULL REFERENCES orders(id) ON DELETE CASCADE,
+	status VARCHAR(20) NOT NULL DEFAULT 'requested'
+		CHECK (status IN ('requested', 'approved', 'rejected', 'in_transit', 'received', 'closed', 'cancelled')),
+	reason TEXT NOT NULL,
+	notes TEXT,
+	warehouse_id UUID REFERENCES warehouses(id) ON DELETE SET NULL,
+	return_shipment_id UUID REFERENCES shipments(id) ON DELETE SET NULL,
+	rejection_reason TEXT,
+	resolution VARCHAR(10) CHECK (resolution IN ('refund', 'credit', 'none')),
+	resolution_amount DECIMAL(12,2),
+	refund_reference VARCHAR(255),
+	credit_note_id UUID REFERENCES invoices(id) ON DELETE SET NULL,
+	created_by UUID REFERENCES users(id) ON DELETE SET NULL,
+	approved_at TIMESTAMPTZ,
+	received_at TIMESTAMPTZ,
+	closed_at TIMESTAMPTZ,
+	created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+	updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+	UNIQUE(tenant_id, rma_number)
+);
+CREATE INDEX IF NOT EXISTS idx_rmas_order ON rmas(tenant_id, order_id);
+CREATE INDEX IF NOT EXISTS idx_rmas_status ON rmas(tenant_id, status);
+
+-- quantity is what the customer asked to send back, approved_quantity what
+-- was agreed and received_quantity what arrived. What arrived is either
+-- restocked or written off, by the condition it arrived in: A as new, B
+-- good, C damaged, D unusable.
+CREATE TABLE IF NOT EXISTS rma_lines (
+	id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+	tenant_id UUID NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+	rma_id UUID NOT NULL REFERENCES rmas(id) ON DELETE CASCADE,
+	order_line_id UUID NOT NULL REFERENCES order_lines(id) ON DELETE CASCADE,
+	quantity INTEGER NOT NULL CHECK (quantity > 0),
+	approved_quantity INTEGER NOT NULL DEFAULT 0 CHECK (approved_quantity BETWEEN 0 AND quantity),
+	received_quantity INTEGER NOT NULL DEFAULT 0 CHECK (received_quantity BETWEEN 0 AND approved_quantity),
+	restocked_quantity INTEGER NOT NULL DEFAULT 0,
+	written_off_quantity INTEGER NOT NULL DEFAULT 0,
+	condition CHAR(1) CHECK (condition IN ('A', 'B', 'C', 'D')),
+	inspection_notes TEXT,
+	UNIQUE(rma_id, order_line_id),
+	CHECK (restocked_quantity + written_off_quantity = received_quantity)
+);
+CREATE INDEX IF NOT EXISTS idx_rma_lines_order_line ON rma_lines(order_line_id);
+
+SELECT enable_tenant_rls('rmas');
+SELECT enable_tenant_rls('rma_lines');
@@ -0,0 +1,3 @@
+SELECT disable_tenant_rls('stock_movements');
+DROP TABLE IF EXISTS stock_movements;
+DROP FUNCTION IF EXISTS stock_movements_append_only();
@@ -0,0 +1,57 @@
+-- An append-only ledger of every change to an item's stock on hand. quantity
+-- is signed: receipts and returns add, picks and write-offs take away,
+-- adjustments go either way, and a transfer is a pair of movements out of
+-- one warehouse and into another. balance is the item's quantity after the
+-- movement, and the movements of an item add up to its quantity.
+CREATE TABLE IF NOT EXISTS stock_movements (
+	id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+	tenant_id UUID NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+	inventory_item_id UUID NOT NULL REFERENCES inventory_items(id) ON DELETE CASCADE,
+	warehouse_id UUID REFERENCES warehouses(id) ON DELETE SET NULL,
+	sku VARCHAR(100) NOT NULL,
+	movement_type VARCHAR(20) NOT NULL
+		CHECK (movement_type IN ('receipt', 'pick', 'adjustment', 'transfer', 'return', 'write_off')),
+	quantity INTEGER NOT NULL CHECK (quantity <> 0),
+	balance INTEGER NOT NULL,
+	reason TEXT,
+	reference_type VARCHAR(30),
+	reference_id UUID,
+	reference VARCHAR(100),
+	created_by UUID REFERENCES users(id) ON DELETE SET NULL,
+	created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+CREATE INDEX IF NOT EXISTS idx_stock_movements_item ON stock_movements(tenant_id, inventory_item_id, created_at);
+CREATE INDEX IF NOT EXISTS idx_stock_movements_created ON stock_movements(tenant_id, created_at);
+
+-- Movements cannot be changed or removed, except along with their item or
+-- tenant. The warehouse and user references are cleared in place when those
+-- go.
+CREATE OR REPLACE FUNCTION stock_movements_append_only() RETURNS trigger
+LANGUAGE plpgsql AS $$
+BEGIN
+	IF TG_OP = 'DELETE' THEN
+		IF NOT EXISTS (SELECT 1 FROM inventory_items WHERE id = OLD.inventory_item_id)
+			OR NOT EXISTS (SELECT 1 FROM tenants WHERE id = OLD.tenant_id) THEN
+			RETURN OLD;
+		END IF;
+	ELSIF NEW.id = OLD.id AND NEW.inventory_item_id = OLD.inventory_item_id AND NEW.movement_type = OLD.movement_type
+		AND NEW.quantity = OLD.quantity AND NEW.balance = OLD.balance AND NEW.created_at = OLD.created_at
+		AND (NEW.warehouse_id IS NULL OR NEW.warehouse_id = OLD.warehouse_id)
+		AND (NEW.created_by IS NULL OR NEW.created_by = OLD.created_by) THEN
+		RETURN NEW;
+	END IF;
+	RAISE EXCEPTION 'stock movements are append-only';
+END
+$$;
+
+DROP TRIGGER IF EXISTS stock_movements_append_only ON stock_movements;
+CREATE TRIGGER stock_movements_append_only BEFORE UPDATE OR DELETE ON stock_movements
+	FOR EACH ROW EXECUTE FUNCTION stock_movements_append_only();
+
+-- Open the ledger of existing items with their stock on hand.
+INSERT INTO stock_movements (tenant_id, inventory_item_id, warehouse_id, sku, movement_type, quantity, balance, reason, created_at)
+SELECT i.tenant_id, i.id, i.warehouse_id, i.sku, 'adjustment', i.quantity, i.quantity, 'Opening balance', i.created_at
+FROM inventory_items i
+WHERE i.quantity <> 0 AND NOT EXISTS (SELECT 1 FROM stock_movements m WHERE m.inventory_item_id = i.id);
+
+SELECT enable_tenant_rls('stock_movements');
@@ -0,0 +1,25 @@
+SELECT disable_tenant_rls('transfer_order_lines');
+SELECT disable_tenant_rls('transfer_orders');
+DROP TABLE IF EXISTS transfer_order_lines;
+DROP TABLE IF EXISTS transfer_orders;
+
+-- A SKU stocked in several warehouses keeps only its stock level created
+-- first.
+DELETE FROM inventory_items i USING inventory_items o
+WHERE o.tenant_id = i.tenant_id AND o.sku = i.sku AND (o.created_at, o.id) < (i.created_at, i.id);
+DROP INDEX IF EXISTS idx_inventory_items_sku;
+ALTER TABLE inventory_items
+	ADD COLUMN IF NOT EXISTS name VARCHAR(255),
+	ADD COLUMN IF NOT EXISTS category VARCHAR(100),
+	ADD COLUMN IF NOT EXISTS unit_price DECIMAL(10,2),
+	ADD COLUMN IF NOT EXISTS weight DECIMAL(10,2);
+UPDATE inventory_items i SET name = p.name, category = p.category, unit_price = p.unit_price, weight = p.weight
+FROM products p WHERE p.id = i.product_id;
+ALTER TABLE inventory_items
+	DROP CONSTRAINT IF EXISTS inventory_items_product_warehouse_key,
+	ADD CONSTRAINT inventory_items_tenant_id_sku_key UNIQUE (tenant_id, sku),
+	DROP COLUMN IF EXISTS in_transit,
+	DROP COLUMN IF EXISTS product_id;
+
+SELECT disable_tenant_rls('products');
+DROP TABLE IF EXISTS products;
@@ -0,0 +1,80 @@
+-- The product catalog, split from per-warehouse stock. A product is a SKU
+-- and what describes it; inventory_items becomes its stock level in one
+-- warehouse, so a SKU can be stocked in several. Each stock level keeps a
+-- copy of its product's sku, as order lines and stock movements do.
+CREATE TABLE IF NOT EXISTS products (
+	id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+	tenant_id UUID NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+	sku VARCHAR(100) NOT NULL,
+	name VARCHAR(255),
+	category VARCHAR(100),
+	unit_price DECIMAL(10,2),
+	weight DECIMAL(10,2),
+	created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+	updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+	UNIQUE(tenant_id, sku)
+);
+
+INSERT INTO products (tenant_id, sku, name, category, unit_price, weight, created_at, updated_at)
+SELECT tenant_id, sku, name, category, unit_price, weight, COALESCE(created_at, NOW()), COALESCE(updated_at, NOW())
+FROM inventory_items
+ON CONFLICT (tenant_id, sku) DO NOTHING;
+
+-- in_transit is stock transferred to the warehouse that has not arrived yet.
+ALTER TABLE inventory_items
+	ADD COLUMN IF NOT EXISTS product_id UUID REFERENCES products(id) ON DELETE CASCADE,
+	ADD COLUMN IF NOT EXISTS in_transit INTEGER NOT NULL DEFAULT 0 CHECK (in_transit >= 0);
+UPDATE inventory_items i SET product_id = p.id
+FROM products p
+WHERE p.tenant_id = i.tenant_id AND p.sku = i.sku AND i.product_id IS NULL;
+ALTER TABLE inventory_items
+	ALTER COLUMN product_id SET NOT NULL,
+	DROP CONSTRAINT IF EXISTS inventory_items_tenant_id_sku_key,
+	ADD CONSTRAINT inventory_items_product_warehouse_key UNIQUE (product_id, warehouse_id),
+	DROP COLUMN IF EXISTS name,
+	DROP COLUMN IF EXISTS category,
+	DROP COLUMN IF EXISTS unit_price,
+	DROP COLUMN IF EXISTS weight;
+CREATE INDEX IF NOT EXISTS idx_inventory_items_sku ON inventory_items(tenant_id, sku);
+
+-- Transfer orders move stock between two of a tenant's warehouses. A
+-- pending transfer holds its stock at the source; dispatching it takes the
+-- stock out on a shipment and counts it in transit at the destination,
+-- where receiving it puts what arrived into stock.
+CREATE TABLE IF NOT EXISTS transfer_orders (
+	id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+	tenant_id UUID NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+	transfer_number VARCHAR(50) NOT NULL,
+	from_warehouse_id UUID NOT NULL REFERENCES warehouses(id),
+	to_warehouse_id UUID NOT NULL REFERENCES warehouses(id),
+	status VARCHAR(20) NOT NULL DEFAULT 'pending'
+		CHECK (status IN ('pending', 'in_transit', 'received', 'cancelled')),
+	shipment_id UUID REFERENCES shipments(id) ON DELETE SET NULL,
+	notes TEXT,
+	created_by UUID REFERENCES users(id) ON DELETE SET NULL,
+	dispatched_at TIMESTAMPTZ,
+	received_at TIMESTAMPTZ,
+	created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+	updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+	UNIQUE(tenant_id, transfer_number),
+	CHECK (from_warehouse_id <> to_warehouse_id)
+);
+CREATE INDEX IF NOT EXISTS idx_transfer_orders_status ON transfer_orders(tenant_id, status);
+CREATE INDEX IF NOT EXISTS idx_transfer_orders_from ON transfer_orders(tenant_id, from_warehouse_id);
+CREATE INDEX IF NOT EXISTS idx_transfer_orders_to ON transfer_orders(tenant_id, to_warehouse_id);
+
+-- received_quantity is what arrived; the rest of quantity was lost on the way.
+CREATE TABLE IF NOT EXISTS transfer_order_lines (
+	id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+	tenant_id UUID NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+	transfer_order_id UUID NOT NULL REFERENCES transfer_orders(id) ON DELETE CASCADE,
+	product_id UUID NOT NULL REFERENCES products(id),
+	sku VARCHAR(100) NOT NULL,
+	quantity INTEGER NOT NULL CHECK (quantity > 0),
+	received_quantity INTEGER NOT NULL DEFAULT 0 CHECK (received_quantity BETWEEN 0 AND quantity),
+	UNIQUE(transfer_order_id, product_id)
+);
+
+SELECT enable_tenant_rls('products');
+SELECT enable_tenant_rls('transfer_orders');
+SELECT enable_tenant_rls('transfer_order_lines');
@@ -0,0 +1,6 @@
+SELECT disable_tenant_rls('purchase_order_lines');
+SELECT disable_tenant_rls('purchase_orders');
+SELECT disable_tenant_rls('supplier_products');
+DROP TABLE IF EXISTS purchase_order_lines;
+DROP TABLE IF EXISTS purchase_orders;
+DROP TABLE IF EXISTS supplier_products;
@@ -0,0 +1,62 @@
+-- The supplier catalog: what each vendor supplies, under its own SKU, at
+-- what cost, how long it takes to arrive and the least it will sell. The
+-- reorder job buys a product from its preferred supplier, or else the
+-- cheapest.
+CREATE TABLE IF NOT EXISTS supplier_products (
+	id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+	tenant_id UUID NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+	vendor_id UUID NOT NULL REFERENCES vendors(id) ON DELETE CASCADE,
+	product_id UUID NOT NULL REFERENCES products(id) ON DELETE CASCADE,
+	vendor_sku VARCHAR(100),
+	unit_cost DECIMAL(10,2) NOT NULL CHECK (unit_cost >= 0),
+	lead_time_days INTEGER NOT NULL DEFAULT 0 CHECK (lead_time_days >= 0),
+	min_order_quantity INTEGER NOT NULL DEFAULT 1 CHECK (min_order_quantity > 0),
+	preferred BOOLEAN NOT NULL DEFAULT FALSE,
+	created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+	updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+	UNIQUE(vendor_id, product_id)
+);
+CREATE INDEX IF NOT EXISTS idx_supplier_products_product ON supplier_products(tenant_id, product_id);
+
+-- Purchase orders buy stock from a vendor into one warehouse. A draft is
+-- sent to the vendor, then received in one or more deliveries; source says
+-- whether a user or the reorder job drafted it.
+CREATE TABLE IF NOT EXISTS purchase_orders (
+	id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+	tenant_id UUID NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+	po_number VARCHAR(50) NOT NULL,
+	vendor_id UUID NOT NULL REFERENCES vendors(id),
+	warehouse_id UUID NOT NULL REFERENCES warehouses(id),
+	status VARCHAR(20) NOT NULL DEFAULT 'draft'
+		CHECK (status IN ('draft', 'sent', 'partially_received', 'received', 'cancelled')),
+	source VARCHAR(20) NOT NULL DEFAULT 'manual' CHECK (source IN ('manual', 'reorder')),
+	expected_date DATE,
+	notes TEXT,
+	created_by UUID REFERENCES users(id) ON DELETE SET NULL,
+	sent_at TIMESTAMPTZ,
+	received_at TIMESTAMPTZ,
+	created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+	updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+	UNIQUE(tenant_id, po_number)
+);
+CREATE INDEX IF NOT EXISTS idx_purchase_orders_status ON purchase_orders(tenant_id, status);
+CREATE INDEX IF NOT EXISTS idx_purchase_orders_vendor ON purchase_orders(tenant_id, vendor_id);
+CREATE INDEX IF NOT EXISTS idx_purchase_orders_warehouse ON purchase_orders(tenant_id, warehouse_id);
+
+CREATE TABLE IF NOT EXISTS purchase_order_lines (
+	id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+	tenant_id UUID NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+	purchase_order_id UUID NOT NULL REFERENCES purchase_orders(id) ON DELETE CASCADE,
+	product_id UUID NOT NULL REFERENCES products(id),
+	sku VARCHAR(100) NOT NULL,
+	vendor_sku VARCHAR(100),
+	quantity INTEGER NOT NULL CHECK (quantity > 0),
+	received_quantity INTEGER NOT NULL DEFAULT 0 CHECK (received_quantity BETWEEN 0 AND quantity),
+	unit_cost DECIMAL(10,2) NOT NULL CHECK (unit_cost >= 0),
+	UNIQUE(purchase_order_id, product_id)
+);
+CREATE INDEX IF NOT EXISTS idx_purchase_order_lines_product ON purchase_order_lines(tenant_id, product_id);
+
+SELECT enable_tenant_rls('supplier_products');
+SELECT enable_tenant_rls('purchase_orders');
+SELECT enable_tenant_rls('purchase_order_lines');
@@ -0,0 +1,9 @@
+DROP FUNCTION IF EXISTS stock_capacity_use(VARCHAR, INTEGER, INTEGER, NUMERIC, NUMERIC);
+ALTER TABLE warehouses
+	DROP COLUMN IF EXISTS fill_alert_level,
+	DROP COLUMN IF EXISTS fill_thresholds,
+	DROP COLUMN IF EXISTS capacity_unit,
+	ALTER COLUMN used_capacity TYPE INTEGER USING CEIL(used_capacity);
+ALTER TABLE products
+	DROP COLUMN IF EXISTS units_per_pallet,
+	DROP COLUMN IF EXISTS volume;
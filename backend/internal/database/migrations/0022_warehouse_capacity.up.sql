@@ -0,0 +1,81 @@
+-- What one unit of a product takes up in a warehouse: its volume in cubic
+-- metres and how many fit on a pallet. Its weight is already recorded.
+ALTER TABLE products
+	ADD COLUMN IF NOT EXISTS volume DECIMAL(10,4) CHECK (volume > 0),
+	ADD COLUMN IF NOT EXISTS units_per_pallet INTEGER CHECK (units_per_pallet > 0);
+
+-- A warehouse measures its capacity in pallets, cubic metres or kg.
+-- used_capacity is no longer set by hand: it is the space the stock on hand
+-- takes up, kept in step with the stock ledger. Admins and managers are told
+-- when it passes one of fill_thresholds (percentages of capacity);
+-- fill_alert_level is the highest threshold they have been told about.
+-- Volume and weight are not whole numbers, so neither is used_capacity.
+ALTER TABLE warehouses
+	ALTER COLUMN used_capacity TYPE DECIMAL(14,4),
+	ADD COLUMN IF NOT EXISTS capacity_unit VARCHAR(20) NOT NULL DEFAULT 'pallets'
+		CHECK (capacity_unit IN ('pallets', 'cubic_metres', 'kg')),
+	ADD COLUMN IF NOT EXISTS fill_thresholds INTEGER[] NOT NULL DEFAULT '{80,95}'
+		CHECK (0 < ALL(fill_thresholds) AND 100 >= ALL(fill_thresholds)),
+	ADD COLUMN IF NOT EXISTS fill_alert_level INTEGER NOT NULL DEFAULT 0;
+
+-- The space quantity units of a product take up, in a warehouse's unit.
+-- Stock of one product is not mixed with another's on a pallet, so a part
+-- pallet counts as a whole one; cubic metres and kg are not rounded. A
+-- product without the measure takes up none.
+CREATE OR REPLACE FUNCTION stock_capacity_use(unit VARCHAR, quantity INTEGER, units_per_pallet INTEGER, volume NUMERIC, weight NUMERIC)
+RETURNS NUMERIC LANGUAGE sql IMMUTABLE AS $$
+	SELECT CASE WHEN quantity <= 0 THEN 0 ELSE COALESCE(CASE unit
+		WHEN 'pallets' THEN CEIL(quantity::numeric / units_per_pallet)
+		WHEN 'cubic_metres' THEN quantity * volume
+		WHEN 'kg' THEN quantity * weight
+	END, 0) END
+$$;
+
+-- Used capacity is worked out afresh only for a warehouse whose stock on hand
+-- all has the measure its unit needs. No product has a volume or pallet size
+-- yet, so a warehouse holding stock keeps its hand-entered figure: stock
+-- moves shift it from there, and it is worked out afresh once its products'
+-- measures are saved. Any other warehouse's figure is reset, to 0 when it
+-- has no stock on hand. The migrate output warns how many warehouses kept
+-- their figure and how many were reset.
+DO $$
+DECLARE
+	kept_count INTEGER;
+	reset_count INTEGER;
+BEGIN
+	CREATE TEMP TABLE unmeasured_warehouses ON COMMIT DROP AS
+	SELECT DISTINCT i.warehouse_id AS id
+	FROM inventory_items i
+	JOIN warehouses w ON w.id = i.warehouse_id
+	JOIN products p ON p.id = i.product_id
+	WHERE i.quantity > 0 AND CASE w.capacity_unit
+		WHEN 'pallets' THEN p.units_per_pallet::numeric
+		WHEN 'cubic_metres' THEN p.volume
+		ELSE p.weight
+	END IS NULL;
+	SELECT COUNT(*) INTO kept_count FROM unmeasured_warehouses;
+
+	WITH fresh AS (
+		SELECT w.id, COALESCE((
+			SELECT SUM(stock_capacity_use(w.capacity_unit, i.quantity, p.units_per_pallet, p.volume, p.weight))
+			FROM inventory_items i JOIN products p ON p.id = i.product_id
+			WHERE i.warehouse_id = w.id
+		), 0) AS used
+		FROM warehouses w
+		WHERE w.id NOT IN (SELECT id FROM unmeasured_warehouses)
+	), changed AS (
+		UPDATE warehouses w SET used_capacity = f.used
+		FROM fresh f
+		WHERE w.id = f.id AND w.used_capacity IS DISTINCT FROM f.used
+		RETURNING 1
+	)
+	SELECT COUNT(*) INTO reset_count FROM changed;
+
+	IF kept_count > 0 THEN
+		RAISE WARNING '% warehouse(s) keep their hand-entered used capacity: their stock has no volume or pallet size; set the products'' measures to have it worked out from stock', kept_count;
+	END IF;
+	IF reset_count > 0 THEN
+		RAISE WARNING '% warehouse(s) had their used capacity reset to what their stock on hand takes up', reset_count;
+	END IF;
+END
+$$;
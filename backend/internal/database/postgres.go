@@ -3,8 +3,10 @@ package database
 import (
 	"context"
 	"fmt"
+	"log"
 	"time"
 
+	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
@@ -21,6 +23,17 @@ func Connect(databaseURL string) (*pgxpool.Pool, error) {
 	config.MaxConnIdleTime = 30 * time.Minute
 	config.HealthCheckPeriod = 1 * time.Minute
 
+	// Scope every checkout to the caller's tenant for row-level security.
+	config.BeforeAcquire = prepareConn
+
+	// Migrations raise warnings about data they could not carry over as it
+	// was; log them so they show up in the migrate output.
+	config.ConnConfig.OnNotice = func(_ *pgconn.PgConn, n *pgconn.Notice) {
+		if n.Severity == "WARNING" {
+			log.Printf("postgres warning: %s", n.Message)
+		}
+	}
+
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
@@ -0,0 +1,60 @@
+package database
+
+import (
+	"context"
+	"log"
+
+	"cargomax-api/internal/models"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+// AppRole is the unprivileged role every pooled connection assumes before it
+// is handed out. Row-level security policies on tenant tables (migration
+// 0003) only expose rows whose tenant_id matches the app.tenant_id session
+// variable, which is set from models.CtxTenantID on the acquiring context.
+// The login role keeps ownership of the tables and therefore bypasses RLS;
+// it is only restored for contexts marked with Privileged.
+const AppRole = "cargomax_app"
+
+type privilegedKey struct{}
+
+// Privileged marks ctx for the privileged connection path: connections
+// acquired with it run as the login role (bypassing RLS) with no tenant set.
+// Reserve it for legitimately cross-tenant work -- pre-auth lookups for login,
+// background worker scans, migrations and seeding -- and keep each use
+// as close to the single query that needs it as possible.
+func Privileged(ctx context.Context) context.Context {
+	return context.WithValue(ctx, privilegedKey{}, true)
+}
+
+// IsPrivileged reports whether ctx was marked with Privileged.
+func IsPrivileged(ctx context.Context) bool {
+	v, _ := ctx.Value(privilegedKey{}).(bool)
+	return v
+}
+
+// prepareConn is installed as the pool's BeforeAcquire hook. It resets the
+// connection's role and tenant on every checkout so that no state leaks
+// between requests that share a pooled connection. Contexts without a tenant
+// get an empty app.tenant_id and therefore see no tenant rows at all.
+func prepareConn(ctx context.Context, conn *pgx.Conn) bool {
+	role, tenant := AppRole, ""
+	if IsPrivileged(ctx) {
+		role = "none"
+	} else if tenantID, ok := ctx.Value(models.CtxTenantID).(uuid.UUID); ok {
+		tenant = tenantID.String()
+	}
+
+	_, err := conn.Exec(ctx,
+		`SELECT set_config('role', $1, false), set_config('app.tenant_id', $2, false)`,
+		role, tenant,
+	)
+	if err != nil {
+		// Returning false makes the pool discard this connection and try another.
+		log.Printf("database: failed to prepare pooled connection: %v", err)
+		return false
+	}
+	return true
+}
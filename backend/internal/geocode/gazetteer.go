@@ -0,0 +1,292 @@
+package geocode
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode"
+
+	"golang.org/x/text/runes"
+	"golang.org/x/text/transform"
+	"golang.org/x/text/unicode/norm"
+
+	"cargomax-api/internal/models"
+)
+
+// Gazetteer is an in-memory Geocoder over postcode and city centroids. An
+// address is placed by its postcode when the gazetteer knows it, otherwise by
+// its city. It is safe for concurrent use, and more data can be imported while
+// it serves lookups.
+//
+// The CSV it imports has a header row naming its columns, in any order:
+//
+//	country,postal_code,city,region,latitude,longitude
+//	DE,20457,Hamburg,Hamburg,53.5413,9.9841
+//	DE,,Berlin,Berlin,52.5200,13.4050
+//
+// country, latitude and longitude are required, and each row needs a
+// postal_code, a city or both. A row without a postal code is the city's own
+// centroid; otherwise a city is placed at the mean of its postcodes. region
+// is optional and tells apart cities that share a name.
+type Gazetteer struct {
+	mu        sync.RWMutex
+	postcodes map[string]place
+	// cities is keyed by country, region and city. Every row is also
+	// counted under an empty region so a city can be found without one.
+	cities map[string]*cityEntry
+	// regions holds the regions seen for each country and city, so a city
+	// name that exists in several of them is not guessed at.
+	regions   map[string]map[string]bool
+	countries map[string]bool
+}
+
+type place struct {
+	lat, lng float64
+}
+
+type cityEntry struct {
+	centroid       *place
+	sumLat, sumLng float64
+	n              int
+}
+
+func (c *cityEntry) place() place {
+	if c.centroid != nil {
+		return *c.centroid
+	}
+	return place{c.sumLat / float64(c.n), c.sumLng / float64(c.n)}
+}
+
+// NewGazetteer returns an empty gazetteer.
+func NewGazetteer() *Gazetteer {
+	return &Gazetteer{
+		postcodes: make(map[string]place),
+		cities:    make(map[string]*cityEntry),
+		regions:   make(map[string]map[string]bool),
+		countries: make(map[string]bool),
+	}
+}
+
+// LoadGazetteer returns a gazetteer holding the CSV file at path.
+func LoadGazetteer(path string) (*Gazetteer, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open gazetteer: %w", err)
+	}
+	defer f.Close()
+
+	g := NewGazetteer()
+	if _, err := g.Import(f); err != nil {
+		return nil, err
+	}
+	return g, nil
+}
+
+type gazetteerRow struct {
+	country, postcode, city, region string
+	p                               place
+}
+
+// Import adds the rows of a gazetteer CSV and returns how many it read. A
+// postcode already present is moved to the new position. The file is checked
+// in full first, so a bad row imports nothing.
+func (g *Gazetteer) Import(r io.Reader) (int, error) {
+	cr := csv.NewReader(r)
+	cr.TrimLeadingSpace = true
+	header, err := cr.Read()
+	if err != nil {
+		if errors.Is(err, io.EOF) {
+			return 0, fmt.Errorf("gazetteer is empty")
+		}
+		return 0, fmt.Errorf("failed to read gazetteer header: %w", err)
+	}
+	col := map[string]int{}
+	for i, name := range header {
+		col[strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff")))] = i
+	}
+	for _, name := range []string{"country", "latitude", "longitude"} {
+		if _, ok := col[name]; !ok {
+			return 0, fmt.Errorf("gazetteer has no %s column", name)
+		}
+	}
+	_, hasPostcode := col["postal_code"]
+	_, hasCity := col["city"]
+	if !hasPostcode && !hasCity {
+		return 0, fmt.Errorf("gazetteer needs a postal_code or city column")
+	}
+	field := func(rec []string, name string) string {
+		if i, ok := col[name]; ok && i < len(rec) {
+			return strings.TrimSpace(rec[i])
+		}
+		return ""
+	}
+
+	var rows []gazetteerRow
+	for {
+		rec, err := cr.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return 0, fmt.Errorf("failed to read gazetteer: %w", err)
+		}
+		line, _ := cr.FieldPos(0)
+		row := gazetteerRow{
+			country:  strings.ToUpper(field(rec, "country")),
+			postcode: postcodeKey(field(rec, "postal_code")),
+			city:     nameKey(field(rec, "city")),
+			region:   nameKey(field(rec, "region")),
+		}
+		if len(row.country) != 2 {
+			return 0, fmt.Errorf("gazetteer line %d: country must be a two-letter ISO code", line)
+		}
+		if row.postcode == "" && row.city == "" {
+			return 0, fmt.Errorf("gazetteer line %d: needs a postal_code or a city", line)
+		}
+		lat, err1 := strconv.ParseFloat(field(rec, "latitude"), 64)
+		lng, err2 := strconv.ParseFloat(field(rec, "longitude"), 64)
+		if err1 != nil || err2 != nil || lat < -90 || lat > 90 || lng < -180 || lng > 180 {
+			return 0, fmt.Errorf("gazetteer line %d: invalid coordinates", line)
+		}
+		row.p = place{lat, lng}
+		rows = append(rows, row)
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	for _, row := range rows {
+		g.countries[row.country] = true
+		if row.postcode != "" {
+			g.postcodes[row.country+"|"+row.postcode] = row.p
+		}
+		if row.city == "" {
+			continue
+		}
+		keys := []string{row.country + "||" + row.city}
+		if row.region != "" {
+			cityKey := row.country + "|" + row.city
+			if g.regions[cityKey] == nil {
+				g.regions[cityKey] = make(map[string]bool)
+			}
+			g.regions[cityKey][row.region] = true
+			keys = append(keys, row.country+"|"+row.region+"|"+row.city)
+		}
+		for _, key := range keys {
+			e := g.cities[key]
+			if e == nil {
+				e = &cityEntry{}
+				g.cities[key] = e
+			}
+			if row.postcode == "" {
+				p := row.p
+				e.centroid = &p
+			}
+			e.sumLat += row.p.lat
+			e.sumLng += row.p.lng
+			e.n++
+		}
+	}
+	return len(rows), nil
+}
+
+// Len returns the number of postcodes the gazetteer knows.
+func (g *Gazetteer) Len() int {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	return len(g.postcodes)
+}
+
+// Geocode places a by postcode, then by city. Without a country the address
+// is matched in every country imported and placed only if exactly one
+// matches.
+func (g *Gazetteer) Geocode(_ context.Context, a models.Address) (*Result, error) {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+
+	countries := []string{strings.ToUpper(strings.TrimSpace(a.Country))}
+	if countries[0] == "" {
+		countries = countries[:0]
+		for c := range g.countries {
+			countries = append(countries, c)
+		}
+	}
+
+	var found *Result
+	for _, country := range countries {
+		res, ok := g.lookup(country, a)
+		if !ok {
+			continue
+		}
+		if found != nil {
+			return nil, ErrNotFound
+		}
+		found = res
+	}
+	if found == nil {
+		return nil, ErrNotFound
+	}
+	return found, nil
+}
+
+func (g *Gazetteer) lookup(country string, a models.Address) (*Result, bool) {
+	if pc := postcodeKey(a.PostalCode); pc != "" {
+		p, ok := g.postcodes[country+"|"+pc]
+		if !ok {
+			// Postcodes such as the UK's are often listed by their
+			// outward part alone.
+			if outward, _, cut := strings.Cut(strings.TrimSpace(a.PostalCode), " "); cut {
+				p, ok = g.postcodes[country+"|"+postcodeKey(outward)]
+			}
+		}
+		if ok {
+			return &Result{Latitude: p.lat, Longitude: p.lng, Precision: PrecisionPostcode}, true
+		}
+	}
+
+	city := nameKey(a.City)
+	if city == "" {
+		return nil, false
+	}
+	if region := nameKey(a.Region); region != "" {
+		if e, ok := g.cities[country+"|"+region+"|"+city]; ok {
+			p := e.place()
+			return &Result{Latitude: p.lat, Longitude: p.lng, Precision: PrecisionCity}, true
+		}
+	}
+	if len(g.regions[country+"|"+city]) > 1 {
+		return nil, false
+	}
+	e, ok := g.cities[country+"||"+city]
+	if !ok {
+		return nil, false
+	}
+	p := e.place()
+	return &Result{Latitude: p.lat, Longitude: p.lng, Precision: PrecisionCity}, true
+}
+
+// postcodeKey upper-cases a postcode and drops spaces and hyphens, so
+// "sw1a 1aa" and "SW1A1AA" match.
+func postcodeKey(s string) string {
+	return strings.Map(func(r rune) rune {
+		if r == ' ' || r == '-' || r == '\t' {
+			return -1
+		}
+		return r
+	}, strings.ToUpper(s))
+}
+
+// nameKey folds case, accents and runs of white space in a place name, so
+// "München", "MUNCHEN" and "munchen " match.
+func nameKey(s string) string {
+	t := transform.Chain(norm.NFD, runes.Remove(runes.In(unicode.Mn)), norm.NFC)
+	if folded, _, err := transform.String(t, s); err == nil {
+		s = folded
+	}
+	return strings.ToLower(strings.Join(strings.Fields(s), " "))
+}
@@ -0,0 +1,154 @@
+package geocode
+
+import (
+	"context"
+	"errors"
+	"math"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"cargomax-api/internal/models"
+)
+
+// testdata/gazetteer.csv has Hamburg by two postcodes and Berlin by its own
+// centroid and a postcode, accented names, a Springfield in two US states, a
+// Paris in two countries and a UK outward postcode.
+func loadFixture(t *testing.T) *Gazetteer {
+	t.Helper()
+	g, err := LoadGazetteer(filepath.Join("testdata", "gazetteer.csv"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return g
+}
+
+func TestGazetteerLoad(t *testing.T) {
+	if got := loadFixture(t).Len(); got != 10 {
+		t.Errorf("Len() = %d, want 10", got)
+	}
+	if _, err := LoadGazetteer(filepath.Join("testdata", "missing.csv")); err == nil {
+		t.Errorf("LoadGazetteer of a missing file: no error")
+	}
+}
+
+func TestGazetteerGeocode(t *testing.T) {
+	g := loadFixture(t)
+	for _, tc := range []struct {
+		name      string
+		addr      models.Address
+		lat, lng  float64
+		precision string
+	}{
+		{"postcode", models.Address{Country: "DE", PostalCode: "20457"}, 53.5413, 9.9841, PrecisionPostcode},
+		{"postcode before city", models.Address{Country: "DE", PostalCode: "10115", City: "Berlin"}, 52.5323, 13.3846, PrecisionPostcode},
+		{"postcode spacing and country case", models.Address{Country: " de ", PostalCode: " 20-457 "}, 53.5413, 9.9841, PrecisionPostcode},
+		{"outward postcode", models.Address{Country: "GB", PostalCode: "sw1a 1aa"}, 51.5014, -0.1419, PrecisionPostcode},
+		{"unknown postcode falls back to the city", models.Address{Country: "DE", PostalCode: "99999", City: "Hamburg"}, 53.5458, 9.99235, PrecisionCity},
+		{"city's own centroid", models.Address{Country: "DE", City: "Berlin"}, 52.52, 13.405, PrecisionCity},
+		{"city case and spacing", models.Address{Country: "DE", City: "  FRANKFURT   am  main "}, 50.1109, 8.6821, PrecisionCity},
+		{"accented city", models.Address{Country: "DE", City: "München"}, 48.1374, 11.5755, PrecisionCity},
+		{"city without its accent", models.Address{Country: "DE", City: "MUNCHEN"}, 48.1374, 11.5755, PrecisionCity},
+		{"accented region", models.Address{Country: "FR", City: "paris", Region: "ile-de-france"}, 48.8606, 2.3376, PrecisionCity},
+		{"region tells cities apart", models.Address{Country: "US", City: "Springfield", Region: "missouri"}, 37.209, -93.2923, PrecisionCity},
+		{"unknown region ignored", models.Address{Country: "DE", City: "Hamburg", Region: "Holstein"}, 53.5458, 9.99235, PrecisionCity},
+		{"no country, one match", models.Address{City: "munchen"}, 48.1374, 11.5755, PrecisionCity},
+	} {
+		res, err := g.Geocode(context.Background(), tc.addr)
+		if err != nil {
+			t.Errorf("%s: %v", tc.name, err)
+			continue
+		}
+		if !near(res.Latitude, tc.lat) || !near(res.Longitude, tc.lng) || res.Precision != tc.precision {
+			t.Errorf("%s: %g,%g by %s, want %g,%g by %s", tc.name, res.Latitude, res.Longitude, res.Precision, tc.lat, tc.lng, tc.precision)
+		}
+	}
+}
+
+func TestGazetteerNotFound(t *testing.T) {
+	g := loadFixture(t)
+	for _, tc := range []struct {
+		name string
+		addr models.Address
+	}{
+		{"empty address", models.Address{}},
+		{"unknown city", models.Address{Country: "DE", City: "Atlantis"}},
+		{"unknown postcode, no city", models.Address{Country: "DE", PostalCode: "99999"}},
+		{"unknown country", models.Address{Country: "XX", City: "Berlin"}},
+		{"city in another country", models.Address{Country: "FR", City: "Berlin"}},
+		{"city in two regions", models.Address{Country: "US", City: "Springfield"}},
+		{"city in two countries", models.Address{City: "Paris"}},
+		{"similar name", models.Address{Country: "DE", City: "Frankfurt"}},
+	} {
+		if res, err := g.Geocode(context.Background(), tc.addr); !errors.Is(err, ErrNotFound) {
+			t.Errorf("%s: %+v, %v, want ErrNotFound", tc.name, res, err)
+		}
+	}
+}
+
+func TestGazetteerImport(t *testing.T) {
+	g := loadFixture(t)
+	// Columns in another order, a byte order mark and spaced fields; the
+	// second row moves a postcode already known.
+	n, err := g.Import(strings.NewReader("\ufeffLatitude, Longitude, Country, Postal_Code, City\n" +
+		"50.9375, 6.9603, de, 50667, Köln\n" +
+		"53.55, 9.99, DE, 20457, Hamburg\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 2 || g.Len() != 11 {
+		t.Errorf("imported %d rows to %d postcodes, want 2 to 11", n, g.Len())
+	}
+	res, err := g.Geocode(context.Background(), models.Address{Country: "DE", City: "koln"})
+	if err != nil || !near(res.Latitude, 50.9375) {
+		t.Errorf("Koln: %+v, %v, want 50.9375", res, err)
+	}
+	res, err = g.Geocode(context.Background(), models.Address{Country: "DE", PostalCode: "20457"})
+	if err != nil || !near(res.Latitude, 53.55) || !near(res.Longitude, 9.99) {
+		t.Errorf("moved postcode: %+v, %v, want 53.55,9.99", res, err)
+	}
+}
+
+func TestGazetteerImportRejects(t *testing.T) {
+	const header = "country,postal_code,city,region,latitude,longitude\n"
+	for _, tc := range []struct {
+		name, data string
+	}{
+		{"empty file", ""},
+		{"no latitude column", "country,postal_code,longitude\nDE,10115,13.4\n"},
+		{"no postcode or city column", "country,latitude,longitude\nDE,52.5,13.4\n"},
+		{"three-letter country", header + "DEU,10115,Berlin,,52.5,13.4\n"},
+		{"missing country", header + ",10115,Berlin,,52.5,13.4\n"},
+		{"no postcode or city", header + "DE,,,Berlin,52.5,13.4\n"},
+		{"latitude not a number", header + "DE,10115,Berlin,,north,13.4\n"},
+		{"latitude out of range", header + "DE,10115,Berlin,,91,13.4\n"},
+		{"longitude out of range", header + "DE,10115,Berlin,,52.5,-181\n"},
+		{"missing longitude", header + "DE,10115,Berlin,,52.5,\n"},
+		{"short row", header + "DE,10115,Berlin\n"},
+		{"unclosed quote", header + "DE,10115,\"Berlin,,52.5,13.4\n"},
+	} {
+		if n, err := NewGazetteer().Import(strings.NewReader(tc.data)); err == nil {
+			t.Errorf("%s: imported %d rows", tc.name, n)
+		}
+	}
+
+	// A bad row further down imports nothing, not even the good rows
+	// before it.
+	g := loadFixture(t)
+	_, err := g.Import(strings.NewReader(header +
+		"DE,50667,Köln,,50.9375,6.9603\n" +
+		"DE,01067,Dresden,,51.05,north\n"))
+	if err == nil || !strings.Contains(err.Error(), "line 3") {
+		t.Errorf("bad third line: err %v, want a line 3 error", err)
+	}
+	if g.Len() != 10 {
+		t.Errorf("Len() = %d after a failed import, want 10", g.Len())
+	}
+	if _, err := g.Geocode(context.Background(), models.Address{Country: "DE", City: "Köln"}); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Köln from a failed import: err %v, want ErrNotFound", err)
+	}
+}
+
+func near(a, b float64) bool {
+	return math.Abs(a-b) < 1e-9
+}
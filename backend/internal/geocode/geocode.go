@@ -0,0 +1,34 @@
+// Package geocode turns postal addresses into coordinates. The Geocoder
+// interface lets a hosted service be swapped in later; Gazetteer is the
+// offline implementation, answering from postcode and city centroids
+// imported from CSV.
+package geocode
+
+import (
+	"context"
+	"errors"
+
+	"cargomax-api/internal/models"
+)
+
+// ErrNotFound is returned when an address cannot be placed.
+var ErrNotFound = errors.New("address not found")
+
+// Precision says how closely a result locates an address.
+const (
+	PrecisionPostcode = "postcode"
+	PrecisionCity     = "city"
+)
+
+// Result is the position found for an address.
+type Result struct {
+	Latitude  float64
+	Longitude float64
+	Precision string
+}
+
+// Geocoder places an address on the map.
+type Geocoder interface {
+	// Geocode returns the position of a, or ErrNotFound.
+	Geocode(ctx context.Context, a models.Address) (*Result, error)
+}
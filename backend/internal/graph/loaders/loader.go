@@ -0,0 +1,97 @@
+// Package loaders provides request-scoped batch loaders for GraphQL field
+// resolvers, so that resolving a relationship on every item of a list costs
+// one query instead of one per item.
+package loaders
+
+import (
+	"context"
+	"sync"
+
+	"github.com/google/uuid"
+)
+
+// FetchFunc loads every entity in ids for a tenant. Ids without a matching row
+// are simply absent from the returned map.
+type FetchFunc[T any] func(ctx context.Context, tenantID uuid.UUID, ids []uuid.UUID) (map[uuid.UUID]*T, error)
+
+type key struct {
+	tenantID uuid.UUID
+	id       uuid.UUID
+}
+
+type result[T any] struct {
+	value *T
+	err   error
+}
+
+// Loader batches and caches lookups by id. Load only queues the key and
+// returns a thunk; graphql-go resolves thunks breadth-first once the whole
+// level of the response has been visited, so the first thunk to run fetches
+// every key queued so far in a single query and the rest hit the cache.
+type Loader[T any] struct {
+	fetch FetchFunc[T]
+
+	mu      sync.Mutex
+	pending map[uuid.UUID][]uuid.UUID // tenant -> queued ids
+	done    map[key]result[T]
+}
+
+// NewLoader creates a Loader backed by fetch.
+func NewLoader[T any](fetch FetchFunc[T]) *Loader[T] {
+	return &Loader[T]{
+		fetch:   fetch,
+		pending: map[uuid.UUID][]uuid.UUID{},
+		done:    map[key]result[T]{},
+	}
+}
+
+// Load queues id and returns a graphql-go thunk resolving to the entity, or
+// to null when it does not exist in the tenant.
+func (l *Loader[T]) Load(ctx context.Context, tenantID, id uuid.UUID) func() (interface{}, error) {
+	l.enqueue(tenantID, id)
+	return func() (interface{}, error) {
+		v, err := l.Get(ctx, tenantID, id)
+		if err != nil || v == nil {
+			return nil, err
+		}
+		return v, nil
+	}
+}
+
+// Get returns the entity immediately, dispatching the pending batch for the
+// tenant if id has not been fetched yet.
+func (l *Loader[T]) Get(ctx context.Context, tenantID, id uuid.UUID) (*T, error) {
+	l.enqueue(tenantID, id)
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	k := key{tenantID, id}
+	if r, ok := l.done[k]; ok {
+		return r.value, r.err
+	}
+
+	ids := l.pending[tenantID]
+	delete(l.pending, tenantID)
+	found, err := l.fetch(ctx, tenantID, ids)
+	for _, queued := range ids {
+		l.done[key{tenantID, queued}] = result[T]{value: found[queued], err: err}
+	}
+	r := l.done[k]
+	return r.value, r.err
+}
+
+func (l *Loader[T]) enqueue(tenantID, id uuid.UUID) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if _, ok := l.done[key{tenantID, id}]; ok {
+		return
+	}
+	for _, queued := range l.pending[tenantID] {
+		if queued == id {
+			return
+		}
+	}
+	l.pending[tenantID] = append(l.pending[tenantID], id)
+}
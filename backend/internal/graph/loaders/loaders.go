@@ -0,0 +1,87 @@
+package loaders
+
+import (
+	"context"
+
+	"cargomax-api/internal/models"
+	"cargomax-api/internal/repository"
+
+	"github.com/google/uuid"
+)
+
+// Loaders holds one Loader per entity type reachable through a nested
+// GraphQL field. A fresh set is created for every request so that cached
+// rows never outlive it.
+type Loaders struct {
+	Shipments           *Loader[models.Shipment]
+	ShipmentEvents      *Loader[[]models.ShipmentEvent]      // keyed by shipment ID
+	ShipmentAssignments *Loader[[]models.ShipmentAssignment] // keyed by shipment ID
+	ShipmentETAs        *Loader[[]models.ShipmentETA]        // keyed by shipment ID
+	ProofsOfDelivery    *Loader[models.ProofOfDelivery]      // keyed by shipment ID
+	Vehicles            *Loader[models.Vehicle]
+	Warehouses          *Loader[models.Warehouse]
+}
+
+// New creates a request's loaders on top of the repositories' GetByIDs queries.
+func New(shipmentRepo *repository.ShipmentRepo, vehicleRepo *repository.VehicleRepo, warehouseRepo *repository.WarehouseRepo) *Loaders {
+	return &Loaders{
+		Shipments: NewLoader(func(ctx context.Context, tenantID uuid.UUID, ids []uuid.UUID) (map[uuid.UUID]*models.Shipment, error) {
+			rows, err := shipmentRepo.GetByIDs(ctx, tenantID, ids)
+			return index(rows, func(s *models.Shipment) uuid.UUID { return s.ID }), err
+		}),
+		ShipmentEvents: NewLoader(func(ctx context.Context, tenantID uuid.UUID, ids []uuid.UUID) (map[uuid.UUID]*[]models.ShipmentEvent, error) {
+			byShipment, err := shipmentRepo.ListEventsByShipments(ctx, tenantID, ids)
+			return indexGroups(byShipment), err
+		}),
+		ShipmentAssignments: NewLoader(func(ctx context.Context, tenantID uuid.UUID, ids []uuid.UUID) (map[uuid.UUID]*[]models.ShipmentAssignment, error) {
+			byShipment, err := shipmentRepo.ListAssignmentsByShipments(ctx, tenantID, ids)
+			return indexGroups(byShipment), err
+		}),
+		ShipmentETAs: NewLoader(func(ctx context.Context, tenantID uuid.UUID, ids []uuid.UUID) (map[uuid.UUID]*[]models.ShipmentETA, error) {
+			byShipment, err := shipmentRepo.ListETAsByShipments(ctx, tenantID, ids)
+			return indexGroups(byShipment), err
+		}),
+		ProofsOfDelivery: NewLoader(func(ctx context.Context, tenantID uuid.UUID, ids []uuid.UUID) (map[uuid.UUID]*models.ProofOfDelivery, error) {
+			rows, err := shipmentRepo.ListProofsByShipments(ctx, tenantID, ids)
+			return index(rows, func(p *models.ProofOfDelivery) uuid.UUID { return p.ShipmentID }), err
+		}),
+		Vehicles: NewLoader(func(ctx context.Context, tenantID uuid.UUID, ids []uuid.UUID) (map[uuid.UUID]*models.Vehicle, error) {
+			rows, err := vehicleRepo.GetByIDs(ctx, tenantID, ids)
+			return index(rows, func(v *models.Vehicle) uuid.UUID { return v.ID }), err
+		}),
+		Warehouses: NewLoader(func(ctx context.Context, tenantID uuid.UUID, ids []uuid.UUID) (map[uuid.UUID]*models.Warehouse, error) {
+			rows, err := warehouseRepo.GetByIDs(ctx, tenantID, ids)
+			return index(rows, func(w *models.Warehouse) uuid.UUID { return w.ID }), err
+		}),
+	}
+}
+
+func index[T any](rows []T, id func(*T) uuid.UUID) map[uuid.UUID]*T {
+	m := make(map[uuid.UUID]*T, len(rows))
+	for i := range rows {
+		m[id(&rows[i])] = &rows[i]
+	}
+	return m
+}
+
+func indexGroups[T any](groups map[uuid.UUID][]T) map[uuid.UUID]*[]T {
+	m := make(map[uuid.UUID]*[]T, len(groups))
+	for id, rows := range groups {
+		rows := rows
+		m[id] = &rows
+	}
+	return m
+}
+
+type ctxKey struct{}
+
+// WithLoaders returns ctx carrying l.
+func WithLoaders(ctx context.Context, l *Loaders) context.Context {
+	return context.WithValue(ctx, ctxKey{}, l)
+}
+
+// FromContext returns the loaders attached to ctx, or nil.
+func FromContext(ctx context.Context) *Loaders {
+	l, _ := ctx.Value(ctxKey{}).(*Loaders)
+	return l
+}
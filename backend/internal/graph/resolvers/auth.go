@@ -1,6 +1,7 @@
 package resolvers
 
 import (
+	"context"
 	"crypto/rand"
 	"encoding/hex"
 	"fmt"
@@ -79,6 +80,10 @@ func (r *Resolver) AuthMutations() graphql.Fields {
 				}
 				verifyToken := hex.EncodeToString(verifyBytes)
 
+				// The request has no tenant yet; scope the remaining writes to the
+				// new tenant so they pass row-level security.
+				ctx := context.WithValue(p.Context, models.CtxTenantID, tenant.ID)
+
 				// Create user (UserRepo.Create hashes the plain password internally).
 				user := &models.User{
 					TenantID:         tenant.ID,
@@ -89,7 +94,7 @@ func (r *Resolver) AuthMutations() graphql.Fields {
 					EmailVerified:    false,
 					EmailVerifyToken: &verifyToken,
 				}
-				if err := r.UserRepo.Create(p.Context, user, password); err != nil {
+				if err := r.UserRepo.Create(ctx, user, password); err != nil {
 					return nil, fmt.Errorf("failed to create user: %w", err)
 				}
 
@@ -196,7 +201,8 @@ func (r *Resolver) AuthMutations() graphql.Fields {
 					return false, fmt.Errorf("invalid or expired verification token")
 				}
 
-				if err := r.UserRepo.UpdateEmailVerified(p.Context, user.TenantID, user.ID, true); err != nil {
+				ctx := context.WithValue(p.Context, models.CtxTenantID, user.TenantID)
+				if err := r.UserRepo.UpdateEmailVerified(ctx, user.TenantID, user.ID, true); err != nil {
 					return false, fmt.Errorf("failed to verify email: %w", err)
 				}
 
@@ -236,8 +242,10 @@ func (r *Resolver) AuthMutations() graphql.Fields {
 					return nil, fmt.Errorf("invalid token type: expected refresh token")
 				}
 
-				// Fetch fresh user to get current role/email.
-				user, err := r.UserRepo.GetByID(p.Context, claims.TenantID, claims.UserID)
+				// Fetch fresh user to get current role/email. The access token may
+				// have expired, so the tenant comes from the refresh token.
+				ctx := context.WithValue(p.Context, models.CtxTenantID, claims.TenantID)
+				user, err := r.UserRepo.GetByID(ctx, claims.TenantID, claims.UserID)
 				if err != nil {
 					return nil, fmt.Errorf("user not found")
 				}
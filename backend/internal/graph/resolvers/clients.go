@@ -165,10 +165,25 @@ func (r *Resolver) ClientMutations() graphql.Fields {
 				if v, ok := input["status"].(string); ok {
 					c.Status = v
 				}
+				if v, ok := input["latitude"].(float64); ok {
+					c.Latitude = &v
+				}
+				if v, ok := input["longitude"].(float64); ok {
+					c.Longitude = &v
+				}
+				if err := r.setClientAddress(p.Context, c, input); err != nil {
+					return nil, err
+				}
+				if err := checkLocation(c.Latitude, c.Longitude); err != nil {
+					return nil, err
+				}
 
 				if err := r.ClientRepo.Create(p.Context, c); err != nil {
 					return nil, err
 				}
+				if err := r.ZoneRepo.SyncClientZone(p.Context, tenantID, c.ID); err != nil {
+					return nil, err
+				}
 				return c, nil
 			},
 		},
@@ -219,10 +234,25 @@ func (r *Resolver) ClientMutations() graphql.Fields {
 				if v, ok := input["status"].(string); ok {
 					c.Status = v
 				}
+				if v, ok := input["latitude"].(float64); ok {
+					c.Latitude = &v
+				}
+				if v, ok := input["longitude"].(float64); ok {
+					c.Longitude = &v
+				}
+				if err := r.setClientAddress(p.Context, c, input); err != nil {
+					return nil, err
+				}
+				if err := checkLocation(c.Latitude, c.Longitude); err != nil {
+					return nil, err
+				}
 
 				if err := r.ClientRepo.Update(p.Context, tenantID, id, c); err != nil {
 					return nil, err
 				}
+				if err := r.ZoneRepo.SyncClientZone(p.Context, tenantID, id); err != nil {
+					return nil, err
+				}
 				return r.ClientRepo.GetByID(p.Context, tenantID, id)
 			},
 		},
@@ -102,11 +102,12 @@ func (r *Resolver) DashboardQueries() graphql.Fields {
 					fleetUtil = float64(stats.ActiveVehicles) / float64(stats.TotalVehicles) * 100.0
 				}
 
-				// Warehouse utilization: (total inventory - low stock) / total inventory.
-				var warehouseUtil float64
-				if stats.TotalInventory > 0 {
-					warehouseUtil = float64(stats.TotalInventory-stats.LowStockItems) / float64(stats.TotalInventory) * 100.0
+				// Warehouse utilization: the mean fill of the active warehouses.
+				capacity, err := r.WarehouseRepo.GetCapacityStats(p.Context, tenantID)
+				if err != nil {
+					return nil, fmt.Errorf("failed to fetch performance metrics: %w", err)
 				}
+				warehouseUtil := capacity.Utilization
 
 				// Order fulfillment rate: (total orders - pending) / total orders.
 				var fulfillmentRate float64
@@ -0,0 +1,1063 @@
+package resolvers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"cargomax-api/internal/graph/types"
+	"cargomax-api/internal/models"
+	"cargomax-api/internal/repository"
+	"cargomax-api/internal/routing"
+
+	"github.com/google/uuid"
+	"github.com/graphql-go/graphql"
+)
+
+// DispatchQueries returns the GraphQL query fields for the dispatch board.
+func (r *Resolver) DispatchQueries() graphql.Fields {
+	return graphql.Fields{
+		"routePlans": &graphql.Field{
+			Type:        graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(types.RoutePlanType))),
+			Description: "The route plans of a day (YYYY-MM-DD), optionally only one driver's, in planned start order.",
+			Args: graphql.FieldConfigArgument{
+				"date":     &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.String)},
+				"driverId": &graphql.ArgumentConfig{Type: graphql.String},
+			},
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				tenantID, err := requireTenant(p.Context)
+				if err != nil {
+					return nil, err
+				}
+				date := p.Args["date"].(string)
+				if _, err := time.Parse(models.RouteDateLayout, date); err != nil {
+					return nil, fmt.Errorf("invalid date, want YYYY-MM-DD: %w", err)
+				}
+				var driverID *uuid.UUID
+				if v, ok := p.Args["driverId"].(string); ok && v != "" {
+					id, err := uuid.Parse(v)
+					if err != nil {
+						return nil, fmt.Errorf("invalid driver id: %w", err)
+					}
+					driverID = &id
+				}
+
+				plans, err := r.RouteRepo.ListByDate(p.Context, tenantID, date, driverID)
+				if err != nil {
+					return nil, fmt.Errorf("failed to list route plans: %w", err)
+				}
+				return routePlanList(plans), nil
+			},
+		},
+
+		"routePlan": &graphql.Field{
+			Type:        types.RoutePlanType,
+			Description: "Get a single route plan by ID.",
+			Args: graphql.FieldConfigArgument{
+				"id": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.String)},
+			},
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				tenantID, err := requireTenant(p.Context)
+				if err != nil {
+					return nil, err
+				}
+				id, err := uuid.Parse(p.Args["id"].(string))
+				if err != nil {
+					return nil, fmt.Errorf("invalid route plan id: %w", err)
+				}
+				plan, err := r.RouteRepo.GetByID(p.Context, tenantID, id)
+				if errors.Is(err, repository.ErrRouteNotFound) {
+					return nil, nil
+				}
+				if err != nil {
+					return nil, fmt.Errorf("failed to get route plan: %w", err)
+				}
+				return routePlanOut(plan), nil
+			},
+		},
+	}
+}
+
+// DispatchMutations returns the GraphQL mutation fields for building,
+// reordering and assigning route plans.
+func (r *Resolver) DispatchMutations() graphql.Fields {
+	return graphql.Fields{
+		// -----------------------------------------------------------------
+		// createRoutePlan
+		// -----------------------------------------------------------------
+		"createRoutePlan": &graphql.Field{
+			Type:        types.RoutePlanType,
+			Description: "Create a draft route plan for a day, with its stops in visiting order.",
+			Args: graphql.FieldConfigArgument{
+				"input": &graphql.ArgumentConfig{Type: graphql.NewNonNull(types.RoutePlanInputType)},
+			},
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				tenantID, userID, err := requireAuth(p.Context)
+				if err != nil {
+					return nil, err
+				}
+				input := p.Args["input"].(map[string]interface{})
+				if v, _ := input["planDate"].(string); v == "" {
+					return nil, fmt.Errorf("planDate is required")
+				}
+				if v, _ := input["name"].(string); strings.TrimSpace(v) == "" {
+					return nil, fmt.Errorf("name is required")
+				}
+				if v, _ := input["plannedStart"].(string); v == "" {
+					return nil, fmt.Errorf("plannedStart is required")
+				}
+
+				plan := &models.RoutePlan{TenantID: tenantID, CreatedBy: &userID}
+				if err := r.applyRoutePlanInput(p.Context, tenantID, plan, input); err != nil {
+					return nil, err
+				}
+				stops, _ := input["stops"].([]interface{})
+				for i, v := range stops {
+					stop := models.RouteStop{}
+					if err := r.applyRouteStopInput(p.Context, tenantID, &stop, v.(map[string]interface{})); err != nil {
+						return nil, fmt.Errorf("stop %d: %w", i+1, err)
+					}
+					plan.Stops = append(plan.Stops, stop)
+				}
+
+				if err := r.RouteRepo.Create(p.Context, plan); err != nil {
+					return nil, fmt.Errorf("failed to create route plan: %w", err)
+				}
+				return routePlanOut(plan), nil
+			},
+		},
+
+		// -----------------------------------------------------------------
+		// updateRoutePlan
+		// -----------------------------------------------------------------
+		"updateRoutePlan": &graphql.Field{
+			Type:        types.RoutePlanType,
+			Description: "Update a route plan's date, name, warehouse, start or notes. Use the stop mutations to change its stops.",
+			Args: graphql.FieldConfigArgument{
+				"id":    &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.String)},
+				"input": &graphql.ArgumentConfig{Type: graphql.NewNonNull(types.RoutePlanInputType)},
+			},
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				tenantID, err := requireTenant(p.Context)
+				if err != nil {
+					return nil, err
+				}
+				plan, err := r.editableRoutePlan(p.Context, tenantID, p.Args["id"].(string))
+				if err != nil {
+					return nil, err
+				}
+				input := p.Args["input"].(map[string]interface{})
+				if _, ok := input["stops"]; ok {
+					return nil, fmt.Errorf("stops cannot be replaced here; use addRouteStop, updateRouteStop, removeRouteStop or reorderRouteStops")
+				}
+				if err := r.applyRoutePlanInput(p.Context, tenantID, plan, input); err != nil {
+					return nil, err
+				}
+				return r.saveRoutePlan(p.Context, tenantID, plan)
+			},
+		},
+
+		// -----------------------------------------------------------------
+		// deleteRoutePlan
+		// -----------------------------------------------------------------
+		"deleteRoutePlan": &graphql.Field{
+			Type:        graphql.Boolean,
+			Description: "Delete a draft route plan. Plans that were assigned are kept.",
+			Args: graphql.FieldConfigArgument{
+				"id": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.String)},
+			},
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				tenantID, err := requireTenant(p.Context)
+				if err != nil {
+					return nil, err
+				}
+				id, err := uuid.Parse(p.Args["id"].(string))
+				if err != nil {
+					return false, fmt.Errorf("invalid route plan id: %w", err)
+				}
+				if err := r.RouteRepo.Delete(p.Context, tenantID, id); err != nil {
+					return false, fmt.Errorf("failed to delete route plan: %w", err)
+				}
+				return true, nil
+			},
+		},
+
+		// -----------------------------------------------------------------
+		// addRouteStop
+		// -----------------------------------------------------------------
+		"addRouteStop": &graphql.Field{
+			Type:        types.RoutePlanType,
+			Description: "Add a stop to a route plan at position (1-based), or at the end.",
+			Args: graphql.FieldConfigArgument{
+				"routeId":  &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.String)},
+				"input":    &graphql.ArgumentConfig{Type: graphql.NewNonNull(types.RouteStopInputType)},
+				"position": &graphql.ArgumentConfig{Type: graphql.Int},
+			},
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				tenantID, err := requireTenant(p.Context)
+				if err != nil {
+					return nil, err
+				}
+				plan, err := r.editableRoutePlan(p.Context, tenantID, p.Args["routeId"].(string))
+				if err != nil {
+					return nil, err
+				}
+				stop := models.RouteStop{}
+				if err := r.applyRouteStopInput(p.Context, tenantID, &stop, p.Args["input"].(map[string]interface{})); err != nil {
+					return nil, err
+				}
+
+				at := len(plan.Stops)
+				if v, ok := p.Args["position"].(int); ok {
+					if v < 1 || v > len(plan.Stops)+1 {
+						return nil, fmt.Errorf("position must be between 1 and %d", len(plan.Stops)+1)
+					}
+					at = v - 1
+				}
+				plan.Stops = append(plan.Stops[:at], append([]models.RouteStop{stop}, plan.Stops[at:]...)...)
+				return r.saveRoutePlan(p.Context, tenantID, plan)
+			},
+		},
+
+		// -----------------------------------------------------------------
+		// updateRouteStop
+		// -----------------------------------------------------------------
+		"updateRouteStop": &graphql.Field{
+			Type:        types.RoutePlanType,
+			Description: "Change a stop on an editable route plan. Omitted fields are kept.",
+			Args: graphql.FieldConfigArgument{
+				"stopId": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.String)},
+				"input":  &graphql.ArgumentConfig{Type: graphql.NewNonNull(types.RouteStopInputType)},
+			},
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				tenantID, err := requireTenant(p.Context)
+				if err != nil {
+					return nil, err
+				}
+				plan, i, err := r.routePlanByStop(p.Context, tenantID, p.Args["stopId"].(string))
+				if err != nil {
+					return nil, err
+				}
+				if err := r.applyRouteStopInput(p.Context, tenantID, &plan.Stops[i], p.Args["input"].(map[string]interface{})); err != nil {
+					return nil, err
+				}
+				return r.saveRoutePlan(p.Context, tenantID, plan)
+			},
+		},
+
+		// -----------------------------------------------------------------
+		// removeRouteStop
+		// -----------------------------------------------------------------
+		"removeRouteStop": &graphql.Field{
+			Type:        types.RoutePlanType,
+			Description: "Remove a stop from an editable route plan.",
+			Args: graphql.FieldConfigArgument{
+				"stopId": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.String)},
+			},
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				tenantID, err := requireTenant(p.Context)
+				if err != nil {
+					return nil, err
+				}
+				plan, i, err := r.routePlanByStop(p.Context, tenantID, p.Args["stopId"].(string))
+				if err != nil {
+					return nil, err
+				}
+				plan.Stops = append(plan.Stops[:i], plan.Stops[i+1:]...)
+				return r.saveRoutePlan(p.Context, tenantID, plan)
+			},
+		},
+
+		// -----------------------------------------------------------------
+		// reorderRouteStops
+		// -----------------------------------------------------------------
+		"reorderRouteStops": &graphql.Field{
+			Type:        types.RoutePlanType,
+			Description: "Put a route plan's stops in a new visiting order. stopIds must list every stop of the plan exactly once.",
+			Args: graphql.FieldConfigArgument{
+				"routeId": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.String)},
+				"stopIds": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(graphql.String)))},
+			},
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				tenantID, err := requireTenant(p.Context)
+				if err != nil {
+					return nil, err
+				}
+				plan, err := r.editableRoutePlan(p.Context, tenantID, p.Args["routeId"].(string))
+				if err != nil {
+					return nil, err
+				}
+
+				ids := p.Args["stopIds"].([]interface{})
+				if len(ids) != len(plan.Stops) {
+					return nil, fmt.Errorf("stopIds must list all %d stops of the plan", len(plan.Stops))
+				}
+				byID := make(map[uuid.UUID]models.RouteStop, len(plan.Stops))
+				for _, s := range plan.Stops {
+					byID[s.ID] = s
+				}
+				ordered := make([]models.RouteStop, 0, len(ids))
+				for _, v := range ids {
+					id, err := uuid.Parse(v.(string))
+					if err != nil {
+						return nil, fmt.Errorf("invalid stop id: %w", err)
+					}
+					s, ok := byID[id]
+					if !ok {
+						return nil, fmt.Errorf("stop %s is not on this plan or is listed twice", id)
+					}
+					delete(byID, id)
+					ordered = append(ordered, s)
+				}
+				plan.Stops = ordered
+				return r.saveRoutePlan(p.Context, tenantID, plan)
+			},
+		},
+
+		// -----------------------------------------------------------------
+		// skipRouteStop
+		// -----------------------------------------------------------------
+		"skipRouteStop": &graphql.Field{
+			Type:        types.RoutePlanType,
+			Description: "Skip a pending stop, on a plan being driven too. A plan whose last open stop is skipped completes.",
+			Args: graphql.FieldConfigArgument{
+				"stopId": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.String)},
+			},
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				tenantID, err := requireTenant(p.Context)
+				if err != nil {
+					return nil, err
+				}
+				stopID, err := uuid.Parse(p.Args["stopId"].(string))
+				if err != nil {
+					return nil, fmt.Errorf("invalid stop id: %w", err)
+				}
+				plan, err := r.RouteRepo.GetByStop(p.Context, tenantID, stopID)
+				if err != nil {
+					return nil, fmt.Errorf("failed to get route plan: %w", err)
+				}
+				skipped, err := r.RouteRepo.SkipStop(p.Context, tenantID, stopID)
+				if err != nil {
+					return nil, fmt.Errorf("failed to skip route stop: %w", err)
+				}
+				if !skipped {
+					return nil, fmt.Errorf("only pending stops can be skipped")
+				}
+
+				plan, err = r.RouteRepo.GetByID(p.Context, tenantID, plan.ID)
+				if err != nil {
+					return nil, fmt.Errorf("failed to get route plan: %w", err)
+				}
+				// Later stops on a plan not yet driven move up.
+				if models.IsRouteEditable(plan.Status) {
+					return r.saveRoutePlan(p.Context, tenantID, plan)
+				}
+				return routePlanOut(plan), nil
+			},
+		},
+
+		// -----------------------------------------------------------------
+		// assignRoutePlan
+		// -----------------------------------------------------------------
+		"assignRoutePlan": &graphql.Field{
+			Type:        types.RoutePlanType,
+			Description: "Give a route plan to a driver and optionally a vehicle. The plan's open shipments are assigned to them as well. A driver holds one assigned plan per day.",
+			Args: graphql.FieldConfigArgument{
+				"id":        &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.String)},
+				"driverId":  &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.String)},
+				"vehicleId": &graphql.ArgumentConfig{Type: graphql.String},
+			},
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				tenantID, userID, err := requireAuth(p.Context)
+				if err != nil {
+					return nil, err
+				}
+				id, err := uuid.Parse(p.Args["id"].(string))
+				if err != nil {
+					return nil, fmt.Errorf("invalid route plan id: %w", err)
+				}
+				driverID, err := uuid.Parse(p.Args["driverId"].(string))
+				if err != nil {
+					return nil, fmt.Errorf("invalid driver id: %w", err)
+				}
+				var vehicleID *uuid.UUID
+				if v, ok := p.Args["vehicleId"].(string); ok && v != "" {
+					vid, err := uuid.Parse(v)
+					if err != nil {
+						return nil, fmt.Errorf("invalid vehicle id: %w", err)
+					}
+					vehicleID = &vid
+				}
+
+				if err := r.RouteRepo.Assign(p.Context, tenantID, id, driverID, vehicleID); err != nil {
+					return nil, fmt.Errorf("failed to assign route plan: %w", err)
+				}
+				plan, err := r.RouteRepo.GetByID(p.Context, tenantID, id)
+				if err != nil {
+					return nil, fmt.Errorf("failed to get route plan: %w", err)
+				}
+
+				// Shipments already delivered or cancelled keep their history.
+				for _, s := range plan.Stops {
+					if s.ShipmentID == nil || s.Status == models.StopSkipped {
+						continue
+					}
+					a := &models.ShipmentAssignment{ShipmentID: *s.ShipmentID, DriverID: &driverID, VehicleID: vehicleID, AssignedBy: &userID}
+					if err := r.ShipmentRepo.Assign(p.Context, tenantID, a); err != nil && !errors.Is(err, repository.ErrShipmentClosed) {
+						return nil, fmt.Errorf("failed to assign shipment %s: %w", s.ShipmentID, err)
+					}
+				}
+				return routePlanOut(plan), nil
+			},
+		},
+
+		// -----------------------------------------------------------------
+		// optimizeRoutes
+		// -----------------------------------------------------------------
+		"optimizeRoutes": &graphql.Field{
+			Type:        graphql.NewNonNull(types.RouteProposalType),
+			Description: "Propose delivery routes for a day from a warehouse: which free vehicle takes which pending, unrouted shipments and in what order, within vehicle capacity, delivery windows and the shift. Nothing is saved. distanceMatrix gives road kilometers between the warehouse (index 0) and shipmentIds in order; without it distances are estimated from coordinates.",
+			Args: graphql.FieldConfigArgument{
+				"date":           &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.String)},
+				"warehouseId":    &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.String)},
+				"start":          &graphql.ArgumentConfig{Type: graphql.String},
+				"shiftHours":     &graphql.ArgumentConfig{Type: graphql.Float},
+				"shipmentIds":    &graphql.ArgumentConfig{Type: graphql.NewList(graphql.NewNonNull(graphql.String))},
+				"vehicleIds":     &graphql.ArgumentConfig{Type: graphql.NewList(graphql.NewNonNull(graphql.String))},
+				"distanceMatrix": &graphql.ArgumentConfig{Type: graphql.NewList(graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(graphql.Float))))},
+			},
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				tenantID, err := requireTenant(p.Context)
+				if err != nil {
+					return nil, err
+				}
+				return r.proposeRoutes(p.Context, tenantID, p.Args)
+			},
+		},
+
+		// -----------------------------------------------------------------
+		// acceptRouteProposal
+		// -----------------------------------------------------------------
+		"acceptRouteProposal": &graphql.Field{
+			Type:        graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(types.RoutePlanType))),
+			Description: "Create a draft route plan per accepted route: leave the warehouse, deliver the shipments in the given order and return. The routes may have been edited since optimizeRoutes proposed them.",
+			Args: graphql.FieldConfigArgument{
+				"input": &graphql.ArgumentConfig{Type: graphql.NewNonNull(types.RouteProposalInputType)},
+			},
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				tenantID, userID, err := requireAuth(p.Context)
+				if err != nil {
+					return nil, err
+				}
+				return r.acceptRouteProposal(p.Context, tenantID, userID, p.Args["input"].(map[string]interface{}))
+			},
+		},
+	}
+}
+
+// proposeRoutes runs the route optimizer for the optimizeRoutes arguments.
+func (r *Resolver) proposeRoutes(ctx context.Context, tenantID uuid.UUID, args map[string]interface{}) (*models.RouteProposal, error) {
+	date := args["date"].(string)
+	day, err := time.Parse(models.RouteDateLayout, date)
+	if err != nil {
+		return nil, fmt.Errorf("invalid date, want YYYY-MM-DD: %w", err)
+	}
+	depot, err := r.routeDepot(ctx, tenantID, args["warehouseId"].(string))
+	if err != nil {
+		return nil, err
+	}
+	start := day.Add(models.RouteDefaultStartHour * time.Hour)
+	if v, ok := args["start"].(string); ok && v != "" {
+		if start, err = time.Parse(time.RFC3339, v); err != nil {
+			return nil, fmt.Errorf("invalid start: %w", err)
+		}
+	}
+	shiftHours := float64(models.RouteDefaultShiftHours)
+	if v, ok := args["shiftHours"].(float64); ok {
+		if v <= 0 || v > 24 {
+			return nil, fmt.Errorf("shiftHours must be between 0 and 24")
+		}
+		shiftHours = v
+	}
+	shipmentIDs, err := parseIDList(args["shipmentIds"], "shipment")
+	if err != nil {
+		return nil, err
+	}
+	vehicleIDs, err := parseIDList(args["vehicleIds"], "vehicle")
+	if err != nil {
+		return nil, err
+	}
+	rawMatrix, hasMatrix := args["distanceMatrix"].([]interface{})
+	if hasMatrix && shipmentIDs == nil {
+		return nil, fmt.Errorf("distanceMatrix needs shipmentIds to fix its order")
+	}
+
+	shipments, err := r.routableShipments(ctx, tenantID, date, shipmentIDs)
+	if err != nil {
+		return nil, err
+	}
+	vehicles, err := r.routableVehicles(ctx, tenantID, date, vehicleIDs)
+	if err != nil {
+		return nil, err
+	}
+	if len(vehicles) == 0 {
+		return nil, fmt.Errorf("no vehicles available on %s", date)
+	}
+
+	proposal := &models.RouteProposal{
+		PlanDate:     date,
+		WarehouseID:  depot.ID,
+		PlannedStart: start,
+		ShiftHours:   shiftHours,
+		Routes:       []models.ProposedRoute{},
+		Unassigned:   []models.UnroutedShipment{},
+	}
+
+	// Job k is shipments[located[k]], at matrix index located[k]+1.
+	var located []int
+	points := []routing.Point{{Latitude: *depot.Latitude, Longitude: *depot.Longitude}}
+	for i, sh := range shipments {
+		if sh.DestinationLatitude == nil || sh.DestinationLongitude == nil {
+			proposal.Unassigned = append(proposal.Unassigned, models.UnroutedShipment{ShipmentID: sh.ID, Reason: "no destination coordinates"})
+			continue
+		}
+		located = append(located, i)
+		points = append(points, routing.Point{Latitude: *sh.DestinationLatitude, Longitude: *sh.DestinationLongitude})
+	}
+
+	var matrix routing.Matrix
+	if hasMatrix {
+		km := make([][]float64, len(rawMatrix))
+		for i, row := range rawMatrix {
+			for _, d := range row.([]interface{}) {
+				km[i] = append(km[i], d.(float64))
+			}
+		}
+		full, err := routing.TableMatrix(km, len(shipments)+1, models.ETADefaultSpeedKmh)
+		if err != nil {
+			return nil, err
+		}
+		idx := []int{0}
+		for _, i := range located {
+			idx = append(idx, i+1)
+		}
+		matrix = routing.Subset(full, idx)
+	} else {
+		matrix = routing.HaversineMatrix(points, models.ETARoadFactor, models.ETADefaultSpeedKmh)
+	}
+
+	problem := routing.Problem{
+		Start:       start,
+		ShiftLength: time.Duration(shiftHours * float64(time.Hour)),
+		Matrix:      matrix,
+	}
+	for _, v := range vehicles {
+		veh := routing.Vehicle{}
+		if v.CapacityKg != nil {
+			veh.CapacityKg = *v.CapacityKg
+		}
+		problem.Vehicles = append(problem.Vehicles, veh)
+	}
+	for _, i := range located {
+		job := routing.Job{
+			Service:   models.RouteDefaultServiceMinutes * time.Minute,
+			WindowEnd: shipments[i].EstimatedDelivery,
+		}
+		if shipments[i].Weight != nil {
+			job.WeightKg = *shipments[i].Weight
+		}
+		problem.Jobs = append(problem.Jobs, job)
+	}
+
+	sol := routing.Solve(problem)
+	for _, rt := range sol.Routes {
+		v := vehicles[rt.Vehicle]
+		route := models.ProposedRoute{
+			VehicleID:  v.ID,
+			LoadKg:     rt.LoadKg,
+			CapacityKg: v.CapacityKg,
+			DistanceKm: roundKm(rt.Meters),
+			ReturnAt:   rt.Return,
+		}
+		for k, j := range rt.Jobs {
+			sh := shipments[located[j]]
+			route.Stops = append(route.Stops, models.ProposedStop{
+				Sequence:       k + 1,
+				ShipmentID:     sh.ID,
+				PlannedArrival: rt.Arrivals[k],
+				WindowEnd:      sh.EstimatedDelivery,
+			})
+		}
+		proposal.Routes = append(proposal.Routes, route)
+	}
+	for _, u := range sol.Unassigned {
+		proposal.Unassigned = append(proposal.Unassigned, models.UnroutedShipment{ShipmentID: shipments[located[u.Job]].ID, Reason: u.Reason})
+	}
+	proposal.TotalDistanceKm = roundKm(sol.Meters)
+	return proposal, nil
+}
+
+// acceptRouteProposal creates the draft plans of an accepted proposal.
+func (r *Resolver) acceptRouteProposal(ctx context.Context, tenantID, userID uuid.UUID, input map[string]interface{}) (interface{}, error) {
+	date := input["planDate"].(string)
+	if _, err := time.Parse(models.RouteDateLayout, date); err != nil {
+		return nil, fmt.Errorf("invalid planDate, want YYYY-MM-DD: %w", err)
+	}
+	depot, err := r.routeDepot(ctx, tenantID, input["warehouseId"].(string))
+	if err != nil {
+		return nil, err
+	}
+	start, err := time.Parse(time.RFC3339, input["plannedStart"].(string))
+	if err != nil {
+		return nil, fmt.Errorf("invalid plannedStart: %w", err)
+	}
+	routes := input["routes"].([]interface{})
+	if len(routes) == 0 {
+		return nil, fmt.Errorf("at least one route is required")
+	}
+
+	var vehicleIDs, shipmentIDs []uuid.UUID
+	perRoute := make([][]uuid.UUID, len(routes))
+	seen := map[uuid.UUID]bool{}
+	for i, raw := range routes {
+		rt := raw.(map[string]interface{})
+		vid, err := uuid.Parse(rt["vehicleId"].(string))
+		if err != nil {
+			return nil, fmt.Errorf("route %d: invalid vehicle id: %w", i+1, err)
+		}
+		if seen[vid] {
+			return nil, fmt.Errorf("route %d: vehicle %s is used twice", i+1, vid)
+		}
+		seen[vid] = true
+		vehicleIDs = append(vehicleIDs, vid)
+		ids, err := parseIDList(rt["shipmentIds"], "shipment")
+		if err != nil {
+			return nil, fmt.Errorf("route %d: %w", i+1, err)
+		}
+		if len(ids) == 0 {
+			return nil, fmt.Errorf("route %d has no shipments", i+1)
+		}
+		for _, id := range ids {
+			if seen[id] {
+				return nil, fmt.Errorf("route %d: shipment %s is used twice", i+1, id)
+			}
+			seen[id] = true
+		}
+		perRoute[i] = ids
+		shipmentIDs = append(shipmentIDs, ids...)
+	}
+
+	vehicles, err := r.routableVehicles(ctx, tenantID, date, vehicleIDs)
+	if err != nil {
+		return nil, err
+	}
+	shipments, err := r.routableShipments(ctx, tenantID, date, shipmentIDs)
+	if err != nil {
+		return nil, err
+	}
+	byID := make(map[uuid.UUID]*models.Shipment, len(shipments))
+	for i := range shipments {
+		byID[shipments[i].ID] = &shipments[i]
+	}
+
+	depotStop := models.RouteStop{
+		Type:         models.StopDepot,
+		WarehouseID:  &depot.ID,
+		Label:        depot.Name,
+		Latitude:     *depot.Latitude,
+		Longitude:    *depot.Longitude,
+		RadiusMeters: models.RouteStopRadiusMeters,
+	}
+	plans := make([]*models.RoutePlan, len(routes))
+	for i, v := range vehicles {
+		plan := &models.RoutePlan{
+			Name:         fmt.Sprintf("%s %s", depot.Name, v.VehicleID),
+			WarehouseID:  &depot.ID,
+			VehicleID:    &vehicles[i].ID,
+			PlannedStart: start,
+			CreatedBy:    &userID,
+		}
+		plan.Stops = append(plan.Stops, depotStop)
+		var load float64
+		for _, id := range perRoute[i] {
+			sh := byID[id]
+			if sh.DestinationLatitude == nil || sh.DestinationLongitude == nil {
+				return nil, fmt.Errorf("shipment %s has no destination coordinates", sh.TrackingNumber)
+			}
+			if sh.Weight != nil {
+				load += *sh.Weight
+			}
+			plan.Stops = append(plan.Stops, models.RouteStop{
+				Type:           models.StopDelivery,
+				ShipmentID:     &sh.ID,
+				Label:          sh.TrackingNumber,
+				Latitude:       *sh.DestinationLatitude,
+				Longitude:      *sh.DestinationLongitude,
+				RadiusMeters:   models.RouteStopRadiusMeters,
+				WindowEnd:      sh.EstimatedDelivery,
+				ServiceMinutes: models.RouteDefaultServiceMinutes,
+			})
+		}
+		if v.CapacityKg != nil && load > *v.CapacityKg {
+			return nil, fmt.Errorf("vehicle %s cannot carry %.1f kg (capacity %.1f kg)", v.VehicleID, load, *v.CapacityKg)
+		}
+		plan.Stops = append(plan.Stops, depotStop)
+		plans[i] = plan
+	}
+
+	if err := r.RouteRepo.CreateProposed(ctx, tenantID, date, plans); err != nil {
+		return nil, fmt.Errorf("failed to create route plans: %w", err)
+	}
+	out := make([]models.RoutePlan, len(plans))
+	for i, plan := range plans {
+		out[i] = *plan
+	}
+	return routePlanList(out), nil
+}
+
+// routeDepot loads the warehouse routes start from, which must be located.
+func (r *Resolver) routeDepot(ctx context.Context, tenantID uuid.UUID, rawID string) (*models.Warehouse, error) {
+	id, err := uuid.Parse(rawID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid warehouse id: %w", err)
+	}
+	w, err := r.WarehouseRepo.GetByID(ctx, tenantID, id)
+	if err != nil {
+		return nil, fmt.Errorf("warehouse not found: %w", err)
+	}
+	if w.Latitude == nil || w.Longitude == nil {
+		return nil, fmt.Errorf("warehouse %s has no coordinates", w.Name)
+	}
+	return w, nil
+}
+
+// routableShipments returns the pending, unrouted shipments for date, or
+// exactly those in ids, in that order, failing if one of them does not
+// qualify.
+func (r *Resolver) routableShipments(ctx context.Context, tenantID uuid.UUID, date string, ids []uuid.UUID) ([]models.Shipment, error) {
+	shipments, err := r.RouteRepo.UnroutedShipments(ctx, tenantID, date, ids)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list shipments: %w", err)
+	}
+	if ids == nil {
+		return shipments, nil
+	}
+	byID := make(map[uuid.UUID]models.Shipment, len(shipments))
+	for _, s := range shipments {
+		byID[s.ID] = s
+	}
+	out := make([]models.Shipment, len(ids))
+	for i, id := range ids {
+		s, ok := byID[id]
+		if !ok {
+			return nil, fmt.Errorf("shipment %s is not pending or is already on a route plan", id)
+		}
+		out[i] = s
+	}
+	return out, nil
+}
+
+// routableVehicles returns the vehicles free on date, or exactly those in
+// ids, in that order, failing if one of them is not free.
+func (r *Resolver) routableVehicles(ctx context.Context, tenantID uuid.UUID, date string, ids []uuid.UUID) ([]models.Vehicle, error) {
+	vehicles, err := r.RouteRepo.FreeVehicles(ctx, tenantID, date, ids)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list vehicles: %w", err)
+	}
+	if ids == nil {
+		return vehicles, nil
+	}
+	byID := make(map[uuid.UUID]models.Vehicle, len(vehicles))
+	for _, v := range vehicles {
+		byID[v.ID] = v
+	}
+	out := make([]models.Vehicle, len(ids))
+	for i, id := range ids {
+		v, ok := byID[id]
+		if !ok {
+			return nil, fmt.Errorf("vehicle %s is not available on %s", id, date)
+		}
+		out[i] = v
+	}
+	return out, nil
+}
+
+// parseIDList parses an optional list-of-IDs argument, which may not repeat
+// an ID; it returns nil when the argument is absent.
+func parseIDList(raw interface{}, what string) ([]uuid.UUID, error) {
+	list, ok := raw.([]interface{})
+	if !ok {
+		return nil, nil
+	}
+	ids := make([]uuid.UUID, 0, len(list))
+	seen := make(map[uuid.UUID]bool, len(list))
+	for _, v := range list {
+		id, err := uuid.Parse(v.(string))
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s id: %w", what, err)
+		}
+		if seen[id] {
+			return nil, fmt.Errorf("%s %s is listed twice", what, id)
+		}
+		seen[id] = true
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
+// roundKm converts meters to kilometers with two decimals.
+func roundKm(meters float64) float64 {
+	return math.Round(meters/10) / 100
+}
+
+// editableRoutePlan loads a plan by its ID argument, failing unless it can
+// still be changed.
+func (r *Resolver) editableRoutePlan(ctx context.Context, tenantID uuid.UUID, rawID string) (*models.RoutePlan, error) {
+	id, err := uuid.Parse(rawID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid route plan id: %w", err)
+	}
+	plan, err := r.RouteRepo.GetByID(ctx, tenantID, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get route plan: %w", err)
+	}
+	if !models.IsRouteEditable(plan.Status) {
+		return nil, fmt.Errorf("%w: plan is %s", repository.ErrRouteLocked, plan.Status)
+	}
+	return plan, nil
+}
+
+// routePlanByStop loads the editable plan holding a stop and the stop's index
+// in it.
+func (r *Resolver) routePlanByStop(ctx context.Context, tenantID uuid.UUID, rawID string) (*models.RoutePlan, int, error) {
+	stopID, err := uuid.Parse(rawID)
+	if err != nil {
+		return nil, 0, fmt.Errorf("invalid stop id: %w", err)
+	}
+	plan, err := r.RouteRepo.GetByStop(ctx, tenantID, stopID)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to get route plan: %w", err)
+	}
+	if !models.IsRouteEditable(plan.Status) {
+		return nil, 0, fmt.Errorf("%w: plan is %s", repository.ErrRouteLocked, plan.Status)
+	}
+	for i, s := range plan.Stops {
+		if s.ID == stopID {
+			return plan, i, nil
+		}
+	}
+	return nil, 0, repository.ErrRouteNotFound
+}
+
+func (r *Resolver) saveRoutePlan(ctx context.Context, tenantID uuid.UUID, plan *models.RoutePlan) (interface{}, error) {
+	if err := r.RouteRepo.Save(ctx, tenantID, plan); err != nil {
+		return nil, fmt.Errorf("failed to save route plan: %w", err)
+	}
+	return routePlanOut(plan), nil
+}
+
+// applyRoutePlanInput copies the header fields present in input onto plan.
+func (r *Resolver) applyRoutePlanInput(ctx context.Context, tenantID uuid.UUID, plan *models.RoutePlan, input map[string]interface{}) error {
+	if v, ok := input["planDate"].(string); ok {
+		if _, err := time.Parse(models.RouteDateLayout, v); err != nil {
+			return fmt.Errorf("invalid planDate, want YYYY-MM-DD: %w", err)
+		}
+		plan.PlanDate = v
+	}
+	if v, ok := input["name"].(string); ok {
+		if strings.TrimSpace(v) == "" {
+			return fmt.Errorf("name cannot be empty")
+		}
+		plan.Name = strings.TrimSpace(v)
+	}
+	if v, ok := input["warehouseId"].(string); ok {
+		if v == "" {
+			plan.WarehouseID = nil
+		} else {
+			id, err := uuid.Parse(v)
+			if err != nil {
+				return fmt.Errorf("invalid warehouse id: %w", err)
+			}
+			if _, err := r.WarehouseRepo.GetByID(ctx, tenantID, id); err != nil {
+				return fmt.Errorf("warehouse not found: %w", err)
+			}
+			plan.WarehouseID = &id
+		}
+	}
+	if v, ok := input["plannedStart"].(string); ok {
+		t, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			return fmt.Errorf("invalid plannedStart: %w", err)
+		}
+		plan.PlannedStart = t
+	}
+	if v, ok := input["notes"].(string); ok {
+		plan.Notes = &v
+	}
+	return nil
+}
+
+// applyRouteStopInput copies the fields present in input onto s. A shipment,
+// warehouse or zone reference also sets the stop's position and label unless
+// those are given; explicit latitude/longitude always win. A new stop must end up with
+// a position.
+func (r *Resolver) applyRouteStopInput(ctx context.Context, tenantID uuid.UUID, s *models.RouteStop, input map[string]interface{}) error {
+	located := s.ID != uuid.Nil
+	if s.RadiusMeters == 0 {
+		s.RadiusMeters = models.RouteStopRadiusMeters
+	}
+	if s.ID == uuid.Nil {
+		s.ServiceMinutes = models.RouteDefaultServiceMinutes
+	}
+	label, _ := input["label"].(string)
+
+	if v, ok := input["shipmentId"].(string); ok && v != "" {
+		id, err := uuid.Parse(v)
+		if err != nil {
+			return fmt.Errorf("invalid shipment id: %w", err)
+		}
+		shipment, err := r.ShipmentRepo.GetByID(ctx, tenantID, id)
+		if err != nil {
+			return fmt.Errorf("shipment not found: %w", err)
+		}
+		s.ShipmentID = &id
+		if s.Type == "" {
+			s.Type = models.StopDelivery
+		}
+		if s.Label == "" {
+			s.Label = shipment.TrackingNumber
+		}
+		if s.WindowEnd == nil {
+			s.WindowEnd = shipment.EstimatedDelivery
+		}
+		if shipment.DestinationLatitude != nil && shipment.DestinationLongitude != nil {
+			s.Latitude, s.Longitude = *shipment.DestinationLatitude, *shipment.DestinationLongitude
+			located = true
+		}
+	}
+	if v, ok := input["warehouseId"].(string); ok && v != "" {
+		id, err := uuid.Parse(v)
+		if err != nil {
+			return fmt.Errorf("invalid warehouse id: %w", err)
+		}
+		w, err := r.WarehouseRepo.GetByID(ctx, tenantID, id)
+		if err != nil {
+			return fmt.Errorf("warehouse not found: %w", err)
+		}
+		s.WarehouseID = &id
+		if s.Type == "" {
+			s.Type = models.StopDepot
+		}
+		if s.Label == "" {
+			s.Label = w.Name
+		}
+		if w.Latitude != nil && w.Longitude != nil {
+			s.Latitude, s.Longitude = *w.Latitude, *w.Longitude
+			located = true
+		}
+	}
+	if v, ok := input["zoneId"].(string); ok && v != "" {
+		id, err := uuid.Parse(v)
+		if err != nil {
+			return fmt.Errorf("invalid zone id: %w", err)
+		}
+		z, err := r.ZoneRepo.GetByID(ctx, tenantID, id)
+		if err != nil {
+			return fmt.Errorf("zone not found: %w", err)
+		}
+		s.ZoneID = &id
+		s.Latitude, s.Longitude, s.RadiusMeters = z.Latitude, z.Longitude, z.RadiusMeters
+		if s.Label == "" {
+			s.Label = z.Label
+		}
+		located = true
+	}
+
+	lat, hasLat := input["latitude"].(float64)
+	lng, hasLng := input["longitude"].(float64)
+	if hasLat != hasLng {
+		return fmt.Errorf("latitude and longitude must be set together")
+	}
+	if hasLat {
+		if lat < -90 || lat > 90 || lng < -180 || lng > 180 {
+			return fmt.Errorf("coordinates out of range")
+		}
+		s.Latitude, s.Longitude = lat, lng
+		located = true
+	}
+	if !located {
+		return fmt.Errorf("stop has no position: give latitude/longitude, a zone, a located warehouse or a shipment with destination coordinates")
+	}
+
+	if v, ok := input["type"].(string); ok && v != "" {
+		if !models.IsStopType(v) {
+			return fmt.Errorf("unknown stop type %q", v)
+		}
+		s.Type = v
+	}
+	if s.Type == "" {
+		s.Type = models.StopVisit
+	}
+	if label = strings.TrimSpace(label); label != "" {
+		s.Label = label
+	}
+	if s.Label == "" {
+		s.Label = fmt.Sprintf("%.5f, %.5f", s.Latitude, s.Longitude)
+	}
+	if v, ok := input["radiusMeters"].(int); ok {
+		if v <= 0 {
+			return fmt.Errorf("radiusMeters must be positive")
+		}
+		s.RadiusMeters = v
+	}
+	if v, ok := input["serviceMinutes"].(int); ok {
+		if v < 0 {
+			return fmt.Errorf("serviceMinutes cannot be negative")
+		}
+		s.ServiceMinutes = v
+	}
+	for _, w := range []struct {
+		field string
+		dst   **time.Time
+	}{{"windowStart", &s.WindowStart}, {"windowEnd", &s.WindowEnd}} {
+		v, ok := input[w.field].(string)
+		if !ok {
+			continue
+		}
+		if v == "" {
+			*w.dst = nil
+			continue
+		}
+		t, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			return fmt.Errorf("invalid %s: %w", w.field, err)
+		}
+		*w.dst = &t
+	}
+	if s.WindowStart != nil && s.WindowEnd != nil && s.WindowEnd.Before(*s.WindowStart) {
+		return fmt.Errorf("windowEnd is before windowStart")
+	}
+	return nil
+}
+
+// routePlanOut returns plan with a non-nil stop list, which the schema
+// declares non-null.
+func routePlanOut(plan *models.RoutePlan) *models.RoutePlan {
+	if plan.Stops == nil {
+		plan.Stops = []models.RouteStop{}
+	}
+	return plan
+}
+
+func routePlanList(plans []models.RoutePlan) []models.RoutePlan {
+	if plans == nil {
+		return []models.RoutePlan{}
+	}
+	for i := range plans {
+		routePlanOut(&plans[i])
+	}
+	return plans
+}
@@ -1,6 +1,7 @@
 package resolvers
 
 import (
+	"context"
 	"fmt"
 	"time"
 
@@ -236,36 +237,8 @@ func (r *Resolver) FleetMutations() graphql.Fields {
 					UpdatedAt: now,
 				}
 
-				if v, ok := input["name"].(string); ok {
-					vehicle.Name = &v
-				}
-				if v, ok := input["type"].(string); ok {
-					vehicle.Type = &v
-				}
-				if v, ok := input["status"].(string); ok && v != "" {
-					vehicle.Status = v
-				}
-				if v, ok := input["fuelLevel"].(int); ok {
-					vehicle.FuelLevel = v
-				}
-				if v, ok := input["mileage"].(int); ok {
-					vehicle.Mileage = v
-				}
-				if v, ok := input["lastService"].(string); ok && v != "" {
-					if t, err := time.Parse(time.RFC3339, v); err == nil {
-						vehicle.LastService = &t
-					}
-				}
-				if v, ok := input["nextService"].(string); ok && v != "" {
-					if t, err := time.Parse(time.RFC3339, v); err == nil {
-						vehicle.NextService = &t
-					}
-				}
-				if v, ok := input["licensePlate"].(string); ok {
-					vehicle.LicensePlate = &v
-				}
-				if v, ok := input["year"].(int); ok {
-					vehicle.Year = &v
+				if err := applyVehicleInput(vehicle, input); err != nil {
+					return nil, err
 				}
 
 				if err := r.VehicleRepo.Create(p.Context, vehicle); err != nil {
@@ -336,6 +309,12 @@ func (r *Resolver) FleetMutations() graphql.Fields {
 				if v, ok := input["year"].(int); ok {
 					vehicle.Year = &v
 				}
+				if v, ok := input["capacityKg"].(float64); ok {
+					if v <= 0 {
+						return nil, fmt.Errorf("capacityKg must be positive")
+					}
+					vehicle.CapacityKg = &v
+				}
 
 				vehicle.UpdatedAt = time.Now()
 
@@ -403,45 +382,8 @@ func (r *Resolver) FleetMutations() graphql.Fields {
 					UpdatedAt:       now,
 				}
 
-				if v, ok := input["firstName"].(string); ok {
-					driver.FirstName = &v
-				}
-				if v, ok := input["lastName"].(string); ok {
-					driver.LastName = &v
-				}
-				if v, ok := input["email"].(string); ok {
-					driver.Email = &v
-				}
-				if v, ok := input["phone"].(string); ok {
-					driver.Phone = &v
-				}
-				if v, ok := input["licenseNumber"].(string); ok {
-					driver.LicenseNumber = &v
-				}
-				if v, ok := input["licenseExpiry"].(string); ok && v != "" {
-					if t, err := time.Parse(time.RFC3339, v); err == nil {
-						driver.LicenseExpiry = &t
-					}
-				}
-				if v, ok := input["status"].(string); ok && v != "" {
-					driver.Status = v
-				}
-				if v, ok := input["rating"].(float64); ok {
-					driver.Rating = &v
-				}
-				if v, ok := input["totalDeliveries"].(int); ok {
-					driver.TotalDeliveries = v
-				}
-				if v, ok := input["vehicleId"].(string); ok && v != "" {
-					vid, parseErr := uuid.Parse(v)
-					if parseErr != nil {
-						return nil, fmt.Errorf("invalid vehicle id for driver: %w", parseErr)
-					}
-					// Validate the vehicle belongs to the same tenant (prevent IDOR).
-					if _, err := r.VehicleRepo.GetByID(p.Context, tenantID, vid); err != nil {
-						return nil, fmt.Errorf("vehicle not found in tenant")
-					}
-					driver.VehicleID = &vid
+				if err := r.applyDriverInput(p.Context, tenantID, driver, input); err != nil {
+					return nil, err
 				}
 
 				if err := r.DriverRepo.Create(p.Context, driver); err != nil {
@@ -704,3 +646,100 @@ func (r *Resolver) FleetMutations() graphql.Fields {
 		},
 	}
 }
+
+// applyVehicleInput sets the fields given in a VehicleInput on vehicle. It
+// is shared by createVehicle and bulk import, which applies a row onto either
+// a new vehicle or the existing one it updates.
+func applyVehicleInput(vehicle *models.Vehicle, input map[string]interface{}) error {
+	if v, ok := input["vehicleId"].(string); ok {
+		vehicle.VehicleID = v
+	}
+	if v, ok := input["name"].(string); ok {
+		vehicle.Name = &v
+	}
+	if v, ok := input["type"].(string); ok {
+		vehicle.Type = &v
+	}
+	if v, ok := input["status"].(string); ok && v != "" {
+		vehicle.Status = v
+	}
+	if v, ok := input["fuelLevel"].(int); ok {
+		vehicle.FuelLevel = v
+	}
+	if v, ok := input["mileage"].(int); ok {
+		vehicle.Mileage = v
+	}
+	if v, ok := input["lastService"].(string); ok && v != "" {
+		if t, err := time.Parse(time.RFC3339, v); err == nil {
+			vehicle.LastService = &t
+		}
+	}
+	if v, ok := input["nextService"].(string); ok && v != "" {
+		if t, err := time.Parse(time.RFC3339, v); err == nil {
+			vehicle.NextService = &t
+		}
+	}
+	if v, ok := input["licensePlate"].(string); ok {
+		vehicle.LicensePlate = &v
+	}
+	if v, ok := input["year"].(int); ok {
+		vehicle.Year = &v
+	}
+	if v, ok := input["capacityKg"].(float64); ok {
+		if v <= 0 {
+			return fmt.Errorf("capacityKg must be positive")
+		}
+		vehicle.CapacityKg = &v
+	}
+	return nil
+}
+
+// applyDriverInput sets the fields given in a DriverInput on driver, checking
+// that an assigned vehicle belongs to the tenant. It is shared by
+// createDriver and bulk import.
+func (r *Resolver) applyDriverInput(ctx context.Context, tenantID uuid.UUID, driver *models.Driver, input map[string]interface{}) error {
+	if v, ok := input["employeeId"].(string); ok {
+		driver.EmployeeID = v
+	}
+	if v, ok := input["firstName"].(string); ok {
+		driver.FirstName = &v
+	}
+	if v, ok := input["lastName"].(string); ok {
+		driver.LastName = &v
+	}
+	if v, ok := input["email"].(string); ok {
+		driver.Email = &v
+	}
+	if v, ok := input["phone"].(string); ok {
+		driver.Phone = &v
+	}
+	if v, ok := input["licenseNumber"].(string); ok {
+		driver.LicenseNumber = &v
+	}
+	if v, ok := input["licenseExpiry"].(string); ok && v != "" {
+		if t, err := time.Parse(time.RFC3339, v); err == nil {
+			driver.LicenseExpiry = &t
+		}
+	}
+	if v, ok := input["status"].(string); ok && v != "" {
+		driver.Status = v
+	}
+	if v, ok := input["rating"].(float64); ok {
+		driver.Rating = &v
+	}
+	if v, ok := input["totalDeliveries"].(int); ok {
+		driver.TotalDeliveries = v
+	}
+	if v, ok := input["vehicleId"].(string); ok && v != "" {
+		vid, parseErr := uuid.Parse(v)
+		if parseErr != nil {
+			return fmt.Errorf("invalid vehicle id for driver: %w", parseErr)
+		}
+		// Validate the vehicle belongs to the same tenant (prevent IDOR).
+		if _, err := r.VehicleRepo.GetByID(ctx, tenantID, vid); err != nil {
+			return fmt.Errorf("vehicle not found in tenant")
+		}
+		driver.VehicleID = &vid
+	}
+	return nil
+}
@@ -0,0 +1,236 @@
+package resolvers
+
+import (
+	"context"
+	"fmt"
+
+	"cargomax-api/internal/graph/types"
+	"cargomax-api/internal/models"
+
+	"github.com/google/uuid"
+	"github.com/graphql-go/graphql"
+)
+
+// FulfillmentQueries returns GraphQL query fields for pick lists.
+func (r *Resolver) FulfillmentQueries() graphql.Fields {
+	return graphql.Fields{
+		"pickLists": &graphql.Field{
+			Type:        graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(types.FulfillmentType))),
+			Description: "Fulfillments waiting to be picked, oldest first, at one warehouse or at all of them.",
+			Args: graphql.FieldConfigArgument{
+				"warehouseId": &graphql.ArgumentConfig{Type: graphql.String},
+			},
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				tenantID, err := requireTenant(p.Context)
+				if err != nil {
+					return nil, err
+				}
+				var warehouseID *uuid.UUID
+				if v, ok := p.Args["warehouseId"].(string); ok {
+					id, err := uuid.Parse(v)
+					if err != nil {
+						return nil, fmt.Errorf("invalid warehouse id: %w", err)
+					}
+					warehouseID = &id
+				}
+				return r.FulfillmentRepo.PickLists(p.Context, tenantID, warehouseID)
+			},
+		},
+		"fulfillment": &graphql.Field{
+			Type: types.FulfillmentType,
+			Args: graphql.FieldConfigArgument{
+				"id": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.String)},
+			},
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				tenantID, err := requireTenant(p.Context)
+				if err != nil {
+					return nil, err
+				}
+				id, err := uuid.Parse(p.Args["id"].(string))
+				if err != nil {
+					return nil, fmt.Errorf("invalid fulfillment id: %w", err)
+				}
+				return r.FulfillmentRepo.GetByID(p.Context, tenantID, id)
+			},
+		},
+	}
+}
+
+// FulfillmentMutations returns GraphQL mutation fields that pick, pack and
+// ship orders with lines.
+func (r *Resolver) FulfillmentMutations() graphql.Fields {
+	return graphql.Fields{
+		"createPickLists": &graphql.Field{
+			Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(types.FulfillmentType))),
+			Description: "Put an order's reserved stock on pick lists, one per warehouse, and move the order to processing. " +
+				"Without lines, everything not on a pick list yet is picked; giving part of it splits the order across shipments.",
+			Args: graphql.FieldConfigArgument{
+				"orderId": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.String)},
+				"lines":   &graphql.ArgumentConfig{Type: graphql.NewList(graphql.NewNonNull(types.PickInputType))},
+			},
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				tenantID, userID, err := requireAuth(p.Context)
+				if err != nil {
+					return nil, err
+				}
+				orderID, err := uuid.Parse(p.Args["orderId"].(string))
+				if err != nil {
+					return nil, fmt.Errorf("invalid order id: %w", err)
+				}
+				picks, err := pickInputs(p.Args["lines"])
+				if err != nil {
+					return nil, err
+				}
+				return r.FulfillmentRepo.CreatePickLists(p.Context, tenantID, orderID, picks, &userID)
+			},
+		},
+		"confirmPick": &graphql.Field{
+			Type:        types.FulfillmentType,
+			Description: "Record a pick list as picked. lines gives what was actually picked of lines that came up short; the rest can go on another pick list.",
+			Args: graphql.FieldConfigArgument{
+				"id":    &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.String)},
+				"lines": &graphql.ArgumentConfig{Type: graphql.NewList(graphql.NewNonNull(types.PickInputType))},
+			},
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				tenantID, err := requireTenant(p.Context)
+				if err != nil {
+					return nil, err
+				}
+				id, err := uuid.Parse(p.Args["id"].(string))
+				if err != nil {
+					return nil, fmt.Errorf("invalid fulfillment id: %w", err)
+				}
+				picked, err := pickInputs(p.Args["lines"])
+				if err != nil {
+					return nil, err
+				}
+				return r.FulfillmentRepo.ConfirmPick(p.Context, tenantID, id, picked)
+			},
+		},
+		"packFulfillment": &graphql.Field{
+			Type: types.FulfillmentType,
+			Description: "Confirm a picked fulfillment as packed and create the shipment carrying it from its warehouse. " +
+				"The stock leaves the warehouse; the order ships once all of it has.",
+			Args: graphql.FieldConfigArgument{
+				"id":    &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.String)},
+				"input": &graphql.ArgumentConfig{Type: graphql.NewNonNull(types.PackInputType)},
+			},
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				tenantID, err := requireTenant(p.Context)
+				if err != nil {
+					return nil, err
+				}
+				id, err := uuid.Parse(p.Args["id"].(string))
+				if err != nil {
+					return nil, fmt.Errorf("invalid fulfillment id: %w", err)
+				}
+				input := p.Args["input"].(map[string]interface{})
+				if w := input["weight"].(float64); w <= 0 {
+					return nil, fmt.Errorf("weight must be positive")
+				}
+				if input["dimensions"].(string) == "" {
+					return nil, fmt.Errorf("dimensions are required")
+				}
+
+				f, err := r.FulfillmentRepo.GetByID(p.Context, tenantID, id)
+				if err != nil {
+					return nil, err
+				}
+				s, err := r.fulfillmentShipment(p.Context, tenantID, f, input)
+				if err != nil {
+					return nil, err
+				}
+				return r.FulfillmentRepo.Pack(p.Context, tenantID, id, s, shipmentActor(p.Context))
+			},
+		},
+		"cancelFulfillment": &graphql.Field{
+			Type:        types.FulfillmentType,
+			Description: "Drop a pick list that has not shipped. Its stock stays reserved for the order.",
+			Args: graphql.FieldConfigArgument{
+				"id": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.String)},
+			},
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				tenantID, err := requireTenant(p.Context)
+				if err != nil {
+					return nil, err
+				}
+				id, err := uuid.Parse(p.Args["id"].(string))
+				if err != nil {
+					return nil, fmt.Errorf("invalid fulfillment id: %w", err)
+				}
+				return r.FulfillmentRepo.Cancel(p.Context, tenantID, id)
+			},
+		},
+	}
+}
+
+// pickInputs reads a list of PickInput. A missing list is nil.
+func pickInputs(v interface{}) ([]models.PickRequest, error) {
+	list, ok := v.([]interface{})
+	if !ok {
+		return nil, nil
+	}
+	out := make([]models.PickRequest, 0, len(list))
+	for _, item := range list {
+		m, _ := item.(map[string]interface{})
+		v, _ := m["orderLineId"].(string)
+		id, err := uuid.Parse(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid order line id: %w", err)
+		}
+		n, _ := m["quantity"].(int)
+		out = append(out, models.PickRequest{OrderLineID: id, Quantity: n})
+	}
+	return out, nil
+}
+
+// fulfillmentShipment fills in the shipment a packed fulfillment goes out
+// in: the parcel and destination from input, the customer from the order,
+// the origin from the fulfillment's warehouse and a tracking number. Without
+// a destination it goes to the order's client's address.
+func (r *Resolver) fulfillmentShipment(ctx context.Context, tenantID uuid.UUID, f *models.Fulfillment, input map[string]interface{}) (*models.Shipment, error) {
+	o, err := r.OrderRepo.GetByID(ctx, tenantID, f.OrderID)
+	if err != nil {
+		return nil, err
+	}
+	s := &models.Shipment{
+		TenantID:      tenantID,
+		Status:        models.ShipmentPending,
+		CustomerName:  o.CustomerName,
+		CustomerEmail: o.CustomerEmail,
+	}
+	fields := map[string]interface{}{}
+	for k, v := range input {
+		fields[k] = v
+	}
+	if f.WarehouseID != nil {
+		fields["warehouseId"] = f.WarehouseID.String()
+	}
+	if err := r.applyShipmentInput(ctx, tenantID, s, fields); err != nil {
+		return nil, err
+	}
+
+	if s.Destination == nil && o.ClientID != nil {
+		c, err := r.ClientRepo.GetByID(ctx, tenantID, *o.ClientID)
+		if err != nil {
+			return nil, err
+		}
+		s.Destination = c.Address
+		s.DestinationAddress = c.AddressDetails
+		s.DestinationLatitude, s.DestinationLongitude = c.Latitude, c.Longitude
+	}
+	if s.Destination == nil || *s.Destination == "" {
+		return nil, fmt.Errorf("the order has no client address to ship to; give a destination")
+	}
+
+	if s.TrackingNumber == "" {
+		tf, err := r.SettingRepo.TrackingFormat(ctx, tenantID)
+		if err != nil {
+			return nil, err
+		}
+		if s.TrackingNumber, err = r.ShipmentRepo.NextTrackingNumber(ctx, tenantID, tf); err != nil {
+			return nil, fmt.Errorf("failed to generate tracking number: %w", err)
+		}
+	}
+	return s, nil
+}
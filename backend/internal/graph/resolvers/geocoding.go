@@ -0,0 +1,228 @@
+package resolvers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+
+	"cargomax-api/internal/geocode"
+	"cargomax-api/internal/graph/types"
+	"cargomax-api/internal/models"
+
+	"github.com/graphql-go/graphql"
+)
+
+// GeocodingQueries returns GraphQL query fields for placing addresses.
+func (r *Resolver) GeocodingQueries() graphql.Fields {
+	return graphql.Fields{
+		"geocodeAddress": &graphql.Field{
+			Type:        types.GeocodeResultType,
+			Description: "Look up the position of an address in the gazetteer; null when it cannot be placed.",
+			Args: graphql.FieldConfigArgument{
+				"address": &graphql.ArgumentConfig{Type: graphql.NewNonNull(types.AddressInputType)},
+			},
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				if _, err := requireTenant(p.Context); err != nil {
+					return nil, err
+				}
+				a, _, err := addressInput(p.Args, "address")
+				if err != nil {
+					return nil, err
+				}
+				if r.Geocoder == nil {
+					return nil, nil
+				}
+				res, err := r.Geocoder.Geocode(p.Context, *a)
+				if errors.Is(err, geocode.ErrNotFound) {
+					return nil, nil
+				}
+				if err != nil {
+					return nil, fmt.Errorf("failed to geocode address: %w", err)
+				}
+				return res, nil
+			},
+		},
+	}
+}
+
+// GeocodingMutations returns GraphQL mutation fields for placing addresses.
+func (r *Resolver) GeocodingMutations() graphql.Fields {
+	return graphql.Fields{
+		"syncLocationZones": &graphql.Field{
+			Type: types.LocationSyncResultType,
+			Description: "Geocode every warehouse and client that has a structured address but no coordinates, " +
+				"then bring their approved zones in line with their coordinates.",
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				tenantID, err := requireTenant(p.Context)
+				if err != nil {
+					return nil, err
+				}
+
+				geocoded, unplaced := 0, 0
+				warehouses, err := r.WarehouseRepo.ListUnlocated(p.Context, tenantID)
+				if err != nil {
+					return nil, err
+				}
+				for _, w := range warehouses {
+					lat, lng := r.geocode(p.Context, w.AddressDetails)
+					if lat == nil {
+						unplaced++
+						continue
+					}
+					if err := r.WarehouseRepo.SetLocation(p.Context, tenantID, w.ID, *lat, *lng); err != nil {
+						return nil, err
+					}
+					geocoded++
+				}
+				clients, err := r.ClientRepo.ListUnlocated(p.Context, tenantID)
+				if err != nil {
+					return nil, err
+				}
+				for _, c := range clients {
+					lat, lng := r.geocode(p.Context, c.AddressDetails)
+					if lat == nil {
+						unplaced++
+						continue
+					}
+					if err := r.ClientRepo.SetLocation(p.Context, tenantID, c.ID, *lat, *lng); err != nil {
+						return nil, err
+					}
+					geocoded++
+				}
+
+				zones, err := r.ZoneRepo.SyncLinkedZones(p.Context, tenantID)
+				if err != nil {
+					return nil, err
+				}
+				return map[string]interface{}{
+					"geocoded": geocoded,
+					"unplaced": unplaced,
+					"zones":    zones,
+				}, nil
+			},
+		},
+	}
+}
+
+// addressInput reads the AddressInput argument under key. given is false when
+// the argument was left out.
+func addressInput(input map[string]interface{}, key string) (a *models.Address, given bool, err error) {
+	m, ok := input[key].(map[string]interface{})
+	if !ok {
+		return nil, false, nil
+	}
+	a = &models.Address{}
+	a.Line1, _ = m["line1"].(string)
+	a.Line2, _ = m["line2"].(string)
+	a.City, _ = m["city"].(string)
+	a.Region, _ = m["region"].(string)
+	a.PostalCode, _ = m["postalCode"].(string)
+	a.Country, _ = m["country"].(string)
+	if err := a.Normalize(); err != nil {
+		return nil, true, fmt.Errorf("%s: %w", key, err)
+	}
+	return a, true, nil
+}
+
+// geocode returns the coordinates of a, or nils when a is nil, no geocoder is
+// configured or the address cannot be placed. Saving a record never fails
+// because its address could not be geocoded.
+func (r *Resolver) geocode(ctx context.Context, a *models.Address) (lat, lng *float64) {
+	if a == nil || r.Geocoder == nil {
+		return nil, nil
+	}
+	res, err := r.Geocoder.Geocode(ctx, *a)
+	if err != nil {
+		if !errors.Is(err, geocode.ErrNotFound) {
+			log.Printf("geocode: failed to place %q: %v", a.Format(), err)
+		}
+		return nil, nil
+	}
+	return &res.Latitude, &res.Longitude
+}
+
+// setWarehouseAddress applies the addressDetails argument: it fills the
+// free-text address when that was not given and the coordinates, by
+// geocoding, when they were not.
+func (r *Resolver) setWarehouseAddress(ctx context.Context, w *models.Warehouse, input map[string]interface{}) error {
+	a, given, err := addressInput(input, "addressDetails")
+	if err != nil || !given {
+		return err
+	}
+	w.AddressDetails = a
+	if w.Address == nil {
+		formatted := a.Format()
+		w.Address = &formatted
+	}
+	if w.Latitude == nil && w.Longitude == nil {
+		w.Latitude, w.Longitude = r.geocode(ctx, a)
+	}
+	return nil
+}
+
+// setClientAddress is setWarehouseAddress for a client.
+func (r *Resolver) setClientAddress(ctx context.Context, c *models.Client, input map[string]interface{}) error {
+	a, given, err := addressInput(input, "addressDetails")
+	if err != nil || !given {
+		return err
+	}
+	c.AddressDetails = a
+	if c.Address == nil {
+		formatted := a.Format()
+		c.Address = &formatted
+	}
+	if c.Latitude == nil && c.Longitude == nil {
+		c.Latitude, c.Longitude = r.geocode(ctx, a)
+	}
+	return nil
+}
+
+// setShipmentAddresses applies the originAddress and destinationAddress
+// arguments. Each fills its free-text column when that was not given in the
+// same input; a new destination is geocoded unless its coordinates were
+// given, replacing the coordinates of the old one.
+func (r *Resolver) setShipmentAddresses(ctx context.Context, s *models.Shipment, input map[string]interface{}) error {
+	a, given, err := addressInput(input, "originAddress")
+	if err != nil {
+		return err
+	}
+	if given {
+		s.OriginAddress = a
+		if _, ok := input["origin"]; !ok {
+			formatted := a.Format()
+			s.Origin = &formatted
+		}
+	}
+
+	a, given, err = addressInput(input, "destinationAddress")
+	if err != nil || !given {
+		return err
+	}
+	s.DestinationAddress = a
+	if _, ok := input["destination"]; !ok {
+		formatted := a.Format()
+		s.Destination = &formatted
+	}
+	_, latGiven := input["destinationLatitude"]
+	_, lngGiven := input["destinationLongitude"]
+	if !latGiven && !lngGiven {
+		s.DestinationLatitude, s.DestinationLongitude = r.geocode(ctx, a)
+	}
+	return nil
+}
+
+// checkLocation requires the coordinates to be given together and to be a
+// valid position.
+func checkLocation(lat, lng *float64) error {
+	if lat == nil && lng == nil {
+		return nil
+	}
+	if lat == nil || lng == nil {
+		return fmt.Errorf("latitude and longitude must be set together")
+	}
+	if *lat < -90 || *lat > 90 || *lng < -180 || *lng > 180 {
+		return fmt.Errorf("coordinates out of range")
+	}
+	return nil
+}
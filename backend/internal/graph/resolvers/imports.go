@@ -0,0 +1,533 @@
+package resolvers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"strings"
+	"time"
+
+	"cargomax-api/internal/graph/types"
+	"cargomax-api/internal/imports"
+	"cargomax-api/internal/jobs"
+	"cargomax-api/internal/models"
+	"cargomax-api/internal/repository"
+
+	"github.com/google/uuid"
+	"github.com/graphql-go/graphql"
+)
+
+// importKinds names the imports.Kind values in the schema.
+var importKinds = map[imports.Kind]string{
+	imports.String: "string",
+	imports.Int:    "int",
+	imports.Float:  "float",
+	imports.Time:   "date",
+}
+
+// ImportQueries returns GraphQL query fields for bulk imports. Files are
+// uploaded through the manager REST API.
+func (r *Resolver) ImportQueries() graphql.Fields {
+	return graphql.Fields{
+		"importBatch": &graphql.Field{
+			Type: types.ImportBatchType,
+			Args: graphql.FieldConfigArgument{
+				"id": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.String)},
+			},
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				tenantID, err := requireTenant(p.Context)
+				if err != nil {
+					return nil, err
+				}
+				id, err := uuid.Parse(p.Args["id"].(string))
+				if err != nil {
+					return nil, fmt.Errorf("invalid import id: %w", err)
+				}
+				return r.ImportRepo.GetByID(p.Context, tenantID, id)
+			},
+		},
+		"importBatches": &graphql.Field{
+			Type:        graphql.NewList(types.ImportBatchType),
+			Description: "The tenant's most recent imports, newest first.",
+			Args: graphql.FieldConfigArgument{
+				"limit": &graphql.ArgumentConfig{Type: graphql.Int, DefaultValue: 20},
+			},
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				tenantID, err := requireTenant(p.Context)
+				if err != nil {
+					return nil, err
+				}
+				limit, _ := p.Args["limit"].(int)
+				if limit < 1 || limit > 100 {
+					limit = 20
+				}
+				return r.ImportRepo.List(p.Context, tenantID, limit)
+			},
+		},
+		"importFields": &graphql.Field{
+			Type:        graphql.NewList(types.ImportFieldType),
+			Description: "The fields an entity's import file can map its columns onto.",
+			Args: graphql.FieldConfigArgument{
+				"entity": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.String)},
+			},
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				if _, err := requireTenant(p.Context); err != nil {
+					return nil, err
+				}
+				spec, ok := imports.SpecFor(p.Args["entity"].(string))
+				if !ok {
+					return nil, fmt.Errorf("unknown import entity %q", p.Args["entity"])
+				}
+				fields := make([]map[string]interface{}, 0, len(spec.Fields))
+				for _, f := range spec.Fields {
+					field := map[string]interface{}{
+						"name":     f.Name,
+						"type":     importKinds[f.Kind],
+						"required": f.Required,
+						"key":      f.Key,
+					}
+					if f.Ref != "" {
+						field["ref"] = f.Ref
+					}
+					fields = append(fields, field)
+				}
+				return fields, nil
+			},
+		},
+	}
+}
+
+// ImportMutations returns GraphQL mutation fields for bulk imports.
+func (r *Resolver) ImportMutations() graphql.Fields {
+	args := graphql.FieldConfigArgument{
+		"id":      &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.String)},
+		"mode":    &graphql.ArgumentConfig{Type: graphql.String, Description: "insert, upsert or skip; defaults to the batch's mode."},
+		"mapping": &graphql.ArgumentConfig{Type: graphql.NewList(graphql.NewNonNull(types.ImportColumnInputType)), Description: "Defaults to the batch's mapping, suggested from the header on upload."},
+	}
+	return graphql.Fields{
+		"validateImport": &graphql.Field{
+			Type: types.ImportBatchType,
+			Description: "Dry-run an import: check every row with the same rules as the create mutations " +
+				"and count what a commit would create, update and skip. Nothing is written but the batch.",
+			Args: args,
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				tenantID, err := requireTenant(p.Context)
+				if err != nil {
+					return nil, err
+				}
+				b, err := r.importBatchArgs(p, tenantID)
+				if err != nil {
+					return nil, err
+				}
+				plan, err := r.planImport(p.Context, tenantID, b)
+				if err != nil {
+					return nil, err
+				}
+				plan.apply(b)
+				b.Status = models.ImportValidated
+				if err := r.ImportRepo.Save(p.Context, b); err != nil {
+					return nil, err
+				}
+				return b, nil
+			},
+		},
+		"commitImport": &graphql.Field{
+			Type: types.ImportBatchType,
+			Description: "Import the file in one transaction: every row is written or, if any row is invalid, none. " +
+				"Files over the synchronous limit are queued and committed in the background; poll importBatch for the outcome.",
+			Args: args,
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				tenantID, err := requireTenant(p.Context)
+				if err != nil {
+					return nil, err
+				}
+				b, err := r.importBatchArgs(p, tenantID)
+				if err != nil {
+					return nil, err
+				}
+				claimed, err := r.ImportRepo.Queue(p.Context, b)
+				if err != nil {
+					return nil, err
+				}
+				if !claimed {
+					return nil, fmt.Errorf("import is already being committed")
+				}
+
+				if b.TotalRows > models.ImportSyncRows && r.Scheduler != nil {
+					_, err := r.Scheduler.Enqueue(p.Context, models.ImportCommitJob, models.ImportCommitPayload{BatchID: b.ID},
+						jobs.EnqueueOptions{TenantID: &tenantID, DedupeKey: "import:" + b.ID.String()})
+					if err != nil {
+						r.failImport(p.Context, b, "failed to queue the import")
+						return nil, err
+					}
+					return b, nil
+				}
+				if err := r.commitImport(p.Context, tenantID, b); err != nil {
+					return nil, err
+				}
+				return b, nil
+			},
+		},
+	}
+}
+
+// importBatchArgs loads the batch named by a mutation's id and applies its
+// mode and mapping arguments. Batches being or already committed are
+// refused.
+func (r *Resolver) importBatchArgs(p graphql.ResolveParams, tenantID uuid.UUID) (*models.ImportBatch, error) {
+	id, err := uuid.Parse(p.Args["id"].(string))
+	if err != nil {
+		return nil, fmt.Errorf("invalid import id: %w", err)
+	}
+	b, err := r.ImportRepo.GetByID(p.Context, tenantID, id)
+	if err != nil {
+		return nil, err
+	}
+	switch b.Status {
+	case models.ImportQueued:
+		return nil, fmt.Errorf("import is already being committed")
+	case models.ImportCompleted:
+		return nil, fmt.Errorf("import has already been committed")
+	}
+	if v, ok := p.Args["mode"].(string); ok && v != "" {
+		if !models.IsImportMode(v) {
+			return nil, fmt.Errorf("mode must be insert, upsert or skip")
+		}
+		b.Mode = v
+	}
+	if list, ok := p.Args["mapping"].([]interface{}); ok {
+		b.Mapping = make([]models.ImportColumn, 0, len(list))
+		for _, item := range list {
+			m := item.(map[string]interface{})
+			b.Mapping = append(b.Mapping, models.ImportColumn{Field: m["field"].(string), Column: m["column"].(string)})
+		}
+	}
+	return b, nil
+}
+
+// CommitImport commits a batch queued by commitImport. It is run by the
+// import worker; a batch that is no longer queued was handled already and is
+// left alone. The outcome is recorded on the batch, so only a batch that
+// cannot be loaded is reported as an error.
+func (r *Resolver) CommitImport(ctx context.Context, tenantID, batchID uuid.UUID) error {
+	b, err := r.ImportRepo.GetByID(ctx, tenantID, batchID)
+	if errors.Is(err, repository.ErrImportNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if b.Status != models.ImportQueued {
+		return nil
+	}
+	return r.commitImport(ctx, tenantID, b)
+}
+
+// commitImport plans a claimed batch and, when every row is valid, writes it.
+// The batch ends completed or failed.
+func (r *Resolver) commitImport(ctx context.Context, tenantID uuid.UUID, b *models.ImportBatch) error {
+	plan, err := r.planImport(ctx, tenantID, b)
+	if err != nil {
+		r.failImport(ctx, b, err.Error())
+		return nil
+	}
+	plan.apply(b)
+	if plan.failed > 0 {
+		r.failImport(ctx, b, fmt.Sprintf("%d of %d rows are invalid; nothing was imported", plan.failed, b.TotalRows))
+		return nil
+	}
+
+	var f models.TrackingFormat
+	if b.Entity == models.ImportShipments {
+		if f, err = r.SettingRepo.TrackingFormat(ctx, tenantID); err != nil {
+			r.failImport(ctx, b, err.Error())
+			return nil
+		}
+	}
+	note := "Imported from " + b.Filename
+	ev := &models.ShipmentEvent{ActorType: "system", Note: &note}
+	if b.CreatedBy != nil {
+		ev.ActorType = "user"
+		ev.ActorID = b.CreatedBy
+	}
+
+	created, updated, err := r.ImportRepo.Apply(ctx, tenantID, plan.records, f, ev)
+	if err != nil {
+		log.Printf("import %s: %v", b.ID, err)
+		r.failImport(ctx, b, err.Error())
+		return nil
+	}
+	now := time.Now()
+	b.Created, b.Updated = created, updated
+	b.Status = models.ImportCompleted
+	b.CompletedAt = &now
+	return r.ImportRepo.Save(ctx, b)
+}
+
+// failImport records that a batch imported nothing, and why.
+func (r *Resolver) failImport(ctx context.Context, b *models.ImportBatch, reason string) {
+	b.Status = models.ImportFailed
+	b.Error = &reason
+	if err := r.ImportRepo.Save(ctx, b); err != nil {
+		log.Printf("import %s: failed to record failure: %v", b.ID, err)
+	}
+}
+
+// importPlan is the outcome of checking an import file row by row.
+type importPlan struct {
+	records                   []models.ImportRecord
+	created, updated, skipped int
+	failed                    int
+	errors                    []models.ImportRowError
+}
+
+func (pl *importPlan) reject(row int, field, msg string) {
+	if len(pl.errors) < models.ImportMaxErrors {
+		pl.errors = append(pl.errors, models.ImportRowError{Row: row, Field: field, Message: msg})
+	}
+}
+
+// apply copies the plan's counts and errors onto b.
+func (pl *importPlan) apply(b *models.ImportBatch) {
+	b.Created, b.Updated, b.Skipped, b.Failed = pl.created, pl.updated, pl.skipped, pl.failed
+	b.Errors = pl.errors
+	b.Error = nil
+	b.CompletedAt = nil
+}
+
+// readImportFile parses the file uploaded for b.
+func (r *Resolver) readImportFile(ctx context.Context, b *models.ImportBatch) (*imports.Table, error) {
+	if r.Storage == nil {
+		return nil, fmt.Errorf("file storage is not configured")
+	}
+	rc, err := r.Storage.Open(ctx, b.StorageKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open import file: %w", err)
+	}
+	defer rc.Close()
+	data, err := io.ReadAll(io.LimitReader(rc, models.ImportMaxFileBytes+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read import file: %w", err)
+	}
+	return imports.Parse(b.Filename, data)
+}
+
+// planImport checks every row of b's file against its mapping and mode. Each
+// row becomes the input a create mutation would receive and goes through the
+// same apply*Input validation, onto a new record or, when upserting, onto the
+// existing record with the same key. The returned error is for the batch as a
+// whole, such as a mapping that leaves out a required field.
+func (r *Resolver) planImport(ctx context.Context, tenantID uuid.UUID, b *models.ImportBatch) (*importPlan, error) {
+	spec, ok := imports.SpecFor(b.Entity)
+	if !ok {
+		return nil, fmt.Errorf("unknown import entity %q", b.Entity)
+	}
+	table, err := r.readImportFile(ctx, b)
+	if err != nil {
+		return nil, err
+	}
+	cols, err := spec.Columns(table.Header, b.Mapping)
+	if err != nil {
+		return nil, err
+	}
+
+	// Convert every row first so keys and references can be looked up in one
+	// query each.
+	inputs := make([]map[string]interface{}, len(table.Rows))
+	rowErrs := make([][]models.ImportRowError, len(table.Rows))
+	key := spec.Key()
+	var keys []string
+	refs := map[string][]string{}
+	for i, row := range table.Rows {
+		inputs[i], rowErrs[i] = spec.Input(row, cols)
+		if k, _ := inputs[i][key.Name].(string); k != "" {
+			keys = append(keys, k)
+		}
+		for _, f := range spec.Fields {
+			if v, _ := inputs[i][f.Name].(string); f.Ref != "" && v != "" {
+				if _, err := uuid.Parse(v); err != nil {
+					refs[f.Ref] = append(refs[f.Ref], v)
+				}
+			}
+		}
+	}
+	existing, err := r.ImportRepo.KeyIDs(ctx, tenantID, b.Entity, keys)
+	if err != nil {
+		return nil, err
+	}
+	refIDs := map[string]map[string]uuid.UUID{}
+	for entity, values := range refs {
+		if refIDs[entity], err = r.ImportRepo.KeyIDs(ctx, tenantID, entity, values); err != nil {
+			return nil, err
+		}
+	}
+
+	plan := &importPlan{}
+	seen := map[string]int{}
+	for i, row := range table.Rows {
+		input := inputs[i]
+		for _, e := range rowErrs[i] {
+			plan.reject(e.Row, e.Field, e.Message)
+		}
+		failed := len(rowErrs[i]) > 0
+
+		for _, f := range spec.Fields {
+			if f.Ref == "" {
+				continue
+			}
+			if msg := resolveImportRef(input, f, refIDs[f.Ref]); msg != "" {
+				plan.reject(row.Line, f.Name, msg)
+				failed = true
+			}
+		}
+		k, _ := input[key.Name].(string)
+		lookup := importLookupKey(b.Entity, k, input)
+		if k != "" {
+			if first, dup := seen[lookup]; dup {
+				plan.reject(row.Line, key.Name, fmt.Sprintf("%q is also on row %d", k, first))
+				failed = true
+			} else {
+				seen[lookup] = row.Line
+			}
+		}
+		if failed {
+			plan.failed++
+			continue
+		}
+
+		id, exists := existing[lookup]
+		if exists {
+			switch b.Mode {
+			case models.ImportInsert:
+				plan.reject(row.Line, key.Name, fmt.Sprintf("%q already exists; choose upsert or skip to import it", k))
+				plan.failed++
+				continue
+			case models.ImportSkip:
+				plan.skipped++
+				continue
+			}
+		}
+
+		var existingID *uuid.UUID
+		if exists {
+			existingID = &id
+		}
+		v, err := r.importValue(ctx, tenantID, b.Entity, existingID, input)
+		if err != nil {
+			plan.reject(row.Line, "", err.Error())
+			plan.failed++
+			continue
+		}
+		plan.records = append(plan.records, models.ImportRecord{Row: row.Line, Existing: exists, Value: v})
+		if exists {
+			plan.updated++
+		} else {
+			plan.created++
+		}
+	}
+	return plan, nil
+}
+
+// importLookupKey is what a row with key k is matched on among the existing
+// records and the file's other rows: k itself, or for inventory, stocked
+// once per warehouse, k in the row's warehouse.
+func importLookupKey(entity, k string, input map[string]interface{}) string {
+	if entity != models.ImportInventory || k == "" {
+		return k
+	}
+	v, _ := input["warehouseId"].(string)
+	if id, err := uuid.Parse(v); err == nil {
+		return models.InventoryImportKey(k, id)
+	}
+	return k
+}
+
+// resolveImportRef replaces a reference cell with the id of the record it
+// names, under the field's input key. A cell holding an id is passed on
+// as it is; the apply*Input function still checks it belongs to the tenant.
+func resolveImportRef(input map[string]interface{}, f imports.Field, ids map[string]uuid.UUID) string {
+	v, ok := input[f.Name].(string)
+	if !ok {
+		return ""
+	}
+	delete(input, f.Name)
+	if _, err := uuid.Parse(v); err == nil {
+		input[f.Input] = v
+		return ""
+	}
+	lookup := v
+	if f.Ref == models.ImportWarehouses {
+		lookup = strings.ToLower(v)
+	}
+	id, found := ids[lookup]
+	switch {
+	case !found:
+		return fmt.Sprintf("no %s %q", strings.TrimSuffix(f.Ref, "s"), v)
+	case id == uuid.Nil:
+		return fmt.Sprintf("several %s are named %q", f.Ref, v)
+	}
+	input[f.Input] = id.String()
+	return ""
+}
+
+// importValue builds the record a row writes: the existing record with id,
+// or a new one with the same defaults as the create mutation and database,
+// with the row's input applied.
+func (r *Resolver) importValue(ctx context.Context, tenantID uuid.UUID, entity string, id *uuid.UUID, input map[string]interface{}) (interface{}, error) {
+	switch entity {
+	case models.ImportShipments:
+		s := &models.Shipment{TenantID: tenantID, Status: models.ShipmentPending}
+		if id != nil {
+			var err error
+			if s, err = r.ShipmentRepo.GetByID(ctx, tenantID, *id); err != nil {
+				return nil, err
+			}
+			// Status moves only through updateShipmentStatus, which keeps
+			// the timeline.
+			if v, _ := input["status"].(string); v != "" && v != s.Status {
+				return nil, fmt.Errorf("status of an existing shipment cannot be changed by import (it is %s)", s.Status)
+			}
+			delete(input, "status")
+		}
+		return s, r.applyShipmentInput(ctx, tenantID, s, input)
+	case models.ImportOrders:
+		o := &models.Order{TenantID: tenantID, Status: "pending", Type: "standard"}
+		if id != nil {
+			var err error
+			if o, err = r.OrderRepo.GetByID(ctx, tenantID, *id); err != nil {
+				return nil, err
+			}
+		}
+		return o, r.applyOrderInput(ctx, tenantID, o, input)
+	case models.ImportInventory:
+		item := &models.InventoryItem{TenantID: tenantID, Status: "in_stock"}
+		if id != nil {
+			var err error
+			if item, err = r.InventoryRepo.GetByID(ctx, tenantID, *id); err != nil {
+				return nil, err
+			}
+		}
+		return item, r.applyInventoryInput(ctx, tenantID, item, input)
+	case models.ImportDrivers:
+		d := &models.Driver{TenantID: tenantID, Status: "available"}
+		if id != nil {
+			var err error
+			if d, err = r.DriverRepo.GetByID(ctx, tenantID, *id); err != nil {
+				return nil, err
+			}
+		}
+		return d, r.applyDriverInput(ctx, tenantID, d, input)
+	case models.ImportVehicles:
+		v := &models.Vehicle{TenantID: tenantID, Status: "available", FuelLevel: 100}
+		if id != nil {
+			var err error
+			if v, err = r.VehicleRepo.GetByID(ctx, tenantID, *id); err != nil {
+				return nil, err
+			}
+		}
+		return v, applyVehicleInput(v, input)
+	}
+	return nil, fmt.Errorf("unknown import entity %q", entity)
+}
@@ -0,0 +1,224 @@
+package resolvers
+
+import (
+	"fmt"
+	"time"
+
+	"cargomax-api/internal/graph/types"
+	"cargomax-api/internal/models"
+
+	"github.com/google/uuid"
+	"github.com/graphql-go/graphql"
+)
+
+// InvoiceDateLayout is the format of invoice date arguments and fields.
+const InvoiceDateLayout = "2006-01-02"
+
+// InvoiceQueries returns GraphQL query fields for invoices.
+func (r *Resolver) InvoiceQueries() graphql.Fields {
+	return graphql.Fields{
+		"invoices": &graphql.Field{
+			Type:        types.InvoiceConnectionType,
+			Description: "Invoices and credit notes, the most recently issued first.",
+			Args: graphql.FieldConfigArgument{
+				"page":     &graphql.ArgumentConfig{Type: graphql.Int, DefaultValue: 1},
+				"perPage":  &graphql.ArgumentConfig{Type: graphql.Int, DefaultValue: 20},
+				"clientId": &graphql.ArgumentConfig{Type: graphql.String},
+				"kind":     &graphql.ArgumentConfig{Type: graphql.String, Description: "invoice or credit_note."},
+				"status":   &graphql.ArgumentConfig{Type: graphql.String},
+			},
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				tenantID, err := requireTenant(p.Context)
+				if err != nil {
+					return nil, err
+				}
+				page, perPage := clampPagination(p.Args["page"].(int), p.Args["perPage"].(int))
+
+				var f models.InvoiceFilter
+				if f.ClientID, err = r.clientArg(p.Context, tenantID, p.Args); err != nil {
+					return nil, err
+				}
+				f.Kind, _ = p.Args["kind"].(string)
+				f.Status, _ = p.Args["status"].(string)
+
+				items, total, err := r.InvoiceRepo.List(p.Context, tenantID, f, page, perPage)
+				if err != nil {
+					return nil, err
+				}
+
+				totalPages := 0
+				if perPage > 0 {
+					totalPages = (total + perPage - 1) / perPage
+				}
+				return map[string]interface{}{
+					"items":      items,
+					"totalCount": total,
+					"page":       page,
+					"perPage":    perPage,
+					"totalPages": totalPages,
+				}, nil
+			},
+		},
+		"invoice": &graphql.Field{
+			Type: types.InvoiceType,
+			Args: graphql.FieldConfigArgument{
+				"id": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.String)},
+			},
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				tenantID, err := requireTenant(p.Context)
+				if err != nil {
+					return nil, err
+				}
+				id, err := uuid.Parse(p.Args["id"].(string))
+				if err != nil {
+					return nil, fmt.Errorf("invalid invoice id: %w", err)
+				}
+				return r.InvoiceRepo.GetByID(p.Context, tenantID, id)
+			},
+		},
+	}
+}
+
+// InvoiceMutations returns GraphQL mutation fields for issuing invoices and
+// credit notes and recording payments.
+func (r *Resolver) InvoiceMutations() graphql.Fields {
+	return graphql.Fields{
+		"generateInvoices": &graphql.Field{
+			Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(types.InvoiceType))),
+			Description: "Bill the orders delivered from periodStart to periodEnd (inclusive, YYYY-MM-DD) that are not " +
+				"yet invoiced: one invoice per client, or only the given client's. Returns the invoices issued.",
+			Args: graphql.FieldConfigArgument{
+				"periodStart": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.String)},
+				"periodEnd":   &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.String)},
+				"clientId":    &graphql.ArgumentConfig{Type: graphql.String},
+			},
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				tenantID, userID, err := requireAuth(p.Context)
+				if err != nil {
+					return nil, err
+				}
+				run := models.InvoiceRun{IssueDate: time.Now().UTC().Truncate(24 * time.Hour), CreatedBy: &userID}
+				if run.PeriodStart, err = time.Parse(InvoiceDateLayout, p.Args["periodStart"].(string)); err != nil {
+					return nil, fmt.Errorf("periodStart must be a date (YYYY-MM-DD)")
+				}
+				if run.PeriodEnd, err = time.Parse(InvoiceDateLayout, p.Args["periodEnd"].(string)); err != nil {
+					return nil, fmt.Errorf("periodEnd must be a date (YYYY-MM-DD)")
+				}
+				if run.PeriodEnd.Before(run.PeriodStart) {
+					return nil, fmt.Errorf("periodEnd cannot be before periodStart")
+				}
+				if run.ClientID, err = r.clientArg(p.Context, tenantID, p.Args); err != nil {
+					return nil, err
+				}
+				settings, err := r.SettingRepo.InvoiceSettings(p.Context, tenantID)
+				if err != nil {
+					return nil, err
+				}
+				return r.InvoiceRepo.Generate(p.Context, tenantID, run, settings)
+			},
+		},
+		"recordPayment": &graphql.Field{
+			Type:        types.InvoiceType,
+			Description: "Record money received against an invoice, up to its balance due. It is added to the client's totalSpent.",
+			Args: graphql.FieldConfigArgument{
+				"invoiceId": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.String)},
+				"amount":    &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.Float)},
+				"method":    &graphql.ArgumentConfig{Type: graphql.String, Description: "How it was paid, e.g. bank_transfer or card."},
+				"reference": &graphql.ArgumentConfig{Type: graphql.String},
+				"paidAt":    &graphql.ArgumentConfig{Type: graphql.String, Description: "RFC 3339; defaults to now."},
+			},
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				tenantID, userID, err := requireAuth(p.Context)
+				if err != nil {
+					return nil, err
+				}
+				invoiceID, err := uuid.Parse(p.Args["invoiceId"].(string))
+				if err != nil {
+					return nil, fmt.Errorf("invalid invoice id: %w", err)
+				}
+				pay := &models.Payment{Amount: models.RoundMoney(p.Args["amount"].(float64)), PaidAt: time.Now(), CreatedBy: &userID}
+				if v, ok := p.Args["method"].(string); ok && v != "" {
+					pay.Method = &v
+				}
+				if v, ok := p.Args["reference"].(string); ok && v != "" {
+					pay.Reference = &v
+				}
+				if v, ok := p.Args["paidAt"].(string); ok {
+					if pay.PaidAt, err = time.Parse(time.RFC3339, v); err != nil {
+						return nil, fmt.Errorf("paidAt must be an RFC 3339 time")
+					}
+				}
+				return r.InvoiceRepo.RecordPayment(p.Context, tenantID, invoiceID, pay)
+			},
+		},
+		"createCreditNote": &graphql.Field{
+			Type: types.InvoiceType,
+			Description: "Issue a credit note against an invoice, crediting its returned orders in full and any amount " +
+				"besides, before tax. Tax is credited at the invoice's rates.",
+			Args: graphql.FieldConfigArgument{
+				"invoiceId": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.String)},
+				"reason":    &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.String)},
+				"orderIds":  &graphql.ArgumentConfig{Type: graphql.NewList(graphql.NewNonNull(graphql.String)), Description: "Returned orders billed on the invoice."},
+				"amount":    &graphql.ArgumentConfig{Type: graphql.Float},
+			},
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				tenantID, userID, err := requireAuth(p.Context)
+				if err != nil {
+					return nil, err
+				}
+				cn := models.CreditNoteRequest{IssueDate: time.Now().UTC().Truncate(24 * time.Hour), CreatedBy: &userID}
+				if cn.InvoiceID, err = uuid.Parse(p.Args["invoiceId"].(string)); err != nil {
+					return nil, fmt.Errorf("invalid invoice id: %w", err)
+				}
+				cn.Reason = p.Args["reason"].(string)
+				if cn.Reason == "" {
+					return nil, fmt.Errorf("a credit note needs a reason")
+				}
+				for _, v := range stringList(p.Args["orderIds"]) {
+					id, err := uuid.Parse(v)
+					if err != nil {
+						return nil, fmt.Errorf("invalid order id: %w", err)
+					}
+					cn.OrderIDs = append(cn.OrderIDs, id)
+				}
+				if v, ok := p.Args["amount"].(float64); ok {
+					if v < 0 {
+						return nil, fmt.Errorf("amount cannot be negative")
+					}
+					cn.Amount = models.RoundMoney(v)
+				}
+				if len(cn.OrderIDs) == 0 && cn.Amount == 0 {
+					return nil, fmt.Errorf("a credit note needs orders or an amount to credit")
+				}
+				settings, err := r.SettingRepo.InvoiceSettings(p.Context, tenantID)
+				if err != nil {
+					return nil, err
+				}
+				return r.InvoiceRepo.CreateCreditNote(p.Context, tenantID, cn, settings)
+			},
+		},
+		"voidInvoice": &graphql.Field{
+			Type:        types.InvoiceType,
+			Description: "Void an invoice nothing has been paid or credited on. It keeps its number and its orders can be billed again.",
+			Args: graphql.FieldConfigArgument{
+				"id":     &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.String)},
+				"reason": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.String)},
+			},
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				tenantID, err := requireTenant(p.Context)
+				if err != nil {
+					return nil, err
+				}
+				id, err := uuid.Parse(p.Args["id"].(string))
+				if err != nil {
+					return nil, fmt.Errorf("invalid invoice id: %w", err)
+				}
+				reason := p.Args["reason"].(string)
+				if reason == "" {
+					return nil, fmt.Errorf("voiding an invoice needs a reason")
+				}
+				return r.InvoiceRepo.Void(p.Context, tenantID, id, reason)
+			},
+		},
+	}
+}
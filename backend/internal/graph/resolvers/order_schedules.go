@@ -0,0 +1,236 @@
+package resolvers
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"cargomax-api/internal/graph/types"
+	"cargomax-api/internal/models"
+
+	"github.com/google/uuid"
+	"github.com/graphql-go/graphql"
+)
+
+// OrderScheduleQueries returns GraphQL query fields for standing orders.
+func (r *Resolver) OrderScheduleQueries() graphql.Fields {
+	return graphql.Fields{
+		"orderSchedules": &graphql.Field{
+			Type:        graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(types.OrderScheduleType))),
+			Description: "The tenant's standing orders by name, or one client's.",
+			Args: graphql.FieldConfigArgument{
+				"clientId": &graphql.ArgumentConfig{Type: graphql.String},
+			},
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				tenantID, err := requireTenant(p.Context)
+				if err != nil {
+					return nil, err
+				}
+				var clientID *uuid.UUID
+				if v, ok := p.Args["clientId"].(string); ok {
+					id, err := uuid.Parse(v)
+					if err != nil {
+						return nil, fmt.Errorf("invalid client id: %w", err)
+					}
+					clientID = &id
+				}
+				return r.OrderScheduleRepo.List(p.Context, tenantID, clientID)
+			},
+		},
+		"orderSchedule": &graphql.Field{
+			Type: types.OrderScheduleType,
+			Args: graphql.FieldConfigArgument{
+				"id": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.String)},
+			},
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				tenantID, err := requireTenant(p.Context)
+				if err != nil {
+					return nil, err
+				}
+				id, err := uuid.Parse(p.Args["id"].(string))
+				if err != nil {
+					return nil, fmt.Errorf("invalid standing order id: %w", err)
+				}
+				return r.OrderScheduleRepo.GetByID(p.Context, tenantID, id)
+			},
+		},
+	}
+}
+
+// OrderScheduleMutations returns GraphQL mutation fields that manage
+// standing orders. Their orders are placed by the order schedule worker.
+func (r *Resolver) OrderScheduleMutations() graphql.Fields {
+	return graphql.Fields{
+		"createOrderSchedule": &graphql.Field{
+			Type: types.OrderScheduleType,
+			Description: "Add a standing order. Each occurrence's order is placed leadDays ahead as a scheduled order, " +
+				"and confirmed, reserving its stock, on the day.",
+			Args: graphql.FieldConfigArgument{
+				"input": &graphql.ArgumentConfig{Type: graphql.NewNonNull(types.OrderScheduleInputType)},
+			},
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				tenantID, userID, err := requireAuth(p.Context)
+				if err != nil {
+					return nil, err
+				}
+				s := &models.OrderSchedule{TenantID: tenantID, OrderType: "standard", Every: 1, Active: true, CreatedBy: &userID}
+				if err := r.applyOrderScheduleInput(p.Context, tenantID, s, p.Args["input"].(map[string]interface{})); err != nil {
+					return nil, err
+				}
+				if err := r.OrderScheduleRepo.Create(p.Context, s); err != nil {
+					return nil, err
+				}
+				return s, nil
+			},
+		},
+		"updateOrderSchedule": &graphql.Field{
+			Type: types.OrderScheduleType,
+			Description: "Change a standing order; optional fields left out are kept. Orders already placed are left as they are, " +
+				"and the next one follows the new schedule.",
+			Args: graphql.FieldConfigArgument{
+				"id":    &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.String)},
+				"input": &graphql.ArgumentConfig{Type: graphql.NewNonNull(types.OrderScheduleInputType)},
+			},
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				tenantID, err := requireTenant(p.Context)
+				if err != nil {
+					return nil, err
+				}
+				id, err := uuid.Parse(p.Args["id"].(string))
+				if err != nil {
+					return nil, fmt.Errorf("invalid standing order id: %w", err)
+				}
+				s, err := r.OrderScheduleRepo.GetByID(p.Context, tenantID, id)
+				if err != nil {
+					return nil, err
+				}
+				if err := r.applyOrderScheduleInput(p.Context, tenantID, s, p.Args["input"].(map[string]interface{})); err != nil {
+					return nil, err
+				}
+				if err := r.OrderScheduleRepo.Update(p.Context, tenantID, id, s); err != nil {
+					return nil, err
+				}
+				return r.OrderScheduleRepo.GetByID(p.Context, tenantID, id)
+			},
+		},
+		"deleteOrderSchedule": &graphql.Field{
+			Type:        graphql.Boolean,
+			Description: "Remove a standing order. Orders it placed are kept.",
+			Args: graphql.FieldConfigArgument{
+				"id": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.String)},
+			},
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				tenantID, err := requireTenant(p.Context)
+				if err != nil {
+					return nil, err
+				}
+				id, err := uuid.Parse(p.Args["id"].(string))
+				if err != nil {
+					return nil, fmt.Errorf("invalid standing order id: %w", err)
+				}
+				if err := r.OrderScheduleRepo.Delete(p.Context, tenantID, id); err != nil {
+					return nil, err
+				}
+				return true, nil
+			},
+		},
+	}
+}
+
+// applyOrderScheduleInput sets the fields given in an OrderScheduleInput on
+// s, resolving its lines against the tenant's inventory, and validates the
+// result.
+func (r *Resolver) applyOrderScheduleInput(ctx context.Context, tenantID uuid.UUID, s *models.OrderSchedule, input map[string]interface{}) error {
+	s.Name, _ = input["name"].(string)
+	s.OrderPrefix = strings.TrimSpace(input["orderPrefix"].(string))
+	if _, ok := input["clientId"]; ok {
+		clientID, err := r.clientArg(ctx, tenantID, input)
+		if err != nil {
+			return err
+		}
+		s.ClientID = clientID
+	}
+	if v, ok := input["customerName"].(string); ok {
+		s.CustomerName = &v
+	}
+	if v, ok := input["customerEmail"].(string); ok {
+		s.CustomerEmail = &v
+	}
+	if v, ok := input["orderType"].(string); ok {
+		s.OrderType = v
+	}
+	s.Frequency, _ = input["frequency"].(string)
+	if v, ok := input["every"].(int); ok {
+		s.Every = v
+	}
+	if v, ok := input["leadDays"].(int); ok {
+		s.LeadDays = v
+	}
+	if v, ok := input["autoFulfill"].(bool); ok {
+		s.AutoFulfill = v
+	}
+	if v, ok := input["active"].(bool); ok {
+		s.Active = v
+	}
+
+	start, err := time.Parse(models.ScheduleDateLayout, input["startDate"].(string))
+	if err != nil {
+		return fmt.Errorf("invalid startDate, want YYYY-MM-DD: %w", err)
+	}
+	s.StartDate = start
+	if v, ok := input["endDate"].(string); ok {
+		s.EndDate = nil
+		if v != "" {
+			end, err := time.Parse(models.ScheduleDateLayout, v)
+			if err != nil {
+				return fmt.Errorf("invalid endDate, want YYYY-MM-DD: %w", err)
+			}
+			s.EndDate = &end
+		}
+	}
+	if _, ok := input["skipDates"]; ok {
+		s.SkipDates = []time.Time{}
+		for _, v := range stringList(input["skipDates"]) {
+			d, err := time.Parse(models.ScheduleDateLayout, v)
+			if err != nil {
+				return fmt.Errorf("invalid skip date %q, want YYYY-MM-DD", v)
+			}
+			s.SkipDates = append(s.SkipDates, d)
+		}
+	}
+	if s.SkipDates == nil {
+		s.SkipDates = []time.Time{}
+	}
+
+	list, _ := input["lines"].([]interface{})
+	s.Lines = make([]models.ScheduleLine, 0, len(list))
+	for _, v := range list {
+		in, _ := v.(map[string]interface{})
+		sku, _ := in["sku"].(string)
+		quantity, _ := in["quantity"].(int)
+		if quantity <= 0 {
+			return fmt.Errorf("the quantity of %s must be positive", sku)
+		}
+		warehouseID, err := optionalID(in, "warehouseId", "warehouse")
+		if err != nil {
+			return err
+		}
+		item, err := r.InventoryRepo.GetBySKU(ctx, tenantID, sku, warehouseID)
+		if err != nil && warehouseID != nil {
+			return fmt.Errorf("%s is not stocked in warehouse %s", sku, warehouseID)
+		}
+		if err != nil {
+			return fmt.Errorf("inventory item %s not found", sku)
+		}
+		l := models.ScheduleLine{InventoryItemID: item.ID, SKU: item.SKU, WarehouseID: item.WarehouseID, Quantity: quantity}
+		if price, ok := in["unitPrice"].(float64); ok {
+			if price < 0 {
+				return fmt.Errorf("the unit price of %s cannot be negative", sku)
+			}
+			l.UnitPrice = &price
+		}
+		s.Lines = append(s.Lines, l)
+	}
+	return s.Validate()
+}
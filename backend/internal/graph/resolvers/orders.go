@@ -1,11 +1,14 @@
 package resolvers
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"time"
 
 	"cargomax-api/internal/graph/types"
 	"cargomax-api/internal/models"
+	"cargomax-api/internal/pricing"
 
 	"github.com/google/uuid"
 	"github.com/graphql-go/graphql"
@@ -116,9 +119,12 @@ func (r *Resolver) OrderQueries() graphql.Fields {
 		},
 		"returnOrders": &graphql.Field{
 			Type: types.OrderConnectionType,
+			Description: "Orders with returns: those marked returned and those with an RMA, whatever its state. " +
+				"rmaStatus lists only orders with an RMA in that status.",
 			Args: graphql.FieldConfigArgument{
-				"page":    &graphql.ArgumentConfig{Type: graphql.Int, DefaultValue: 1},
-				"perPage": &graphql.ArgumentConfig{Type: graphql.Int, DefaultValue: 20},
+				"rmaStatus": &graphql.ArgumentConfig{Type: graphql.String},
+				"page":      &graphql.ArgumentConfig{Type: graphql.Int, DefaultValue: 1},
+				"perPage":   &graphql.ArgumentConfig{Type: graphql.Int, DefaultValue: 20},
 			},
 			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
 				tenantID, err := requireTenant(p.Context)
@@ -126,8 +132,9 @@ func (r *Resolver) OrderQueries() graphql.Fields {
 					return nil, err
 				}
 				page, perPage := clampPagination(p.Args["page"].(int), p.Args["perPage"].(int))
+				rmaStatus, _ := p.Args["rmaStatus"].(string)
 
-				items, total, err := r.OrderRepo.List(p.Context, tenantID, "returned", page, perPage)
+				items, total, err := r.OrderRepo.ListReturns(p.Context, tenantID, rmaStatus, page, perPage)
 				if err != nil {
 					return nil, err
 				}
@@ -198,38 +205,13 @@ func (r *Resolver) OrderMutations() graphql.Fields {
 					TenantID:    tenantID,
 					OrderNumber: input["orderNumber"].(string),
 				}
-				if v, ok := input["customerName"].(string); ok {
-					o.CustomerName = &v
-				}
-				if v, ok := input["customerEmail"].(string); ok {
-					o.CustomerEmail = &v
-				}
-				if v, ok := input["status"].(string); ok {
-					o.Status = v
-				}
-				if v, ok := input["type"].(string); ok {
-					o.Type = v
-				}
-				if v, ok := input["totalAmount"].(float64); ok {
-					o.TotalAmount = &v
-				}
-				if v, ok := input["shipmentId"].(string); ok {
-					sid, err := uuid.Parse(v)
-					if err != nil {
-						return nil, fmt.Errorf("invalid shipment id: %w", err)
-					}
-					// Validate the shipment belongs to the same tenant (prevent IDOR).
-					if _, err := r.ShipmentRepo.GetByID(p.Context, tenantID, sid); err != nil {
-						return nil, fmt.Errorf("shipment not found in tenant")
-					}
-					o.ShipmentID = &sid
+				if err := r.applyOrderInput(p.Context, tenantID, o, input); err != nil {
+					return nil, err
 				}
-				if v, ok := input["scheduledDate"].(string); ok {
-					t, err := time.Parse(time.RFC3339, v)
-					if err != nil {
-						return nil, fmt.Errorf("invalid scheduledDate format: %w", err)
+				if o.TotalAmount == nil && o.ShipmentID != nil {
+					if err := r.priceOrder(p.Context, tenantID, o, stringList(input["shippingOptions"])); err != nil {
+						return nil, err
 					}
-					o.ScheduledDate = &t
 				}
 
 				if err := r.OrderRepo.Create(p.Context, o); err != nil {
@@ -254,9 +236,20 @@ func (r *Resolver) OrderMutations() graphql.Fields {
 					return nil, fmt.Errorf("invalid order id: %w", err)
 				}
 				input := p.Args["input"].(map[string]interface{})
+				existing, err := r.OrderRepo.GetByID(p.Context, tenantID, id)
+				if err != nil {
+					return nil, err
+				}
 
 				o := &models.Order{
 					OrderNumber: input["orderNumber"].(string),
+					ClientID:    existing.ClientID,
+					Quote:       existing.Quote,
+				}
+				if _, ok := input["clientId"]; ok {
+					if o.ClientID, err = r.clientArg(p.Context, tenantID, input); err != nil {
+						return nil, err
+					}
 				}
 				if v, ok := input["customerName"].(string); ok {
 					o.CustomerName = &v
@@ -291,6 +284,9 @@ func (r *Resolver) OrderMutations() graphql.Fields {
 					}
 					o.ScheduledDate = &t
 				}
+				if o.Lines, err = r.orderLinesInput(p.Context, tenantID, input); err != nil {
+					return nil, err
+				}
 
 				if err := r.OrderRepo.Update(p.Context, tenantID, id, o); err != nil {
 					return nil, err
@@ -298,6 +294,27 @@ func (r *Resolver) OrderMutations() graphql.Fields {
 				return r.OrderRepo.GetByID(p.Context, tenantID, id)
 			},
 		},
+		"confirmOrder": &graphql.Field{
+			Type:        types.OrderType,
+			Description: "Move a scheduled order to pending, reserving its stock. Fails, leaving it scheduled, when there is not enough.",
+			Args: graphql.FieldConfigArgument{
+				"id": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.String)},
+			},
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				tenantID, err := requireTenant(p.Context)
+				if err != nil {
+					return nil, err
+				}
+				id, err := uuid.Parse(p.Args["id"].(string))
+				if err != nil {
+					return nil, fmt.Errorf("invalid order id: %w", err)
+				}
+				if err := r.OrderRepo.Confirm(p.Context, tenantID, id); err != nil {
+					return nil, err
+				}
+				return r.OrderRepo.GetByID(p.Context, tenantID, id)
+			},
+		},
 		"cancelOrder": &graphql.Field{
 			Type: types.OrderType,
 			Args: graphql.FieldConfigArgument{
@@ -346,3 +363,133 @@ func (r *Resolver) OrderMutations() graphql.Fields {
 		},
 	}
 }
+
+// applyOrderInput sets the fields given in an OrderInput on o, checking that
+// a linked shipment belongs to the tenant. It is shared by createOrder and
+// bulk import.
+func (r *Resolver) applyOrderInput(ctx context.Context, tenantID uuid.UUID, o *models.Order, input map[string]interface{}) error {
+	if v, ok := input["orderNumber"].(string); ok {
+		o.OrderNumber = v
+	}
+	if v, ok := input["customerName"].(string); ok {
+		o.CustomerName = &v
+	}
+	if v, ok := input["customerEmail"].(string); ok {
+		o.CustomerEmail = &v
+	}
+	if v, ok := input["status"].(string); ok {
+		o.Status = v
+	}
+	if v, ok := input["type"].(string); ok {
+		o.Type = v
+	}
+	if v, ok := input["totalAmount"].(float64); ok {
+		o.TotalAmount = &v
+	}
+	if v, ok := input["shipmentId"].(string); ok {
+		sid, err := uuid.Parse(v)
+		if err != nil {
+			return fmt.Errorf("invalid shipment id: %w", err)
+		}
+		// Validate the shipment belongs to the same tenant (prevent IDOR).
+		if _, err := r.ShipmentRepo.GetByID(ctx, tenantID, sid); err != nil {
+			return fmt.Errorf("shipment not found in tenant")
+		}
+		o.ShipmentID = &sid
+	}
+	if v, ok := input["scheduledDate"].(string); ok {
+		t, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			return fmt.Errorf("invalid scheduledDate format: %w", err)
+		}
+		o.ScheduledDate = &t
+	}
+	if _, ok := input["clientId"]; ok {
+		clientID, err := r.clientArg(ctx, tenantID, input)
+		if err != nil {
+			return err
+		}
+		o.ClientID = clientID
+	}
+	lines, err := r.orderLinesInput(ctx, tenantID, input)
+	if err != nil {
+		return err
+	}
+	o.Lines = lines
+	return nil
+}
+
+// orderLinesInput resolves an OrderInput's lines against the tenant's
+// inventory, pricing them at the item's unit price unless one is given. A
+// line without a warehouse is taken from the one with the most of its SKU
+// available. It
+// returns nil when the input has no lines, which leaves an order's lines as
+// they are.
+func (r *Resolver) orderLinesInput(ctx context.Context, tenantID uuid.UUID, input map[string]interface{}) ([]models.OrderLine, error) {
+	list, ok := input["lines"].([]interface{})
+	if !ok {
+		return nil, nil
+	}
+	if _, ok := input["totalAmount"].(float64); ok {
+		return nil, fmt.Errorf("an order with lines is totalled from them; omit totalAmount")
+	}
+	lines := make([]models.OrderLine, 0, len(list))
+	for _, v := range list {
+		in, _ := v.(map[string]interface{})
+		sku, _ := in["sku"].(string)
+		quantity, _ := in["quantity"].(int)
+		if quantity <= 0 {
+			return nil, fmt.Errorf("the quantity of %s must be positive", sku)
+		}
+		warehouseID, err := optionalID(in, "warehouseId", "warehouse")
+		if err != nil {
+			return nil, err
+		}
+		item, err := r.InventoryRepo.GetBySKU(ctx, tenantID, sku, warehouseID)
+		if err != nil && warehouseID != nil {
+			return nil, fmt.Errorf("%s is not stocked in warehouse %s", sku, warehouseID)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("inventory item %s not found", sku)
+		}
+		l := models.OrderLine{
+			InventoryItemID: &item.ID,
+			SKU:             item.SKU,
+			WarehouseID:     &item.WarehouseID,
+			Description:     item.Name,
+			Quantity:        quantity,
+		}
+		if item.UnitPrice != nil {
+			l.UnitPrice = *item.UnitPrice
+		}
+		if price, ok := in["unitPrice"].(float64); ok {
+			if price < 0 {
+				return nil, fmt.Errorf("the unit price of %s cannot be negative", sku)
+			}
+			l.UnitPrice = price
+		}
+		l.LineTotal = models.RoundMoney(float64(l.Quantity) * l.UnitPrice)
+		lines = append(lines, l)
+	}
+	return lines, nil
+}
+
+// priceOrder sets the total of an order without one from a quote for its
+// shipment. An order no rate card covers, or whose shipment's dimensions
+// cannot be read, is left without a total, as orders were before rate cards.
+func (r *Resolver) priceOrder(ctx context.Context, tenantID uuid.UUID, o *models.Order, options []string) error {
+	s, err := r.ShipmentRepo.GetByID(ctx, tenantID, *o.ShipmentID)
+	if err != nil {
+		return fmt.Errorf("shipment not found in tenant")
+	}
+	q, err := r.quoteShipment(ctx, tenantID, o.ClientID, s, options, nil)
+	if errors.Is(err, pricing.ErrNoRate) || errors.Is(err, pricing.ErrBadDimensions) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("%w; give the order a totalAmount", err)
+	}
+	o.TotalAmount = &q.Total
+	o.Quote = q
+	return nil
+}
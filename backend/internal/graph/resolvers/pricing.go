@@ -0,0 +1,335 @@
+package resolvers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"cargomax-api/internal/graph/types"
+	"cargomax-api/internal/models"
+	"cargomax-api/internal/pricing"
+	"cargomax-api/internal/utils"
+
+	"github.com/google/uuid"
+	"github.com/graphql-go/graphql"
+)
+
+// PricingQueries returns GraphQL query fields for rate cards and quotes.
+func (r *Resolver) PricingQueries() graphql.Fields {
+	return graphql.Fields{
+		"rateCards": &graphql.Field{
+			Type:        graphql.NewList(types.RateCardType),
+			Description: "The tenant's rate cards, default cards first.",
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				tenantID, err := requireTenant(p.Context)
+				if err != nil {
+					return nil, err
+				}
+				return r.RateCardRepo.List(p.Context, tenantID)
+			},
+		},
+		"rateCard": &graphql.Field{
+			Type: types.RateCardType,
+			Args: graphql.FieldConfigArgument{
+				"id": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.String)},
+			},
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				tenantID, err := requireTenant(p.Context)
+				if err != nil {
+					return nil, err
+				}
+				id, err := uuid.Parse(p.Args["id"].(string))
+				if err != nil {
+					return nil, fmt.Errorf("invalid rate card id: %w", err)
+				}
+				return r.RateCardRepo.GetByID(p.Context, tenantID, id)
+			},
+		},
+		"quoteShipment": &graphql.Field{
+			Type: types.QuoteType,
+			Description: "Price a shipment from the rate cards: an existing one by shipmentId, with any other " +
+				"argument overriding its details, or one described by the arguments alone. The client's card " +
+				"is tried before the tenant default.",
+			Args: graphql.FieldConfigArgument{
+				"shipmentId":         &graphql.ArgumentConfig{Type: graphql.String},
+				"clientId":           &graphql.ArgumentConfig{Type: graphql.String},
+				"warehouseId":        &graphql.ArgumentConfig{Type: graphql.String, Description: "Where it ships from; supplies the origin address and position."},
+				"originAddress":      &graphql.ArgumentConfig{Type: types.AddressInputType},
+				"destinationAddress": &graphql.ArgumentConfig{Type: types.AddressInputType},
+				"weight":             &graphql.ArgumentConfig{Type: graphql.Float, Description: "Actual weight in kilograms."},
+				"dimensions":         &graphql.ArgumentConfig{Type: graphql.String, Description: "Length x width x height, e.g. \"120x80x50 cm\"."},
+				"distanceKm":         &graphql.ArgumentConfig{Type: graphql.Float, Description: "Road distance; estimated from the positions when left out."},
+				"options":            &graphql.ArgumentConfig{Type: graphql.NewList(graphql.NewNonNull(graphql.String)), Description: "Conditional surcharges to add, e.g. residential or hazmat."},
+			},
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				tenantID, err := requireTenant(p.Context)
+				if err != nil {
+					return nil, err
+				}
+				s := &models.Shipment{TenantID: tenantID}
+				if v, ok := p.Args["shipmentId"].(string); ok {
+					id, err := uuid.Parse(v)
+					if err != nil {
+						return nil, fmt.Errorf("invalid shipment id: %w", err)
+					}
+					if s, err = r.ShipmentRepo.GetByID(p.Context, tenantID, id); err != nil {
+						return nil, fmt.Errorf("shipment not found")
+					}
+				}
+				if err := r.setShipmentAddresses(p.Context, s, p.Args); err != nil {
+					return nil, err
+				}
+				if err := r.setShipmentWarehouse(p.Context, tenantID, s, p.Args); err != nil {
+					return nil, err
+				}
+				if v, ok := p.Args["weight"].(float64); ok {
+					s.Weight = &v
+				}
+				if v, ok := p.Args["dimensions"].(string); ok {
+					s.Dimensions = &v
+				}
+				clientID, err := r.clientArg(p.Context, tenantID, p.Args)
+				if err != nil {
+					return nil, err
+				}
+				var distance *float64
+				if v, ok := p.Args["distanceKm"].(float64); ok {
+					if v < 0 {
+						return nil, fmt.Errorf("distanceKm cannot be negative")
+					}
+					distance = &v
+				}
+				return r.quoteShipment(p.Context, tenantID, clientID, s, stringList(p.Args["options"]), distance)
+			},
+		},
+	}
+}
+
+// PricingMutations returns GraphQL mutation fields for rate cards.
+func (r *Resolver) PricingMutations() graphql.Fields {
+	return graphql.Fields{
+		"createRateCard": &graphql.Field{
+			Type:        types.RateCardType,
+			Description: "Add a rate card. A card without a client is the tenant default; a tenant has one active default and one active card per client.",
+			Args: graphql.FieldConfigArgument{
+				"input": &graphql.ArgumentConfig{Type: graphql.NewNonNull(types.RateCardInputType)},
+			},
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				tenantID, err := requireTenant(p.Context)
+				if err != nil {
+					return nil, err
+				}
+				c := &models.RateCard{TenantID: tenantID, Active: true}
+				if err := r.applyRateCardInput(p.Context, tenantID, c, p.Args["input"].(map[string]interface{})); err != nil {
+					return nil, err
+				}
+				if err := r.RateCardRepo.Create(p.Context, c); err != nil {
+					return nil, err
+				}
+				return c, nil
+			},
+		},
+		"updateRateCard": &graphql.Field{
+			Type:        types.RateCardType,
+			Description: "Change a rate card. Lists given replace the card's; fields left out are kept.",
+			Args: graphql.FieldConfigArgument{
+				"id":    &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.String)},
+				"input": &graphql.ArgumentConfig{Type: graphql.NewNonNull(types.RateCardInputType)},
+			},
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				tenantID, err := requireTenant(p.Context)
+				if err != nil {
+					return nil, err
+				}
+				id, err := uuid.Parse(p.Args["id"].(string))
+				if err != nil {
+					return nil, fmt.Errorf("invalid rate card id: %w", err)
+				}
+				c, err := r.RateCardRepo.GetByID(p.Context, tenantID, id)
+				if err != nil {
+					return nil, err
+				}
+				if err := r.applyRateCardInput(p.Context, tenantID, c, p.Args["input"].(map[string]interface{})); err != nil {
+					return nil, err
+				}
+				if err := r.RateCardRepo.Update(p.Context, tenantID, id, c); err != nil {
+					return nil, err
+				}
+				return c, nil
+			},
+		},
+		"deleteRateCard": &graphql.Field{
+			Type: graphql.Boolean,
+			Args: graphql.FieldConfigArgument{
+				"id": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.String)},
+			},
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				tenantID, err := requireTenant(p.Context)
+				if err != nil {
+					return nil, err
+				}
+				id, err := uuid.Parse(p.Args["id"].(string))
+				if err != nil {
+					return nil, fmt.Errorf("invalid rate card id: %w", err)
+				}
+				if err := r.RateCardRepo.Delete(p.Context, tenantID, id); err != nil {
+					return nil, err
+				}
+				return true, nil
+			},
+		},
+	}
+}
+
+// applyRateCardInput sets the fields given in a RateCardInput on c and
+// validates the result.
+func (r *Resolver) applyRateCardInput(ctx context.Context, tenantID uuid.UUID, c *models.RateCard, input map[string]interface{}) error {
+	c.Name, _ = input["name"].(string)
+	if _, ok := input["clientId"]; ok {
+		clientID, err := r.clientArg(ctx, tenantID, input)
+		if err != nil {
+			return err
+		}
+		c.ClientID = clientID
+	}
+	if v, ok := input["currency"].(string); ok {
+		c.Currency = v
+	}
+	if v, ok := input["volumetricDivisor"].(float64); ok {
+		c.VolumetricDivisor = v
+	}
+	if v, ok := input["minCharge"].(float64); ok {
+		c.MinCharge = v
+	}
+	if v, ok := input["active"].(bool); ok {
+		c.Active = v
+	}
+	if list, ok := input["zones"].([]interface{}); ok {
+		c.Zones = make([]models.RateZone, 0, len(list))
+		for _, item := range list {
+			m := item.(map[string]interface{})
+			z := models.RateZone{Regions: stringList(m["regions"]), PostalPrefixes: stringList(m["postalPrefixes"])}
+			z.Code, _ = m["code"].(string)
+			z.Name, _ = m["name"].(string)
+			z.Country, _ = m["country"].(string)
+			c.Zones = append(c.Zones, z)
+		}
+	}
+	if list, ok := input["lanes"].([]interface{}); ok {
+		c.Lanes = make([]models.RateLane, 0, len(list))
+		for _, item := range list {
+			m := item.(map[string]interface{})
+			var l models.RateLane
+			l.Origin, _ = m["origin"].(string)
+			l.Destination, _ = m["destination"].(string)
+			l.BaseCharge, _ = m["baseCharge"].(float64)
+			l.PerKm, _ = m["perKm"].(float64)
+			breaks, _ := m["breaks"].([]interface{})
+			for _, b := range breaks {
+				bm := b.(map[string]interface{})
+				l.Breaks = append(l.Breaks, models.WeightBreak{MinKg: bm["minKg"].(float64), PerKg: bm["perKg"].(float64)})
+			}
+			c.Lanes = append(c.Lanes, l)
+		}
+	}
+	if list, ok := input["surcharges"].([]interface{}); ok {
+		c.Surcharges = make([]models.Surcharge, 0, len(list))
+		for _, item := range list {
+			m := item.(map[string]interface{})
+			var s models.Surcharge
+			s.Code, _ = m["code"].(string)
+			s.Name, _ = m["name"].(string)
+			s.Kind, _ = m["kind"].(string)
+			s.Amount, _ = m["amount"].(float64)
+			s.Condition, _ = m["condition"].(string)
+			c.Surcharges = append(c.Surcharges, s)
+		}
+	}
+	return pricing.Validate(c)
+}
+
+// clientArg reads an optional clientId argument, checking the client belongs
+// to the tenant. An empty id means no client.
+func (r *Resolver) clientArg(ctx context.Context, tenantID uuid.UUID, args map[string]interface{}) (*uuid.UUID, error) {
+	v, _ := args["clientId"].(string)
+	if v == "" {
+		return nil, nil
+	}
+	id, err := uuid.Parse(v)
+	if err != nil {
+		return nil, fmt.Errorf("invalid client id: %w", err)
+	}
+	// Validate the client belongs to the same tenant (prevent IDOR).
+	if _, err := r.ClientRepo.GetByID(ctx, tenantID, id); err != nil {
+		return nil, fmt.Errorf("client not found in tenant")
+	}
+	return &id, nil
+}
+
+// quoteShipment prices s for clientID. Without a distance it is estimated
+// from the warehouse's position, or the geocoded origin, to the destination's
+// coordinates. It returns an error wrapping pricing.ErrNoRate when no active
+// card covers the shipment.
+func (r *Resolver) quoteShipment(ctx context.Context, tenantID uuid.UUID, clientID *uuid.UUID, s *models.Shipment, options []string, distanceKm *float64) (*models.Quote, error) {
+	cards, err := r.RateCardRepo.ForClient(ctx, tenantID, clientID)
+	if err != nil {
+		return nil, err
+	}
+	if len(cards) == 0 {
+		return nil, fmt.Errorf("%w: the tenant has no active rate card", pricing.ErrNoRate)
+	}
+
+	req := pricing.Request{Origin: s.OriginAddress, Destination: s.DestinationAddress, Options: options, DistanceKm: distanceKm}
+	if s.Weight != nil {
+		req.WeightKg = *s.Weight
+	}
+	if s.Dimensions != nil {
+		req.Dimensions = *s.Dimensions
+	}
+	if req.DistanceKm == nil {
+		req.DistanceKm = r.shipmentDistance(ctx, tenantID, s)
+	}
+	q, err := pricing.Quote(cards, req)
+	if errors.Is(err, pricing.ErrNoRate) {
+		return nil, err
+	}
+	if err != nil {
+		return nil, fmt.Errorf("cannot price shipment: %w", err)
+	}
+	return q, nil
+}
+
+// shipmentDistance estimates the road distance of s in kilometres, or nil
+// when either end cannot be placed.
+func (r *Resolver) shipmentDistance(ctx context.Context, tenantID uuid.UUID, s *models.Shipment) *float64 {
+	var fromLat, fromLng *float64
+	if s.WarehouseID != nil {
+		if w, err := r.WarehouseRepo.GetByID(ctx, tenantID, *s.WarehouseID); err == nil {
+			fromLat, fromLng = w.Latitude, w.Longitude
+		}
+	}
+	if fromLat == nil || fromLng == nil {
+		fromLat, fromLng = r.geocode(ctx, s.OriginAddress)
+	}
+	toLat, toLng := s.DestinationLatitude, s.DestinationLongitude
+	if toLat == nil || toLng == nil {
+		toLat, toLng = r.geocode(ctx, s.DestinationAddress)
+	}
+	if fromLat == nil || fromLng == nil || toLat == nil || toLng == nil {
+		return nil
+	}
+	km := utils.HaversineMeters(*fromLat, *fromLng, *toLat, *toLng) / 1000 * models.ETARoadFactor
+	return &km
+}
+
+// stringList converts a list argument into strings.
+func stringList(v interface{}) []string {
+	list, _ := v.([]interface{})
+	out := make([]string, 0, len(list))
+	for _, item := range list {
+		if s, ok := item.(string); ok {
+			out = append(out, s)
+		}
+	}
+	return out
+}
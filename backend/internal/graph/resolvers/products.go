@@ -0,0 +1,186 @@
+package resolvers
+
+import (
+	"fmt"
+
+	"cargomax-api/internal/graph/types"
+	"cargomax-api/internal/models"
+
+	"github.com/google/uuid"
+	"github.com/graphql-go/graphql"
+)
+
+// ProductQueries returns GraphQL query fields for the product catalog.
+func (r *Resolver) ProductQueries() graphql.Fields {
+	return graphql.Fields{
+		"products": &graphql.Field{
+			Type:        types.ProductConnectionType,
+			Description: "The tenant's catalog by name, optionally in one category and matching search on SKU or name.",
+			Args: graphql.FieldConfigArgument{
+				"search":   &graphql.ArgumentConfig{Type: graphql.String},
+				"category": &graphql.ArgumentConfig{Type: graphql.String},
+				"page":     &graphql.ArgumentConfig{Type: graphql.Int, DefaultValue: 1},
+				"perPage":  &graphql.ArgumentConfig{Type: graphql.Int, DefaultValue: 20},
+			},
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				tenantID, err := requireTenant(p.Context)
+				if err != nil {
+					return nil, err
+				}
+				page, perPage := clampPagination(p.Args["page"].(int), p.Args["perPage"].(int))
+				search, _ := p.Args["search"].(string)
+				category, _ := p.Args["category"].(string)
+
+				items, total, err := r.ProductRepo.List(p.Context, tenantID, search, category, page, perPage)
+				if err != nil {
+					return nil, err
+				}
+
+				totalPages := 0
+				if perPage > 0 {
+					totalPages = (total + perPage - 1) / perPage
+				}
+				return map[string]interface{}{
+					"items":      items,
+					"totalCount": total,
+					"page":       page,
+					"perPage":    perPage,
+					"totalPages": totalPages,
+				}, nil
+			},
+		},
+		"product": &graphql.Field{
+			Type:        types.ProductType,
+			Description: "A product by id or by SKU.",
+			Args: graphql.FieldConfigArgument{
+				"id":  &graphql.ArgumentConfig{Type: graphql.String},
+				"sku": &graphql.ArgumentConfig{Type: graphql.String},
+			},
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				tenantID, err := requireTenant(p.Context)
+				if err != nil {
+					return nil, err
+				}
+				if sku, ok := p.Args["sku"].(string); ok {
+					return r.ProductRepo.GetBySKU(p.Context, tenantID, sku)
+				}
+				v, ok := p.Args["id"].(string)
+				if !ok {
+					return nil, fmt.Errorf("give a product id or SKU")
+				}
+				id, err := uuid.Parse(v)
+				if err != nil {
+					return nil, fmt.Errorf("invalid product id: %w", err)
+				}
+				return r.ProductRepo.GetByID(p.Context, tenantID, id)
+			},
+		},
+	}
+}
+
+// ProductMutations returns GraphQL mutation fields that maintain the product
+// catalog. Stocking a product in a warehouse is createInventoryItem.
+func (r *Resolver) ProductMutations() graphql.Fields {
+	return graphql.Fields{
+		"createProduct": &graphql.Field{
+			Type: types.ProductType,
+			Args: graphql.FieldConfigArgument{
+				"input": &graphql.ArgumentConfig{Type: graphql.NewNonNull(types.ProductInputType)},
+			},
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				tenantID, err := requireTenant(p.Context)
+				if err != nil {
+					return nil, err
+				}
+				product := &models.Product{TenantID: tenantID}
+				if err := applyProductInput(product, p.Args["input"].(map[string]interface{})); err != nil {
+					return nil, err
+				}
+				if err := r.ProductRepo.Create(p.Context, product); err != nil {
+					return nil, err
+				}
+				return product, nil
+			},
+		},
+		"updateProduct": &graphql.Field{
+			Type:        types.ProductType,
+			Description: "Change a product. The change shows on its stock in every warehouse.",
+			Args: graphql.FieldConfigArgument{
+				"id":    &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.String)},
+				"input": &graphql.ArgumentConfig{Type: graphql.NewNonNull(types.ProductInputType)},
+			},
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				tenantID, err := requireTenant(p.Context)
+				if err != nil {
+					return nil, err
+				}
+				id, err := uuid.Parse(p.Args["id"].(string))
+				if err != nil {
+					return nil, fmt.Errorf("invalid product id: %w", err)
+				}
+				product := &models.Product{}
+				if err := applyProductInput(product, p.Args["input"].(map[string]interface{})); err != nil {
+					return nil, err
+				}
+				if err := r.ProductRepo.Update(p.Context, tenantID, id, product); err != nil {
+					return nil, err
+				}
+				return r.ProductRepo.GetByID(p.Context, tenantID, id)
+			},
+		},
+		"deleteProduct": &graphql.Field{
+			Type:        graphql.Boolean,
+			Description: "Remove a product from the catalog with its stock in every warehouse.",
+			Args: graphql.FieldConfigArgument{
+				"id": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.String)},
+			},
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				tenantID, err := requireTenant(p.Context)
+				if err != nil {
+					return nil, err
+				}
+				id, err := uuid.Parse(p.Args["id"].(string))
+				if err != nil {
+					return nil, fmt.Errorf("invalid product id: %w", err)
+				}
+				if err := r.ProductRepo.Delete(p.Context, tenantID, id); err != nil {
+					return nil, err
+				}
+				return true, nil
+			},
+		},
+	}
+}
+
+// applyProductInput sets the fields of a ProductInput on product.
+func applyProductInput(product *models.Product, input map[string]interface{}) error {
+	product.SKU, _ = input["sku"].(string)
+	if product.SKU == "" {
+		return fmt.Errorf("a product needs a SKU")
+	}
+	if v, ok := input["name"].(string); ok {
+		product.Name = &v
+	}
+	if v, ok := input["category"].(string); ok {
+		product.Category = &v
+	}
+	if v, ok := input["unitPrice"].(float64); ok {
+		product.UnitPrice = &v
+	}
+	if v, ok := input["weight"].(float64); ok {
+		product.Weight = &v
+	}
+	if v, ok := input["volume"].(float64); ok {
+		if v <= 0 {
+			return fmt.Errorf("volume must be positive")
+		}
+		product.Volume = &v
+	}
+	if v, ok := input["unitsPerPallet"].(int); ok {
+		if v <= 0 {
+			return fmt.Errorf("unitsPerPallet must be positive")
+		}
+		product.UnitsPerPallet = &v
+	}
+	return nil
+}
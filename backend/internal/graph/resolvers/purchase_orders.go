@@ -0,0 +1,375 @@
+package resolvers
+
+import (
+	"fmt"
+	"time"
+
+	"cargomax-api/internal/graph/types"
+	"cargomax-api/internal/models"
+	"cargomax-api/internal/repository"
+
+	"github.com/google/uuid"
+	"github.com/graphql-go/graphql"
+)
+
+// PurchaseOrderQueries returns GraphQL query fields for the supplier catalog
+// and purchase orders.
+func (r *Resolver) PurchaseOrderQueries() graphql.Fields {
+	return graphql.Fields{
+		"supplierProducts": &graphql.Field{
+			Type:        graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(types.SupplierProductType))),
+			Description: "The supplier catalog by SKU, optionally of one vendor and one SKU, with the preferred supplier and then the cheapest first.",
+			Args: graphql.FieldConfigArgument{
+				"vendorId": &graphql.ArgumentConfig{Type: graphql.String},
+				"sku":      &graphql.ArgumentConfig{Type: graphql.String},
+			},
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				tenantID, err := requireTenant(p.Context)
+				if err != nil {
+					return nil, err
+				}
+				vendorID, err := optionalID(p.Args, "vendorId", "vendor")
+				if err != nil {
+					return nil, err
+				}
+				var productID *uuid.UUID
+				if sku, ok := p.Args["sku"].(string); ok {
+					product, err := r.ProductRepo.GetBySKU(p.Context, tenantID, sku)
+					if err != nil {
+						return nil, err
+					}
+					productID = &product.ID
+				}
+				return r.SupplierProductRepo.List(p.Context, tenantID, vendorID, productID)
+			},
+		},
+		"purchaseOrders": &graphql.Field{
+			Type:        types.PurchaseOrderConnectionType,
+			Description: "The tenant's purchase orders, newest first, optionally in one status, from one source, to one vendor or into one warehouse.",
+			Args: graphql.FieldConfigArgument{
+				"status":      &graphql.ArgumentConfig{Type: graphql.String},
+				"source":      &graphql.ArgumentConfig{Type: graphql.String},
+				"vendorId":    &graphql.ArgumentConfig{Type: graphql.String},
+				"warehouseId": &graphql.ArgumentConfig{Type: graphql.String},
+				"page":        &graphql.ArgumentConfig{Type: graphql.Int, DefaultValue: 1},
+				"perPage":     &graphql.ArgumentConfig{Type: graphql.Int, DefaultValue: 20},
+			},
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				tenantID, err := requireTenant(p.Context)
+				if err != nil {
+					return nil, err
+				}
+				page, perPage := clampPagination(p.Args["page"].(int), p.Args["perPage"].(int))
+				var f repository.PurchaseOrderFilter
+				f.Status, _ = p.Args["status"].(string)
+				if f.Status != "" && !models.IsPurchaseStatus(f.Status) {
+					return nil, fmt.Errorf("unknown purchase order status %q", f.Status)
+				}
+				f.Source, _ = p.Args["source"].(string)
+				if f.VendorID, err = optionalID(p.Args, "vendorId", "vendor"); err != nil {
+					return nil, err
+				}
+				if f.WarehouseID, err = optionalID(p.Args, "warehouseId", "warehouse"); err != nil {
+					return nil, err
+				}
+
+				items, total, err := r.PurchaseOrderRepo.List(p.Context, tenantID, f, page, perPage)
+				if err != nil {
+					return nil, err
+				}
+
+				totalPages := 0
+				if perPage > 0 {
+					totalPages = (total + perPage - 1) / perPage
+				}
+				return map[string]interface{}{
+					"items":      items,
+					"totalCount": total,
+					"page":       page,
+					"perPage":    perPage,
+					"totalPages": totalPages,
+				}, nil
+			},
+		},
+		"purchaseOrder": &graphql.Field{
+			Type: types.PurchaseOrderType,
+			Args: graphql.FieldConfigArgument{
+				"id": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.String)},
+			},
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				tenantID, err := requireTenant(p.Context)
+				if err != nil {
+					return nil, err
+				}
+				id, err := uuid.Parse(p.Args["id"].(string))
+				if err != nil {
+					return nil, fmt.Errorf("invalid purchase order id: %w", err)
+				}
+				return r.PurchaseOrderRepo.GetByID(p.Context, tenantID, id)
+			},
+		},
+	}
+}
+
+// PurchaseOrderMutations returns GraphQL mutation fields that maintain the
+// supplier catalog and take purchase orders from draft through sending to
+// receipt, and that run the reorder job on demand.
+func (r *Resolver) PurchaseOrderMutations() graphql.Fields {
+	return graphql.Fields{
+		"createSupplierProduct": &graphql.Field{
+			Type:        types.SupplierProductType,
+			Description: "Add a product to a vendor's catalog.",
+			Args: graphql.FieldConfigArgument{
+				"input": &graphql.ArgumentConfig{Type: graphql.NewNonNull(types.SupplierProductInputType)},
+			},
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				tenantID, err := requireTenant(p.Context)
+				if err != nil {
+					return nil, err
+				}
+				s := &models.SupplierProduct{TenantID: tenantID}
+				if err := applySupplierProductInput(s, p.Args["input"].(map[string]interface{})); err != nil {
+					return nil, err
+				}
+				if err := r.SupplierProductRepo.Create(p.Context, s); err != nil {
+					return nil, err
+				}
+				return r.SupplierProductRepo.GetByID(p.Context, tenantID, s.ID)
+			},
+		},
+		"updateSupplierProduct": &graphql.Field{
+			Type: types.SupplierProductType,
+			Args: graphql.FieldConfigArgument{
+				"id":    &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.String)},
+				"input": &graphql.ArgumentConfig{Type: graphql.NewNonNull(types.SupplierProductInputType)},
+			},
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				tenantID, err := requireTenant(p.Context)
+				if err != nil {
+					return nil, err
+				}
+				id, err := uuid.Parse(p.Args["id"].(string))
+				if err != nil {
+					return nil, fmt.Errorf("invalid supplier product id: %w", err)
+				}
+				s := &models.SupplierProduct{}
+				if err := applySupplierProductInput(s, p.Args["input"].(map[string]interface{})); err != nil {
+					return nil, err
+				}
+				if err := r.SupplierProductRepo.Update(p.Context, tenantID, id, s); err != nil {
+					return nil, err
+				}
+				return r.SupplierProductRepo.GetByID(p.Context, tenantID, id)
+			},
+		},
+		"deleteSupplierProduct": &graphql.Field{
+			Type: graphql.Boolean,
+			Args: graphql.FieldConfigArgument{
+				"id": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.String)},
+			},
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				tenantID, err := requireTenant(p.Context)
+				if err != nil {
+					return nil, err
+				}
+				id, err := uuid.Parse(p.Args["id"].(string))
+				if err != nil {
+					return nil, fmt.Errorf("invalid supplier product id: %w", err)
+				}
+				if err := r.SupplierProductRepo.Delete(p.Context, tenantID, id); err != nil {
+					return nil, err
+				}
+				return true, nil
+			},
+		},
+		"createPurchaseOrder": &graphql.Field{
+			Type: types.PurchaseOrderType,
+			Description: "Draft a purchase order. Lines are priced from the vendor's catalog unless given a unit cost, and must " +
+				"be at least the vendor's minimum order quantity.",
+			Args: graphql.FieldConfigArgument{
+				"input": &graphql.ArgumentConfig{Type: graphql.NewNonNull(types.PurchaseOrderInputType)},
+			},
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				tenantID, userID, err := requireAuth(p.Context)
+				if err != nil {
+					return nil, err
+				}
+				po := &models.PurchaseOrder{TenantID: tenantID, CreatedBy: &userID}
+				lines, err := applyPurchaseOrderInput(po, p.Args["input"].(map[string]interface{}))
+				if err != nil {
+					return nil, err
+				}
+				return r.PurchaseOrderRepo.Create(p.Context, po, lines)
+			},
+		},
+		"updatePurchaseOrder": &graphql.Field{
+			Type:        types.PurchaseOrderType,
+			Description: "Change a draft purchase order, replacing its lines.",
+			Args: graphql.FieldConfigArgument{
+				"id":    &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.String)},
+				"input": &graphql.ArgumentConfig{Type: graphql.NewNonNull(types.PurchaseOrderInputType)},
+			},
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				tenantID, err := requireTenant(p.Context)
+				if err != nil {
+					return nil, err
+				}
+				id, err := uuid.Parse(p.Args["id"].(string))
+				if err != nil {
+					return nil, fmt.Errorf("invalid purchase order id: %w", err)
+				}
+				po := &models.PurchaseOrder{}
+				lines, err := applyPurchaseOrderInput(po, p.Args["input"].(map[string]interface{}))
+				if err != nil {
+					return nil, err
+				}
+				return r.PurchaseOrderRepo.UpdateDraft(p.Context, tenantID, id, po, lines)
+			},
+		},
+		"sendPurchaseOrder": &graphql.Field{
+			Type:        types.PurchaseOrderType,
+			Description: "Mark a draft as sent to the vendor. Without an expected date it is expected after the vendor's longest lead time for its lines.",
+			Args: graphql.FieldConfigArgument{
+				"id": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.String)},
+			},
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				tenantID, err := requireTenant(p.Context)
+				if err != nil {
+					return nil, err
+				}
+				id, err := uuid.Parse(p.Args["id"].(string))
+				if err != nil {
+					return nil, fmt.Errorf("invalid purchase order id: %w", err)
+				}
+				return r.PurchaseOrderRepo.Send(p.Context, tenantID, id)
+			},
+		},
+		"receivePurchaseOrder": &graphql.Field{
+			Type: types.PurchaseOrderType,
+			Description: "Book a delivery against a sent purchase order into its warehouse, recorded as receipts. lines gives what " +
+				"arrived; without it everything outstanding arrived. The order is received once every line has arrived in full.",
+			Args: graphql.FieldConfigArgument{
+				"id":    &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.String)},
+				"lines": &graphql.ArgumentConfig{Type: graphql.NewList(graphql.NewNonNull(types.ReceiptLineInputType))},
+			},
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				tenantID, err := requireTenant(p.Context)
+				if err != nil {
+					return nil, err
+				}
+				id, err := uuid.Parse(p.Args["id"].(string))
+				if err != nil {
+					return nil, fmt.Errorf("invalid purchase order id: %w", err)
+				}
+				return r.PurchaseOrderRepo.Receive(p.Context, tenantID, id, purchaseInputs(p.Args["lines"]))
+			},
+		},
+		"cancelPurchaseOrder": &graphql.Field{
+			Type:        types.PurchaseOrderType,
+			Description: "Withdraw a purchase order not yet received in full. A partially received order is closed short.",
+			Args: graphql.FieldConfigArgument{
+				"id": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.String)},
+			},
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				tenantID, err := requireTenant(p.Context)
+				if err != nil {
+					return nil, err
+				}
+				id, err := uuid.Parse(p.Args["id"].(string))
+				if err != nil {
+					return nil, fmt.Errorf("invalid purchase order id: %w", err)
+				}
+				return r.PurchaseOrderRepo.Cancel(p.Context, tenantID, id)
+			},
+		},
+		"draftReorders": &graphql.Field{
+			Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(types.PurchaseOrderType))),
+			Description: "Run the reorder job now, in one warehouse or all of them, returning the purchase orders it drafted. A stock " +
+				"level is reordered once what it has available, in transit and on open purchase orders falls to its minimum plus " +
+				"the demand expected over the supplier's lead time, and is ordered up to that plus coverDays of demand. Demand is " +
+				"what was picked over the last lookbackDays.",
+			Args: graphql.FieldConfigArgument{
+				"warehouseId":  &graphql.ArgumentConfig{Type: graphql.String},
+				"lookbackDays": &graphql.ArgumentConfig{Type: graphql.Int, DefaultValue: models.DefaultReorderPolicy.LookbackDays},
+				"coverDays":    &graphql.ArgumentConfig{Type: graphql.Int, DefaultValue: models.DefaultReorderPolicy.CoverDays},
+			},
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				tenantID, userID, err := requireAuth(p.Context)
+				if err != nil {
+					return nil, err
+				}
+				warehouseID, err := optionalID(p.Args, "warehouseId", "warehouse")
+				if err != nil {
+					return nil, err
+				}
+				policy := models.ReorderPolicy{LookbackDays: p.Args["lookbackDays"].(int), CoverDays: p.Args["coverDays"].(int)}
+				if policy.LookbackDays <= 0 || policy.CoverDays < 0 {
+					return nil, fmt.Errorf("lookbackDays must be positive and coverDays not negative")
+				}
+				return r.PurchaseOrderRepo.DraftReorders(p.Context, tenantID, warehouseID, policy, &userID)
+			},
+		},
+	}
+}
+
+// applySupplierProductInput sets the fields of a SupplierProductInput on s.
+func applySupplierProductInput(s *models.SupplierProduct, input map[string]interface{}) error {
+	vendorID, err := uuid.Parse(input["vendorId"].(string))
+	if err != nil {
+		return fmt.Errorf("invalid vendor id: %w", err)
+	}
+	s.VendorID = vendorID
+	s.SKU = input["sku"].(string)
+	if v, ok := input["vendorSku"].(string); ok {
+		s.VendorSKU = &v
+	}
+	s.UnitCost = input["unitCost"].(float64)
+	s.LeadTimeDays, _ = input["leadTimeDays"].(int)
+	s.MinOrderQuantity, _ = input["minOrderQuantity"].(int)
+	s.Preferred, _ = input["preferred"].(bool)
+	return nil
+}
+
+// applyPurchaseOrderInput sets the fields of a PurchaseOrderInput on po and
+// returns its lines.
+func applyPurchaseOrderInput(po *models.PurchaseOrder, input map[string]interface{}) ([]models.PurchaseQuantity, error) {
+	var err error
+	if po.VendorID, err = uuid.Parse(input["vendorId"].(string)); err != nil {
+		return nil, fmt.Errorf("invalid vendor id: %w", err)
+	}
+	if po.WarehouseID, err = uuid.Parse(input["warehouseId"].(string)); err != nil {
+		return nil, fmt.Errorf("invalid warehouse id: %w", err)
+	}
+	if v, ok := input["expectedDate"].(string); ok && v != "" {
+		d, err := time.Parse(InvoiceDateLayout, v)
+		if err != nil {
+			return nil, fmt.Errorf("expectedDate must be a date (YYYY-MM-DD)")
+		}
+		po.ExpectedDate = &d
+	}
+	if v, ok := input["notes"].(string); ok {
+		po.Notes = &v
+	}
+	return purchaseInputs(input["lines"]), nil
+}
+
+// purchaseInputs reads a list of PurchaseLineInput or ReceiptLineInput. A
+// missing list is nil.
+func purchaseInputs(v interface{}) []models.PurchaseQuantity {
+	list, ok := v.([]interface{})
+	if !ok {
+		return nil
+	}
+	out := make([]models.PurchaseQuantity, 0, len(list))
+	for _, item := range list {
+		m, _ := item.(map[string]interface{})
+		q := models.PurchaseQuantity{}
+		q.SKU, _ = m["sku"].(string)
+		q.Quantity, _ = m["quantity"].(int)
+		if c, ok := m["unitCost"].(float64); ok {
+			q.UnitCost = &c
+		}
+		out = append(out, q)
+	}
+	return out
+}
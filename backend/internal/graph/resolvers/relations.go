@@ -0,0 +1,810 @@
+package resolvers
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"cargomax-api/internal/graph/loaders"
+	"cargomax-api/internal/graph/types"
+	"cargomax-api/internal/models"
+
+	"github.com/google/uuid"
+	"github.com/graphql-go/graphql"
+)
+
+// LoaderMiddleware attaches a fresh set of batch loaders to every request so
+// nested fields resolved across a list share one query per entity type.
+func (r *Resolver) LoaderMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		ctx := loaders.WithLoaders(req.Context(), r.newLoaders())
+		next.ServeHTTP(w, req.WithContext(ctx))
+	})
+}
+
+func (r *Resolver) newLoaders() *loaders.Loaders {
+	return loaders.New(r.ShipmentRepo, r.VehicleRepo, r.WarehouseRepo)
+}
+
+// loadersFor returns the request's loaders, falling back to an unshared set when
+// the schema is executed outside LoaderMiddleware (tests, scripts).
+func (r *Resolver) loadersFor(ctx context.Context) *loaders.Loaders {
+	if l := loaders.FromContext(ctx); l != nil {
+		return l
+	}
+	return r.newLoaders()
+}
+
+// AttachRelations adds the nested object fields that resolve foreign keys
+// through the batch loaders. The types package only declares flat fields, so
+// the relationships are wired here where the repositories are available.
+func (r *Resolver) AttachRelations() {
+	types.OrderType.AddFieldConfig("shipment", &graphql.Field{
+		Type:        types.ShipmentType,
+		Description: "The shipment fulfilling this order, if any.",
+		Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+			o, ok := source[models.Order](p.Source)
+			if !ok || o.ShipmentID == nil {
+				return nil, nil
+			}
+			return r.loadShipment(p.Context, *o.ShipmentID)
+		},
+	})
+
+	types.OrderType.AddFieldConfig("lines", &graphql.Field{
+		Type:        graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(types.OrderLineType))),
+		Description: "The items ordered, by line number.",
+		Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+			o, ok := source[models.Order](p.Source)
+			if !ok {
+				return []models.OrderLine{}, nil
+			}
+			if o.Lines != nil {
+				return o.Lines, nil
+			}
+			return r.OrderRepo.Lines(p.Context, o.TenantID, o.ID)
+		},
+	})
+
+	types.OrderType.AddFieldConfig("fulfillments", &graphql.Field{
+		Type:        graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(types.FulfillmentType))),
+		Description: "The order's pick lists and the shipments they were packed into, oldest first.",
+		Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+			o, ok := source[models.Order](p.Source)
+			if !ok {
+				return []models.Fulfillment{}, nil
+			}
+			return r.FulfillmentRepo.ListByOrder(p.Context, o.TenantID, o.ID)
+		},
+	})
+
+	types.OrderType.AddFieldConfig("rmas", &graphql.Field{
+		Type:        graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(types.RMAType))),
+		Description: "Returns authorized against the order, oldest first.",
+		Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+			o, ok := source[models.Order](p.Source)
+			if !ok {
+				return []models.RMA{}, nil
+			}
+			return r.RMARepo.ListByOrder(p.Context, o.TenantID, o.ID)
+		},
+	})
+
+	types.FulfillmentType.AddFieldConfig("shipment", &graphql.Field{
+		Type:        types.ShipmentType,
+		Description: "The shipment created when the fulfillment was packed.",
+		Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+			f, ok := source[models.Fulfillment](p.Source)
+			if !ok || f.ShipmentID == nil {
+				return nil, nil
+			}
+			return r.loadShipment(p.Context, *f.ShipmentID)
+		},
+	})
+
+	types.ShipmentType.AddFieldConfig("events", &graphql.Field{
+		Type:        graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(types.ShipmentEventType))),
+		Description: "The shipment's tracking timeline, oldest event first.",
+		Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+			s, ok := source[models.Shipment](p.Source)
+			if !ok {
+				return []models.ShipmentEvent{}, nil
+			}
+			return r.loadShipmentEvents(p.Context, s.ID)
+		},
+	})
+
+	types.ShipmentType.AddFieldConfig("assignment", &graphql.Field{
+		Type:        types.ShipmentAssignmentType,
+		Description: "The driver and vehicle currently carrying the shipment, if assigned.",
+		Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+			s, ok := source[models.Shipment](p.Source)
+			if !ok {
+				return nil, nil
+			}
+			history, err := r.loadShipmentAssignments(p.Context, s.ID)
+			if err != nil {
+				return nil, err
+			}
+			return func() (interface{}, error) {
+				v, err := history()
+				if err != nil {
+					return nil, err
+				}
+				list := v.([]models.ShipmentAssignment)
+				if n := len(list); n > 0 && list[n-1].UnassignedAt == nil {
+					return &list[n-1], nil
+				}
+				return nil, nil
+			}, nil
+		},
+	})
+
+	types.ShipmentType.AddFieldConfig("assignmentHistory", &graphql.Field{
+		Type:        graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(types.ShipmentAssignmentType))),
+		Description: "Every assignment the shipment has had, oldest first.",
+		Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+			s, ok := source[models.Shipment](p.Source)
+			if !ok {
+				return []models.ShipmentAssignment{}, nil
+			}
+			history, err := r.loadShipmentAssignments(p.Context, s.ID)
+			if err != nil {
+				return nil, err
+			}
+			return history, nil
+		},
+	})
+
+	types.ShipmentType.AddFieldConfig("etaHistory", &graphql.Field{
+		Type:        graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(types.ShipmentETAType))),
+		Description: "Every live ETA prediction that moved the shipment's ETA, oldest first.",
+		Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+			s, ok := source[models.Shipment](p.Source)
+			if !ok {
+				return []models.ShipmentETA{}, nil
+			}
+			return r.loadShipmentETAs(p.Context, s.ID)
+		},
+	})
+
+	types.ShipmentType.AddFieldConfig("proofOfDelivery", &graphql.Field{
+		Type:        types.ProofOfDeliveryType,
+		Description: "The driver's proof of delivery, if the shipment was delivered with one.",
+		Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+			s, ok := source[models.Shipment](p.Source)
+			if !ok {
+				return nil, nil
+			}
+			return r.loadProofOfDelivery(p.Context, s.ID)
+		},
+	})
+
+	types.ProofOfDeliveryType.AddFieldConfig("signature", &graphql.Field{
+		Type: types.ProofOfDeliveryFileType,
+		Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+			pod, ok := source[models.ProofOfDelivery](p.Source)
+			if !ok {
+				return nil, nil
+			}
+			for _, f := range pod.Files {
+				if f.Kind == models.PODSignature {
+					return f, nil
+				}
+			}
+			return nil, nil
+		},
+	})
+
+	types.ProofOfDeliveryType.AddFieldConfig("photos", &graphql.Field{
+		Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(types.ProofOfDeliveryFileType))),
+		Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+			photos := []models.PODFile{}
+			if pod, ok := source[models.ProofOfDelivery](p.Source); ok {
+				for _, f := range pod.Files {
+					if f.Kind == models.PODPhoto {
+						photos = append(photos, f)
+					}
+				}
+			}
+			return photos, nil
+		},
+	})
+
+	types.ProofOfDeliveryFileType.AddFieldConfig("dataUrl", &graphql.Field{
+		Type:        graphql.String,
+		Description: "The image as a data: URL, read from file storage.",
+		Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+			f, ok := source[models.PODFile](p.Source)
+			if !ok {
+				return nil, nil
+			}
+			return r.podFileDataURL(p.Context, f)
+		},
+	})
+
+	types.ShipmentAssignmentType.AddFieldConfig("vehicle", &graphql.Field{
+		Type:        types.VehicleType,
+		Description: "The vehicle carrying the shipment under this assignment.",
+		Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+			a, ok := source[models.ShipmentAssignment](p.Source)
+			if !ok || a.VehicleID == nil {
+				return nil, nil
+			}
+			return r.loadVehicle(p.Context, *a.VehicleID)
+		},
+	})
+
+	types.RoutePlanType.AddFieldConfig("vehicle", &graphql.Field{
+		Type:        types.VehicleType,
+		Description: "The vehicle driving the plan, if assigned.",
+		Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+			rp, ok := source[models.RoutePlan](p.Source)
+			if !ok || rp.VehicleID == nil {
+				return nil, nil
+			}
+			return r.loadVehicle(p.Context, *rp.VehicleID)
+		},
+	})
+
+	types.RoutePlanType.AddFieldConfig("warehouse", &graphql.Field{
+		Type:        types.WarehouseType,
+		Description: "The warehouse the plan starts from, if any.",
+		Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+			rp, ok := source[models.RoutePlan](p.Source)
+			if !ok || rp.WarehouseID == nil {
+				return nil, nil
+			}
+			return r.loadWarehouse(p.Context, *rp.WarehouseID)
+		},
+	})
+
+	types.ShipmentType.AddFieldConfig("warehouse", &graphql.Field{
+		Type:        types.WarehouseType,
+		Description: "The warehouse the shipment leaves from, if set.",
+		Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+			s, ok := source[models.Shipment](p.Source)
+			if !ok || s.WarehouseID == nil {
+				return nil, nil
+			}
+			return r.loadWarehouse(p.Context, *s.WarehouseID)
+		},
+	})
+
+	types.RouteStopType.AddFieldConfig("shipment", &graphql.Field{
+		Type:        types.ShipmentType,
+		Description: "The shipment picked up or delivered at this stop, if any.",
+		Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+			s, ok := source[models.RouteStop](p.Source)
+			if !ok || s.ShipmentID == nil {
+				return nil, nil
+			}
+			return r.loadShipment(p.Context, *s.ShipmentID)
+		},
+	})
+
+	types.RouteStopType.AddFieldConfig("missesWindow", &graphql.Field{
+		Type:        graphql.NewNonNull(graphql.Boolean),
+		Description: "True when the stop is planned, or was reached, after its window closed.",
+		Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+			s, ok := source[models.RouteStop](p.Source)
+			return ok && s.MissesWindow(), nil
+		},
+	})
+
+	types.ProposedRouteType.AddFieldConfig("vehicle", &graphql.Field{
+		Type:        types.VehicleType,
+		Description: "The vehicle that would drive the route.",
+		Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+			rt, ok := source[models.ProposedRoute](p.Source)
+			if !ok {
+				return nil, nil
+			}
+			return r.loadVehicle(p.Context, rt.VehicleID)
+		},
+	})
+
+	types.ProposedStopType.AddFieldConfig("shipment", &graphql.Field{
+		Type:        types.ShipmentType,
+		Description: "The shipment delivered at this stop.",
+		Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+			s, ok := source[models.ProposedStop](p.Source)
+			if !ok {
+				return nil, nil
+			}
+			return r.loadShipment(p.Context, s.ShipmentID)
+		},
+	})
+
+	types.UnroutedShipmentType.AddFieldConfig("shipment", &graphql.Field{
+		Type:        types.ShipmentType,
+		Description: "The shipment left out.",
+		Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+			u, ok := source[models.UnroutedShipment](p.Source)
+			if !ok {
+				return nil, nil
+			}
+			return r.loadShipment(p.Context, u.ShipmentID)
+		},
+	})
+
+	types.DriverType.AddFieldConfig("vehicle", &graphql.Field{
+		Type:        types.VehicleType,
+		Description: "The vehicle currently assigned to this driver, if any.",
+		Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+			d, ok := source[models.Driver](p.Source)
+			if !ok || d.VehicleID == nil {
+				return nil, nil
+			}
+			return r.loadVehicle(p.Context, *d.VehicleID)
+		},
+	})
+
+	types.MaintenanceType.AddFieldConfig("vehicle", &graphql.Field{
+		Type:        types.VehicleType,
+		Description: "The vehicle this maintenance record belongs to.",
+		Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+			m, ok := source[models.MaintenanceRecord](p.Source)
+			if !ok {
+				return nil, nil
+			}
+			return r.loadVehicle(p.Context, m.VehicleID)
+		},
+	})
+
+	types.WarehouseType.AddFieldConfig("utilization", &graphql.Field{
+		Type:        graphql.NewNonNull(graphql.Float),
+		Description: "usedCapacity as a percentage of capacity.",
+		Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+			w, ok := source[models.Warehouse](p.Source)
+			if !ok {
+				return 0.0, nil
+			}
+			return w.Utilization(), nil
+		},
+	})
+
+	types.InventoryItemType.AddFieldConfig("warehouse", &graphql.Field{
+		Type:        types.WarehouseType,
+		Description: "The warehouse holding this item.",
+		Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+			i, ok := source[models.InventoryItem](p.Source)
+			if !ok {
+				return nil, nil
+			}
+			return r.loadWarehouse(p.Context, i.WarehouseID)
+		},
+	})
+
+	types.InventoryItemType.AddFieldConfig("available", &graphql.Field{
+		Type:        graphql.NewNonNull(graphql.Int),
+		Description: "On hand and not reserved for an order.",
+		Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+			i, ok := source[models.InventoryItem](p.Source)
+			if !ok {
+				return 0, nil
+			}
+			return i.Available(), nil
+		},
+	})
+
+	types.InventoryItemType.AddFieldConfig("product", &graphql.Field{
+		Type:        types.ProductType,
+		Description: "The catalog entry this is stock of.",
+		Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+			i, ok := source[models.InventoryItem](p.Source)
+			if !ok {
+				return nil, nil
+			}
+			return r.ProductRepo.GetByID(p.Context, i.TenantID, i.ProductID)
+		},
+	})
+
+	types.ProductType.AddFieldConfig("available", &graphql.Field{
+		Type:        graphql.NewNonNull(graphql.Int),
+		Description: "On hand and not reserved, across all warehouses.",
+		Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+			pr, ok := source[models.Product](p.Source)
+			if !ok {
+				return 0, nil
+			}
+			return pr.Available(), nil
+		},
+	})
+
+	types.ProductType.AddFieldConfig("stock", &graphql.Field{
+		Type:        graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(types.InventoryItemType))),
+		Description: "The product's stock level in each warehouse that holds it.",
+		Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+			pr, ok := source[models.Product](p.Source)
+			if !ok {
+				return []models.InventoryItem{}, nil
+			}
+			return r.InventoryRepo.ListByProduct(p.Context, pr.TenantID, pr.ID)
+		},
+	})
+
+	types.TransferOrderType.AddFieldConfig("fromWarehouse", &graphql.Field{
+		Type:        types.WarehouseType,
+		Description: "The warehouse the stock is sent from.",
+		Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+			t, ok := source[models.TransferOrder](p.Source)
+			if !ok {
+				return nil, nil
+			}
+			return r.loadWarehouse(p.Context, t.FromWarehouseID)
+		},
+	})
+
+	types.TransferOrderType.AddFieldConfig("toWarehouse", &graphql.Field{
+		Type:        types.WarehouseType,
+		Description: "The warehouse the stock is sent to.",
+		Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+			t, ok := source[models.TransferOrder](p.Source)
+			if !ok {
+				return nil, nil
+			}
+			return r.loadWarehouse(p.Context, t.ToWarehouseID)
+		},
+	})
+
+	types.TransferOrderType.AddFieldConfig("shipment", &graphql.Field{
+		Type:        types.ShipmentType,
+		Description: "The shipment carrying the stock, once dispatched.",
+		Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+			t, ok := source[models.TransferOrder](p.Source)
+			if !ok || t.ShipmentID == nil {
+				return nil, nil
+			}
+			return r.loadShipment(p.Context, *t.ShipmentID)
+		},
+	})
+
+	types.SupplierProductType.AddFieldConfig("vendor", &graphql.Field{
+		Type: types.VendorType,
+		Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+			s, ok := source[models.SupplierProduct](p.Source)
+			if !ok {
+				return nil, nil
+			}
+			return r.VendorRepo.GetByID(p.Context, s.TenantID, s.VendorID)
+		},
+	})
+
+	types.SupplierProductType.AddFieldConfig("product", &graphql.Field{
+		Type: types.ProductType,
+		Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+			s, ok := source[models.SupplierProduct](p.Source)
+			if !ok {
+				return nil, nil
+			}
+			return r.ProductRepo.GetByID(p.Context, s.TenantID, s.ProductID)
+		},
+	})
+
+	types.VendorType.AddFieldConfig("suppliedProducts", &graphql.Field{
+		Type:        graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(types.SupplierProductType))),
+		Description: "The vendor's catalog.",
+		Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+			v, ok := source[models.Vendor](p.Source)
+			if !ok {
+				return []models.SupplierProduct{}, nil
+			}
+			return r.SupplierProductRepo.List(p.Context, v.TenantID, &v.ID, nil)
+		},
+	})
+
+	types.ProductType.AddFieldConfig("suppliers", &graphql.Field{
+		Type:        graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(types.SupplierProductType))),
+		Description: "The vendors that supply the product, the preferred one and then the cheapest first.",
+		Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+			pr, ok := source[models.Product](p.Source)
+			if !ok {
+				return []models.SupplierProduct{}, nil
+			}
+			return r.SupplierProductRepo.List(p.Context, pr.TenantID, nil, &pr.ID)
+		},
+	})
+
+	types.PurchaseOrderType.AddFieldConfig("expectedDate", &graphql.Field{
+		Type:        graphql.String,
+		Description: "YYYY-MM-DD.",
+		Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+			po, ok := source[models.PurchaseOrder](p.Source)
+			if !ok {
+				return nil, nil
+			}
+			return formatDate(po.ExpectedDate), nil
+		},
+	})
+
+	types.PurchaseOrderType.AddFieldConfig("totalAmount", &graphql.Field{
+		Type:        graphql.NewNonNull(graphql.Float),
+		Description: "What the order costs at its lines' unit costs.",
+		Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+			po, ok := source[models.PurchaseOrder](p.Source)
+			if !ok {
+				return 0.0, nil
+			}
+			return po.Total(), nil
+		},
+	})
+
+	types.PurchaseOrderType.AddFieldConfig("vendor", &graphql.Field{
+		Type: types.VendorType,
+		Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+			po, ok := source[models.PurchaseOrder](p.Source)
+			if !ok {
+				return nil, nil
+			}
+			return r.VendorRepo.GetByID(p.Context, po.TenantID, po.VendorID)
+		},
+	})
+
+	types.PurchaseOrderType.AddFieldConfig("warehouse", &graphql.Field{
+		Type:        types.WarehouseType,
+		Description: "The warehouse the stock is delivered to.",
+		Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+			po, ok := source[models.PurchaseOrder](p.Source)
+			if !ok {
+				return nil, nil
+			}
+			return r.loadWarehouse(p.Context, po.WarehouseID)
+		},
+	})
+
+	invoiceDates := map[string]func(*models.Invoice) *time.Time{
+		"issueDate":   func(i *models.Invoice) *time.Time { return &i.IssueDate },
+		"dueDate":     func(i *models.Invoice) *time.Time { return &i.DueDate },
+		"periodStart": func(i *models.Invoice) *time.Time { return i.PeriodStart },
+		"periodEnd":   func(i *models.Invoice) *time.Time { return i.PeriodEnd },
+	}
+	for name, date := range invoiceDates {
+		types.InvoiceType.AddFieldConfig(name, &graphql.Field{
+			Type:        graphql.String,
+			Description: "YYYY-MM-DD.",
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				i, ok := source[models.Invoice](p.Source)
+				if !ok {
+					return nil, nil
+				}
+				return formatDate(date(i)), nil
+			},
+		})
+	}
+
+	types.InvoiceType.AddFieldConfig("balanceDue", &graphql.Field{
+		Type:        graphql.NewNonNull(graphql.Float),
+		Description: "What the client still owes; negative when a credit note left it owed a refund.",
+		Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+			i, ok := source[models.Invoice](p.Source)
+			if !ok {
+				return 0.0, nil
+			}
+			return i.BalanceDue(), nil
+		},
+	})
+
+	types.InvoiceType.AddFieldConfig("client", &graphql.Field{
+		Type:        types.ClientType,
+		Description: "The client billed.",
+		Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+			i, ok := source[models.Invoice](p.Source)
+			if !ok {
+				return nil, nil
+			}
+			return r.ClientRepo.GetByID(p.Context, i.TenantID, i.ClientID)
+		},
+	})
+
+	types.InvoiceType.AddFieldConfig("payments", &graphql.Field{
+		Type:        graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(types.PaymentType))),
+		Description: "Payments received against the invoice, oldest first.",
+		Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+			i, ok := source[models.Invoice](p.Source)
+			if !ok {
+				return []models.Payment{}, nil
+			}
+			return r.InvoiceRepo.Payments(p.Context, i.TenantID, i.ID)
+		},
+	})
+
+	types.InvoiceType.AddFieldConfig("creditNotes", &graphql.Field{
+		Type:        graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(types.InvoiceType))),
+		Description: "Credit notes issued against the invoice, oldest first.",
+		Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+			i, ok := source[models.Invoice](p.Source)
+			if !ok {
+				return []models.Invoice{}, nil
+			}
+			return r.InvoiceRepo.CreditNotes(p.Context, i.TenantID, i.ID)
+		},
+	})
+
+	types.RMAType.AddFieldConfig("receivedValue", &graphql.Field{
+		Type:        graphql.NewNonNull(graphql.Float),
+		Description: "What the goods received were sold for: the amount refunded or credited by default.",
+		Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+			m, ok := source[models.RMA](p.Source)
+			if !ok {
+				return 0.0, nil
+			}
+			return m.ReceivedValue(), nil
+		},
+	})
+
+	types.RMAType.AddFieldConfig("order", &graphql.Field{
+		Type:        types.OrderType,
+		Description: "The order the goods were sold on.",
+		Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+			m, ok := source[models.RMA](p.Source)
+			if !ok {
+				return nil, nil
+			}
+			return r.OrderRepo.GetByID(p.Context, m.TenantID, m.OrderID)
+		},
+	})
+
+	types.RMAType.AddFieldConfig("returnShipment", &graphql.Field{
+		Type:        types.ShipmentType,
+		Description: "The shipment bringing the goods back, once booked.",
+		Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+			m, ok := source[models.RMA](p.Source)
+			if !ok || m.ReturnShipmentID == nil {
+				return nil, nil
+			}
+			return r.loadShipment(p.Context, *m.ReturnShipmentID)
+		},
+	})
+
+	types.RMAType.AddFieldConfig("creditNote", &graphql.Field{
+		Type:        types.InvoiceType,
+		Description: "The credit note issued when the return was credited.",
+		Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+			m, ok := source[models.RMA](p.Source)
+			if !ok || m.CreditNoteID == nil {
+				return nil, nil
+			}
+			return r.InvoiceRepo.GetByID(p.Context, m.TenantID, *m.CreditNoteID)
+		},
+	})
+
+	scheduleDates := map[string]func(*models.OrderSchedule) *time.Time{
+		"startDate": func(s *models.OrderSchedule) *time.Time { return &s.StartDate },
+		"endDate":   func(s *models.OrderSchedule) *time.Time { return s.EndDate },
+		"nextDate":  func(s *models.OrderSchedule) *time.Time { return s.NextDate },
+	}
+	for name, date := range scheduleDates {
+		types.OrderScheduleType.AddFieldConfig(name, &graphql.Field{
+			Type:        graphql.String,
+			Description: "YYYY-MM-DD.",
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				s, ok := source[models.OrderSchedule](p.Source)
+				if !ok {
+					return nil, nil
+				}
+				return formatDate(date(s)), nil
+			},
+		})
+	}
+
+	types.OrderScheduleType.AddFieldConfig("skipDates", &graphql.Field{
+		Type:        graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(graphql.String))),
+		Description: "YYYY-MM-DD.",
+		Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+			s, ok := source[models.OrderSchedule](p.Source)
+			if !ok {
+				return []string{}, nil
+			}
+			out := make([]string, len(s.SkipDates))
+			for i := range s.SkipDates {
+				out[i] = formatDate(&s.SkipDates[i]).(string)
+			}
+			return out, nil
+		},
+	})
+
+	types.AgingReportType.AddFieldConfig("asOf", &graphql.Field{
+		Type:        graphql.NewNonNull(graphql.String),
+		Description: "YYYY-MM-DD.",
+		Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+			a, ok := source[models.AgingReport](p.Source)
+			if !ok {
+				return nil, nil
+			}
+			return formatDate(&a.AsOf), nil
+		},
+	})
+}
+
+// formatDate formats a calendar date, or nil for no date.
+func formatDate(t *time.Time) interface{} {
+	if t == nil {
+		return nil
+	}
+	return t.Format(InvoiceDateLayout)
+}
+
+func (r *Resolver) loadShipment(ctx context.Context, id uuid.UUID) (interface{}, error) {
+	tenantID, err := requireTenant(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return r.loadersFor(ctx).Shipments.Load(ctx, tenantID, id), nil
+}
+
+func (r *Resolver) loadShipmentEvents(ctx context.Context, shipmentID uuid.UUID) (interface{}, error) {
+	tenantID, err := requireTenant(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return sliceThunk[models.ShipmentEvent](r.loadersFor(ctx).ShipmentEvents.Load(ctx, tenantID, shipmentID)), nil
+}
+
+func (r *Resolver) loadShipmentAssignments(ctx context.Context, shipmentID uuid.UUID) (func() (interface{}, error), error) {
+	tenantID, err := requireTenant(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return sliceThunk[models.ShipmentAssignment](r.loadersFor(ctx).ShipmentAssignments.Load(ctx, tenantID, shipmentID)), nil
+}
+
+func (r *Resolver) loadShipmentETAs(ctx context.Context, shipmentID uuid.UUID) (interface{}, error) {
+	tenantID, err := requireTenant(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return sliceThunk[models.ShipmentETA](r.loadersFor(ctx).ShipmentETAs.Load(ctx, tenantID, shipmentID)), nil
+}
+
+func (r *Resolver) loadProofOfDelivery(ctx context.Context, shipmentID uuid.UUID) (interface{}, error) {
+	tenantID, err := requireTenant(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return r.loadersFor(ctx).ProofsOfDelivery.Load(ctx, tenantID, shipmentID), nil
+}
+
+// sliceThunk adapts a loader thunk for a grouped list to resolve to the slice
+// itself rather than the loader's pointer, as graphql-go only completes list
+// fields from slice values. A missing group resolves to an empty list.
+func sliceThunk[T any](thunk func() (interface{}, error)) func() (interface{}, error) {
+	return func() (interface{}, error) {
+		v, err := thunk()
+		if err != nil {
+			return nil, err
+		}
+		if list, ok := v.(*[]T); ok {
+			return *list, nil
+		}
+		return []T{}, nil
+	}
+}
+
+func (r *Resolver) loadVehicle(ctx context.Context, id uuid.UUID) (interface{}, error) {
+	tenantID, err := requireTenant(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return r.loadersFor(ctx).Vehicles.Load(ctx, tenantID, id), nil
+}
+
+func (r *Resolver) loadWarehouse(ctx context.Context, id uuid.UUID) (interface{}, error) {
+	tenantID, err := requireTenant(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return r.loadersFor(ctx).Warehouses.Load(ctx, tenantID, id), nil
+}
+
+// source unwraps a parent value that list resolvers may hand over either as a
+// struct or as a pointer to one.
+func source[T any](src interface{}) (*T, bool) {
+	switch v := src.(type) {
+	case *T:
+		return v, v != nil
+	case T:
+		return &v, true
+	}
+	return nil, false
+}
@@ -1,6 +1,9 @@
 package resolvers
 
 import (
+	"fmt"
+	"time"
+
 	"cargomax-api/internal/graph/types"
 
 	"github.com/graphql-go/graphql"
@@ -51,5 +54,25 @@ func (r *Resolver) ReportQueries() graphql.Fields {
 				return r.ReportRepo.GetFleetReport(p.Context, tenantID, year)
 			},
 		},
+		"agingReport": &graphql.Field{
+			Type:        types.AgingReportType,
+			Description: "What clients owe on open invoices, by days past due.",
+			Args: graphql.FieldConfigArgument{
+				"asOf": &graphql.ArgumentConfig{Type: graphql.String, Description: "YYYY-MM-DD; defaults to today."},
+			},
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				tenantID, err := requireTenant(p.Context)
+				if err != nil {
+					return nil, err
+				}
+				asOf := time.Now()
+				if v, ok := p.Args["asOf"].(string); ok {
+					if asOf, err = time.Parse(InvoiceDateLayout, v); err != nil {
+						return nil, fmt.Errorf("asOf must be a date (YYYY-MM-DD)")
+					}
+				}
+				return r.ReportRepo.GetAgingReport(p.Context, tenantID, asOf)
+			},
+		},
 	}
 }
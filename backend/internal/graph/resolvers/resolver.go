@@ -3,10 +3,15 @@ package resolvers
 import (
 	"context"
 	"fmt"
+	"time"
 
 	"cargomax-api/internal/config"
+	"cargomax-api/internal/geocode"
+	"cargomax-api/internal/jobs"
+	"cargomax-api/internal/middleware"
 	"cargomax-api/internal/models"
 	"cargomax-api/internal/repository"
+	"cargomax-api/internal/storage"
 
 	"github.com/google/uuid"
 )
@@ -14,27 +19,61 @@ import (
 // Resolver holds references to every repository and the application configuration.
 // It is the single root object shared across all GraphQL resolver methods.
 type Resolver struct {
-	UserRepo         *repository.UserRepo
-	TenantRepo       *repository.TenantRepo
-	ShipmentRepo     *repository.ShipmentRepo
-	VehicleRepo      *repository.VehicleRepo
-	DriverRepo       *repository.DriverRepo
-	MaintenanceRepo  *repository.MaintenanceRepo
-	WarehouseRepo    *repository.WarehouseRepo
-	InventoryRepo    *repository.InventoryRepo
-	OrderRepo        *repository.OrderRepo
-	VendorRepo       *repository.VendorRepo
-	ClientRepo       *repository.ClientRepo
-	FeedbackRepo     *repository.FeedbackRepo
-	DashboardRepo    *repository.DashboardRepo
-	ReportRepo       *repository.ReportRepo
-	NotificationRepo *repository.NotificationRepo
-	SettingRepo      *repository.SettingRepo
-	RoleRepo         *repository.RoleRepo
-	ActivityRepo     *repository.ActivityRepo
-	Config           *config.Config
+	UserRepo            *repository.UserRepo
+	TenantRepo          *repository.TenantRepo
+	ShipmentRepo        *repository.ShipmentRepo
+	VehicleRepo         *repository.VehicleRepo
+	DriverRepo          *repository.DriverRepo
+	MaintenanceRepo     *repository.MaintenanceRepo
+	WarehouseRepo       *repository.WarehouseRepo
+	InventoryRepo       *repository.InventoryRepo
+	OrderRepo           *repository.OrderRepo
+	VendorRepo          *repository.VendorRepo
+	ClientRepo          *repository.ClientRepo
+	FeedbackRepo        *repository.FeedbackRepo
+	DashboardRepo       *repository.DashboardRepo
+	ReportRepo          *repository.ReportRepo
+	NotificationRepo    *repository.NotificationRepo
+	SettingRepo         *repository.SettingRepo
+	RoleRepo            *repository.RoleRepo
+	ActivityRepo        *repository.ActivityRepo
+	RouteRepo           *repository.RouteRepo
+	ZoneRepo            *repository.ZoneRepo
+	ImportRepo          *repository.ImportRepo
+	RateCardRepo        *repository.RateCardRepo
+	InvoiceRepo         *repository.InvoiceRepo
+	FulfillmentRepo     *repository.FulfillmentRepo
+	OrderScheduleRepo   *repository.OrderScheduleRepo
+	RMARepo             *repository.RMARepo
+	StockMovementRepo   *repository.StockMovementRepo
+	ProductRepo         *repository.ProductRepo
+	TransferRepo        *repository.TransferRepo
+	SupplierProductRepo *repository.SupplierProductRepo
+	PurchaseOrderRepo   *repository.PurchaseOrderRepo
+	Config              *config.Config
+
+	// TrackingLimiter throttles publicTracking per client IP. It is shared
+	// with the REST public tracking API so both count against one budget.
+	TrackingLimiter *middleware.RateLimiter
+
+	// Storage holds uploaded files; proof-of-delivery images are read from it.
+	Storage storage.Store
+
+	// Geocoder places structured addresses. Without one, records keep only
+	// the coordinates they are given.
+	Geocoder geocode.Geocoder
+
+	// Scheduler runs large import commits in the background. Without one,
+	// every import is committed in the request.
+	Scheduler *jobs.Scheduler
 }
 
+// Public tracking allows this many lookups per client IP per window.
+const (
+	PublicTrackingLimit  = 20
+	PublicTrackingWindow = time.Minute
+)
+
 // NewResolver constructs a Resolver with all required dependencies.
 func NewResolver(
 	userRepo *repository.UserRepo,
@@ -55,28 +94,55 @@ func NewResolver(
 	settingRepo *repository.SettingRepo,
 	roleRepo *repository.RoleRepo,
 	activityRepo *repository.ActivityRepo,
+	routeRepo *repository.RouteRepo,
+	zoneRepo *repository.ZoneRepo,
+	importRepo *repository.ImportRepo,
+	rateCardRepo *repository.RateCardRepo,
+	invoiceRepo *repository.InvoiceRepo,
+	fulfillmentRepo *repository.FulfillmentRepo,
+	orderScheduleRepo *repository.OrderScheduleRepo,
+	rmaRepo *repository.RMARepo,
+	stockMovementRepo *repository.StockMovementRepo,
+	productRepo *repository.ProductRepo,
+	transferRepo *repository.TransferRepo,
+	supplierProductRepo *repository.SupplierProductRepo,
+	purchaseOrderRepo *repository.PurchaseOrderRepo,
 	cfg *config.Config,
 ) *Resolver {
 	return &Resolver{
-		UserRepo:         userRepo,
-		TenantRepo:       tenantRepo,
-		ShipmentRepo:     shipmentRepo,
-		VehicleRepo:      vehicleRepo,
-		DriverRepo:       driverRepo,
-		MaintenanceRepo:  maintenanceRepo,
-		WarehouseRepo:    warehouseRepo,
-		InventoryRepo:    inventoryRepo,
-		OrderRepo:        orderRepo,
-		VendorRepo:       vendorRepo,
-		ClientRepo:       clientRepo,
-		FeedbackRepo:     feedbackRepo,
-		DashboardRepo:    dashboardRepo,
-		ReportRepo:       reportRepo,
-		NotificationRepo: notificationRepo,
-		SettingRepo:      settingRepo,
-		RoleRepo:         roleRepo,
-		ActivityRepo:     activityRepo,
-		Config:           cfg,
+		UserRepo:            userRepo,
+		TenantRepo:          tenantRepo,
+		ShipmentRepo:        shipmentRepo,
+		VehicleRepo:         vehicleRepo,
+		DriverRepo:          driverRepo,
+		MaintenanceRepo:     maintenanceRepo,
+		WarehouseRepo:       warehouseRepo,
+		InventoryRepo:       inventoryRepo,
+		OrderRepo:           orderRepo,
+		VendorRepo:          vendorRepo,
+		ClientRepo:          clientRepo,
+		FeedbackRepo:        feedbackRepo,
+		DashboardRepo:       dashboardRepo,
+		ReportRepo:          reportRepo,
+		NotificationRepo:    notificationRepo,
+		SettingRepo:         settingRepo,
+		RoleRepo:            roleRepo,
+		ActivityRepo:        activityRepo,
+		RouteRepo:           routeRepo,
+		ZoneRepo:            zoneRepo,
+		ImportRepo:          importRepo,
+		RateCardRepo:        rateCardRepo,
+		InvoiceRepo:         invoiceRepo,
+		FulfillmentRepo:     fulfillmentRepo,
+		OrderScheduleRepo:   orderScheduleRepo,
+		RMARepo:             rmaRepo,
+		StockMovementRepo:   stockMovementRepo,
+		ProductRepo:         productRepo,
+		TransferRepo:        transferRepo,
+		SupplierProductRepo: supplierProductRepo,
+		PurchaseOrderRepo:   purchaseOrderRepo,
+		Config:              cfg,
+		TrackingLimiter:     middleware.NewRateLimiter(PublicTrackingLimit, PublicTrackingWindow),
 	}
 }
 
@@ -0,0 +1,355 @@
+package resolvers
+
+import (
+	"context"
+	"fmt"
+
+	"cargomax-api/internal/graph/types"
+	"cargomax-api/internal/models"
+
+	"github.com/google/uuid"
+	"github.com/graphql-go/graphql"
+)
+
+// RMAQueries returns GraphQL query fields for return authorizations.
+func (r *Resolver) RMAQueries() graphql.Fields {
+	return graphql.Fields{
+		"rmas": &graphql.Field{
+			Type:        types.RMAConnectionType,
+			Description: "The tenant's RMAs, newest first, optionally in one status.",
+			Args: graphql.FieldConfigArgument{
+				"status":  &graphql.ArgumentConfig{Type: graphql.String},
+				"page":    &graphql.ArgumentConfig{Type: graphql.Int, DefaultValue: 1},
+				"perPage": &graphql.ArgumentConfig{Type: graphql.Int, DefaultValue: 20},
+			},
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				tenantID, err := requireTenant(p.Context)
+				if err != nil {
+					return nil, err
+				}
+				page, perPage := clampPagination(p.Args["page"].(int), p.Args["perPage"].(int))
+				status, _ := p.Args["status"].(string)
+
+				items, total, err := r.RMARepo.List(p.Context, tenantID, status, page, perPage)
+				if err != nil {
+					return nil, err
+				}
+
+				totalPages := 0
+				if perPage > 0 {
+					totalPages = (total + perPage - 1) / perPage
+				}
+				return map[string]interface{}{
+					"items":      items,
+					"totalCount": total,
+					"page":       page,
+					"perPage":    perPage,
+					"totalPages": totalPages,
+				}, nil
+			},
+		},
+		"rma": &graphql.Field{
+			Type: types.RMAType,
+			Args: graphql.FieldConfigArgument{
+				"id": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.String)},
+			},
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				tenantID, err := requireTenant(p.Context)
+				if err != nil {
+					return nil, err
+				}
+				id, err := uuid.Parse(p.Args["id"].(string))
+				if err != nil {
+					return nil, fmt.Errorf("invalid RMA id: %w", err)
+				}
+				return r.RMARepo.GetByID(p.Context, tenantID, id)
+			},
+		},
+	}
+}
+
+// RMAMutations returns GraphQL mutation fields that take a return from
+// request through approval, the return shipment and inspection to a refund
+// or credit.
+func (r *Resolver) RMAMutations() graphql.Fields {
+	return graphql.Fields{
+		"requestReturn": &graphql.Field{
+			Type: types.RMAType,
+			Description: "Open an RMA for shipped quantities of an order's lines. A line cannot have more returned than shipped, " +
+				"less what came back already or is on another open RMA.",
+			Args: graphql.FieldConfigArgument{
+				"orderId": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.String)},
+				"lines":   &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(types.ReturnLineInputType)))},
+				"reason":  &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.String)},
+				"notes":   &graphql.ArgumentConfig{Type: graphql.String},
+			},
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				tenantID, userID, err := requireAuth(p.Context)
+				if err != nil {
+					return nil, err
+				}
+				orderID, err := uuid.Parse(p.Args["orderId"].(string))
+				if err != nil {
+					return nil, fmt.Errorf("invalid order id: %w", err)
+				}
+				reason := p.Args["reason"].(string)
+				if reason == "" {
+					return nil, fmt.Errorf("a return needs a reason")
+				}
+				lines, err := returnInputs(p.Args["lines"])
+				if err != nil {
+					return nil, err
+				}
+				var notes *string
+				if v, ok := p.Args["notes"].(string); ok {
+					notes = &v
+				}
+				return r.RMARepo.Request(p.Context, tenantID, orderID, lines, reason, notes, &userID)
+			},
+		},
+		"approveReturn": &graphql.Field{
+			Type: types.RMAType,
+			Description: "Approve a requested RMA. lines gives the quantity accepted of lines not accepted in full, " +
+				"zero to refuse one; the rest are accepted as asked.",
+			Args: graphql.FieldConfigArgument{
+				"id":    &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.String)},
+				"lines": &graphql.ArgumentConfig{Type: graphql.NewList(graphql.NewNonNull(types.ReturnLineInputType))},
+			},
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				tenantID, err := requireTenant(p.Context)
+				if err != nil {
+					return nil, err
+				}
+				id, err := uuid.Parse(p.Args["id"].(string))
+				if err != nil {
+					return nil, fmt.Errorf("invalid RMA id: %w", err)
+				}
+				lines, err := returnInputs(p.Args["lines"])
+				if err != nil {
+					return nil, err
+				}
+				return r.RMARepo.Approve(p.Context, tenantID, id, lines)
+			},
+		},
+		"rejectReturn": &graphql.Field{
+			Type: types.RMAType,
+			Args: graphql.FieldConfigArgument{
+				"id":     &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.String)},
+				"reason": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.String)},
+			},
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				tenantID, err := requireTenant(p.Context)
+				if err != nil {
+					return nil, err
+				}
+				id, err := uuid.Parse(p.Args["id"].(string))
+				if err != nil {
+					return nil, fmt.Errorf("invalid RMA id: %w", err)
+				}
+				reason := p.Args["reason"].(string)
+				if reason == "" {
+					return nil, fmt.Errorf("a rejection needs a reason")
+				}
+				return r.RMARepo.Reject(p.Context, tenantID, id, reason)
+			},
+		},
+		"createReturnShipment": &graphql.Field{
+			Type:        types.RMAType,
+			Description: "Book the shipment bringing an approved RMA's goods back to its warehouse. The RMA is then in transit.",
+			Args: graphql.FieldConfigArgument{
+				"id":    &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.String)},
+				"input": &graphql.ArgumentConfig{Type: types.ReturnShipmentInputType},
+			},
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				tenantID, err := requireTenant(p.Context)
+				if err != nil {
+					return nil, err
+				}
+				id, err := uuid.Parse(p.Args["id"].(string))
+				if err != nil {
+					return nil, fmt.Errorf("invalid RMA id: %w", err)
+				}
+				input, _ := p.Args["input"].(map[string]interface{})
+				rma, err := r.RMARepo.GetByID(p.Context, tenantID, id)
+				if err != nil {
+					return nil, err
+				}
+				s, err := r.returnShipment(p.Context, tenantID, rma, input)
+				if err != nil {
+					return nil, err
+				}
+				return r.RMARepo.ShipBack(p.Context, tenantID, id, s, shipmentActor(p.Context))
+			},
+		},
+		"receiveReturn": &graphql.Field{
+			Type: types.RMAType,
+			Description: "Record the inspection of an RMA's goods on arrival. Grades A and B go back into stock and C and D are " +
+				"written off unless restock says otherwise; lines left out arrived with nothing.",
+			Args: graphql.FieldConfigArgument{
+				"id":    &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.String)},
+				"lines": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(types.InspectionInputType)))},
+			},
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				tenantID, err := requireTenant(p.Context)
+				if err != nil {
+					return nil, err
+				}
+				id, err := uuid.Parse(p.Args["id"].(string))
+				if err != nil {
+					return nil, fmt.Errorf("invalid RMA id: %w", err)
+				}
+				list, _ := p.Args["lines"].([]interface{})
+				inspections := make([]models.Inspection, 0, len(list))
+				for _, item := range list {
+					m, _ := item.(map[string]interface{})
+					v, _ := m["orderLineId"].(string)
+					lineID, err := uuid.Parse(v)
+					if err != nil {
+						return nil, fmt.Errorf("invalid order line id: %w", err)
+					}
+					in := models.Inspection{OrderLineID: lineID}
+					in.Quantity, _ = m["quantity"].(int)
+					in.Condition, _ = m["condition"].(string)
+					if v, ok := m["restock"].(bool); ok {
+						in.Restock = &v
+					}
+					if v, ok := m["notes"].(string); ok {
+						in.Notes = &v
+					}
+					inspections = append(inspections, in)
+				}
+				return r.RMARepo.Receive(p.Context, tenantID, id, inspections)
+			},
+		},
+		"resolveReturn": &graphql.Field{
+			Type: types.RMAType,
+			Description: "Settle a received RMA and close it: refund the customer, credit the invoice that billed the order with " +
+				"a credit note, or neither. amount defaults to what the goods received were sold for; a credit adds tax at the invoice's rates.",
+			Args: graphql.FieldConfigArgument{
+				"id":         &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.String)},
+				"resolution": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.String), Description: "refund, credit or none."},
+				"amount":     &graphql.ArgumentConfig{Type: graphql.Float},
+				"reference":  &graphql.ArgumentConfig{Type: graphql.String, Description: "The refund's payment reference."},
+			},
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				tenantID, userID, err := requireAuth(p.Context)
+				if err != nil {
+					return nil, err
+				}
+				id, err := uuid.Parse(p.Args["id"].(string))
+				if err != nil {
+					return nil, fmt.Errorf("invalid RMA id: %w", err)
+				}
+				res := models.RMAResolution{Kind: p.Args["resolution"].(string), CreatedBy: &userID}
+				if v, ok := p.Args["amount"].(float64); ok {
+					res.Amount = &v
+				}
+				if v, ok := p.Args["reference"].(string); ok {
+					res.Reference = &v
+				}
+				settings, err := r.SettingRepo.InvoiceSettings(p.Context, tenantID)
+				if err != nil {
+					return nil, err
+				}
+				return r.RMARepo.Resolve(p.Context, tenantID, id, res, settings)
+			},
+		},
+		"cancelReturn": &graphql.Field{
+			Type:        types.RMAType,
+			Description: "Withdraw an RMA whose goods have not been received.",
+			Args: graphql.FieldConfigArgument{
+				"id": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.String)},
+			},
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				tenantID, err := requireTenant(p.Context)
+				if err != nil {
+					return nil, err
+				}
+				id, err := uuid.Parse(p.Args["id"].(string))
+				if err != nil {
+					return nil, fmt.Errorf("invalid RMA id: %w", err)
+				}
+				return r.RMARepo.Cancel(p.Context, tenantID, id)
+			},
+		},
+	}
+}
+
+// returnInputs reads a list of ReturnLineInput. A missing list is nil.
+func returnInputs(v interface{}) ([]models.ReturnRequest, error) {
+	list, ok := v.([]interface{})
+	if !ok {
+		return nil, nil
+	}
+	out := make([]models.ReturnRequest, 0, len(list))
+	for _, item := range list {
+		m, _ := item.(map[string]interface{})
+		v, _ := m["orderLineId"].(string)
+		id, err := uuid.Parse(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid order line id: %w", err)
+		}
+		n, _ := m["quantity"].(int)
+		out = append(out, models.ReturnRequest{OrderLineID: id, Quantity: n})
+	}
+	return out, nil
+}
+
+// returnShipment fills in the shipment bringing an RMA's goods back: the
+// parcel from input, the customer from the order and a tracking number. It
+// comes from the order's client's address and goes to the RMA's warehouse
+// unless input says otherwise.
+func (r *Resolver) returnShipment(ctx context.Context, tenantID uuid.UUID, rma *models.RMA, input map[string]interface{}) (*models.Shipment, error) {
+	o, err := r.OrderRepo.GetByID(ctx, tenantID, rma.OrderID)
+	if err != nil {
+		return nil, err
+	}
+	s := &models.Shipment{
+		TenantID:      tenantID,
+		Status:        models.ShipmentPending,
+		CustomerName:  o.CustomerName,
+		CustomerEmail: o.CustomerEmail,
+	}
+	if input == nil {
+		input = map[string]interface{}{}
+	}
+	if err := r.applyShipmentInput(ctx, tenantID, s, input); err != nil {
+		return nil, err
+	}
+
+	if s.Origin == nil && o.ClientID != nil {
+		c, err := r.ClientRepo.GetByID(ctx, tenantID, *o.ClientID)
+		if err != nil {
+			return nil, err
+		}
+		s.Origin = c.Address
+		s.OriginAddress = c.AddressDetails
+	}
+	if s.Destination == nil && rma.WarehouseID != nil {
+		w, err := r.WarehouseRepo.GetByID(ctx, tenantID, *rma.WarehouseID)
+		if err != nil {
+			return nil, err
+		}
+		destination := w.Name
+		if w.Address != nil && *w.Address != "" {
+			destination = *w.Address
+		}
+		s.Destination = &destination
+		s.DestinationAddress = w.AddressDetails
+		s.DestinationLatitude, s.DestinationLongitude = w.Latitude, w.Longitude
+	}
+	if s.Destination == nil || *s.Destination == "" {
+		return nil, fmt.Errorf("the RMA has no warehouse to return to; give a destination")
+	}
+
+	if s.TrackingNumber == "" {
+		tf, err := r.SettingRepo.TrackingFormat(ctx, tenantID)
+		if err != nil {
+			return nil, err
+		}
+		if s.TrackingNumber, err = r.ShipmentRepo.NextTrackingNumber(ctx, tenantID, tf); err != nil {
+			return nil, fmt.Errorf("failed to generate tracking number: %w", err)
+		}
+	}
+	return s, nil
+}
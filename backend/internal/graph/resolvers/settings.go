@@ -2,6 +2,7 @@ package resolvers
 
 import (
 	"fmt"
+	"strconv"
 
 	"cargomax-api/internal/graph/types"
 	"cargomax-api/internal/models"
@@ -94,7 +95,10 @@ func (r *Resolver) SettingsMutations() graphql.Fields {
 					return nil, err
 				}
 				key := p.Args["key"].(string)
-				value := p.Args["value"].(string)
+				value, err := normalizeSetting(key, p.Args["value"].(string))
+				if err != nil {
+					return nil, err
+				}
 				s := &models.Setting{ID: uuid.New(), TenantID: tenantID, Key: key, Value: &value, UpdatedBy: &userID}
 				if err := r.SettingRepo.Set(p.Context, s); err != nil {
 					return nil, err
@@ -182,3 +186,35 @@ func (r *Resolver) SettingsMutations() graphql.Fields {
 		},
 	}
 }
+
+// normalizeSetting validates the settings the server itself reads and returns
+// the value to store. Other keys are stored as given.
+func normalizeSetting(key, value string) (string, error) {
+	switch key {
+	case models.SettingTrackingPrefix:
+		return models.ParseTrackingPrefix(value)
+	case models.SettingTrackingDigits:
+		n, err := models.ParseTrackingDigits(value)
+		if err != nil {
+			return "", err
+		}
+		return strconv.Itoa(n), nil
+	case models.SettingInvoicePrefix, models.SettingCreditNotePrefix:
+		return models.ParseInvoicePrefix(value)
+	case models.SettingInvoiceTaxes:
+		taxes, err := models.ParseInvoiceTaxes(value)
+		if err != nil {
+			return "", err
+		}
+		return models.FormatInvoiceTaxes(taxes), nil
+	case models.SettingPaymentTermsDays:
+		n, err := models.ParsePaymentTermsDays(value)
+		if err != nil {
+			return "", err
+		}
+		return strconv.Itoa(n), nil
+	case models.SettingInvoiceCurrency:
+		return models.ParseInvoiceCurrency(value)
+	}
+	return value, nil
+}
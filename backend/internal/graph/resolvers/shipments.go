@@ -1,11 +1,20 @@
 package resolvers
 
 import (
+	"context"
+	"encoding/base64"
+	"errors"
 	"fmt"
+	"io"
+	"net/http"
+	"strings"
 	"time"
 
 	"cargomax-api/internal/graph/types"
+	"cargomax-api/internal/middleware"
 	"cargomax-api/internal/models"
+	"cargomax-api/internal/repository"
+	"cargomax-api/internal/storage"
 
 	"github.com/google/uuid"
 	"github.com/graphql-go/graphql"
@@ -119,6 +128,39 @@ func (r *Resolver) ShipmentQueries() graphql.Fields {
 			},
 		},
 
+		// -----------------------------------------------------------------
+		// publicTracking (unauthenticated, redacted)
+		// -----------------------------------------------------------------
+		"publicTracking": &graphql.Field{
+			Type:        types.PublicTrackingType,
+			Description: "Public tracking for consignees without a login. Resolves a tracking number across all tenants when the verifier (destination postcode or customer email) matches, returning a redacted view. Throttled per client IP.",
+			Args: graphql.FieldConfigArgument{
+				"trackingNumber": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.String)},
+				"verifier":       &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.String)},
+			},
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				// Throttling is per client IP, so a caller without one would
+				// share a bucket with every other.
+				req, ok := p.Context.Value(models.CtxHTTPRequest).(*http.Request)
+				if !ok {
+					return nil, fmt.Errorf("internal error: HTTP request not found in context")
+				}
+				if ok, _ := r.TrackingLimiter.Allow(middleware.ClientIP(req)); !ok {
+					return nil, fmt.Errorf("too many tracking requests, try again later")
+				}
+
+				trackingNumber := strings.TrimSpace(p.Args["trackingNumber"].(string))
+				tracking, err := r.ShipmentRepo.FindPublicTracking(p.Context, trackingNumber, p.Args["verifier"].(string))
+				if errors.Is(err, repository.ErrShipmentNotFound) {
+					return nil, fmt.Errorf("no shipment matches that tracking number and verifier")
+				}
+				if err != nil {
+					return nil, fmt.Errorf("failed to look up shipment: %w", err)
+				}
+				return tracking, nil
+			},
+		},
+
 		// -----------------------------------------------------------------
 		// delayedShipments
 		// -----------------------------------------------------------------
@@ -138,6 +180,26 @@ func (r *Resolver) ShipmentQueries() graphql.Fields {
 				return shipments, nil
 			},
 		},
+
+		// -----------------------------------------------------------------
+		// atRiskShipments
+		// -----------------------------------------------------------------
+		"atRiskShipments": &graphql.Field{
+			Type:        graphql.NewList(types.ShipmentType),
+			Description: "Returns open shipments whose live ETA lands after their promised delivery time, before that time has passed.",
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				tenantID, err := requireTenant(p.Context)
+				if err != nil {
+					return nil, err
+				}
+
+				shipments, err := r.ShipmentRepo.GetAtRisk(p.Context, tenantID)
+				if err != nil {
+					return nil, fmt.Errorf("failed to list at-risk shipments: %w", err)
+				}
+				return shipments, nil
+			},
+		},
 	}
 }
 
@@ -162,53 +224,27 @@ func (r *Resolver) ShipmentMutations() graphql.Fields {
 
 				now := time.Now()
 				shipment := &models.Shipment{
-					ID:             uuid.New(),
-					TenantID:       tenantID,
-					TrackingNumber: input["trackingNumber"].(string),
-					Status:         "pending",
-					CreatedAt:      now,
-					UpdatedAt:      now,
+					ID:        uuid.New(),
+					TenantID:  tenantID,
+					Status:    "pending",
+					CreatedAt: now,
+					UpdatedAt: now,
 				}
 
-				if v, ok := input["origin"].(string); ok {
-					shipment.Origin = &v
-				}
-				if v, ok := input["destination"].(string); ok {
-					shipment.Destination = &v
-				}
-				if v, ok := input["status"].(string); ok && v != "" {
-					shipment.Status = v
-				}
-				if v, ok := input["carrier"].(string); ok {
-					shipment.Carrier = &v
-				}
-				if v, ok := input["weight"].(float64); ok {
-					shipment.Weight = &v
-				}
-				if v, ok := input["dimensions"].(string); ok {
-					shipment.Dimensions = &v
+				if err := r.applyShipmentInput(p.Context, tenantID, shipment, input); err != nil {
+					return nil, err
 				}
-				if v, ok := input["estimatedDelivery"].(string); ok && v != "" {
-					if t, err := time.Parse(time.RFC3339, v); err == nil {
-						shipment.EstimatedDelivery = &t
+				if shipment.TrackingNumber == "" {
+					f, err := r.SettingRepo.TrackingFormat(p.Context, tenantID)
+					if err != nil {
+						return nil, err
 					}
-				}
-				if v, ok := input["actualDelivery"].(string); ok && v != "" {
-					if t, err := time.Parse(time.RFC3339, v); err == nil {
-						shipment.ActualDelivery = &t
+					if shipment.TrackingNumber, err = r.ShipmentRepo.NextTrackingNumber(p.Context, tenantID, f); err != nil {
+						return nil, fmt.Errorf("failed to generate tracking number: %w", err)
 					}
 				}
-				if v, ok := input["customerName"].(string); ok {
-					shipment.CustomerName = &v
-				}
-				if v, ok := input["customerEmail"].(string); ok {
-					shipment.CustomerEmail = &v
-				}
-				if v, ok := input["notes"].(string); ok {
-					shipment.Notes = &v
-				}
 
-				if err := r.ShipmentRepo.Create(p.Context, shipment); err != nil {
+				if err := r.ShipmentRepo.Create(p.Context, shipment, shipmentActor(p.Context)); err != nil {
 					return nil, fmt.Errorf("failed to create shipment: %w", err)
 				}
 				return shipment, nil
@@ -243,8 +279,14 @@ func (r *Resolver) ShipmentMutations() graphql.Fields {
 
 				input := p.Args["input"].(map[string]interface{})
 
-				if v, ok := input["trackingNumber"].(string); ok {
-					shipment.TrackingNumber = v
+				if v, ok := input["trackingNumber"].(string); ok && strings.TrimSpace(v) != shipment.TrackingNumber {
+					shipment.TrackingNumber = strings.TrimSpace(v)
+					if shipment.TrackingNumber == "" {
+						return nil, fmt.Errorf("trackingNumber cannot be empty")
+					}
+					if err := r.checkTrackingNumber(p.Context, tenantID, shipment.TrackingNumber); err != nil {
+						return nil, err
+					}
 				}
 				if v, ok := input["origin"].(string); ok {
 					shipment.Origin = &v
@@ -252,9 +294,6 @@ func (r *Resolver) ShipmentMutations() graphql.Fields {
 				if v, ok := input["destination"].(string); ok {
 					shipment.Destination = &v
 				}
-				if v, ok := input["status"].(string); ok {
-					shipment.Status = v
-				}
 				if v, ok := input["carrier"].(string); ok {
 					shipment.Carrier = &v
 				}
@@ -283,9 +322,38 @@ func (r *Resolver) ShipmentMutations() graphql.Fields {
 				if v, ok := input["notes"].(string); ok {
 					shipment.Notes = &v
 				}
+				if v, ok := input["destinationLatitude"].(float64); ok {
+					shipment.DestinationLatitude = &v
+				}
+				if v, ok := input["destinationLongitude"].(float64); ok {
+					shipment.DestinationLongitude = &v
+				}
+
+				if err := r.setShipmentAddresses(p.Context, shipment, input); err != nil {
+					return nil, err
+				}
+				if err := checkDestination(shipment); err != nil {
+					return nil, err
+				}
+				if err := r.setShipmentWarehouse(p.Context, tenantID, shipment, input); err != nil {
+					return nil, err
+				}
 
 				shipment.UpdatedAt = time.Now()
 
+				// A status change goes through the state machine in the same
+				// transaction as the other fields, so an illegal move or a
+				// failed field update leaves the shipment and its timeline as
+				// they were.
+				if v, ok := input["status"].(string); ok && v != "" && v != shipment.Status {
+					ev := shipmentActor(p.Context)
+					ev.Status = v
+					if err := r.ShipmentRepo.UpdateWithTransition(p.Context, tenantID, id, shipment, ev); err != nil {
+						return nil, fmt.Errorf("failed to update shipment: %w", err)
+					}
+					return shipment, nil
+				}
+
 				if err := r.ShipmentRepo.Update(p.Context, tenantID, id, shipment); err != nil {
 					return nil, fmt.Errorf("failed to update shipment: %w", err)
 				}
@@ -293,6 +361,126 @@ func (r *Resolver) ShipmentMutations() graphql.Fields {
 			},
 		},
 
+		// -----------------------------------------------------------------
+		// updateShipmentStatus
+		// -----------------------------------------------------------------
+		"updateShipmentStatus": &graphql.Field{
+			Type:        types.ShipmentType,
+			Description: "Move a shipment to a new status and record the change on its timeline. Illegal transitions are rejected.",
+			Args: graphql.FieldConfigArgument{
+				"id":         &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.String)},
+				"status":     &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.String)},
+				"location":   &graphql.ArgumentConfig{Type: graphql.String},
+				"note":       &graphql.ArgumentConfig{Type: graphql.String},
+				"occurredAt": &graphql.ArgumentConfig{Type: graphql.String},
+			},
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				tenantID, err := requireTenant(p.Context)
+				if err != nil {
+					return nil, err
+				}
+				id, err := uuid.Parse(p.Args["id"].(string))
+				if err != nil {
+					return nil, fmt.Errorf("invalid shipment id: %w", err)
+				}
+
+				ev := shipmentActor(p.Context)
+				ev.Status = p.Args["status"].(string)
+				if v, ok := p.Args["location"].(string); ok && v != "" {
+					ev.Location = &v
+				}
+				if v, ok := p.Args["note"].(string); ok && v != "" {
+					ev.Note = &v
+				}
+				if v, ok := p.Args["occurredAt"].(string); ok && v != "" {
+					t, err := time.Parse(time.RFC3339, v)
+					if err != nil {
+						return nil, fmt.Errorf("invalid occurredAt: %w", err)
+					}
+					ev.OccurredAt = t
+				}
+
+				shipment, err := r.ShipmentRepo.Transition(p.Context, tenantID, id, ev)
+				if err != nil {
+					return nil, fmt.Errorf("failed to update shipment status: %w", err)
+				}
+				return shipment, nil
+			},
+		},
+
+		// -----------------------------------------------------------------
+		// assignShipment
+		// -----------------------------------------------------------------
+		"assignShipment": &graphql.Field{
+			Type:        types.ShipmentAssignmentType,
+			Description: "Assign a shipment to a driver and/or vehicle, replacing its current assignment. A driver on an active shift is attached with the shift's truck unless another vehicle is given.",
+			Args: graphql.FieldConfigArgument{
+				"shipmentId": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.String)},
+				"driverId":   &graphql.ArgumentConfig{Type: graphql.String},
+				"vehicleId":  &graphql.ArgumentConfig{Type: graphql.String},
+			},
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				tenantID, userID, err := requireAuth(p.Context)
+				if err != nil {
+					return nil, err
+				}
+				shipmentID, err := uuid.Parse(p.Args["shipmentId"].(string))
+				if err != nil {
+					return nil, fmt.Errorf("invalid shipment id: %w", err)
+				}
+
+				a := &models.ShipmentAssignment{ShipmentID: shipmentID, AssignedBy: &userID}
+				if v, ok := p.Args["driverId"].(string); ok && v != "" {
+					id, err := uuid.Parse(v)
+					if err != nil {
+						return nil, fmt.Errorf("invalid driver id: %w", err)
+					}
+					a.DriverID = &id
+				}
+				if v, ok := p.Args["vehicleId"].(string); ok && v != "" {
+					id, err := uuid.Parse(v)
+					if err != nil {
+						return nil, fmt.Errorf("invalid vehicle id: %w", err)
+					}
+					a.VehicleID = &id
+				}
+				if a.DriverID == nil && a.VehicleID == nil {
+					return nil, fmt.Errorf("driverId or vehicleId is required")
+				}
+
+				if err := r.ShipmentRepo.Assign(p.Context, tenantID, a); err != nil {
+					return nil, fmt.Errorf("failed to assign shipment: %w", err)
+				}
+				return a, nil
+			},
+		},
+
+		// -----------------------------------------------------------------
+		// unassignShipment
+		// -----------------------------------------------------------------
+		"unassignShipment": &graphql.Field{
+			Type:        graphql.Boolean,
+			Description: "Close a shipment's current assignment. The assignment stays in its history.",
+			Args: graphql.FieldConfigArgument{
+				"shipmentId": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.String)},
+			},
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				tenantID, err := requireTenant(p.Context)
+				if err != nil {
+					return nil, err
+				}
+				shipmentID, err := uuid.Parse(p.Args["shipmentId"].(string))
+				if err != nil {
+					return false, fmt.Errorf("invalid shipment id: %w", err)
+				}
+
+				if err := r.ShipmentRepo.Unassign(p.Context, tenantID, shipmentID); err != nil {
+					return false, fmt.Errorf("failed to unassign shipment: %w", err)
+				}
+				return true, nil
+			},
+		},
+
 		// -----------------------------------------------------------------
 		// deleteShipment
 		// -----------------------------------------------------------------
@@ -320,3 +508,177 @@ func (r *Resolver) ShipmentMutations() graphql.Fields {
 		},
 	}
 }
+
+// applyShipmentInput sets the fields given in a ShipmentInput on shipment:
+// a known status, addresses (geocoding the destination), valid destination
+// coordinates, a warehouse of the tenant and a tracking number whose check
+// digit is right. It is shared by createShipment and bulk import; a blank
+// tracking number is left for the caller to generate.
+func (r *Resolver) applyShipmentInput(ctx context.Context, tenantID uuid.UUID, shipment *models.Shipment, input map[string]interface{}) error {
+	if v, ok := input["origin"].(string); ok {
+		shipment.Origin = &v
+	}
+	if v, ok := input["destination"].(string); ok {
+		shipment.Destination = &v
+	}
+	if v, ok := input["status"].(string); ok && v != "" {
+		if !models.IsShipmentStatus(v) {
+			return fmt.Errorf("unknown shipment status %q", v)
+		}
+		shipment.Status = v
+	}
+	if v, ok := input["carrier"].(string); ok {
+		shipment.Carrier = &v
+	}
+	if v, ok := input["weight"].(float64); ok {
+		shipment.Weight = &v
+	}
+	if v, ok := input["dimensions"].(string); ok {
+		shipment.Dimensions = &v
+	}
+	if v, ok := input["estimatedDelivery"].(string); ok && v != "" {
+		if t, err := time.Parse(time.RFC3339, v); err == nil {
+			shipment.EstimatedDelivery = &t
+		}
+	}
+	if v, ok := input["actualDelivery"].(string); ok && v != "" {
+		if t, err := time.Parse(time.RFC3339, v); err == nil {
+			shipment.ActualDelivery = &t
+		}
+	}
+	if v, ok := input["customerName"].(string); ok {
+		shipment.CustomerName = &v
+	}
+	if v, ok := input["customerEmail"].(string); ok {
+		shipment.CustomerEmail = &v
+	}
+	if v, ok := input["notes"].(string); ok {
+		shipment.Notes = &v
+	}
+	if v, ok := input["destinationLatitude"].(float64); ok {
+		shipment.DestinationLatitude = &v
+	}
+	if v, ok := input["destinationLongitude"].(float64); ok {
+		shipment.DestinationLongitude = &v
+	}
+
+	if err := r.setShipmentAddresses(ctx, shipment, input); err != nil {
+		return err
+	}
+	if err := checkDestination(shipment); err != nil {
+		return err
+	}
+	if err := r.setShipmentWarehouse(ctx, tenantID, shipment, input); err != nil {
+		return err
+	}
+
+	if v, _ := input["trackingNumber"].(string); strings.TrimSpace(v) != "" {
+		shipment.TrackingNumber = strings.TrimSpace(v)
+		if err := r.checkTrackingNumber(ctx, tenantID, shipment.TrackingNumber); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// checkDestination requires the destination coordinates to be given
+// together and to be a valid position.
+func checkDestination(s *models.Shipment) error {
+	lat, lng := s.DestinationLatitude, s.DestinationLongitude
+	if lat == nil && lng == nil {
+		return nil
+	}
+	if lat == nil || lng == nil {
+		return fmt.Errorf("destinationLatitude and destinationLongitude must be set together")
+	}
+	if *lat < -90 || *lat > 90 || *lng < -180 || *lng > 180 {
+		return fmt.Errorf("destination coordinates out of range")
+	}
+	return nil
+}
+
+// checkTrackingNumber rejects a caller-supplied tracking number that has the
+// shape of the tenant's generated numbers but a wrong check digit, which is
+// almost always a typo. Numbers in any other format, such as a carrier's, are
+// accepted as they are.
+func (r *Resolver) checkTrackingNumber(ctx context.Context, tenantID uuid.UUID, tn string) error {
+	f, err := r.SettingRepo.TrackingFormat(ctx, tenantID)
+	if err != nil {
+		return err
+	}
+	if f.Matches(tn) && !f.Valid(tn) {
+		return fmt.Errorf("tracking number %s has an invalid check digit", tn)
+	}
+	return nil
+}
+
+// setShipmentWarehouse applies input's warehouseId to s after checking the
+// warehouse belongs to the tenant; an empty id clears it. A shipment without
+// an origin takes the warehouse's address, or its name, and its structured
+// address.
+func (r *Resolver) setShipmentWarehouse(ctx context.Context, tenantID uuid.UUID, s *models.Shipment, input map[string]interface{}) error {
+	v, ok := input["warehouseId"].(string)
+	if !ok {
+		return nil
+	}
+	if v == "" {
+		s.WarehouseID = nil
+		return nil
+	}
+	id, err := uuid.Parse(v)
+	if err != nil {
+		return fmt.Errorf("invalid warehouse id: %w", err)
+	}
+	w, err := r.WarehouseRepo.GetByID(ctx, tenantID, id)
+	if err != nil {
+		return fmt.Errorf("warehouse not found")
+	}
+	s.WarehouseID = &w.ID
+	if s.OriginAddress == nil {
+		s.OriginAddress = w.AddressDetails
+	}
+	if s.Origin == nil || *s.Origin == "" {
+		origin := w.Name
+		if w.Address != nil && *w.Address != "" {
+			origin = *w.Address
+		}
+		s.Origin = &origin
+	}
+	return nil
+}
+
+// shipmentActor starts a timeline event attributed to the calling user, or to
+// the system when the context carries no user.
+func shipmentActor(ctx context.Context) *models.ShipmentEvent {
+	ev := &models.ShipmentEvent{ActorType: "system"}
+	if uid, ok := ctx.Value(models.CtxUserID).(uuid.UUID); ok && uid != uuid.Nil {
+		ev.ActorType = "user"
+		ev.ActorID = &uid
+	}
+	return ev
+}
+
+// podFileDataURL reads a proof-of-delivery image from file storage and
+// returns it as a data: URL. The file must belong to the caller's tenant.
+func (r *Resolver) podFileDataURL(ctx context.Context, f *models.PODFile) (interface{}, error) {
+	tenantID, err := requireTenant(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if f.TenantID != tenantID || r.Storage == nil {
+		return nil, nil
+	}
+	rc, err := r.Storage.Open(ctx, f.StorageKey)
+	if errors.Is(err, storage.ErrNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+	data, err := io.ReadAll(io.LimitReader(rc, models.PODMaxFileBytes+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read proof of delivery file: %w", err)
+	}
+	return "data:" + f.ContentType + ";base64," + base64.StdEncoding.EncodeToString(data), nil
+}
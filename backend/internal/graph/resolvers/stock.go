@@ -0,0 +1,182 @@
+package resolvers
+
+import (
+	"fmt"
+	"time"
+
+	"cargomax-api/internal/graph/types"
+	"cargomax-api/internal/models"
+
+	"github.com/google/uuid"
+	"github.com/graphql-go/graphql"
+)
+
+// StockQueries returns GraphQL query fields for the stock ledger.
+func (r *Resolver) StockQueries() graphql.Fields {
+	return graphql.Fields{
+		"stockMovements": &graphql.Field{
+			Type:        types.StockMovementConnectionType,
+			Description: "Stock movements, newest first, of one item or warehouse or all of them, optionally of one type and between two days.",
+			Args: graphql.FieldConfigArgument{
+				"inventoryItemId": &graphql.ArgumentConfig{Type: graphql.String},
+				"warehouseId":     &graphql.ArgumentConfig{Type: graphql.String},
+				"type":            &graphql.ArgumentConfig{Type: graphql.String},
+				"from":            &graphql.ArgumentConfig{Type: graphql.String, Description: "YYYY-MM-DD, inclusive."},
+				"to":              &graphql.ArgumentConfig{Type: graphql.String, Description: "YYYY-MM-DD, inclusive."},
+				"page":            &graphql.ArgumentConfig{Type: graphql.Int, DefaultValue: 1},
+				"perPage":         &graphql.ArgumentConfig{Type: graphql.Int, DefaultValue: 20},
+			},
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				tenantID, err := requireTenant(p.Context)
+				if err != nil {
+					return nil, err
+				}
+				page, perPage := clampPagination(p.Args["page"].(int), p.Args["perPage"].(int))
+
+				var f models.StockMovementFilter
+				if f.InventoryItemID, err = optionalID(p.Args, "inventoryItemId", "inventory item"); err != nil {
+					return nil, err
+				}
+				if f.WarehouseID, err = optionalID(p.Args, "warehouseId", "warehouse"); err != nil {
+					return nil, err
+				}
+				if v, ok := p.Args["type"].(string); ok && v != "" {
+					if !models.IsMovementType(v) {
+						return nil, fmt.Errorf("unknown movement type %q", v)
+					}
+					f.Type = v
+				}
+				if v, ok := p.Args["from"].(string); ok {
+					d, err := time.Parse("2006-01-02", v)
+					if err != nil {
+						return nil, fmt.Errorf("invalid from date, want YYYY-MM-DD: %w", err)
+					}
+					f.From = &d
+				}
+				if v, ok := p.Args["to"].(string); ok {
+					d, err := time.Parse("2006-01-02", v)
+					if err != nil {
+						return nil, fmt.Errorf("invalid to date, want YYYY-MM-DD: %w", err)
+					}
+					d = d.AddDate(0, 0, 1)
+					f.To = &d
+				}
+
+				items, total, err := r.StockMovementRepo.List(p.Context, tenantID, f, page, perPage)
+				if err != nil {
+					return nil, err
+				}
+
+				totalPages := 0
+				if perPage > 0 {
+					totalPages = (total + perPage - 1) / perPage
+				}
+				return map[string]interface{}{
+					"items":      items,
+					"totalCount": total,
+					"page":       page,
+					"perPage":    perPage,
+					"totalPages": totalPages,
+				}, nil
+			},
+		},
+		"stockDiscrepancies": &graphql.Field{
+			Type:        graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(types.StockDiscrepancyType))),
+			Description: "Items, in one warehouse or all of them, whose stock on hand is not what their movements add up to.",
+			Args: graphql.FieldConfigArgument{
+				"warehouseId": &graphql.ArgumentConfig{Type: graphql.String},
+			},
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				tenantID, err := requireTenant(p.Context)
+				if err != nil {
+					return nil, err
+				}
+				warehouseID, err := optionalID(p.Args, "warehouseId", "warehouse")
+				if err != nil {
+					return nil, err
+				}
+				return r.StockMovementRepo.Discrepancies(p.Context, tenantID, warehouseID)
+			},
+		},
+	}
+}
+
+// StockMutations returns GraphQL mutation fields that move stock outside
+// orders and reconcile the ledger.
+func (r *Resolver) StockMutations() graphql.Fields {
+	return graphql.Fields{
+		"adjustStock": &graphql.Field{
+			Type:        types.InventoryItemType,
+			Description: "Change an item's stock on hand by quantity, e.g. after a count, or write damaged stock off with a negative quantity.",
+			Args: graphql.FieldConfigArgument{
+				"id":        &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.String)},
+				"quantity":  &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.Int)},
+				"type":      &graphql.ArgumentConfig{Type: graphql.String, DefaultValue: models.MovementAdjustment, Description: "adjustment or write_off."},
+				"reason":    &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.String)},
+				"reference": &graphql.ArgumentConfig{Type: graphql.String},
+			},
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				tenantID, err := requireTenant(p.Context)
+				if err != nil {
+					return nil, err
+				}
+				id, err := uuid.Parse(p.Args["id"].(string))
+				if err != nil {
+					return nil, fmt.Errorf("invalid inventory item id: %w", err)
+				}
+				n := p.Args["quantity"].(int)
+				movementType := p.Args["type"].(string)
+				switch {
+				case movementType != models.MovementAdjustment && movementType != models.MovementWriteOff:
+					return nil, fmt.Errorf("stock can only be adjusted or written off by hand")
+				case n == 0:
+					return nil, fmt.Errorf("quantity cannot be zero")
+				case movementType == models.MovementWriteOff && n > 0:
+					return nil, fmt.Errorf("a write-off takes stock away; give a negative quantity")
+				}
+				ref := models.StockRef{Reason: p.Args["reason"].(string)}
+				if ref.Reason == "" {
+					return nil, fmt.Errorf("an adjustment needs a reason")
+				}
+				ref.Number, _ = p.Args["reference"].(string)
+				if err := r.InventoryRepo.Adjust(p.Context, tenantID, id, movementType, n, ref); err != nil {
+					return nil, err
+				}
+				return r.InventoryRepo.GetByID(p.Context, tenantID, id)
+			},
+		},
+		"reconcileStock": &graphql.Field{
+			Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(types.StockDiscrepancyType))),
+			Description: "Post an adjustment for every item, in one warehouse or all of them, whose ledger disagrees with its stock on hand, " +
+				"taking the stock on hand as counted. Returns the items adjusted.",
+			Args: graphql.FieldConfigArgument{
+				"warehouseId": &graphql.ArgumentConfig{Type: graphql.String},
+				"reason":      &graphql.ArgumentConfig{Type: graphql.String, DefaultValue: "Reconciliation"},
+			},
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				tenantID, err := requireTenant(p.Context)
+				if err != nil {
+					return nil, err
+				}
+				warehouseID, err := optionalID(p.Args, "warehouseId", "warehouse")
+				if err != nil {
+					return nil, err
+				}
+				return r.StockMovementRepo.Reconcile(p.Context, tenantID, warehouseID, p.Args["reason"].(string))
+			},
+		},
+	}
+}
+
+// optionalID parses the optional id argument key, naming what in the error.
+func optionalID(args map[string]interface{}, key, what string) (*uuid.UUID, error) {
+	v, ok := args[key].(string)
+	if !ok {
+		return nil, nil
+	}
+	id, err := uuid.Parse(v)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s id: %w", what, err)
+	}
+	return &id, nil
+}
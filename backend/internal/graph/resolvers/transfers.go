@@ -0,0 +1,246 @@
+package resolvers
+
+import (
+	"context"
+	"fmt"
+
+	"cargomax-api/internal/graph/types"
+	"cargomax-api/internal/models"
+
+	"github.com/google/uuid"
+	"github.com/graphql-go/graphql"
+)
+
+// TransferQueries returns GraphQL query fields for transfer orders.
+func (r *Resolver) TransferQueries() graphql.Fields {
+	return graphql.Fields{
+		"transferOrders": &graphql.Field{
+			Type:        types.TransferOrderConnectionType,
+			Description: "The tenant's transfer orders, newest first, optionally in one status and to or from one warehouse.",
+			Args: graphql.FieldConfigArgument{
+				"status":      &graphql.ArgumentConfig{Type: graphql.String},
+				"warehouseId": &graphql.ArgumentConfig{Type: graphql.String},
+				"page":        &graphql.ArgumentConfig{Type: graphql.Int, DefaultValue: 1},
+				"perPage":     &graphql.ArgumentConfig{Type: graphql.Int, DefaultValue: 20},
+			},
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				tenantID, err := requireTenant(p.Context)
+				if err != nil {
+					return nil, err
+				}
+				page, perPage := clampPagination(p.Args["page"].(int), p.Args["perPage"].(int))
+				status, _ := p.Args["status"].(string)
+				if status != "" && !models.IsTransferStatus(status) {
+					return nil, fmt.Errorf("unknown transfer order status %q", status)
+				}
+				warehouseID, err := optionalID(p.Args, "warehouseId", "warehouse")
+				if err != nil {
+					return nil, err
+				}
+
+				items, total, err := r.TransferRepo.List(p.Context, tenantID, status, warehouseID, page, perPage)
+				if err != nil {
+					return nil, err
+				}
+
+				totalPages := 0
+				if perPage > 0 {
+					totalPages = (total + perPage - 1) / perPage
+				}
+				return map[string]interface{}{
+					"items":      items,
+					"totalCount": total,
+					"page":       page,
+					"perPage":    perPage,
+					"totalPages": totalPages,
+				}, nil
+			},
+		},
+		"transferOrder": &graphql.Field{
+			Type: types.TransferOrderType,
+			Args: graphql.FieldConfigArgument{
+				"id": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.String)},
+			},
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				tenantID, err := requireTenant(p.Context)
+				if err != nil {
+					return nil, err
+				}
+				id, err := uuid.Parse(p.Args["id"].(string))
+				if err != nil {
+					return nil, fmt.Errorf("invalid transfer order id: %w", err)
+				}
+				return r.TransferRepo.GetByID(p.Context, tenantID, id)
+			},
+		},
+	}
+}
+
+// TransferMutations returns GraphQL mutation fields that move stock between
+// warehouses: a transfer order holds the stock at its source, is dispatched
+// on a shipment and is received at its destination.
+func (r *Resolver) TransferMutations() graphql.Fields {
+	return graphql.Fields{
+		"createTransferOrder": &graphql.Field{
+			Type:        types.TransferOrderType,
+			Description: "Open a transfer of SKUs between two warehouses. The stock is held at the source until the transfer is dispatched.",
+			Args: graphql.FieldConfigArgument{
+				"input": &graphql.ArgumentConfig{Type: graphql.NewNonNull(types.TransferOrderInputType)},
+			},
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				tenantID, userID, err := requireAuth(p.Context)
+				if err != nil {
+					return nil, err
+				}
+				input := p.Args["input"].(map[string]interface{})
+				from, err := uuid.Parse(input["fromWarehouseId"].(string))
+				if err != nil {
+					return nil, fmt.Errorf("invalid warehouse id: %w", err)
+				}
+				to, err := uuid.Parse(input["toWarehouseId"].(string))
+				if err != nil {
+					return nil, fmt.Errorf("invalid warehouse id: %w", err)
+				}
+				t := &models.TransferOrder{TenantID: tenantID, FromWarehouseID: from, ToWarehouseID: to, CreatedBy: &userID}
+				if v, ok := input["notes"].(string); ok {
+					t.Notes = &v
+				}
+				return r.TransferRepo.Create(p.Context, t, transferInputs(input["lines"]))
+			},
+		},
+		"dispatchTransferOrder": &graphql.Field{
+			Type: types.TransferOrderType,
+			Description: "Send a pending transfer on a shipment from its source warehouse to its destination. The stock leaves " +
+				"the source and shows in transit at the destination until it is received.",
+			Args: graphql.FieldConfigArgument{
+				"id":       &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.String)},
+				"shipment": &graphql.ArgumentConfig{Type: types.TransferShipmentInputType},
+			},
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				tenantID, err := requireTenant(p.Context)
+				if err != nil {
+					return nil, err
+				}
+				id, err := uuid.Parse(p.Args["id"].(string))
+				if err != nil {
+					return nil, fmt.Errorf("invalid transfer order id: %w", err)
+				}
+				input, _ := p.Args["shipment"].(map[string]interface{})
+				t, err := r.TransferRepo.GetByID(p.Context, tenantID, id)
+				if err != nil {
+					return nil, err
+				}
+				s, err := r.transferShipment(p.Context, tenantID, t, input)
+				if err != nil {
+					return nil, err
+				}
+				return r.TransferRepo.Dispatch(p.Context, tenantID, id, s, shipmentActor(p.Context))
+			},
+		},
+		"receiveTransferOrder": &graphql.Field{
+			Type: types.TransferOrderType,
+			Description: "Take a transfer in at its destination. lines gives what arrived of SKUs that did not arrive in full; " +
+				"the rest arrived as sent.",
+			Args: graphql.FieldConfigArgument{
+				"id":    &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.String)},
+				"lines": &graphql.ArgumentConfig{Type: graphql.NewList(graphql.NewNonNull(types.TransferLineInputType))},
+			},
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				tenantID, err := requireTenant(p.Context)
+				if err != nil {
+					return nil, err
+				}
+				id, err := uuid.Parse(p.Args["id"].(string))
+				if err != nil {
+					return nil, fmt.Errorf("invalid transfer order id: %w", err)
+				}
+				return r.TransferRepo.Receive(p.Context, tenantID, id, transferInputs(p.Args["lines"]))
+			},
+		},
+		"cancelTransferOrder": &graphql.Field{
+			Type:        types.TransferOrderType,
+			Description: "Withdraw a transfer that has not been dispatched, giving back the stock it held.",
+			Args: graphql.FieldConfigArgument{
+				"id": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.String)},
+			},
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				tenantID, err := requireTenant(p.Context)
+				if err != nil {
+					return nil, err
+				}
+				id, err := uuid.Parse(p.Args["id"].(string))
+				if err != nil {
+					return nil, fmt.Errorf("invalid transfer order id: %w", err)
+				}
+				return r.TransferRepo.Cancel(p.Context, tenantID, id)
+			},
+		},
+	}
+}
+
+// transferInputs reads a list of TransferLineInput. A missing list is nil.
+func transferInputs(v interface{}) []models.TransferQuantity {
+	list, ok := v.([]interface{})
+	if !ok {
+		return nil
+	}
+	out := make([]models.TransferQuantity, 0, len(list))
+	for _, item := range list {
+		m, _ := item.(map[string]interface{})
+		q := models.TransferQuantity{}
+		q.SKU, _ = m["sku"].(string)
+		q.Quantity, _ = m["quantity"].(int)
+		out = append(out, q)
+	}
+	return out
+}
+
+// transferShipment fills in the shipment carrying a transfer: the parcel
+// from input, a tracking number, and the route from the source warehouse to
+// the destination, whose name goes on it as the customer.
+func (r *Resolver) transferShipment(ctx context.Context, tenantID uuid.UUID, t *models.TransferOrder, input map[string]interface{}) (*models.Shipment, error) {
+	from, err := r.WarehouseRepo.GetByID(ctx, tenantID, t.FromWarehouseID)
+	if err != nil {
+		return nil, err
+	}
+	to, err := r.WarehouseRepo.GetByID(ctx, tenantID, t.ToWarehouseID)
+	if err != nil {
+		return nil, err
+	}
+	s := &models.Shipment{
+		TenantID:    tenantID,
+		Status:      models.ShipmentPending,
+		WarehouseID: &from.ID,
+	}
+	if input == nil {
+		input = map[string]interface{}{}
+	}
+	if err := r.applyShipmentInput(ctx, tenantID, s, input); err != nil {
+		return nil, err
+	}
+
+	origin, destination := warehouseAddress(from), warehouseAddress(to)
+	s.Origin, s.OriginAddress = &origin, from.AddressDetails
+	s.Destination, s.DestinationAddress = &destination, to.AddressDetails
+	s.DestinationLatitude, s.DestinationLongitude = to.Latitude, to.Longitude
+	s.CustomerName = &to.Name
+
+	if s.TrackingNumber == "" {
+		tf, err := r.SettingRepo.TrackingFormat(ctx, tenantID)
+		if err != nil {
+			return nil, err
+		}
+		if s.TrackingNumber, err = r.ShipmentRepo.NextTrackingNumber(ctx, tenantID, tf); err != nil {
+			return nil, fmt.Errorf("failed to generate tracking number: %w", err)
+		}
+	}
+	return s, nil
+}
+
+// warehouseAddress is a warehouse's address, or its name when it has none.
+func warehouseAddress(w *models.Warehouse) string {
+	if w.Address != nil && *w.Address != "" {
+		return *w.Address
+	}
+	return w.Name
+}
@@ -1,7 +1,9 @@
 package resolvers
 
 import (
+	"context"
 	"fmt"
+	"slices"
 
 	"cargomax-api/internal/graph/types"
 	"cargomax-api/internal/models"
@@ -90,13 +92,21 @@ func (r *Resolver) WarehouseQueries() graphql.Fields {
 			},
 		},
 		"lowStockItems": &graphql.Field{
-			Type: graphql.NewList(types.InventoryItemType),
+			Type:        graphql.NewList(types.InventoryItemType),
+			Description: "Stock levels at or below their warehouse's minimum, in one warehouse or all of them.",
+			Args: graphql.FieldConfigArgument{
+				"warehouseId": &graphql.ArgumentConfig{Type: graphql.String},
+			},
 			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
 				tenantID, err := requireTenant(p.Context)
 				if err != nil {
 					return nil, err
 				}
-				return r.InventoryRepo.GetLowStock(p.Context, tenantID)
+				warehouseID, err := optionalID(p.Args, "warehouseId", "warehouse")
+				if err != nil {
+					return nil, err
+				}
+				return r.InventoryRepo.GetLowStock(p.Context, tenantID, warehouseID)
 			},
 		},
 	}
@@ -130,8 +140,8 @@ func (r *Resolver) WarehouseMutations() graphql.Fields {
 				if v, ok := input["capacity"].(int); ok {
 					w.Capacity = v
 				}
-				if v, ok := input["usedCapacity"].(int); ok {
-					w.UsedCapacity = v
+				if err := applyWarehouseCapacity(w, input); err != nil {
+					return nil, err
 				}
 				if v, ok := input["manager"].(string); ok {
 					w.Manager = &v
@@ -142,10 +152,25 @@ func (r *Resolver) WarehouseMutations() graphql.Fields {
 				if v, ok := input["status"].(string); ok {
 					w.Status = v
 				}
+				if v, ok := input["latitude"].(float64); ok {
+					w.Latitude = &v
+				}
+				if v, ok := input["longitude"].(float64); ok {
+					w.Longitude = &v
+				}
+				if err := r.setWarehouseAddress(p.Context, w, input); err != nil {
+					return nil, err
+				}
+				if err := checkWarehouseLocation(w); err != nil {
+					return nil, err
+				}
 
 				if err := r.WarehouseRepo.Create(p.Context, w); err != nil {
 					return nil, err
 				}
+				if err := r.ZoneRepo.SyncWarehouseZone(p.Context, tenantID, w.ID); err != nil {
+					return nil, err
+				}
 				return w, nil
 			},
 		},
@@ -178,8 +203,8 @@ func (r *Resolver) WarehouseMutations() graphql.Fields {
 				if v, ok := input["capacity"].(int); ok {
 					w.Capacity = v
 				}
-				if v, ok := input["usedCapacity"].(int); ok {
-					w.UsedCapacity = v
+				if err := applyWarehouseCapacity(w, input); err != nil {
+					return nil, err
 				}
 				if v, ok := input["manager"].(string); ok {
 					w.Manager = &v
@@ -190,10 +215,25 @@ func (r *Resolver) WarehouseMutations() graphql.Fields {
 				if v, ok := input["status"].(string); ok {
 					w.Status = v
 				}
+				if v, ok := input["latitude"].(float64); ok {
+					w.Latitude = &v
+				}
+				if v, ok := input["longitude"].(float64); ok {
+					w.Longitude = &v
+				}
+				if err := r.setWarehouseAddress(p.Context, w, input); err != nil {
+					return nil, err
+				}
+				if err := checkWarehouseLocation(w); err != nil {
+					return nil, err
+				}
 
 				if err := r.WarehouseRepo.Update(p.Context, tenantID, id, w); err != nil {
 					return nil, err
 				}
+				if err := r.ZoneRepo.SyncWarehouseZone(p.Context, tenantID, id); err != nil {
+					return nil, err
+				}
 				return r.WarehouseRepo.GetByID(p.Context, tenantID, id)
 			},
 		},
@@ -229,41 +269,9 @@ func (r *Resolver) WarehouseMutations() graphql.Fields {
 				}
 				input := p.Args["input"].(map[string]interface{})
 
-				warehouseID, err := uuid.Parse(input["warehouseId"].(string))
-				if err != nil {
-					return nil, fmt.Errorf("invalid warehouse id: %w", err)
-				}
-
-				// Validate the warehouse belongs to the same tenant (prevent IDOR).
-				if _, err := r.WarehouseRepo.GetByID(p.Context, tenantID, warehouseID); err != nil {
-					return nil, fmt.Errorf("warehouse not found in tenant")
-				}
-
-				item := &models.InventoryItem{
-					TenantID:    tenantID,
-					WarehouseID: warehouseID,
-					SKU:         input["sku"].(string),
-				}
-				if v, ok := input["name"].(string); ok {
-					item.Name = &v
-				}
-				if v, ok := input["category"].(string); ok {
-					item.Category = &v
-				}
-				if v, ok := input["quantity"].(int); ok {
-					item.Quantity = v
-				}
-				if v, ok := input["minQuantity"].(int); ok {
-					item.MinQuantity = v
-				}
-				if v, ok := input["unitPrice"].(float64); ok {
-					item.UnitPrice = &v
-				}
-				if v, ok := input["weight"].(float64); ok {
-					item.Weight = &v
-				}
-				if v, ok := input["status"].(string); ok {
-					item.Status = v
+				item := &models.InventoryItem{TenantID: tenantID}
+				if err := r.applyInventoryInput(p.Context, tenantID, item, input); err != nil {
+					return nil, err
 				}
 
 				if err := r.InventoryRepo.Create(p.Context, item); err != nil {
@@ -299,9 +307,14 @@ func (r *Resolver) WarehouseMutations() graphql.Fields {
 					return nil, fmt.Errorf("warehouse not found in tenant")
 				}
 
+				current, err := r.InventoryRepo.GetByID(p.Context, tenantID, id)
+				if err != nil {
+					return nil, err
+				}
 				item := &models.InventoryItem{
 					WarehouseID: warehouseID,
 					SKU:         input["sku"].(string),
+					Quantity:    current.Quantity,
 				}
 				if v, ok := input["name"].(string); ok {
 					item.Name = &v
@@ -325,17 +338,21 @@ func (r *Resolver) WarehouseMutations() graphql.Fields {
 					item.Status = v
 				}
 
-				if err := r.InventoryRepo.Update(p.Context, tenantID, id, item); err != nil {
+				reason, _ := input["reason"].(string)
+				if err := r.InventoryRepo.Update(p.Context, tenantID, id, item, reason); err != nil {
 					return nil, err
 				}
 				return r.InventoryRepo.GetByID(p.Context, tenantID, id)
 			},
 		},
 		"restockItem": &graphql.Field{
-			Type: types.InventoryItemType,
+			Type:        types.InventoryItemType,
+			Description: "Receive stock of an item, recorded as a receipt.",
 			Args: graphql.FieldConfigArgument{
-				"id":       &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.String)},
-				"quantity": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.Int)},
+				"id":        &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.String)},
+				"quantity":  &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.Int)},
+				"reason":    &graphql.ArgumentConfig{Type: graphql.String},
+				"reference": &graphql.ArgumentConfig{Type: graphql.String, Description: "The document the stock came in on, e.g. a delivery note number."},
 			},
 			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
 				tenantID, err := requireTenant(p.Context)
@@ -351,7 +368,10 @@ func (r *Resolver) WarehouseMutations() graphql.Fields {
 					return nil, fmt.Errorf("restock quantity must be positive")
 				}
 
-				if err := r.InventoryRepo.Restock(p.Context, tenantID, id, quantity); err != nil {
+				ref := models.StockRef{}
+				ref.Reason, _ = p.Args["reason"].(string)
+				ref.Number, _ = p.Args["reference"].(string)
+				if err := r.InventoryRepo.Restock(p.Context, tenantID, id, quantity, ref); err != nil {
 					return nil, err
 				}
 				return r.InventoryRepo.GetByID(p.Context, tenantID, id)
@@ -359,3 +379,79 @@ func (r *Resolver) WarehouseMutations() graphql.Fields {
 		},
 	}
 }
+
+// checkWarehouseLocation requires the coordinates to be given together and to
+// be a valid position.
+func checkWarehouseLocation(w *models.Warehouse) error {
+	return checkLocation(w.Latitude, w.Longitude)
+}
+
+// applyWarehouseCapacity sets the capacity unit and fill thresholds of a
+// WarehouseInput on w. Thresholds are kept in ascending order. Fields left
+// out stay empty: Create fills in the defaults and Update keeps the stored
+// values.
+func applyWarehouseCapacity(w *models.Warehouse, input map[string]interface{}) error {
+	if v, ok := input["capacityUnit"].(string); ok {
+		if !models.IsCapacityUnit(v) {
+			return fmt.Errorf("unknown capacity unit %q", v)
+		}
+		w.CapacityUnit = v
+	}
+	list, ok := input["fillThresholds"].([]interface{})
+	if !ok {
+		return nil
+	}
+	w.FillThresholds = []int{}
+	for _, v := range list {
+		t, _ := v.(int)
+		if t < 1 || t > 100 {
+			return fmt.Errorf("fill thresholds must be between 1 and 100")
+		}
+		w.FillThresholds = append(w.FillThresholds, t)
+	}
+	slices.Sort(w.FillThresholds)
+	w.FillThresholds = slices.Compact(w.FillThresholds)
+	return nil
+}
+
+// applyInventoryInput sets the fields given in an InventoryItemInput on item,
+// checking that the warehouse belongs to the tenant. It is shared by
+// createInventoryItem and bulk import.
+func (r *Resolver) applyInventoryInput(ctx context.Context, tenantID uuid.UUID, item *models.InventoryItem, input map[string]interface{}) error {
+	if v, ok := input["warehouseId"].(string); ok {
+		warehouseID, err := uuid.Parse(v)
+		if err != nil {
+			return fmt.Errorf("invalid warehouse id: %w", err)
+		}
+		// Validate the warehouse belongs to the same tenant (prevent IDOR).
+		if _, err := r.WarehouseRepo.GetByID(ctx, tenantID, warehouseID); err != nil {
+			return fmt.Errorf("warehouse not found in tenant")
+		}
+		item.WarehouseID = warehouseID
+	}
+	if v, ok := input["sku"].(string); ok {
+		item.SKU = v
+	}
+	if v, ok := input["name"].(string); ok {
+		item.Name = &v
+	}
+	if v, ok := input["category"].(string); ok {
+		item.Category = &v
+	}
+	if v, ok := input["quantity"].(int); ok {
+		item.Quantity = v
+	}
+	if v, ok := input["minQuantity"].(int); ok {
+		item.MinQuantity = v
+	}
+	if v, ok := input["unitPrice"].(float64); ok {
+		item.UnitPrice = &v
+	}
+	if v, ok := input["weight"].(float64); ok {
+		item.Weight = &v
+	}
+	if v, ok := input["status"].(string); ok {
+		item.Status = v
+	}
+	return nil
+}
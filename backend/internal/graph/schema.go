@@ -25,6 +25,9 @@ func NewSchema(r *resolvers.Resolver) (graphql.Schema, error) {
 	for k, v := range r.FleetQueries() {
 		queryFields[k] = v
 	}
+	for k, v := range r.DispatchQueries() {
+		queryFields[k] = v
+	}
 	for k, v := range r.WarehouseQueries() {
 		queryFields[k] = v
 	}
@@ -43,6 +46,39 @@ func NewSchema(r *resolvers.Resolver) (graphql.Schema, error) {
 	for k, v := range r.SettingsQueries() {
 		queryFields[k] = v
 	}
+	for k, v := range r.GeocodingQueries() {
+		queryFields[k] = v
+	}
+	for k, v := range r.ImportQueries() {
+		queryFields[k] = v
+	}
+	for k, v := range r.PricingQueries() {
+		queryFields[k] = v
+	}
+	for k, v := range r.InvoiceQueries() {
+		queryFields[k] = v
+	}
+	for k, v := range r.FulfillmentQueries() {
+		queryFields[k] = v
+	}
+	for k, v := range r.OrderScheduleQueries() {
+		queryFields[k] = v
+	}
+	for k, v := range r.RMAQueries() {
+		queryFields[k] = v
+	}
+	for k, v := range r.StockQueries() {
+		queryFields[k] = v
+	}
+	for k, v := range r.ProductQueries() {
+		queryFields[k] = v
+	}
+	for k, v := range r.TransferQueries() {
+		queryFields[k] = v
+	}
+	for k, v := range r.PurchaseOrderQueries() {
+		queryFields[k] = v
+	}
 
 	// Merge mutation fields from every domain resolver.
 	for k, v := range r.AuthMutations() {
@@ -54,6 +90,9 @@ func NewSchema(r *resolvers.Resolver) (graphql.Schema, error) {
 	for k, v := range r.FleetMutations() {
 		mutationFields[k] = v
 	}
+	for k, v := range r.DispatchMutations() {
+		mutationFields[k] = v
+	}
 	for k, v := range r.WarehouseMutations() {
 		mutationFields[k] = v
 	}
@@ -69,6 +108,42 @@ func NewSchema(r *resolvers.Resolver) (graphql.Schema, error) {
 	for k, v := range r.SettingsMutations() {
 		mutationFields[k] = v
 	}
+	for k, v := range r.GeocodingMutations() {
+		mutationFields[k] = v
+	}
+	for k, v := range r.ImportMutations() {
+		mutationFields[k] = v
+	}
+	for k, v := range r.PricingMutations() {
+		mutationFields[k] = v
+	}
+	for k, v := range r.InvoiceMutations() {
+		mutationFields[k] = v
+	}
+	for k, v := range r.FulfillmentMutations() {
+		mutationFields[k] = v
+	}
+	for k, v := range r.OrderScheduleMutations() {
+		mutationFields[k] = v
+	}
+	for k, v := range r.RMAMutations() {
+		mutationFields[k] = v
+	}
+	for k, v := range r.StockMutations() {
+		mutationFields[k] = v
+	}
+	for k, v := range r.ProductMutations() {
+		mutationFields[k] = v
+	}
+	for k, v := range r.TransferMutations() {
+		mutationFields[k] = v
+	}
+	for k, v := range r.PurchaseOrderMutations() {
+		mutationFields[k] = v
+	}
+
+	// Nested relationship fields (Order.shipment, Driver.vehicle, ...).
+	r.AttachRelations()
 
 	return graphql.NewSchema(graphql.SchemaConfig{
 		Query: graphql.NewObject(graphql.ObjectConfig{
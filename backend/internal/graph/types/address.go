@@ -0,0 +1,53 @@
+package types
+
+import "github.com/graphql-go/graphql"
+
+// ---------------------------------------------------------------------------
+// Address
+// ---------------------------------------------------------------------------
+
+// AddressType is a postal address broken into parts.
+var AddressType = graphql.NewObject(graphql.ObjectConfig{
+	Name: "Address",
+	Fields: graphql.Fields{
+		"line1":      &graphql.Field{Type: graphql.String},
+		"line2":      &graphql.Field{Type: graphql.String},
+		"city":       &graphql.Field{Type: graphql.String},
+		"region":     &graphql.Field{Type: graphql.String},
+		"postalCode": &graphql.Field{Type: graphql.String},
+		"country":    &graphql.Field{Type: graphql.String, Description: "ISO 3166-1 alpha-2 code."},
+	},
+})
+
+// AddressInputType contains the parts of a postal address.
+var AddressInputType = graphql.NewInputObject(graphql.InputObjectConfig{
+	Name: "AddressInput",
+	Fields: graphql.InputObjectConfigFieldMap{
+		"line1":      &graphql.InputObjectFieldConfig{Type: graphql.String},
+		"line2":      &graphql.InputObjectFieldConfig{Type: graphql.String},
+		"city":       &graphql.InputObjectFieldConfig{Type: graphql.String},
+		"region":     &graphql.InputObjectFieldConfig{Type: graphql.String},
+		"postalCode": &graphql.InputObjectFieldConfig{Type: graphql.String},
+		"country":    &graphql.InputObjectFieldConfig{Type: graphql.String},
+	},
+})
+
+// GeocodeResultType is the position found for an address.
+var GeocodeResultType = graphql.NewObject(graphql.ObjectConfig{
+	Name: "GeocodeResult",
+	Fields: graphql.Fields{
+		"latitude":  &graphql.Field{Type: graphql.NewNonNull(graphql.Float)},
+		"longitude": &graphql.Field{Type: graphql.NewNonNull(graphql.Float)},
+		"precision": &graphql.Field{Type: graphql.NewNonNull(graphql.String), Description: "postcode or city: how closely the position locates the address."},
+	},
+})
+
+// LocationSyncResultType reports what syncLocationZones did.
+var LocationSyncResultType = graphql.NewObject(graphql.ObjectConfig{
+	Name: "LocationSyncResult",
+	Fields: graphql.Fields{
+		"geocoded": &graphql.Field{Type: graphql.NewNonNull(graphql.Int), Description: "Warehouses and clients placed from their address."},
+		"unplaced": &graphql.Field{Type: graphql.NewNonNull(graphql.Int), Description: "Warehouses and clients whose address the gazetteer does not know."},
+		"zones":    &graphql.Field{Type: graphql.NewNonNull(graphql.Int), Description: "Approved zones created or updated."},
+	},
+})
@@ -22,6 +22,9 @@ var ClientType = graphql.NewObject(graphql.ObjectConfig{
 		"totalSpent":         &graphql.Field{Type: graphql.Float},
 		"satisfactionRating": &graphql.Field{Type: graphql.Float},
 		"status":             &graphql.Field{Type: graphql.String},
+		"addressDetails":     &graphql.Field{Type: AddressType, Description: "The structured form of address."},
+		"latitude":           &graphql.Field{Type: graphql.Float},
+		"longitude":          &graphql.Field{Type: graphql.Float},
 		"createdAt":          &graphql.Field{Type: graphql.String},
 		"updatedAt":          &graphql.Field{Type: graphql.String},
 	},
@@ -41,6 +44,12 @@ var ClientInputType = graphql.NewInputObject(graphql.InputObjectConfig{
 		"totalSpent":         &graphql.InputObjectFieldConfig{Type: graphql.Float},
 		"satisfactionRating": &graphql.InputObjectFieldConfig{Type: graphql.Float},
 		"status":             &graphql.InputObjectFieldConfig{Type: graphql.String},
+		"addressDetails": &graphql.InputObjectFieldConfig{
+			Type:        AddressInputType,
+			Description: "Structured address. Fills address when that is not given, and latitude/longitude by geocoding when they are not.",
+		},
+		"latitude":  &graphql.InputObjectFieldConfig{Type: graphql.Float},
+		"longitude": &graphql.InputObjectFieldConfig{Type: graphql.Float},
 	},
 })
 
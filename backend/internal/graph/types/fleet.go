@@ -22,6 +22,7 @@ var VehicleType = graphql.NewObject(graphql.ObjectConfig{
 		"nextService":  &graphql.Field{Type: graphql.String},
 		"licensePlate": &graphql.Field{Type: graphql.String},
 		"year":         &graphql.Field{Type: graphql.Int},
+		"capacityKg":   &graphql.Field{Type: graphql.Float},
 		"createdAt":    &graphql.Field{Type: graphql.String},
 		"updatedAt":    &graphql.Field{Type: graphql.String},
 	},
@@ -41,6 +42,7 @@ var VehicleInputType = graphql.NewInputObject(graphql.InputObjectConfig{
 		"nextService":  &graphql.InputObjectFieldConfig{Type: graphql.String},
 		"licensePlate": &graphql.InputObjectFieldConfig{Type: graphql.String},
 		"year":         &graphql.InputObjectFieldConfig{Type: graphql.Int},
+		"capacityKg":   &graphql.InputObjectFieldConfig{Type: graphql.Float},
 	},
 })
 
@@ -0,0 +1,67 @@
+package types
+
+import "github.com/graphql-go/graphql"
+
+// FulfillmentLineType is one entry on a pick list.
+var FulfillmentLineType = graphql.NewObject(graphql.ObjectConfig{
+	Name: "FulfillmentLine",
+	Fields: graphql.Fields{
+		"orderLineId": &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+		"lineNumber":  &graphql.Field{Type: graphql.NewNonNull(graphql.Int)},
+		"sku":         &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+		"description": &graphql.Field{Type: graphql.String},
+		"quantity":    &graphql.Field{Type: graphql.NewNonNull(graphql.Int), Description: "To pick, or picked once the pick is confirmed."},
+	},
+})
+
+// FulfillmentType is the part of an order picked at one warehouse and packed
+// into one shipment.
+var FulfillmentType = graphql.NewObject(graphql.ObjectConfig{
+	Name: "Fulfillment",
+	Fields: graphql.Fields{
+		"id":          &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+		"orderId":     &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+		"warehouseId": &graphql.Field{Type: graphql.String},
+		"status":      &graphql.Field{Type: graphql.NewNonNull(graphql.String), Description: "picking, picked, shipped or cancelled."},
+		"shipmentId":  &graphql.Field{Type: graphql.String, Description: "The shipment created when it was packed."},
+		"weight":      &graphql.Field{Type: graphql.Float},
+		"dimensions":  &graphql.Field{Type: graphql.String},
+		"lines":       &graphql.Field{Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(FulfillmentLineType)))},
+		"createdBy":   &graphql.Field{Type: graphql.String},
+		"pickedAt":    &graphql.Field{Type: graphql.String},
+		"packedAt":    &graphql.Field{Type: graphql.String},
+		"createdAt":   &graphql.Field{Type: graphql.String},
+		"updatedAt":   &graphql.Field{Type: graphql.String},
+	},
+})
+
+// PickInputType is a quantity of an order line to pick, or that was picked.
+var PickInputType = graphql.NewInputObject(graphql.InputObjectConfig{
+	Name: "PickInput",
+	Fields: graphql.InputObjectConfigFieldMap{
+		"orderLineId": &graphql.InputObjectFieldConfig{Type: graphql.NewNonNull(graphql.String)},
+		"quantity":    &graphql.InputObjectFieldConfig{Type: graphql.NewNonNull(graphql.Int)},
+	},
+})
+
+// PackInputType describes a packed parcel and the shipment to send it in.
+var PackInputType = graphql.NewInputObject(graphql.InputObjectConfig{
+	Name: "PackInput",
+	Fields: graphql.InputObjectConfigFieldMap{
+		"weight":            &graphql.InputObjectFieldConfig{Type: graphql.NewNonNull(graphql.Float), Description: "Parcel weight in kg."},
+		"dimensions":        &graphql.InputObjectFieldConfig{Type: graphql.NewNonNull(graphql.String), Description: "e.g. 40x30x20 cm."},
+		"carrier":           &graphql.InputObjectFieldConfig{Type: graphql.String},
+		"trackingNumber":    &graphql.InputObjectFieldConfig{Type: graphql.String, Description: "Generated when not given."},
+		"destination":       &graphql.InputObjectFieldConfig{Type: graphql.String},
+		"estimatedDelivery": &graphql.InputObjectFieldConfig{Type: graphql.String},
+		"notes":             &graphql.InputObjectFieldConfig{Type: graphql.String},
+
+		"destinationLatitude":  &graphql.InputObjectFieldConfig{Type: graphql.Float},
+		"destinationLongitude": &graphql.InputObjectFieldConfig{Type: graphql.Float},
+
+		"destinationAddress": &graphql.InputObjectFieldConfig{
+			Type:        AddressInputType,
+			Description: "Where the parcel goes. Without a destination, the order's client's address is used.",
+		},
+	},
+})
@@ -0,0 +1,74 @@
+package types
+
+import "github.com/graphql-go/graphql"
+
+// ---------------------------------------------------------------------------
+// Bulk import
+// ---------------------------------------------------------------------------
+
+// ImportColumnType maps a file column onto an import field.
+var ImportColumnType = graphql.NewObject(graphql.ObjectConfig{
+	Name: "ImportColumn",
+	Fields: graphql.Fields{
+		"field":  &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+		"column": &graphql.Field{Type: graphql.NewNonNull(graphql.String), Description: "Header of the column in the file."},
+	},
+})
+
+// ImportColumnInputType maps a file column onto an import field.
+var ImportColumnInputType = graphql.NewInputObject(graphql.InputObjectConfig{
+	Name: "ImportColumnInput",
+	Fields: graphql.InputObjectConfigFieldMap{
+		"field":  &graphql.InputObjectFieldConfig{Type: graphql.NewNonNull(graphql.String)},
+		"column": &graphql.InputObjectFieldConfig{Type: graphql.NewNonNull(graphql.String)},
+	},
+})
+
+// ImportRowErrorType is a problem with one row of an import file.
+var ImportRowErrorType = graphql.NewObject(graphql.ObjectConfig{
+	Name: "ImportRowError",
+	Fields: graphql.Fields{
+		"row":     &graphql.Field{Type: graphql.NewNonNull(graphql.Int), Description: "Line in the file; the header is line 1."},
+		"field":   &graphql.Field{Type: graphql.String, Description: "Empty when the problem is with the row as a whole."},
+		"message": &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+	},
+})
+
+// ImportFieldType describes a field an entity can import.
+var ImportFieldType = graphql.NewObject(graphql.ObjectConfig{
+	Name: "ImportField",
+	Fields: graphql.Fields{
+		"name":     &graphql.Field{Type: graphql.NewNonNull(graphql.String), Description: "Input field name; address parts are dotted, e.g. destinationAddress.city."},
+		"type":     &graphql.Field{Type: graphql.NewNonNull(graphql.String), Description: "string, int, float or date."},
+		"required": &graphql.Field{Type: graphql.NewNonNull(graphql.Boolean)},
+		"key":      &graphql.Field{Type: graphql.NewNonNull(graphql.Boolean), Description: "Rows are matched on this field for upserts."},
+		"ref":      &graphql.Field{Type: graphql.String, Description: "Entity the field refers to by name or key, e.g. warehouses."},
+	},
+})
+
+// ImportBatchType is an uploaded file and the outcome of its last dry run or
+// commit.
+var ImportBatchType = graphql.NewObject(graphql.ObjectConfig{
+	Name: "ImportBatch",
+	Fields: graphql.Fields{
+		"id":          &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+		"tenantId":    &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+		"entity":      &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+		"filename":    &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+		"status":      &graphql.Field{Type: graphql.NewNonNull(graphql.String), Description: "uploaded, validated, queued, completed or failed."},
+		"mode":        &graphql.Field{Type: graphql.NewNonNull(graphql.String), Description: "insert, upsert or skip: what happens to rows whose key exists."},
+		"columns":     &graphql.Field{Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(graphql.String))), Description: "Header row of the file."},
+		"mapping":     &graphql.Field{Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(ImportColumnType)))},
+		"totalRows":   &graphql.Field{Type: graphql.NewNonNull(graphql.Int)},
+		"created":     &graphql.Field{Type: graphql.NewNonNull(graphql.Int)},
+		"updated":     &graphql.Field{Type: graphql.NewNonNull(graphql.Int)},
+		"skipped":     &graphql.Field{Type: graphql.NewNonNull(graphql.Int)},
+		"failed":      &graphql.Field{Type: graphql.NewNonNull(graphql.Int), Description: "Rows with at least one error."},
+		"errors":      &graphql.Field{Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(ImportRowErrorType)))},
+		"error":       &graphql.Field{Type: graphql.String},
+		"createdBy":   &graphql.Field{Type: graphql.String},
+		"createdAt":   &graphql.Field{Type: graphql.String},
+		"updatedAt":   &graphql.Field{Type: graphql.String},
+		"completedAt": &graphql.Field{Type: graphql.String},
+	},
+})
@@ -0,0 +1,103 @@
+package types
+
+import "github.com/graphql-go/graphql"
+
+// InvoiceLineType is one billed order, or an amount credited.
+var InvoiceLineType = graphql.NewObject(graphql.ObjectConfig{
+	Name: "InvoiceLine",
+	Fields: graphql.Fields{
+		"orderId":     &graphql.Field{Type: graphql.String},
+		"description": &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+		"amount":      &graphql.Field{Type: graphql.NewNonNull(graphql.Float)},
+	},
+})
+
+// InvoiceTaxType is one tax charged on an invoice's subtotal.
+var InvoiceTaxType = graphql.NewObject(graphql.ObjectConfig{
+	Name: "InvoiceTax",
+	Fields: graphql.Fields{
+		"name":   &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+		"rate":   &graphql.Field{Type: graphql.NewNonNull(graphql.Float), Description: "Percent of the subtotal."},
+		"amount": &graphql.Field{Type: graphql.NewNonNull(graphql.Float)},
+	},
+})
+
+// PaymentType is money received against an invoice.
+var PaymentType = graphql.NewObject(graphql.ObjectConfig{
+	Name: "Payment",
+	Fields: graphql.Fields{
+		"id":        &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+		"invoiceId": &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+		"clientId":  &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+		"amount":    &graphql.Field{Type: graphql.NewNonNull(graphql.Float)},
+		"method":    &graphql.Field{Type: graphql.String},
+		"reference": &graphql.Field{Type: graphql.String},
+		"paidAt":    &graphql.Field{Type: graphql.String},
+		"createdBy": &graphql.Field{Type: graphql.String},
+		"createdAt": &graphql.Field{Type: graphql.String},
+	},
+})
+
+// InvoiceType is an invoice billing a client's delivered orders, or a credit
+// note against one. The dates are added with the relations.
+var InvoiceType = graphql.NewObject(graphql.ObjectConfig{
+	Name: "Invoice",
+	Fields: graphql.Fields{
+		"id":                &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+		"tenantId":          &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+		"clientId":          &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+		"kind":              &graphql.Field{Type: graphql.NewNonNull(graphql.String), Description: "invoice or credit_note."},
+		"invoiceNumber":     &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+		"status":            &graphql.Field{Type: graphql.NewNonNull(graphql.String), Description: "issued, partially_paid, paid or void."},
+		"creditedInvoiceId": &graphql.Field{Type: graphql.String, Description: "The invoice a credit note is against."},
+		"billToName":        &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+		"billToAddress":     &graphql.Field{Type: graphql.String},
+		"currency":          &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+		"lines":             &graphql.Field{Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(InvoiceLineType)))},
+		"taxes":             &graphql.Field{Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(InvoiceTaxType)))},
+		"subtotal":          &graphql.Field{Type: graphql.NewNonNull(graphql.Float)},
+		"taxTotal":          &graphql.Field{Type: graphql.NewNonNull(graphql.Float)},
+		"total":             &graphql.Field{Type: graphql.NewNonNull(graphql.Float)},
+		"amountPaid":        &graphql.Field{Type: graphql.NewNonNull(graphql.Float)},
+		"amountCredited":    &graphql.Field{Type: graphql.NewNonNull(graphql.Float), Description: "What credit notes have taken off the invoice."},
+		"reason":            &graphql.Field{Type: graphql.String, Description: "Why a credit note was issued or the invoice voided."},
+		"createdBy":         &graphql.Field{Type: graphql.String},
+		"createdAt":         &graphql.Field{Type: graphql.String},
+		"updatedAt":         &graphql.Field{Type: graphql.String},
+	},
+})
+
+// InvoiceConnectionType is a paginated list of invoices.
+var InvoiceConnectionType = ConnectionType("InvoiceConnection", InvoiceType)
+
+// AgingBucketType is the amount outstanding on invoices a given number of
+// days past due.
+var AgingBucketType = graphql.NewObject(graphql.ObjectConfig{
+	Name: "AgingBucket",
+	Fields: graphql.Fields{
+		"label":    &graphql.Field{Type: graphql.NewNonNull(graphql.String), Description: "current, 1-30, 31-60, 61-90 or 90+ days past due."},
+		"amount":   &graphql.Field{Type: graphql.NewNonNull(graphql.Float)},
+		"invoices": &graphql.Field{Type: graphql.NewNonNull(graphql.Int)},
+	},
+})
+
+// ClientAgingType is one client's outstanding invoices by age.
+var ClientAgingType = graphql.NewObject(graphql.ObjectConfig{
+	Name: "ClientAging",
+	Fields: graphql.Fields{
+		"clientId":    &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+		"companyName": &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+		"buckets":     &graphql.Field{Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(AgingBucketType)))},
+		"total":       &graphql.Field{Type: graphql.NewNonNull(graphql.Float)},
+	},
+})
+
+// AgingReportType is the tenant's accounts receivable by age.
+var AgingReportType = graphql.NewObject(graphql.ObjectConfig{
+	Name: "AgingReport",
+	Fields: graphql.Fields{
+		"buckets": &graphql.Field{Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(AgingBucketType)))},
+		"clients": &graphql.Field{Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(ClientAgingType))), Description: "The largest debtor first."},
+		"total":   &graphql.Field{Type: graphql.NewNonNull(graphql.Float)},
+	},
+})
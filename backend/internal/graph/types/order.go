@@ -18,11 +18,49 @@ var OrderType = graphql.NewObject(graphql.ObjectConfig{
 		"scheduledDate":      &graphql.Field{Type: graphql.String},
 		"returnReason":       &graphql.Field{Type: graphql.String},
 		"cancellationReason": &graphql.Field{Type: graphql.String},
+		"clientId":           &graphql.Field{Type: graphql.String},
+		"quote":              &graphql.Field{Type: QuoteType, Description: "The itemised price the total was set from, when it was quoted."},
+		"invoiceId":          &graphql.Field{Type: graphql.String, Description: "The invoice billing the order, once it is invoiced."},
+		"creditNoteId":       &graphql.Field{Type: graphql.String, Description: "The credit note refunding the order after a return."},
+		"orderScheduleId":    &graphql.Field{Type: graphql.String, Description: "The standing order that placed it."},
+		"scheduleFailure":    &graphql.Field{Type: graphql.String, Description: "Why a due scheduled order could not be confirmed yet, e.g. a stock shortage."},
 		"createdAt":          &graphql.Field{Type: graphql.String},
 		"updatedAt":          &graphql.Field{Type: graphql.String},
 	},
 })
 
+// OrderLineType is a quantity of an inventory item on an order, with the
+// stock it holds.
+var OrderLineType = graphql.NewObject(graphql.ObjectConfig{
+	Name: "OrderLine",
+	Fields: graphql.Fields{
+		"id":               &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+		"orderId":          &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+		"lineNumber":       &graphql.Field{Type: graphql.NewNonNull(graphql.Int)},
+		"inventoryItemId":  &graphql.Field{Type: graphql.String, Description: "Unset once the item is deleted."},
+		"sku":              &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+		"warehouseId":      &graphql.Field{Type: graphql.String},
+		"description":      &graphql.Field{Type: graphql.String},
+		"quantity":         &graphql.Field{Type: graphql.NewNonNull(graphql.Int)},
+		"unitPrice":        &graphql.Field{Type: graphql.NewNonNull(graphql.Float)},
+		"lineTotal":        &graphql.Field{Type: graphql.NewNonNull(graphql.Float)},
+		"reservedQuantity": &graphql.Field{Type: graphql.NewNonNull(graphql.Int), Description: "Held in stock for the order and not yet shipped."},
+		"shippedQuantity":  &graphql.Field{Type: graphql.NewNonNull(graphql.Int)},
+		"returnedQuantity": &graphql.Field{Type: graphql.NewNonNull(graphql.Int)},
+	},
+})
+
+// OrderLineInputType is a line for createOrder or updateOrder.
+var OrderLineInputType = graphql.NewInputObject(graphql.InputObjectConfig{
+	Name: "OrderLineInput",
+	Fields: graphql.InputObjectConfigFieldMap{
+		"sku":         &graphql.InputObjectFieldConfig{Type: graphql.NewNonNull(graphql.String)},
+		"warehouseId": &graphql.InputObjectFieldConfig{Type: graphql.String, Description: "The warehouse to take the SKU from; by default the one with the most of it available."},
+		"quantity":    &graphql.InputObjectFieldConfig{Type: graphql.NewNonNull(graphql.Int)},
+		"unitPrice":   &graphql.InputObjectFieldConfig{Type: graphql.Float, Description: "Defaults to the item's unit price."},
+	},
+})
+
 // OrderInputType contains fields for creating or updating an order.
 var OrderInputType = graphql.NewInputObject(graphql.InputObjectConfig{
 	Name: "OrderInput",
@@ -37,6 +75,9 @@ var OrderInputType = graphql.NewInputObject(graphql.InputObjectConfig{
 		"scheduledDate":      &graphql.InputObjectFieldConfig{Type: graphql.String},
 		"returnReason":       &graphql.InputObjectFieldConfig{Type: graphql.String},
 		"cancellationReason": &graphql.InputObjectFieldConfig{Type: graphql.String},
+		"clientId":           &graphql.InputObjectFieldConfig{Type: graphql.String, Description: "Prices the order from the client's rate card."},
+		"shippingOptions":    &graphql.InputObjectFieldConfig{Type: graphql.NewList(graphql.NewNonNull(graphql.String)), Description: "Quote options, e.g. residential, used when createOrder prices the shipment."},
+		"lines":              &graphql.InputObjectFieldConfig{Type: graphql.NewList(graphql.NewNonNull(OrderLineInputType)), Description: "Items to reserve; they replace the order's lines and set its total. Omit to keep the lines."},
 	},
 })
 
@@ -0,0 +1,73 @@
+package types
+
+import "github.com/graphql-go/graphql"
+
+// ScheduleLineType is a line of a standing order.
+var ScheduleLineType = graphql.NewObject(graphql.ObjectConfig{
+	Name: "ScheduleLine",
+	Fields: graphql.Fields{
+		"inventoryItemId": &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+		"sku":             &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+		"warehouseId":     &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+		"quantity":        &graphql.Field{Type: graphql.NewNonNull(graphql.Int)},
+		"unitPrice":       &graphql.Field{Type: graphql.Float, Description: "Unset to charge the item's price when each order is placed."},
+	},
+})
+
+// OrderScheduleType is a standing order: the same order placed on a
+// recurring schedule. The dates are added with the relations.
+var OrderScheduleType = graphql.NewObject(graphql.ObjectConfig{
+	Name: "OrderSchedule",
+	Fields: graphql.Fields{
+		"id":            &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+		"name":          &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+		"orderPrefix":   &graphql.Field{Type: graphql.NewNonNull(graphql.String), Description: "Orders are numbered prefix-YYYYMMDD after the day they are for."},
+		"clientId":      &graphql.Field{Type: graphql.String},
+		"customerName":  &graphql.Field{Type: graphql.String},
+		"customerEmail": &graphql.Field{Type: graphql.String},
+		"orderType":     &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+		"lines":         &graphql.Field{Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(ScheduleLineType)))},
+		"frequency":     &graphql.Field{Type: graphql.NewNonNull(graphql.String), Description: "daily, weekly or monthly."},
+		"every":         &graphql.Field{Type: graphql.NewNonNull(graphql.Int), Description: "Days, weeks or months between orders."},
+		"leadDays":      &graphql.Field{Type: graphql.NewNonNull(graphql.Int), Description: "How many days ahead each order is placed as a scheduled order."},
+		"autoFulfill":   &graphql.Field{Type: graphql.NewNonNull(graphql.Boolean), Description: "Raise pick lists when each order is confirmed."},
+		"active":        &graphql.Field{Type: graphql.NewNonNull(graphql.Boolean)},
+		"createdBy":     &graphql.Field{Type: graphql.String},
+		"createdAt":     &graphql.Field{Type: graphql.String},
+		"updatedAt":     &graphql.Field{Type: graphql.String},
+	},
+})
+
+// ScheduleLineInputType is a line of a standing order, by SKU.
+var ScheduleLineInputType = graphql.NewInputObject(graphql.InputObjectConfig{
+	Name: "ScheduleLineInput",
+	Fields: graphql.InputObjectConfigFieldMap{
+		"sku":         &graphql.InputObjectFieldConfig{Type: graphql.NewNonNull(graphql.String)},
+		"warehouseId": &graphql.InputObjectFieldConfig{Type: graphql.String, Description: "The warehouse to take the SKU from; by default the one with the most of it available."},
+		"quantity":    &graphql.InputObjectFieldConfig{Type: graphql.NewNonNull(graphql.Int)},
+		"unitPrice":   &graphql.InputObjectFieldConfig{Type: graphql.Float, Description: "Fixes the price; omit to charge the item's price at the time."},
+	},
+})
+
+// OrderScheduleInputType contains fields for creating or replacing a
+// standing order. Dates are YYYY-MM-DD.
+var OrderScheduleInputType = graphql.NewInputObject(graphql.InputObjectConfig{
+	Name: "OrderScheduleInput",
+	Fields: graphql.InputObjectConfigFieldMap{
+		"name":          &graphql.InputObjectFieldConfig{Type: graphql.NewNonNull(graphql.String)},
+		"orderPrefix":   &graphql.InputObjectFieldConfig{Type: graphql.NewNonNull(graphql.String)},
+		"clientId":      &graphql.InputObjectFieldConfig{Type: graphql.String},
+		"customerName":  &graphql.InputObjectFieldConfig{Type: graphql.String},
+		"customerEmail": &graphql.InputObjectFieldConfig{Type: graphql.String},
+		"orderType":     &graphql.InputObjectFieldConfig{Type: graphql.String, Description: "Defaults to standard."},
+		"lines":         &graphql.InputObjectFieldConfig{Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(ScheduleLineInputType)))},
+		"frequency":     &graphql.InputObjectFieldConfig{Type: graphql.NewNonNull(graphql.String)},
+		"every":         &graphql.InputObjectFieldConfig{Type: graphql.Int, Description: "Defaults to 1."},
+		"startDate":     &graphql.InputObjectFieldConfig{Type: graphql.NewNonNull(graphql.String)},
+		"endDate":       &graphql.InputObjectFieldConfig{Type: graphql.String, Description: "The last day an order may be for; omit to run on."},
+		"skipDates":     &graphql.InputObjectFieldConfig{Type: graphql.NewList(graphql.NewNonNull(graphql.String)), Description: "Days that get no order, e.g. holidays."},
+		"leadDays":      &graphql.InputObjectFieldConfig{Type: graphql.Int, Description: "Defaults to 0: each order is placed on its day."},
+		"autoFulfill":   &graphql.InputObjectFieldConfig{Type: graphql.Boolean},
+		"active":        &graphql.InputObjectFieldConfig{Type: graphql.Boolean, Description: "Defaults to true."},
+	},
+})
@@ -0,0 +1,42 @@
+package types
+
+import "github.com/graphql-go/graphql"
+
+// ProductType is an entry in the tenant's product catalog. Its stock is
+// held per warehouse in inventory items.
+var ProductType = graphql.NewObject(graphql.ObjectConfig{
+	Name: "Product",
+	Fields: graphql.Fields{
+		"id":             &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+		"tenantId":       &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+		"sku":            &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+		"name":           &graphql.Field{Type: graphql.String},
+		"category":       &graphql.Field{Type: graphql.String},
+		"unitPrice":      &graphql.Field{Type: graphql.Float},
+		"weight":         &graphql.Field{Type: graphql.Float, Description: "A unit's weight in kg."},
+		"volume":         &graphql.Field{Type: graphql.Float, Description: "A unit's volume in cubic metres."},
+		"unitsPerPallet": &graphql.Field{Type: graphql.Int, Description: "How many units fit on a pallet."},
+		"quantity":       &graphql.Field{Type: graphql.NewNonNull(graphql.Int), Description: "On hand across all warehouses."},
+		"reserved":       &graphql.Field{Type: graphql.NewNonNull(graphql.Int), Description: "Held for open orders and transfers across all warehouses."},
+		"inTransit":      &graphql.Field{Type: graphql.NewNonNull(graphql.Int), Description: "On its way between warehouses on transfer orders."},
+		"createdAt":      &graphql.Field{Type: graphql.String},
+		"updatedAt":      &graphql.Field{Type: graphql.String},
+	},
+})
+
+// ProductInputType contains fields for creating or updating a product.
+var ProductInputType = graphql.NewInputObject(graphql.InputObjectConfig{
+	Name: "ProductInput",
+	Fields: graphql.InputObjectConfigFieldMap{
+		"sku":            &graphql.InputObjectFieldConfig{Type: graphql.NewNonNull(graphql.String)},
+		"name":           &graphql.InputObjectFieldConfig{Type: graphql.String},
+		"category":       &graphql.InputObjectFieldConfig{Type: graphql.String},
+		"unitPrice":      &graphql.InputObjectFieldConfig{Type: graphql.Float},
+		"weight":         &graphql.InputObjectFieldConfig{Type: graphql.Float},
+		"volume":         &graphql.InputObjectFieldConfig{Type: graphql.Float},
+		"unitsPerPallet": &graphql.InputObjectFieldConfig{Type: graphql.Int},
+	},
+})
+
+// ProductConnectionType is a paginated list of products.
+var ProductConnectionType = ConnectionType("ProductConnection", ProductType)
@@ -0,0 +1,106 @@
+package types
+
+import "github.com/graphql-go/graphql"
+
+// SupplierProductType is a product a vendor supplies and the terms it
+// supplies it on.
+var SupplierProductType = graphql.NewObject(graphql.ObjectConfig{
+	Name: "SupplierProduct",
+	Fields: graphql.Fields{
+		"id":               &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+		"vendorId":         &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+		"productId":        &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+		"sku":              &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+		"vendorSku":        &graphql.Field{Type: graphql.String, Description: "The vendor's own code for the product."},
+		"unitCost":         &graphql.Field{Type: graphql.NewNonNull(graphql.Float)},
+		"leadTimeDays":     &graphql.Field{Type: graphql.NewNonNull(graphql.Int), Description: "Days from ordering to delivery."},
+		"minOrderQuantity": &graphql.Field{Type: graphql.NewNonNull(graphql.Int)},
+		"preferred":        &graphql.Field{Type: graphql.NewNonNull(graphql.Boolean), Description: "The supplier the reorder job buys the product from."},
+		"createdAt":        &graphql.Field{Type: graphql.String},
+		"updatedAt":        &graphql.Field{Type: graphql.String},
+	},
+})
+
+// SupplierProductInputType contains fields for creating or updating a
+// supplier catalog entry.
+var SupplierProductInputType = graphql.NewInputObject(graphql.InputObjectConfig{
+	Name: "SupplierProductInput",
+	Fields: graphql.InputObjectConfigFieldMap{
+		"vendorId":         &graphql.InputObjectFieldConfig{Type: graphql.NewNonNull(graphql.String)},
+		"sku":              &graphql.InputObjectFieldConfig{Type: graphql.NewNonNull(graphql.String)},
+		"vendorSku":        &graphql.InputObjectFieldConfig{Type: graphql.String},
+		"unitCost":         &graphql.InputObjectFieldConfig{Type: graphql.NewNonNull(graphql.Float)},
+		"leadTimeDays":     &graphql.InputObjectFieldConfig{Type: graphql.Int, DefaultValue: 0},
+		"minOrderQuantity": &graphql.InputObjectFieldConfig{Type: graphql.Int, DefaultValue: 1},
+		"preferred":        &graphql.InputObjectFieldConfig{Type: graphql.Boolean, DefaultValue: false, Description: "Takes over from the product's preferred supplier."},
+	},
+})
+
+// PurchaseOrderLineType is a quantity of a product on a purchase order.
+var PurchaseOrderLineType = graphql.NewObject(graphql.ObjectConfig{
+	Name: "PurchaseOrderLine",
+	Fields: graphql.Fields{
+		"id":               &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+		"productId":        &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+		"sku":              &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+		"vendorSku":        &graphql.Field{Type: graphql.String},
+		"quantity":         &graphql.Field{Type: graphql.NewNonNull(graphql.Int), Description: "Ordered."},
+		"receivedQuantity": &graphql.Field{Type: graphql.NewNonNull(graphql.Int)},
+		"unitCost":         &graphql.Field{Type: graphql.NewNonNull(graphql.Float)},
+	},
+})
+
+// PurchaseOrderType buys stock from a vendor into one warehouse.
+var PurchaseOrderType = graphql.NewObject(graphql.ObjectConfig{
+	Name: "PurchaseOrder",
+	Fields: graphql.Fields{
+		"id":          &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+		"poNumber":    &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+		"vendorId":    &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+		"warehouseId": &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+		"status":      &graphql.Field{Type: graphql.NewNonNull(graphql.String), Description: "draft, sent, partially_received, received or cancelled."},
+		"source":      &graphql.Field{Type: graphql.NewNonNull(graphql.String), Description: "manual, or reorder when the reorder job drafted it."},
+		"notes":       &graphql.Field{Type: graphql.String},
+		"lines":       &graphql.Field{Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(PurchaseOrderLineType)))},
+		"createdBy":   &graphql.Field{Type: graphql.String},
+		"sentAt":      &graphql.Field{Type: graphql.String},
+		"receivedAt":  &graphql.Field{Type: graphql.String},
+		"createdAt":   &graphql.Field{Type: graphql.String},
+		"updatedAt":   &graphql.Field{Type: graphql.String},
+	},
+})
+
+// PurchaseOrderConnectionType is a paginated list of purchase orders.
+var PurchaseOrderConnectionType = ConnectionType("PurchaseOrderConnection", PurchaseOrderType)
+
+// PurchaseLineInputType is a quantity of a SKU to order.
+var PurchaseLineInputType = graphql.NewInputObject(graphql.InputObjectConfig{
+	Name: "PurchaseLineInput",
+	Fields: graphql.InputObjectConfigFieldMap{
+		"sku":      &graphql.InputObjectFieldConfig{Type: graphql.NewNonNull(graphql.String)},
+		"quantity": &graphql.InputObjectFieldConfig{Type: graphql.NewNonNull(graphql.Int)},
+		"unitCost": &graphql.InputObjectFieldConfig{Type: graphql.Float, Description: "Defaults to the vendor's catalog cost."},
+	},
+})
+
+// PurchaseOrderInputType contains fields for creating or changing a draft
+// purchase order.
+var PurchaseOrderInputType = graphql.NewInputObject(graphql.InputObjectConfig{
+	Name: "PurchaseOrderInput",
+	Fields: graphql.InputObjectConfigFieldMap{
+		"vendorId":     &graphql.InputObjectFieldConfig{Type: graphql.NewNonNull(graphql.String)},
+		"warehouseId":  &graphql.InputObjectFieldConfig{Type: graphql.NewNonNull(graphql.String), Description: "Where the stock is delivered."},
+		"expectedDate": &graphql.InputObjectFieldConfig{Type: graphql.String, Description: "YYYY-MM-DD. Set from the vendor's lead times when the order is sent without one."},
+		"notes":        &graphql.InputObjectFieldConfig{Type: graphql.String},
+		"lines":        &graphql.InputObjectFieldConfig{Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(PurchaseLineInputType)))},
+	},
+})
+
+// ReceiptLineInputType is a quantity of a SKU that arrived.
+var ReceiptLineInputType = graphql.NewInputObject(graphql.InputObjectConfig{
+	Name: "ReceiptLineInput",
+	Fields: graphql.InputObjectConfigFieldMap{
+		"sku":      &graphql.InputObjectFieldConfig{Type: graphql.NewNonNull(graphql.String)},
+		"quantity": &graphql.InputObjectFieldConfig{Type: graphql.NewNonNull(graphql.Int)},
+	},
+})
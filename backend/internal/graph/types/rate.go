@@ -0,0 +1,157 @@
+package types
+
+import "github.com/graphql-go/graphql"
+
+// RateZoneType groups addresses a rate card prices alike.
+var RateZoneType = graphql.NewObject(graphql.ObjectConfig{
+	Name: "RateZone",
+	Fields: graphql.Fields{
+		"code":           &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+		"name":           &graphql.Field{Type: graphql.String},
+		"country":        &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+		"regions":        &graphql.Field{Type: graphql.NewList(graphql.NewNonNull(graphql.String)), Description: "Empty matches every region of the country."},
+		"postalPrefixes": &graphql.Field{Type: graphql.NewList(graphql.NewNonNull(graphql.String)), Description: "Empty matches every postal code."},
+	},
+})
+
+// WeightBreakType is a per-kilogram rate from a minimum weight.
+var WeightBreakType = graphql.NewObject(graphql.ObjectConfig{
+	Name: "WeightBreak",
+	Fields: graphql.Fields{
+		"minKg": &graphql.Field{Type: graphql.NewNonNull(graphql.Float)},
+		"perKg": &graphql.Field{Type: graphql.NewNonNull(graphql.Float)},
+	},
+})
+
+// RateLaneType prices shipments from one zone to another.
+var RateLaneType = graphql.NewObject(graphql.ObjectConfig{
+	Name: "RateLane",
+	Fields: graphql.Fields{
+		"origin":      &graphql.Field{Type: graphql.String, Description: "Zone code; empty matches any origin."},
+		"destination": &graphql.Field{Type: graphql.String, Description: "Zone code; empty matches any destination."},
+		"baseCharge":  &graphql.Field{Type: graphql.NewNonNull(graphql.Float)},
+		"perKm":       &graphql.Field{Type: graphql.NewNonNull(graphql.Float)},
+		"breaks":      &graphql.Field{Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(WeightBreakType)))},
+	},
+})
+
+// SurchargeType is an extra charge on top of the freight.
+var SurchargeType = graphql.NewObject(graphql.ObjectConfig{
+	Name: "Surcharge",
+	Fields: graphql.Fields{
+		"code":      &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+		"name":      &graphql.Field{Type: graphql.String},
+		"kind":      &graphql.Field{Type: graphql.NewNonNull(graphql.String), Description: "percent (of the freight), flat or per_kg."},
+		"amount":    &graphql.Field{Type: graphql.NewNonNull(graphql.Float)},
+		"condition": &graphql.Field{Type: graphql.NewNonNull(graphql.String), Description: "always, or the quote option that adds it, e.g. residential."},
+	},
+})
+
+// RateCardType is a tenant's, or one client's, shipping rates.
+var RateCardType = graphql.NewObject(graphql.ObjectConfig{
+	Name: "RateCard",
+	Fields: graphql.Fields{
+		"id":                &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+		"tenantId":          &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+		"name":              &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+		"clientId":          &graphql.Field{Type: graphql.String, Description: "Null for the tenant default card."},
+		"currency":          &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+		"volumetricDivisor": &graphql.Field{Type: graphql.NewNonNull(graphql.Float), Description: "Cubic centimetres per chargeable kilogram."},
+		"minCharge":         &graphql.Field{Type: graphql.NewNonNull(graphql.Float)},
+		"active":            &graphql.Field{Type: graphql.NewNonNull(graphql.Boolean)},
+		"zones":             &graphql.Field{Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(RateZoneType)))},
+		"lanes":             &graphql.Field{Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(RateLaneType)))},
+		"surcharges":        &graphql.Field{Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(SurchargeType)))},
+		"createdAt":         &graphql.Field{Type: graphql.String},
+		"updatedAt":         &graphql.Field{Type: graphql.String},
+	},
+})
+
+// RateZoneInputType defines a zone of a rate card.
+var RateZoneInputType = graphql.NewInputObject(graphql.InputObjectConfig{
+	Name: "RateZoneInput",
+	Fields: graphql.InputObjectConfigFieldMap{
+		"code":           &graphql.InputObjectFieldConfig{Type: graphql.NewNonNull(graphql.String)},
+		"name":           &graphql.InputObjectFieldConfig{Type: graphql.String},
+		"country":        &graphql.InputObjectFieldConfig{Type: graphql.NewNonNull(graphql.String)},
+		"regions":        &graphql.InputObjectFieldConfig{Type: graphql.NewList(graphql.NewNonNull(graphql.String))},
+		"postalPrefixes": &graphql.InputObjectFieldConfig{Type: graphql.NewList(graphql.NewNonNull(graphql.String))},
+	},
+})
+
+// WeightBreakInputType defines a weight break of a lane.
+var WeightBreakInputType = graphql.NewInputObject(graphql.InputObjectConfig{
+	Name: "WeightBreakInput",
+	Fields: graphql.InputObjectConfigFieldMap{
+		"minKg": &graphql.InputObjectFieldConfig{Type: graphql.NewNonNull(graphql.Float)},
+		"perKg": &graphql.InputObjectFieldConfig{Type: graphql.NewNonNull(graphql.Float)},
+	},
+})
+
+// RateLaneInputType defines a lane of a rate card.
+var RateLaneInputType = graphql.NewInputObject(graphql.InputObjectConfig{
+	Name: "RateLaneInput",
+	Fields: graphql.InputObjectConfigFieldMap{
+		"origin":      &graphql.InputObjectFieldConfig{Type: graphql.String},
+		"destination": &graphql.InputObjectFieldConfig{Type: graphql.String},
+		"baseCharge":  &graphql.InputObjectFieldConfig{Type: graphql.Float},
+		"perKm":       &graphql.InputObjectFieldConfig{Type: graphql.Float},
+		"breaks":      &graphql.InputObjectFieldConfig{Type: graphql.NewList(graphql.NewNonNull(WeightBreakInputType))},
+	},
+})
+
+// SurchargeInputType defines a surcharge of a rate card.
+var SurchargeInputType = graphql.NewInputObject(graphql.InputObjectConfig{
+	Name: "SurchargeInput",
+	Fields: graphql.InputObjectConfigFieldMap{
+		"code":      &graphql.InputObjectFieldConfig{Type: graphql.NewNonNull(graphql.String)},
+		"name":      &graphql.InputObjectFieldConfig{Type: graphql.String},
+		"kind":      &graphql.InputObjectFieldConfig{Type: graphql.NewNonNull(graphql.String)},
+		"amount":    &graphql.InputObjectFieldConfig{Type: graphql.NewNonNull(graphql.Float)},
+		"condition": &graphql.InputObjectFieldConfig{Type: graphql.String, Description: "Defaults to always."},
+	},
+})
+
+// RateCardInputType contains fields for creating or replacing a rate card.
+var RateCardInputType = graphql.NewInputObject(graphql.InputObjectConfig{
+	Name: "RateCardInput",
+	Fields: graphql.InputObjectConfigFieldMap{
+		"name":              &graphql.InputObjectFieldConfig{Type: graphql.NewNonNull(graphql.String)},
+		"clientId":          &graphql.InputObjectFieldConfig{Type: graphql.String},
+		"currency":          &graphql.InputObjectFieldConfig{Type: graphql.String},
+		"volumetricDivisor": &graphql.InputObjectFieldConfig{Type: graphql.Float},
+		"minCharge":         &graphql.InputObjectFieldConfig{Type: graphql.Float},
+		"active":            &graphql.InputObjectFieldConfig{Type: graphql.Boolean},
+		"zones":             &graphql.InputObjectFieldConfig{Type: graphql.NewList(graphql.NewNonNull(RateZoneInputType))},
+		"lanes":             &graphql.InputObjectFieldConfig{Type: graphql.NewList(graphql.NewNonNull(RateLaneInputType))},
+		"surcharges":        &graphql.InputObjectFieldConfig{Type: graphql.NewList(graphql.NewNonNull(SurchargeInputType))},
+	},
+})
+
+// QuoteLineType is one charge on a quote.
+var QuoteLineType = graphql.NewObject(graphql.ObjectConfig{
+	Name: "QuoteLine",
+	Fields: graphql.Fields{
+		"code":        &graphql.Field{Type: graphql.NewNonNull(graphql.String), Description: "base, weight, distance, minimum or a surcharge code."},
+		"description": &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+		"amount":      &graphql.Field{Type: graphql.NewNonNull(graphql.Float)},
+	},
+})
+
+// QuoteType is an itemised shipping price.
+var QuoteType = graphql.NewObject(graphql.ObjectConfig{
+	Name: "Quote",
+	Fields: graphql.Fields{
+		"rateCardId":         &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+		"rateCardName":       &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+		"currency":           &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+		"originZone":         &graphql.Field{Type: graphql.String},
+		"destinationZone":    &graphql.Field{Type: graphql.String},
+		"actualWeightKg":     &graphql.Field{Type: graphql.NewNonNull(graphql.Float)},
+		"volumetricWeightKg": &graphql.Field{Type: graphql.NewNonNull(graphql.Float)},
+		"chargeableWeightKg": &graphql.Field{Type: graphql.NewNonNull(graphql.Float), Description: "The greater of the actual and volumetric weight."},
+		"distanceKm":         &graphql.Field{Type: graphql.Float},
+		"lines":              &graphql.Field{Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(QuoteLineType)))},
+		"total":              &graphql.Field{Type: graphql.NewNonNull(graphql.Float)},
+	},
+})
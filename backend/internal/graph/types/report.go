@@ -18,13 +18,17 @@ var MonthlyRevenueDataType = graphql.NewObject(graphql.ObjectConfig{
 	},
 })
 
-// RevenueReportType aggregates revenue data across multiple months.
+// RevenueReportType aggregates revenue data across multiple months. Revenue
+// is what was invoiced, less credit notes, before tax.
 var RevenueReportType = graphql.NewObject(graphql.ObjectConfig{
 	Name: "RevenueReport",
 	Fields: graphql.Fields{
 		"totalRevenue":  &graphql.Field{Type: graphql.Float},
 		"totalExpenses": &graphql.Field{Type: graphql.Float},
 		"totalProfit":   &graphql.Field{Type: graphql.Float},
+		"totalTax":      &graphql.Field{Type: graphql.Float, Description: "Tax invoiced, less tax credited."},
+		"collected":     &graphql.Field{Type: graphql.Float, Description: "Payments received in the year."},
+		"outstanding":   &graphql.Field{Type: graphql.Float, Description: "Still owed on the year's invoices."},
 		"monthlyData":   &graphql.Field{Type: graphql.NewList(MonthlyRevenueDataType)},
 	},
 })
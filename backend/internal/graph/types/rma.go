@@ -0,0 +1,97 @@
+package types
+
+import "github.com/graphql-go/graphql"
+
+// RMALineType is a quantity of an order line on an RMA, with what was
+// approved, received and done with it.
+var RMALineType = graphql.NewObject(graphql.ObjectConfig{
+	Name: "RMALine",
+	Fields: graphql.Fields{
+		"orderLineId":        &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+		"lineNumber":         &graphql.Field{Type: graphql.NewNonNull(graphql.Int)},
+		"sku":                &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+		"description":        &graphql.Field{Type: graphql.String},
+		"unitPrice":          &graphql.Field{Type: graphql.NewNonNull(graphql.Float)},
+		"quantity":           &graphql.Field{Type: graphql.NewNonNull(graphql.Int), Description: "Asked to be returned."},
+		"approvedQuantity":   &graphql.Field{Type: graphql.NewNonNull(graphql.Int)},
+		"receivedQuantity":   &graphql.Field{Type: graphql.NewNonNull(graphql.Int)},
+		"restockedQuantity":  &graphql.Field{Type: graphql.NewNonNull(graphql.Int), Description: "Of what was received, put back into stock."},
+		"writtenOffQuantity": &graphql.Field{Type: graphql.NewNonNull(graphql.Int), Description: "Of what was received, not fit to sell again."},
+		"condition":          &graphql.Field{Type: graphql.String, Description: "The grade given on inspection: A as new, B good, C damaged or D unusable."},
+		"inspectionNotes":    &graphql.Field{Type: graphql.String},
+	},
+})
+
+// RMAType is a return merchandise authorization.
+var RMAType = graphql.NewObject(graphql.ObjectConfig{
+	Name: "RMA",
+	Fields: graphql.Fields{
+		"id":               &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+		"rmaNumber":        &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+		"orderId":          &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+		"status":           &graphql.Field{Type: graphql.NewNonNull(graphql.String), Description: "requested, approved, rejected, in_transit, received, closed or cancelled."},
+		"reason":           &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+		"notes":            &graphql.Field{Type: graphql.String},
+		"warehouseId":      &graphql.Field{Type: graphql.String, Description: "Where the goods come back to."},
+		"returnShipmentId": &graphql.Field{Type: graphql.String},
+		"rejectionReason":  &graphql.Field{Type: graphql.String},
+		"resolution":       &graphql.Field{Type: graphql.String, Description: "refund, credit or none, once closed."},
+		"resolutionAmount": &graphql.Field{Type: graphql.Float},
+		"refundReference":  &graphql.Field{Type: graphql.String},
+		"creditNoteId":     &graphql.Field{Type: graphql.String},
+		"lines":            &graphql.Field{Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(RMALineType)))},
+		"createdBy":        &graphql.Field{Type: graphql.String},
+		"approvedAt":       &graphql.Field{Type: graphql.String},
+		"receivedAt":       &graphql.Field{Type: graphql.String},
+		"closedAt":         &graphql.Field{Type: graphql.String},
+		"createdAt":        &graphql.Field{Type: graphql.String},
+		"updatedAt":        &graphql.Field{Type: graphql.String},
+	},
+})
+
+// RMAConnectionType is a paginated list of RMAs.
+var RMAConnectionType = ConnectionType("RMAConnection", RMAType)
+
+// ReturnLineInputType is a quantity of an order line to return, or approve
+// for return.
+var ReturnLineInputType = graphql.NewInputObject(graphql.InputObjectConfig{
+	Name: "ReturnLineInput",
+	Fields: graphql.InputObjectConfigFieldMap{
+		"orderLineId": &graphql.InputObjectFieldConfig{Type: graphql.NewNonNull(graphql.String)},
+		"quantity":    &graphql.InputObjectFieldConfig{Type: graphql.NewNonNull(graphql.Int)},
+	},
+})
+
+// InspectionInputType records what arrived of an RMA line.
+var InspectionInputType = graphql.NewInputObject(graphql.InputObjectConfig{
+	Name: "InspectionInput",
+	Fields: graphql.InputObjectConfigFieldMap{
+		"orderLineId": &graphql.InputObjectFieldConfig{Type: graphql.NewNonNull(graphql.String)},
+		"quantity":    &graphql.InputObjectFieldConfig{Type: graphql.NewNonNull(graphql.Int), Description: "Received, up to what was approved."},
+		"condition":   &graphql.InputObjectFieldConfig{Type: graphql.NewNonNull(graphql.String), Description: "A, B, C or D."},
+		"restock": &graphql.InputObjectFieldConfig{
+			Type:        graphql.Boolean,
+			Description: "Put the goods back into stock or write them off. Defaults to restocking grades A and B.",
+		},
+		"notes": &graphql.InputObjectFieldConfig{Type: graphql.String},
+	},
+})
+
+// ReturnShipmentInputType describes the shipment bringing an RMA's goods
+// back.
+var ReturnShipmentInputType = graphql.NewInputObject(graphql.InputObjectConfig{
+	Name: "ReturnShipmentInput",
+	Fields: graphql.InputObjectConfigFieldMap{
+		"carrier":           &graphql.InputObjectFieldConfig{Type: graphql.String},
+		"trackingNumber":    &graphql.InputObjectFieldConfig{Type: graphql.String, Description: "Generated when not given."},
+		"origin":            &graphql.InputObjectFieldConfig{Type: graphql.String, Description: "Defaults to the order's client's address."},
+		"destination":       &graphql.InputObjectFieldConfig{Type: graphql.String, Description: "Defaults to the address of the warehouse the goods come back to."},
+		"weight":            &graphql.InputObjectFieldConfig{Type: graphql.Float},
+		"dimensions":        &graphql.InputObjectFieldConfig{Type: graphql.String},
+		"estimatedDelivery": &graphql.InputObjectFieldConfig{Type: graphql.String},
+		"notes":             &graphql.InputObjectFieldConfig{Type: graphql.String},
+
+		"originAddress":      &graphql.InputObjectFieldConfig{Type: AddressInputType},
+		"destinationAddress": &graphql.InputObjectFieldConfig{Type: AddressInputType},
+	},
+})
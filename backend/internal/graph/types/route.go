@@ -0,0 +1,159 @@
+package types
+
+import "github.com/graphql-go/graphql"
+
+// RouteStopType is one stop on a route plan. plannedArrival is computed from
+// the stop order; actualArrival/actualDeparture are recorded when the
+// driver's GPS pings enter and leave the stop's radius.
+var RouteStopType = graphql.NewObject(graphql.ObjectConfig{
+	Name: "RouteStop",
+	Fields: graphql.Fields{
+		"id":              &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+		"routeId":         &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+		"sequence":        &graphql.Field{Type: graphql.NewNonNull(graphql.Int)},
+		"type":            &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+		"shipmentId":      &graphql.Field{Type: graphql.String},
+		"warehouseId":     &graphql.Field{Type: graphql.String},
+		"zoneId":          &graphql.Field{Type: graphql.String},
+		"label":           &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+		"latitude":        &graphql.Field{Type: graphql.NewNonNull(graphql.Float)},
+		"longitude":       &graphql.Field{Type: graphql.NewNonNull(graphql.Float)},
+		"radiusMeters":    &graphql.Field{Type: graphql.NewNonNull(graphql.Int)},
+		"windowStart":     &graphql.Field{Type: graphql.String},
+		"windowEnd":       &graphql.Field{Type: graphql.String},
+		"serviceMinutes":  &graphql.Field{Type: graphql.NewNonNull(graphql.Int)},
+		"plannedArrival":  &graphql.Field{Type: graphql.String},
+		"actualArrival":   &graphql.Field{Type: graphql.String},
+		"actualDeparture": &graphql.Field{Type: graphql.String},
+		"status":          &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+		"createdAt":       &graphql.Field{Type: graphql.String},
+		"updatedAt":       &graphql.Field{Type: graphql.String},
+	},
+})
+
+// RoutePlanType is a driver's ordered list of stops for one day.
+var RoutePlanType = graphql.NewObject(graphql.ObjectConfig{
+	Name: "RoutePlan",
+	Fields: graphql.Fields{
+		"id":           &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+		"tenantId":     &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+		"planDate":     &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+		"name":         &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+		"warehouseId":  &graphql.Field{Type: graphql.String},
+		"driverId":     &graphql.Field{Type: graphql.String},
+		"vehicleId":    &graphql.Field{Type: graphql.String},
+		"shiftId":      &graphql.Field{Type: graphql.String},
+		"status":       &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+		"plannedStart": &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+		"notes":        &graphql.Field{Type: graphql.String},
+		"createdBy":    &graphql.Field{Type: graphql.String},
+		"createdAt":    &graphql.Field{Type: graphql.String},
+		"updatedAt":    &graphql.Field{Type: graphql.String},
+		"stops":        &graphql.Field{Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(RouteStopType)))},
+	},
+})
+
+// RouteStopInputType describes a stop to add or change. The position comes
+// from latitude/longitude when given, else from the shipment's destination,
+// the warehouse or the zone.
+var RouteStopInputType = graphql.NewInputObject(graphql.InputObjectConfig{
+	Name: "RouteStopInput",
+	Fields: graphql.InputObjectConfigFieldMap{
+		"type":           &graphql.InputObjectFieldConfig{Type: graphql.String},
+		"shipmentId":     &graphql.InputObjectFieldConfig{Type: graphql.String},
+		"warehouseId":    &graphql.InputObjectFieldConfig{Type: graphql.String},
+		"zoneId":         &graphql.InputObjectFieldConfig{Type: graphql.String},
+		"label":          &graphql.InputObjectFieldConfig{Type: graphql.String},
+		"latitude":       &graphql.InputObjectFieldConfig{Type: graphql.Float},
+		"longitude":      &graphql.InputObjectFieldConfig{Type: graphql.Float},
+		"radiusMeters":   &graphql.InputObjectFieldConfig{Type: graphql.Int},
+		"windowStart":    &graphql.InputObjectFieldConfig{Type: graphql.String},
+		"windowEnd":      &graphql.InputObjectFieldConfig{Type: graphql.String},
+		"serviceMinutes": &graphql.InputObjectFieldConfig{Type: graphql.Int},
+	},
+})
+
+// RoutePlanInputType contains fields for creating or updating a route plan.
+// stops is only read on create.
+var RoutePlanInputType = graphql.NewInputObject(graphql.InputObjectConfig{
+	Name: "RoutePlanInput",
+	Fields: graphql.InputObjectConfigFieldMap{
+		"planDate":     &graphql.InputObjectFieldConfig{Type: graphql.String},
+		"name":         &graphql.InputObjectFieldConfig{Type: graphql.String},
+		"warehouseId":  &graphql.InputObjectFieldConfig{Type: graphql.String},
+		"plannedStart": &graphql.InputObjectFieldConfig{Type: graphql.String},
+		"notes":        &graphql.InputObjectFieldConfig{Type: graphql.String},
+		"stops":        &graphql.InputObjectFieldConfig{Type: graphql.NewList(graphql.NewNonNull(RouteStopInputType))},
+	},
+})
+
+// ProposedStopType is a delivery on a proposed route.
+var ProposedStopType = graphql.NewObject(graphql.ObjectConfig{
+	Name: "ProposedStop",
+	Fields: graphql.Fields{
+		"sequence":       &graphql.Field{Type: graphql.NewNonNull(graphql.Int)},
+		"shipmentId":     &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+		"plannedArrival": &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+		"windowEnd":      &graphql.Field{Type: graphql.String},
+	},
+})
+
+// ProposedRouteType is the shipments one vehicle would deliver, in order,
+// leaving and returning to the warehouse.
+var ProposedRouteType = graphql.NewObject(graphql.ObjectConfig{
+	Name: "ProposedRoute",
+	Fields: graphql.Fields{
+		"vehicleId":  &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+		"loadKg":     &graphql.Field{Type: graphql.NewNonNull(graphql.Float)},
+		"capacityKg": &graphql.Field{Type: graphql.Float},
+		"distanceKm": &graphql.Field{Type: graphql.NewNonNull(graphql.Float)},
+		"returnAt":   &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+		"stops":      &graphql.Field{Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(ProposedStopType)))},
+	},
+})
+
+// UnroutedShipmentType is a shipment the optimizer left out, and why.
+var UnroutedShipmentType = graphql.NewObject(graphql.ObjectConfig{
+	Name: "UnroutedShipment",
+	Fields: graphql.Fields{
+		"shipmentId": &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+		"reason":     &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+	},
+})
+
+// RouteProposalType is the optimizer's suggested routes for a day. It is not
+// saved; pass its routes to acceptRouteProposal to create the plans.
+var RouteProposalType = graphql.NewObject(graphql.ObjectConfig{
+	Name: "RouteProposal",
+	Fields: graphql.Fields{
+		"planDate":        &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+		"warehouseId":     &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+		"plannedStart":    &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+		"shiftHours":      &graphql.Field{Type: graphql.NewNonNull(graphql.Float)},
+		"totalDistanceKm": &graphql.Field{Type: graphql.NewNonNull(graphql.Float)},
+		"routes":          &graphql.Field{Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(ProposedRouteType)))},
+		"unassigned":      &graphql.Field{Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(UnroutedShipmentType)))},
+	},
+})
+
+// ProposedRouteInputType is one accepted route: a vehicle and its shipments
+// in delivery order.
+var ProposedRouteInputType = graphql.NewInputObject(graphql.InputObjectConfig{
+	Name: "ProposedRouteInput",
+	Fields: graphql.InputObjectConfigFieldMap{
+		"vehicleId":   &graphql.InputObjectFieldConfig{Type: graphql.NewNonNull(graphql.String)},
+		"shipmentIds": &graphql.InputObjectFieldConfig{Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(graphql.String)))},
+	},
+})
+
+// RouteProposalInputType is a route proposal as accepted by the dispatcher,
+// possibly edited.
+var RouteProposalInputType = graphql.NewInputObject(graphql.InputObjectConfig{
+	Name: "RouteProposalInput",
+	Fields: graphql.InputObjectConfigFieldMap{
+		"planDate":     &graphql.InputObjectFieldConfig{Type: graphql.NewNonNull(graphql.String)},
+		"warehouseId":  &graphql.InputObjectFieldConfig{Type: graphql.NewNonNull(graphql.String)},
+		"plannedStart": &graphql.InputObjectFieldConfig{Type: graphql.NewNonNull(graphql.String)},
+		"routes":       &graphql.InputObjectFieldConfig{Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(ProposedRouteInputType)))},
+	},
+})
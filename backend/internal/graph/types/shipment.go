@@ -22,14 +22,137 @@ var ShipmentType = graphql.NewObject(graphql.ObjectConfig{
 		"notes":             &graphql.Field{Type: graphql.String},
 		"createdAt":         &graphql.Field{Type: graphql.String},
 		"updatedAt":         &graphql.Field{Type: graphql.String},
+
+		"destinationLatitude":  &graphql.Field{Type: graphql.Float},
+		"destinationLongitude": &graphql.Field{Type: graphql.Float},
+		"predictedDelivery":    &graphql.Field{Type: graphql.String, Description: "Live ETA from the carrying driver's GPS pings; compare with estimatedDelivery, the promised time."},
+		"predictedLate":        &graphql.Field{Type: graphql.NewNonNull(graphql.Boolean), Description: "True while the live ETA lands more than 15 minutes after estimatedDelivery."},
+		"etaUpdatedAt":         &graphql.Field{Type: graphql.String},
+
+		"warehouseId": &graphql.Field{Type: graphql.String, Description: "The warehouse the shipment leaves from; its label is printed in that warehouse's daily batch."},
+
+		"originAddress":      &graphql.Field{Type: AddressType, Description: "The structured form of origin."},
+		"destinationAddress": &graphql.Field{Type: AddressType, Description: "The structured form of destination."},
+	},
+})
+
+// ShipmentETAType is one recorded live ETA prediction next to the delivery
+// time promised when it was made.
+var ShipmentETAType = graphql.NewObject(graphql.ObjectConfig{
+	Name: "ShipmentETA",
+	Fields: graphql.Fields{
+		"id":                &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+		"shipmentId":        &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+		"shiftId":           &graphql.Field{Type: graphql.String},
+		"predictedDelivery": &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+		"promisedDelivery":  &graphql.Field{Type: graphql.String},
+		"predictedLate":     &graphql.Field{Type: graphql.NewNonNull(graphql.Boolean)},
+		"remainingKm":       &graphql.Field{Type: graphql.NewNonNull(graphql.Float)},
+		"speedKmh":          &graphql.Field{Type: graphql.NewNonNull(graphql.Float)},
+		"computedAt":        &graphql.Field{Type: graphql.String},
 	},
 })
 
-// ShipmentInputType contains fields for creating or updating a shipment.
+// ProofOfDeliveryType is the record a driver leaves when delivering a
+// shipment. latitude/longitude/capturedAt are the driver's GPS fix at the
+// time; distanceMeters is how far that was from the destination.
+var ProofOfDeliveryType = graphql.NewObject(graphql.ObjectConfig{
+	Name: "ProofOfDelivery",
+	Fields: graphql.Fields{
+		"id":             &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+		"shipmentId":     &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+		"driverId":       &graphql.Field{Type: graphql.String},
+		"shiftId":        &graphql.Field{Type: graphql.String},
+		"recipientName":  &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+		"latitude":       &graphql.Field{Type: graphql.NewNonNull(graphql.Float)},
+		"longitude":      &graphql.Field{Type: graphql.NewNonNull(graphql.Float)},
+		"accuracy":       &graphql.Field{Type: graphql.Float},
+		"distanceMeters": &graphql.Field{Type: graphql.NewNonNull(graphql.Float)},
+		"capturedAt":     &graphql.Field{Type: graphql.String},
+		"createdAt":      &graphql.Field{Type: graphql.String},
+	},
+})
+
+// ProofOfDeliveryFileType is a signature or photo attached to a proof of
+// delivery.
+var ProofOfDeliveryFileType = graphql.NewObject(graphql.ObjectConfig{
+	Name: "ProofOfDeliveryFile",
+	Fields: graphql.Fields{
+		"id":          &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+		"kind":        &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+		"contentType": &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+		"sizeBytes":   &graphql.Field{Type: graphql.NewNonNull(graphql.Int)},
+		"createdAt":   &graphql.Field{Type: graphql.String},
+	},
+})
+
+// ShipmentEventType is one entry on a shipment's tracking timeline.
+var ShipmentEventType = graphql.NewObject(graphql.ObjectConfig{
+	Name: "ShipmentEvent",
+	Fields: graphql.Fields{
+		"id":         &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+		"shipmentId": &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+		"status":     &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+		"location":   &graphql.Field{Type: graphql.String},
+		"note":       &graphql.Field{Type: graphql.String},
+		"actorType":  &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+		"actorId":    &graphql.Field{Type: graphql.String},
+		"occurredAt": &graphql.Field{Type: graphql.String},
+		"createdAt":  &graphql.Field{Type: graphql.String},
+	},
+})
+
+// ShipmentAssignmentType links a shipment to the driver, vehicle and shift
+// carrying it. unassignedAt is null on the current assignment.
+var ShipmentAssignmentType = graphql.NewObject(graphql.ObjectConfig{
+	Name: "ShipmentAssignment",
+	Fields: graphql.Fields{
+		"id":           &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+		"shipmentId":   &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+		"driverId":     &graphql.Field{Type: graphql.String},
+		"vehicleId":    &graphql.Field{Type: graphql.String},
+		"shiftId":      &graphql.Field{Type: graphql.String},
+		"assignedBy":   &graphql.Field{Type: graphql.String},
+		"assignedAt":   &graphql.Field{Type: graphql.String},
+		"unassignedAt": &graphql.Field{Type: graphql.String},
+	},
+})
+
+// PublicTrackingEventType is a timeline entry on the public tracking view.
+var PublicTrackingEventType = graphql.NewObject(graphql.ObjectConfig{
+	Name: "PublicTrackingEvent",
+	Fields: graphql.Fields{
+		"status":     &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+		"location":   &graphql.Field{Type: graphql.String},
+		"occurredAt": &graphql.Field{Type: graphql.String},
+	},
+})
+
+// PublicTrackingType is the redacted shipment view shown to anonymous
+// consignees.
+var PublicTrackingType = graphql.NewObject(graphql.ObjectConfig{
+	Name: "PublicTracking",
+	Fields: graphql.Fields{
+		"trackingNumber":    &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+		"status":            &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+		"carrier":           &graphql.Field{Type: graphql.String},
+		"origin":            &graphql.Field{Type: graphql.String, Description: "City and region only."},
+		"destination":       &graphql.Field{Type: graphql.String, Description: "City and region only."},
+		"estimatedDelivery": &graphql.Field{Type: graphql.String},
+		"predictedDelivery": &graphql.Field{Type: graphql.String, Description: "Live ETA from the carrying driver's GPS pings."},
+		"predictedLate":     &graphql.Field{Type: graphql.NewNonNull(graphql.Boolean), Description: "True while the live ETA lands more than 15 minutes after estimatedDelivery."},
+		"actualDelivery":    &graphql.Field{Type: graphql.String},
+		"events":            &graphql.Field{Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(PublicTrackingEventType)))},
+	},
+})
+
+// ShipmentInputType contains fields for creating or updating a shipment. A
+// shipment created without a trackingNumber gets one generated in the
+// tenant's format.
 var ShipmentInputType = graphql.NewInputObject(graphql.InputObjectConfig{
 	Name: "ShipmentInput",
 	Fields: graphql.InputObjectConfigFieldMap{
-		"trackingNumber":    &graphql.InputObjectFieldConfig{Type: graphql.NewNonNull(graphql.String)},
+		"trackingNumber":    &graphql.InputObjectFieldConfig{Type: graphql.String},
 		"origin":            &graphql.InputObjectFieldConfig{Type: graphql.String},
 		"destination":       &graphql.InputObjectFieldConfig{Type: graphql.String},
 		"status":            &graphql.InputObjectFieldConfig{Type: graphql.String},
@@ -41,6 +164,19 @@ var ShipmentInputType = graphql.NewInputObject(graphql.InputObjectConfig{
 		"customerName":      &graphql.InputObjectFieldConfig{Type: graphql.String},
 		"customerEmail":     &graphql.InputObjectFieldConfig{Type: graphql.String},
 		"notes":             &graphql.InputObjectFieldConfig{Type: graphql.String},
+
+		"destinationLatitude":  &graphql.InputObjectFieldConfig{Type: graphql.Float},
+		"destinationLongitude": &graphql.InputObjectFieldConfig{Type: graphql.Float},
+		"warehouseId":          &graphql.InputObjectFieldConfig{Type: graphql.String},
+
+		"originAddress": &graphql.InputObjectFieldConfig{
+			Type:        AddressInputType,
+			Description: "Structured origin. Fills origin when that is not given; defaults to the warehouse's address.",
+		},
+		"destinationAddress": &graphql.InputObjectFieldConfig{
+			Type:        AddressInputType,
+			Description: "Structured destination. Fills destination when that is not given, and destinationLatitude/Longitude by geocoding when they are not.",
+		},
 	},
 })
 
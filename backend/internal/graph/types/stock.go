@@ -0,0 +1,39 @@
+package types
+
+import "github.com/graphql-go/graphql"
+
+// StockMovementType is an entry in the stock ledger.
+var StockMovementType = graphql.NewObject(graphql.ObjectConfig{
+	Name: "StockMovement",
+	Fields: graphql.Fields{
+		"id":              &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+		"inventoryItemId": &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+		"warehouseId":     &graphql.Field{Type: graphql.String},
+		"sku":             &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+		"type":            &graphql.Field{Type: graphql.NewNonNull(graphql.String), Description: "receipt, pick, adjustment, transfer, return or write_off."},
+		"quantity":        &graphql.Field{Type: graphql.NewNonNull(graphql.Int), Description: "Added to stock on hand; negative when taken away."},
+		"balance":         &graphql.Field{Type: graphql.NewNonNull(graphql.Int), Description: "On hand after the movement."},
+		"reason":          &graphql.Field{Type: graphql.String},
+		"referenceType":   &graphql.Field{Type: graphql.String, Description: "The kind of document behind the movement, e.g. order or rma."},
+		"referenceId":     &graphql.Field{Type: graphql.String},
+		"reference":       &graphql.Field{Type: graphql.String, Description: "The document's number."},
+		"createdBy":       &graphql.Field{Type: graphql.String},
+		"createdAt":       &graphql.Field{Type: graphql.String},
+	},
+})
+
+// StockMovementConnectionType is a paginated list of stock movements.
+var StockMovementConnectionType = ConnectionType("StockMovementConnection", StockMovementType)
+
+// StockDiscrepancyType is an item whose stock on hand disagrees with its
+// ledger.
+var StockDiscrepancyType = graphql.NewObject(graphql.ObjectConfig{
+	Name: "StockDiscrepancy",
+	Fields: graphql.Fields{
+		"inventoryItemId": &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+		"sku":             &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+		"warehouseId":     &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+		"quantity":        &graphql.Field{Type: graphql.NewNonNull(graphql.Int), Description: "On hand."},
+		"ledgerQuantity":  &graphql.Field{Type: graphql.NewNonNull(graphql.Int), Description: "What the item's movements add up to."},
+	},
+})
@@ -0,0 +1,73 @@
+package types
+
+import "github.com/graphql-go/graphql"
+
+// TransferLineType is a quantity of a product on a transfer order.
+var TransferLineType = graphql.NewObject(graphql.ObjectConfig{
+	Name: "TransferLine",
+	Fields: graphql.Fields{
+		"id":               &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+		"productId":        &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+		"sku":              &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+		"quantity":         &graphql.Field{Type: graphql.NewNonNull(graphql.Int), Description: "Sent."},
+		"receivedQuantity": &graphql.Field{Type: graphql.NewNonNull(graphql.Int), Description: "Arrived, once received."},
+	},
+})
+
+// TransferOrderType moves stock from one of the tenant's warehouses to
+// another.
+var TransferOrderType = graphql.NewObject(graphql.ObjectConfig{
+	Name: "TransferOrder",
+	Fields: graphql.Fields{
+		"id":              &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+		"transferNumber":  &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+		"fromWarehouseId": &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+		"toWarehouseId":   &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+		"status":          &graphql.Field{Type: graphql.NewNonNull(graphql.String), Description: "pending, in_transit, received or cancelled."},
+		"shipmentId":      &graphql.Field{Type: graphql.String},
+		"notes":           &graphql.Field{Type: graphql.String},
+		"lines":           &graphql.Field{Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(TransferLineType)))},
+		"createdBy":       &graphql.Field{Type: graphql.String},
+		"dispatchedAt":    &graphql.Field{Type: graphql.String},
+		"receivedAt":      &graphql.Field{Type: graphql.String},
+		"createdAt":       &graphql.Field{Type: graphql.String},
+		"updatedAt":       &graphql.Field{Type: graphql.String},
+	},
+})
+
+// TransferOrderConnectionType is a paginated list of transfer orders.
+var TransferOrderConnectionType = ConnectionType("TransferOrderConnection", TransferOrderType)
+
+// TransferLineInputType is a quantity of a SKU to send, or that arrived.
+var TransferLineInputType = graphql.NewInputObject(graphql.InputObjectConfig{
+	Name: "TransferLineInput",
+	Fields: graphql.InputObjectConfigFieldMap{
+		"sku":      &graphql.InputObjectFieldConfig{Type: graphql.NewNonNull(graphql.String)},
+		"quantity": &graphql.InputObjectFieldConfig{Type: graphql.NewNonNull(graphql.Int)},
+	},
+})
+
+// TransferOrderInputType contains fields for creating a transfer order.
+var TransferOrderInputType = graphql.NewInputObject(graphql.InputObjectConfig{
+	Name: "TransferOrderInput",
+	Fields: graphql.InputObjectConfigFieldMap{
+		"fromWarehouseId": &graphql.InputObjectFieldConfig{Type: graphql.NewNonNull(graphql.String)},
+		"toWarehouseId":   &graphql.InputObjectFieldConfig{Type: graphql.NewNonNull(graphql.String)},
+		"lines":           &graphql.InputObjectFieldConfig{Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(TransferLineInputType)))},
+		"notes":           &graphql.InputObjectFieldConfig{Type: graphql.String},
+	},
+})
+
+// TransferShipmentInputType describes the shipment carrying a transfer
+// order's stock.
+var TransferShipmentInputType = graphql.NewInputObject(graphql.InputObjectConfig{
+	Name: "TransferShipmentInput",
+	Fields: graphql.InputObjectConfigFieldMap{
+		"carrier":           &graphql.InputObjectFieldConfig{Type: graphql.String},
+		"trackingNumber":    &graphql.InputObjectFieldConfig{Type: graphql.String, Description: "Generated when not given."},
+		"weight":            &graphql.InputObjectFieldConfig{Type: graphql.Float},
+		"dimensions":        &graphql.InputObjectFieldConfig{Type: graphql.String},
+		"estimatedDelivery": &graphql.InputObjectFieldConfig{Type: graphql.String},
+		"notes":             &graphql.InputObjectFieldConfig{Type: graphql.String},
+	},
+})
@@ -10,18 +10,23 @@ import "github.com/graphql-go/graphql"
 var WarehouseType = graphql.NewObject(graphql.ObjectConfig{
 	Name: "Warehouse",
 	Fields: graphql.Fields{
-		"id":           &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
-		"tenantId":     &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
-		"name":         &graphql.Field{Type: graphql.String},
-		"location":     &graphql.Field{Type: graphql.String},
-		"address":      &graphql.Field{Type: graphql.String},
-		"capacity":     &graphql.Field{Type: graphql.Int},
-		"usedCapacity": &graphql.Field{Type: graphql.Int},
-		"manager":      &graphql.Field{Type: graphql.String},
-		"phone":        &graphql.Field{Type: graphql.String},
-		"status":       &graphql.Field{Type: graphql.String},
-		"createdAt":    &graphql.Field{Type: graphql.String},
-		"updatedAt":    &graphql.Field{Type: graphql.String},
+		"id":             &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+		"tenantId":       &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+		"name":           &graphql.Field{Type: graphql.String},
+		"location":       &graphql.Field{Type: graphql.String},
+		"address":        &graphql.Field{Type: graphql.String},
+		"capacity":       &graphql.Field{Type: graphql.Int, Description: "In capacityUnit."},
+		"usedCapacity":   &graphql.Field{Type: graphql.Float, Description: "The space the stock on hand takes up, in capacityUnit. Kept in step with the stock ledger; pallets are whole, cubic metres and kg are not rounded."},
+		"capacityUnit":   &graphql.Field{Type: graphql.NewNonNull(graphql.String), Description: "pallets, cubic_metres or kg."},
+		"fillThresholds": &graphql.Field{Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(graphql.Int))), Description: "Percentages of capacity past which admins and managers are told the warehouse is filling up."},
+		"manager":        &graphql.Field{Type: graphql.String},
+		"phone":          &graphql.Field{Type: graphql.String},
+		"status":         &graphql.Field{Type: graphql.String},
+		"latitude":       &graphql.Field{Type: graphql.Float},
+		"longitude":      &graphql.Field{Type: graphql.Float},
+		"addressDetails": &graphql.Field{Type: AddressType, Description: "The structured form of address."},
+		"createdAt":      &graphql.Field{Type: graphql.String},
+		"updatedAt":      &graphql.Field{Type: graphql.String},
 	},
 })
 
@@ -33,10 +38,20 @@ var WarehouseInputType = graphql.NewInputObject(graphql.InputObjectConfig{
 		"location":     &graphql.InputObjectFieldConfig{Type: graphql.String},
 		"address":      &graphql.InputObjectFieldConfig{Type: graphql.String},
 		"capacity":     &graphql.InputObjectFieldConfig{Type: graphql.Int},
-		"usedCapacity": &graphql.InputObjectFieldConfig{Type: graphql.Int},
+		"capacityUnit": &graphql.InputObjectFieldConfig{Type: graphql.String, Description: "pallets, cubic_metres or kg. Defaults to pallets; an update without it keeps the unit."},
 		"manager":      &graphql.InputObjectFieldConfig{Type: graphql.String},
 		"phone":        &graphql.InputObjectFieldConfig{Type: graphql.String},
 		"status":       &graphql.InputObjectFieldConfig{Type: graphql.String},
+		"latitude":     &graphql.InputObjectFieldConfig{Type: graphql.Float},
+		"longitude":    &graphql.InputObjectFieldConfig{Type: graphql.Float},
+		"addressDetails": &graphql.InputObjectFieldConfig{
+			Type:        AddressInputType,
+			Description: "Structured address. Fills address when that is not given, and latitude/longitude by geocoding when they are not.",
+		},
+		"fillThresholds": &graphql.InputObjectFieldConfig{
+			Type:        graphql.NewList(graphql.NewNonNull(graphql.Int)),
+			Description: "Percentages of capacity, from 1 to 100. Defaults to 80 and 95; an update without it keeps the thresholds. An empty list turns the alerts off.",
+		},
 	},
 })
 
@@ -47,17 +62,20 @@ var WarehouseConnectionType = ConnectionType("WarehouseConnection", WarehouseTyp
 // InventoryItem
 // ---------------------------------------------------------------------------
 
-// InventoryItemType represents an inventory item stored in a warehouse.
+// InventoryItemType represents a product's stock level in a warehouse.
 var InventoryItemType = graphql.NewObject(graphql.ObjectConfig{
 	Name: "InventoryItem",
 	Fields: graphql.Fields{
 		"id":          &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
 		"tenantId":    &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+		"productId":   &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
 		"warehouseId": &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
 		"sku":         &graphql.Field{Type: graphql.String},
 		"name":        &graphql.Field{Type: graphql.String},
 		"category":    &graphql.Field{Type: graphql.String},
-		"quantity":    &graphql.Field{Type: graphql.Int},
+		"quantity":    &graphql.Field{Type: graphql.Int, Description: "On hand, including what orders have reserved."},
+		"reserved":    &graphql.Field{Type: graphql.NewNonNull(graphql.Int), Description: "Held for open orders."},
+		"inTransit":   &graphql.Field{Type: graphql.NewNonNull(graphql.Int), Description: "On its way here on transfer orders; not yet on hand."},
 		"minQuantity": &graphql.Field{Type: graphql.Int},
 		"unitPrice":   &graphql.Field{Type: graphql.Float},
 		"weight":      &graphql.Field{Type: graphql.Float},
@@ -75,11 +93,12 @@ var InventoryItemInputType = graphql.NewInputObject(graphql.InputObjectConfig{
 		"sku":         &graphql.InputObjectFieldConfig{Type: graphql.NewNonNull(graphql.String)},
 		"name":        &graphql.InputObjectFieldConfig{Type: graphql.String},
 		"category":    &graphql.InputObjectFieldConfig{Type: graphql.String},
-		"quantity":    &graphql.InputObjectFieldConfig{Type: graphql.Int},
+		"quantity":    &graphql.InputObjectFieldConfig{Type: graphql.Int, Description: "Stock on hand; a change is recorded as an adjustment. Kept when left out of an update."},
 		"minQuantity": &graphql.InputObjectFieldConfig{Type: graphql.Int},
 		"unitPrice":   &graphql.InputObjectFieldConfig{Type: graphql.Float},
 		"weight":      &graphql.InputObjectFieldConfig{Type: graphql.Float},
 		"status":      &graphql.InputObjectFieldConfig{Type: graphql.String},
+		"reason":      &graphql.InputObjectFieldConfig{Type: graphql.String, Description: "Why the quantity changed, recorded on its stock movement."},
 	},
 })
 
@@ -0,0 +1,324 @@
+package imports
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"cargomax-api/internal/models"
+)
+
+// Kind is how a cell is converted into an input value.
+type Kind int
+
+const (
+	String Kind = iota
+	Int
+	Float
+	// Time accepts RFC 3339, "2006-01-02 15:04[:05]", "2006-01-02" and
+	// Excel serial dates, all read as UTC, and yields an RFC 3339 string.
+	Time
+)
+
+// Field is a column an entity can import. Name is the key of the matching
+// GraphQL input field; a dot nests it in an input object, so
+// "destinationAddress.city" becomes input["destinationAddress"]["city"].
+type Field struct {
+	Name     string
+	Kind     Kind
+	Required bool
+	// Key marks the unique field rows are matched on for upserts.
+	Key bool
+	// Ref names the entity this field refers to by its key (a warehouse by
+	// name or id, a vehicle by vehicle ID, a shipment by tracking number).
+	// The importer looks the record up and passes its id as Input.
+	Ref   string
+	Input string
+	// Aliases are other headers the column is recognised by.
+	Aliases []string
+}
+
+// Spec lists the fields of one import entity.
+type Spec struct {
+	Entity string
+	Fields []Field
+}
+
+func addressFields(prefix string) []Field {
+	var fs []Field
+	for _, part := range []string{"line1", "line2", "city", "region", "postalCode", "country"} {
+		fs = append(fs, Field{Name: prefix + "." + part})
+	}
+	return fs
+}
+
+var specs = map[string]*Spec{
+	models.ImportShipments: {Entity: models.ImportShipments, Fields: append(append([]Field{
+		{Name: "trackingNumber", Key: true, Aliases: []string{"tracking", "tracking no"}},
+		{Name: "origin"},
+		{Name: "destination"},
+		{Name: "status"},
+		{Name: "carrier"},
+		{Name: "weight", Kind: Float, Aliases: []string{"weight kg"}},
+		{Name: "dimensions"},
+		{Name: "estimatedDelivery", Kind: Time, Aliases: []string{"eta"}},
+		{Name: "actualDelivery", Kind: Time},
+		{Name: "customerName", Aliases: []string{"customer"}},
+		{Name: "customerEmail"},
+		{Name: "notes"},
+		{Name: "destinationLatitude", Kind: Float},
+		{Name: "destinationLongitude", Kind: Float},
+		{Name: "warehouse", Ref: models.ImportWarehouses, Input: "warehouseId"},
+	}, addressFields("originAddress")...), addressFields("destinationAddress")...)},
+
+	models.ImportOrders: {Entity: models.ImportOrders, Fields: []Field{
+		{Name: "orderNumber", Required: true, Key: true, Aliases: []string{"order", "order no"}},
+		{Name: "customerName", Aliases: []string{"customer"}},
+		{Name: "customerEmail"},
+		{Name: "status"},
+		{Name: "type"},
+		{Name: "totalAmount", Kind: Float, Aliases: []string{"total", "amount"}},
+		{Name: "shipment", Ref: models.ImportShipments, Input: "shipmentId", Aliases: []string{"tracking number"}},
+		{Name: "scheduledDate", Kind: Time},
+	}},
+
+	models.ImportInventory: {Entity: models.ImportInventory, Fields: []Field{
+		{Name: "sku", Required: true, Key: true},
+		{Name: "warehouse", Required: true, Ref: models.ImportWarehouses, Input: "warehouseId"},
+		{Name: "name"},
+		{Name: "category"},
+		{Name: "quantity", Kind: Int, Aliases: []string{"qty", "on hand"}},
+		{Name: "minQuantity", Kind: Int, Aliases: []string{"min qty", "reorder point"}},
+		{Name: "unitPrice", Kind: Float, Aliases: []string{"price"}},
+		{Name: "weight", Kind: Float},
+		{Name: "status"},
+	}},
+
+	models.ImportDrivers: {Entity: models.ImportDrivers, Fields: []Field{
+		{Name: "employeeId", Required: true, Key: true, Aliases: []string{"employee", "employee no"}},
+		{Name: "firstName"},
+		{Name: "lastName"},
+		{Name: "email"},
+		{Name: "phone"},
+		{Name: "licenseNumber"},
+		{Name: "licenseExpiry", Kind: Time},
+		{Name: "status"},
+		{Name: "rating", Kind: Float},
+		{Name: "totalDeliveries", Kind: Int},
+		{Name: "vehicle", Ref: models.ImportVehicles, Input: "vehicleId"},
+	}},
+
+	models.ImportVehicles: {Entity: models.ImportVehicles, Fields: []Field{
+		{Name: "vehicleId", Required: true, Key: true, Aliases: []string{"vehicle", "unit", "fleet number"}},
+		{Name: "name"},
+		{Name: "type"},
+		{Name: "status"},
+		{Name: "fuelLevel", Kind: Int},
+		{Name: "mileage", Kind: Int, Aliases: []string{"odometer"}},
+		{Name: "lastService", Kind: Time},
+		{Name: "nextService", Kind: Time},
+		{Name: "licensePlate", Aliases: []string{"plate", "registration"}},
+		{Name: "year", Kind: Int},
+		{Name: "capacityKg", Kind: Float, Aliases: []string{"capacity"}},
+	}},
+}
+
+// SpecFor returns the fields of entity.
+func SpecFor(entity string) (*Spec, bool) {
+	s, ok := specs[entity]
+	return s, ok
+}
+
+// Field returns the field called name.
+func (s *Spec) Field(name string) (*Field, bool) {
+	for i := range s.Fields {
+		if s.Fields[i].Name == name {
+			return &s.Fields[i], true
+		}
+	}
+	return nil, false
+}
+
+// Key returns the field rows are matched on.
+func (s *Spec) Key() *Field {
+	for i := range s.Fields {
+		if s.Fields[i].Key {
+			return &s.Fields[i]
+		}
+	}
+	return nil
+}
+
+// headerKey folds a header or field name for comparison: "Tracking Number",
+// "tracking_number" and "trackingNumber" are all "trackingnumber".
+func headerKey(s string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= '0' && r <= '9':
+			return r
+		case r >= 'A' && r <= 'Z':
+			return r + 'a' - 'A'
+		}
+		return -1
+	}, s)
+}
+
+// names returns the folded names a column for f may have. Address parts
+// also match without the word "address", e.g. "Destination City".
+func (f *Field) names() []string {
+	names := []string{headerKey(f.Name)}
+	if prefix, part, ok := strings.Cut(f.Name, "."); ok {
+		names = append(names, headerKey(strings.TrimSuffix(prefix, "Address")+part))
+	}
+	for _, a := range f.Aliases {
+		names = append(names, headerKey(a))
+	}
+	return names
+}
+
+// Suggest maps every header that names a field, by its name or an alias, to
+// that field.
+func (s *Spec) Suggest(header []string) []models.ImportColumn {
+	mapping := []models.ImportColumn{}
+	used := map[string]bool{}
+	for _, h := range header {
+		if f := s.match(headerKey(h), used); f != nil {
+			mapping = append(mapping, models.ImportColumn{Field: f.Name, Column: h})
+			used[f.Name] = true
+		}
+	}
+	return mapping
+}
+
+func (s *Spec) match(key string, used map[string]bool) *Field {
+	for i := range s.Fields {
+		f := &s.Fields[i]
+		if used[f.Name] {
+			continue
+		}
+		for _, n := range f.names() {
+			if n == key {
+				return f
+			}
+		}
+	}
+	return nil
+}
+
+// Columns checks mapping against the spec and the file's header and returns
+// the column index of every mapped field. Every required field must be
+// mapped, and a field or column may be mapped once.
+func (s *Spec) Columns(header []string, mapping []models.ImportColumn) (map[string]int, error) {
+	index := make(map[string]int, len(header))
+	for i, h := range header {
+		if h != "" {
+			index[strings.ToLower(h)] = i
+		}
+	}
+	cols := make(map[string]int, len(mapping))
+	usedCols := map[int]bool{}
+	for _, m := range mapping {
+		if _, ok := s.Field(m.Field); !ok {
+			return nil, fmt.Errorf("%s have no field %q", s.Entity, m.Field)
+		}
+		if _, dup := cols[m.Field]; dup {
+			return nil, fmt.Errorf("field %q is mapped twice", m.Field)
+		}
+		i, ok := index[strings.ToLower(m.Column)]
+		if !ok {
+			return nil, fmt.Errorf("file has no column %q", m.Column)
+		}
+		if usedCols[i] {
+			return nil, fmt.Errorf("column %q is mapped twice", m.Column)
+		}
+		usedCols[i] = true
+		cols[m.Field] = i
+	}
+	for _, f := range s.Fields {
+		if _, ok := cols[f.Name]; f.Required && !ok {
+			return nil, fmt.Errorf("required field %q is not mapped", f.Name)
+		}
+	}
+	return cols, nil
+}
+
+// Input converts a row into a create-mutation input map using cols from
+// Columns. Empty cells are left out, as if the field had not been given. Ref
+// fields keep the raw cell under their own name for the importer to resolve.
+func (s *Spec) Input(row Row, cols map[string]int) (map[string]interface{}, []models.ImportRowError) {
+	input := map[string]interface{}{}
+	var errs []models.ImportRowError
+	for _, f := range s.Fields {
+		i, ok := cols[f.Name]
+		if !ok {
+			continue
+		}
+		cell := ""
+		if i < len(row.Cells) {
+			cell = row.Cells[i]
+		}
+		if cell == "" {
+			if f.Required {
+				errs = append(errs, models.ImportRowError{Row: row.Line, Field: f.Name, Message: "is required"})
+			}
+			continue
+		}
+		v, err := convert(f.Kind, cell)
+		if err != nil {
+			errs = append(errs, models.ImportRowError{Row: row.Line, Field: f.Name, Message: err.Error()})
+			continue
+		}
+		if prefix, part, nested := strings.Cut(f.Name, "."); nested {
+			obj, _ := input[prefix].(map[string]interface{})
+			if obj == nil {
+				obj = map[string]interface{}{}
+				input[prefix] = obj
+			}
+			obj[part] = v
+		} else {
+			input[f.Name] = v
+		}
+	}
+	return input, errs
+}
+
+// Excel's day zero; serial 60 is the 29 February 1900 that never was, so
+// counting from 30 December 1899 is right for every date after it.
+var excelEpoch = time.Date(1899, 12, 30, 0, 0, 0, 0, time.UTC)
+
+var timeLayouts = []string{time.RFC3339, "2006-01-02T15:04:05", "2006-01-02 15:04:05", "2006-01-02 15:04", "2006-01-02"}
+
+func convert(kind Kind, cell string) (interface{}, error) {
+	switch kind {
+	case Int:
+		if n, err := strconv.Atoi(cell); err == nil {
+			return n, nil
+		}
+		// Spreadsheets store every number as a float.
+		f, err := strconv.ParseFloat(cell, 64)
+		if err != nil || f != math.Trunc(f) || math.Abs(f) > math.MaxInt32 {
+			return nil, fmt.Errorf("%q is not a whole number", cell)
+		}
+		return int(f), nil
+	case Float:
+		f, err := strconv.ParseFloat(cell, 64)
+		if err != nil || math.IsNaN(f) || math.IsInf(f, 0) {
+			return nil, fmt.Errorf("%q is not a number", cell)
+		}
+		return f, nil
+	case Time:
+		for _, layout := range timeLayouts {
+			if t, err := time.ParseInLocation(layout, cell, time.UTC); err == nil {
+				return t.UTC().Format(time.RFC3339), nil
+			}
+		}
+		if days, err := strconv.ParseFloat(cell, 64); err == nil && days >= 1 && days < 2958466 {
+			t := excelEpoch.Add(time.Duration(math.Round(days*86400)) * time.Second)
+			return t.Format(time.RFC3339), nil
+		}
+		return nil, fmt.Errorf("%q is not a date (use YYYY-MM-DD or RFC 3339)", cell)
+	}
+	return cell, nil
+}
@@ -0,0 +1,215 @@
+package imports
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"cargomax-api/internal/models"
+)
+
+func parseFixture(t *testing.T, name string) *Table {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join("testdata", name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	table, err := Parse(name, data)
+	if err != nil {
+		t.Fatalf("Parse(%q): %v", name, err)
+	}
+	return table
+}
+
+// testdata/shipments.xlsx has a shared-string header, a data row mixing
+// shared strings (one of them rich text), numbers, a serial date, an inline
+// string and a boolean, a row of blanks, and a later row with gaps.
+func TestParseXLSX(t *testing.T) {
+	table := parseFixture(t, "shipments.xlsx")
+	header := []string{"Tracking Number", "Weight (kg)", "ETA", "Destination City", "destinationAddress.postalCode", "Notes"}
+	if !reflect.DeepEqual(table.Header, header) {
+		t.Errorf("header %q, want %q", table.Header, header)
+	}
+	rows := []Row{
+		{Line: 2, Cells: []string{"CM0000012344", "12.5", "45678.5", "Berlin", "10115", "TRUE"}},
+		{Line: 5, Cells: []string{"CM0000012351", "", "2025-02-01", "Hamburg"}},
+	}
+	if !reflect.DeepEqual(table.Rows, rows) {
+		t.Errorf("rows %q, want %q", table.Rows, rows)
+	}
+}
+
+// testdata/shipments.csv is a semicolon-separated export with a byte order
+// mark, CRLF line ends, a blank row and a quoted cell holding a semicolon.
+func TestParseCSV(t *testing.T) {
+	table := parseFixture(t, "shipments.csv")
+	header := []string{"Tracking Number", "Weight kg", "ETA", "Destination City", "Destination Postal Code", "Notes"}
+	if !reflect.DeepEqual(table.Header, header) {
+		t.Errorf("header %q, want %q", table.Header, header)
+	}
+	rows := []Row{
+		{Line: 2, Cells: []string{"CM0000012344", "12.5", "45678.5", "Berlin", "10115", ""}},
+		{Line: 4, Cells: []string{"CM0000012351", "3", "2025-02-01 08:30", "Hamburg", "20095", "Leave at gate; ring twice"}},
+	}
+	if !reflect.DeepEqual(table.Rows, rows) {
+		t.Errorf("rows %q, want %q", table.Rows, rows)
+	}
+}
+
+func TestParseRejects(t *testing.T) {
+	for _, tc := range []struct {
+		name, data string
+	}{
+		{"shipments.txt", "a,b\n1,2\n"},
+		{"shipments.csv", ""},
+		{"shipments.csv", "sku,name,SKU\n1,2,3\n"},
+		{"shipments.csv", "sku,\"name\n"},
+		{"shipments.xlsx", "not a zip"},
+	} {
+		if _, err := Parse(tc.name, []byte(tc.data)); err == nil {
+			t.Errorf("Parse(%q, %q) accepted", tc.name, tc.data)
+		}
+	}
+	if _, err := Parse("shipments.ods", nil); !errors.Is(err, ErrUnsupportedFormat) {
+		t.Errorf("Parse of an .ods file: err %v, want ErrUnsupportedFormat", err)
+	}
+}
+
+func TestShipmentInput(t *testing.T) {
+	spec, _ := SpecFor(models.ImportShipments)
+	for _, tc := range []struct {
+		file   string
+		inputs []map[string]interface{}
+	}{
+		{"shipments.xlsx", []map[string]interface{}{
+			{
+				"trackingNumber":     "CM0000012344",
+				"weight":             12.5,
+				"estimatedDelivery":  "2025-01-21T12:00:00Z",
+				"notes":              "TRUE",
+				"destinationAddress": map[string]interface{}{"city": "Berlin", "postalCode": "10115"},
+			},
+			{
+				"trackingNumber":     "CM0000012351",
+				"estimatedDelivery":  "2025-02-01T00:00:00Z",
+				"destinationAddress": map[string]interface{}{"city": "Hamburg"},
+			},
+		}},
+		{"shipments.csv", []map[string]interface{}{
+			{
+				"trackingNumber":     "CM0000012344",
+				"weight":             12.5,
+				"estimatedDelivery":  "2025-01-21T12:00:00Z",
+				"destinationAddress": map[string]interface{}{"city": "Berlin", "postalCode": "10115"},
+			},
+			{
+				"trackingNumber":     "CM0000012351",
+				"weight":             3.0,
+				"estimatedDelivery":  "2025-02-01T08:30:00Z",
+				"notes":              "Leave at gate; ring twice",
+				"destinationAddress": map[string]interface{}{"city": "Hamburg", "postalCode": "20095"},
+			},
+		}},
+	} {
+		table := parseFixture(t, tc.file)
+		mapping := spec.Suggest(table.Header)
+		if len(mapping) != len(table.Header) {
+			t.Errorf("%s: Suggest mapped %v, want every column", tc.file, mapping)
+		}
+		cols, err := spec.Columns(table.Header, mapping)
+		if err != nil {
+			t.Errorf("%s: Columns: %v", tc.file, err)
+			continue
+		}
+		for i, row := range table.Rows {
+			input, errs := spec.Input(row, cols)
+			if len(errs) > 0 {
+				t.Errorf("%s line %d: %v", tc.file, row.Line, errs)
+			}
+			if !reflect.DeepEqual(input, tc.inputs[i]) {
+				t.Errorf("%s line %d: input %v, want %v", tc.file, row.Line, input, tc.inputs[i])
+			}
+		}
+	}
+}
+
+func TestInputErrors(t *testing.T) {
+	spec, _ := SpecFor(models.ImportInventory)
+	header := []string{"SKU", "Warehouse", "Qty"}
+	cols, err := spec.Columns(header, spec.Suggest(header))
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, errs := spec.Input(Row{Line: 7, Cells: []string{"SKU-1", "", "2.5"}}, cols)
+	want := []models.ImportRowError{
+		{Row: 7, Field: "warehouse", Message: "is required"},
+		{Row: 7, Field: "quantity", Message: `"2.5" is not a whole number`},
+	}
+	if !reflect.DeepEqual(errs, want) {
+		t.Errorf("errors %v, want %v", errs, want)
+	}
+
+	if _, err := spec.Columns(header, []models.ImportColumn{{Field: "sku", Column: "SKU"}}); err == nil {
+		t.Errorf("Columns without the required warehouse accepted")
+	}
+}
+
+func TestConvert(t *testing.T) {
+	for _, tc := range []struct {
+		kind Kind
+		cell string
+		want interface{}
+	}{
+		{String, "  as is ", "  as is "},
+		{Int, "42", 42},
+		{Int, "-3", -3},
+		{Int, "42.0", 42},
+		{Float, "12.5", 12.5},
+		{Float, "7", 7.0},
+		{Time, "2025-01-21", "2025-01-21T00:00:00Z"},
+		{Time, "2025-01-21 08:30", "2025-01-21T08:30:00Z"},
+		{Time, "2025-01-21 08:30:15", "2025-01-21T08:30:15Z"},
+		{Time, "2025-01-21T08:30:15", "2025-01-21T08:30:15Z"},
+		{Time, "2025-01-21T08:30:00+02:00", "2025-01-21T06:30:00Z"},
+		// Excel serial dates, with the time of day as the fraction.
+		{Time, "45678", "2025-01-21T00:00:00Z"},
+		{Time, "45678.5", "2025-01-21T12:00:00Z"},
+		{Time, "45700.25", "2025-02-12T06:00:00Z"},
+		{Time, "61", "1900-03-01T00:00:00Z"},
+		{Time, "1", "1899-12-31T00:00:00Z"},
+	} {
+		got, err := convert(tc.kind, tc.cell)
+		if err != nil {
+			t.Errorf("convert(%d, %q): %v", tc.kind, tc.cell, err)
+			continue
+		}
+		if got != tc.want {
+			t.Errorf("convert(%d, %q) = %#v, want %#v", tc.kind, tc.cell, got, tc.want)
+		}
+	}
+}
+
+func TestConvertRejects(t *testing.T) {
+	for _, tc := range []struct {
+		kind Kind
+		cell string
+	}{
+		{Int, "2.5"},
+		{Int, "ten"},
+		{Int, "1e12"},
+		{Float, "heavy"},
+		{Float, "NaN"},
+		{Float, "Inf"},
+		{Time, "tomorrow"},
+		{Time, "21/01/2025"},
+		{Time, "0"},
+		{Time, "-5"},
+		{Time, "3000000"},
+	} {
+		if v, err := convert(tc.kind, tc.cell); err == nil {
+			t.Errorf("convert(%d, %q) = %#v, want an error", tc.kind, tc.cell, v)
+		}
+	}
+}
@@ -0,0 +1,116 @@
+// Package imports reads CSV and XLSX files for bulk import and turns their
+// rows into the same input maps the GraphQL create mutations receive, so the
+// resolvers' validation applies to imported records unchanged. It knows
+// nothing about the database; the graph layer plans and commits imports.
+package imports
+
+import (
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+)
+
+// ErrUnsupportedFormat is returned for files that are neither .csv nor .xlsx.
+var ErrUnsupportedFormat = errors.New("file must be .csv or .xlsx")
+
+// Row is one non-blank row of a file. Line is its line number, the header
+// being line 1, so errors can point at the spreadsheet row a user sees.
+type Row struct {
+	Line  int
+	Cells []string
+}
+
+// Table is a parsed file: the header row and the data rows below it.
+type Table struct {
+	Header []string
+	Rows   []Row
+}
+
+// Parse reads a CSV or XLSX file, chosen by the extension of name. Only the
+// first worksheet of a workbook is read. Cells are trimmed and blank rows are
+// dropped. A column without a header cannot be mapped and is ignored.
+func Parse(name string, data []byte) (*Table, error) {
+	var t *Table
+	var err error
+	switch strings.ToLower(filepath.Ext(name)) {
+	case ".csv":
+		t, err = parseCSV(data)
+	case ".xlsx":
+		t, err = parseXLSX(data)
+	default:
+		return nil, ErrUnsupportedFormat
+	}
+	if err != nil {
+		return nil, err
+	}
+	for len(t.Header) > 0 && t.Header[len(t.Header)-1] == "" {
+		t.Header = t.Header[:len(t.Header)-1]
+	}
+	if len(t.Header) == 0 {
+		return nil, fmt.Errorf("file has no header row")
+	}
+	seen := make(map[string]bool, len(t.Header))
+	for _, h := range t.Header {
+		if h == "" {
+			continue
+		}
+		key := strings.ToLower(h)
+		if seen[key] {
+			return nil, fmt.Errorf("column %q appears twice", h)
+		}
+		seen[key] = true
+	}
+	return t, nil
+}
+
+// add appends a data row unless every cell is blank.
+func (t *Table) add(line int, cells []string) {
+	blank := true
+	for i := range cells {
+		cells[i] = strings.TrimSpace(cells[i])
+		if cells[i] != "" {
+			blank = false
+		}
+	}
+	if !blank {
+		t.Rows = append(t.Rows, Row{Line: line, Cells: cells})
+	}
+}
+
+// parseCSV reads comma- or semicolon-separated values; spreadsheet exports
+// in many European locales use semicolons. A UTF-8 byte order mark is
+// dropped.
+func parseCSV(data []byte) (*Table, error) {
+	data = bytes.TrimPrefix(data, []byte("\ufeff"))
+	first, _, _ := bytes.Cut(data, []byte("\n"))
+
+	cr := csv.NewReader(bytes.NewReader(data))
+	cr.FieldsPerRecord = -1
+	if bytes.Count(first, []byte(";")) > bytes.Count(first, []byte(",")) {
+		cr.Comma = ';'
+	}
+
+	t := &Table{}
+	for {
+		rec, err := cr.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid CSV: %w", err)
+		}
+		if t.Header == nil {
+			for _, h := range rec {
+				t.Header = append(t.Header, strings.TrimSpace(h))
+			}
+			continue
+		}
+		line, _ := cr.FieldPos(0)
+		t.add(line, rec)
+	}
+	return t, nil
+}
@@ -0,0 +1,212 @@
+package imports
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"strconv"
+	"strings"
+)
+
+// The parts of an Office Open XML workbook that are needed to read the first
+// worksheet's cell values. Styles are not read, so dates arrive as Excel
+// serial numbers; the field conversion understands those.
+type xlsxWorkbook struct {
+	Sheets []struct {
+		RelID string `xml:"http://schemas.openxmlformats.org/officeDocument/2006/relationships id,attr"`
+	} `xml:"sheets>sheet"`
+}
+
+type xlsxRels struct {
+	Relationships []struct {
+		ID     string `xml:"Id,attr"`
+		Target string `xml:"Target,attr"`
+	} `xml:"Relationship"`
+}
+
+// xlsxText is a shared or inline string: plain text, or rich text in runs.
+type xlsxText struct {
+	T    string `xml:"t"`
+	Runs []struct {
+		T string `xml:"t"`
+	} `xml:"r"`
+}
+
+func (s xlsxText) String() string {
+	if len(s.Runs) == 0 {
+		return s.T
+	}
+	var b strings.Builder
+	for _, r := range s.Runs {
+		b.WriteString(r.T)
+	}
+	return b.String()
+}
+
+type xlsxSharedStrings struct {
+	Items []xlsxText `xml:"si"`
+}
+
+type xlsxSheet struct {
+	Rows []struct {
+		R     int `xml:"r,attr"`
+		Cells []struct {
+			R      string    `xml:"r,attr"`
+			T      string    `xml:"t,attr"`
+			V      string    `xml:"v"`
+			Inline *xlsxText `xml:"is"`
+		} `xml:"c"`
+	} `xml:"sheetData>row"`
+}
+
+// maxXLSXPart bounds how much any one part of a workbook may inflate to, so
+// a small zip cannot expand into gigabytes of XML.
+const maxXLSXPart = 64 << 20
+
+func parseXLSX(data []byte) (*Table, error) {
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, fmt.Errorf("invalid XLSX file: %w", err)
+	}
+	files := make(map[string]*zip.File, len(zr.File))
+	for _, f := range zr.File {
+		files[f.Name] = f
+	}
+
+	var wb xlsxWorkbook
+	if err := readXLSXPart(files, "xl/workbook.xml", &wb); err != nil {
+		return nil, err
+	}
+	if len(wb.Sheets) == 0 {
+		return nil, fmt.Errorf("workbook has no worksheets")
+	}
+	var rels xlsxRels
+	if err := readXLSXPart(files, "xl/_rels/workbook.xml.rels", &rels); err != nil {
+		return nil, err
+	}
+	sheetPath := ""
+	for _, rel := range rels.Relationships {
+		if rel.ID == wb.Sheets[0].RelID {
+			// Targets are relative to xl/ unless they are absolute.
+			if strings.HasPrefix(rel.Target, "/") {
+				sheetPath = strings.TrimPrefix(rel.Target, "/")
+			} else {
+				sheetPath = path.Join("xl", rel.Target)
+			}
+		}
+	}
+	if sheetPath == "" {
+		return nil, fmt.Errorf("workbook does not locate its first worksheet")
+	}
+
+	var shared xlsxSharedStrings
+	if _, ok := files["xl/sharedStrings.xml"]; ok {
+		if err := readXLSXPart(files, "xl/sharedStrings.xml", &shared); err != nil {
+			return nil, err
+		}
+	}
+	var sheet xlsxSheet
+	if err := readXLSXPart(files, sheetPath, &sheet); err != nil {
+		return nil, err
+	}
+
+	t := &Table{}
+	line := 0
+	for _, row := range sheet.Rows {
+		if row.R > 0 {
+			line = row.R
+		} else {
+			line++
+		}
+		var cells []string
+		next := 0
+		for _, c := range row.Cells {
+			col := next
+			if c.R != "" {
+				if col, err = xlsxColumn(c.R); err != nil {
+					return nil, err
+				}
+			}
+			next = col + 1
+			var v string
+			switch c.T {
+			case "s":
+				i, err := strconv.Atoi(c.V)
+				if err != nil || i < 0 || i >= len(shared.Items) {
+					return nil, fmt.Errorf("cell %s refers to a missing shared string", c.R)
+				}
+				v = shared.Items[i].String()
+			case "inlineStr":
+				if c.Inline != nil {
+					v = c.Inline.String()
+				}
+			case "b":
+				v = "FALSE"
+				if c.V == "1" {
+					v = "TRUE"
+				}
+			default:
+				v = c.V
+			}
+			for len(cells) <= col {
+				cells = append(cells, "")
+			}
+			cells[col] = v
+		}
+		if t.Header == nil {
+			if len(cells) == 0 {
+				continue
+			}
+			for _, h := range cells {
+				t.Header = append(t.Header, strings.TrimSpace(h))
+			}
+			continue
+		}
+		t.add(line, cells)
+	}
+	return t, nil
+}
+
+func readXLSXPart(files map[string]*zip.File, name string, v interface{}) error {
+	f, ok := files[name]
+	if !ok {
+		return fmt.Errorf("invalid XLSX file: %s is missing", name)
+	}
+	rc, err := f.Open()
+	if err != nil {
+		return fmt.Errorf("invalid XLSX file: %w", err)
+	}
+	defer rc.Close()
+	data, err := io.ReadAll(io.LimitReader(rc, maxXLSXPart+1))
+	if err != nil {
+		return fmt.Errorf("invalid XLSX file: %w", err)
+	}
+	if len(data) > maxXLSXPart {
+		return fmt.Errorf("invalid XLSX file: %s is too large", name)
+	}
+	if err := xml.Unmarshal(data, v); err != nil {
+		return fmt.Errorf("invalid XLSX file: %s: %w", name, err)
+	}
+	return nil
+}
+
+// xlsxColumn returns the zero-based column of a cell reference such as "C7".
+func xlsxColumn(ref string) (int, error) {
+	col := 0
+	n := 0
+	for _, r := range ref {
+		if r < 'A' || r > 'Z' {
+			break
+		}
+		col = col*26 + int(r-'A') + 1
+		n++
+	}
+	if n == 0 || n > 3 {
+		return 0, errors.New("invalid cell reference " + strconv.Quote(ref))
+	}
+	return col - 1, nil
+}
@@ -0,0 +1,2052 @@
+package integration
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"cargomax-api/internal/database"
+	"cargomax-api/internal/graph"
+	"cargomax-api/internal/graph/loaders"
+	"cargomax-api/internal/graph/resolvers"
+	"cargomax-api/internal/models"
+	"cargomax-api/internal/routing"
+
+	"github.com/google/uuid"
+	"github.com/graphql-go/graphql"
+)
+
+func newSchema(t *testing.T) graphql.Schema {
+	t.Helper()
+	r := env.repos
+	res := resolvers.NewResolver(
+		r.User, r.Tenant, r.Shipment, r.Vehicle, r.Driver, r.Maintenance,
+		r.Warehouse, r.Inventory, r.Order, r.Vendor, r.Client, r.Feedback,
+		r.Dashboard, r.Report, r.Notification, r.Setting, r.Role, r.Activity,
+		r.Route, r.Zone, r.Import, r.RateCard, r.Invoice, r.Fulfillment, r.OrderSchedule, r.RMA, r.StockMovement, r.Product, r.Transfer,
+		r.SupplierProduct, r.PurchaseOrder, env.cfg,
+	)
+	res.Storage = env.store
+	res.Geocoder = env.geocoder
+	schema, err := graph.NewSchema(res)
+	if err != nil {
+		t.Fatalf("build schema: %v", err)
+	}
+	return schema
+}
+
+func execGraphQL(schema graphql.Schema, ctx context.Context, query string) *graphql.Result {
+	return graphql.Do(graphql.Params{Schema: schema, RequestString: query, Context: ctx})
+}
+
+// TestGraphQLQueriesRejectForeignIDs fetches tenant A's entities by id while
+// authenticated as tenant B's admin.
+func TestGraphQLQueriesRejectForeignIDs(t *testing.T) {
+	schema := newSchema(t)
+	a := env.a
+	ctx := userCtx(env.b)
+
+	queries := map[string]string{
+		"shipment":       fmt.Sprintf(`{ shipment(id: %q) { id tenantId } }`, a.Shipment.ID),
+		"trackShipment":  fmt.Sprintf(`{ trackShipment(trackingNumber: %q) { id tenantId } }`, a.Shipment.TrackingNumber),
+		"vehicle":        fmt.Sprintf(`{ vehicle(id: %q) { id tenantId } }`, a.Vehicle.ID),
+		"driver":         fmt.Sprintf(`{ driver(id: %q) { id tenantId } }`, a.Driver.ID),
+		"warehouse":      fmt.Sprintf(`{ warehouse(id: %q) { id tenantId } }`, a.Warehouse.ID),
+		"order":          fmt.Sprintf(`{ order(id: %q) { id tenantId } }`, a.Order.ID),
+		"vendor":         fmt.Sprintf(`{ vendor(id: %q) { id tenantId } }`, a.Vendor.ID),
+		"client":         fmt.Sprintf(`{ client(id: %q) { id tenantId } }`, a.Client.ID),
+		"clientFeedback": fmt.Sprintf(`{ clientFeedback(clientId: %q) { items { id } totalCount } }`, a.Client.ID),
+		"routePlan":      fmt.Sprintf(`{ routePlan(id: %q) { id tenantId } }`, a.Route.ID),
+	}
+	for name, q := range queries {
+		t.Run(name, func(t *testing.T) {
+			// The owner must be able to run the same query, otherwise an
+			// error below would prove nothing.
+			if own := execGraphQL(schema, userCtx(a), q); len(own.Errors) > 0 {
+				t.Fatalf("owner query failed: %v", own.Errors)
+			}
+
+			res := execGraphQL(schema, ctx, q)
+			data, _ := res.Data.(map[string]interface{})
+			if len(res.Errors) > 0 || data == nil {
+				return
+			}
+			switch v := data[name].(type) {
+			case nil:
+			case map[string]interface{}:
+				if tid, ok := v["tenantId"]; ok && tid == a.TenantID.String() {
+					t.Errorf("%s returned tenant A's row", name)
+				}
+				if total, ok := v["totalCount"].(int); ok && total != 0 {
+					t.Errorf("%s returned %d of tenant A's rows", name, total)
+				}
+			default:
+				t.Errorf("%s returned unexpected %T", name, v)
+			}
+		})
+	}
+}
+
+// TestGraphQLListsAreTenantScoped checks list queries only ever return the
+// caller's tenant.
+func TestGraphQLListsAreTenantScoped(t *testing.T) {
+	schema := newSchema(t)
+	b := env.b
+
+	lists := []string{"shipments", "vehicles", "drivers", "warehouses", "orders", "vendors", "clients"}
+	for _, name := range lists {
+		t.Run(name, func(t *testing.T) {
+			res := execGraphQL(schema, userCtx(b), fmt.Sprintf(`{ %s(page: 1, perPage: 100) { items { id tenantId } } }`, name))
+			if len(res.Errors) > 0 {
+				t.Fatalf("%s: %v", name, res.Errors)
+			}
+			conn := res.Data.(map[string]interface{})[name].(map[string]interface{})
+			for _, it := range conn["items"].([]interface{}) {
+				if tid := it.(map[string]interface{})["tenantId"]; tid != b.TenantID.String() {
+					t.Errorf("%s returned a row from tenant %v", name, tid)
+				}
+			}
+		})
+	}
+
+	res := execGraphQL(schema, userCtx(b), fmt.Sprintf(`{ inventoryItems(warehouseId: %q) { items { id } totalCount } }`, env.a.Warehouse.ID))
+	if len(res.Errors) == 0 {
+		conn := res.Data.(map[string]interface{})["inventoryItems"].(map[string]interface{})
+		if conn["totalCount"].(int) != 0 {
+			t.Errorf("inventoryItems filtered by tenant A's warehouse returned %v items", conn["totalCount"])
+		}
+	}
+
+	res = execGraphQL(schema, userCtx(b), `{ atRiskShipments { id tenantId } }`)
+	if len(res.Errors) > 0 {
+		t.Fatal(res.Errors)
+	}
+	for _, it := range res.Data.(map[string]interface{})["atRiskShipments"].([]interface{}) {
+		if tid := it.(map[string]interface{})["tenantId"]; tid != b.TenantID.String() {
+			t.Errorf("atRiskShipments returned a row from tenant %v", tid)
+		}
+	}
+
+	res = execGraphQL(schema, userCtx(b), fmt.Sprintf(`{ shipment(id: %q) { predictedDelivery predictedLate etaHistory { shipmentId promisedDelivery remainingKm } } }`, b.Shipment.ID))
+	if len(res.Errors) > 0 {
+		t.Fatal(res.Errors)
+	}
+	sh := res.Data.(map[string]interface{})["shipment"].(map[string]interface{})
+	if history := sh["etaHistory"].([]interface{}); sh["predictedDelivery"] == nil || len(history) == 0 {
+		t.Errorf("fixture shipment ETA %v", sh)
+	}
+}
+
+// TestGraphQLMutationsRejectForeignIDs runs update/delete style mutations as
+// tenant B against tenant A's ids and checks A's rows are unchanged.
+func TestGraphQLMutationsRejectForeignIDs(t *testing.T) {
+	schema := newSchema(t)
+	a := env.a
+	ctx := userCtx(env.b)
+
+	mutations := []string{
+		fmt.Sprintf(`mutation { updateShipment(id: %q, input: { trackingNumber: "PWNED", status: "delivered" }) { id } }`, a.Shipment.ID),
+		fmt.Sprintf(`mutation { updateShipmentStatus(id: %q, status: "delivered") { id } }`, a.Shipment.ID),
+		fmt.Sprintf(`mutation { updateVendor(id: %q, input: { name: "pwned", status: "inactive" }) { id } }`, a.Vendor.ID),
+		fmt.Sprintf(`mutation { updateClient(id: %q, input: { companyName: "pwned", status: "inactive" }) { id } }`, a.Client.ID),
+		fmt.Sprintf(`mutation { updateWarehouse(id: %q, input: { name: "pwned", capacity: 1 }) { id } }`, a.Warehouse.ID),
+		fmt.Sprintf(`mutation { updateRole(id: %q, input: { name: "pwned" }) { id } }`, a.Role.ID),
+		fmt.Sprintf(`mutation { restockItem(id: %q, quantity: 500) { id } }`, a.Inventory.ID),
+		fmt.Sprintf(`mutation { cancelOrder(id: %q, reason: "pwned") { id } }`, a.Order.ID),
+		fmt.Sprintf(`mutation { markNotificationRead(id: %q) }`, a.Notification.ID),
+		fmt.Sprintf(`mutation { deleteVehicle(id: %q) }`, a.Vehicle.ID),
+		fmt.Sprintf(`mutation { deleteDriver(id: %q) }`, a.Driver.ID),
+		fmt.Sprintf(`mutation { deleteShipment(id: %q) }`, a.Shipment.ID),
+		fmt.Sprintf(`mutation { updateRoutePlan(id: %q, input: { name: "pwned" }) { id } }`, a.Route.ID),
+		fmt.Sprintf(`mutation { reorderRouteStops(routeId: %q, stopIds: [%q, %q]) { id } }`, a.Route.ID, a.Route.Stops[1].ID, a.Route.Stops[0].ID),
+		fmt.Sprintf(`mutation { removeRouteStop(stopId: %q) { id } }`, a.Route.Stops[1].ID),
+		fmt.Sprintf(`mutation { skipRouteStop(stopId: %q) { id } }`, a.Route.Stops[1].ID),
+		fmt.Sprintf(`mutation { assignRoutePlan(id: %q, driverId: %q) { id } }`, a.Route.ID, env.b.Driver.ID),
+		fmt.Sprintf(`mutation { deleteRoutePlan(id: %q) }`, a.Route.ID),
+	}
+	for _, m := range mutations {
+		execGraphQL(schema, ctx, m)
+	}
+
+	checks := map[string]string{
+		"shipment":     fmt.Sprintf(`SELECT COUNT(*) FROM shipments WHERE id = '%s' AND tracking_number <> 'PWNED' AND status = 'in_transit'`, a.Shipment.ID),
+		"vendor":       fmt.Sprintf(`SELECT COUNT(*) FROM vendors WHERE id = '%s' AND name <> 'pwned'`, a.Vendor.ID),
+		"client":       fmt.Sprintf(`SELECT COUNT(*) FROM clients WHERE id = '%s' AND company_name <> 'pwned'`, a.Client.ID),
+		"warehouse":    fmt.Sprintf(`SELECT COUNT(*) FROM warehouses WHERE id = '%s' AND capacity = 1000`, a.Warehouse.ID),
+		"role":         fmt.Sprintf(`SELECT COUNT(*) FROM roles WHERE id = '%s' AND name <> 'pwned'`, a.Role.ID),
+		"inventory":    fmt.Sprintf(`SELECT COUNT(*) FROM inventory_items WHERE id = '%s' AND quantity = 1`, a.Inventory.ID),
+		"order":        fmt.Sprintf(`SELECT COUNT(*) FROM orders WHERE id = '%s' AND status = 'pending'`, a.Order.ID),
+		"notification": fmt.Sprintf(`SELECT COUNT(*) FROM notifications WHERE id = '%s' AND NOT read`, a.Notification.ID),
+		"vehicle":      fmt.Sprintf(`SELECT COUNT(*) FROM vehicles WHERE id = '%s'`, a.Vehicle.ID),
+		"driver":       fmt.Sprintf(`SELECT COUNT(*) FROM drivers WHERE id = '%s'`, a.Driver.ID),
+		"route plan":   fmt.Sprintf(`SELECT COUNT(*) FROM route_plans WHERE id = '%s' AND status = 'draft' AND name <> 'pwned'`, a.Route.ID),
+		"route stops":  fmt.Sprintf(`SELECT COUNT(*) FROM route_stops WHERE id = '%s' AND sequence = 2 AND status = 'pending'`, a.Route.Stops[1].ID),
+	}
+	for name, q := range checks {
+		if env.count(t, q) != 1 {
+			t.Errorf("tenant B modified tenant A's %s through GraphQL", name)
+		}
+	}
+}
+
+// TestGraphQLCreatesRejectForeignReferences covers the IDOR checks on
+// mutations that reference another entity by id.
+func TestGraphQLCreatesRejectForeignReferences(t *testing.T) {
+	schema := newSchema(t)
+	a := env.a
+	ctx := userCtx(env.b)
+
+	cases := map[string]string{
+		"createInventoryItem": fmt.Sprintf(`mutation { createInventoryItem(input: { warehouseId: %q, sku: "IDOR-SKU" }) { id } }`, a.Warehouse.ID),
+		"createMaintenance":   fmt.Sprintf(`mutation { createMaintenance(input: { vehicleId: %q, type: "idor" }) { id } }`, a.Vehicle.ID),
+		"submitFeedback":      fmt.Sprintf(`mutation { submitFeedback(input: { clientId: %q, rating: 1, comment: "idor" }) { id } }`, a.Client.ID),
+		"createRoutePlan":     fmt.Sprintf(`mutation { createRoutePlan(input: { planDate: "2030-01-01", name: "idor", plannedStart: "2030-01-01T08:00:00Z", stops: [{ shipmentId: %q }] }) { id } }`, a.Shipment.ID),
+		"addRouteStop":        fmt.Sprintf(`mutation { addRouteStop(routeId: %q, input: { zoneId: %q }) { id } }`, env.b.Route.ID, a.Zone.ID),
+		"optimizeRoutes":      fmt.Sprintf(`mutation { optimizeRoutes(date: "2030-01-01", warehouseId: %q) { totalDistanceKm } }`, a.Warehouse.ID),
+		"createShipment":      fmt.Sprintf(`mutation { createShipment(input: { trackingNumber: "IDOR-WH", warehouseId: %q }) { id } }`, a.Warehouse.ID),
+		"acceptRouteProposal": fmt.Sprintf(`mutation { acceptRouteProposal(input: { planDate: "2030-01-01", warehouseId: %q, plannedStart: "2030-01-01T08:00:00Z", routes: [{ vehicleId: %q, shipmentIds: [%q] }] }) { id } }`, env.b.Warehouse.ID, a.Vehicle.ID, a.Shipment.ID),
+	}
+	for name, m := range cases {
+		t.Run(name, func(t *testing.T) {
+			if res := execGraphQL(schema, ctx, m); len(res.Errors) == 0 {
+				t.Errorf("%s accepted tenant A's id", name)
+			}
+		})
+	}
+
+	if n := env.count(t, `SELECT COUNT(*) FROM inventory_items WHERE sku = 'IDOR-SKU'`); n != 0 {
+		t.Errorf("created %d inventory items in tenant A's warehouse", n)
+	}
+	if n := env.count(t, `SELECT COUNT(*) FROM maintenance_records WHERE type = 'idor'`); n != 0 {
+		t.Errorf("created %d maintenance records on tenant A's vehicle", n)
+	}
+	if n := env.count(t, `SELECT COUNT(*) FROM client_feedback WHERE comment = 'idor'`); n != 0 {
+		t.Errorf("created %d feedback rows for tenant A's client", n)
+	}
+	if n := env.count(t, `SELECT COUNT(*) FROM route_stops WHERE zone_id = $1 OR (shipment_id = $2 AND tenant_id <> $3)`, a.Zone.ID, a.Shipment.ID, a.TenantID); n != 0 {
+		t.Errorf("created %d route stops at tenant A's zone or shipment", n)
+	}
+	if n := env.count(t, `SELECT COUNT(*) FROM route_plans WHERE vehicle_id = $1 AND tenant_id <> $2`, a.Vehicle.ID, a.TenantID); n != 0 {
+		t.Errorf("created %d route plans driven by tenant A's vehicle", n)
+	}
+	if n := env.count(t, `SELECT COUNT(*) FROM shipments WHERE tracking_number = 'IDOR-WH'`); n != 0 {
+		t.Errorf("created %d shipments leaving tenant A's warehouse", n)
+	}
+}
+
+// TestGraphQLRequiresTenant checks resolvers refuse to run without claims.
+func TestGraphQLRequiresTenant(t *testing.T) {
+	schema := newSchema(t)
+	res := execGraphQL(schema, context.Background(), `{ shipments { items { id } } }`)
+	if len(res.Errors) == 0 {
+		t.Error("shipments resolved without an authenticated tenant")
+	}
+}
+
+// TestGraphQLRelationsStayInTenant resolves nested relationship fields through
+// the batch loaders and checks a foreign key pointing into another tenant
+// resolves to null instead of leaking the row.
+func TestGraphQLRelationsStayInTenant(t *testing.T) {
+	schema := newSchema(t)
+	r := env.repos
+	a, b := env.a, env.b
+	ctx := loaders.WithLoaders(userCtx(b), loaders.New(r.Shipment, r.Vehicle, r.Warehouse))
+
+	out := execGraphQL(schema, ctx, `{ drivers(perPage: 100) { items { id vehicleId vehicle { id tenantId } } } }`)
+	if len(out.Errors) > 0 {
+		t.Fatal(out.Errors)
+	}
+	resolved := false
+	for _, it := range out.Data.(map[string]interface{})["drivers"].(map[string]interface{})["items"].([]interface{}) {
+		d := it.(map[string]interface{})
+		v, _ := d["vehicle"].(map[string]interface{})
+		if v == nil {
+			continue
+		}
+		if v["tenantId"] != b.TenantID.String() || v["id"] != d["vehicleId"] {
+			t.Errorf("driver %v resolved vehicle %v", d["id"], v)
+		}
+		if d["id"] == b.Driver.ID.String() {
+			resolved = true
+		}
+	}
+	if !resolved {
+		t.Error("fixture driver's vehicle did not resolve")
+	}
+
+	// Point B's order at A's shipment behind the resolvers' back.
+	if _, err := env.pool.Exec(database.Privileged(context.Background()), `UPDATE orders SET shipment_id = $1 WHERE id = $2`, a.Shipment.ID, b.Order.ID); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		env.pool.Exec(database.Privileged(context.Background()), `UPDATE orders SET shipment_id = NULL WHERE id = $1`, b.Order.ID)
+	})
+
+	out = execGraphQL(schema, ctx, fmt.Sprintf(`{ order(id: %q) { id shipment { id } } }`, b.Order.ID))
+	if len(out.Errors) > 0 {
+		t.Fatal(out.Errors)
+	}
+	if s := out.Data.(map[string]interface{})["order"].(map[string]interface{})["shipment"]; s != nil {
+		t.Errorf("Order.shipment resolved a foreign shipment: %v", s)
+	}
+}
+
+// TestGraphQLShipmentTimeline moves a shipment through updateShipmentStatus
+// and updateShipment and reads the resulting timeline back from events.
+func TestGraphQLShipmentTimeline(t *testing.T) {
+	schema := newSchema(t)
+	b := env.b
+	ctx := userCtx(b)
+
+	out := execGraphQL(schema, ctx, fmt.Sprintf(`mutation { createShipment(input: { trackingNumber: "TL-%s" }) { id } }`, uuid.NewString()[:8]))
+	if len(out.Errors) > 0 {
+		t.Fatal(out.Errors)
+	}
+	id := out.Data.(map[string]interface{})["createShipment"].(map[string]interface{})["id"].(string)
+
+	move := func(m string) {
+		t.Helper()
+		if res := execGraphQL(schema, ctx, m); len(res.Errors) > 0 {
+			t.Fatal(res.Errors)
+		}
+	}
+	move(fmt.Sprintf(`mutation { updateShipmentStatus(id: %q, status: "in_transit", location: "Fixture Hub") { id } }`, id))
+
+	// A legal move whose field update fails, here on a taken tracking
+	// number, is not recorded either.
+	res := execGraphQL(schema, ctx, fmt.Sprintf(`mutation { updateShipment(id: %q, input: { trackingNumber: %q, status: "delivered" }) { id } }`, id, b.Shipment.TrackingNumber))
+	if len(res.Errors) == 0 {
+		t.Fatal("updateShipment took another shipment's tracking number")
+	}
+	if env.count(t, `SELECT COUNT(*) FROM shipment_events WHERE shipment_id = $1 AND status = 'delivered'`, id) != 0 ||
+		env.count(t, `SELECT COUNT(*) FROM shipments WHERE id = $1 AND status = 'in_transit' AND actual_delivery IS NULL`, id) != 1 {
+		t.Error("the failed update left the shipment delivered")
+	}
+
+	move(fmt.Sprintf(`mutation { updateShipment(id: %q, input: { trackingNumber: "TL-%s", status: "delivered" }) { id } }`, id, id[:8]))
+
+	res = execGraphQL(schema, ctx, fmt.Sprintf(`mutation { updateShipment(id: %q, input: { trackingNumber: "TL-%s", status: "pending", notes: "rewound" }) { id } }`, id, id[:8]))
+	if len(res.Errors) == 0 {
+		t.Error("updateShipment moved a delivered shipment back to pending")
+	}
+
+	out = execGraphQL(schema, ctx, fmt.Sprintf(`{ shipment(id: %q) { status actualDelivery notes events { status location actorType actorId } } }`, id))
+	if len(out.Errors) > 0 {
+		t.Fatal(out.Errors)
+	}
+	s := out.Data.(map[string]interface{})["shipment"].(map[string]interface{})
+	if s["status"] != "delivered" || s["actualDelivery"] == nil || s["notes"] != nil {
+		t.Errorf("shipment after rejected rewind: %v", s)
+	}
+	var statuses []string
+	for _, it := range s["events"].([]interface{}) {
+		e := it.(map[string]interface{})
+		statuses = append(statuses, e["status"].(string))
+		if e["actorType"] != "user" || e["actorId"] != b.Admin.ID.String() {
+			t.Errorf("event %v not attributed to the caller", e)
+		}
+	}
+	if got := fmt.Sprint(statuses); got != "[pending in_transit delivered]" {
+		t.Errorf("timeline %s", got)
+	}
+}
+
+// TestGraphQLPublicTrackingNeedsNoLogin resolves publicTracking without any
+// claims in the context and checks the redacted type has no tenant fields.
+// It does need the HTTP request, to throttle by client IP.
+func TestGraphQLPublicTrackingNeedsNoLogin(t *testing.T) {
+	schema := newSchema(t)
+	b := env.b
+	s := &models.Shipment{TenantID: b.TenantID, TrackingNumber: "GQLPUB-" + uuid.NewString()[:8], Status: models.ShipmentPending, Destination: str("Springfield 12345"), Notes: str("internal")}
+	if err := env.repos.Shipment.Create(tenantCtx(context.Background(), b.TenantID), s, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := context.WithValue(context.Background(), models.CtxHTTPRequest, httptest.NewRequest(http.MethodPost, "/graphql", nil))
+	q := `{ publicTracking(trackingNumber: %q, verifier: %q) { trackingNumber status predictedLate events { status } } }`
+	if out := execGraphQL(schema, context.Background(), fmt.Sprintf(q, s.TrackingNumber, "12345")); len(out.Errors) == 0 {
+		t.Error("publicTracking answered without a client to throttle")
+	}
+	out := execGraphQL(schema, ctx, fmt.Sprintf(q, s.TrackingNumber, "12345"))
+	if len(out.Errors) > 0 {
+		t.Fatal(out.Errors)
+	}
+	if pt := out.Data.(map[string]interface{})["publicTracking"].(map[string]interface{}); pt["status"] != "pending" {
+		t.Errorf("publicTracking returned %v", pt)
+	}
+
+	if out := execGraphQL(schema, ctx, fmt.Sprintf(q, s.TrackingNumber, "54321")); len(out.Errors) == 0 {
+		t.Error("publicTracking accepted a wrong verifier")
+	}
+	if out := execGraphQL(schema, ctx, fmt.Sprintf(`{ publicTracking(trackingNumber: %q, verifier: "12345") { notes } }`, s.TrackingNumber)); len(out.Errors) == 0 {
+		t.Error("PublicTracking exposes notes")
+	}
+}
+
+// TestGraphQLDispatchBoard builds a day's route through the dispatch API,
+// reorders and edits its stops, assigns it and checks a driver cannot be
+// booked on two plans the same day.
+func TestGraphQLDispatchBoard(t *testing.T) {
+	schema := newSchema(t)
+	b := env.b
+	ctx := userCtx(b)
+	r := env.repos
+	tag := uuid.NewString()[:8]
+
+	run := func(q string) map[string]interface{} {
+		t.Helper()
+		res := execGraphQL(schema, ctx, q)
+		if len(res.Errors) > 0 {
+			t.Fatalf("%s: %v", q, res.Errors)
+		}
+		return res.Data.(map[string]interface{})
+	}
+	stopsOf := func(plan interface{}) []map[string]interface{} {
+		var out []map[string]interface{}
+		for _, s := range plan.(map[string]interface{})["stops"].([]interface{}) {
+			out = append(out, s.(map[string]interface{}))
+		}
+		return out
+	}
+
+	promised := time.Date(2031, 3, 4, 9, 0, 0, 0, time.UTC)
+	near := &models.Shipment{TenantID: b.TenantID, TrackingNumber: "RT-NEAR-" + tag, Status: models.ShipmentPending, EstimatedDelivery: &promised, DestinationLatitude: ptr(40.75), DestinationLongitude: ptr(-73.99)}
+	far := &models.Shipment{TenantID: b.TenantID, TrackingNumber: "RT-FAR-" + tag, Status: models.ShipmentPending, DestinationLatitude: ptr(40.95), DestinationLongitude: ptr(-73.80)}
+	for _, s := range []*models.Shipment{near, far} {
+		if err := r.Shipment.Create(ctx, s, nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	const fields = `id status driverId vehicleId stops { id sequence type label latitude plannedArrival windowEnd missesWindow shipment { trackingNumber } }`
+	plan := run(fmt.Sprintf(`mutation { createRoutePlan(input: { planDate: "2031-03-04", name: "North %s", warehouseId: %q, plannedStart: "2031-03-04T08:00:00Z",
+		stops: [{ zoneId: %q }, { shipmentId: %q }, { shipmentId: %q, serviceMinutes: 20 }] }) { %s } }`,
+		tag, b.Warehouse.ID, b.Zone.ID, far.ID, near.ID, fields))["createRoutePlan"].(map[string]interface{})
+	planID := plan["id"].(string)
+	stops := stopsOf(plan)
+	if plan["status"] != models.RouteDraft || len(stops) != 3 {
+		t.Fatalf("created plan %v", plan)
+	}
+	if stops[0]["label"] != b.Zone.Label || stops[0]["type"] != models.StopVisit || stops[1]["type"] != models.StopDelivery || stops[1]["label"] != far.TrackingNumber {
+		t.Errorf("stops not filled from their zone and shipments: %v", stops)
+	}
+	for _, s := range stops {
+		if s["plannedArrival"] == nil {
+			t.Errorf("stop %v has no planned arrival", s["sequence"])
+		}
+	}
+	// The near shipment's promise is 9:00, but it is visited after the far one.
+	if stops[2]["missesWindow"] != true {
+		t.Errorf("near stop planned at %v misses its %v window: got %v", stops[2]["plannedArrival"], stops[2]["windowEnd"], stops[2]["missesWindow"])
+	}
+
+	ids := func(order ...int) string {
+		var out []string
+		for _, i := range order {
+			out = append(out, fmt.Sprintf("%q", stops[i]["id"]))
+		}
+		return "[" + strings.Join(out, ", ") + "]"
+	}
+	for _, bad := range []string{ids(0, 1), ids(0, 1, 1), ids(0, 1, 2, 2)} {
+		if res := execGraphQL(schema, ctx, fmt.Sprintf(`mutation { reorderRouteStops(routeId: %q, stopIds: %s) { id } }`, planID, bad)); len(res.Errors) == 0 {
+			t.Errorf("reorder accepted %s", bad)
+		}
+	}
+	reordered := stopsOf(run(fmt.Sprintf(`mutation { reorderRouteStops(routeId: %q, stopIds: %s) { %s } }`, planID, ids(0, 2, 1), fields))["reorderRouteStops"])
+	if reordered[1]["id"] != stops[2]["id"] || reordered[1]["sequence"] != 2 || reordered[2]["id"] != stops[1]["id"] || reordered[2]["sequence"] != 3 {
+		t.Errorf("reordered stops %v", reordered)
+	}
+	if reordered[1]["missesWindow"] != false {
+		t.Errorf("near stop still misses its window after moving up: %v", reordered[1])
+	}
+
+	if res := execGraphQL(schema, ctx, fmt.Sprintf(`mutation { addRouteStop(routeId: %q, input: { label: "nowhere" }) { id } }`, planID)); len(res.Errors) == 0 {
+		t.Error("addRouteStop accepted a stop without a position")
+	}
+	added := stopsOf(run(fmt.Sprintf(`mutation { addRouteStop(routeId: %q, position: 1, input: { type: "pickup", label: "Yard %s", latitude: 40.70, longitude: -74.01 }) { %s } }`, planID, tag, fields))["addRouteStop"])
+	if len(added) != 4 || added[0]["label"] != "Yard "+tag || added[0]["sequence"] != 1 || added[1]["id"] != stops[0]["id"] {
+		t.Errorf("stops after adding at position 1: %v", added)
+	}
+	run(fmt.Sprintf(`mutation { updateRouteStop(stopId: %q, input: { label: "Gate %s", serviceMinutes: 5 }) { id } }`, added[0]["id"], tag))
+	removed := stopsOf(run(fmt.Sprintf(`mutation { removeRouteStop(stopId: %q) { %s } }`, added[0]["id"], fields))["removeRouteStop"])
+	if len(removed) != 3 || removed[0]["id"] != stops[0]["id"] || removed[0]["sequence"] != 1 {
+		t.Errorf("stops after removing the first: %v", removed)
+	}
+
+	driver := &models.Driver{TenantID: b.TenantID, EmployeeID: "RT-" + tag, FirstName: str("Route"), LastName: str("Planner"), Status: "available"}
+	if err := r.Driver.Create(ctx, driver); err != nil {
+		t.Fatal(err)
+	}
+	assigned := run(fmt.Sprintf(`mutation { assignRoutePlan(id: %q, driverId: %q, vehicleId: %q) { %s vehicle { id } } }`, planID, driver.ID, b.Vehicle.ID, fields))["assignRoutePlan"].(map[string]interface{})
+	if assigned["status"] != models.RouteAssigned || assigned["driverId"] != driver.ID.String() || assigned["vehicle"].(map[string]interface{})["id"] != b.Vehicle.ID.String() {
+		t.Errorf("assigned plan %v", assigned)
+	}
+	for _, s := range []*models.Shipment{near, far} {
+		if n := env.count(t, `SELECT COUNT(*) FROM shipment_assignments WHERE shipment_id = $1 AND driver_id = $2 AND unassigned_at IS NULL`, s.ID, driver.ID); n != 1 {
+			t.Errorf("%s not assigned to the plan's driver", s.TrackingNumber)
+		}
+	}
+
+	other := run(fmt.Sprintf(`mutation { createRoutePlan(input: { planDate: "2031-03-04", name: "South %s", plannedStart: "2031-03-04T13:00:00Z" }) { id stops { id } } }`, tag))["createRoutePlan"].(map[string]interface{})
+	if res := execGraphQL(schema, ctx, fmt.Sprintf(`mutation { assignRoutePlan(id: %q, driverId: %q) { id } }`, other["id"], driver.ID)); len(res.Errors) == 0 {
+		t.Error("driver was booked on two plans the same day")
+	}
+
+	board := run(fmt.Sprintf(`{ routePlans(date: "2031-03-04", driverId: %q) { id name } }`, driver.ID))["routePlans"].([]interface{})
+	if len(board) != 1 || board[0].(map[string]interface{})["id"] != planID {
+		t.Errorf("driver's plans for the day %v", board)
+	}
+	if all := run(`{ routePlans(date: "2031-03-04") { id } }`)["routePlans"].([]interface{}); len(all) != 2 {
+		t.Errorf("plans for the day %v, want both", all)
+	}
+
+	if res := execGraphQL(schema, ctx, fmt.Sprintf(`mutation { deleteRoutePlan(id: %q) }`, planID)); len(res.Errors) == 0 {
+		t.Error("deleteRoutePlan removed an assigned plan")
+	}
+	if deleted := run(fmt.Sprintf(`mutation { deleteRoutePlan(id: %q) }`, other["id"]))["deleteRoutePlan"]; deleted != true {
+		t.Errorf("deleteRoutePlan on a draft = %v", deleted)
+	}
+}
+
+// TestGraphQLRouteOptimization proposes routes for a day's shipments across
+// two vehicles and accepts them as draft plans.
+func TestGraphQLRouteOptimization(t *testing.T) {
+	schema := newSchema(t)
+	b := env.b
+	ctx := userCtx(b)
+	r := env.repos
+	tag := uuid.NewString()[:8]
+
+	run := func(q string) map[string]interface{} {
+		t.Helper()
+		res := execGraphQL(schema, ctx, q)
+		if len(res.Errors) > 0 {
+			t.Fatalf("%s: %v", q, res.Errors)
+		}
+		return res.Data.(map[string]interface{})
+	}
+
+	depot := run(fmt.Sprintf(`mutation { createWarehouse(input: { name: "Depot %s", status: "active", latitude: 40.7128, longitude: -74.0060 }) { id latitude } }`, tag))["createWarehouse"].(map[string]interface{})
+	if depot["latitude"] != 40.7128 {
+		t.Fatalf("warehouse coordinates not saved: %v", depot)
+	}
+	var vehicleIDs []string
+	for _, capacity := range []float64{500, 1000} {
+		v := run(fmt.Sprintf(`mutation { createVehicle(input: { vehicleId: "OPT-%.0f-%s", status: "available", capacityKg: %.0f }) { id capacityKg } }`, capacity, tag, capacity))["createVehicle"].(map[string]interface{})
+		if v["capacityKg"] != capacity {
+			t.Fatalf("vehicle capacity not saved: %v", v)
+		}
+		vehicleIDs = append(vehicleIDs, v["id"].(string))
+	}
+
+	day := time.Date(2032, 5, 6, 0, 0, 0, 0, time.UTC)
+	shipment := func(name string, weight float64, lat, lng *float64, promised *time.Time) *models.Shipment {
+		s := &models.Shipment{TenantID: b.TenantID, TrackingNumber: "OPT-" + name + "-" + tag, Status: models.ShipmentPending, Weight: &weight, EstimatedDelivery: promised, DestinationLatitude: lat, DestinationLongitude: lng}
+		if err := r.Shipment.Create(ctx, s, nil); err != nil {
+			t.Fatal(err)
+		}
+		return s
+	}
+	routable := []*models.Shipment{
+		shipment("A", 300, ptr(40.75), ptr(-73.99), ptr(day.Add(14*time.Hour))),
+		shipment("B", 400, ptr(40.80), ptr(-73.95), nil),
+		shipment("C", 350, ptr(40.68), ptr(-73.94), nil),
+		shipment("D", 200, ptr(40.85), ptr(-73.88), ptr(day.Add(17*time.Hour))),
+	}
+	heavy := shipment("HEAVY", 3000, ptr(40.74), ptr(-74.03), nil)
+	late := shipment("LATE", 100, ptr(39.95), ptr(-75.17), ptr(day.Add(9*time.Hour)))
+	lost := shipment("LOST", 100, nil, nil, nil)
+
+	var all []string
+	for _, s := range append(routable, heavy, late, lost) {
+		all = append(all, fmt.Sprintf("%q", s.ID))
+	}
+	optimize := fmt.Sprintf(`mutation { optimizeRoutes(date: "2032-05-06", warehouseId: %q, shipmentIds: [%s], vehicleIds: [%q, %q]) {
+		plannedStart totalDistanceKm
+		routes { vehicleId loadKg capacityKg distanceKm vehicle { id } stops { sequence shipmentId plannedArrival shipment { trackingNumber } } }
+		unassigned { shipmentId reason shipment { trackingNumber } } } }`,
+		depot["id"], strings.Join(all, ", "), vehicleIDs[0], vehicleIDs[1])
+	proposal := run(optimize)["optimizeRoutes"].(map[string]interface{})
+
+	reasons := map[string]string{}
+	for _, u := range proposal["unassigned"].([]interface{}) {
+		u := u.(map[string]interface{})
+		reasons[u["shipmentId"].(string)] = u["reason"].(string)
+	}
+	for s, want := range map[*models.Shipment]string{heavy: routing.ReasonOverweight, late: routing.ReasonWindow, lost: "no destination coordinates"} {
+		if reasons[s.ID.String()] != want {
+			t.Errorf("%s left out for %q, want %q", s.TrackingNumber, reasons[s.ID.String()], want)
+		}
+	}
+	if len(reasons) != 3 {
+		t.Errorf("unassigned %v, want only the heavy, late and unlocated shipments", reasons)
+	}
+
+	placed := map[string]int{}
+	var routes []string
+	for _, rt := range proposal["routes"].([]interface{}) {
+		rt := rt.(map[string]interface{})
+		if rt["vehicle"].(map[string]interface{})["id"] != rt["vehicleId"] {
+			t.Errorf("route vehicle relation %v", rt)
+		}
+		if rt["loadKg"].(float64) > rt["capacityKg"].(float64) {
+			t.Errorf("route over capacity: %v", rt)
+		}
+		var ids []string
+		for i, st := range rt["stops"].([]interface{}) {
+			st := st.(map[string]interface{})
+			if st["sequence"] != i+1 || st["plannedArrival"] == nil || st["shipment"] == nil {
+				t.Errorf("proposed stop %v", st)
+			}
+			placed[st["shipmentId"].(string)]++
+			ids = append(ids, fmt.Sprintf("%q", st["shipmentId"]))
+		}
+		routes = append(routes, fmt.Sprintf(`{ vehicleId: %q, shipmentIds: [%s] }`, rt["vehicleId"], strings.Join(ids, ", ")))
+	}
+	for _, s := range routable {
+		if placed[s.ID.String()] != 1 {
+			t.Errorf("%s placed %d times", s.TrackingNumber, placed[s.ID.String()])
+		}
+	}
+	if len(routes) != 2 {
+		t.Errorf("1250 kg over a 500 and a 1000 kg vehicle used %d routes", len(routes))
+	}
+	if n := env.count(t, `SELECT COUNT(*) FROM route_plans WHERE warehouse_id = $1`, depot["id"]); n != 0 {
+		t.Errorf("optimizeRoutes saved %d plans", n)
+	}
+
+	// A supplied matrix replaces the estimate: 0-1-2-0 is the shortest tour.
+	tour := run(fmt.Sprintf(`mutation { optimizeRoutes(date: "2032-05-06", warehouseId: %q, shipmentIds: [%q, %q], vehicleIds: [%q],
+		distanceMatrix: [[0, 10, 20], [10, 0, 5], [20, 5, 0]]) { totalDistanceKm routes { stops { shipmentId } } } }`,
+		depot["id"], routable[0].ID, routable[1].ID, vehicleIDs[1]))["optimizeRoutes"].(map[string]interface{})
+	if tour["totalDistanceKm"] != 35.0 || len(tour["routes"].([]interface{})) != 1 {
+		t.Errorf("tour over the supplied matrix %v, want one route of 35 km", tour)
+	}
+	if res := execGraphQL(schema, ctx, fmt.Sprintf(`mutation { optimizeRoutes(date: "2032-05-06", warehouseId: %q, shipmentIds: [%q], distanceMatrix: [[0, 1], [1]]) { totalDistanceKm } }`, depot["id"], routable[0].ID)); len(res.Errors) == 0 {
+		t.Error("optimizeRoutes accepted a ragged distance matrix")
+	}
+
+	accept := fmt.Sprintf(`mutation { acceptRouteProposal(input: { planDate: "2032-05-06", warehouseId: %q, plannedStart: %q, routes: [%s] }) {
+		id status vehicleId warehouseId stops { type shipmentId plannedArrival } } }`,
+		depot["id"], "2032-05-06T08:00:00Z", strings.Join(routes, ", "))
+	plans := run(accept)["acceptRouteProposal"].([]interface{})
+	if len(plans) != len(routes) {
+		t.Fatalf("accepted %d plans for %d routes", len(plans), len(routes))
+	}
+	for _, p := range plans {
+		p := p.(map[string]interface{})
+		stops := p["stops"].([]interface{})
+		first, last := stops[0].(map[string]interface{}), stops[len(stops)-1].(map[string]interface{})
+		if p["status"] != models.RouteDraft || p["vehicleId"] == nil || p["warehouseId"] != depot["id"] || first["type"] != models.StopDepot || last["type"] != models.StopDepot {
+			t.Errorf("accepted plan %v", p)
+		}
+		for _, st := range stops[1 : len(stops)-1] {
+			if st := st.(map[string]interface{}); st["type"] != models.StopDelivery || st["plannedArrival"] == nil {
+				t.Errorf("accepted delivery stop %v", st)
+			}
+		}
+	}
+	if res := execGraphQL(schema, ctx, accept); len(res.Errors) == 0 {
+		t.Error("the same shipments were accepted onto a second set of plans")
+	}
+	if res := execGraphQL(schema, ctx, fmt.Sprintf(`mutation { optimizeRoutes(date: "2032-05-06", warehouseId: %q, shipmentIds: [%q]) { totalDistanceKm } }`, depot["id"], routable[0].ID)); len(res.Errors) == 0 {
+		t.Error("optimizeRoutes offered a shipment already on a plan")
+	}
+	if res := execGraphQL(schema, ctx, fmt.Sprintf(`mutation { optimizeRoutes(date: "2032-05-06", warehouseId: %q, shipmentIds: [%q], vehicleIds: [%q]) { totalDistanceKm } }`, depot["id"], lost.ID, vehicleIDs[0])); len(res.Errors) == 0 {
+		t.Error("optimizeRoutes offered a vehicle already on a plan that day")
+	}
+}
+
+// TestGraphQLTrackingNumbers generates tracking numbers in the default and a
+// configured format and checks supplied numbers against the check digit.
+func TestGraphQLTrackingNumbers(t *testing.T) {
+	schema := newSchema(t)
+	b := env.b
+	ctx := userCtx(b)
+	t.Cleanup(func() {
+		env.pool.Exec(database.Privileged(context.Background()), `DELETE FROM settings WHERE tenant_id = $1 AND key = ANY($2)`,
+			b.TenantID, []string{models.SettingTrackingPrefix, models.SettingTrackingDigits})
+	})
+
+	run := func(q string) map[string]interface{} {
+		t.Helper()
+		res := execGraphQL(schema, ctx, q)
+		if len(res.Errors) > 0 {
+			t.Fatalf("%s: %v", q, res.Errors)
+		}
+		return res.Data.(map[string]interface{})
+	}
+	create := fmt.Sprintf(`mutation { createShipment(input: { destination: "1 Main St, Chicago, IL 60601", warehouseId: %q }) { trackingNumber origin warehouse { id } } }`, b.Warehouse.ID)
+
+	first := run(create)["createShipment"].(map[string]interface{})
+	tn := first["trackingNumber"].(string)
+	if f := models.DefaultTrackingFormat(); !f.Valid(tn) {
+		t.Fatalf("generated %q is not a valid default-format tracking number", tn)
+	}
+	if first["origin"] != b.Warehouse.Name || first["warehouse"].(map[string]interface{})["id"] != b.Warehouse.ID.String() {
+		t.Errorf("shipment did not leave from its warehouse: %v", first)
+	}
+	second := run(create)["createShipment"].(map[string]interface{})["trackingNumber"].(string)
+	if second == tn {
+		t.Errorf("generated %q twice", tn)
+	}
+
+	if res := execGraphQL(schema, ctx, `mutation { updateSetting(key: "tracking_digits", value: "3") { key } }`); len(res.Errors) == 0 {
+		t.Error("accepted 3 tracking digits")
+	}
+	run(`mutation { updateSetting(key: "tracking_prefix", value: "zq") { value } }`)
+	run(`mutation { updateSetting(key: "tracking_digits", value: "7") { value } }`)
+	custom := run(create)["createShipment"].(map[string]interface{})["trackingNumber"].(string)
+	if f := (models.TrackingFormat{Prefix: "ZQ", Digits: 7}); !f.Valid(custom) {
+		t.Errorf("generated %q, want the ZQ format with 7 digits", custom)
+	}
+
+	// A typo in a number of the tenant's own format is caught; a carrier's
+	// number in another format is taken as it is.
+	typo := custom[:len(custom)-1] + string('0'+(custom[len(custom)-1]-'0'+1)%10)
+	if res := execGraphQL(schema, ctx, fmt.Sprintf(`mutation { createShipment(input: { trackingNumber: %q }) { id } }`, typo)); len(res.Errors) == 0 {
+		t.Errorf("accepted %q with a wrong check digit", typo)
+	}
+	run(`mutation { createShipment(input: { trackingNumber: "1Z999AA10123456784" }) { id } }`)
+}
+
+// TestGraphQLAddressGeocoding saves structured addresses and checks that they
+// are placed from the gazetteer, and that warehouses and clients get approved
+// zones that follow their coordinates, all within the caller's tenant.
+func TestGraphQLAddressGeocoding(t *testing.T) {
+	schema := newSchema(t)
+	a, b := env.a, env.b
+	ctx := userCtx(b)
+	var warehouseIDs, clientIDs []string
+	t.Cleanup(func() {
+		pctx := database.Privileged(context.Background())
+		env.pool.Exec(pctx, `DELETE FROM warehouses WHERE id::text = ANY($1)`, warehouseIDs)
+		env.pool.Exec(pctx, `DELETE FROM clients WHERE id::text = ANY($1)`, clientIDs)
+	})
+
+	run := func(q string) map[string]interface{} {
+		t.Helper()
+		res := execGraphQL(schema, ctx, q)
+		if len(res.Errors) > 0 {
+			t.Fatalf("%s: %v", q, res.Errors)
+		}
+		return res.Data.(map[string]interface{})
+	}
+	zones := `SELECT COUNT(*) FROM approved_zones WHERE tenant_id = $1 AND warehouse_id::text = $2 AND type = 'warehouse' AND latitude = $3`
+
+	wh := run(`mutation { createWarehouse(input: { name: "Hamburg Hub", addressDetails: { line1: " Am Sandtorkai 1", postalCode: "20457", city: "Hamburg", country: "de" } }) {
+		id address latitude longitude addressDetails { postalCode country } } }`)["createWarehouse"].(map[string]interface{})
+	whID := wh["id"].(string)
+	warehouseIDs = append(warehouseIDs, whID)
+	if wh["address"] != "Am Sandtorkai 1, 20457 Hamburg, DE" || wh["latitude"] != 53.5413 || wh["longitude"] != 9.9841 {
+		t.Errorf("warehouse not placed by its postcode: %v", wh)
+	}
+	if env.count(t, zones, b.TenantID, whID, 53.5413) != 1 {
+		t.Error("no warehouse zone at the geocoded position")
+	}
+
+	s := run(fmt.Sprintf(`mutation { createShipment(input: { warehouseId: %q, destinationAddress: { line1: "Marienplatz 8", city: "Munich", region: "Bavaria", country: "DE" } }) {
+		origin destination destinationLatitude originAddress { postalCode } } }`, whID))["createShipment"].(map[string]interface{})
+	if s["destination"] != "Marienplatz 8, Munich, Bavaria, DE" || s["destinationLatitude"] != 48.1374 {
+		t.Errorf("destination not placed by its city: %v", s)
+	}
+	if s["origin"] != wh["address"] || s["originAddress"].(map[string]interface{})["postalCode"] != "20457" {
+		t.Errorf("origin not taken from the warehouse: %v", s)
+	}
+
+	c := run(`mutation { createClient(input: { companyName: "Elbe Traders", addressDetails: { postalCode: "20095", country: "DE" } }) { id latitude } }`)["createClient"].(map[string]interface{})
+	clientID := c["id"].(string)
+	clientIDs = append(clientIDs, clientID)
+	if c["latitude"] != 53.5503 {
+		t.Errorf("client latitude = %v, want 53.5503", c["latitude"])
+	}
+	// A radius a manager has widened survives the site moving.
+	env.pool.Exec(database.Privileged(context.Background()), `UPDATE approved_zones SET radius_meters = 400 WHERE client_id::text = $1`, clientID)
+	run(fmt.Sprintf(`mutation { updateClient(id: %q, input: { companyName: "Elbe Traders", addressDetails: { postalCode: "20457", country: "DE" } }) { id } }`, clientID))
+	if env.count(t, `SELECT COUNT(*) FROM approved_zones WHERE client_id::text = $1 AND type = 'client_site' AND latitude = 53.5413 AND radius_meters = 400`, clientID) != 1 {
+		t.Error("client zone did not follow the client's new address")
+	}
+
+	// Clearing the address and coordinates removes the warehouse's zone.
+	run(fmt.Sprintf(`mutation { updateWarehouse(id: %q, input: { name: "Hamburg Hub" }) { id } }`, whID))
+	if env.count(t, `SELECT COUNT(*) FROM approved_zones WHERE warehouse_id::text = $1`, whID) != 0 {
+		t.Error("zone kept for a warehouse without coordinates")
+	}
+
+	if res := execGraphQL(schema, ctx, `mutation { createWarehouse(input: { name: "Bad", addressDetails: { city: "Hamburg", country: "Germany" } }) { id } }`); len(res.Errors) == 0 {
+		t.Error("accepted a country that is not an ISO code")
+	}
+
+	g := run(`{ city: geocodeAddress(address: { city: "hamburg", country: "DE" }) { precision } unknown: geocodeAddress(address: { postalCode: "99999", country: "DE" }) { precision } }`)
+	if g["city"].(map[string]interface{})["precision"] != "city" || g["unknown"] != nil {
+		t.Errorf("geocodeAddress = %v", g)
+	}
+
+	// Records saved before geocoding existed are placed by the backfill,
+	// which never touches another tenant's warehouses.
+	old := &models.Warehouse{TenantID: b.TenantID, Name: "Old Depot", Status: "active", AddressDetails: &models.Address{PostalCode: "20095", Country: "DE"}}
+	if err := env.repos.Warehouse.Create(tenantCtx(context.Background(), b.TenantID), old); err != nil {
+		t.Fatal(err)
+	}
+	warehouseIDs = append(warehouseIDs, old.ID.String())
+	sync := run(`mutation { syncLocationZones { geocoded unplaced zones } }`)["syncLocationZones"].(map[string]interface{})
+	if sync["geocoded"] != 1 || sync["unplaced"] != 0 {
+		t.Errorf("syncLocationZones = %v, want one warehouse geocoded", sync)
+	}
+	if env.count(t, zones, b.TenantID, old.ID.String(), 53.5503) != 1 {
+		t.Error("backfill did not create the old warehouse's zone")
+	}
+	if err := env.repos.Zone.SyncWarehouseZone(tenantCtx(context.Background(), b.TenantID), b.TenantID, a.Warehouse.ID); err != nil {
+		t.Fatal(err)
+	}
+	if env.count(t, `SELECT COUNT(*) FROM approved_zones WHERE warehouse_id = $1`, a.Warehouse.ID) != 0 {
+		t.Error("tenant B created a zone for tenant A's warehouse")
+	}
+}
+
+// TestGraphQLRateCardQuotes prices shipments from a default and a client rate
+// card and checks that createOrder takes its total from the quote when none
+// is given.
+func TestGraphQLRateCardQuotes(t *testing.T) {
+	schema := newSchema(t)
+	a, b := env.a, env.b
+	ctx := userCtx(b)
+	var clientIDs []string
+	t.Cleanup(func() {
+		pctx := database.Privileged(context.Background())
+		env.pool.Exec(pctx, `DELETE FROM rate_cards WHERE tenant_id = $1`, b.TenantID)
+		env.pool.Exec(pctx, `DELETE FROM clients WHERE id::text = ANY($1)`, clientIDs)
+	})
+
+	run := func(q string) map[string]interface{} {
+		t.Helper()
+		res := execGraphQL(schema, ctx, q)
+		if len(res.Errors) > 0 {
+			t.Fatalf("%s: %v", q, res.Errors)
+		}
+		return res.Data.(map[string]interface{})
+	}
+	total := func(q map[string]interface{}) interface{} { return q["total"] }
+
+	card := run(`mutation { createRateCard(input: {
+		name: "Domestic", minCharge: 30,
+		zones: [{ code: "north", country: "de", regions: ["Hamburg"] }, { code: "south", country: "DE", regions: ["Bavaria"] }],
+		lanes: [{ origin: "north", destination: "south", baseCharge: 20, perKm: 0.1, breaks: [{ minKg: 0, perKg: 2 }, { minKg: 100, perKg: 1.5 }] }],
+		surcharges: [{ code: "fuel", name: "Fuel", kind: "percent", amount: 10 }, { code: "residential", kind: "flat", amount: 5, condition: "residential" }]
+	}) { id currency volumetricDivisor zones { code country } surcharges { condition } } }`)["createRateCard"].(map[string]interface{})
+	if card["currency"] != "USD" || card["volumetricDivisor"] != 5000.0 || card["zones"].([]interface{})[0].(map[string]interface{})["country"] != "DE" {
+		t.Errorf("card not normalized: %v", card)
+	}
+	cardID := card["id"].(string)
+
+	for name, input := range map[string]string{
+		"unknown zone": `{ name: "Bad", lanes: [{ origin: "east" }] }`,
+		"late break":   `{ name: "Bad", lanes: [{ breaks: [{ minKg: 5, perKg: 1 }] }] }`,
+		"bad kind":     `{ name: "Bad", surcharges: [{ code: "x", kind: "weekly", amount: 1 }] }`,
+		"two defaults": `{ name: "Second" }`,
+	} {
+		if res := execGraphQL(schema, ctx, fmt.Sprintf(`mutation { createRateCard(input: %s) { id } }`, input)); len(res.Errors) == 0 {
+			t.Errorf("%s: card accepted", name)
+		}
+	}
+
+	route := `originAddress: { city: "Hamburg", region: "Hamburg", country: "DE" }, destinationAddress: { city: "Munich", region: "Bavaria", country: "DE" }`
+	fields := `{ rateCardId chargeableWeightKg volumetricWeightKg lines { code amount } total }`
+	// 100x50x40 cm is 40 kg volumetric, more than the 10 kg it weighs:
+	// 20 base + 40 kg at 2 + 500 km at 0.1 = 150, plus 10% fuel.
+	q := run(fmt.Sprintf(`{ quoteShipment(%s, weight: 10, dimensions: "100 x 50 x 40 cm", distanceKm: 500) %s }`, route, fields))["quoteShipment"].(map[string]interface{})
+	if q["chargeableWeightKg"] != 40.0 || q["rateCardId"] != cardID || total(q) != 165.0 {
+		t.Errorf("quote = %v, want 40 kg chargeable and 165 total", q)
+	}
+	if lines := q["lines"].([]interface{}); len(lines) != 4 || lines[3].(map[string]interface{})["code"] != "fuel" {
+		t.Errorf("lines = %v, want base, weight, distance and fuel", lines)
+	}
+	q = run(fmt.Sprintf(`{ quoteShipment(%s, weight: 1, distanceKm: 10, options: ["residential"]) %s }`, route, fields))["quoteShipment"].(map[string]interface{})
+	// 20 + 2 + 1 = 23 is raised to the 30 minimum, then 3 fuel and 5 residential.
+	if total(q) != 38.0 {
+		t.Errorf("minimum charge quote = %v, want 38", q)
+	}
+	if res := execGraphQL(schema, ctx, fmt.Sprintf(`{ quoteShipment(%s, weight: 1, distanceKm: 10, options: ["hazmat"]) { total } }`, route)); len(res.Errors) == 0 {
+		t.Error("quoted an option no card has a surcharge for")
+	}
+	// Without a distance it is estimated from the geocoded addresses.
+	q = run(fmt.Sprintf(`{ quoteShipment(%s, weight: 1) { distanceKm } }`, route))["quoteShipment"].(map[string]interface{})
+	if km, _ := q["distanceKm"].(float64); km < 600 || km > 800 {
+		t.Errorf("estimated distance = %v km, want Hamburg to Munich by road", q["distanceKm"])
+	}
+	if res := execGraphQL(schema, ctx, `{ quoteShipment(originAddress: { region: "Hamburg", country: "DE" }, destinationAddress: { region: "Bavaria", country: "DE" }, weight: 1) { total } }`); len(res.Errors) == 0 {
+		t.Error("quoted a per-km lane without a distance or coordinates")
+	}
+
+	// A client's card is tried first and keeps the default's surcharges it
+	// does not override.
+	c := run(`mutation { createClient(input: { companyName: "Isar Foods" }) { id } }`)["createClient"].(map[string]interface{})
+	clientID := c["id"].(string)
+	clientIDs = append(clientIDs, clientID)
+	run(fmt.Sprintf(`mutation { createRateCard(input: {
+		name: "Isar Foods", clientId: %q,
+		zones: [{ code: "south", country: "DE", regions: ["Bavaria"] }],
+		lanes: [{ destination: "south", baseCharge: 10, breaks: [{ minKg: 0, perKg: 1 }] }],
+		surcharges: [{ code: "fuel", kind: "percent", amount: 5 }]
+	}) { id } }`, clientID))
+	q = run(fmt.Sprintf(`{ quoteShipment(%s, clientId: %q, weight: 10, options: ["residential"]) %s }`, route, clientID, fields))["quoteShipment"].(map[string]interface{})
+	if q["rateCardId"] == cardID || total(q) != 26.0 {
+		t.Errorf("client quote = %v, want 10 + 10 + 5%% fuel + 5 residential", q)
+	}
+
+	s := run(fmt.Sprintf(`mutation { createShipment(input: { weight: 10, %s }) { id } }`, route))["createShipment"].(map[string]interface{})
+	o := run(fmt.Sprintf(`mutation { createOrder(input: { orderNumber: "RC-1", shipmentId: %q, clientId: %q }) { id totalAmount clientId quote { total rateCardName } } }`,
+		s["id"], clientID))["createOrder"].(map[string]interface{})
+	if o["totalAmount"] != 21.0 || o["quote"].(map[string]interface{})["rateCardName"] != "Isar Foods" {
+		t.Errorf("order not priced from the client card: %v", o)
+	}
+	o = run(fmt.Sprintf(`mutation { updateOrder(id: %q, input: { orderNumber: "RC-1", totalAmount: 19.5 }) { totalAmount clientId quote { total } } }`, o["id"]))["updateOrder"].(map[string]interface{})
+	if o["totalAmount"] != 19.5 || o["clientId"] != clientID || o["quote"] == nil {
+		t.Errorf("update lost the order's client or quote: %v", o)
+	}
+	o = run(fmt.Sprintf(`mutation { createOrder(input: { orderNumber: "RC-2", shipmentId: %q, totalAmount: 99 }) { totalAmount quote { total } } }`, s["id"]))["createOrder"].(map[string]interface{})
+	if o["totalAmount"] != 99.0 || o["quote"] != nil {
+		t.Errorf("given amount replaced by a quote: %v", o)
+	}
+	abroad := run(`mutation { createShipment(input: { destinationAddress: { city: "Lyon", country: "FR" } }) { id } }`)["createShipment"].(map[string]interface{})
+	o = run(fmt.Sprintf(`mutation { createOrder(input: { orderNumber: "RC-3", shipmentId: %q }) { totalAmount } }`, abroad["id"]))["createOrder"].(map[string]interface{})
+	if o["totalAmount"] != nil {
+		t.Errorf("order with no covering lane priced at %v", o["totalAmount"])
+	}
+	// Free-text dimensions from before rate cards do not stop the order.
+	pallet := run(fmt.Sprintf(`mutation { createShipment(input: { weight: 10, dimensions: "one euro pallet", %s }) { id } }`, route))["createShipment"].(map[string]interface{})
+	if res := execGraphQL(schema, ctx, fmt.Sprintf(`{ quoteShipment(shipmentId: %q) { total } }`, pallet["id"])); len(res.Errors) == 0 {
+		t.Error("quoted unreadable dimensions")
+	}
+	o = run(fmt.Sprintf(`mutation { createOrder(input: { orderNumber: "RC-4", shipmentId: %q }) { totalAmount quote { total } } }`, pallet["id"]))["createOrder"].(map[string]interface{})
+	if o["totalAmount"] != nil || o["quote"] != nil {
+		t.Errorf("order with unreadable dimensions priced: %v", o)
+	}
+
+	// Tenant A sees none of it.
+	actx := userCtx(a)
+	if res := execGraphQL(schema, actx, fmt.Sprintf(`{ rateCard(id: %q) { id } }`, cardID)); len(res.Errors) == 0 {
+		t.Error("tenant A read tenant B's rate card")
+	}
+	if res := execGraphQL(schema, actx, `{ rateCards { id } }`); len(res.Errors) > 0 || len(res.Data.(map[string]interface{})["rateCards"].([]interface{})) != 0 {
+		t.Errorf("tenant A listed rate cards: %v", res)
+	}
+	if res := execGraphQL(schema, actx, fmt.Sprintf(`{ quoteShipment(clientId: %q, weight: 1) { total } }`, clientID)); len(res.Errors) == 0 {
+		t.Error("tenant A quoted for tenant B's client")
+	}
+}
+
+func TestGraphQLInvoicing(t *testing.T) {
+	schema := newSchema(t)
+	a, b := env.a, env.b
+	ctx := userCtx(b)
+	t.Cleanup(func() {
+		pctx := database.Privileged(context.Background())
+		env.pool.Exec(pctx, `DELETE FROM orders WHERE tenant_id = $1 AND order_number LIKE 'INV-T%'`, b.TenantID)
+		env.pool.Exec(pctx, `DELETE FROM payments WHERE tenant_id = $1`, b.TenantID)
+		env.pool.Exec(pctx, `DELETE FROM invoices WHERE tenant_id = $1 AND kind = 'credit_note'`, b.TenantID)
+		env.pool.Exec(pctx, `DELETE FROM invoices WHERE tenant_id = $1`, b.TenantID)
+		env.pool.Exec(pctx, `DELETE FROM clients WHERE tenant_id = $1 AND company_name = 'Harbour Supplies'`, b.TenantID)
+		env.pool.Exec(pctx, `DELETE FROM settings WHERE tenant_id = $1 AND key IN ('invoice_prefix', 'invoice_taxes', 'payment_terms_days')`, b.TenantID)
+	})
+
+	run := func(q string) map[string]interface{} {
+		t.Helper()
+		res := execGraphQL(schema, ctx, q)
+		if len(res.Errors) > 0 {
+			t.Fatalf("%s: %v", q, res.Errors)
+		}
+		return res.Data.(map[string]interface{})
+	}
+	fails := func(name, q string) {
+		t.Helper()
+		if res := execGraphQL(schema, ctx, q); len(res.Errors) == 0 {
+			t.Errorf("%s: accepted", name)
+		}
+	}
+
+	fails("bad taxes", `mutation { updateSetting(key: "invoice_taxes", value: "GST") { key } }`)
+	run(`mutation { updateSetting(key: "invoice_taxes", value: "GST=5, PST=7") { value } }`)
+	run(`mutation { updateSetting(key: "payment_terms_days", value: "14") { value } }`)
+	run(`mutation { updateSetting(key: "invoice_prefix", value: "ib") { value } }`)
+
+	clientID := run(`mutation { createClient(input: { companyName: "Harbour Supplies" }) { id } }`)["createClient"].(map[string]interface{})["id"].(string)
+	order := func(number, status string, amount float64) string {
+		t.Helper()
+		return run(fmt.Sprintf(`mutation { createOrder(input: { orderNumber: %q, clientId: %q, status: %q, totalAmount: %v }) { id } }`,
+			number, clientID, status, amount))["createOrder"].(map[string]interface{})["id"].(string)
+	}
+	first := order("INV-T1", "delivered", 100)
+	second := order("INV-T2", "delivered", 250)
+	order("INV-T3", "pending", 50)
+
+	today := time.Now().UTC()
+	day := func(offset int) string { return today.AddDate(0, 0, offset).Format("2006-01-02") }
+	generate := fmt.Sprintf(`mutation { generateInvoices(periodStart: %q, periodEnd: %q, clientId: %q) {
+		id invoiceNumber status issueDate dueDate subtotal taxTotal total balanceDue lines { orderId amount } taxes { name amount } } }`,
+		day(-1), day(1), clientID)
+	invoices := run(generate)["generateInvoices"].([]interface{})
+	if len(invoices) != 1 {
+		t.Fatalf("generated %d invoices, want 1", len(invoices))
+	}
+	inv := invoices[0].(map[string]interface{})
+	invoiceID := inv["id"].(string)
+	// The pending order is not billed; 350 is taxed at 5% and 7%.
+	if len(inv["lines"].([]interface{})) != 2 || inv["subtotal"] != 350.0 || inv["taxTotal"] != 42.0 || inv["total"] != 392.0 {
+		t.Errorf("invoice = %v, want two lines, 350 + 42 tax", inv)
+	}
+	if taxes := inv["taxes"].([]interface{}); len(taxes) != 2 || taxes[1].(map[string]interface{})["amount"] != 24.5 {
+		t.Errorf("taxes = %v, want GST 17.5 and PST 24.5", taxes)
+	}
+	if !strings.HasPrefix(inv["invoiceNumber"].(string), "IB-") || inv["issueDate"] != day(0) || inv["dueDate"] != day(14) {
+		t.Errorf("invoice numbered or dated wrongly: %v", inv)
+	}
+	if again := run(generate)["generateInvoices"].([]interface{}); len(again) != 0 {
+		t.Errorf("orders billed twice: %v", again)
+	}
+	if o := run(fmt.Sprintf(`{ order(id: %q) { invoiceId } }`, first))["order"].(map[string]interface{}); o["invoiceId"] != invoiceID {
+		t.Errorf("order not marked invoiced: %v", o)
+	}
+
+	fails("overpayment", fmt.Sprintf(`mutation { recordPayment(invoiceId: %q, amount: 500) { id } }`, invoiceID))
+	paid := run(fmt.Sprintf(`mutation { recordPayment(invoiceId: %q, amount: 200, method: "bank_transfer") { status amountPaid balanceDue payments { amount method } } }`,
+		invoiceID))["recordPayment"].(map[string]interface{})
+	if paid["status"] != "partially_paid" || paid["balanceDue"] != 192.0 || len(paid["payments"].([]interface{})) != 1 {
+		t.Errorf("after payment: %v", paid)
+	}
+	spent := func() float64 {
+		t.Helper()
+		v, _ := run(fmt.Sprintf(`{ client(id: %q) { totalSpent } }`, clientID))["client"].(map[string]interface{})["totalSpent"].(float64)
+		return v
+	}
+	if got := spent(); got != 200 {
+		t.Errorf("totalSpent = %v, want 200", got)
+	}
+
+	// Only returned orders on the invoice can be credited.
+	fails("credit undelivered", fmt.Sprintf(`mutation { createCreditNote(invoiceId: %q, reason: "x", orderIds: [%q]) { id } }`, invoiceID, first))
+	run(fmt.Sprintf(`mutation { updateOrder(id: %q, input: { orderNumber: "INV-T2", status: "returned", returnReason: "damaged" }) { id } }`, second))
+	note := run(fmt.Sprintf(`mutation { createCreditNote(invoiceId: %q, reason: "Damaged in transit", orderIds: [%q]) {
+		kind invoiceNumber creditedInvoiceId total } }`, invoiceID, second))["createCreditNote"].(map[string]interface{})
+	if note["kind"] != "credit_note" || note["creditedInvoiceId"] != invoiceID || note["total"] != 280.0 {
+		t.Errorf("credit note = %v, want 250 + 12%% tax against the invoice", note)
+	}
+	fails("credit twice", fmt.Sprintf(`mutation { createCreditNote(invoiceId: %q, reason: "x", orderIds: [%q]) { id } }`, invoiceID, second))
+	// 392 - 200 paid - 280 credited leaves 88 owed back to the client.
+	credited := run(fmt.Sprintf(`{ invoice(id: %q) { status balanceDue creditNotes { id } } }`, invoiceID))["invoice"].(map[string]interface{})
+	if credited["status"] != "paid" || credited["balanceDue"] != -88.0 || len(credited["creditNotes"].([]interface{})) != 1 {
+		t.Errorf("after credit: %v", credited)
+	}
+	if got := spent(); got != 112 {
+		t.Errorf("totalSpent = %v, want 112 after the refund", got)
+	}
+	fails("void paid", fmt.Sprintf(`mutation { voidInvoice(id: %q, reason: "x") { id } }`, invoiceID))
+
+	// A void invoice releases its orders to the next run.
+	third := order("INV-T4", "delivered", 80)
+	voided := run(generate)["generateInvoices"].([]interface{})[0].(map[string]interface{})
+	v := run(fmt.Sprintf(`mutation { voidInvoice(id: %q, reason: "Wrong period") { status } }`, voided["id"]))["voidInvoice"].(map[string]interface{})
+	if v["status"] != "void" {
+		t.Errorf("voided invoice status = %v", v["status"])
+	}
+	if o := run(fmt.Sprintf(`{ order(id: %q) { invoiceId } }`, third))["order"].(map[string]interface{}); o["invoiceId"] != nil {
+		t.Errorf("void invoice kept its order: %v", o)
+	}
+	reissued := run(generate)["generateInvoices"].([]interface{})
+	if len(reissued) != 1 || reissued[0].(map[string]interface{})["total"] != 89.6 {
+		t.Fatalf("reissued = %v, want one invoice of 89.60", reissued)
+	}
+
+	// 16 days past due by then.
+	aging := run(fmt.Sprintf(`{ agingReport(asOf: %q) { asOf total buckets { label amount invoices } clients { clientId total } } }`, day(30)))["agingReport"].(map[string]interface{})
+	bucket := aging["buckets"].([]interface{})[1].(map[string]interface{})
+	if aging["total"] != 89.6 || bucket["label"] != "1-30" || bucket["invoices"] != 1 || len(aging["clients"].([]interface{})) != 1 {
+		t.Errorf("aging = %v", aging)
+	}
+	revenue := run(fmt.Sprintf(`{ revenueReport(year: %d) { totalRevenue collected outstanding } }`, today.Year()))["revenueReport"].(map[string]interface{})
+	// 350 + 80 invoiced less 250 credited; the void invoice does not count.
+	if revenue["totalRevenue"] != 180.0 || revenue["collected"] != 200.0 || revenue["outstanding"] != 89.6 {
+		t.Errorf("revenue = %v", revenue)
+	}
+	if list := run(`{ invoices(kind: "invoice", status: "void") { totalCount } }`)["invoices"].(map[string]interface{}); list["totalCount"] != 1 {
+		t.Errorf("void invoices = %v, want 1", list)
+	}
+
+	// Tenant A sees none of it.
+	actx := userCtx(a)
+	if res := execGraphQL(schema, actx, fmt.Sprintf(`{ invoice(id: %q) { id } }`, invoiceID)); len(res.Errors) == 0 {
+		t.Error("tenant A read tenant B's invoice")
+	}
+	if res := execGraphQL(schema, actx, fmt.Sprintf(`mutation { recordPayment(invoiceId: %q, amount: 1) { id } }`, invoiceID)); len(res.Errors) == 0 {
+		t.Error("tenant A paid tenant B's invoice")
+	}
+	if res := execGraphQL(schema, actx, `{ invoices { totalCount } }`); len(res.Errors) > 0 || res.Data.(map[string]interface{})["invoices"].(map[string]interface{})["totalCount"] != 0 {
+		t.Errorf("tenant A listed invoices: %v", res)
+	}
+}
+
+// TestGraphQLFulfillment ships an order stocked at two warehouses: its pick
+// lists are split by warehouse, a short pick is picked again, and each packed
+// parcel goes out in its own shipment. The order moves to shipped with the
+// last parcel and to delivered with the last delivery.
+func TestGraphQLFulfillment(t *testing.T) {
+	schema := newSchema(t)
+	a, b := env.a, env.b
+	ctx := userCtx(b)
+	tag := uuid.NewString()[:8]
+	t.Cleanup(func() {
+		pctx := database.Privileged(context.Background())
+		env.pool.Exec(pctx, `DELETE FROM orders WHERE tenant_id = $1 AND order_number = $2`, b.TenantID, "FUL-"+tag)
+	})
+
+	run := func(q string) map[string]interface{} {
+		t.Helper()
+		res := execGraphQL(schema, ctx, q)
+		if len(res.Errors) > 0 {
+			t.Fatalf("%s: %v", q, res.Errors)
+		}
+		return res.Data.(map[string]interface{})
+	}
+	fails := func(name, q string) {
+		t.Helper()
+		if res := execGraphQL(schema, ctx, q); len(res.Errors) == 0 {
+			t.Errorf("%s: accepted", name)
+		}
+	}
+	field := func(m interface{}, key string) interface{} { return m.(map[string]interface{})[key] }
+
+	north := b.Warehouse.ID.String()
+	south := field(run(fmt.Sprintf(`mutation { createWarehouse(input: { name: "South %s", capacity: 100 }) { id } }`, tag))["createWarehouse"], "id").(string)
+	for _, it := range []struct{ sku, warehouse string }{{"FA-" + tag, north}, {"FB-" + tag, south}} {
+		run(fmt.Sprintf(`mutation { createInventoryItem(input: { warehouseId: %q, sku: %q, quantity: 10, minQuantity: 1, unitPrice: 4 }) { id } }`, it.warehouse, it.sku))
+	}
+
+	o := run(fmt.Sprintf(`mutation { createOrder(input: { orderNumber: "FUL-%s", customerName: "Dock 9",
+		lines: [{ sku: "FA-%s", quantity: 5 }, { sku: "FB-%s", quantity: 2 }] }) { id status totalAmount lines { id } } }`, tag, tag, tag))["createOrder"].(map[string]interface{})
+	if o["status"] != "pending" || o["totalAmount"] != 28.0 {
+		t.Fatalf("created order %v", o)
+	}
+	orderID := o["id"].(string)
+	lineA := field(o["lines"].([]interface{})[0], "id").(string)
+
+	const fulfillmentFields = `{ id status warehouseId lines { orderLineId quantity } shipment { id trackingNumber warehouseId destination weight } }`
+	pack := func(id string) map[string]interface{} {
+		t.Helper()
+		run(fmt.Sprintf(`mutation { confirmPick(id: %q) { status } }`, id))
+		return run(fmt.Sprintf(`mutation { packFulfillment(id: %q, input: { weight: 3.5, dimensions: "40x30x20 cm", destination: "9 Dock Rd" }) %s }`,
+			id, fulfillmentFields))["packFulfillment"].(map[string]interface{})
+	}
+	orderState := func() map[string]interface{} {
+		t.Helper()
+		return run(fmt.Sprintf(`{ order(id: %q) { status shipmentId fulfillments { status } lines { reservedQuantity shippedQuantity } } }`, orderID))["order"].(map[string]interface{})
+	}
+
+	fails("pick more than reserved", fmt.Sprintf(`mutation { createPickLists(orderId: %q, lines: [{ orderLineId: %q, quantity: 6 }]) { id } }`, orderID, lineA))
+	first := run(fmt.Sprintf(`mutation { createPickLists(orderId: %q, lines: [{ orderLineId: %q, quantity: 3 }]) %s }`, orderID, lineA, fulfillmentFields))["createPickLists"].([]interface{})
+	if len(first) != 1 || field(first[0], "warehouseId") != north {
+		t.Fatalf("partial pick lists %v", first)
+	}
+	rest := run(fmt.Sprintf(`mutation { createPickLists(orderId: %q) %s }`, orderID, fulfillmentFields))["createPickLists"].([]interface{})
+	if len(rest) != 2 || field(rest[0], "warehouseId") != north || field(rest[1], "warehouseId") != south {
+		t.Fatalf("remaining pick lists not split by warehouse: %v", rest)
+	}
+	fails("nothing left to pick", fmt.Sprintf(`mutation { createPickLists(orderId: %q) { id } }`, orderID))
+	if lists := run(fmt.Sprintf(`{ pickLists(warehouseId: %q) { id } }`, south))["pickLists"].([]interface{}); len(lists) != 1 {
+		t.Errorf("south has %d pick lists, want 1", len(lists))
+	}
+	if got := orderState()["status"]; got != "processing" {
+		t.Errorf("order with pick lists is %v, want processing", got)
+	}
+
+	fails("pack before picking", fmt.Sprintf(`mutation { packFulfillment(id: %q, input: { weight: 1, dimensions: "1x1x1 cm", destination: "x" }) { id } }`, field(first[0], "id")))
+	packed := pack(field(first[0], "id").(string))
+	s := packed["shipment"].(map[string]interface{})
+	if packed["status"] != "shipped" || s["trackingNumber"] == "" || s["warehouseId"] != north || s["destination"] != "9 Dock Rd" || s["weight"] != 3.5 {
+		t.Errorf("packed fulfillment %v", packed)
+	}
+	if st := orderState(); st["status"] != "processing" || st["shipmentId"] != s["id"] {
+		t.Errorf("after the first parcel the order is %v", st)
+	}
+
+	// Two of the remaining two on line A come up one short.
+	short := field(rest[0], "id").(string)
+	run(fmt.Sprintf(`mutation { confirmPick(id: %q, lines: [{ orderLineId: %q, quantity: 1 }]) { status } }`, short, lineA))
+	run(fmt.Sprintf(`mutation { packFulfillment(id: %q, input: { weight: 1, dimensions: "20x20x20 cm", destination: "9 Dock Rd" }) { id } }`, short))
+	pack(field(rest[1], "id").(string))
+	if st := orderState(); st["status"] != "processing" {
+		t.Errorf("order shipped with a line short: %v", st)
+	}
+	again := run(fmt.Sprintf(`mutation { createPickLists(orderId: %q) %s }`, orderID, fulfillmentFields))["createPickLists"].([]interface{})
+	if len(again) != 1 || field(field(again[0], "lines").([]interface{})[0], "quantity") != 1 {
+		t.Fatalf("re-pick of the short line %v", again)
+	}
+	pack(field(again[0], "id").(string))
+
+	st := orderState()
+	if st["status"] != "shipped" || len(st["fulfillments"].([]interface{})) != 4 {
+		t.Fatalf("fully packed order %v", st)
+	}
+	for _, l := range st["lines"].([]interface{}) {
+		if field(l, "reservedQuantity") != 0 {
+			t.Errorf("shipped line still holds stock: %v", l)
+		}
+	}
+	var onHand int
+	env.scalar(t, &onHand, `SELECT quantity FROM inventory_items WHERE tenant_id = $1 AND sku = $2`, b.TenantID, "FA-"+tag)
+	if onHand != 5 {
+		t.Errorf("FA on hand %d after shipping 5 of 10", onHand)
+	}
+
+	rows, err := env.pool.Query(tenantCtx(context.Background(), b.TenantID), `SELECT shipment_id FROM fulfillments WHERE order_id = $1 AND status = 'shipped'`, orderID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var shipments []uuid.UUID
+	for rows.Next() {
+		var id uuid.UUID
+		rows.Scan(&id)
+		shipments = append(shipments, id)
+	}
+	rows.Close()
+	for i, id := range shipments {
+		for _, to := range []string{models.ShipmentInTransit, models.ShipmentDelivered} {
+			if _, err := env.repos.Shipment.Transition(tenantCtx(context.Background(), b.TenantID), b.TenantID, id, &models.ShipmentEvent{Status: to}); err != nil {
+				t.Fatal(err)
+			}
+		}
+		want := "shipped"
+		if i == len(shipments)-1 {
+			want = "delivered"
+		}
+		if got := orderState()["status"]; got != want {
+			t.Errorf("after %d of %d deliveries the order is %v, want %s", i+1, len(shipments), got, want)
+		}
+	}
+
+	if res := execGraphQL(schema, userCtx(a), fmt.Sprintf(`{ fulfillment(id: %q) { id } }`, field(first[0], "id"))); len(res.Errors) == 0 {
+		t.Error("another tenant read the fulfillment")
+	}
+}
+
+// TestGraphQLOrderSchedule manages a weekly standing order: its dates read
+// back as days, a skipped week moves the next order on, bad rules are
+// refused and tenant A cannot see it.
+func TestGraphQLOrderSchedule(t *testing.T) {
+	schema := newSchema(t)
+	a, b := env.a, env.b
+	ctx := userCtx(b)
+	tag := uuid.NewString()[:8]
+
+	run := func(q string) map[string]interface{} {
+		t.Helper()
+		res := execGraphQL(schema, ctx, q)
+		if len(res.Errors) > 0 {
+			t.Fatalf("%s: %v", q, res.Errors)
+		}
+		return res.Data.(map[string]interface{})
+	}
+	fails := func(name, q string) {
+		t.Helper()
+		if res := execGraphQL(schema, ctx, q); len(res.Errors) == 0 {
+			t.Errorf("%s: accepted", name)
+		}
+	}
+
+	run(fmt.Sprintf(`mutation { createInventoryItem(input: { warehouseId: %q, sku: "SCH-%s", quantity: 10, minQuantity: 1, unitPrice: 3 }) { id } }`, b.Warehouse.ID, tag))
+	start := time.Now().UTC().AddDate(0, 0, 7).Format(models.ScheduleDateLayout)
+	const fields = `{ id orderPrefix frequency every startDate endDate nextDate skipDates active lines { sku quantity unitPrice } }`
+	s := run(fmt.Sprintf(`mutation { createOrderSchedule(input: { name: "Weekly %s", orderPrefix: "SCH-%s", frequency: "weekly",
+		startDate: %q, lines: [{ sku: "SCH-%s", quantity: 4 }] }) %s }`, tag, tag, start, tag, fields))["createOrderSchedule"].(map[string]interface{})
+	if s["startDate"] != start || s["nextDate"] != start || s["endDate"] != nil || s["active"] != true {
+		t.Fatalf("created standing order %v", s)
+	}
+	if line := s["lines"].([]interface{})[0].(map[string]interface{}); line["quantity"] != 4 || line["unitPrice"] != nil {
+		t.Errorf("standing order line %v", line)
+	}
+	id := s["id"].(string)
+
+	next := time.Now().UTC().AddDate(0, 0, 14).Format(models.ScheduleDateLayout)
+	s = run(fmt.Sprintf(`mutation { updateOrderSchedule(id: %q, input: { name: "Weekly %s", orderPrefix: "SCH-%s", frequency: "weekly",
+		startDate: %q, skipDates: [%q], lines: [{ sku: "SCH-%s", quantity: 4, unitPrice: 2.5 }] }) %s }`, id, tag, tag, start, start, tag, fields))["updateOrderSchedule"].(map[string]interface{})
+	if s["nextDate"] != next || fmt.Sprint(s["skipDates"]) != "["+start+"]" {
+		t.Errorf("after skipping the first week: %v", s)
+	}
+
+	fails("unknown frequency", fmt.Sprintf(`mutation { createOrderSchedule(input: { name: "x", orderPrefix: "X-%s", frequency: "hourly", startDate: %q, lines: [{ sku: "SCH-%s", quantity: 1 }] }) { id } }`, tag, start, tag))
+	fails("end before start", fmt.Sprintf(`mutation { createOrderSchedule(input: { name: "x", orderPrefix: "X-%s", frequency: "daily", startDate: %q, endDate: "2020-01-01", lines: [{ sku: "SCH-%s", quantity: 1 }] }) { id } }`, tag, start, tag))
+	fails("duplicate prefix", fmt.Sprintf(`mutation { createOrderSchedule(input: { name: "x", orderPrefix: "SCH-%s", frequency: "daily", startDate: %q, lines: [{ sku: "SCH-%s", quantity: 1 }] }) { id } }`, tag, start, tag))
+	fails("other tenant's item", fmt.Sprintf(`mutation { createOrderSchedule(input: { name: "x", orderPrefix: "X-%s", frequency: "daily", startDate: %q, lines: [{ sku: %q, quantity: 1 }] }) { id } }`, tag, start, a.Inventory.SKU))
+
+	if res := execGraphQL(schema, userCtx(a), fmt.Sprintf(`{ orderSchedule(id: %q) { id } }`, id)); len(res.Errors) == 0 {
+		t.Errorf("tenant A read tenant B's standing order: %v", res.Data)
+	}
+	if res := execGraphQL(schema, userCtx(a), fmt.Sprintf(`mutation { deleteOrderSchedule(id: %q) }`, id)); len(res.Errors) == 0 {
+		t.Errorf("tenant A deleted tenant B's standing order")
+	}
+	run(fmt.Sprintf(`mutation { deleteOrderSchedule(id: %q) }`, id))
+}
+
+// TestGraphQLRMA returns a delivered order in two RMAs. The first is partly
+// approved, shipped back and inspected, restocking what is as new and
+// writing off what is unusable, and is credited on the order's invoice; the
+// second brings the rest back for a refund, which returns the order.
+// returnOrders follows the RMAs as they move.
+func TestGraphQLRMA(t *testing.T) {
+	schema := newSchema(t)
+	a, b := env.a, env.b
+	ctx := userCtx(b)
+	tag := uuid.NewString()[:8]
+	var clientID string
+	t.Cleanup(func() {
+		pctx := database.Privileged(context.Background())
+		env.pool.Exec(pctx, `DELETE FROM shipments WHERE id IN (SELECT return_shipment_id FROM rmas WHERE tenant_id = $1 AND reason LIKE $2)`, b.TenantID, tag+"%")
+		env.pool.Exec(pctx, `DELETE FROM orders WHERE tenant_id = $1 AND order_number = $2`, b.TenantID, "RMA-"+tag)
+		env.pool.Exec(pctx, `DELETE FROM invoices WHERE tenant_id = $1 AND client_id::text = $2 AND kind = 'credit_note'`, b.TenantID, clientID)
+		env.pool.Exec(pctx, `DELETE FROM invoices WHERE tenant_id = $1 AND client_id::text = $2`, b.TenantID, clientID)
+		env.pool.Exec(pctx, `DELETE FROM clients WHERE tenant_id = $1 AND id::text = $2`, b.TenantID, clientID)
+	})
+
+	run := func(q string) map[string]interface{} {
+		t.Helper()
+		res := execGraphQL(schema, ctx, q)
+		if len(res.Errors) > 0 {
+			t.Fatalf("%s: %v", q, res.Errors)
+		}
+		return res.Data.(map[string]interface{})
+	}
+	fails := func(name, q string) {
+		t.Helper()
+		if res := execGraphQL(schema, ctx, q); len(res.Errors) == 0 {
+			t.Errorf("%s: accepted", name)
+		}
+	}
+	field := func(m interface{}, key string) interface{} { return m.(map[string]interface{})[key] }
+	onHand := func(sku string) int {
+		t.Helper()
+		var n int
+		env.scalar(t, &n, `SELECT quantity FROM inventory_items WHERE tenant_id = $1 AND sku = $2`, b.TenantID, sku)
+		return n
+	}
+
+	clientID = field(run(`mutation { createClient(input: { companyName: "Return Traders", address: "4 Quay St" }) { id } }`)["createClient"], "id").(string)
+	skuA, skuB := "RA-"+tag, "RB-"+tag
+	for _, it := range []struct {
+		sku   string
+		price float64
+	}{{skuA, 4}, {skuB, 6}} {
+		run(fmt.Sprintf(`mutation { createInventoryItem(input: { warehouseId: %q, sku: %q, quantity: 10, minQuantity: 1, unitPrice: %v }) { id } }`,
+			b.Warehouse.ID, it.sku, it.price))
+	}
+	o := run(fmt.Sprintf(`mutation { createOrder(input: { orderNumber: "RMA-%s", clientId: %q, status: "delivered",
+		lines: [{ sku: %q, quantity: 4 }, { sku: %q, quantity: 2 }] }) { id lines { id } } }`, tag, clientID, skuA, skuB))["createOrder"].(map[string]interface{})
+	orderID := o["id"].(string)
+	lineA := field(o["lines"].([]interface{})[0], "id").(string)
+	lineB := field(o["lines"].([]interface{})[1], "id").(string)
+	today := time.Now().UTC().Format("2006-01-02")
+	run(fmt.Sprintf(`mutation { generateInvoices(periodStart: %q, periodEnd: %q, clientId: %q) { id } }`, today, today, clientID))
+
+	const rmaFields = `{ id rmaNumber status warehouseId receivedValue resolution resolutionAmount
+		lines { orderLineId quantity approvedQuantity receivedQuantity restockedQuantity writtenOffQuantity condition }
+		returnShipment { origin destination trackingNumber } creditNote { kind subtotal } }`
+	request := func(lines string) string {
+		return fmt.Sprintf(`mutation { requestReturn(orderId: %q, reason: "%s damaged", lines: [%s]) %s }`, orderID, tag, lines, rmaFields)
+	}
+	returns := func(status string) int {
+		t.Helper()
+		list := run(fmt.Sprintf(`{ returnOrders(rmaStatus: %q) { items { id } } }`, status))["returnOrders"].(map[string]interface{})
+		n := 0
+		for _, it := range list["items"].([]interface{}) {
+			if field(it, "id") == orderID {
+				n++
+			}
+		}
+		return n
+	}
+
+	fails("return more than shipped", request(fmt.Sprintf(`{ orderLineId: %q, quantity: 5 }`, lineA)))
+	rma := run(request(fmt.Sprintf(`{ orderLineId: %q, quantity: 3 }, { orderLineId: %q, quantity: 2 }`, lineA, lineB)))["requestReturn"].(map[string]interface{})
+	id := rma["id"].(string)
+	if rma["status"] != "requested" || !strings.HasPrefix(rma["rmaNumber"].(string), "RMA-") || rma["warehouseId"] != b.Warehouse.ID.String() {
+		t.Fatalf("requested RMA %v", rma)
+	}
+	fails("return what another RMA holds", request(fmt.Sprintf(`{ orderLineId: %q, quantity: 2 }`, lineA)))
+	fails("return the order around the RMA", fmt.Sprintf(`mutation { returnOrder(id: %q, reason: "x") { id } }`, orderID))
+	fails("receive before approval", fmt.Sprintf(`mutation { receiveReturn(id: %q, lines: []) { id } }`, id))
+	if returns("requested") != 1 {
+		t.Error("returnOrders does not list the order with a requested RMA")
+	}
+
+	run(fmt.Sprintf(`mutation { approveReturn(id: %q, lines: [{ orderLineId: %q, quantity: 2 }]) { id } }`, id, lineA))
+	rma = run(fmt.Sprintf(`mutation { createReturnShipment(id: %q, input: { carrier: "DHL" }) %s }`, id, rmaFields))["createReturnShipment"].(map[string]interface{})
+	s := rma["returnShipment"].(map[string]interface{})
+	if rma["status"] != "in_transit" || s["origin"] != "4 Quay St" || s["destination"] != b.Warehouse.Name || s["trackingNumber"] == "" {
+		t.Errorf("return shipment %v", rma)
+	}
+
+	fails("receive more than approved", fmt.Sprintf(`mutation { receiveReturn(id: %q, lines: [{ orderLineId: %q, quantity: 3, condition: "A" }]) { id } }`, id, lineA))
+	fails("unknown grade", fmt.Sprintf(`mutation { receiveReturn(id: %q, lines: [{ orderLineId: %q, quantity: 1, condition: "E" }]) { id } }`, id, lineA))
+	rma = run(fmt.Sprintf(`mutation { receiveReturn(id: %q, lines: [{ orderLineId: %q, quantity: 2, condition: "A" },
+		{ orderLineId: %q, quantity: 2, condition: "D" }]) %s }`, id, lineA, lineB, rmaFields))["receiveReturn"].(map[string]interface{})
+	ra, rb := rma["lines"].([]interface{})[0], rma["lines"].([]interface{})[1]
+	if rma["status"] != "received" || rma["receivedValue"] != 20.0 ||
+		field(ra, "restockedQuantity") != 2 || field(rb, "writtenOffQuantity") != 2 || field(rb, "condition") != "D" {
+		t.Errorf("received RMA %v", rma)
+	}
+	if na, nb := onHand(skuA), onHand(skuB); na != 8 || nb != 8 {
+		t.Errorf("on hand %d and %d, want 8 of A restocked and B written off at 8", na, nb)
+	}
+	if returns("received") != 1 || returns("in_transit") != 0 {
+		t.Error("returnOrders does not follow the RMA")
+	}
+
+	rma = run(fmt.Sprintf(`mutation { resolveReturn(id: %q, resolution: "credit") %s }`, id, rmaFields))["resolveReturn"].(map[string]interface{})
+	if rma["status"] != "closed" || rma["resolutionAmount"] != 20.0 || field(rma["creditNote"], "kind") != "credit_note" || field(rma["creditNote"], "subtotal") != 20.0 {
+		t.Errorf("credited RMA %v", rma)
+	}
+
+	// The second RMA brings back the other two of line A.
+	fails("return what came back", request(fmt.Sprintf(`{ orderLineId: %q, quantity: 3 }`, lineA)))
+	second := field(run(request(fmt.Sprintf(`{ orderLineId: %q, quantity: 2 }`, lineA)))["requestReturn"], "id").(string)
+	run(fmt.Sprintf(`mutation { approveReturn(id: %q) { id } }`, second))
+	run(fmt.Sprintf(`mutation { receiveReturn(id: %q, lines: [{ orderLineId: %q, quantity: 2, condition: "C", restock: true }]) { id } }`, second, lineA))
+	refunded := run(fmt.Sprintf(`mutation { resolveReturn(id: %q, resolution: "refund", reference: "BT-1") { status resolution resolutionAmount } }`,
+		second))["resolveReturn"].(map[string]interface{})
+	if refunded["status"] != "closed" || refunded["resolution"] != "refund" || refunded["resolutionAmount"] != 8.0 {
+		t.Errorf("refunded RMA %v", refunded)
+	}
+	if st := field(run(fmt.Sprintf(`{ order(id: %q) { status rmas { id } } }`, orderID))["order"], "status"); st != "returned" {
+		t.Errorf("fully returned order is %v", st)
+	}
+	if onHand(skuA) != 10 || returns("") != 1 {
+		t.Error("the second return was not restocked or listed")
+	}
+
+	if res := execGraphQL(schema, userCtx(a), fmt.Sprintf(`{ rma(id: %q) { id } }`, id)); len(res.Errors) == 0 {
+		t.Error("another tenant read the RMA")
+	}
+	if res := execGraphQL(schema, userCtx(a), fmt.Sprintf(`mutation { cancelReturn(id: %q) { id } }`, second)); len(res.Errors) == 0 {
+		t.Error("another tenant cancelled the RMA")
+	}
+}
+
+// TestGraphQLStockLedger follows an item's stock through its ledger: an
+// opening balance, a receipt, a counted adjustment, a write-off and an order
+// pick each leave a movement with its reason and document. Stock changed
+// behind the ledger's back shows up as a discrepancy until reconciled.
+func TestGraphQLStockLedger(t *testing.T) {
+	schema := newSchema(t)
+	a, b := env.a, env.b
+	ctx := userCtx(b)
+	tag := uuid.NewString()[:8]
+	sku := "LED-" + tag
+	t.Cleanup(func() {
+		pctx := database.Privileged(context.Background())
+		env.pool.Exec(pctx, `DELETE FROM orders WHERE tenant_id = $1 AND order_number = $2`, b.TenantID, "LED-"+tag)
+		env.pool.Exec(pctx, `DELETE FROM inventory_items WHERE tenant_id = $1 AND sku = $2`, b.TenantID, sku)
+	})
+
+	run := func(q string) map[string]interface{} {
+		t.Helper()
+		res := execGraphQL(schema, ctx, q)
+		if len(res.Errors) > 0 {
+			t.Fatalf("%s: %v", q, res.Errors)
+		}
+		return res.Data.(map[string]interface{})
+	}
+	fails := func(name, q string) {
+		t.Helper()
+		if res := execGraphQL(schema, ctx, q); len(res.Errors) == 0 {
+			t.Errorf("%s: accepted", name)
+		}
+	}
+	field := func(m interface{}, key string) interface{} { return m.(map[string]interface{})[key] }
+
+	itemID := field(run(fmt.Sprintf(`mutation { createInventoryItem(input: { warehouseId: %q, sku: %q, quantity: 10, minQuantity: 3 }) { id } }`,
+		b.Warehouse.ID, sku))["createInventoryItem"], "id").(string)
+	run(fmt.Sprintf(`mutation { restockItem(id: %q, quantity: 5, reason: "Weekly delivery", reference: "GRN-%s") { id } }`, itemID, tag))
+	run(fmt.Sprintf(`mutation { updateInventoryItem(id: %q, input: { warehouseId: %q, sku: %q, quantity: 12, reason: "Cycle count" }) { id } }`,
+		itemID, b.Warehouse.ID, sku))
+	fails("write stock on", fmt.Sprintf(`mutation { adjustStock(id: %q, quantity: 2, type: "write_off", reason: "x") { id } }`, itemID))
+	fails("receive stock by hand", fmt.Sprintf(`mutation { adjustStock(id: %q, quantity: 2, type: "receipt", reason: "x") { id } }`, itemID))
+	fails("adjust without a reason", fmt.Sprintf(`mutation { adjustStock(id: %q, quantity: 2, reason: "") { id } }`, itemID))
+	fails("write off more than on hand", fmt.Sprintf(`mutation { adjustStock(id: %q, quantity: -50, type: "write_off", reason: "x") { id } }`, itemID))
+	written := run(fmt.Sprintf(`mutation { adjustStock(id: %q, quantity: -2, type: "write_off", reason: "Water damage") { quantity } }`, itemID))["adjustStock"]
+	if field(written, "quantity") != 10 {
+		t.Errorf("written off %v", written)
+	}
+	run(fmt.Sprintf(`mutation { createOrder(input: { orderNumber: "LED-%s", customerName: "Ledger Co", status: "shipped",
+		lines: [{ sku: %q, quantity: 4 }] }) { id } }`, tag, sku))
+
+	const movementFields = `totalCount items { type quantity balance reason referenceType reference warehouseId createdBy }`
+	moves := run(fmt.Sprintf(`{ stockMovements(inventoryItemId: %q) { %s } }`, itemID, movementFields))["stockMovements"].(map[string]interface{})
+	want := []struct {
+		typ             string
+		quantity, total int
+		reason, ref     string
+	}{
+		{"pick", -4, 6, "", "LED-" + tag},
+		{"write_off", -2, 10, "Water damage", ""},
+		{"adjustment", -3, 12, "Cycle count", ""},
+		{"receipt", 5, 15, "Weekly delivery", "GRN-" + tag},
+		{"adjustment", 10, 10, "Opening balance", ""},
+	}
+	items := moves["items"].([]interface{})
+	if moves["totalCount"] != len(want) || len(items) != len(want) {
+		t.Fatalf("movements %v", moves)
+	}
+	for i, w := range want {
+		m := items[i].(map[string]interface{})
+		reason, _ := m["reason"].(string)
+		ref, _ := m["reference"].(string)
+		if m["type"] != w.typ || m["quantity"] != w.quantity || m["balance"] != w.total || reason != w.reason || ref != w.ref ||
+			m["warehouseId"] != b.Warehouse.ID.String() || m["createdBy"] != b.Admin.ID.String() {
+			t.Errorf("movement %d = %v, want %+v", i, m, w)
+		}
+	}
+	if field(items[0], "referenceType") != "order" {
+		t.Errorf("pick not referred to its order: %v", items[0])
+	}
+
+	today := time.Now().UTC()
+	between := func(from, to time.Time, typ string) int {
+		t.Helper()
+		return run(fmt.Sprintf(`{ stockMovements(inventoryItemId: %q, from: %q, to: %q, type: %q) { totalCount } }`,
+			itemID, from.Format("2006-01-02"), to.Format("2006-01-02"), typ))["stockMovements"].(map[string]interface{})["totalCount"].(int)
+	}
+	if n := between(today.AddDate(0, 0, -1), today, "pick"); n != 1 {
+		t.Errorf("%d picks up to today, want 1", n)
+	}
+	if n := between(today.AddDate(0, 0, 1), today.AddDate(0, 0, 2), "adjustment"); n != 0 {
+		t.Errorf("%d adjustments tomorrow", n)
+	}
+	fails("unknown movement type", `{ stockMovements(type: "theft") { totalCount } }`)
+	fails("bad date", `{ stockMovements(from: "yesterday") { totalCount } }`)
+
+	discrepancies := func() []interface{} {
+		t.Helper()
+		var out []interface{}
+		for _, d := range run(fmt.Sprintf(`{ stockDiscrepancies(warehouseId: %q) { sku quantity ledgerQuantity } }`, b.Warehouse.ID))["stockDiscrepancies"].([]interface{}) {
+			if field(d, "sku") == sku {
+				out = append(out, d)
+			}
+		}
+		return out
+	}
+	if d := discrepancies(); len(d) != 0 {
+		t.Errorf("ledger disagrees with stock on hand: %v", d)
+	}
+	pctx := database.Privileged(context.Background())
+	if _, err := env.pool.Exec(pctx, `UPDATE inventory_items SET quantity = 20 WHERE id = $1`, itemID); err != nil {
+		t.Fatal(err)
+	}
+	if d := discrepancies(); len(d) != 1 || field(d[0], "quantity") != 20 || field(d[0], "ledgerQuantity") != 6 {
+		t.Errorf("discrepancies %v", d)
+	}
+	if _, err := env.pool.Exec(pctx, `UPDATE stock_movements SET quantity = 20 WHERE inventory_item_id = $1`, itemID); err == nil {
+		t.Error("stock movement rewritten")
+	}
+	if _, err := env.pool.Exec(pctx, `DELETE FROM stock_movements WHERE inventory_item_id = $1`, itemID); err == nil {
+		t.Error("stock movement deleted")
+	}
+	fixed := run(fmt.Sprintf(`mutation { reconcileStock(warehouseId: %q, reason: "Stocktake") { sku } }`, b.Warehouse.ID))["reconcileStock"].([]interface{})
+	if len(fixed) == 0 || len(discrepancies()) != 0 {
+		t.Errorf("reconciled %v", fixed)
+	}
+	latest := field(run(fmt.Sprintf(`{ stockMovements(inventoryItemId: %q, perPage: 1) { %s } }`, itemID, movementFields))["stockMovements"], "items").([]interface{})
+	if m := latest[0].(map[string]interface{}); m["type"] != "adjustment" || m["quantity"] != 14 || m["balance"] != 20 || m["reason"] != "Stocktake" {
+		t.Errorf("reconciling movement %v", m)
+	}
+
+	// Tenant A sees none of it and cannot move it.
+	actx := userCtx(a)
+	if res := execGraphQL(schema, actx, fmt.Sprintf(`{ stockMovements(inventoryItemId: %q) { totalCount } }`, itemID)); len(res.Errors) > 0 ||
+		field(res.Data.(map[string]interface{})["stockMovements"], "totalCount") != 0 {
+		t.Errorf("tenant A listed movements: %v", res)
+	}
+	if res := execGraphQL(schema, actx, fmt.Sprintf(`mutation { adjustStock(id: %q, quantity: -1, reason: "x") { id } }`, itemID)); len(res.Errors) == 0 {
+		t.Error("tenant A adjusted the stock")
+	}
+	if n := env.count(t, `SELECT COUNT(*) FROM inventory_items WHERE id = $1 AND quantity = 20`, itemID); n != 1 {
+		t.Error("stock changed by tenant A")
+	}
+}
+
+// TestGraphQLTransfers stocks one product in two warehouses, orders it from
+// the second, and moves stock between them on transfer orders: held at the
+// source, dispatched on a shipment, in transit, then received short.
+func TestGraphQLTransfers(t *testing.T) {
+	schema := newSchema(t)
+	a, b := env.a, env.b
+	ctx := userCtx(b)
+	tag := uuid.NewString()[:8]
+	sku, other := "TRF-"+tag, "TRG-"+tag
+	var depot string
+	t.Cleanup(func() {
+		pctx := database.Privileged(context.Background())
+		env.pool.Exec(pctx, `DELETE FROM orders WHERE tenant_id = $1 AND order_number = $2`, b.TenantID, "TRF-"+tag)
+		env.pool.Exec(pctx, `DELETE FROM shipments WHERE id IN (SELECT shipment_id FROM transfer_orders WHERE tenant_id = $1 AND notes = $2)`, b.TenantID, tag)
+		env.pool.Exec(pctx, `DELETE FROM transfer_orders WHERE tenant_id = $1 AND notes = $2`, b.TenantID, tag)
+		env.pool.Exec(pctx, `DELETE FROM products WHERE tenant_id = $1 AND sku = ANY($2)`, b.TenantID, []string{sku, other})
+		env.pool.Exec(pctx, `DELETE FROM warehouses WHERE tenant_id = $1 AND id::text = $2`, b.TenantID, depot)
+	})
+
+	run := func(q string) map[string]interface{} {
+		t.Helper()
+		res := execGraphQL(schema, ctx, q)
+		if len(res.Errors) > 0 {
+			t.Fatalf("%s: %v", q, res.Errors)
+		}
+		return res.Data.(map[string]interface{})
+	}
+	fails := func(name, q string) {
+		t.Helper()
+		if res := execGraphQL(schema, ctx, q); len(res.Errors) == 0 {
+			t.Errorf("%s: accepted", name)
+		}
+	}
+	field := func(m interface{}, key string) interface{} { return m.(map[string]interface{})[key] }
+
+	home := b.Warehouse.ID.String()
+	depot = field(run(fmt.Sprintf(`mutation { createWarehouse(input: { name: "Depot %s", address: "1 Quay Road" }) { id } }`, tag))["createWarehouse"], "id").(string)
+	run(fmt.Sprintf(`mutation { createInventoryItem(input: { warehouseId: %q, sku: %q, name: "Crate", unitPrice: 3, quantity: 20, minQuantity: 5 }) { id } }`, home, sku))
+	run(fmt.Sprintf(`mutation { createInventoryItem(input: { warehouseId: %q, sku: %q, quantity: 2, minQuantity: 5 }) { id } }`, depot, sku))
+	run(fmt.Sprintf(`mutation { createInventoryItem(input: { warehouseId: %q, sku: %q, name: "Lid", quantity: 6 }) { id } }`, home, other))
+	fails("stock a SKU twice in a warehouse", fmt.Sprintf(`mutation { createInventoryItem(input: { warehouseId: %q, sku: %q, quantity: 1 }) { id } }`, depot, sku))
+	fails("add a SKU to the catalog twice", fmt.Sprintf(`mutation { createProduct(input: { sku: %q }) { id } }`, sku))
+
+	stock := func() (map[string]interface{}, map[string]map[string]interface{}) {
+		t.Helper()
+		p := run(fmt.Sprintf(`{ product(sku: %q) { name unitPrice quantity reserved inTransit available stock { warehouseId quantity reserved inTransit available } } }`,
+			sku))["product"].(map[string]interface{})
+		levels := map[string]map[string]interface{}{}
+		for _, l := range p["stock"].([]interface{}) {
+			levels[field(l, "warehouseId").(string)] = l.(map[string]interface{})
+		}
+		return p, levels
+	}
+	p, levels := stock()
+	if p["name"] != "Crate" || p["unitPrice"] != 3.0 || p["quantity"] != 22 || len(levels) != 2 || levels[depot]["quantity"] != 2 {
+		t.Fatalf("product %v", p)
+	}
+
+	lowIn := func(warehouse string) bool {
+		t.Helper()
+		for _, i := range run(fmt.Sprintf(`{ lowStockItems(warehouseId: %q) { sku } }`, warehouse))["lowStockItems"].([]interface{}) {
+			if field(i, "sku") == sku {
+				return true
+			}
+		}
+		return false
+	}
+	if lowIn(home) || !lowIn(depot) {
+		t.Error("low stock not evaluated per warehouse")
+	}
+
+	run(fmt.Sprintf(`mutation { createOrder(input: { orderNumber: "TRF-%s", customerName: "Quay Stores",
+		lines: [{ sku: %q, quantity: 1, warehouseId: %q }] }) { id } }`, tag, sku, depot))
+	if _, levels = stock(); levels[depot]["reserved"] != 1 || levels[home]["reserved"] != 0 {
+		t.Errorf("order reserved from the wrong warehouse: %v", levels)
+	}
+
+	const transferFields = `{ id transferNumber status shipmentId lines { sku quantity receivedQuantity }
+		shipment { trackingNumber origin destination customerName warehouseId } }`
+	create := func(s string, n int) map[string]interface{} {
+		t.Helper()
+		return run(fmt.Sprintf(`mutation { createTransferOrder(input: { fromWarehouseId: %q, toWarehouseId: %q, notes: %q,
+			lines: [{ sku: %q, quantity: %d }] }) %s }`, home, depot, tag, s, n, transferFields))["createTransferOrder"].(map[string]interface{})
+	}
+	fails("transfer to the same warehouse", fmt.Sprintf(`mutation { createTransferOrder(input: { fromWarehouseId: %q, toWarehouseId: %q,
+		lines: [{ sku: %q, quantity: 1 }] }) { id } }`, home, home, sku))
+	fails("transfer more than available", fmt.Sprintf(`mutation { createTransferOrder(input: { fromWarehouseId: %q, toWarehouseId: %q,
+		lines: [{ sku: %q, quantity: 21 }] }) { id } }`, home, depot, sku))
+	fails("transfer what the source does not stock", fmt.Sprintf(`mutation { createTransferOrder(input: { fromWarehouseId: %q, toWarehouseId: %q,
+		lines: [{ sku: %q, quantity: 1 }] }) { id } }`, depot, home, other))
+
+	tr := create(sku, 8)
+	id := tr["id"].(string)
+	if tr["status"] != "pending" || !strings.HasPrefix(tr["transferNumber"].(string), "TRF-") || tr["shipmentId"] != nil {
+		t.Fatalf("created transfer %v", tr)
+	}
+	if _, levels = stock(); levels[home]["reserved"] != 8 || levels[home]["available"] != 12 {
+		t.Errorf("transfer did not hold the stock at its source: %v", levels[home])
+	}
+
+	tr = run(fmt.Sprintf(`mutation { dispatchTransferOrder(id: %q, shipment: { carrier: "Own fleet" }) %s }`, id, transferFields))["dispatchTransferOrder"].(map[string]interface{})
+	s, _ := tr["shipment"].(map[string]interface{})
+	if tr["status"] != "in_transit" || s == nil || s["trackingNumber"] == "" || s["destination"] != "1 Quay Road" ||
+		s["customerName"] != "Depot "+tag || s["warehouseId"] != home {
+		t.Fatalf("dispatched transfer %v", tr)
+	}
+	p, levels = stock()
+	if levels[home]["quantity"] != 12 || levels[home]["reserved"] != 0 || levels[depot]["quantity"] != 2 || levels[depot]["inTransit"] != 8 || p["inTransit"] != 8 {
+		t.Errorf("stock after dispatch: %v", p)
+	}
+	fails("cancel a transfer in transit", fmt.Sprintf(`mutation { cancelTransferOrder(id: %q) { id } }`, id))
+	fails("receive more than sent", fmt.Sprintf(`mutation { receiveTransferOrder(id: %q, lines: [{ sku: %q, quantity: 9 }]) { id } }`, id, sku))
+
+	tr = run(fmt.Sprintf(`mutation { receiveTransferOrder(id: %q, lines: [{ sku: %q, quantity: 7 }]) %s }`, id, sku, transferFields))["receiveTransferOrder"].(map[string]interface{})
+	if tr["status"] != "received" || field(tr["lines"].([]interface{})[0], "receivedQuantity") != 7 {
+		t.Errorf("received transfer %v", tr)
+	}
+	p, levels = stock()
+	if levels[depot]["quantity"] != 9 || levels[depot]["inTransit"] != 0 || p["quantity"] != 21 || p["inTransit"] != 0 {
+		t.Errorf("stock after receipt: %v", p)
+	}
+	if lowIn(depot) {
+		t.Error("depot still low on stock")
+	}
+	var depotItem string
+	for _, i := range run(fmt.Sprintf(`{ inventoryItems(warehouseId: %q) { items { id sku } } }`, depot))["inventoryItems"].(map[string]interface{})["items"].([]interface{}) {
+		if field(i, "sku") == sku {
+			depotItem = field(i, "id").(string)
+		}
+	}
+	moves := field(run(fmt.Sprintf(`{ stockMovements(inventoryItemId: %q, type: "transfer") { items { quantity referenceType reference } } }`,
+		depotItem))["stockMovements"], "items").([]interface{})
+	if len(moves) != 1 || field(moves[0], "quantity") != 7 || field(moves[0], "referenceType") != "transfer" || field(moves[0], "reference") != tr["transferNumber"] {
+		t.Errorf("transfer movements %v", moves)
+	}
+
+	// A cancelled transfer gives its stock back; one to a warehouse without
+	// the product opens a stock level there.
+	cancelled := create(other, 2)
+	run(fmt.Sprintf(`mutation { cancelTransferOrder(id: %q) { id } }`, cancelled["id"]))
+	opened := create(other, 4)
+	run(fmt.Sprintf(`mutation { dispatchTransferOrder(id: %q) { id } }`, opened["id"]))
+	levelOf := func(s, warehouse string) map[string]interface{} {
+		t.Helper()
+		for _, l := range run(fmt.Sprintf(`{ product(sku: %q) { stock { warehouseId quantity reserved inTransit } } }`, s))["product"].(map[string]interface{})["stock"].([]interface{}) {
+			if field(l, "warehouseId") == warehouse {
+				return l.(map[string]interface{})
+			}
+		}
+		return nil
+	}
+	if l := levelOf(other, depot); l == nil || l["quantity"] != 0 || l["inTransit"] != 4 {
+		t.Errorf("stock level opened at the destination: %v", l)
+	}
+	if l := levelOf(other, home); l["quantity"] != 2 || l["reserved"] != 0 {
+		t.Errorf("source after a cancelled and a dispatched transfer: %v", l)
+	}
+	run(fmt.Sprintf(`mutation { receiveTransferOrder(id: %q) { id } }`, opened["id"]))
+	if l := levelOf(other, depot); l["quantity"] != 4 || l["inTransit"] != 0 {
+		t.Errorf("received in full: %v", l)
+	}
+	list := run(fmt.Sprintf(`{ transferOrders(warehouseId: %q, status: "received") { totalCount } }`, depot))["transferOrders"]
+	if field(list, "totalCount") != 2 {
+		t.Errorf("received transfers to the depot: %v", list)
+	}
+
+	// Tenant A sees none of it and cannot move it.
+	actx := userCtx(a)
+	if res := execGraphQL(schema, actx, fmt.Sprintf(`{ transferOrder(id: %q) { id } }`, id)); len(res.Errors) == 0 {
+		t.Error("tenant A read the transfer")
+	}
+	if res := execGraphQL(schema, actx, fmt.Sprintf(`{ product(sku: %q) { id } }`, sku)); len(res.Errors) == 0 {
+		t.Error("tenant A read the product")
+	}
+	if res := execGraphQL(schema, actx, fmt.Sprintf(`mutation { cancelTransferOrder(id: %q) { id } }`, cancelled["id"])); len(res.Errors) == 0 {
+		t.Error("tenant A cancelled the transfer")
+	}
+}
+
+// TestGraphQLPurchaseOrders lists a product with two vendors, buys it on a
+// purchase order received in two deliveries, and has the reorder job draft
+// an order from what was picked.
+func TestGraphQLPurchaseOrders(t *testing.T) {
+	schema := newSchema(t)
+	a, b := env.a, env.b
+	ctx := userCtx(b)
+	tag := uuid.NewString()[:8]
+	sku, other := "PUR-"+tag, "PUS-"+tag
+	var vendors []string
+	t.Cleanup(func() {
+		pctx := database.Privileged(context.Background())
+		env.pool.Exec(pctx, `DELETE FROM purchase_orders WHERE tenant_id = $1 AND vendor_id::text = ANY($2)`, b.TenantID, vendors)
+		env.pool.Exec(pctx, `DELETE FROM products WHERE tenant_id = $1 AND sku = ANY($2)`, b.TenantID, []string{sku, other})
+		env.pool.Exec(pctx, `DELETE FROM vendors WHERE tenant_id = $1 AND id::text = ANY($2)`, b.TenantID, vendors)
+	})
+
+	run := func(q string) map[string]interface{} {
+		t.Helper()
+		res := execGraphQL(schema, ctx, q)
+		if len(res.Errors) > 0 {
+			t.Fatalf("%s: %v", q, res.Errors)
+		}
+		return res.Data.(map[string]interface{})
+	}
+	fails := func(name, q string) {
+		t.Helper()
+		if res := execGraphQL(schema, ctx, q); len(res.Errors) == 0 {
+			t.Errorf("%s: accepted", name)
+		}
+	}
+	field := func(m interface{}, key string) interface{} { return m.(map[string]interface{})[key] }
+
+	home := b.Warehouse.ID.String()
+	for _, name := range []string{"Crate Works", "Budget Crates"} {
+		v := run(fmt.Sprintf(`mutation { createVendor(input: { name: "%s %s", status: "active" }) { id } }`, name, tag))["createVendor"]
+		vendors = append(vendors, field(v, "id").(string))
+	}
+	works, budget := vendors[0], vendors[1]
+	item := field(run(fmt.Sprintf(`mutation { createInventoryItem(input: { warehouseId: %q, sku: %q, name: "Crate", quantity: 4, minQuantity: 10 }) { id } }`,
+		home, sku))["createInventoryItem"], "id").(string)
+	run(fmt.Sprintf(`mutation { createProduct(input: { sku: %q, name: "Lid" }) { id } }`, other))
+
+	run(fmt.Sprintf(`mutation { createSupplierProduct(input: { vendorId: %q, sku: %q, vendorSku: "CW-1", unitCost: 2.5, leadTimeDays: 3,
+		minOrderQuantity: 12, preferred: true }) { id } }`, works, sku))
+	run(fmt.Sprintf(`mutation { createSupplierProduct(input: { vendorId: %q, sku: %q, unitCost: 2, leadTimeDays: 1 }) { id } }`, budget, sku))
+	fails("list a product with a vendor twice", fmt.Sprintf(`mutation { createSupplierProduct(input: { vendorId: %q, sku: %q, unitCost: 1 }) { id } }`, works, sku))
+	fails("list a SKU not in the catalog", fmt.Sprintf(`mutation { createSupplierProduct(input: { vendorId: %q, sku: "NONE-%s", unitCost: 1 }) { id } }`, works, tag))
+	fails("list at a negative cost", fmt.Sprintf(`mutation { createSupplierProduct(input: { vendorId: %q, sku: %q, unitCost: -1 }) { id } }`, works, other))
+	suppliers := run(fmt.Sprintf(`{ supplierProducts(sku: %q) { vendorId vendorSku preferred vendor { id } } }`, sku))["supplierProducts"].([]interface{})
+	if len(suppliers) != 2 || field(suppliers[0], "vendorId") != works || field(suppliers[0], "vendorSku") != "CW-1" || field(field(suppliers[1], "vendor"), "id") != budget {
+		t.Fatalf("suppliers %v", suppliers)
+	}
+
+	const poFields = `{ id poNumber status source vendorId expectedDate totalAmount lines { sku vendorSku quantity receivedQuantity unitCost } }`
+	order := func(lines string) string {
+		return fmt.Sprintf(`{ vendorId: %q, warehouseId: %q, notes: %q, lines: [%s] }`, works, home, tag, lines)
+	}
+	fails("order fewer than the minimum", fmt.Sprintf(`mutation { createPurchaseOrder(input: %s) { id } }`, order(fmt.Sprintf(`{ sku: %q, quantity: 5 }`, sku))))
+	fails("order off the vendor's catalog unpriced", fmt.Sprintf(`mutation { createPurchaseOrder(input: %s) { id } }`, order(fmt.Sprintf(`{ sku: %q, quantity: 5 }`, other))))
+
+	po := run(fmt.Sprintf(`mutation { createPurchaseOrder(input: %s) %s }`, order(fmt.Sprintf(`{ sku: %q, quantity: 12 }`, sku)), poFields))["createPurchaseOrder"].(map[string]interface{})
+	id := po["id"].(string)
+	if po["status"] != "draft" || po["source"] != "manual" || !strings.HasPrefix(po["poNumber"].(string), "PO-") || po["totalAmount"] != 30.0 ||
+		field(po["lines"].([]interface{})[0], "vendorSku") != "CW-1" || po["expectedDate"] != nil {
+		t.Fatalf("created purchase order %v", po)
+	}
+	po = run(fmt.Sprintf(`mutation { updatePurchaseOrder(id: %q, input: %s) %s }`, id,
+		order(fmt.Sprintf(`{ sku: %q, quantity: 20 }, { sku: %q, quantity: 3, unitCost: 1.25 }`, sku, other)), poFields))["updatePurchaseOrder"].(map[string]interface{})
+	if len(po["lines"].([]interface{})) != 2 || po["totalAmount"] != 53.75 {
+		t.Fatalf("updated purchase order %v", po)
+	}
+
+	po = run(fmt.Sprintf(`mutation { sendPurchaseOrder(id: %q) %s }`, id, poFields))["sendPurchaseOrder"].(map[string]interface{})
+	if po["status"] != "sent" || po["expectedDate"] == nil {
+		t.Fatalf("sent purchase order %v", po)
+	}
+	fails("change a sent order", fmt.Sprintf(`mutation { updatePurchaseOrder(id: %q, input: %s) { id } }`, id, order(fmt.Sprintf(`{ sku: %q, quantity: 12 }`, sku))))
+	fails("receive more than outstanding", fmt.Sprintf(`mutation { receivePurchaseOrder(id: %q, lines: [{ sku: %q, quantity: 21 }]) { id } }`, id, sku))
+	fails("receive nothing", fmt.Sprintf(`mutation { receivePurchaseOrder(id: %q, lines: []) { id } }`, id))
+
+	level := func(s string) map[string]interface{} {
+		t.Helper()
+		for _, l := range run(fmt.Sprintf(`{ product(sku: %q) { stock { id warehouseId quantity } } }`, s))["product"].(map[string]interface{})["stock"].([]interface{}) {
+			if field(l, "warehouseId") == home {
+				return l.(map[string]interface{})
+			}
+		}
+		return nil
+	}
+	po = run(fmt.Sprintf(`mutation { receivePurchaseOrder(id: %q, lines: [{ sku: %q, quantity: 8 }]) %s }`, id, sku, poFields))["receivePurchaseOrder"].(map[string]interface{})
+	if po["status"] != "partially_received" || level(sku)["quantity"] != 12 {
+		t.Fatalf("partially received purchase order %v", po)
+	}
+	po = run(fmt.Sprintf(`mutation { receivePurchaseOrder(id: %q) %s }`, id, poFields))["receivePurchaseOrder"].(map[string]interface{})
+	if po["status"] != "received" || level(sku)["quantity"] != 24 {
+		t.Fatalf("received purchase order %v", po)
+	}
+	// Receiving opens a stock level for a product the warehouse did not hold.
+	if l := level(other); l == nil || l["quantity"] != 3 {
+		t.Errorf("stock level opened by receipt: %v", l)
+	}
+	moves := field(run(fmt.Sprintf(`{ stockMovements(inventoryItemId: %q, type: "receipt") { items { quantity referenceType reference } } }`,
+		item))["stockMovements"], "items").([]interface{})
+	if len(moves) != 2 || field(moves[0], "referenceType") != "purchase_order" || field(moves[0], "reference") != po["poNumber"] {
+		t.Errorf("receipt movements %v", moves)
+	}
+	fails("cancel a received order", fmt.Sprintf(`mutation { cancelPurchaseOrder(id: %q) { id } }`, id))
+	cancelled := run(fmt.Sprintf(`mutation { createPurchaseOrder(input: %s) { id } }`, order(fmt.Sprintf(`{ sku: %q, quantity: 12 }`, sku))))["createPurchaseOrder"]
+	if s := field(run(fmt.Sprintf(`mutation { cancelPurchaseOrder(id: %q) { status } }`, field(cancelled, "id")))["cancelPurchaseOrder"], "status"); s != "cancelled" {
+		t.Errorf("cancelled purchase order status %v", s)
+	}
+
+	// 30 picked over the last 30 days is a crate a day: with 3 days' lead
+	// time the level is reordered at 13, and ordered up to 13 plus 14 days.
+	run(fmt.Sprintf(`mutation { adjustStock(id: %q, quantity: -14, reason: "count") { id } }`, item))
+	if _, err := env.pool.Exec(database.Privileged(context.Background()),
+		`INSERT INTO stock_movements (tenant_id, inventory_item_id, warehouse_id, sku, movement_type, quantity, balance)
+		 VALUES ($1, $2, $3, $4, 'pick', -30, 10)`, b.TenantID, item, home, sku); err != nil {
+		t.Fatal(err)
+	}
+	reorder := func() []interface{} {
+		t.Helper()
+		var out []interface{}
+		for _, d := range run(fmt.Sprintf(`mutation { draftReorders(warehouseId: %q) %s }`, home, poFields))["draftReorders"].([]interface{}) {
+			if field(d, "vendorId") == works || field(d, "vendorId") == budget {
+				out = append(out, d)
+			}
+		}
+		return out
+	}
+	drafts := reorder()
+	if len(drafts) != 1 || field(drafts[0], "vendorId") != works || field(drafts[0], "source") != "reorder" || field(drafts[0], "status") != "draft" {
+		t.Fatalf("reorder drafts %v", drafts)
+	}
+	lines := field(drafts[0], "lines").([]interface{})
+	if len(lines) != 1 || field(lines[0], "sku") != sku || field(lines[0], "quantity") != 17 || field(lines[0], "unitCost") != 2.5 {
+		t.Errorf("reorder lines %v", lines)
+	}
+	if again := reorder(); len(again) != 0 {
+		t.Errorf("reorder drafted again: %v", again)
+	}
+	list := run(fmt.Sprintf(`{ purchaseOrders(vendorId: %q, source: "reorder") { totalCount } }`, works))["purchaseOrders"]
+	if field(list, "totalCount") != 1 {
+		t.Errorf("reorder drafts listed: %v", list)
+	}
+	fails("delete a vendor with purchase orders", fmt.Sprintf(`mutation { deleteVendor(id: %q) }`, works))
+
+	// Tenant A sees none of it and cannot act on it.
+	actx := userCtx(a)
+	if res := execGraphQL(schema, actx, fmt.Sprintf(`{ purchaseOrder(id: %q) { id } }`, id)); len(res.Errors) == 0 {
+		t.Error("tenant A read the purchase order")
+	}
+	if res := execGraphQL(schema, actx, fmt.Sprintf(`mutation { sendPurchaseOrder(id: %q) { id } }`, field(drafts[0], "id"))); len(res.Errors) == 0 {
+		t.Error("tenant A sent the purchase order")
+	}
+	if res := execGraphQL(schema, actx, fmt.Sprintf(`mutation { createSupplierProduct(input: { vendorId: %q, sku: %q, unitCost: 1 }) { id } }`, budget, other)); len(res.Errors) == 0 {
+		t.Error("tenant A listed with tenant B's vendor")
+	}
+}
+
+func TestGraphQLWarehouseCapacity(t *testing.T) {
+	schema := newSchema(t)
+	a, b := env.a, env.b
+	ctx := userCtx(b)
+	tag := uuid.NewString()[:8]
+	sku, light, name := "CAP-"+tag, "CAL-"+tag, "Capacity "+tag
+	var depot string
+	t.Cleanup(func() {
+		pctx := database.Privileged(context.Background())
+		env.pool.Exec(pctx, `DELETE FROM notifications WHERE tenant_id = $1 AND type = 'warehouse' AND message LIKE $2`, b.TenantID, name+"%")
+		env.pool.Exec(pctx, `DELETE FROM products WHERE tenant_id = $1 AND sku = ANY($2)`, b.TenantID, []string{sku, light})
+		env.pool.Exec(pctx, `DELETE FROM warehouses WHERE tenant_id = $1 AND id::text = $2`, b.TenantID, depot)
+	})
+
+	run := func(q string) map[string]interface{} {
+		t.Helper()
+		res := execGraphQL(schema, ctx, q)
+		if len(res.Errors) > 0 {
+			t.Fatalf("%s: %v", q, res.Errors)
+		}
+		return res.Data.(map[string]interface{})
+	}
+	fails := func(name, q string) {
+		t.Helper()
+		if res := execGraphQL(schema, ctx, q); len(res.Errors) == 0 {
+			t.Errorf("%s: accepted", name)
+		}
+	}
+	used := func() (float64, float64) {
+		t.Helper()
+		w := run(fmt.Sprintf(`{ warehouse(id: %q) { usedCapacity utilization } }`, depot))["warehouse"].(map[string]interface{})
+		return w["usedCapacity"].(float64), w["utilization"].(float64)
+	}
+	alerts := func() int {
+		t.Helper()
+		var n int
+		if err := env.pool.QueryRow(database.Privileged(context.Background()),
+			`SELECT COUNT(*) FROM notifications WHERE user_id = $1 AND type = 'warehouse' AND message LIKE $2`,
+			b.Admin.ID, name+"%").Scan(&n); err != nil {
+			t.Fatal(err)
+		}
+		return n
+	}
+
+	fails("an unknown capacity unit", fmt.Sprintf(`mutation { createWarehouse(input: { name: %q, capacity: 10, capacityUnit: "crates" }) { id } }`, name))
+	fails("a threshold of nothing", fmt.Sprintf(`mutation { createWarehouse(input: { name: %q, capacity: 10, fillThresholds: [0] }) { id } }`, name))
+	fails("a threshold over full", fmt.Sprintf(`mutation { createWarehouse(input: { name: %q, capacity: 10, fillThresholds: [101] }) { id } }`, name))
+
+	w := run(fmt.Sprintf(`mutation { createWarehouse(input: { name: %q, capacity: 10, fillThresholds: [90, 50, 90], status: "active" })
+		{ id capacityUnit fillThresholds usedCapacity } }`, name))["createWarehouse"].(map[string]interface{})
+	depot = w["id"].(string)
+	if w["capacityUnit"] != "pallets" || fmt.Sprint(w["fillThresholds"]) != "[50 90]" || w["usedCapacity"] != 0.0 {
+		t.Fatalf("created warehouse %v", w)
+	}
+	fails("a zero pallet size", fmt.Sprintf(`mutation { createProduct(input: { sku: %q, unitsPerPallet: 0 }) { id } }`, sku))
+	product := run(fmt.Sprintf(`mutation { createProduct(input: { sku: %q, name: "Drum", weight: 2.5, unitsPerPallet: 10 }) { id } }`,
+		sku))["createProduct"].(map[string]interface{})["id"].(string)
+
+	// 30 drums at 10 a pallet is 3 of the 10 pallets.
+	item := run(fmt.Sprintf(`mutation { createInventoryItem(input: { warehouseId: %q, sku: %q, quantity: 30 }) { id } }`,
+		depot, sku))["createInventoryItem"].(map[string]interface{})["id"].(string)
+	if n, pct := used(); n != 3 || pct != 30 {
+		t.Fatalf("used capacity %v (%v%%), want 3 (30%%)", n, pct)
+	}
+	adjust := func(n int) {
+		t.Helper()
+		run(fmt.Sprintf(`mutation { adjustStock(id: %q, quantity: %d, reason: "count") { id } }`, item, n))
+	}
+	// A part pallet still takes a pallet space.
+	adjust(25)
+	if n, _ := used(); n != 6 || alerts() != 1 {
+		t.Fatalf("used capacity %v with %d alerts, want 6 with 1", n, alerts())
+	}
+	adjust(1)
+	if alerts() != 1 {
+		t.Errorf("alerted again within the same threshold")
+	}
+	adjust(39)
+	if n, _ := used(); n != 10 || alerts() != 2 {
+		t.Fatalf("used capacity %v with %d alerts, want 10 with 2", n, alerts())
+	}
+	// Emptying below the thresholds arms them again.
+	adjust(-60)
+	if n, _ := used(); n != 4 || alerts() != 2 {
+		t.Fatalf("used capacity %v with %d alerts, want 4 with 2", n, alerts())
+	}
+	adjust(20)
+	if alerts() != 3 {
+		t.Errorf("%d alerts after passing 50%% again, want 3", alerts())
+	}
+
+	// Measured by weight, 55 drums at 2.5 kg are 137.5 kg; at 4 kg, 220.
+	run(fmt.Sprintf(`mutation { updateWarehouse(id: %q, input: { name: %q, capacity: 1000, capacityUnit: "kg", status: "active" }) { id } }`, depot, name))
+	if n, _ := used(); n != 137.5 {
+		t.Errorf("used capacity by weight %v, want 137.5", n)
+	}
+	run(fmt.Sprintf(`mutation { updateProduct(id: %q, input: { sku: %q, name: "Drum", weight: 4, unitsPerPallet: 10 }) { id } }`, product, sku))
+	if n, _ := used(); n != 220 {
+		t.Errorf("used capacity after reweighing %v, want 220", n)
+	}
+	// Light lines add what they weigh, not a kilo each, however they move.
+	run(fmt.Sprintf(`mutation { createProduct(input: { sku: %q, name: "Label", weight: 0.05 }) { id } }`, light))
+	labels := run(fmt.Sprintf(`mutation { createInventoryItem(input: { warehouseId: %q, sku: %q, quantity: 3 }) { id } }`,
+		depot, light))["createInventoryItem"].(map[string]interface{})["id"].(string)
+	for i := 0; i < 3; i++ {
+		run(fmt.Sprintf(`mutation { adjustStock(id: %q, quantity: 1, reason: "count") { id } }`, labels))
+	}
+	if n, _ := used(); n != 220.3 {
+		t.Errorf("used capacity with light lines %v, want 220.3", n)
+	}
+
+	// An update that leaves out the unit and thresholds keeps them.
+	w = run(fmt.Sprintf(`mutation { updateWarehouse(id: %q, input: { name: %q, capacity: 2000, status: "active" })
+		{ capacityUnit fillThresholds usedCapacity } }`, depot, name))["updateWarehouse"].(map[string]interface{})
+	if w["capacityUnit"] != "kg" || fmt.Sprint(w["fillThresholds"]) != "[50 90]" || w["usedCapacity"] != 220.3 {
+		t.Errorf("warehouse after a partial update %v, want kg, [50 90] and 220.3", w)
+	}
+
+	// Tenant A cannot see it or fill it.
+	actx := userCtx(a)
+	if res := execGraphQL(schema, actx, fmt.Sprintf(`{ warehouse(id: %q) { usedCapacity } }`, depot)); len(res.Errors) == 0 && res.Data.(map[string]interface{})["warehouse"] != nil {
+		t.Error("tenant A read the warehouse")
+	}
+	if res := execGraphQL(schema, actx, fmt.Sprintf(`mutation { adjustStock(id: %q, quantity: 100, reason: "count") { id } }`, item)); len(res.Errors) == 0 {
+		t.Error("tenant A adjusted the stock")
+	}
+
+	id, _ := uuid.Parse(item)
+	if err := env.repos.Inventory.Delete(tenantCtx(context.Background(), b.TenantID), b.TenantID, id); err != nil {
+		t.Fatal(err)
+	}
+	if n, _ := used(); n != 0.3 {
+		t.Errorf("used capacity %v after the drums were removed, want 0.3", n)
+	}
+}
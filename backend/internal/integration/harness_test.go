@@ -0,0 +1,481 @@
+// Package integration holds the cross-tenant isolation suite. It runs
+// against a real PostgreSQL server: set TEST_DATABASE_URL to a role that may
+// CREATE DATABASE (e.g. `make test-db-up` and `make test-integration`).
+// Every run creates a throwaway database, applies all migrations, seeds the
+// demo tenants and builds a fresh fixture set for two tenants through the
+// repositories. Without TEST_DATABASE_URL the suite is skipped.
+package integration
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"cargomax-api/internal/config"
+	"cargomax-api/internal/database"
+	"cargomax-api/internal/geocode"
+	"cargomax-api/internal/models"
+	"cargomax-api/internal/repository"
+	"cargomax-api/internal/seed"
+	"cargomax-api/internal/storage"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const driverPin = "4321"
+
+// repos bundles every repository the suite exercises.
+type repos struct {
+	User            *repository.UserRepo
+	Tenant          *repository.TenantRepo
+	Shipment        *repository.ShipmentRepo
+	Vehicle         *repository.VehicleRepo
+	Driver          *repository.DriverRepo
+	Maintenance     *repository.MaintenanceRepo
+	Warehouse       *repository.WarehouseRepo
+	Inventory       *repository.InventoryRepo
+	Order           *repository.OrderRepo
+	Vendor          *repository.VendorRepo
+	Client          *repository.ClientRepo
+	Feedback        *repository.FeedbackRepo
+	Dashboard       *repository.DashboardRepo
+	Report          *repository.ReportRepo
+	Notification    *repository.NotificationRepo
+	Setting         *repository.SettingRepo
+	Role            *repository.RoleRepo
+	Activity        *repository.ActivityRepo
+	Shift           *repository.ShiftRepo
+	Ping            *repository.GPSPingRepo
+	Alert           *repository.AlertRepo
+	Zone            *repository.ZoneRepo
+	Import          *repository.ImportRepo
+	RateCard        *repository.RateCardRepo
+	Invoice         *repository.InvoiceRepo
+	Fulfillment     *repository.FulfillmentRepo
+	OrderSchedule   *repository.OrderScheduleRepo
+	RMA             *repository.RMARepo
+	StockMovement   *repository.StockMovementRepo
+	Product         *repository.ProductRepo
+	Transfer        *repository.TransferRepo
+	SupplierProduct *repository.SupplierProductRepo
+	PurchaseOrder   *repository.PurchaseOrderRepo
+	Route           *repository.RouteRepo
+	Job             *repository.JobRepo
+}
+
+// fixture is one tenant's known rows, created through the repositories.
+type fixture struct {
+	TenantID     uuid.UUID
+	Admin        *models.User
+	Shipment     *models.Shipment
+	Delivered    *models.Shipment
+	POD          *models.ProofOfDelivery
+	Vehicle      *models.Vehicle
+	Driver       *models.Driver
+	Maintenance  *models.MaintenanceRecord
+	Warehouse    *models.Warehouse
+	Inventory    *models.InventoryItem
+	Order        *models.Order
+	Vendor       *models.Vendor
+	Client       *models.Client
+	Feedback     *models.Feedback
+	Role         *models.Role
+	Notification *models.Notification
+	Activity     *models.Activity
+	Shift        *models.Shift
+	Assignment   *models.ShipmentAssignment
+	Alert        *models.Alert
+	Zone         *models.ApprovedZone
+	Route        *models.RoutePlan
+	Job          *models.Job
+}
+
+type testEnv struct {
+	pool  *pgxpool.Pool
+	cfg   *config.Config
+	repos repos
+	store *storage.LocalDisk
+	// geocoder answers from fixtureGazetteer.
+	geocoder *geocode.Gazetteer
+	// a and b are the two tenants; tests act as b against a's rows and
+	// vice versa.
+	a, b *fixture
+}
+
+var env *testEnv
+
+func TestMain(m *testing.M) {
+	os.Exit(run(m))
+}
+
+func run(m *testing.M) int {
+	baseURL := os.Getenv("TEST_DATABASE_URL")
+	if baseURL == "" {
+		fmt.Println("integration: TEST_DATABASE_URL not set, skipping")
+		return 0
+	}
+
+	ctx := context.Background()
+	dbURL, drop, err := createThrowawayDB(ctx, baseURL)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "integration: %v\n", err)
+		return 1
+	}
+	defer drop()
+
+	pool, err := database.Connect(dbURL)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "integration: connect: %v\n", err)
+		return 1
+	}
+	defer pool.Close()
+
+	if _, err := database.MigrateUp(ctx, pool, 0); err != nil {
+		fmt.Fprintf(os.Stderr, "integration: migrate: %v\n", err)
+		return 1
+	}
+	if err := seed.SeedData(pool); err != nil {
+		fmt.Fprintf(os.Stderr, "integration: seed: %v\n", err)
+		return 1
+	}
+
+	env, err = newTestEnv(ctx, pool)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "integration: fixtures: %v\n", err)
+		return 1
+	}
+	uploads, err := os.MkdirTemp("", "cargomax-it-uploads-")
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "integration: storage: %v\n", err)
+		return 1
+	}
+	defer os.RemoveAll(uploads)
+	if env.store, err = storage.NewLocalDisk(uploads); err != nil {
+		fmt.Fprintf(os.Stderr, "integration: storage: %v\n", err)
+		return 1
+	}
+	env.geocoder = geocode.NewGazetteer()
+	if _, err := env.geocoder.Import(strings.NewReader(fixtureGazetteer)); err != nil {
+		fmt.Fprintf(os.Stderr, "integration: gazetteer: %v\n", err)
+		return 1
+	}
+	return m.Run()
+}
+
+// fixtureGazetteer places two Hamburg postcodes and Munich by its city row.
+const fixtureGazetteer = `country,postal_code,city,region,latitude,longitude
+DE,20457,Hamburg,Hamburg,53.5413,9.9841
+DE,20095,Hamburg,Hamburg,53.5503,10.0006
+DE,,Munich,Bavaria,48.1374,11.5755
+`
+
+// createThrowawayDB creates a uniquely named database next to the one in
+// baseURL and returns its URL and a function that drops it.
+func createThrowawayDB(ctx context.Context, baseURL string) (string, func(), error) {
+	admin, err := pgx.Connect(ctx, baseURL)
+	if err != nil {
+		return "", nil, fmt.Errorf("connect to %s: %w", baseURL, err)
+	}
+
+	suffix := make([]byte, 6)
+	if _, err := rand.Read(suffix); err != nil {
+		admin.Close(ctx)
+		return "", nil, err
+	}
+	name := "cargomax_it_" + hex.EncodeToString(suffix)
+	if _, err := admin.Exec(ctx, "CREATE DATABASE "+pgx.Identifier{name}.Sanitize()); err != nil {
+		admin.Close(ctx)
+		return "", nil, fmt.Errorf("create database: %w", err)
+	}
+
+	u, err := url.Parse(baseURL)
+	if err != nil {
+		admin.Close(ctx)
+		return "", nil, err
+	}
+	u.Path = "/" + name
+
+	drop := func() {
+		if _, err := admin.Exec(context.Background(), "DROP DATABASE IF EXISTS "+pgx.Identifier{name}.Sanitize()+" WITH (FORCE)"); err != nil {
+			fmt.Fprintf(os.Stderr, "integration: drop %s: %v\n", name, err)
+		}
+		admin.Close(context.Background())
+	}
+	return u.String(), drop, nil
+}
+
+func newTestEnv(ctx context.Context, pool *pgxpool.Pool) (*testEnv, error) {
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	e := &testEnv{
+		pool: pool,
+		cfg: &config.Config{
+			JWTPrivateKey: priv,
+			JWTPublicKey:  pub,
+			FrontendURL:   "http://localhost:3000",
+		},
+		repos: repos{
+			User:            repository.NewUserRepo(pool),
+			Tenant:          repository.NewTenantRepo(pool),
+			Shipment:        repository.NewShipmentRepo(pool),
+			Vehicle:         repository.NewVehicleRepo(pool),
+			Driver:          repository.NewDriverRepo(pool),
+			Maintenance:     repository.NewMaintenanceRepo(pool),
+			Warehouse:       repository.NewWarehouseRepo(pool),
+			Inventory:       repository.NewInventoryRepo(pool),
+			Order:           repository.NewOrderRepo(pool),
+			Vendor:          repository.NewVendorRepo(pool),
+			Client:          repository.NewClientRepo(pool),
+			Feedback:        repository.NewFeedbackRepo(pool),
+			Dashboard:       repository.NewDashboardRepo(pool),
+			Report:          repository.NewReportRepo(pool),
+			Notification:    repository.NewNotificationRepo(pool),
+			Setting:         repository.NewSettingRepo(pool),
+			Role:            repository.NewRoleRepo(pool),
+			Activity:        repository.NewActivityRepo(pool),
+			Shift:           repository.NewShiftRepo(pool),
+			Ping:            repository.NewGPSPingRepo(pool),
+			Alert:           repository.NewAlertRepo(pool),
+			Zone:            repository.NewZoneRepo(pool),
+			Import:          repository.NewImportRepo(pool),
+			RateCard:        repository.NewRateCardRepo(pool),
+			Invoice:         repository.NewInvoiceRepo(pool),
+			Fulfillment:     repository.NewFulfillmentRepo(pool),
+			OrderSchedule:   repository.NewOrderScheduleRepo(pool),
+			RMA:             repository.NewRMARepo(pool),
+			StockMovement:   repository.NewStockMovementRepo(pool),
+			Product:         repository.NewProductRepo(pool),
+			Transfer:        repository.NewTransferRepo(pool),
+			SupplierProduct: repository.NewSupplierProductRepo(pool),
+			PurchaseOrder:   repository.NewPurchaseOrderRepo(pool),
+			Route:           repository.NewRouteRepo(pool),
+			Job:             repository.NewJobRepo(pool),
+		},
+	}
+
+	// The seeded demo tenants give the fixtures realistic neighbours.
+	for name, dst := range map[string]**fixture{"Acme Logistics": &e.a, "Beta Transport": &e.b} {
+		var tenantID uuid.UUID
+		if err := pool.QueryRow(database.Privileged(ctx), `SELECT id FROM tenants WHERE name = $1`, name).Scan(&tenantID); err != nil {
+			return nil, fmt.Errorf("look up tenant %s: %w", name, err)
+		}
+		f, err := e.buildFixture(ctx, tenantID)
+		if err != nil {
+			return nil, fmt.Errorf("tenant %s: %w", name, err)
+		}
+		*dst = f
+	}
+	return e, nil
+}
+
+// buildFixture creates one row per repository for tenantID, acting as that
+// tenant so row-level security applies to the inserts too.
+func (e *testEnv) buildFixture(ctx context.Context, tenantID uuid.UUID) (*fixture, error) {
+	ctx = tenantCtx(ctx, tenantID)
+	r := e.repos
+	f := &fixture{TenantID: tenantID}
+	tag := uuid.NewString()[:8]
+	now := time.Now()
+
+	f.Admin = &models.User{TenantID: tenantID, Email: "admin-" + tag + "@example.com", FirstName: str("Test"), LastName: str("Admin"), Role: "admin"}
+	if err := r.User.Create(ctx, f.Admin, "password123"); err != nil {
+		return nil, err
+	}
+
+	f.Vehicle = &models.Vehicle{TenantID: tenantID, VehicleID: "TRK-" + tag, Name: str("Fixture Truck"), Type: str("truck"), Status: "active", FuelLevel: 80, LicensePlate: str("PL-" + tag)}
+	if err := r.Vehicle.Create(ctx, f.Vehicle); err != nil {
+		return nil, err
+	}
+
+	f.Driver = &models.Driver{TenantID: tenantID, EmployeeID: "EMP-" + tag, FirstName: str("Fix"), LastName: str("Ture"), Phone: str("+1555" + tag), Status: "available", VehicleID: &f.Vehicle.ID}
+	if err := r.Driver.Create(ctx, f.Driver); err != nil {
+		return nil, err
+	}
+	pinHash, err := bcrypt.GenerateFromPassword([]byte(driverPin), bcrypt.MinCost)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := e.pool.Exec(ctx, `UPDATE drivers SET pin_hash = $1 WHERE id = $2`, string(pinHash), f.Driver.ID); err != nil {
+		return nil, err
+	}
+
+	f.Maintenance = &models.MaintenanceRecord{TenantID: tenantID, VehicleID: f.Vehicle.ID, Type: str("oil_change"), Description: str("fixture"), Status: "scheduled", ScheduledDate: ptr(now.Add(48 * time.Hour))}
+	if err := r.Maintenance.Create(ctx, f.Maintenance); err != nil {
+		return nil, err
+	}
+
+	f.Shipment = &models.Shipment{TenantID: tenantID, TrackingNumber: "TRK" + tag, Origin: str("Origin"), Destination: str("Destination"), Status: "in_transit", CustomerName: str("Fixture Customer"), EstimatedDelivery: ptr(now.Add(24 * time.Hour)), DestinationLatitude: ptr(39.9526), DestinationLongitude: ptr(-75.1652)}
+	if err := r.Shipment.Create(ctx, f.Shipment, nil); err != nil {
+		return nil, err
+	}
+
+	f.Warehouse = &models.Warehouse{TenantID: tenantID, Name: "Fixture Warehouse " + tag, Capacity: 1000, Status: "active", Latitude: ptr(40.7128), Longitude: ptr(-74.0060)}
+	if err := r.Warehouse.Create(ctx, f.Warehouse); err != nil {
+		return nil, err
+	}
+
+	f.Inventory = &models.InventoryItem{TenantID: tenantID, WarehouseID: f.Warehouse.ID, SKU: "SKU-" + tag, Name: str("Fixture Item"), Quantity: 1, MinQuantity: 10, Status: "low_stock"}
+	if err := r.Inventory.Create(ctx, f.Inventory); err != nil {
+		return nil, err
+	}
+
+	f.Order = &models.Order{TenantID: tenantID, OrderNumber: "ORD-" + tag, CustomerName: str("Fixture Customer"), Status: "pending", Type: "standard", TotalAmount: ptr(99.5)}
+	if err := r.Order.Create(ctx, f.Order); err != nil {
+		return nil, err
+	}
+
+	f.Vendor = &models.Vendor{TenantID: tenantID, Name: "Fixture Vendor " + tag, Status: "active"}
+	if err := r.Vendor.Create(ctx, f.Vendor); err != nil {
+		return nil, err
+	}
+
+	f.Client = &models.Client{TenantID: tenantID, CompanyName: "Fixture Client " + tag, Status: "active"}
+	if err := r.Client.Create(ctx, f.Client); err != nil {
+		return nil, err
+	}
+
+	f.Feedback = &models.Feedback{TenantID: tenantID, ClientID: f.Client.ID, Rating: 4, Comment: str("fixture")}
+	if err := r.Feedback.Create(ctx, f.Feedback); err != nil {
+		return nil, err
+	}
+
+	f.Role = &models.Role{TenantID: tenantID, Name: "fixture-" + tag, Permissions: `{"shipments":"read"}`}
+	if err := r.Role.Create(ctx, f.Role); err != nil {
+		return nil, err
+	}
+
+	f.Notification = &models.Notification{TenantID: tenantID, UserID: f.Admin.ID, Title: str("Fixture"), Message: str("fixture"), Type: str("info")}
+	if err := r.Notification.Create(ctx, f.Notification); err != nil {
+		return nil, err
+	}
+	if err := r.Notification.UpdatePreference(ctx, &models.NotificationPreference{TenantID: tenantID, UserID: f.Admin.ID, EventType: "shipment_delayed", EmailEnabled: true}); err != nil {
+		return nil, err
+	}
+
+	if err := r.Setting.Set(ctx, &models.Setting{TenantID: tenantID, Key: "fixture_key", Value: str("value-" + tag), Category: str("fixture"), UpdatedBy: &f.Admin.ID}); err != nil {
+		return nil, err
+	}
+
+	f.Activity = &models.Activity{TenantID: tenantID, UserID: &f.Admin.ID, Action: str("created"), EntityType: str("shipment"), EntityID: &f.Shipment.ID}
+	if err := r.Activity.Create(ctx, f.Activity); err != nil {
+		return nil, err
+	}
+
+	f.Shift = &models.Shift{TenantID: tenantID, DriverID: f.Driver.ID, TruckID: f.Vehicle.ID}
+	if err := r.Shift.Create(ctx, f.Shift); err != nil {
+		return nil, err
+	}
+	f.Assignment = &models.ShipmentAssignment{ShipmentID: f.Shipment.ID, DriverID: &f.Driver.ID, AssignedBy: &f.Admin.ID}
+	if err := r.Shipment.Assign(ctx, tenantID, f.Assignment); err != nil {
+		return nil, err
+	}
+	if _, err := r.Ping.BulkInsert(ctx, []models.GPSPing{
+		{TenantID: tenantID, DriverID: f.Driver.ID, TruckID: f.Vehicle.ID, ShiftID: f.Shift.ID, Latitude: 40.7128, Longitude: -74.0060, SpeedKmh: 50, IsMoving: true, RecordedAt: now.Add(-2 * time.Minute), ReceivedAt: now},
+		{TenantID: tenantID, DriverID: f.Driver.ID, TruckID: f.Vehicle.ID, ShiftID: f.Shift.ID, Latitude: 40.7306, Longitude: -73.9352, SpeedKmh: 0, RecordedAt: now.Add(-1 * time.Minute), ReceivedAt: now},
+	}); err != nil {
+		return nil, err
+	}
+	eta, _ := models.EstimateETA(f.Shipment, 40.7306, -73.9352, now.Add(-1*time.Minute), 50)
+	eta.ShiftID = &f.Shift.ID
+	if _, err := r.Shipment.RecordETA(ctx, tenantID, &eta); err != nil {
+		return nil, err
+	}
+	if _, err := e.pool.Exec(ctx, `INSERT INTO lane_speeds (tenant_id, origin, destination, avg_speed_kmh) VALUES ($1, 'origin', 'destination', 50)`, tenantID); err != nil {
+		return nil, err
+	}
+
+	// The POD's file rows point at keys that were never written; only the
+	// rows matter to the fixtures.
+	f.Delivered = &models.Shipment{TenantID: tenantID, TrackingNumber: "DLV" + tag, Status: models.ShipmentOutForDelivery, DestinationLatitude: ptr(40.7306), DestinationLongitude: ptr(-73.9352)}
+	if err := r.Shipment.Create(ctx, f.Delivered, nil); err != nil {
+		return nil, err
+	}
+	f.POD = &models.ProofOfDelivery{
+		ShipmentID: f.Delivered.ID, DriverID: &f.Driver.ID, ShiftID: &f.Shift.ID, RecipientName: "Fixture Recipient",
+		Latitude: 40.7306, Longitude: -73.9352, CapturedAt: now.Add(-1 * time.Minute),
+		Files: []models.PODFile{{Kind: models.PODSignature, StorageKey: tenantID.String() + "/pod/fixture.png", ContentType: "image/png", SizeBytes: 8}},
+	}
+	if _, err := r.Shipment.Deliver(ctx, tenantID, f.POD, &models.ShipmentEvent{ActorType: "driver", ActorID: &f.Driver.ID}); err != nil {
+		return nil, err
+	}
+
+	f.Zone = &models.ApprovedZone{TenantID: tenantID, Label: "Fixture Depot", Latitude: 40.7128, Longitude: -74.0060, RadiusMeters: 300, Type: "warehouse"}
+	if err := r.Zone.Create(ctx, f.Zone); err != nil {
+		return nil, err
+	}
+	if err := r.Zone.CreateOrUpdateAlertConfig(ctx, &models.AlertConfig{TenantID: tenantID, MaxStopDurationMinutes: 7, AlertOnDriverOffline: true, OfflineThresholdMinutes: 4, NotifyViaEmail: true}); err != nil {
+		return nil, err
+	}
+
+	// A draft plan: the route worker only follows assigned ones.
+	f.Route = &models.RoutePlan{
+		TenantID: tenantID, PlanDate: now.UTC().Format(models.RouteDateLayout), Name: "Fixture Route " + tag,
+		WarehouseID: &f.Warehouse.ID, PlannedStart: now, CreatedBy: &f.Admin.ID,
+		Stops: []models.RouteStop{
+			{Type: models.StopDepot, ZoneID: &f.Zone.ID, Label: f.Zone.Label, Latitude: f.Zone.Latitude, Longitude: f.Zone.Longitude, RadiusMeters: f.Zone.RadiusMeters},
+			{Type: models.StopDelivery, ShipmentID: &f.Shipment.ID, Label: f.Shipment.TrackingNumber, Latitude: 39.9526, Longitude: -75.1652, RadiusMeters: models.RouteStopRadiusMeters, ServiceMinutes: models.RouteDefaultServiceMinutes},
+		},
+	}
+	if err := r.Route.Create(ctx, f.Route); err != nil {
+		return nil, err
+	}
+
+	f.Alert = &models.Alert{TenantID: tenantID, DriverID: f.Driver.ID, ShiftID: &f.Shift.ID, Type: "unauthorized_stop", Status: "triggered", StopLatitude: ptr(40.7306), StopLongitude: ptr(-73.9352)}
+	if err := r.Alert.Create(ctx, f.Alert); err != nil {
+		return nil, err
+	}
+
+	f.Job = &models.Job{TenantID: &tenantID, Type: "fixture.noop", RunAt: now.Add(time.Hour)}
+	if _, err := r.Job.Enqueue(ctx, f.Job); err != nil {
+		return nil, err
+	}
+
+	return f, nil
+}
+
+// tenantCtx returns ctx scoped to tenantID the way the auth middleware does.
+func tenantCtx(ctx context.Context, tenantID uuid.UUID) context.Context {
+	return context.WithValue(ctx, models.CtxTenantID, tenantID)
+}
+
+// userCtx returns ctx carrying the full set of claims for an authenticated user.
+func userCtx(f *fixture) context.Context {
+	ctx := tenantCtx(context.Background(), f.TenantID)
+	ctx = context.WithValue(ctx, models.CtxUserID, f.Admin.ID)
+	ctx = context.WithValue(ctx, models.CtxUserRole, f.Admin.Role)
+	return context.WithValue(ctx, models.CtxUserEmail, f.Admin.Email)
+}
+
+// count runs a privileged COUNT(*) so assertions see the true table state.
+func (e *testEnv) count(t *testing.T, query string, args ...any) int {
+	t.Helper()
+	var n int
+	if err := e.pool.QueryRow(database.Privileged(context.Background()), query, args...).Scan(&n); err != nil {
+		t.Fatalf("count %q: %v", query, err)
+	}
+	return n
+}
+
+// scalar reads a single column value with privileges, bypassing RLS.
+func (e *testEnv) scalar(t *testing.T, dst any, query string, args ...any) {
+	t.Helper()
+	if err := e.pool.QueryRow(database.Privileged(context.Background()), query, args...).Scan(dst); err != nil {
+		t.Fatalf("scalar %q: %v", query, err)
+	}
+}
+
+func str(s string) *string { return &s }
+
+func ptr[T any](v T) *T { return &v }
@@ -0,0 +1,744 @@
+package integration
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"cargomax-api/internal/database"
+	"cargomax-api/internal/models"
+	"cargomax-api/internal/repository"
+
+	"github.com/google/uuid"
+)
+
+// TestRepositoryGetsRejectForeignIDs looks up every one of tenant A's rows
+// while acting as tenant B. Each lookup must fail.
+func TestRepositoryGetsRejectForeignIDs(t *testing.T) {
+	a, b := env.a, env.b
+	ctx := tenantCtx(context.Background(), b.TenantID)
+	r := env.repos
+
+	cases := map[string]func() error{
+		"user":        func() error { _, err := r.User.GetByID(ctx, b.TenantID, a.Admin.ID); return err },
+		"userByEmail": func() error { _, err := r.User.GetByEmail(ctx, b.TenantID, a.Admin.Email); return err },
+		"shipment":    func() error { _, err := r.Shipment.GetByID(ctx, b.TenantID, a.Shipment.ID); return err },
+		"tracking": func() error {
+			_, err := r.Shipment.GetByTracking(ctx, b.TenantID, a.Shipment.TrackingNumber)
+			return err
+		},
+		"vehicle":     func() error { _, err := r.Vehicle.GetByID(ctx, b.TenantID, a.Vehicle.ID); return err },
+		"driver":      func() error { _, err := r.Driver.GetByID(ctx, b.TenantID, a.Driver.ID); return err },
+		"maintenance": func() error { _, err := r.Maintenance.GetByID(ctx, b.TenantID, a.Maintenance.ID); return err },
+		"warehouse":   func() error { _, err := r.Warehouse.GetByID(ctx, b.TenantID, a.Warehouse.ID); return err },
+		"inventory":   func() error { _, err := r.Inventory.GetByID(ctx, b.TenantID, a.Inventory.ID); return err },
+		"sku":         func() error { _, err := r.Inventory.GetBySKU(ctx, b.TenantID, a.Inventory.SKU, nil); return err },
+		"order":       func() error { _, err := r.Order.GetByID(ctx, b.TenantID, a.Order.ID); return err },
+		"vendor":      func() error { _, err := r.Vendor.GetByID(ctx, b.TenantID, a.Vendor.ID); return err },
+		"client":      func() error { _, err := r.Client.GetByID(ctx, b.TenantID, a.Client.ID); return err },
+		"role":        func() error { _, err := r.Role.GetByID(ctx, b.TenantID, a.Role.ID); return err },
+		"notification": func() error {
+			_, err := r.Notification.GetByID(ctx, b.TenantID, a.Admin.ID, a.Notification.ID)
+			return err
+		},
+		"shift":       func() error { _, err := r.Shift.GetByID(ctx, b.TenantID, a.Shift.ID); return err },
+		"activeShift": func() error { _, err := r.Shift.GetActiveByDriver(ctx, b.TenantID, a.Driver.ID); return err },
+		"latestPing":  func() error { _, err := r.Ping.GetLatestByDriver(ctx, b.TenantID, a.Driver.ID); return err },
+		"alert":       func() error { _, err := r.Alert.GetByID(ctx, b.TenantID, a.Alert.ID); return err },
+		"zone":        func() error { _, err := r.Zone.GetByID(ctx, b.TenantID, a.Zone.ID); return err },
+		"route":       func() error { _, err := r.Route.GetByID(ctx, b.TenantID, a.Route.ID); return err },
+		"routeStop":   func() error { _, err := r.Route.GetByStop(ctx, b.TenantID, a.Route.Stops[0].ID); return err },
+	}
+	for name, fn := range cases {
+		t.Run(name, func(t *testing.T) {
+			if err := fn(); err == nil {
+				t.Errorf("tenant B read tenant A's %s", name)
+			}
+		})
+	}
+
+	// Both tenants own a "fixture_key" setting; B must get its own value.
+	var aValue string
+	env.scalar(t, &aValue, `SELECT value FROM settings WHERE tenant_id = $1 AND key = 'fixture_key'`, a.TenantID)
+	s, err := r.Setting.Get(ctx, b.TenantID, "fixture_key")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.TenantID != b.TenantID || (s.Value != nil && *s.Value == aValue) {
+		t.Error("tenant B read tenant A's setting")
+	}
+}
+
+// TestRepositoryListsAreTenantScoped checks that list and aggregate queries
+// never include the other tenant's rows.
+func TestRepositoryListsAreTenantScoped(t *testing.T) {
+	a, b := env.a, env.b
+	ctx := tenantCtx(context.Background(), b.TenantID)
+	r := env.repos
+
+	seen := func(t *testing.T, name string, ids []uuid.UUID, foreign uuid.UUID) {
+		t.Helper()
+		for _, id := range ids {
+			if id == foreign {
+				t.Errorf("%s list contains tenant A's row %s", name, foreign)
+			}
+		}
+	}
+
+	shipments, _, err := r.Shipment.List(ctx, b.TenantID, "", 1, 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ids := make([]uuid.UUID, 0, len(shipments))
+	for _, s := range shipments {
+		if s.TenantID != b.TenantID {
+			t.Errorf("shipment %s belongs to %s", s.ID, s.TenantID)
+		}
+		ids = append(ids, s.ID)
+	}
+	seen(t, "shipments", ids, a.Shipment.ID)
+
+	vehicles, _, err := r.Vehicle.List(ctx, b.TenantID, "", 1, 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ids = ids[:0]
+	for _, v := range vehicles {
+		ids = append(ids, v.ID)
+	}
+	seen(t, "vehicles", ids, a.Vehicle.ID)
+
+	drivers, _, err := r.Driver.List(ctx, b.TenantID, 1, 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ids = ids[:0]
+	for _, d := range drivers {
+		ids = append(ids, d.ID)
+	}
+	seen(t, "drivers", ids, a.Driver.ID)
+
+	items, _, err := r.Inventory.List(ctx, b.TenantID, &a.Warehouse.ID, 1, 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(items) != 0 {
+		t.Errorf("filtering by tenant A's warehouse returned %d items", len(items))
+	}
+
+	low, err := r.Inventory.GetLowStock(ctx, b.TenantID, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ids = ids[:0]
+	for _, i := range low {
+		ids = append(ids, i.ID)
+	}
+	seen(t, "low stock", ids, a.Inventory.ID)
+	if low, err = r.Inventory.GetLowStock(ctx, b.TenantID, &a.Warehouse.ID); err != nil || len(low) != 0 {
+		t.Errorf("low stock in tenant A's warehouse: %d items, %v", len(low), err)
+	}
+
+	records, _, err := r.Maintenance.List(ctx, b.TenantID, &a.Vehicle.ID, 1, 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 0 {
+		t.Errorf("filtering by tenant A's vehicle returned %d maintenance records", len(records))
+	}
+
+	feedback, _, err := r.Feedback.ListByClient(ctx, b.TenantID, a.Client.ID, 1, 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(feedback) != 0 {
+		t.Errorf("filtering by tenant A's client returned %d feedback rows", len(feedback))
+	}
+
+	notifications, _, err := r.Notification.List(ctx, b.TenantID, a.Admin.ID, 1, 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(notifications) != 0 {
+		t.Errorf("tenant B listed %d of tenant A's admin notifications", len(notifications))
+	}
+
+	prefs, err := r.Notification.GetPreferences(ctx, b.TenantID, a.Admin.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(prefs) != 0 {
+		t.Errorf("tenant B read %d of tenant A's notification preferences", len(prefs))
+	}
+
+	activity, _, err := r.Activity.ListByEntity(ctx, b.TenantID, "shipment", a.Shipment.ID, 1, 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(activity) != 0 {
+		t.Errorf("tenant B read %d activity rows for tenant A's shipment", len(activity))
+	}
+
+	pings, err := r.Ping.GetByShift(ctx, b.TenantID, a.Shift.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pings) != 0 {
+		t.Errorf("tenant B read %d pings from tenant A's shift", len(pings))
+	}
+	km, err := r.Ping.CalculateShiftKm(ctx, b.TenantID, a.Shift.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if km != 0 {
+		t.Errorf("tenant B computed %.2f km on tenant A's shift", km)
+	}
+
+	latest, err := r.Ping.GetLatestByTenant(ctx, b.TenantID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, p := range latest {
+		if p.TenantID != b.TenantID {
+			t.Errorf("live position for tenant %s leaked", p.TenantID)
+		}
+	}
+
+	alerts, err := r.Alert.GetByTenant(ctx, b.TenantID, 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ids = ids[:0]
+	for _, al := range alerts {
+		ids = append(ids, al.ID)
+	}
+	seen(t, "alerts", ids, a.Alert.ID)
+
+	zones, err := r.Zone.GetByTenant(ctx, b.TenantID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ids = ids[:0]
+	for _, z := range zones {
+		ids = append(ids, z.ID)
+	}
+	seen(t, "zones", ids, a.Zone.ID)
+
+	jobs, err := r.Job.ListByTenant(ctx, b.TenantID, "", 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ids = ids[:0]
+	for _, j := range jobs {
+		ids = append(ids, j.ID)
+	}
+	seen(t, "jobs", ids, a.Job.ID)
+
+	recent, err := r.Alert.HasRecentAlert(ctx, b.TenantID, a.Driver.ID, "unauthorized_stop", 60)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if recent {
+		t.Error("HasRecentAlert saw tenant A's alert")
+	}
+
+	atRisk, err := r.Shipment.GetAtRisk(ctx, b.TenantID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ids = ids[:0]
+	for _, s := range atRisk {
+		ids = append(ids, s.ID)
+	}
+	seen(t, "at-risk shipments", ids, a.Shipment.ID)
+
+	etas, err := r.Shipment.ListETAsByShipments(ctx, b.TenantID, []uuid.UUID{a.Shipment.ID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(etas) != 0 {
+		t.Errorf("ListETAsByShipments returned tenant A's predictions: %v", etas)
+	}
+
+	pods, err := r.Shipment.ListProofsByShipments(ctx, b.TenantID, []uuid.UUID{a.Delivered.ID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pods) != 0 {
+		t.Errorf("ListProofsByShipments returned tenant A's proof of delivery: %v", pods)
+	}
+
+	plans, err := r.Route.ListByDate(ctx, b.TenantID, a.Route.PlanDate, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ids = ids[:0]
+	for _, p := range plans {
+		ids = append(ids, p.ID)
+	}
+	seen(t, "route plans", ids, a.Route.ID)
+
+	// Route candidates: a pending shipment and a free vehicle of tenant A's.
+	aCtx := tenantCtx(context.Background(), a.TenantID)
+	pending := &models.Shipment{TenantID: a.TenantID, TrackingNumber: "UNR-" + uuid.NewString()[:8], Status: models.ShipmentPending, DestinationLatitude: ptr(40.7), DestinationLongitude: ptr(-74.0)}
+	if err := r.Shipment.Create(aCtx, pending, nil); err != nil {
+		t.Fatal(err)
+	}
+	free := &models.Vehicle{TenantID: a.TenantID, VehicleID: "FREE-" + uuid.NewString()[:8], Status: "available"}
+	if err := r.Vehicle.Create(aCtx, free); err != nil {
+		t.Fatal(err)
+	}
+	for _, only := range [][]uuid.UUID{nil, {pending.ID}} {
+		unrouted, err := r.Route.UnroutedShipments(ctx, b.TenantID, time.Now().UTC().Format(models.RouteDateLayout), only)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ids = ids[:0]
+		for _, s := range unrouted {
+			ids = append(ids, s.ID)
+		}
+		seen(t, "unrouted shipments", ids, pending.ID)
+	}
+	for _, only := range [][]uuid.UUID{nil, {free.ID}} {
+		vehicles, err := r.Route.FreeVehicles(ctx, b.TenantID, time.Now().UTC().Format(models.RouteDateLayout), only)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ids = ids[:0]
+		for _, v := range vehicles {
+			ids = append(ids, v.ID)
+		}
+		seen(t, "free vehicles", ids, free.ID)
+	}
+
+	outgoing, err := r.Shipment.ListOutgoing(ctx, b.TenantID, a.Warehouse.ID, time.Now().UTC().Format(models.RouteDateLayout))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(outgoing) != 0 {
+		t.Errorf("outgoing shipments for tenant A's warehouse listed %d rows", len(outgoing))
+	}
+
+	inUse, err := r.Shift.IsTruckInUse(ctx, b.TenantID, a.Vehicle.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if inUse {
+		t.Error("IsTruckInUse saw tenant A's shift")
+	}
+}
+
+// TestRepositoryWritesIgnoreForeignIDs runs every mutating repository method
+// as tenant B against tenant A's ids and then verifies, with privileges, that
+// A's rows are untouched.
+func TestRepositoryWritesIgnoreForeignIDs(t *testing.T) {
+	a, b := env.a, env.b
+	ctx := tenantCtx(context.Background(), b.TenantID)
+	r := env.repos
+
+	// Errors are expected from the repos that check RowsAffected and
+	// deliberately ignored here: the assertions below are on table state.
+	_ = r.Shipment.Update(ctx, b.TenantID, a.Shipment.ID, &models.Shipment{TrackingNumber: "PWNED", Status: "delivered", Notes: str("pwned")})
+	_, _ = r.Shipment.Transition(ctx, b.TenantID, a.Shipment.ID, &models.ShipmentEvent{Status: models.ShipmentDelivered, Note: str("pwned")})
+	_ = r.Shipment.Assign(ctx, b.TenantID, &models.ShipmentAssignment{ShipmentID: a.Shipment.ID, DriverID: &b.Driver.ID})
+	_ = r.Shipment.Unassign(ctx, b.TenantID, a.Shipment.ID)
+	_, _ = r.Shipment.AttachToShift(ctx, b.TenantID, a.Driver.ID, b.Shift.ID, b.Vehicle.ID)
+	_, _ = r.Shipment.RecordETA(ctx, b.TenantID, &models.ShipmentETA{ShipmentID: a.Shipment.ID, PredictedDelivery: time.Now().Add(99 * time.Hour), PredictedLate: true})
+	_ = r.Vehicle.Update(ctx, b.TenantID, a.Vehicle.ID, &models.Vehicle{VehicleID: "PWNED", Status: "retired"})
+	_ = r.Driver.Update(ctx, b.TenantID, a.Driver.ID, &models.Driver{EmployeeID: "PWNED", FirstName: str("P"), LastName: str("W"), Status: "suspended"})
+	_ = r.Maintenance.Update(ctx, b.TenantID, a.Maintenance.ID, &models.MaintenanceRecord{VehicleID: b.Vehicle.ID, Status: "completed"})
+	_ = r.Warehouse.Update(ctx, b.TenantID, a.Warehouse.ID, &models.Warehouse{Name: "pwned", Capacity: 1, Status: "closed"})
+	_ = r.Inventory.Update(ctx, b.TenantID, a.Inventory.ID, &models.InventoryItem{WarehouseID: b.Warehouse.ID, SKU: "PWNED", Quantity: 9999, Status: "in_stock"}, "pwned")
+	_ = r.Inventory.Restock(ctx, b.TenantID, a.Inventory.ID, 500, models.StockRef{Reason: "pwned"})
+	_ = r.Inventory.Adjust(ctx, b.TenantID, a.Inventory.ID, models.MovementWriteOff, -5, models.StockRef{Reason: "pwned"})
+	_, _ = r.StockMovement.Reconcile(ctx, b.TenantID, &a.Warehouse.ID, "pwned")
+	_ = r.Order.Update(ctx, b.TenantID, a.Order.ID, &models.Order{OrderNumber: "PWNED", Status: "delivered", Type: "standard"})
+	_ = r.Order.CancelOrder(ctx, b.TenantID, a.Order.ID, "pwned")
+	_ = r.Order.ReturnOrder(ctx, b.TenantID, a.Order.ID, "pwned")
+	_ = r.Vendor.Update(ctx, b.TenantID, a.Vendor.ID, &models.Vendor{Name: "pwned", Status: "inactive"})
+	_ = r.Client.Update(ctx, b.TenantID, a.Client.ID, &models.Client{CompanyName: "pwned", Status: "inactive"})
+	_ = r.Role.Update(ctx, b.TenantID, a.Role.ID, &models.Role{Name: "pwned", Permissions: "{}"})
+	_ = r.Notification.MarkRead(ctx, b.TenantID, a.Admin.ID, a.Notification.ID)
+	_ = r.User.UpdateEmailVerified(ctx, b.TenantID, a.Admin.ID, true)
+	_ = r.User.SetVerifyToken(ctx, b.TenantID, a.Admin.ID, "pwned")
+	_ = r.Alert.Acknowledge(ctx, b.TenantID, a.Alert.ID)
+	_ = r.Alert.Resolve(ctx, b.TenantID, a.Alert.ID, "pwned")
+	_ = r.Alert.MarkFalseAlarm(ctx, b.TenantID, a.Alert.ID)
+	_ = r.Zone.Update(ctx, b.TenantID, a.Zone.ID, &models.ApprovedZone{Label: "pwned", RadiusMeters: 99999, Type: "custom"})
+	_, _ = r.Shift.End(ctx, b.TenantID, a.Driver.ID, a.Shift.ID, 999)
+	_ = r.Route.Save(ctx, b.TenantID, &models.RoutePlan{ID: a.Route.ID, PlanDate: a.Route.PlanDate, Name: "pwned", PlannedStart: time.Now()})
+	_ = r.Route.Assign(ctx, b.TenantID, a.Route.ID, b.Driver.ID, nil)
+	_ = r.Route.Start(ctx, b.TenantID, a.Route.ID, b.Shift.ID)
+	_, _ = r.Route.RecordArrival(ctx, b.TenantID, a.Route.Stops[0].ID, time.Now())
+	_, _ = r.Route.SkipStop(ctx, b.TenantID, a.Route.Stops[1].ID)
+	_ = r.Route.CreateProposed(ctx, b.TenantID, a.Route.PlanDate, []*models.RoutePlan{{Name: "pwned", PlannedStart: time.Now(), Stops: []models.RouteStop{{Type: models.StopDelivery, ShipmentID: &a.Shipment.ID, Label: "pwned"}}}})
+
+	unchanged := []struct {
+		name  string
+		query string
+		id    uuid.UUID
+	}{
+		{"shipment", `SELECT COUNT(*) FROM shipments WHERE id = $1 AND status = 'in_transit' AND COALESCE(notes, '') <> 'pwned'`, a.Shipment.ID},
+		{"shipment timeline", `SELECT COUNT(*) FROM shipment_events WHERE shipment_id = $1 AND note IS NULL`, a.Shipment.ID},
+		{"shipment eta", `SELECT COUNT(*) FROM shipments WHERE id = $1 AND NOT predicted_late AND predicted_delivery < NOW() + INTERVAL '24 hours'`, a.Shipment.ID},
+		{"shipment assignment", `SELECT COUNT(*) FROM shipment_assignments WHERE id = $1 AND unassigned_at IS NULL`, a.Assignment.ID},
+		{"vehicle", `SELECT COUNT(*) FROM vehicles WHERE id = $1 AND status = 'active'`, a.Vehicle.ID},
+		{"driver", `SELECT COUNT(*) FROM drivers WHERE id = $1 AND status = 'available'`, a.Driver.ID},
+		{"maintenance", `SELECT COUNT(*) FROM maintenance_records WHERE id = $1 AND status = 'scheduled'`, a.Maintenance.ID},
+		{"warehouse", `SELECT COUNT(*) FROM warehouses WHERE id = $1 AND capacity = 1000`, a.Warehouse.ID},
+		{"inventory", `SELECT COUNT(*) FROM inventory_items WHERE id = $1 AND quantity = 1`, a.Inventory.ID},
+		{"stock ledger", `SELECT COUNT(*) FROM inventory_items i WHERE id = $1 AND (SELECT COUNT(*) FROM stock_movements m WHERE m.inventory_item_id = i.id) = 1`, a.Inventory.ID},
+		{"order", `SELECT COUNT(*) FROM orders WHERE id = $1 AND status = 'pending'`, a.Order.ID},
+		{"vendor", `SELECT COUNT(*) FROM vendors WHERE id = $1 AND status = 'active'`, a.Vendor.ID},
+		{"client", `SELECT COUNT(*) FROM clients WHERE id = $1 AND status = 'active'`, a.Client.ID},
+		{"role", `SELECT COUNT(*) FROM roles WHERE id = $1 AND name LIKE 'fixture-%'`, a.Role.ID},
+		{"notification", `SELECT COUNT(*) FROM notifications WHERE id = $1 AND NOT read`, a.Notification.ID},
+		{"user", `SELECT COUNT(*) FROM users WHERE id = $1 AND email_verify_token IS DISTINCT FROM 'pwned'`, a.Admin.ID},
+		{"alert", `SELECT COUNT(*) FROM alerts WHERE id = $1 AND status = 'triggered'`, a.Alert.ID},
+		{"zone", `SELECT COUNT(*) FROM approved_zones WHERE id = $1 AND label = 'Fixture Depot'`, a.Zone.ID},
+		{"shift", `SELECT COUNT(*) FROM shifts WHERE id = $1 AND ended_at IS NULL`, a.Shift.ID},
+		{"route plan", `SELECT COUNT(*) FROM route_plans WHERE id = $1 AND status = 'draft' AND driver_id IS NULL AND name LIKE 'Fixture Route%'`, a.Route.ID},
+		{"route stops", `SELECT COUNT(*) FROM route_plans p WHERE id = $1 AND (SELECT COUNT(*) FROM route_stops s WHERE s.route_id = p.id AND s.status = 'pending') = 2`, a.Route.ID},
+		{"shipment routing", `SELECT COUNT(*) FROM shipments s WHERE id = $1 AND NOT EXISTS (SELECT 1 FROM route_stops st WHERE st.shipment_id = s.id AND st.tenant_id <> s.tenant_id)`, a.Shipment.ID},
+	}
+	for _, c := range unchanged {
+		if env.count(t, c.query, c.id) != 1 {
+			t.Errorf("tenant B modified tenant A's %s", c.name)
+		}
+	}
+
+	// Deletes last so the updates above had something to hit.
+	_ = r.Shipment.Delete(ctx, b.TenantID, a.Shipment.ID)
+	_ = r.Vehicle.Delete(ctx, b.TenantID, a.Vehicle.ID)
+	_ = r.Driver.Delete(ctx, b.TenantID, a.Driver.ID)
+	_ = r.Warehouse.Delete(ctx, b.TenantID, a.Warehouse.ID)
+	_ = r.Inventory.Delete(ctx, b.TenantID, a.Inventory.ID)
+	_ = r.Order.Delete(ctx, b.TenantID, a.Order.ID)
+	_ = r.Vendor.Delete(ctx, b.TenantID, a.Vendor.ID)
+	_ = r.Client.Delete(ctx, b.TenantID, a.Client.ID)
+	_ = r.Role.Delete(ctx, b.TenantID, a.Role.ID)
+	_ = r.Zone.Delete(ctx, b.TenantID, a.Zone.ID)
+	_ = r.Route.Delete(ctx, b.TenantID, a.Route.ID)
+
+	for table, id := range map[string]uuid.UUID{
+		"shipments": a.Shipment.ID, "vehicles": a.Vehicle.ID, "drivers": a.Driver.ID,
+		"warehouses": a.Warehouse.ID, "inventory_items": a.Inventory.ID, "orders": a.Order.ID,
+		"vendors": a.Vendor.ID, "clients": a.Client.ID, "roles": a.Role.ID, "approved_zones": a.Zone.ID,
+		"route_plans": a.Route.ID,
+	} {
+		if env.count(t, `SELECT COUNT(*) FROM `+table+` WHERE id = $1`, id) != 1 {
+			t.Errorf("tenant B deleted tenant A's row from %s", table)
+		}
+	}
+}
+
+// TestRepositoryTenantArgumentCannotOverrideContext passes tenant A's id as
+// the explicit tenantID while the connection is scoped to tenant B, which is
+// what a handler bug mixing up the two would do. RLS must still hide A's rows.
+func TestRepositoryTenantArgumentCannotOverrideContext(t *testing.T) {
+	a, b := env.a, env.b
+	ctx := tenantCtx(context.Background(), b.TenantID)
+
+	if _, err := env.repos.Shipment.GetByID(ctx, a.TenantID, a.Shipment.ID); err == nil {
+		t.Error("tenant A's shipment readable with tenant B's connection")
+	}
+	list, total, err := env.repos.Vendor.List(ctx, a.TenantID, 1, 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if total != 0 || len(list) != 0 {
+		t.Errorf("listed %d of tenant A's vendors with tenant B's connection", total)
+	}
+	_ = env.repos.Vendor.Delete(ctx, a.TenantID, a.Vendor.ID)
+	if env.count(t, `SELECT COUNT(*) FROM vendors WHERE id = $1`, a.Vendor.ID) != 1 {
+		t.Error("deleted tenant A's vendor with tenant B's connection")
+	}
+}
+
+// TestShipmentTransitionsAreEnforced walks a shipment through its lifecycle
+// and checks illegal moves are rejected without touching the row or timeline.
+func TestShipmentTransitionsAreEnforced(t *testing.T) {
+	b := env.b
+	ctx := tenantCtx(context.Background(), b.TenantID)
+	r := env.repos
+
+	s := &models.Shipment{TenantID: b.TenantID, TrackingNumber: "FSM-" + uuid.NewString()[:8], Status: models.ShipmentPending}
+	if err := r.Shipment.Create(ctx, s, &models.ShipmentEvent{ActorType: "user", ActorID: &b.Admin.ID}); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, to := range []string{models.ShipmentPickedUp, models.ShipmentInTransit, models.ShipmentDelivered} {
+		if _, err := r.Shipment.Transition(ctx, b.TenantID, s.ID, &models.ShipmentEvent{Status: to, Location: str("Fixture Hub")}); err != nil {
+			t.Fatalf("transition to %s: %v", to, err)
+		}
+	}
+
+	_, err := r.Shipment.Transition(ctx, b.TenantID, s.ID, &models.ShipmentEvent{Status: models.ShipmentPending})
+	if !errors.Is(err, repository.ErrIllegalShipmentTransition) {
+		t.Errorf("delivered -> pending returned %v, want ErrIllegalShipmentTransition", err)
+	}
+
+	got, err := r.Shipment.GetByID(ctx, b.TenantID, s.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Status != models.ShipmentDelivered || got.ActualDelivery == nil {
+		t.Errorf("status %s, actual delivery %v after delivery", got.Status, got.ActualDelivery)
+	}
+
+	events, err := r.Shipment.ListEvents(ctx, b.TenantID, s.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var statuses []string
+	for _, e := range events {
+		statuses = append(statuses, e.Status)
+	}
+	if want := "pending picked_up in_transit delivered"; strings.Join(statuses, " ") != want {
+		t.Errorf("timeline %v, want %s", statuses, want)
+	}
+	if len(events) > 0 && (events[0].ActorType != "user" || events[0].ActorID == nil || *events[0].ActorID != b.Admin.ID) {
+		t.Errorf("creation event attributed to %s %v", events[0].ActorType, events[0].ActorID)
+	}
+}
+
+// TestShipmentAssignmentsFollowShifts assigns a shipment to an off-shift
+// driver, starts a shift and checks the load moved onto the shift's truck with
+// the earlier assignment kept as history. Foreign drivers and vehicles are
+// rejected as assignees.
+func TestShipmentAssignmentsFollowShifts(t *testing.T) {
+	a, b := env.a, env.b
+	ctx := tenantCtx(context.Background(), b.TenantID)
+	r := env.repos
+	tag := uuid.NewString()[:8]
+
+	truck := &models.Vehicle{TenantID: b.TenantID, VehicleID: "ASN-" + tag, Status: "active"}
+	if err := r.Vehicle.Create(ctx, truck); err != nil {
+		t.Fatal(err)
+	}
+	driver := &models.Driver{TenantID: b.TenantID, EmployeeID: "ASN-" + tag, FirstName: str("Off"), LastName: str("Shift"), Status: "available"}
+	if err := r.Driver.Create(ctx, driver); err != nil {
+		t.Fatal(err)
+	}
+	s := &models.Shipment{TenantID: b.TenantID, TrackingNumber: "ASN-" + tag, Status: models.ShipmentPending}
+	if err := r.Shipment.Create(ctx, s, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	for name, asn := range map[string]*models.ShipmentAssignment{
+		"foreign driver":  {ShipmentID: s.ID, DriverID: &a.Driver.ID},
+		"foreign vehicle": {ShipmentID: s.ID, VehicleID: &a.Vehicle.ID},
+	} {
+		if err := r.Shipment.Assign(ctx, b.TenantID, asn); !errors.Is(err, repository.ErrAssigneeNotFound) {
+			t.Errorf("%s: got %v, want ErrAssigneeNotFound", name, err)
+		}
+	}
+
+	first := &models.ShipmentAssignment{ShipmentID: s.ID, DriverID: &driver.ID, AssignedBy: &b.Admin.ID}
+	if err := r.Shipment.Assign(ctx, b.TenantID, first); err != nil {
+		t.Fatal(err)
+	}
+	if first.ShiftID != nil {
+		t.Errorf("off-shift driver's assignment attached to shift %v", first.ShiftID)
+	}
+
+	shift := &models.Shift{TenantID: b.TenantID, DriverID: driver.ID, TruckID: truck.ID}
+	if err := r.Shift.Create(ctx, shift); err != nil {
+		t.Fatal(err)
+	}
+	if n, err := r.Shipment.AttachToShift(ctx, b.TenantID, driver.ID, shift.ID, truck.ID); err != nil || n != 1 {
+		t.Fatalf("attached %d shipments: %v", n, err)
+	}
+
+	history, err := r.Shipment.ListAssignmentsByShipments(ctx, b.TenantID, []uuid.UUID{s.ID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	rows := history[s.ID]
+	if len(rows) != 2 || rows[0].UnassignedAt == nil || rows[1].UnassignedAt != nil {
+		t.Fatalf("history %+v, want one closed and one open assignment", rows)
+	}
+	if open := rows[1]; open.ShiftID == nil || *open.ShiftID != shift.ID || open.VehicleID == nil || *open.VehicleID != truck.ID || open.AssignedBy == nil || *open.AssignedBy != b.Admin.ID {
+		t.Errorf("open assignment %+v not on the new shift's truck", open)
+	}
+
+	loads, err := r.Shipment.ListAssignedToDriver(ctx, b.TenantID, driver.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(loads) != 1 || loads[0].ID != s.ID {
+		t.Errorf("driver loads %v", loads)
+	}
+}
+
+// TestOrderLinesReserveStock walks orders with lines through their life:
+// open orders reserve stock, an oversell is refused, cancelling releases,
+// shipping takes the stock and returning puts it back. Concurrent orders for
+// the last units never reserve more than is on hand.
+func TestOrderLinesReserveStock(t *testing.T) {
+	b := env.b
+	ctx := tenantCtx(context.Background(), b.TenantID)
+	r := env.repos
+	tag := uuid.NewString()[:8]
+
+	item := &models.InventoryItem{TenantID: b.TenantID, WarehouseID: b.Warehouse.ID, SKU: "RSV-" + tag, Name: str("Reserved Item"), Quantity: 10, MinQuantity: 2, Status: "in_stock", UnitPrice: ptr(2.5)}
+	if err := r.Inventory.Create(ctx, item); err != nil {
+		t.Fatal(err)
+	}
+	line := func(n int) []models.OrderLine {
+		return []models.OrderLine{{InventoryItemID: &item.ID, SKU: item.SKU, WarehouseID: &item.WarehouseID, Quantity: n, UnitPrice: 2.5, LineTotal: models.RoundMoney(float64(n) * 2.5)}}
+	}
+	stock := func() (quantity, reserved int) {
+		got, err := r.Inventory.GetByID(ctx, b.TenantID, item.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return got.Quantity, got.Reserved
+	}
+	order := func(status string, n int) *models.Order {
+		return &models.Order{TenantID: b.TenantID, OrderNumber: "RSV-" + uuid.NewString()[:8], Status: status, Type: "standard", Lines: line(n)}
+	}
+
+	o := order(models.OrderPending, 4)
+	if err := r.Order.Create(ctx, o); err != nil {
+		t.Fatal(err)
+	}
+	if o.TotalAmount == nil || *o.TotalAmount != 10 || len(o.Lines) != 1 || o.Lines[0].ReservedQuantity != 4 {
+		t.Errorf("created order total %v, lines %+v; want 10 with 4 reserved", o.TotalAmount, o.Lines)
+	}
+	if q, res := stock(); q != 10 || res != 4 {
+		t.Errorf("after reserving 4: quantity %d, reserved %d", q, res)
+	}
+
+	if err := r.Order.Create(ctx, order(models.OrderPending, 7)); !errors.Is(err, repository.ErrInsufficientStock) {
+		t.Errorf("overselling returned %v, want ErrInsufficientStock", err)
+	}
+	scheduled := order(models.OrderScheduled, 7)
+	if err := r.Order.Create(ctx, scheduled); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Order.Confirm(ctx, b.TenantID, scheduled.ID); !errors.Is(err, repository.ErrInsufficientStock) {
+		t.Errorf("confirming past the stock returned %v, want ErrInsufficientStock", err)
+	}
+	if _, res := stock(); res != 4 {
+		t.Errorf("failed reservations left %d reserved, want 4", res)
+	}
+
+	if err := r.Order.CancelOrder(ctx, b.TenantID, o.ID, "changed mind"); err != nil {
+		t.Fatal(err)
+	}
+	if q, res := stock(); q != 10 || res != 0 {
+		t.Errorf("after cancelling: quantity %d, reserved %d", q, res)
+	}
+	if err := r.Order.Update(ctx, b.TenantID, o.ID, &models.Order{OrderNumber: o.OrderNumber, Status: models.OrderPending, Type: "standard"}); err == nil {
+		t.Error("reopened a cancelled order with lines")
+	}
+
+	if err := r.Order.Confirm(ctx, b.TenantID, scheduled.ID); err != nil {
+		t.Fatal(err)
+	}
+	scheduled.Status = models.OrderShipped
+	scheduled.Lines = nil
+	if err := r.Order.Update(ctx, b.TenantID, scheduled.ID, scheduled); err != nil {
+		t.Fatal(err)
+	}
+	if q, res := stock(); q != 3 || res != 0 {
+		t.Errorf("after shipping 7: quantity %d, reserved %d", q, res)
+	}
+	if err := r.Order.ReturnOrder(ctx, b.TenantID, scheduled.ID, "damaged"); err != nil {
+		t.Fatal(err)
+	}
+	if q, res := stock(); q != 10 || res != 0 {
+		t.Errorf("after returning 7: quantity %d, reserved %d", q, res)
+	}
+
+	// Ten orders race for the ten units, two at a time: five must win.
+	errs := make(chan error, 10)
+	for i := 0; i < 10; i++ {
+		go func() { errs <- r.Order.Create(ctx, order(models.OrderPending, 2)) }()
+	}
+	won := 0
+	for i := 0; i < 10; i++ {
+		err := <-errs
+		switch {
+		case err == nil:
+			won++
+		case !errors.Is(err, repository.ErrInsufficientStock):
+			t.Errorf("concurrent reservation: %v", err)
+		}
+	}
+	if q, res := stock(); won != 5 || q != 10 || res != 10 {
+		t.Errorf("%d concurrent orders won; quantity %d, reserved %d; want 5 wins and all 10 reserved", won, q, res)
+	}
+
+	a := env.a
+	if err := r.Order.Create(tenantCtx(context.Background(), a.TenantID), &models.Order{TenantID: a.TenantID, OrderNumber: "RSV-" + tag, Status: models.OrderScheduled, Type: "standard", Lines: line(1)}); err == nil {
+		t.Error("reserved another tenant's inventory item")
+	}
+}
+
+// TestStaleJobsAreBuriedAfterTheirLastAttempt leaves claimed jobs running as
+// if their worker had crashed: one with attempts left is reclaimed, one that
+// has used them all is buried instead of being picked up forever.
+func TestStaleJobsAreBuriedAfterTheirLastAttempt(t *testing.T) {
+	b := env.b
+	r := env.repos
+	ctx := context.Background()
+	pctx := database.Privileged(ctx)
+	typ := "test.stale-" + uuid.NewString()[:8]
+	t.Cleanup(func() { env.pool.Exec(pctx, `DELETE FROM jobs WHERE type = $1`, typ) })
+
+	once := &models.Job{TenantID: &b.TenantID, Type: typ, MaxAttempts: 1, RunAt: time.Now().Add(-time.Minute)}
+	twice := &models.Job{TenantID: &b.TenantID, Type: typ, MaxAttempts: 2, RunAt: time.Now().Add(-time.Minute)}
+	for _, j := range []*models.Job{once, twice} {
+		if _, err := r.Job.Enqueue(ctx, j); err != nil {
+			t.Fatal(err)
+		}
+	}
+	crash := func() {
+		t.Helper()
+		if _, err := env.pool.Exec(pctx, `UPDATE jobs SET locked_at = NOW() - INTERVAL '2 hours' WHERE type = $1 AND status = 'running'`, typ); err != nil {
+			t.Fatal(err)
+		}
+	}
+	claim := func(worker string) []uuid.UUID {
+		t.Helper()
+		jobs, err := r.Job.ClaimDue(ctx, worker, []string{typ}, 10, time.Hour)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var ids []uuid.UUID
+		for _, j := range jobs {
+			ids = append(ids, j.ID)
+		}
+		return ids
+	}
+	status := func(id uuid.UUID) string {
+		t.Helper()
+		var s string
+		if err := env.pool.QueryRow(pctx, `SELECT status FROM jobs WHERE id = $1`, id).Scan(&s); err != nil {
+			t.Fatal(err)
+		}
+		return s
+	}
+
+	if ids := claim("worker-1"); len(ids) != 2 {
+		t.Fatalf("claimed %v, want both jobs", ids)
+	}
+	crash()
+	if ids := claim("worker-2"); len(ids) != 1 || ids[0] != twice.ID {
+		t.Fatalf("reclaimed %v, want only the job with an attempt left", ids)
+	}
+	if s := status(once.ID); s != "dead" {
+		t.Errorf("exhausted stale job is %s, want dead", s)
+	}
+	crash()
+	if ids := claim("worker-3"); len(ids) != 0 {
+		t.Errorf("reclaimed %v after the last attempt", ids)
+	}
+	if s := status(twice.ID); s != "dead" {
+		t.Errorf("exhausted stale job is %s, want dead", s)
+	}
+}
@@ -0,0 +1,725 @@
+package integration
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"cargomax-api/internal/auth"
+	"cargomax-api/internal/database"
+	"cargomax-api/internal/middleware"
+	"cargomax-api/internal/models"
+	"cargomax-api/internal/rest"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+)
+
+// newRESTServer mounts the driver, manager and public APIs the way cmd/server does.
+func newRESTServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	r := env.repos
+	hub := rest.NewHub(env.cfg)
+	go hub.Run()
+
+	router := chi.NewRouter()
+	router.Mount("/api/v1", rest.NewTrackingHandler(env.cfg, r.Driver, r.Vehicle, r.Shift, r.Ping, r.Alert, r.Zone, r.Shipment, env.store, hub).Routes())
+	router.Mount("/api/v1/manager", rest.NewManagerHandler(env.cfg, r.Driver, r.Vehicle, r.Shift, r.Ping, r.Alert, r.Zone, r.Shipment, r.Warehouse, r.Tenant, r.Import, r.Invoice, env.store).Routes())
+	router.Mount("/api/v1/public", rest.NewPublicTrackingHandler(r.Shipment, middleware.NewRateLimiter(100, time.Minute)).Routes())
+	srv := httptest.NewServer(router)
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func managerToken(t *testing.T, f *fixture) string {
+	t.Helper()
+	token, err := auth.CreateAccessToken(env.cfg.JWTPrivateKey, f.Admin.ID, f.TenantID, f.Admin.Email, f.Admin.Role)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return token
+}
+
+func driverToken(t *testing.T, f *fixture) string {
+	t.Helper()
+	token, err := auth.CreateAccessToken(env.cfg.JWTPrivateKey, f.Driver.ID, f.TenantID, "", "driver")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return token
+}
+
+// call performs a JSON request and decodes the response body into a map.
+func call(t *testing.T, srv *httptest.Server, method, path, token string, body any) (int, map[string]any) {
+	t.Helper()
+	var buf bytes.Buffer
+	if body != nil {
+		if err := json.NewEncoder(&buf).Encode(body); err != nil {
+			t.Fatal(err)
+		}
+	}
+	req, err := http.NewRequest(method, srv.URL+path, &buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	resp, err := srv.Client().Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	out := map[string]any{}
+	_ = json.NewDecoder(resp.Body).Decode(&out)
+	return resp.StatusCode, out
+}
+
+// idsIn collects the given key from every object in out[listKey].
+func idsIn(out map[string]any, listKey, idKey string) []string {
+	var ids []string
+	list, _ := out[listKey].([]any)
+	for _, it := range list {
+		if m, ok := it.(map[string]any); ok {
+			if id, ok := m[idKey].(string); ok {
+				ids = append(ids, id)
+			}
+		}
+	}
+	return ids
+}
+
+func assertAbsent(t *testing.T, what string, ids []string, foreign uuid.UUID) {
+	t.Helper()
+	for _, id := range ids {
+		if id == foreign.String() {
+			t.Errorf("%s contains tenant A's %s", what, foreign)
+		}
+	}
+}
+
+func TestDriverLoginUsesPrivilegedLookup(t *testing.T) {
+	srv := newRESTServer(t)
+	a := env.a
+
+	status, out := call(t, srv, http.MethodPost, "/api/v1/auth/driver-login", "", map[string]string{"phone": *a.Driver.Phone, "pin": driverPin})
+	if status != http.StatusOK {
+		t.Fatalf("driver-login status %d: %v", status, out)
+	}
+	token, _ := out["token"].(string)
+	claims, err := auth.ValidateToken(env.cfg.JWTPublicKey, token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if claims.TenantID != a.TenantID || claims.UserID != a.Driver.ID {
+		t.Errorf("token issued for tenant %s user %s", claims.TenantID, claims.UserID)
+	}
+
+	if status, _ := call(t, srv, http.MethodPost, "/api/v1/auth/driver-login", "", map[string]string{"phone": *a.Driver.Phone, "pin": "0000"}); status != http.StatusUnauthorized {
+		t.Errorf("wrong PIN returned %d", status)
+	}
+}
+
+func TestDriverAPIIsTenantScoped(t *testing.T) {
+	srv := newRESTServer(t)
+	a, b := env.a, env.b
+	token := driverToken(t, b)
+
+	status, out := call(t, srv, http.MethodGet, "/api/v1/driver/trucks", token, nil)
+	if status != http.StatusOK {
+		t.Fatalf("trucks status %d", status)
+	}
+	assertAbsent(t, "truck list", idsIn(out, "trucks", "id"), a.Vehicle.ID)
+
+	status, _ = call(t, srv, http.MethodPost, "/api/v1/shifts/start", token, map[string]string{"truck_id": a.Vehicle.ID.String()})
+	if status != http.StatusNotFound {
+		t.Errorf("starting a shift on tenant A's truck returned %d, want 404", status)
+	}
+
+	if status, _ := call(t, srv, http.MethodGet, "/api/v1/manager/alerts", token, nil); status != http.StatusForbidden {
+		t.Errorf("driver token on manager API returned %d, want 403", status)
+	}
+}
+
+func TestManagerAPIIsTenantScoped(t *testing.T) {
+	srv := newRESTServer(t)
+	a, b := env.a, env.b
+	token := managerToken(t, b)
+
+	status, out := call(t, srv, http.MethodGet, "/api/v1/manager/tracking/live", token, nil)
+	if status != http.StatusOK {
+		t.Fatalf("live status %d", status)
+	}
+	assertAbsent(t, "live positions", idsIn(out, "positions", "driver_id"), a.Driver.ID)
+
+	status, out = call(t, srv, http.MethodGet, "/api/v1/manager/shifts/active", token, nil)
+	if status != http.StatusOK {
+		t.Fatalf("active shifts status %d", status)
+	}
+	assertAbsent(t, "active shifts", idsIn(out, "shifts", "shift_id"), a.Shift.ID)
+
+	status, out = call(t, srv, http.MethodGet, "/api/v1/manager/zones", token, nil)
+	if status != http.StatusOK {
+		t.Fatalf("zones status %d", status)
+	}
+	assertAbsent(t, "zones", idsIn(out, "zones", "id"), a.Zone.ID)
+
+	alertPath := "/api/v1/manager/alerts/" + a.Alert.ID.String()
+	if status, _ := call(t, srv, http.MethodGet, alertPath, token, nil); status != http.StatusNotFound {
+		t.Errorf("reading tenant A's alert returned %d, want 404", status)
+	}
+	for _, action := range []string{"/acknowledge", "/resolve", "/false-alarm"} {
+		call(t, srv, http.MethodPut, alertPath+action, token, map[string]string{"notes": "pwned"})
+	}
+	if env.count(t, `SELECT COUNT(*) FROM alerts WHERE id = $1 AND status = 'triggered'`, a.Alert.ID) != 1 {
+		t.Error("tenant B changed tenant A's alert status")
+	}
+
+	zonePath := "/api/v1/manager/zones/" + a.Zone.ID.String()
+	call(t, srv, http.MethodPut, zonePath, token, map[string]any{"label": "pwned", "latitude": 0, "longitude": 0, "radius_meters": 99999, "type": "custom"})
+	call(t, srv, http.MethodDelete, zonePath, token, nil)
+	if env.count(t, `SELECT COUNT(*) FROM approved_zones WHERE id = $1 AND label = 'Fixture Depot'`, a.Zone.ID) != 1 {
+		t.Error("tenant B changed or deleted tenant A's zone")
+	}
+
+	// Updating the alert config only ever touches the caller's own row.
+	call(t, srv, http.MethodPut, "/api/v1/manager/alerts/config", token, map[string]any{"max_stop_duration_minutes": 1})
+	if env.count(t, `SELECT COUNT(*) FROM alert_config WHERE tenant_id = $1 AND max_stop_duration_minutes = 7`, a.TenantID) != 1 {
+		t.Error("tenant B's alert config update reached tenant A")
+	}
+}
+
+func TestRESTRejectsMissingOrForeignSignedTokens(t *testing.T) {
+	srv := newRESTServer(t)
+	if status, _ := call(t, srv, http.MethodGet, "/api/v1/manager/zones", "", nil); status != http.StatusUnauthorized {
+		t.Errorf("no token returned %d, want 401", status)
+	}
+	forged := managerToken(t, env.b)
+	forged = forged[:strings.LastIndex(forged, ".")+1] + "AAAA"
+	if status, _ := call(t, srv, http.MethodGet, "/api/v1/manager/zones", forged, nil); status != http.StatusUnauthorized {
+		t.Errorf("bad signature returned %d, want 401", status)
+	}
+}
+
+// TestDriverShipmentsAPI lists a driver's loads, posts pickup and delivery
+// updates for one of them, and checks another tenant's shipment cannot be
+// listed or updated.
+func TestDriverShipmentsAPI(t *testing.T) {
+	srv := newRESTServer(t)
+	a, b := env.a, env.b
+	ctx := tenantCtx(context.Background(), b.TenantID)
+	token := driverToken(t, b)
+
+	s := &models.Shipment{TenantID: b.TenantID, TrackingNumber: "DRV-" + uuid.NewString()[:8], Status: models.ShipmentPending}
+	if err := env.repos.Shipment.Create(ctx, s, nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := env.repos.Shipment.Assign(ctx, b.TenantID, &models.ShipmentAssignment{ShipmentID: s.ID, DriverID: &b.Driver.ID}); err != nil {
+		t.Fatal(err)
+	}
+
+	status, out := call(t, srv, http.MethodGet, "/api/v1/driver/shipments", token, nil)
+	if status != http.StatusOK {
+		t.Fatalf("driver shipments status %d", status)
+	}
+	ids := idsIn(out, "shipments", "id")
+	assertAbsent(t, "driver shipments", ids, a.Shipment.ID)
+	if !strings.Contains(strings.Join(ids, " "), s.ID.String()) {
+		t.Fatalf("assigned shipment missing from %v", ids)
+	}
+
+	path := "/api/v1/driver/shipments/" + s.ID.String() + "/status"
+	if status, out := call(t, srv, http.MethodPost, path, token, map[string]string{"status": "out_for_delivery"}); status != http.StatusConflict {
+		t.Errorf("pending -> out_for_delivery returned %d: %v", status, out)
+	}
+	if status, _ := call(t, srv, http.MethodPost, path, token, map[string]string{"status": "cancelled"}); status != http.StatusBadRequest {
+		t.Errorf("driver cancelling a shipment returned %d, want 400", status)
+	}
+	for _, next := range []string{"picked_up", "in_transit", "out_for_delivery"} {
+		if status, out := call(t, srv, http.MethodPost, path, token, map[string]string{"status": next, "location": "Fixture Street"}); status != http.StatusOK {
+			t.Fatalf("%s returned %d: %v", next, status, out)
+		}
+	}
+	if env.count(t, `SELECT COUNT(*) FROM shipment_events WHERE shipment_id = $1 AND actor_type = 'driver' AND actor_id = $2`, s.ID, b.Driver.ID) != 3 {
+		t.Error("driver updates were not recorded as driver events")
+	}
+	// Drivers deliver with a proof of delivery, not a bare status change.
+	if status, _ := call(t, srv, http.MethodPost, path, token, map[string]string{"status": "delivered"}); status != http.StatusBadRequest {
+		t.Errorf("driver delivering without proof returned %d, want 400", status)
+	}
+
+	foreign := "/api/v1/driver/shipments/" + a.Shipment.ID.String() + "/status"
+	if status, _ := call(t, srv, http.MethodPost, foreign, token, map[string]string{"status": "delayed"}); status != http.StatusNotFound {
+		t.Errorf("updating tenant A's shipment returned %d, want 404", status)
+	}
+	if env.count(t, `SELECT COUNT(*) FROM shipments WHERE id = $1 AND status = 'in_transit'`, a.Shipment.ID) != 1 {
+		t.Error("tenant B's driver changed tenant A's shipment")
+	}
+}
+
+// podUpload is one file part of a proof-of-delivery request.
+type podUpload struct {
+	field, name string
+	data        []byte
+}
+
+var (
+	pngBytes  = append([]byte("\x89PNG\r\n\x1a\n"), make([]byte, 64)...)
+	jpegBytes = append([]byte("\xff\xd8\xff\xe0"), make([]byte, 64)...)
+)
+
+// submitPOD posts a multipart proof of delivery for shipmentID.
+func submitPOD(t *testing.T, srv *httptest.Server, token string, shipmentID uuid.UUID, recipient string, files ...podUpload) (int, map[string]any) {
+	t.Helper()
+	var buf bytes.Buffer
+	mw := multipart.NewWriter(&buf)
+	if recipient != "" {
+		_ = mw.WriteField("recipient_name", recipient)
+	}
+	for _, f := range files {
+		part, err := mw.CreateFormFile(f.field, f.name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		part.Write(f.data)
+	}
+	mw.Close()
+
+	req, err := http.NewRequest(http.MethodPost, srv.URL+"/api/v1/driver/shipments/"+shipmentID.String()+"/pod", &buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+	req.Header.Set("Authorization", "Bearer "+token)
+	resp, err := srv.Client().Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	out := map[string]any{}
+	_ = json.NewDecoder(resp.Body).Decode(&out)
+	return resp.StatusCode, out
+}
+
+// TestProofOfDeliveryAPI delivers a shipment from the driver app: outside the
+// destination geofence, without a signature or for another tenant's load the
+// delivery is refused; inside it the shipment is delivered with its POD, which
+// the tenant's managers can read back through GraphQL and tenant A cannot.
+func TestProofOfDeliveryAPI(t *testing.T) {
+	srv := newRESTServer(t)
+	a, b := env.a, env.b
+	ctx := tenantCtx(context.Background(), b.TenantID)
+	r := env.repos
+	tag := uuid.NewString()[:8]
+
+	truck := &models.Vehicle{TenantID: b.TenantID, VehicleID: "POD-" + tag, Status: "active"}
+	if err := r.Vehicle.Create(ctx, truck); err != nil {
+		t.Fatal(err)
+	}
+	driver := &models.Driver{TenantID: b.TenantID, EmployeeID: "POD-" + tag, FirstName: str("Proof"), LastName: str("Driver"), Status: "available"}
+	if err := r.Driver.Create(ctx, driver); err != nil {
+		t.Fatal(err)
+	}
+	shift := &models.Shift{TenantID: b.TenantID, DriverID: driver.ID, TruckID: truck.ID}
+	if err := r.Shift.Create(ctx, shift); err != nil {
+		t.Fatal(err)
+	}
+	token, err := auth.CreateAccessToken(env.cfg.JWTPrivateKey, driver.ID, b.TenantID, "", "driver")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ship := func(name string, lat, lng *float64) *models.Shipment {
+		t.Helper()
+		s := &models.Shipment{TenantID: b.TenantID, TrackingNumber: "POD-" + name + "-" + tag, Status: models.ShipmentOutForDelivery, DestinationLatitude: lat, DestinationLongitude: lng}
+		if err := r.Shipment.Create(ctx, s, nil); err != nil {
+			t.Fatal(err)
+		}
+		if err := r.Shipment.Assign(ctx, b.TenantID, &models.ShipmentAssignment{ShipmentID: s.ID, DriverID: &driver.ID}); err != nil {
+			t.Fatal(err)
+		}
+		return s
+	}
+	s, noCoords := ship("geo", ptr(41.0), ptr(-75.0)), ship("nogeo", nil, nil)
+	ping := func(lat float64) {
+		t.Helper()
+		if _, err := r.Ping.BulkInsert(ctx, []models.GPSPing{{TenantID: b.TenantID, DriverID: driver.ID, TruckID: truck.ID, ShiftID: shift.ID, Latitude: lat, Longitude: -75.0, Accuracy: 8, RecordedAt: time.Now(), ReceivedAt: time.Now()}}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	signature := podUpload{"signature", "sig.png", pngBytes}
+	photo := podUpload{"photos", "door.jpg", jpegBytes}
+
+	// About 11 km short of the destination.
+	ping(40.9)
+	if status, out := submitPOD(t, srv, token, s.ID, "Jane Doe", signature); status != http.StatusUnprocessableEntity || out["distance_m"] == nil {
+		t.Errorf("delivery outside the geofence returned %d: %v", status, out)
+	}
+
+	// About 110 m away.
+	ping(41.001)
+	if status, _ := submitPOD(t, srv, token, s.ID, "Jane Doe"); status != http.StatusBadRequest {
+		t.Errorf("delivery without a signature returned %d, want 400", status)
+	}
+	if status, _ := submitPOD(t, srv, token, s.ID, "Jane Doe", podUpload{"signature", "sig.png", []byte("not an image")}); status != http.StatusBadRequest {
+		t.Errorf("non-image signature returned %d, want 400", status)
+	}
+	if status, _ := submitPOD(t, srv, token, noCoords.ID, "Jane Doe", signature); status != http.StatusConflict {
+		t.Errorf("delivery without destination coordinates returned %d, want 409", status)
+	}
+	if status, _ := submitPOD(t, srv, token, a.Delivered.ID, "Jane Doe", signature); status != http.StatusNotFound {
+		t.Errorf("delivering tenant A's shipment returned %d, want 404", status)
+	}
+	if n := env.count(t, `SELECT COUNT(*) FROM proof_of_delivery WHERE shipment_id = ANY($1)`, []uuid.UUID{s.ID, noCoords.ID}); n != 0 {
+		t.Fatalf("refused deliveries left %d proofs", n)
+	}
+
+	status, out := submitPOD(t, srv, token, s.ID, "Jane Doe", signature, photo)
+	if status != http.StatusCreated || out["status"] != models.ShipmentDelivered {
+		t.Fatalf("delivery returned %d: %v", status, out)
+	}
+	if env.count(t, `SELECT COUNT(*) FROM shipments WHERE id = $1 AND actual_delivery IS NOT NULL`, s.ID) != 1 {
+		t.Error("delivery did not stamp actual_delivery")
+	}
+	if env.count(t, `SELECT COUNT(*) FROM shipment_events WHERE shipment_id = $1 AND status = 'delivered' AND actor_type = 'driver' AND note LIKE '%Jane Doe%'`, s.ID) != 1 {
+		t.Error("delivery was not recorded on the timeline with the recipient")
+	}
+	if status, _ := submitPOD(t, srv, token, s.ID, "Someone Else", signature); status != http.StatusConflict {
+		t.Errorf("second delivery returned %d, want 409", status)
+	}
+	if n := env.count(t, `SELECT COUNT(*) FROM proof_of_delivery_files f JOIN proof_of_delivery p ON p.id = f.pod_id WHERE p.shipment_id = $1`, s.ID); n != 2 {
+		t.Errorf("delivered shipment has %d POD files, want 2", n)
+	}
+
+	schema := newSchema(t)
+	q := fmt.Sprintf(`{ shipment(id: %q) { proofOfDelivery { recipientName distanceMeters signature { dataUrl } photos { contentType } } } }`, s.ID)
+	res := execGraphQL(schema, userCtx(b), q)
+	if len(res.Errors) > 0 {
+		t.Fatal(res.Errors)
+	}
+	body, _ := json.Marshal(res.Data)
+	for _, want := range []string{`"recipientName":"Jane Doe"`, `"dataUrl":"data:image/png;base64,`, `"contentType":"image/jpeg"`} {
+		if !strings.Contains(string(body), want) {
+			t.Errorf("proofOfDelivery %s lacks %s", body, want)
+		}
+	}
+	if foreign := execGraphQL(schema, userCtx(a), q); strings.Contains(fmt.Sprint(foreign.Data), "Jane Doe") {
+		t.Errorf("tenant A read tenant B's proof of delivery: %v", foreign.Data)
+	}
+}
+
+// TestPublicTrackingIsVerifiedAndRedacted gives both tenants a shipment with
+// the same tracking number and checks the verifier picks the right one, a
+// wrong verifier looks exactly like an unknown number, and the response never
+// carries customer details, notes, tenant ids or the street address.
+func TestPublicTrackingIsVerifiedAndRedacted(t *testing.T) {
+	srv := newRESTServer(t)
+	a, b := env.a, env.b
+	tn := "PUB-" + uuid.NewString()[:8]
+
+	for _, s := range []*models.Shipment{
+		{TenantID: a.TenantID, TrackingNumber: tn, Status: models.ShipmentPending, Destination: str("233 S Wacker Dr, Chicago, IL 60601"), CustomerEmail: str("alice@example.com"), Notes: str("internal note a"),
+			DestinationAddress: &models.Address{Line1: "233 S Wacker Dr", City: "Chicago", Region: "IL", PostalCode: "60601", Country: "US"}},
+		{TenantID: b.TenantID, TrackingNumber: tn, Status: models.ShipmentPending, Destination: str("10 Downing St, London SW1A 1AA"), CustomerEmail: str("bob@example.com"), Notes: str("internal note b"),
+			DestinationAddress: &models.Address{Line1: "10 Downing St", City: "London", PostalCode: "SW1A 1AA", Country: "GB"}},
+	} {
+		if err := env.repos.Shipment.Create(tenantCtx(context.Background(), s.TenantID), s, nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for verifier, dest := range map[string]string{"60601": "Chicago, IL", "alice": "Chicago, IL", "sw1a 1aa": "London", "BOB@example.com": "London"} {
+		status, out := call(t, srv, http.MethodPost, "/api/v1/public/track", "", map[string]string{"tracking_number": tn, "verifier": verifier})
+		if status != http.StatusOK {
+			t.Fatalf("verifier %q returned %d: %v", verifier, status, out)
+		}
+		if out["destination"] != dest {
+			t.Errorf("verifier %q resolved the shipment bound for %v", verifier, out["destination"])
+		}
+		for _, k := range []string{"id", "tenant_id", "customer_email", "customer_name", "notes"} {
+			if _, ok := out[k]; ok {
+				t.Errorf("public tracking exposed %s", k)
+			}
+		}
+		if _, ok := out["predicted_late"]; !ok {
+			t.Error("public tracking left out the live ETA")
+		}
+		if events, _ := out["events"].([]any); len(events) != 1 {
+			t.Errorf("expected the creation event on the timeline, got %v", out["events"])
+		}
+	}
+
+	_, wrong := call(t, srv, http.MethodPost, "/api/v1/public/track", "", map[string]string{"tracking_number": tn, "verifier": "example.com"})
+	status, unknown := call(t, srv, http.MethodPost, "/api/v1/public/track", "", map[string]string{"tracking_number": tn + "X", "verifier": "60601"})
+	if status != http.StatusNotFound || fmt.Sprint(wrong) != fmt.Sprint(unknown) {
+		t.Errorf("wrong verifier %v and unknown number %v (%d) are distinguishable", wrong, unknown, status)
+	}
+}
+
+// TestPublicTrackingIsThrottledPerIP exhausts a small limiter and expects 429.
+func TestPublicTrackingIsThrottledPerIP(t *testing.T) {
+	router := chi.NewRouter()
+	router.Mount("/api/v1/public", rest.NewPublicTrackingHandler(env.repos.Shipment, middleware.NewRateLimiter(3, time.Minute)).Routes())
+	srv := httptest.NewServer(router)
+	t.Cleanup(srv.Close)
+
+	body := map[string]string{"tracking_number": env.a.Shipment.TrackingNumber, "verifier": "guess"}
+	for i := 0; i < 3; i++ {
+		if status, _ := call(t, srv, http.MethodPost, "/api/v1/public/track", "", body); status != http.StatusNotFound {
+			t.Fatalf("request %d returned %d, want 404", i+1, status)
+		}
+	}
+	if status, _ := call(t, srv, http.MethodPost, "/api/v1/public/track", "", body); status != http.StatusTooManyRequests {
+		t.Errorf("fourth request returned %d, want 429", status)
+	}
+}
+
+// fetch performs a GET and returns the raw response.
+func fetch(t *testing.T, srv *httptest.Server, path, token string) (int, http.Header, []byte) {
+	t.Helper()
+	req, err := http.NewRequest(http.MethodGet, srv.URL+path, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	resp, err := srv.Client().Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return resp.StatusCode, resp.Header, body
+}
+
+func TestShippingLabelsAPI(t *testing.T) {
+	srv := newRESTServer(t)
+	a, b := env.a, env.b
+	token := managerToken(t, a)
+	labelPath := "/api/v1/manager/shipments/" + a.Shipment.ID.String() + "/label"
+	batchPath := "/api/v1/manager/warehouses/" + a.Warehouse.ID.String() + "/labels"
+
+	status, header, body := fetch(t, srv, labelPath, token)
+	if status != http.StatusOK || header.Get("Content-Type") != "application/pdf" {
+		t.Fatalf("pdf label: status %d, type %q", status, header.Get("Content-Type"))
+	}
+	if !bytes.HasPrefix(body, []byte("%PDF-")) || !bytes.Contains(body, []byte("/Count 1 ")) {
+		t.Errorf("pdf label is not a one-page PDF")
+	}
+
+	status, _, body = fetch(t, srv, labelPath+"?format=zpl", token)
+	if status != http.StatusOK {
+		t.Fatalf("zpl label: status %d", status)
+	}
+	for _, want := range []string{"^XA", "^BCN", "^BQN", a.Shipment.TrackingNumber, "^XZ"} {
+		if !bytes.Contains(body, []byte(want)) {
+			t.Errorf("zpl label lacks %q", want)
+		}
+	}
+	if status, _, _ := fetch(t, srv, labelPath+"?format=png", token); status != http.StatusBadRequest {
+		t.Errorf("unknown format returned %d, want 400", status)
+	}
+
+	// The fixture route leaves the fixture warehouse today with the fixture
+	// shipment on it, so the batch has at least that page.
+	outgoing, err := env.repos.Shipment.ListOutgoing(tenantCtx(context.Background(), a.TenantID), a.TenantID, a.Warehouse.ID, time.Now().UTC().Format("2006-01-02"))
+	if err != nil || len(outgoing) == 0 {
+		t.Fatalf("outgoing shipments: %d, %v", len(outgoing), err)
+	}
+	status, _, body = fetch(t, srv, batchPath, token)
+	if status != http.StatusOK || !bytes.Contains(body, []byte(fmt.Sprintf("/Count %d ", len(outgoing)))) {
+		t.Errorf("batch: status %d, want a %d-page PDF", status, len(outgoing))
+	}
+	if status, _, _ := fetch(t, srv, batchPath+"?date=2001-01-01", token); status != http.StatusNotFound {
+		t.Errorf("batch for a day without shipments returned %d, want 404", status)
+	}
+
+	foreign := managerToken(t, b)
+	if status, _, _ := fetch(t, srv, labelPath, foreign); status != http.StatusNotFound {
+		t.Errorf("tenant B read tenant A's label: status %d", status)
+	}
+	if status, _, _ := fetch(t, srv, batchPath, foreign); status != http.StatusNotFound {
+		t.Errorf("tenant B read tenant A's warehouse labels: status %d", status)
+	}
+}
+
+// uploadImport posts an import file for entity.
+func uploadImport(t *testing.T, srv *httptest.Server, token, entity, name string, data []byte) (int, map[string]any) {
+	t.Helper()
+	var buf bytes.Buffer
+	mw := multipart.NewWriter(&buf)
+	_ = mw.WriteField("entity", entity)
+	part, err := mw.CreateFormFile("file", name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	part.Write(data)
+	mw.Close()
+
+	req, err := http.NewRequest(http.MethodPost, srv.URL+"/api/v1/manager/imports", &buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+	req.Header.Set("Authorization", "Bearer "+token)
+	resp, err := srv.Client().Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	out := map[string]any{}
+	_ = json.NewDecoder(resp.Body).Decode(&out)
+	return resp.StatusCode, out
+}
+
+// TestBulkImport uploads vehicle and inventory files, dry-runs them in each
+// mode and commits them: a file with any invalid row imports nothing, upsert
+// updates existing records by key, and warehouses are found by name within
+// the tenant only.
+func TestBulkImport(t *testing.T) {
+	srv := newRESTServer(t)
+	schema := newSchema(t)
+	a, b := env.a, env.b
+	ctx := userCtx(b)
+	r := env.repos
+	tag := strings.ToUpper(uuid.NewString()[:6])
+
+	existing := &models.Vehicle{TenantID: b.TenantID, VehicleID: "IMP-" + tag + "-1", Status: "available", Mileage: 10}
+	if err := r.Vehicle.Create(tenantCtx(context.Background(), b.TenantID), existing); err != nil {
+		t.Fatal(err)
+	}
+
+	if status, _ := uploadImport(t, srv, managerToken(t, b), "vehicles", "fleet.txt", []byte("x")); status != http.StatusBadRequest {
+		t.Errorf("uploaded a .txt file: status %d", status)
+	}
+	if status, _ := uploadImport(t, srv, managerToken(t, b), "invoices", "fleet.csv", []byte("a\n1\n")); status != http.StatusBadRequest {
+		t.Errorf("uploaded an unknown entity: status %d", status)
+	}
+
+	csv := fmt.Sprintf("Fleet Number;Name;Odometer;Year\nIMP-%[1]s-1;Old truck;2500;2019\nIMP-%[1]s-2;New truck;0;20x9\nIMP-%[1]s-3;Van;;2021\n", tag)
+	status, batch := uploadImport(t, srv, managerToken(t, b), "vehicles", "fleet.csv", []byte(csv))
+	if status != http.StatusCreated {
+		t.Fatalf("upload: status %d, %v", status, batch)
+	}
+	if batch["total_rows"] != float64(3) || len(batch["mapping"].([]any)) != 4 {
+		t.Fatalf("upload did not map every column: %v", batch)
+	}
+	id := batch["id"].(string)
+
+	run := func(q string) map[string]interface{} {
+		t.Helper()
+		res := execGraphQL(schema, ctx, q)
+		if len(res.Errors) > 0 {
+			t.Fatalf("%s: %v", q, res.Errors)
+		}
+		return res.Data.(map[string]interface{})
+	}
+	const fields = `{ status created updated skipped failed error errors { row field message } }`
+
+	got := run(fmt.Sprintf(`mutation { validateImport(id: %q) %s }`, id, fields))["validateImport"].(map[string]interface{})
+	if got["status"] != models.ImportValidated || got["created"] != 1 || got["failed"] != 2 {
+		t.Errorf("insert dry run: %v", got)
+	}
+	errs := got["errors"].([]interface{})
+	if len(errs) != 2 || errs[0].(map[string]interface{})["row"] != 2 || errs[1].(map[string]interface{})["field"] != "year" {
+		t.Errorf("insert dry run errors: %v", errs)
+	}
+
+	got = run(fmt.Sprintf(`mutation { commitImport(id: %q, mode: "upsert") %s }`, id, fields))["commitImport"].(map[string]interface{})
+	if got["status"] != models.ImportFailed || got["error"] == nil || got["failed"] != 1 {
+		t.Errorf("commit with an invalid row: %v", got)
+	}
+	if n := env.count(t, `SELECT COUNT(*) FROM vehicles WHERE tenant_id = $1 AND vehicle_id LIKE $2`, b.TenantID, "IMP-"+tag+"%"); n != 1 {
+		t.Errorf("failed commit left %d vehicles, want the 1 that existed", n)
+	}
+
+	mapping := `[{ field: "vehicleId", column: "fleet number" }, { field: "name", column: "Name" }, { field: "mileage", column: "Odometer" }]`
+	got = run(fmt.Sprintf(`mutation { validateImport(id: %q, mode: "skip", mapping: %s) %s }`, id, mapping, fields))["validateImport"].(map[string]interface{})
+	if got["created"] != 2 || got["skipped"] != 1 || got["failed"] != 0 {
+		t.Errorf("skip dry run: %v", got)
+	}
+	got = run(fmt.Sprintf(`mutation { commitImport(id: %q, mode: "upsert", mapping: %s) %s }`, id, mapping, fields))["commitImport"].(map[string]interface{})
+	if got["status"] != models.ImportCompleted || got["created"] != 2 || got["updated"] != 1 {
+		t.Fatalf("upsert commit: %v", got)
+	}
+	updated, err := r.Vehicle.GetByID(tenantCtx(context.Background(), b.TenantID), b.TenantID, existing.ID)
+	if err != nil || updated.Mileage != 2500 || updated.Name == nil || *updated.Name != "Old truck" {
+		t.Errorf("upsert did not update the existing vehicle: %+v, %v", updated, err)
+	}
+	if n := env.count(t, `SELECT COUNT(*) FROM vehicles WHERE tenant_id = $1 AND vehicle_id LIKE $2`, b.TenantID, "IMP-"+tag+"%"); n != 3 {
+		t.Errorf("%d vehicles after the import, want 3", n)
+	}
+	if res := execGraphQL(schema, ctx, fmt.Sprintf(`mutation { commitImport(id: %q) { status } }`, id)); len(res.Errors) == 0 {
+		t.Error("committed a completed import twice")
+	}
+	if res := execGraphQL(schema, userCtx(a), fmt.Sprintf(`{ importBatch(id: %q) { id } }`, id)); len(res.Errors) == 0 {
+		t.Error("tenant A read tenant B's import")
+	}
+
+	// Inventory names its warehouse; tenant A's warehouse name is not one of
+	// tenant B's.
+	inv := fmt.Sprintf("sku,warehouse,qty,unit price\nIMP-%[1]s-A,%[2]s,5,1.50\nIMP-%[1]s-B,%[3]s,1,2\n", tag, strings.ToUpper(b.Warehouse.Name), a.Warehouse.Name)
+	status, batch = uploadImport(t, srv, managerToken(t, b), "inventory", "stock.csv", []byte(inv))
+	if status != http.StatusCreated {
+		t.Fatalf("inventory upload: status %d, %v", status, batch)
+	}
+	got = run(fmt.Sprintf(`mutation { validateImport(id: %q) %s }`, batch["id"], fields))["validateImport"].(map[string]interface{})
+	if got["created"] != 1 || got["failed"] != 1 {
+		t.Errorf("inventory dry run: %v", got)
+	}
+}
+
+func TestInvoicePDFAPI(t *testing.T) {
+	srv := newRESTServer(t)
+	a, b := env.a, env.b
+	pctx := database.Privileged(context.Background())
+	var clientID uuid.UUID
+	if err := env.pool.QueryRow(pctx,
+		`INSERT INTO clients (tenant_id, company_name, address) VALUES ($1, 'Pdf Client', '1 Quay Rd, Hamburg') RETURNING id`,
+		a.TenantID).Scan(&clientID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := env.pool.Exec(pctx,
+		`INSERT INTO orders (tenant_id, order_number, customer_name, status, client_id, total_amount) VALUES ($1, 'PDF-1', 'Dock 4', 'delivered', $2, 120)`,
+		a.TenantID, clientID); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		env.pool.Exec(pctx, `DELETE FROM orders WHERE tenant_id = $1 AND order_number = 'PDF-1'`, a.TenantID)
+		env.pool.Exec(pctx, `DELETE FROM invoices WHERE tenant_id = $1 AND client_id = $2`, a.TenantID, clientID)
+		env.pool.Exec(pctx, `DELETE FROM clients WHERE id = $1`, clientID)
+	})
+
+	today := time.Now().UTC().Truncate(24 * time.Hour)
+	run := models.InvoiceRun{ClientID: &clientID, PeriodStart: today.AddDate(0, 0, -1), PeriodEnd: today.AddDate(0, 0, 1), IssueDate: today}
+	invoices, err := env.repos.Invoice.Generate(tenantCtx(context.Background(), a.TenantID), a.TenantID, run, models.DefaultInvoiceSettings())
+	if err != nil || len(invoices) != 1 {
+		t.Fatalf("generate: %d invoices, %v", len(invoices), err)
+	}
+	path := "/api/v1/manager/invoices/" + invoices[0].ID.String() + "/pdf"
+
+	status, header, body := fetch(t, srv, path, managerToken(t, a))
+	if status != http.StatusOK || header.Get("Content-Type") != "application/pdf" {
+		t.Fatalf("invoice pdf: status %d, type %q", status, header.Get("Content-Type"))
+	}
+	if !bytes.HasPrefix(body, []byte("%PDF-")) || !strings.Contains(header.Get("Content-Disposition"), invoices[0].InvoiceNumber) {
+		t.Errorf("invoice pdf is not named %s or not a PDF", invoices[0].InvoiceNumber)
+	}
+	if status, _, _ := fetch(t, srv, path, managerToken(t, b)); status != http.StatusNotFound {
+		t.Errorf("tenant B fetched tenant A's invoice: status %d", status)
+	}
+}
@@ -0,0 +1,121 @@
+package integration
+
+import (
+	"context"
+	"testing"
+
+	"cargomax-api/internal/database"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// tenantTables lists every table protected by the tenant_isolation policy.
+var tenantTables = []string{
+	"users", "shipments", "shipment_events", "shipment_assignments", "vehicles", "drivers", "maintenance_records",
+	"warehouses", "inventory_items", "orders", "vendors", "clients",
+	"client_feedback", "notifications", "notification_preferences", "roles",
+	"settings", "activity_log", "gps_pings", "shifts", "alerts",
+	"approved_zones", "alert_config", "jobs", "shipment_etas", "lane_speeds",
+	"proof_of_delivery", "proof_of_delivery_files", "route_plans", "route_stops",
+	"tenant_sequences", "import_batches", "rate_cards", "invoices", "payments",
+	"order_lines", "fulfillments", "fulfillment_lines", "order_schedules",
+	"rmas", "rma_lines",
+	"stock_movements", "products", "transfer_orders", "transfer_order_lines",
+	"supplier_products", "purchase_orders", "purchase_order_lines",
+}
+
+// TestRLSHidesForeignRows queries every tenant table without a tenant_id
+// filter, the way a buggy repository would, and expects the policies to
+// return only the caller's rows.
+func TestRLSHidesForeignRows(t *testing.T) {
+	for _, table := range tenantTables {
+		t.Run(table, func(t *testing.T) {
+			q := "SELECT COUNT(*) FROM " + pgx.Identifier{table}.Sanitize()
+			for _, self := range []*fixture{env.a, env.b} {
+				var visible int
+				if err := env.pool.QueryRow(tenantCtx(context.Background(), self.TenantID), q).Scan(&visible); err != nil {
+					t.Fatalf("unfiltered count: %v", err)
+				}
+				own := env.count(t, q+" WHERE tenant_id = $1", self.TenantID)
+				if visible != own {
+					t.Errorf("tenant %s sees %d rows, owns %d", self.TenantID, visible, own)
+				}
+			}
+
+			var anonymous int
+			if err := env.pool.QueryRow(context.Background(), q).Scan(&anonymous); err != nil {
+				t.Fatalf("anonymous count: %v", err)
+			}
+			if anonymous != 0 {
+				t.Errorf("context without tenant sees %d rows", anonymous)
+			}
+		})
+	}
+}
+
+// TestRLSBlocksForeignWrites issues unfiltered UPDATE/DELETE statements and
+// an INSERT stamped with another tenant's id.
+func TestRLSBlocksForeignWrites(t *testing.T) {
+	ctxB := tenantCtx(context.Background(), env.b.TenantID)
+
+	ct, err := env.pool.Exec(ctxB, `UPDATE shipments SET notes = 'pwned' WHERE id = $1`, env.a.Shipment.ID)
+	if err != nil {
+		t.Fatalf("update: %v", err)
+	}
+	if ct.RowsAffected() != 0 {
+		t.Errorf("unfiltered UPDATE touched %d foreign rows", ct.RowsAffected())
+	}
+
+	ct, err = env.pool.Exec(ctxB, `DELETE FROM vendors WHERE id = $1`, env.a.Vendor.ID)
+	if err != nil {
+		t.Fatalf("delete: %v", err)
+	}
+	if ct.RowsAffected() != 0 {
+		t.Errorf("unfiltered DELETE removed %d foreign rows", ct.RowsAffected())
+	}
+
+	_, err = env.pool.Exec(ctxB,
+		`INSERT INTO vendors (id, tenant_id, name, status, created_at, updated_at) VALUES (gen_random_uuid(), $1, 'planted', 'active', NOW(), NOW())`,
+		env.a.TenantID,
+	)
+	if err == nil {
+		t.Error("INSERT with a foreign tenant_id succeeded")
+	}
+
+	// Moving an own row into another tenant must fail the WITH CHECK clause.
+	_, err = env.pool.Exec(ctxB, `UPDATE vendors SET tenant_id = $1 WHERE id = $2`, env.a.TenantID, env.b.Vendor.ID)
+	if err == nil {
+		t.Error("UPDATE re-parenting a row to a foreign tenant succeeded")
+	}
+}
+
+// TestRLSSessionDoesNotLeakBetweenCheckouts acquires connections as A, then
+// without a tenant, and checks the session variables were reset.
+func TestRLSSessionDoesNotLeakBetweenCheckouts(t *testing.T) {
+	for i := 0; i < 10; i++ {
+		if _, err := env.pool.Exec(tenantCtx(context.Background(), env.a.TenantID), `SELECT 1`); err != nil {
+			t.Fatal(err)
+		}
+		var n int
+		if err := env.pool.QueryRow(context.Background(), `SELECT COUNT(*) FROM shipments`).Scan(&n); err != nil {
+			t.Fatal(err)
+		}
+		if n != 0 {
+			t.Fatalf("checkout %d inherited a tenant: saw %d shipments", i, n)
+		}
+	}
+}
+
+// TestPrivilegedPathSeesAllTenants documents the bypass used by login and
+// background workers.
+func TestPrivilegedPathSeesAllTenants(t *testing.T) {
+	var n int
+	if err := env.pool.QueryRow(database.Privileged(context.Background()),
+		`SELECT COUNT(DISTINCT tenant_id) FROM shifts WHERE tenant_id IN ($1, $2)`, env.a.TenantID, env.b.TenantID,
+	).Scan(&n); err != nil {
+		t.Fatal(err)
+	}
+	if n != 2 {
+		t.Errorf("privileged query saw %d tenants, want 2", n)
+	}
+}
@@ -0,0 +1,117 @@
+package integration
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"cargomax-api/internal/rest"
+
+	"github.com/gorilla/websocket"
+)
+
+// dialWS connects to srv as f's admin and returns a channel of received
+// messages. A gorilla connection is unusable after a read deadline expires,
+// so reads happen on their own goroutine and tests time out on the channel.
+func dialWS(t *testing.T, srv *httptest.Server, f *fixture) <-chan string {
+	t.Helper()
+	url := "ws" + strings.TrimPrefix(srv.URL, "http") + "?token=" + managerToken(t, f)
+	conn, _, err := websocket.DefaultDialer.Dial(url, nil)
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	msgs := make(chan string, 64)
+	go func() {
+		defer close(msgs)
+		for {
+			_, msg, err := conn.ReadMessage()
+			if err != nil {
+				return
+			}
+			msgs <- string(msg)
+		}
+	}()
+	return msgs
+}
+
+// waitJoined blocks until the client reading msgs has joined hub for f's
+// tenant. Registration happens asynchronously after the upgrade, so it keeps
+// broadcasting marker until the client provably receives it.
+func waitJoined(t *testing.T, hub *rest.Hub, msgs <-chan string, f *fixture, marker string) {
+	t.Helper()
+	deadline := time.After(5 * time.Second)
+	tick := time.NewTicker(100 * time.Millisecond)
+	defer tick.Stop()
+	hub.BroadcastTracking(f.TenantID, map[string]string{"marker": marker})
+	for {
+		select {
+		case msg := <-msgs:
+			if strings.Contains(msg, marker) {
+				return
+			}
+		case <-tick.C:
+			hub.BroadcastTracking(f.TenantID, map[string]string{"marker": marker})
+		case <-deadline:
+			t.Fatalf("client for tenant %s never received %q", f.TenantID, marker)
+		}
+	}
+}
+
+// TestWebSocketFanOutIsTenantScoped connects one live-tracking client per
+// tenant and checks a broadcast for tenant A never reaches tenant B.
+func TestWebSocketFanOutIsTenantScoped(t *testing.T) {
+	hub := rest.NewHub(env.cfg)
+	go hub.Run()
+	srv := httptest.NewServer(http.HandlerFunc(hub.HandleTrackingWS))
+	t.Cleanup(srv.Close)
+
+	msgsA, msgsB := dialWS(t, srv, env.a), dialWS(t, srv, env.b)
+	waitJoined(t, hub, msgsB, env.b, "ready-b")
+	waitJoined(t, hub, msgsA, env.a, "ready-a")
+
+	hub.BroadcastTracking(env.a.TenantID, map[string]string{"driver_id": env.a.Driver.ID.String(), "secret": "tenant-a-only"})
+
+	received := false
+	timeout := time.After(2 * time.Second)
+	for !received {
+		select {
+		case msg := <-msgsA:
+			received = strings.Contains(msg, "tenant-a-only")
+		case <-timeout:
+			t.Fatal("tenant A did not receive its own broadcast")
+		}
+	}
+
+	quiet := time.After(500 * time.Millisecond)
+	for {
+		select {
+		case msg := <-msgsB:
+			if strings.Contains(msg, "tenant-a-only") {
+				t.Fatalf("tenant B received tenant A's broadcast: %s", msg)
+			}
+		case <-quiet:
+			return
+		}
+	}
+}
+
+// TestWebSocketRejectsMissingToken checks the upgrade is refused without
+// credentials.
+func TestWebSocketRejectsMissingToken(t *testing.T) {
+	hub := rest.NewHub(env.cfg)
+	go hub.Run()
+	srv := httptest.NewServer(http.HandlerFunc(hub.HandleAlertsWS))
+	t.Cleanup(srv.Close)
+
+	_, resp, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(srv.URL, "http"), nil)
+	if err == nil {
+		t.Fatal("connected without a token")
+	}
+	if resp == nil || resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("expected 401, got %v", resp)
+	}
+}
@@ -0,0 +1,486 @@
+package integration
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"cargomax-api/internal/models"
+	"cargomax-api/internal/repository"
+	"cargomax-api/internal/rest"
+	"cargomax-api/internal/workers"
+
+	"github.com/google/uuid"
+)
+
+// TestETAWorkerFlagsLateShipments puts two shipments with different promises
+// on a live shift, runs the ETA refresh and checks the one that cannot make
+// its promise is flagged and pushed to the tenant's tracking socket only.
+// Delivering the other folds its pace into the lane history.
+func TestETAWorkerFlagsLateShipments(t *testing.T) {
+	a, b := env.a, env.b
+	ctx := tenantCtx(context.Background(), b.TenantID)
+	r := env.repos
+	tag := uuid.NewString()[:8]
+
+	hub := rest.NewHub(env.cfg)
+	go hub.Run()
+	srv := httptest.NewServer(http.HandlerFunc(hub.HandleTrackingWS))
+	t.Cleanup(srv.Close)
+	msgsA, msgsB := dialWS(t, srv, a), dialWS(t, srv, b)
+	waitJoined(t, hub, msgsB, b, "eta-ready-b")
+	waitJoined(t, hub, msgsA, a, "eta-ready-a")
+
+	truck := &models.Vehicle{TenantID: b.TenantID, VehicleID: "ETA-" + tag, Status: "active"}
+	if err := r.Vehicle.Create(ctx, truck); err != nil {
+		t.Fatal(err)
+	}
+	driver := &models.Driver{TenantID: b.TenantID, EmployeeID: "ETA-" + tag, FirstName: str("Live"), LastName: str("Eta"), Status: "available"}
+	if err := r.Driver.Create(ctx, driver); err != nil {
+		t.Fatal(err)
+	}
+	shift := &models.Shift{TenantID: b.TenantID, DriverID: driver.ID, TruckID: truck.ID}
+	if err := r.Shift.Create(ctx, shift); err != nil {
+		t.Fatal(err)
+	}
+
+	now := time.Now()
+	ship := func(name string, promised time.Duration) *models.Shipment {
+		t.Helper()
+		s := &models.Shipment{
+			TenantID: b.TenantID, TrackingNumber: "ETA-" + name + "-" + tag, Status: models.ShipmentInTransit,
+			Origin: str("Eta Origin " + tag), Destination: str("Eta " + name + " " + tag),
+			EstimatedDelivery: ptr(now.Add(promised)), DestinationLatitude: ptr(41.0), DestinationLongitude: ptr(-75.0),
+		}
+		if err := r.Shipment.Create(ctx, s, nil); err != nil {
+			t.Fatal(err)
+		}
+		if err := r.Shipment.Assign(ctx, b.TenantID, &models.ShipmentAssignment{ShipmentID: s.ID, DriverID: &driver.ID}); err != nil {
+			t.Fatal(err)
+		}
+		return s
+	}
+	late, onTime := ship("late", time.Hour), ship("ontime", 6*time.Hour)
+
+	// About 139 road-km out at 60 km/h blended with the 55 km/h default
+	// prior: roughly 2h25m to go.
+	var pings []models.GPSPing
+	for i := 0; i < 20; i++ {
+		pings = append(pings, models.GPSPing{TenantID: b.TenantID, DriverID: driver.ID, TruckID: truck.ID, ShiftID: shift.ID, Latitude: 40.0, Longitude: -75.0, SpeedKmh: 60, IsMoving: true, RecordedAt: now.Add(-time.Duration(20-i) * 30 * time.Second), ReceivedAt: now})
+	}
+	if _, err := r.Ping.BulkInsert(ctx, pings); err != nil {
+		t.Fatal(err)
+	}
+
+	w := workers.NewETAWorker(r.Shift, r.Ping, r.Shipment, hub)
+	if err := w.RefreshShift(ctx, b.TenantID, shift.ID, driver.ID, now); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := r.Shipment.GetByIDs(ctx, b.TenantID, []uuid.UUID{late.ID, onTime.ID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, s := range got {
+		if s.PredictedDelivery == nil || s.PredictedDelivery.Before(now.Add(2*time.Hour)) || s.PredictedDelivery.After(now.Add(3*time.Hour)) {
+			t.Errorf("%s predicted %v, want 2-3h out", s.TrackingNumber, s.PredictedDelivery)
+		}
+		if want := s.ID == late.ID; s.PredictedLate != want {
+			t.Errorf("%s predicted_late = %v, want %v", s.TrackingNumber, s.PredictedLate, want)
+		}
+	}
+
+	atRisk, err := r.Shipment.GetAtRisk(ctx, b.TenantID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var flagged []uuid.UUID
+	for _, s := range atRisk {
+		flagged = append(flagged, s.ID)
+	}
+	if len(flagged) == 0 || !containsID(flagged, late.ID) || containsID(flagged, onTime.ID) {
+		t.Errorf("at-risk shipments %v, want %s only of the two", flagged, late.ID)
+	}
+
+	pushed := false
+	timeout := time.After(2 * time.Second)
+	for !pushed {
+		select {
+		case msg := <-msgsB:
+			pushed = strings.Contains(msg, `"type":"eta"`) && strings.Contains(msg, late.TrackingNumber)
+		case <-timeout:
+			t.Fatal("tenant B did not receive the ETA push")
+		}
+	}
+
+	// The same position a minute later is no change: no new history row.
+	if err := w.RefreshShift(ctx, b.TenantID, shift.ID, driver.ID, now.Add(time.Minute)); err != nil {
+		t.Fatal(err)
+	}
+	history, err := r.Shipment.ListETAsByShipments(ctx, b.TenantID, []uuid.UUID{late.ID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if h := history[late.ID]; len(h) != 1 || h[0].PromisedDelivery == nil || !h[0].PromisedDelivery.Equal(*late.EstimatedDelivery) || !h[0].PredictedLate {
+		t.Errorf("eta history %+v, want one late prediction against the promise", h)
+	}
+
+	// Tenant B's worker pointed at tenant A's shift finds nothing to predict.
+	before := env.count(t, `SELECT COUNT(*) FROM shipment_etas WHERE shipment_id = $1`, a.Shipment.ID)
+	if err := w.RefreshShift(ctx, b.TenantID, a.Shift.ID, a.Driver.ID, now); err != nil {
+		t.Fatal(err)
+	}
+	if after := env.count(t, `SELECT COUNT(*) FROM shipment_etas WHERE shipment_id = $1`, a.Shipment.ID); after != before {
+		t.Errorf("refreshing tenant A's shift as B recorded %d predictions", after-before)
+	}
+
+	quiet := time.After(300 * time.Millisecond)
+	for waiting := true; waiting; {
+		select {
+		case msg := <-msgsA:
+			if strings.Contains(msg, `"type":"eta"`) {
+				t.Fatalf("tenant A received tenant B's ETA push: %s", msg)
+			}
+		case <-quiet:
+			waiting = false
+		}
+	}
+
+	pings = pings[:0]
+	for i := 0; i < 10; i++ {
+		pings = append(pings, models.GPSPing{TenantID: b.TenantID, DriverID: driver.ID, TruckID: truck.ID, ShiftID: shift.ID, Latitude: 40.5, Longitude: -75.0, SpeedKmh: 30, IsMoving: true, RecordedAt: time.Now(), ReceivedAt: time.Now()})
+	}
+	if _, err := r.Ping.BulkInsert(ctx, pings); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := r.Shipment.Transition(ctx, b.TenantID, onTime.ID, &models.ShipmentEvent{Status: models.ShipmentDelivered}); err != nil {
+		t.Fatal(err)
+	}
+	lane, err := r.Shipment.LaneSpeed(ctx, b.TenantID, onTime.Origin, onTime.Destination)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if lane == nil || *lane < 30 || *lane > 60 {
+		t.Errorf("lane speed after delivery = %v, want the delivery's pace", lane)
+	}
+}
+
+func containsID(ids []uuid.UUID, id uuid.UUID) bool {
+	for _, x := range ids {
+		if x == id {
+			return true
+		}
+	}
+	return false
+}
+
+// TestRouteWorkerRecordsStops drives a shift past the two stops of an
+// assigned route plan and checks the worker starts the plan, records actual
+// arrival and departure from the pings and completes the plan after the last
+// stop. Only the plan's tenant hears about it.
+func TestRouteWorkerRecordsStops(t *testing.T) {
+	a, b := env.a, env.b
+	ctx := tenantCtx(context.Background(), b.TenantID)
+	r := env.repos
+	tag := uuid.NewString()[:8]
+
+	hub := rest.NewHub(env.cfg)
+	go hub.Run()
+	srv := httptest.NewServer(http.HandlerFunc(hub.HandleTrackingWS))
+	t.Cleanup(srv.Close)
+	msgsA, msgsB := dialWS(t, srv, a), dialWS(t, srv, b)
+	waitJoined(t, hub, msgsB, b, "route-ready-b")
+	waitJoined(t, hub, msgsA, a, "route-ready-a")
+
+	truck := &models.Vehicle{TenantID: b.TenantID, VehicleID: "RTE-" + tag, Status: "active"}
+	if err := r.Vehicle.Create(ctx, truck); err != nil {
+		t.Fatal(err)
+	}
+	driver := &models.Driver{TenantID: b.TenantID, EmployeeID: "RTE-" + tag, FirstName: str("Stop"), LastName: str("Watcher"), Status: "available"}
+	if err := r.Driver.Create(ctx, driver); err != nil {
+		t.Fatal(err)
+	}
+	shift := &models.Shift{TenantID: b.TenantID, DriverID: driver.ID, TruckID: truck.ID}
+	if err := r.Shift.Create(ctx, shift); err != nil {
+		t.Fatal(err)
+	}
+
+	plan := &models.RoutePlan{
+		TenantID: b.TenantID, PlanDate: shift.StartedAt.UTC().Format(models.RouteDateLayout), Name: "Worker " + tag, PlannedStart: shift.StartedAt,
+		Stops: []models.RouteStop{
+			{Type: models.StopVisit, Label: "First " + tag, Latitude: 41.0, Longitude: -76.0, RadiusMeters: 200},
+			{Type: models.StopVisit, Label: "Second " + tag, Latitude: 41.1, Longitude: -76.0, RadiusMeters: 200},
+		},
+	}
+	if err := r.Route.Create(ctx, plan); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Route.Assign(ctx, b.TenantID, plan.ID, driver.ID, &truck.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	w := workers.NewRouteWorker(r.Shift, r.Ping, r.Route, hub)
+	now := time.Now()
+	step := func(lat float64, offset time.Duration) {
+		t.Helper()
+		at := now.Add(offset)
+		if _, err := r.Ping.BulkInsert(ctx, []models.GPSPing{{TenantID: b.TenantID, DriverID: driver.ID, TruckID: truck.ID, ShiftID: shift.ID, Latitude: lat, Longitude: -76.0, RecordedAt: at, ReceivedAt: at}}); err != nil {
+			t.Fatal(err)
+		}
+		if err := w.TrackShift(ctx, shift, at); err != nil {
+			t.Fatal(err)
+		}
+	}
+	stopStatus := func() (string, []string) {
+		t.Helper()
+		got, err := r.Route.GetByID(ctx, b.TenantID, plan.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var statuses []string
+		for _, s := range got.Stops {
+			statuses = append(statuses, s.Status)
+		}
+		return got.Status, statuses
+	}
+
+	step(40.5, 0)
+	if status, stops := stopStatus(); status != models.RouteInProgress || fmt.Sprint(stops) != "[pending pending]" {
+		t.Fatalf("after the first ping: plan %s, stops %v", status, stops)
+	}
+	step(41.0005, time.Minute)
+	if _, stops := stopStatus(); fmt.Sprint(stops) != "[arrived pending]" {
+		t.Errorf("at the first stop: stops %v", stops)
+	}
+	step(41.0008, 2*time.Minute)
+	step(41.05, 3*time.Minute)
+	step(41.1, 4*time.Minute)
+	if _, stops := stopStatus(); fmt.Sprint(stops) != "[completed arrived]" {
+		t.Errorf("at the second stop: stops %v", stops)
+	}
+	step(41.2, 5*time.Minute)
+	status, stops := stopStatus()
+	if status != models.RouteCompleted || fmt.Sprint(stops) != "[completed completed]" {
+		t.Errorf("after the last stop: plan %s, stops %v", status, stops)
+	}
+
+	got, err := r.Route.GetByID(ctx, b.TenantID, plan.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	first := got.Stops[0]
+	if got.ShiftID == nil || *got.ShiftID != shift.ID {
+		t.Errorf("plan shift %v, want %s", got.ShiftID, shift.ID)
+	}
+	if first.ActualArrival == nil || first.ActualDeparture == nil ||
+		first.ActualArrival.Sub(now.Add(time.Minute)).Abs() > time.Second || first.ActualDeparture.Sub(now.Add(3*time.Minute)).Abs() > time.Second {
+		t.Errorf("first stop arrival %v departure %v, want the pings' times", first.ActualArrival, first.ActualDeparture)
+	}
+
+	pushed := false
+	timeout := time.After(2 * time.Second)
+	for !pushed {
+		select {
+		case msg := <-msgsB:
+			pushed = strings.Contains(msg, `"type":"route"`) && strings.Contains(msg, "First "+tag)
+		case <-timeout:
+			t.Fatal("tenant B did not receive the stop push")
+		}
+	}
+
+	// Tenant B's worker pointed at tenant A's shift finds no plan to follow.
+	if err := w.TrackShift(ctx, a.Shift, now); err != nil {
+		t.Fatal(err)
+	}
+	if n := env.count(t, `SELECT COUNT(*) FROM route_plans WHERE tenant_id = $1 AND shift_id IS NOT NULL`, a.TenantID); n != 0 {
+		t.Errorf("tracking tenant A's shift as B started %d of A's plans", n)
+	}
+
+	quiet := time.After(300 * time.Millisecond)
+	for waiting := true; waiting; {
+		select {
+		case msg := <-msgsA:
+			if strings.Contains(msg, `"type":"route"`) {
+				t.Fatalf("tenant A received tenant B's stop push: %s", msg)
+			}
+		case <-quiet:
+			waiting = false
+		}
+	}
+}
+
+// TestOrderScheduleWorker runs a daily standing order with a skipped day:
+// each run places the coming order as a scheduled order, confirms it on its
+// day and raises its pick list, and an order short of stock stays scheduled
+// with its admins told once. Tenant A's standing order is out of reach.
+func TestOrderScheduleWorker(t *testing.T) {
+	a, b := env.a, env.b
+	ctx := tenantCtx(context.Background(), b.TenantID)
+	r := env.repos
+	tag := uuid.NewString()[:8]
+
+	monthly := models.OrderSchedule{Frequency: models.ScheduleMonthly, Every: 1, StartDate: time.Date(2027, 1, 31, 0, 0, 0, 0, time.UTC)}
+	if d, _ := monthly.NextOccurrence(time.Date(2027, 2, 1, 0, 0, 0, 0, time.UTC)); d.Format(models.ScheduleDateLayout) != "2027-02-28" {
+		t.Errorf("monthly from the 31st next falls on %s, want 2027-02-28", d.Format(models.ScheduleDateLayout))
+	}
+
+	item := &models.InventoryItem{TenantID: b.TenantID, WarehouseID: b.Warehouse.ID, SKU: "STO-" + tag, Name: str("Standing Item"), Quantity: 5, UnitPrice: ptr(2.5), Status: "in_stock"}
+	if err := r.Inventory.Create(ctx, item); err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now()
+	today := models.Day(now)
+	day := func(n int) time.Time { return today.AddDate(0, 0, n) }
+	s := &models.OrderSchedule{
+		TenantID: b.TenantID, Name: "Daily " + tag, OrderPrefix: "STO-" + tag, OrderType: "standard",
+		Lines:     []models.ScheduleLine{{InventoryItemID: item.ID, SKU: item.SKU, WarehouseID: item.WarehouseID, Quantity: 3}},
+		Frequency: models.ScheduleDaily, Every: 1, StartDate: today, EndDate: ptr(day(3)), SkipDates: []time.Time{day(1)},
+		LeadDays: 1, AutoFulfill: true, Active: true,
+	}
+	if err := s.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.OrderSchedule.Create(ctx, s); err != nil {
+		t.Fatal(err)
+	}
+	if s.NextDate == nil || !s.NextDate.Equal(today) {
+		t.Fatalf("next date %v, want today", s.NextDate)
+	}
+
+	w := workers.NewOrderScheduleWorker(r.OrderSchedule, r.Order, r.Fulfillment, r.Notification)
+	placed := func() []models.Order {
+		t.Helper()
+		rows, err := env.pool.Query(ctx, `SELECT id FROM orders WHERE tenant_id = $1 AND order_schedule_id = $2 ORDER BY scheduled_date`, b.TenantID, s.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var ids []uuid.UUID
+		for rows.Next() {
+			var id uuid.UUID
+			if err := rows.Scan(&id); err != nil {
+				t.Fatal(err)
+			}
+			ids = append(ids, id)
+		}
+		rows.Close()
+		var out []models.Order
+		for _, id := range ids {
+			o, err := r.Order.GetByID(ctx, b.TenantID, id)
+			if err != nil {
+				t.Fatal(err)
+			}
+			out = append(out, *o)
+		}
+		return out
+	}
+	nextDate := func() *time.Time {
+		t.Helper()
+		got, err := r.OrderSchedule.GetByID(ctx, b.TenantID, s.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return got.NextDate
+	}
+
+	// Today's order is placed; tomorrow is skipped and the day after is not
+	// within the lead time yet. A second run places nothing more.
+	w.Materialize(ctx, *s, now)
+	w.Materialize(ctx, *s, now)
+	orders := placed()
+	if len(orders) != 1 {
+		t.Fatalf("placed %d orders, want 1", len(orders))
+	}
+	first := orders[0]
+	if first.OrderNumber != "STO-"+tag+"-"+today.Format("20060102") || first.Status != models.OrderScheduled ||
+		first.ScheduledDate == nil || !first.ScheduledDate.Equal(today) || first.TotalAmount == nil || *first.TotalAmount != 7.5 {
+		t.Errorf("placed order %s %s for %v totalling %v", first.OrderNumber, first.Status, first.ScheduledDate, first.TotalAmount)
+	}
+	if next := nextDate(); next == nil || !next.Equal(day(2)) {
+		t.Errorf("next date %v, want the day after tomorrow", next)
+	}
+
+	// Confirming reserves the stock and, as the schedule auto-fulfils,
+	// raises the pick list.
+	if err := w.Promote(ctx, &first); err != nil {
+		t.Fatal(err)
+	}
+	got, err := r.Order.GetByID(ctx, b.TenantID, first.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Status != models.OrderProcessing {
+		t.Errorf("confirmed order is %s, want processing", got.Status)
+	}
+	if picks, err := r.Fulfillment.ListByOrder(ctx, b.TenantID, first.ID); err != nil || len(picks) != 1 {
+		t.Errorf("pick lists %d (%v), want 1", len(picks), err)
+	}
+
+	// The next order wants 3 with 2 left: it stays scheduled, the failure is
+	// recorded and the admin is told once however often it is retried.
+	w.Materialize(ctx, *s, now.AddDate(0, 0, 1))
+	orders = placed()
+	if len(orders) != 2 {
+		t.Fatalf("placed %d orders, want 2", len(orders))
+	}
+	second := orders[1]
+	due, err := r.Order.DueScheduled(ctx, day(2).Add(time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !containsOrder(due, second.ID) {
+		t.Errorf("the second order is not due on its day")
+	}
+	for i := 0; i < 2; i++ {
+		if err := w.Promote(ctx, &second); err != nil {
+			t.Fatal(err)
+		}
+	}
+	got, err = r.Order.GetByID(ctx, b.TenantID, second.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Status != models.OrderScheduled || got.ScheduleFailure == nil || !strings.Contains(*got.ScheduleFailure, "insufficient stock") {
+		t.Errorf("short order is %s with failure %v", got.Status, got.ScheduleFailure)
+	}
+	if n := env.count(t, `SELECT COUNT(*) FROM notifications WHERE tenant_id = $1 AND user_id = $2 AND message LIKE '%' || $3 || '%'`, b.TenantID, b.Admin.ID, second.OrderNumber); n != 1 {
+		t.Errorf("admin told %d times about the shortage, want 1", n)
+	}
+
+	// The last day is placed and the schedule runs out.
+	w.Materialize(ctx, *s, now.AddDate(0, 0, 2))
+	if orders = placed(); len(orders) != 3 {
+		t.Errorf("placed %d orders, want 3", len(orders))
+	}
+	if next := nextDate(); next != nil {
+		t.Errorf("next date %v after the end date, want none", next)
+	}
+
+	// Tenant B cannot reach tenant A's standing orders.
+	other := &models.OrderSchedule{
+		TenantID: a.TenantID, Name: "Other " + tag, OrderPrefix: "OTH-" + tag, OrderType: "standard",
+		Lines:     []models.ScheduleLine{{InventoryItemID: a.Inventory.ID, SKU: a.Inventory.SKU, WarehouseID: a.Warehouse.ID, Quantity: 1}},
+		Frequency: models.ScheduleWeekly, Every: 1, StartDate: today, SkipDates: []time.Time{}, Active: true,
+	}
+	if err := r.OrderSchedule.Create(tenantCtx(context.Background(), a.TenantID), other); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := r.OrderSchedule.GetByID(ctx, b.TenantID, other.ID); err != repository.ErrOrderScheduleNotFound {
+		t.Errorf("tenant B got tenant A's standing order: %v", err)
+	}
+	if o, err := r.OrderSchedule.Materialize(ctx, b.TenantID, other.ID, now); err != repository.ErrOrderScheduleNotFound || o != nil {
+		t.Errorf("tenant B placed tenant A's standing order: %v", err)
+	}
+}
+
+func containsOrder(orders []models.Order, id uuid.UUID) bool {
+	for _, o := range orders {
+		if o.ID == id {
+			return true
+		}
+	}
+	return false
+}
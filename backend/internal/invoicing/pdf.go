@@ -0,0 +1,205 @@
+// Package invoicing renders invoices and credit notes as A4 PDF documents:
+// the issuer and bill-to details, one row per line, then the taxes, totals
+// and what remains to pay. Long invoices continue onto further pages.
+package invoicing
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"strings"
+
+	"cargomax-api/internal/models"
+	"cargomax-api/internal/pdf"
+)
+
+// A4 in points, and the layout's margins and columns.
+const (
+	pageWidth  = 595
+	pageHeight = 842
+	left       = 50
+	right      = pageWidth - 50
+	descWidth  = 380
+	lineHeight = 16
+	// bottom is where line rows stop and a new page begins.
+	bottom = pageHeight - 150
+)
+
+// Document is what an invoice PDF shows.
+type Document struct {
+	Invoice *models.Invoice
+	// Issuer is the business sending the invoice, normally the tenant.
+	Issuer string
+	// CreditedNumber is the number of the invoice a credit note is against.
+	CreditedNumber string
+}
+
+// PDF writes d as a PDF document.
+func PDF(w io.Writer, d Document) error {
+	inv := d.Invoice
+	if inv == nil || inv.InvoiceNumber == "" {
+		return fmt.Errorf("invoice has no number")
+	}
+	var doc pdf.Document
+	page := newPage(&doc, d)
+	y := header(page, d)
+
+	page.Text(left, y, pdf.HelveticaBold, 9, "DESCRIPTION")
+	page.TextRight(right, y, pdf.HelveticaBold, 9, "AMOUNT ("+inv.Currency+")")
+	y += 14
+	page.Rect(left, y, right-left, 0.8)
+	y += 8
+	for _, l := range inv.Lines {
+		rows := wrap(l.Description, pdf.Helvetica, 10, descWidth)
+		if y+float64(len(rows))*lineHeight > bottom {
+			page = newPage(&doc, d)
+			y = 110
+		}
+		for i, row := range rows {
+			page.Text(left, y+float64(i)*lineHeight, pdf.Helvetica, 10, row)
+		}
+		page.TextRight(right, y, pdf.Helvetica, 10, money(l.Amount))
+		y += float64(len(rows)) * lineHeight
+	}
+
+	// The totals stay together, on a new page if they do not fit.
+	if y+float64(6+len(inv.Taxes))*lineHeight > pageHeight-60 {
+		page = newPage(&doc, d)
+		y = 110
+	}
+	y += 4
+	page.Rect(left+descWidth-80, y, right-left-descWidth+80, 0.8)
+	y += 10
+	total := func(label string, amount float64, f pdf.Font) {
+		page.TextRight(right-110, y, f, 10, label)
+		page.TextRight(right, y, f, 10, money(amount))
+		y += lineHeight
+	}
+	total("Subtotal", inv.Subtotal, pdf.Helvetica)
+	for _, t := range inv.Taxes {
+		total(fmt.Sprintf("%s (%s%%)", t.Name, rate(t.Rate)), t.Amount, pdf.Helvetica)
+	}
+	total("Total", inv.Total, pdf.HelveticaBold)
+	if inv.Kind == models.InvoiceKindInvoice {
+		if inv.AmountPaid > 0 {
+			total("Paid", -inv.AmountPaid, pdf.Helvetica)
+		}
+		if inv.AmountCredited > 0 {
+			total("Credited", -inv.AmountCredited, pdf.Helvetica)
+		}
+		total("Balance due", inv.BalanceDue(), pdf.HelveticaBold)
+	}
+	return doc.Write(w)
+}
+
+// newPage starts a page with the footer every page carries.
+func newPage(doc *pdf.Document, d Document) *pdf.Page {
+	page := doc.AddPage(pageWidth, pageHeight)
+	inv := d.Invoice
+	footer := inv.InvoiceNumber + " - " + d.Issuer
+	if inv.Kind == models.InvoiceKindInvoice && inv.Status != models.InvoiceVoid {
+		footer = fmt.Sprintf("Please pay by %s quoting %s.", inv.DueDate.Format("2 January 2006"), inv.InvoiceNumber)
+	}
+	if inv.Status == models.InvoiceVoid {
+		footer = "This invoice is void and nothing is owed on it."
+	}
+	page.Rect(left, pageHeight-60, right-left, 0.5)
+	page.Text(left, pageHeight-50, pdf.Helvetica, 8, footer)
+	return page
+}
+
+// header draws the title, the issuer, the invoice's dates and the bill-to
+// block, and returns where the lines start.
+func header(page *pdf.Page, d Document) float64 {
+	inv := d.Invoice
+	title := "INVOICE"
+	if inv.Kind == models.InvoiceKindCreditNote {
+		title = "CREDIT NOTE"
+	}
+	if inv.Status == models.InvoiceVoid {
+		title = "VOID " + title
+	}
+	page.Text(left, 50, pdf.HelveticaBold, 18, d.Issuer)
+	page.TextRight(right, 50, pdf.HelveticaBold, 18, title)
+
+	facts := [][2]string{
+		{"Number", inv.InvoiceNumber},
+		{"Issued", inv.IssueDate.Format("2006-01-02")},
+	}
+	if inv.Kind == models.InvoiceKindInvoice {
+		facts = append(facts, [2]string{"Due", inv.DueDate.Format("2006-01-02")})
+		if inv.PeriodStart != nil && inv.PeriodEnd != nil {
+			facts = append(facts, [2]string{"Period", inv.PeriodStart.Format("2006-01-02") + " to " + inv.PeriodEnd.Format("2006-01-02")})
+		}
+	} else if d.CreditedNumber != "" {
+		facts = append(facts, [2]string{"Credits", d.CreditedNumber})
+	}
+	y := 90.0
+	for _, f := range facts {
+		page.TextRight(right-150, y, pdf.Helvetica, 9, f[0])
+		page.TextRight(right, y, pdf.HelveticaBold, 9, f[1])
+		y += 14
+	}
+
+	page.Text(left, 90, pdf.Helvetica, 9, "BILL TO")
+	page.Text(left, 104, pdf.HelveticaBold, 12, inv.BillToName)
+	by := 122.0
+	if inv.BillToAddress != nil {
+		for _, row := range wrap(*inv.BillToAddress, pdf.Helvetica, 10, 250) {
+			page.Text(left, by, pdf.Helvetica, 10, row)
+			by += 13
+		}
+	}
+	if inv.Kind == models.InvoiceKindCreditNote && inv.Reason != nil {
+		by += 6
+		for _, row := range wrap("Reason: "+*inv.Reason, pdf.Helvetica, 10, right-left) {
+			page.Text(left, by, pdf.Helvetica, 10, row)
+			by += 13
+		}
+	}
+	return math.Max(y, by) + 24
+}
+
+// wrap breaks s between words into rows no wider than width points. A word
+// wider than a row is left to overflow it.
+func wrap(s string, f pdf.Font, size, width float64) []string {
+	var rows []string
+	row := ""
+	for _, word := range strings.Fields(s) {
+		next := word
+		if row != "" {
+			next = row + " " + word
+		}
+		if row != "" && pdf.TextWidth(f, size, next) > width {
+			rows = append(rows, row)
+			next = word
+		}
+		row = next
+	}
+	if row != "" || len(rows) == 0 {
+		rows = append(rows, row)
+	}
+	return rows
+}
+
+// money formats an amount with thousands separators and two decimals.
+func money(v float64) string {
+	s := fmt.Sprintf("%.2f", math.Abs(v))
+	whole, cents, _ := strings.Cut(s, ".")
+	var b strings.Builder
+	for i, c := range whole {
+		if i > 0 && (len(whole)-i)%3 == 0 {
+			b.WriteByte(',')
+		}
+		b.WriteRune(c)
+	}
+	if v < 0 && s != "0.00" {
+		return "-" + b.String() + "." + cents
+	}
+	return b.String() + "." + cents
+}
+
+// rate formats a tax rate without trailing zeros.
+func rate(v float64) string {
+	return fmt.Sprintf("%g", v)
+}
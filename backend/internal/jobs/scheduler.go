@@ -0,0 +1,336 @@
+package jobs
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"math"
+	"os"
+	"sync"
+	"time"
+
+	"cargomax-api/internal/models"
+	"cargomax-api/internal/repository"
+
+	"github.com/google/uuid"
+)
+
+// HandlerFunc processes a claimed job. Returning an error schedules a retry
+// with exponential backoff until MaxAttempts is reached, after which the job
+// is moved to the dead-letter state.
+type HandlerFunc func(ctx context.Context, job *models.Job) error
+
+// Scheduler runs persistent background jobs from the jobs table. Cron entries
+// enqueue jobs at their activation times using a dedupe key, so several
+// replicas can evaluate the same schedule without producing duplicates.
+type Scheduler struct {
+	JobRepo      *repository.JobRepo
+	WorkerID     string
+	Concurrency  int
+	PollInterval time.Duration
+	StaleAfter   time.Duration
+
+	mu       sync.RWMutex
+	handlers map[string]HandlerFunc
+	crons    []*cronEntry
+
+	wg        sync.WaitGroup
+	slots     chan struct{}
+	stop      chan struct{}
+	stopped   chan struct{}
+	runCtx    context.Context
+	runCancel context.CancelFunc
+}
+
+type cronEntry struct {
+	name     string
+	schedule Schedule
+	jobType  string
+	payload  json.RawMessage
+	next     time.Time
+}
+
+// EnqueueOptions tunes a single Enqueue call.
+type EnqueueOptions struct {
+	TenantID    *uuid.UUID
+	RunAt       time.Time
+	MaxAttempts int
+	DedupeKey   string
+}
+
+// NewScheduler creates a Scheduler with default settings: four concurrent
+// jobs, one-second polling, and reclaiming jobs locked for over ten minutes.
+func NewScheduler(jobRepo *repository.JobRepo) *Scheduler {
+	host, _ := os.Hostname()
+	return &Scheduler{
+		JobRepo:      jobRepo,
+		WorkerID:     fmt.Sprintf("%s-%d-%s", host, os.Getpid(), uuid.NewString()[:8]),
+		Concurrency:  4,
+		PollInterval: time.Second,
+		StaleAfter:   10 * time.Minute,
+		handlers:     make(map[string]HandlerFunc),
+	}
+}
+
+// Handle registers a raw handler for a job type.
+func (s *Scheduler) Handle(jobType string, fn HandlerFunc) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.handlers[jobType] = fn
+}
+
+// Register registers a typed handler: the job payload is decoded into T and
+// the job's tenant (nil for system jobs) is passed alongside it. The tenant is
+// also placed in the context under models.CtxTenantID.
+func Register[T any](s *Scheduler, jobType string, fn func(ctx context.Context, tenantID *uuid.UUID, payload T) error) {
+	s.Handle(jobType, func(ctx context.Context, job *models.Job) error {
+		var payload T
+		if len(job.Payload) > 0 {
+			if err := json.Unmarshal(job.Payload, &payload); err != nil {
+				return fmt.Errorf("decode %s payload: %w", jobType, err)
+			}
+		}
+		return fn(ctx, job.TenantID, payload)
+	})
+}
+
+// Cron registers a recurring schedule that enqueues jobType with the given
+// payload at every activation. Must be called before Start.
+func (s *Scheduler) Cron(name, spec, jobType string, payload interface{}) error {
+	sched, err := ParseSchedule(spec)
+	if err != nil {
+		return fmt.Errorf("cron %s: %w", name, err)
+	}
+	raw, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("cron %s: encode payload: %w", name, err)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.crons = append(s.crons, &cronEntry{
+		name:     name,
+		schedule: sched,
+		jobType:  jobType,
+		payload:  raw,
+		next:     sched.Next(time.Now()),
+	})
+	return nil
+}
+
+// Enqueue persists a one-off job. Set opts.RunAt for a delayed job. It returns
+// nil, nil when opts.DedupeKey matched an existing job.
+func (s *Scheduler) Enqueue(ctx context.Context, jobType string, payload interface{}, opts EnqueueOptions) (*models.Job, error) {
+	raw, err := json.Marshal(payload)
+	if err != nil {
+		return nil, fmt.Errorf("encode %s payload: %w", jobType, err)
+	}
+	job := &models.Job{
+		TenantID:    opts.TenantID,
+		Type:        jobType,
+		Payload:     raw,
+		RunAt:       opts.RunAt,
+		MaxAttempts: opts.MaxAttempts,
+	}
+	if opts.DedupeKey != "" {
+		key := opts.DedupeKey
+		job.DedupeKey = &key
+	}
+	inserted, err := s.JobRepo.Enqueue(ctx, job)
+	if err != nil {
+		return nil, err
+	}
+	if !inserted {
+		return nil, nil
+	}
+	return job, nil
+}
+
+// EnqueueIn persists a tenant job that becomes due after delay.
+func (s *Scheduler) EnqueueIn(ctx context.Context, tenantID uuid.UUID, jobType string, payload interface{}, delay time.Duration) (*models.Job, error) {
+	return s.Enqueue(ctx, jobType, payload, EnqueueOptions{TenantID: &tenantID, RunAt: time.Now().Add(delay)})
+}
+
+// Start begins evaluating cron entries and executing due jobs. It returns
+// immediately; call Shutdown to drain.
+func (s *Scheduler) Start() {
+	s.slots = make(chan struct{}, s.Concurrency)
+	s.stop = make(chan struct{})
+	s.stopped = make(chan struct{})
+	s.runCtx, s.runCancel = context.WithCancel(context.Background())
+
+	go s.loop()
+	log.Printf("Job scheduler started (worker %s, concurrency %d)", s.WorkerID, s.Concurrency)
+}
+
+// Shutdown stops claiming new jobs and waits for in-flight jobs to finish.
+// If ctx expires first, running jobs are cancelled; they stay 'running' and
+// are reclaimed by any replica once StaleAfter has elapsed.
+func (s *Scheduler) Shutdown(ctx context.Context) error {
+	if s.stop == nil {
+		return nil
+	}
+	close(s.stop)
+	<-s.stopped
+
+	done := make(chan struct{})
+	go func() {
+		s.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		s.runCancel()
+		log.Println("Job scheduler stopped")
+		return nil
+	case <-ctx.Done():
+		s.runCancel()
+		return fmt.Errorf("job scheduler drain interrupted: %w", ctx.Err())
+	}
+}
+
+func (s *Scheduler) loop() {
+	defer close(s.stopped)
+	ticker := time.NewTicker(s.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-s.stop:
+			return
+		case <-ticker.C:
+			s.fireCrons()
+			s.poll()
+		}
+	}
+}
+
+// fireCrons enqueues a job for every cron entry whose activation time passed.
+func (s *Scheduler) fireCrons() {
+	now := time.Now()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, c := range s.crons {
+		if c.next.IsZero() || now.Before(c.next) {
+			continue
+		}
+		key := fmt.Sprintf("cron:%s:%d", c.name, c.next.Unix())
+		job := &models.Job{Type: c.jobType, Payload: c.payload, RunAt: c.next, MaxAttempts: 1, DedupeKey: &key}
+		if _, err := s.JobRepo.Enqueue(s.runCtx, job); err != nil {
+			log.Printf("scheduler: failed to enqueue cron %s: %v", c.name, err)
+			continue
+		}
+		c.next = c.schedule.Next(now)
+	}
+}
+
+// poll claims as many due jobs as there are free execution slots.
+func (s *Scheduler) poll() {
+	free := cap(s.slots) - len(s.slots)
+	if free <= 0 {
+		return
+	}
+
+	s.mu.RLock()
+	types := make([]string, 0, len(s.handlers))
+	for t := range s.handlers {
+		types = append(types, t)
+	}
+	s.mu.RUnlock()
+	if len(types) == 0 {
+		return
+	}
+
+	claimed, err := s.JobRepo.ClaimDue(s.runCtx, s.WorkerID, types, free, s.StaleAfter)
+	if err != nil {
+		log.Printf("scheduler: %v", err)
+		return
+	}
+
+	for i := range claimed {
+		job := claimed[i]
+		s.slots <- struct{}{}
+		s.wg.Add(1)
+		go func() {
+			defer func() {
+				<-s.slots
+				s.wg.Done()
+			}()
+			s.run(&job)
+		}()
+	}
+}
+
+func (s *Scheduler) run(job *models.Job) {
+	s.mu.RLock()
+	handler := s.handlers[job.Type]
+	s.mu.RUnlock()
+
+	ctx := s.runCtx
+	if job.TenantID != nil {
+		ctx = context.WithValue(ctx, models.CtxTenantID, *job.TenantID)
+	}
+
+	err := func() (err error) {
+		defer func() {
+			if rec := recover(); rec != nil {
+				err = fmt.Errorf("panic: %v", rec)
+			}
+		}()
+		return handler(ctx, job)
+	}()
+
+	// Record the outcome even if shutdown cancelled the run context.
+	bg := context.Background()
+	if err == nil {
+		if cerr := s.JobRepo.Complete(bg, job.ID); cerr != nil {
+			log.Printf("scheduler: %v", cerr)
+		}
+		return
+	}
+
+	if job.Attempts >= job.MaxAttempts {
+		log.Printf("scheduler: job %s (%s) dead after %d attempts: %v", job.ID, job.Type, job.Attempts, err)
+		if berr := s.JobRepo.Bury(bg, job.ID, err.Error()); berr != nil {
+			log.Printf("scheduler: %v", berr)
+		}
+		return
+	}
+
+	retryAt := time.Now().Add(Backoff(job.Attempts))
+	log.Printf("scheduler: job %s (%s) attempt %d failed, retrying at %s: %v", job.ID, job.Type, job.Attempts, retryAt.Format(time.RFC3339), err)
+	if rerr := s.JobRepo.Retry(bg, job.ID, err.Error(), retryAt); rerr != nil {
+		log.Printf("scheduler: %v", rerr)
+	}
+}
+
+// Backoff returns the delay before retry number attempt: 30s doubled per
+// attempt, capped at one hour.
+func Backoff(attempt int) time.Duration {
+	if attempt < 1 {
+		attempt = 1
+	}
+	d := 30 * time.Second * time.Duration(math.Pow(2, float64(attempt-1)))
+	if d > time.Hour || d <= 0 {
+		return time.Hour
+	}
+	return d
+}
+
+// RegisterPrune installs a daily housekeeping job that deletes completed jobs
+// older than retention. Dead jobs are kept for inspection.
+func (s *Scheduler) RegisterPrune(retention time.Duration) error {
+	Register(s, "jobs.prune", func(ctx context.Context, _ *uuid.UUID, _ struct{}) error {
+		n, err := s.JobRepo.PruneCompleted(ctx, time.Now().Add(-retention))
+		if err != nil {
+			return err
+		}
+		if n > 0 {
+			log.Printf("scheduler: pruned %d completed jobs", n)
+		}
+		return nil
+	})
+	return s.Cron("jobs.prune", "@daily", "jobs.prune", struct{}{})
+}
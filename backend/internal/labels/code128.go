@@ -0,0 +1,93 @@
+package labels
+
+import "fmt"
+
+// code128Patterns are the bar/space widths of Code 128 symbols 0-106, bar
+// first. Every symbol is 11 modules wide except the stop, which is 13.
+var code128Patterns = [107]string{
+	"212222", "222122", "222221", "121223", "121322", "131222", "122213", "122312", "132212", "221213",
+	"221312", "231212", "112232", "122132", "122231", "113222", "123122", "123221", "223211", "221132",
+	"221231", "213212", "223112", "312131", "311222", "321122", "321221", "312212", "322112", "322211",
+	"212123", "212321", "232121", "111323", "131123", "131321", "112313", "132113", "132311", "211313",
+	"231113", "231311", "112133", "112331", "132131", "113123", "113321", "133121", "313121", "211331",
+	"231131", "213113", "213311", "213131", "311123", "311321", "331121", "312113", "312311", "332111",
+	"314111", "221411", "431111", "111224", "111422", "121124", "121421", "141122", "141221", "112214",
+	"112412", "122114", "122411", "142112", "142211", "241211", "221114", "413111", "241112", "134111",
+	"111242", "121142", "121241", "114212", "124112", "124211", "411212", "421112", "421211", "212141",
+	"214121", "412121", "111143", "111341", "131141", "114113", "114311", "411113", "411311", "113141",
+	"114131", "311141", "411131", "211412", "211214", "211232", "2331112",
+}
+
+const (
+	code128CodeC  = 99
+	code128CodeB  = 100
+	code128StartB = 104
+	code128StartC = 105
+	code128Stop   = 106
+)
+
+// Code128 encodes s, which must be non-empty printable ASCII, as Code 128
+// modules (true for a bar) from the start symbol through the stop symbol,
+// without quiet zones. Runs of four or more digits are packed two to a
+// symbol in code set C; everything else uses code set B.
+func Code128(s string) ([]bool, error) {
+	if s == "" {
+		return nil, fmt.Errorf("code 128: nothing to encode")
+	}
+	for i := 0; i < len(s); i++ {
+		if s[i] < ' ' || s[i] > '~' {
+			return nil, fmt.Errorf("code 128: unsupported character %q", s[i])
+		}
+	}
+
+	var codes []int
+	set := byte(0)
+	switchTo := func(to byte, start, code int) {
+		if set == to {
+			return
+		}
+		if len(codes) == 0 {
+			codes = append(codes, start)
+		} else {
+			codes = append(codes, code)
+		}
+		set = to
+	}
+	for i := 0; i < len(s); {
+		if run := digitRun(s, i); run >= 4 {
+			switchTo('C', code128StartC, code128CodeC)
+			for end := i + run&^1; i < end; i += 2 {
+				codes = append(codes, int(s[i]-'0')*10+int(s[i+1]-'0'))
+			}
+			continue
+		}
+		switchTo('B', code128StartB, code128CodeB)
+		codes = append(codes, int(s[i]-' '))
+		i++
+	}
+
+	check := codes[0]
+	for i, c := range codes[1:] {
+		check += (i + 1) * c
+	}
+	codes = append(codes, check%103, code128Stop)
+
+	var modules []bool
+	for _, c := range codes {
+		for i, w := range code128Patterns[c] {
+			for n := 0; n < int(w-'0'); n++ {
+				modules = append(modules, i%2 == 0)
+			}
+		}
+	}
+	return modules, nil
+}
+
+// digitRun counts the ASCII digits in s starting at i.
+func digitRun(s string, i int) int {
+	n := 0
+	for i+n < len(s) && s[i+n] >= '0' && s[i+n] <= '9' {
+		n++
+	}
+	return n
+}
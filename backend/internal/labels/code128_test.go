@@ -0,0 +1,112 @@
+package labels
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// code128Symbols reads modules back into symbol values by their bar and
+// space widths.
+func code128Symbols(t *testing.T, modules []bool) []int {
+	t.Helper()
+	byPattern := make(map[string]int, len(code128Patterns))
+	for v, p := range code128Patterns {
+		byPattern[p] = v
+	}
+	var widths []string
+	for i := 0; i < len(modules); {
+		n := 1
+		for i+n < len(modules) && modules[i+n] == modules[i] {
+			n++
+		}
+		widths = append(widths, fmt.Sprint(n))
+		i += n
+	}
+	var symbols []int
+	for len(widths) > 0 {
+		n := 6
+		if len(widths) == 7 {
+			n = 7
+		}
+		if len(widths) < n {
+			t.Fatalf("%d widths left over", len(widths))
+		}
+		v, ok := byPattern[strings.Join(widths[:n], "")]
+		if !ok {
+			t.Fatalf("no symbol has widths %v", widths[:n])
+		}
+		symbols = append(symbols, v)
+		widths = widths[n:]
+	}
+	return symbols
+}
+
+func TestCode128(t *testing.T) {
+	for _, tc := range []struct {
+		data string
+		want []int
+	}{
+		// Start B, P J J 1 2 3 C, checksum, stop.
+		{"PJJ123C", []int{104, 48, 42, 42, 17, 18, 19, 35, 55, 106}},
+		// Three digits are cheaper in code set B.
+		{"123", []int{104, 17, 18, 19, 8, 106}},
+		{"1234", []int{105, 12, 34, 82, 106}},
+		// An odd run packs its even part and finishes in code set B.
+		{"A12345", []int{104, 33, 99, 12, 34, 100, 21, 0, 106}},
+		{"CM0000012344", []int{104, 35, 45, 99, 0, 0, 1, 23, 44, 15, 106}},
+		{"a b~", []int{104, 65, 0, 66, 94, 22, 106}},
+	} {
+		modules, err := Code128(tc.data)
+		if err != nil {
+			t.Errorf("Code128(%q): %v", tc.data, err)
+			continue
+		}
+		if want := 11*(len(tc.want)-1) + 13; len(modules) != want {
+			t.Errorf("Code128(%q) is %d modules wide, want %d", tc.data, len(modules), want)
+		}
+		if !modules[0] || !modules[len(modules)-1] {
+			t.Errorf("Code128(%q) does not start and end with a bar", tc.data)
+		}
+		if got := code128Symbols(t, modules); fmt.Sprint(got) != fmt.Sprint(tc.want) {
+			t.Errorf("Code128(%q) = %v, want %v", tc.data, got, tc.want)
+		}
+	}
+}
+
+func TestCode128Rejects(t *testing.T) {
+	for _, data := range []string{"", "tab\there", "café", "\x7f"} {
+		if _, err := Code128(data); err == nil {
+			t.Errorf("Code128(%q) accepted", data)
+		}
+	}
+}
+
+func TestCode128Patterns(t *testing.T) {
+	// The start and stop symbols as printed in the specification.
+	for v, want := range map[int]string{
+		code128StartB: "11010010000",
+		code128StartC: "11010011100",
+		code128CodeB:  "10111101110",
+		code128CodeC:  "10111011110",
+		code128Stop:   "1100011101011",
+		0:             "11011001100",
+	} {
+		var got strings.Builder
+		for i, w := range code128Patterns[v] {
+			got.WriteString(strings.Repeat(map[bool]string{true: "1", false: "0"}[i%2 == 0], int(w-'0')))
+		}
+		if got.String() != want {
+			t.Errorf("symbol %d is %s, want %s", v, got.String(), want)
+		}
+	}
+	for v, p := range code128Patterns[:code128Stop] {
+		sum := 0
+		for _, w := range p {
+			sum += int(w - '0')
+		}
+		if sum != 11 {
+			t.Errorf("symbol %d is %d modules wide", v, sum)
+		}
+	}
+}
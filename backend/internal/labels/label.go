@@ -0,0 +1,184 @@
+// Package labels renders 4x6 inch shipping labels, as ZPL for thermal
+// printers and as PDF for everything else. Both carry the same layout: the
+// sender and origin, the consignee and destination, weight, dimensions and
+// carrier, a QR code and a Code 128 barcode of the tracking number.
+package labels
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"cargomax-api/internal/models"
+)
+
+// Label is the content of one shipping label.
+type Label struct {
+	TrackingNumber string
+	// Sender is the shipper printed above the origin, normally the tenant.
+	Sender     string
+	From       string
+	ShipToName string
+	ShipTo     string
+	Carrier    string
+	WeightKg   *float64
+	Dimensions string
+	ShipDate   time.Time
+	// QRData is encoded in the QR code; it defaults to the tracking number.
+	QRData string
+}
+
+// ForShipment fills a label for s shipped by sender on date.
+func ForShipment(s *models.Shipment, sender string, date time.Time) Label {
+	l := Label{
+		TrackingNumber: s.TrackingNumber,
+		Sender:         sender,
+		WeightKg:       s.Weight,
+		ShipDate:       date,
+	}
+	if s.Origin != nil {
+		l.From = *s.Origin
+	}
+	if s.CustomerName != nil {
+		l.ShipToName = *s.CustomerName
+	}
+	if s.Destination != nil {
+		l.ShipTo = *s.Destination
+	}
+	if s.Carrier != nil {
+		l.Carrier = *s.Carrier
+	}
+	if s.Dimensions != nil {
+		l.Dimensions = *s.Dimensions
+	}
+	return l
+}
+
+// Label size in points (1/72 inch).
+const (
+	labelWidth  = 288
+	labelHeight = 432
+	margin      = 14
+)
+
+type font int
+
+const (
+	fontRegular font = iota
+	fontBold
+	fontMono
+)
+
+// canvas is what a label is drawn on. Coordinates are in points from the top
+// left; y is the top of the text or barcode.
+type canvas interface {
+	text(x, y float64, f font, size float64, s string)
+	centred(y float64, f font, size float64, s string)
+	rule(y float64)
+	code128(y, height float64, data string) error
+	qr(x, y, size float64, data string) error
+}
+
+// draw lays l out on c.
+func draw(c canvas, l Label) error {
+	if l.TrackingNumber == "" {
+		return fmt.Errorf("label has no tracking number")
+	}
+	qrData := l.QRData
+	if qrData == "" {
+		qrData = l.TrackingNumber
+	}
+
+	c.text(margin, 14, fontRegular, 7, "FROM")
+	c.text(margin, 24, fontBold, 10, truncate(l.Sender, 28))
+	for i, line := range wrap(l.From, 30, 3) {
+		c.text(margin, 38+float64(i)*11, fontRegular, 9, line)
+	}
+	c.text(190, 14, fontRegular, 7, "SHIP DATE")
+	c.text(190, 24, fontBold, 10, l.ShipDate.Format("2006-01-02"))
+	c.text(190, 42, fontRegular, 7, "CARRIER")
+	c.text(190, 52, fontBold, 10, truncate(l.Carrier, 14))
+	c.rule(86)
+
+	c.text(margin, 94, fontRegular, 7, "SHIP TO")
+	c.text(margin, 106, fontBold, 14, truncate(l.ShipToName, 30))
+	for i, line := range wrap(l.ShipTo, 34, 5) {
+		c.text(margin, 128+float64(i)*15, fontRegular, 12, line)
+	}
+	c.rule(214)
+
+	weight := "-"
+	if l.WeightKg != nil {
+		weight = fmt.Sprintf("%.1f kg", *l.WeightKg)
+	}
+	dims := l.Dimensions
+	if dims == "" {
+		dims = "-"
+	}
+	c.text(margin, 224, fontRegular, 7, "WEIGHT")
+	c.text(margin, 234, fontBold, 14, weight)
+	c.text(margin, 262, fontRegular, 7, "DIMENSIONS")
+	c.text(margin, 272, fontBold, 14, truncate(dims, 20))
+	if err := c.qr(196, 226, 76, qrData); err != nil {
+		return err
+	}
+	c.rule(318)
+
+	if err := c.code128(332, 60, l.TrackingNumber); err != nil {
+		return err
+	}
+	c.centred(400, fontMono, 14, l.TrackingNumber)
+	return nil
+}
+
+// wrap splits an address into at most maxLines lines of about width
+// characters. Comma-separated parts are kept together where they fit and
+// broken between words where they do not.
+func wrap(s string, width, maxLines int) []string {
+	var lines []string
+	line := ""
+	for _, part := range strings.Split(s, ",") {
+		part = strings.Join(strings.Fields(part), " ")
+		switch {
+		case part == "":
+			continue
+		case line == "":
+			line = part
+			continue
+		case len(line)+2+len(part) <= width:
+			line += ", " + part
+			continue
+		}
+		lines = append(lines, line+",")
+		line = ""
+		for _, word := range strings.Fields(part) {
+			if line != "" && len(line)+1+len(word) > width {
+				lines = append(lines, line)
+				line = ""
+			}
+			if line != "" {
+				line += " "
+			}
+			line += word
+		}
+	}
+	if line != "" {
+		lines = append(lines, line)
+	}
+	if len(lines) > maxLines {
+		lines = append(lines[:maxLines-1], strings.Join(lines[maxLines-1:], " "))
+	}
+	for i, l := range lines {
+		lines[i] = truncate(l, width)
+	}
+	return lines
+}
+
+// truncate shortens s to at most n characters, marking the cut with "...".
+func truncate(s string, n int) string {
+	r := []rune(s)
+	if len(r) <= n {
+		return s
+	}
+	return string(r[:n-3]) + "..."
+}
@@ -0,0 +1,63 @@
+package labels
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+func testLabels() []Label {
+	weight := 2.5
+	return []Label{
+		{TrackingNumber: "CM0000012344", Sender: "Acme_Logistics", From: "Chicago, IL", ShipToName: "Jo ^Smith~", ShipTo: "233 S Wacker Dr, Chicago, IL 60601",
+			Carrier: "FedEx", WeightKg: &weight, ShipDate: time.Date(2025, 3, 4, 0, 0, 0, 0, time.UTC)},
+		{TrackingNumber: "CM0000012351", QRData: "https://cargomax.example/t/CM0000012351"},
+	}
+}
+
+func TestZPL(t *testing.T) {
+	var buf bytes.Buffer
+	if err := ZPL(&buf, testLabels()); err != nil {
+		t.Fatal(err)
+	}
+	out := buf.String()
+	if n := strings.Count(out, "^XA"); n != 2 || strings.Count(out, "^XZ") != 2 {
+		t.Errorf("%d label formats, want 2:\n%s", n, out)
+	}
+	for _, want := range []string{
+		"^PW812\n^LL1218\n",
+		"^BCN,",
+		"^FH^FDCM0000012344^FS",
+		"^FDMA,CM0000012344^FS",
+		"^FDMA,https://cargomax.example/t/CM0000012351^FS",
+		"^FDAcme_5FLogistics^FS",
+		"^FDJo _5ESmith_7E^FS",
+		"^FD2025-03-04^FS",
+		"^FD2.5 kg^FS",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("ZPL lacks %q", want)
+		}
+	}
+	if err := ZPL(&buf, []Label{{}}); err == nil {
+		t.Error("ZPL rendered a label without a tracking number")
+	}
+}
+
+func TestPDF(t *testing.T) {
+	var buf bytes.Buffer
+	if err := PDF(&buf, testLabels()); err != nil {
+		t.Fatal(err)
+	}
+	out := buf.String()
+	if !strings.HasPrefix(out, "%PDF-") || !strings.Contains(out, "%%EOF") {
+		t.Errorf("not a PDF: %.40q", out)
+	}
+	if !strings.Contains(out, "/Count 2 ") {
+		t.Error("PDF does not have a page per label")
+	}
+	if err := PDF(&buf, nil); err == nil {
+		t.Error("PDF rendered no labels")
+	}
+}
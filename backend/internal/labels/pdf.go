@@ -0,0 +1,99 @@
+package labels
+
+import (
+	"fmt"
+	"io"
+
+	"cargomax-api/internal/pdf"
+)
+
+// pdfFonts maps the label fonts onto the standard PDF fonts.
+var pdfFonts = map[font]pdf.Font{
+	fontRegular: pdf.Helvetica,
+	fontBold:    pdf.HelveticaBold,
+	fontMono:    pdf.CourierBold,
+}
+
+// PDF writes labels as a PDF document with one 4x6 inch page per label.
+// Barcodes are drawn as filled rectangles, so the output prints sharply at
+// any resolution.
+func PDF(w io.Writer, labels []Label) error {
+	if len(labels) == 0 {
+		return fmt.Errorf("no labels to render")
+	}
+	var doc pdf.Document
+	for _, l := range labels {
+		c := &pdfCanvas{page: doc.AddPage(labelWidth, labelHeight)}
+		if err := draw(c, l); err != nil {
+			return err
+		}
+	}
+	return doc.Write(w)
+}
+
+// pdfCanvas draws a label on one page.
+type pdfCanvas struct {
+	page *pdf.Page
+}
+
+func (c *pdfCanvas) text(x, y float64, f font, size float64, s string) {
+	c.page.Text(x, y, pdfFonts[f], size, s)
+}
+
+// centred centres s across the page.
+func (c *pdfCanvas) centred(y float64, f font, size float64, s string) {
+	c.text((labelWidth-pdf.TextWidth(pdfFonts[f], size, s))/2, y, f, size, s)
+}
+
+func (c *pdfCanvas) rule(y float64) {
+	c.page.Rect(margin, y, labelWidth-2*margin, 1.2)
+}
+
+// code128 draws the barcode centred with modules of at most 2pt, merging
+// adjacent bars into one rectangle.
+func (c *pdfCanvas) code128(y, height float64, data string) error {
+	modules, err := Code128(data)
+	if err != nil {
+		return err
+	}
+	module := min((labelWidth-2*margin)/float64(len(modules)), 2)
+	x0 := (labelWidth - module*float64(len(modules))) / 2
+	for i := 0; i < len(modules); {
+		if !modules[i] {
+			i++
+			continue
+		}
+		j := i
+		for j < len(modules) && modules[j] {
+			j++
+		}
+		c.page.Rect(x0+float64(i)*module, y, float64(j-i)*module, height)
+		i = j
+	}
+	return nil
+}
+
+// qr draws the symbol size points square, one rectangle per horizontal run
+// of dark modules.
+func (c *pdfCanvas) qr(x, y, size float64, data string) error {
+	modules, err := QR([]byte(data))
+	if err != nil {
+		return err
+	}
+	module := size / float64(len(modules))
+	for r, row := range modules {
+		for i := 0; i < len(row); {
+			if !row[i] {
+				i++
+				continue
+			}
+			j := i
+			for j < len(row) && row[j] {
+				j++
+			}
+			c.page.Rect(x+float64(i)*module, y+float64(r)*module, float64(j-i)*module, module)
+			i = j
+		}
+	}
+	return nil
+}
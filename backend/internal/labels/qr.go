@@ -0,0 +1,431 @@
+package labels
+
+import "fmt"
+
+// qrVersion is the error correction level M block structure of one QR code
+// version: blocks1 blocks of data1 data codewords, then blocks2 blocks of
+// data1+1, each followed by ec codewords. align lists the alignment pattern
+// centre coordinates.
+type qrVersion struct {
+	ec      int
+	blocks1 int
+	data1   int
+	blocks2 int
+	align   []int
+}
+
+// qrVersions covers versions 1-10, enough for 213 bytes, far more than a
+// label needs.
+var qrVersions = [...]qrVersion{
+	1:  {ec: 10, blocks1: 1, data1: 16},
+	2:  {ec: 16, blocks1: 1, data1: 28, align: []int{6, 18}},
+	3:  {ec: 26, blocks1: 1, data1: 44, align: []int{6, 22}},
+	4:  {ec: 18, blocks1: 2, data1: 32, align: []int{6, 26}},
+	5:  {ec: 24, blocks1: 2, data1: 43, align: []int{6, 30}},
+	6:  {ec: 16, blocks1: 4, data1: 27, align: []int{6, 34}},
+	7:  {ec: 18, blocks1: 4, data1: 31, align: []int{6, 22, 38}},
+	8:  {ec: 22, blocks1: 2, data1: 38, blocks2: 2, align: []int{6, 24, 42}},
+	9:  {ec: 22, blocks1: 3, data1: 36, blocks2: 2, align: []int{6, 26, 46}},
+	10: {ec: 26, blocks1: 4, data1: 43, blocks2: 1, align: []int{6, 28, 50}},
+}
+
+func (v qrVersion) dataCodewords() int {
+	return v.blocks1*v.data1 + v.blocks2*(v.data1+1)
+}
+
+// QR encodes data as a QR code in byte mode at error correction level M,
+// using the smallest version that fits. The result is indexed [row][column],
+// true for a dark module, without the quiet zone.
+func QR(data []byte) ([][]bool, error) {
+	return encodeQR(data, -1)
+}
+
+// encodeQR is QR with the data mask given, or chosen by penalty when mask is
+// negative.
+func encodeQR(data []byte, mask int) ([][]bool, error) {
+	ver, ccBits := 0, 0
+	for v := 1; v < len(qrVersions); v++ {
+		ccBits = 8
+		if v >= 10 {
+			ccBits = 16
+		}
+		if 4+ccBits+8*len(data) <= 8*qrVersions[v].dataCodewords() {
+			ver = v
+			break
+		}
+	}
+	if ver == 0 {
+		return nil, fmt.Errorf("qr: %d bytes is too long", len(data))
+	}
+	spec := qrVersions[ver]
+
+	// Mode indicator, length, data, terminator and padding to capacity.
+	var bits bitBuffer
+	bits.append(0b0100, 4)
+	bits.append(len(data), ccBits)
+	for _, b := range data {
+		bits.append(int(b), 8)
+	}
+	capacity := 8 * spec.dataCodewords()
+	bits.append(0, min(4, capacity-len(bits)))
+	bits.append(0, (8-len(bits)%8)%8)
+	for pad := 0xEC; len(bits) < capacity; pad ^= 0xEC ^ 0x11 {
+		bits.append(pad, 8)
+	}
+	codewords := bits.bytes()
+
+	// Split into blocks, add Reed-Solomon codewords and interleave.
+	divisor := rsDivisor(spec.ec)
+	var blocks, ecBlocks [][]byte
+	for i, at := 0, 0; i < spec.blocks1+spec.blocks2; i++ {
+		n := spec.data1
+		if i >= spec.blocks1 {
+			n++
+		}
+		blocks = append(blocks, codewords[at:at+n])
+		ecBlocks = append(ecBlocks, rsRemainder(codewords[at:at+n], divisor))
+		at += n
+	}
+	var stream []byte
+	for i := 0; i <= spec.data1; i++ {
+		for _, b := range blocks {
+			if i < len(b) {
+				stream = append(stream, b[i])
+			}
+		}
+	}
+	for i := 0; i < spec.ec; i++ {
+		for _, b := range ecBlocks {
+			stream = append(stream, b[i])
+		}
+	}
+
+	q := newQRMatrix(ver)
+	q.drawFunctionPatterns(spec.align)
+	q.drawCodewords(stream)
+
+	if mask < 0 {
+		bestPenalty := -1
+		for m := 0; m < 8; m++ {
+			q.applyMask(m)
+			q.drawFormat(m)
+			if p := q.penalty(); bestPenalty < 0 || p < bestPenalty {
+				mask, bestPenalty = m, p
+			}
+			q.applyMask(m)
+		}
+	}
+	q.applyMask(mask)
+	q.drawFormat(mask)
+	return q.modules, nil
+}
+
+// bitBuffer is a sequence of bits, most significant first.
+type bitBuffer []bool
+
+func (b *bitBuffer) append(v, n int) {
+	for i := n - 1; i >= 0; i-- {
+		*b = append(*b, v>>i&1 == 1)
+	}
+}
+
+func (b bitBuffer) bytes() []byte {
+	out := make([]byte, len(b)/8)
+	for i, bit := range b {
+		if bit {
+			out[i/8] |= 0x80 >> (i % 8)
+		}
+	}
+	return out
+}
+
+// gfMul multiplies in GF(2^8) modulo the QR polynomial x^8+x^4+x^3+x^2+1.
+func gfMul(x, y byte) byte {
+	var z int
+	for i := 7; i >= 0; i-- {
+		z = z<<1 ^ (z>>7)*0x11D
+		z ^= int(y>>i&1) * int(x)
+	}
+	return byte(z)
+}
+
+// rsDivisor returns the coefficients of the Reed-Solomon generator
+// polynomial of the given degree, highest power first, leading 1 omitted.
+func rsDivisor(degree int) []byte {
+	result := make([]byte, degree)
+	result[degree-1] = 1
+	root := byte(1)
+	for i := 0; i < degree; i++ {
+		for j := range result {
+			result[j] = gfMul(result[j], root)
+			if j+1 < len(result) {
+				result[j] ^= result[j+1]
+			}
+		}
+		root = gfMul(root, 0x02)
+	}
+	return result
+}
+
+// rsRemainder returns the error correction codewords for data.
+func rsRemainder(data, divisor []byte) []byte {
+	result := make([]byte, len(divisor))
+	for _, b := range data {
+		factor := b ^ result[0]
+		copy(result, result[1:])
+		result[len(result)-1] = 0
+		for i, c := range divisor {
+			result[i] ^= gfMul(c, factor)
+		}
+	}
+	return result
+}
+
+// qrMatrix is a QR code being drawn. function marks the modules that belong
+// to finder, timing, alignment, format and version patterns, which the data
+// and mask leave alone.
+type qrMatrix struct {
+	version  int
+	size     int
+	modules  [][]bool
+	function [][]bool
+}
+
+func newQRMatrix(version int) *qrMatrix {
+	size := version*4 + 17
+	q := &qrMatrix{version: version, size: size, modules: make([][]bool, size), function: make([][]bool, size)}
+	for y := range q.modules {
+		q.modules[y] = make([]bool, size)
+		q.function[y] = make([]bool, size)
+	}
+	return q
+}
+
+func (q *qrMatrix) set(x, y int, dark bool) {
+	q.modules[y][x] = dark
+	q.function[y][x] = true
+}
+
+func (q *qrMatrix) drawFunctionPatterns(align []int) {
+	for i := 0; i < q.size; i++ {
+		q.set(6, i, i%2 == 0)
+		q.set(i, 6, i%2 == 0)
+	}
+
+	// Finder patterns with their light separators.
+	for _, c := range [][2]int{{3, 3}, {q.size - 4, 3}, {3, q.size - 4}} {
+		for dy := -4; dy <= 4; dy++ {
+			for dx := -4; dx <= 4; dx++ {
+				x, y := c[0]+dx, c[1]+dy
+				if x < 0 || x >= q.size || y < 0 || y >= q.size {
+					continue
+				}
+				d := max(abs(dx), abs(dy))
+				q.set(x, y, d != 2 && d != 4)
+			}
+		}
+	}
+
+	// Alignment patterns, except where they would cover a finder.
+	last := len(align) - 1
+	for i, cy := range align {
+		for j, cx := range align {
+			if i == 0 && j == 0 || i == 0 && j == last || i == last && j == 0 {
+				continue
+			}
+			for dy := -2; dy <= 2; dy++ {
+				for dx := -2; dx <= 2; dx++ {
+					q.set(cx+dx, cy+dy, max(abs(dx), abs(dy)) != 1)
+				}
+			}
+		}
+	}
+
+	// Reserve the format areas now; they are drawn once the mask is chosen.
+	q.drawFormat(0)
+
+	if q.version >= 7 {
+		rem := q.version
+		for i := 0; i < 12; i++ {
+			rem = rem<<1 ^ (rem>>11)*0x1F25
+		}
+		bits := q.version<<12 | rem
+		for i := 0; i < 18; i++ {
+			dark := bits>>i&1 == 1
+			a, b := q.size-11+i%3, i/3
+			q.set(a, b, dark)
+			q.set(b, a, dark)
+		}
+	}
+}
+
+// drawFormat writes both copies of the format information for level M and
+// mask, and the dark module.
+func (q *qrMatrix) drawFormat(mask int) {
+	data := 0b00<<3 | mask // level M is 00
+	rem := data
+	for i := 0; i < 10; i++ {
+		rem = rem<<1 ^ (rem>>9)*0x537
+	}
+	bits := (data<<10 | rem) ^ 0x5412
+	bit := func(i int) bool { return bits>>i&1 == 1 }
+
+	for i := 0; i <= 5; i++ {
+		q.set(8, i, bit(i))
+	}
+	q.set(8, 7, bit(6))
+	q.set(8, 8, bit(7))
+	q.set(7, 8, bit(8))
+	for i := 9; i < 15; i++ {
+		q.set(14-i, 8, bit(i))
+	}
+
+	for i := 0; i < 8; i++ {
+		q.set(q.size-1-i, 8, bit(i))
+	}
+	for i := 8; i < 15; i++ {
+		q.set(8, q.size-15+i, bit(i))
+	}
+	q.set(8, q.size-8, true)
+}
+
+// drawCodewords places data in the zigzag order: two-module columns from the
+// right, alternately upwards and downwards, skipping the vertical timing
+// pattern and every function module.
+func (q *qrMatrix) drawCodewords(data []byte) {
+	i := 0
+	for right := q.size - 1; right >= 1; right -= 2 {
+		if right == 6 {
+			right = 5
+		}
+		for vert := 0; vert < q.size; vert++ {
+			for j := 0; j < 2; j++ {
+				x := right - j
+				y := vert
+				if (right+1)&2 == 0 {
+					y = q.size - 1 - vert
+				}
+				if q.function[y][x] || i >= len(data)*8 {
+					continue
+				}
+				q.modules[y][x] = data[i/8]>>(7-i%8)&1 == 1
+				i++
+			}
+		}
+	}
+}
+
+// applyMask flips the data modules selected by mask. Applying the same mask
+// twice undoes it.
+func (q *qrMatrix) applyMask(mask int) {
+	for y := 0; y < q.size; y++ {
+		for x := 0; x < q.size; x++ {
+			if q.function[y][x] {
+				continue
+			}
+			var flip bool
+			switch mask {
+			case 0:
+				flip = (x+y)%2 == 0
+			case 1:
+				flip = y%2 == 0
+			case 2:
+				flip = x%3 == 0
+			case 3:
+				flip = (x+y)%3 == 0
+			case 4:
+				flip = (x/3+y/2)%2 == 0
+			case 5:
+				flip = x*y%2+x*y%3 == 0
+			case 6:
+				flip = (x*y%2+x*y%3)%2 == 0
+			case 7:
+				flip = ((x+y)%2+x*y%3)%2 == 0
+			}
+			if flip {
+				q.modules[y][x] = !q.modules[y][x]
+			}
+		}
+	}
+}
+
+// penalty scores the symbol by the four rules of ISO/IEC 18004 section
+// 7.8.3; the mask with the lowest score is used.
+func (q *qrMatrix) penalty() int {
+	p := 0
+	line := make([]bool, q.size)
+	for i := 0; i < q.size; i++ {
+		p += linePenalty(q.modules[i])
+		for j := 0; j < q.size; j++ {
+			line[j] = q.modules[j][i]
+		}
+		p += linePenalty(line)
+	}
+
+	dark := 0
+	for y := 0; y < q.size; y++ {
+		for x := 0; x < q.size; x++ {
+			if q.modules[y][x] {
+				dark++
+			}
+			if x > 0 && y > 0 {
+				c := q.modules[y][x]
+				if q.modules[y-1][x] == c && q.modules[y][x-1] == c && q.modules[y-1][x-1] == c {
+					p += 3
+				}
+			}
+		}
+	}
+	total := q.size * q.size
+	p += abs(dark*100/total-50) / 5 * 10
+	return p
+}
+
+// qrFinderLike is the 1:1:3:1:1 dark/light run that mimics a finder pattern.
+var qrFinderLike = []bool{true, false, true, true, true, false, true}
+
+// linePenalty scores one row or column for long same-colour runs and for
+// finder-like patterns next to four light modules.
+func linePenalty(line []bool) int {
+	p := 0
+	run := 1
+	for i := 1; i <= len(line); i++ {
+		if i < len(line) && line[i] == line[i-1] {
+			run++
+			continue
+		}
+		if run >= 5 {
+			p += 3 + run - 5
+		}
+		run = 1
+	}
+
+	light := func(from, to int) bool {
+		for i := from; i < to; i++ {
+			if i >= 0 && i < len(line) && line[i] {
+				return false
+			}
+		}
+		return true
+	}
+	for i := 0; i+len(qrFinderLike) <= len(line); i++ {
+		match := true
+		for k, dark := range qrFinderLike {
+			if line[i+k] != dark {
+				match = false
+				break
+			}
+		}
+		if match && (light(i-4, i) || light(i+7, i+11)) {
+			p += 40
+		}
+	}
+	return p
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
@@ -0,0 +1,251 @@
+package labels
+
+import (
+	"strings"
+	"testing"
+)
+
+// qrRows renders a matrix as rows of '#' (dark) and '.' (light).
+func qrRows(m [][]bool) []string {
+	rows := make([]string, len(m))
+	for y, row := range m {
+		var b strings.Builder
+		for _, dark := range row {
+			if dark {
+				b.WriteByte('#')
+			} else {
+				b.WriteByte('.')
+			}
+		}
+		rows[y] = b.String()
+	}
+	return rows
+}
+
+// qrFormatBits reads the format information around the top-left finder,
+// bit 14 first.
+func qrFormatBits(m [][]bool) string {
+	bit := func(x, y int) byte {
+		if m[y][x] {
+			return '1'
+		}
+		return '0'
+	}
+	var b []byte
+	for i := 14; i >= 0; i-- {
+		switch {
+		case i >= 9:
+			b = append(b, bit(14-i, 8))
+		case i == 8:
+			b = append(b, bit(7, 8))
+		case i == 7:
+			b = append(b, bit(8, 8))
+		case i == 6:
+			b = append(b, bit(8, 7))
+		default:
+			b = append(b, bit(8, i))
+		}
+	}
+	return string(b)
+}
+
+// qrFormatBitsCopy reads the second copy, split between the bottom-left and
+// top-right finders.
+func qrFormatBitsCopy(m [][]bool) string {
+	size := len(m)
+	b := make([]byte, 15)
+	for i := 0; i < 15; i++ {
+		var dark bool
+		if i < 8 {
+			dark = m[8][size-1-i]
+		} else {
+			dark = m[size-15+i][8]
+		}
+		b[14-i] = '0'
+		if dark {
+			b[14-i] = '1'
+		}
+	}
+	return string(b)
+}
+
+// The level M format strings from ISO/IEC 18004 table C.1, by mask.
+var qrFormatM = [8]string{
+	"101010000010010",
+	"101000100100101",
+	"101111001111100",
+	"101101101001011",
+	"100010111111001",
+	"100000011001110",
+	"100111110010111",
+	"100101010100000",
+}
+
+func TestQRFormatBits(t *testing.T) {
+	for mask, want := range qrFormatM {
+		m, err := encodeQR([]byte("format"), mask)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := qrFormatBits(m); got != want {
+			t.Errorf("mask %d format bits %s, want %s", mask, got, want)
+		}
+		if got := qrFormatBitsCopy(m); got != want {
+			t.Errorf("mask %d second format copy %s, want %s", mask, got, want)
+		}
+		if !m[len(m)-8][8] {
+			t.Errorf("mask %d has no dark module", mask)
+		}
+	}
+}
+
+func TestQRVersionBits(t *testing.T) {
+	// The version information from ISO/IEC 18004 table D.1.
+	for _, tc := range []struct {
+		bytes, version int
+		want           string
+	}{
+		{120, 7, "000111110010010100"},
+		{150, 8, "001000010110111100"},
+		{210, 10, "001010010011010011"},
+	} {
+		m, err := QR([]byte(strings.Repeat("x", tc.bytes)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if size := tc.version*4 + 17; len(m) != size {
+			t.Fatalf("%d bytes gave a %d module symbol, want version %d (%d)", tc.bytes, len(m), tc.version, size)
+		}
+		got, mirrored := make([]byte, 18), make([]byte, 18)
+		for i := 0; i < 18; i++ {
+			a, b := len(m)-11+i%3, i/3
+			got[17-i], mirrored[17-i] = '0', '0'
+			if m[b][a] {
+				got[17-i] = '1'
+			}
+			if m[a][b] {
+				mirrored[17-i] = '1'
+			}
+		}
+		if string(got) != tc.want || string(mirrored) != tc.want {
+			t.Errorf("version %d bits %s and %s, want %s", tc.version, got, mirrored, tc.want)
+		}
+	}
+}
+
+func TestQRVersionForLength(t *testing.T) {
+	for _, tc := range []struct{ bytes, size int }{
+		{1, 21}, {14, 21}, {15, 25}, {26, 25}, {27, 29}, {213, 57},
+	} {
+		m, err := QR([]byte(strings.Repeat("x", tc.bytes)))
+		if err != nil {
+			t.Fatalf("%d bytes: %v", tc.bytes, err)
+		}
+		if len(m) != tc.size {
+			t.Errorf("%d bytes gave %d modules, want %d", tc.bytes, len(m), tc.size)
+		}
+	}
+	if _, err := QR([]byte(strings.Repeat("x", 214))); err == nil {
+		t.Error("QR accepted 214 bytes")
+	}
+}
+
+// These symbols were checked module for module against an independent
+// encoder with the same mask.
+var qrKnown = []struct {
+	data string
+	mask int
+	rows []string
+}{
+	{"hello world", 2, []string{
+		"#######..#.##.#######",
+		"#.....#...#...#.....#",
+		"#.###.#.####..#.###.#",
+		"#.###.#.###.#.#.###.#",
+		"#.###.#.#.#.#.#.###.#",
+		"#.....#.#..#..#.....#",
+		"#######.#.#.#.#######",
+		"........#.#..........",
+		"#.#####..#.#..#####..",
+		".##.##.#.#.########.#",
+		"#.#.####.##.###..###.",
+		"#.#..#...#.###..###..",
+		"...#.#####..###.....#",
+		"........#.#.#...##..#",
+		"#######....#..#...##.",
+		"#.....#.#....#.#.####",
+		"#.###.#.#..#..##....#",
+		"#.###.#.##..######...",
+		"#.###.#.##..#..#..#..",
+		"#.....#..##.##..###..",
+		"#######.##.##.#.#..#.",
+	}},
+	{"https://cargomax.example/t", 6, []string{
+		"#######.###..##...#######",
+		"#.....#.##.###....#.....#",
+		"#.###.#.#..#.##...#.###.#",
+		"#.###.#..###.#.##.#.###.#",
+		"#.###.#.##..##....#.###.#",
+		"#.....#..##.....#.#.....#",
+		"#######.#.#.#.#.#.#######",
+		"............###..........",
+		"#..######.#...#..#..#.###",
+		"#...##.#..#.#.##...#####.",
+		".#.##.##....#.######.#..#",
+		"...#...##.....#.#.#..####",
+		"#.#######.###..##.##....#",
+		"#.###..##.#.#.####..#..#.",
+		"####.###.#..####..#.#####",
+		"#........#.........#.##.#",
+		"#.##..##.###.#..#####.##.",
+		"........#.#...#.#...#.##.",
+		"#######.#..#.##.#.#.#...#",
+		"#.....#.####.####...#....",
+		"#.###.#.##.##.#######....",
+		"#.###.#.#..#####.##....##",
+		"#.###.#..###....##..#####",
+		"#.....#....#......###.###",
+		"#######.#.###...#....#..#",
+	}},
+}
+
+func TestQRKnownSymbols(t *testing.T) {
+	for _, tc := range qrKnown {
+		m, err := encodeQR([]byte(tc.data), tc.mask)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got := qrRows(m)
+		if len(got) != len(tc.rows) {
+			t.Fatalf("%q: %d rows, want %d", tc.data, len(got), len(tc.rows))
+		}
+		for y := range got {
+			if got[y] != tc.rows[y] {
+				t.Errorf("%q row %d:\n got %s\nwant %s", tc.data, y, got[y], tc.rows[y])
+			}
+		}
+	}
+}
+
+func TestQRUsesTheMaskItReports(t *testing.T) {
+	for _, tc := range qrKnown {
+		m, err := QR([]byte(tc.data))
+		if err != nil {
+			t.Fatal(err)
+		}
+		bits := qrFormatBits(m)
+		mask := -1
+		for i, f := range qrFormatM {
+			if f == bits {
+				mask = i
+			}
+		}
+		if mask < 0 {
+			t.Fatalf("%q: format bits %s are not level M", tc.data, bits)
+		}
+		want, _ := encodeQR([]byte(tc.data), mask)
+		if strings.Join(qrRows(m), "\n") != strings.Join(qrRows(want), "\n") {
+			t.Errorf("%q: symbol does not match its own mask %d", tc.data, mask)
+		}
+	}
+}
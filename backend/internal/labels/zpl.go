@@ -0,0 +1,81 @@
+package labels
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// zplDPI is the resolution of the printers the ZPL targets (8 dots/mm).
+const zplDPI = 203
+
+// ZPL writes labels as ZPL II, one ^XA...^XZ format per label, for 4x6 inch
+// labels on a 203 dpi printer. Barcodes use the printer's own Code 128 and
+// QR code fonts.
+func ZPL(w io.Writer, labels []Label) error {
+	var buf bytes.Buffer
+	for _, l := range labels {
+		c := &zplCanvas{buf: &buf}
+		fmt.Fprintf(&buf, "^XA\n^CI28\n^PW%d\n^LL%d\n^LH0,0\n", dots(labelWidth), dots(labelHeight))
+		if err := draw(c, l); err != nil {
+			return err
+		}
+		buf.WriteString("^XZ\n")
+	}
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+// dots converts points to printer dots.
+func dots(pt float64) int {
+	return int(pt*zplDPI/72 + 0.5)
+}
+
+// zplField escapes s for a ^FH field: the command prefixes and the escape
+// character itself are sent as hex.
+var zplField = strings.NewReplacer("_", "_5F", "^", "_5E", "~", "_7E")
+
+type zplCanvas struct {
+	buf *bytes.Buffer
+}
+
+func (c *zplCanvas) text(x, y float64, _ font, size float64, s string) {
+	if s == "" {
+		return
+	}
+	h := dots(size)
+	fmt.Fprintf(c.buf, "^FO%d,%d^A0N,%d,%d^FH^FD%s^FS\n", dots(x), dots(y), h, h, zplField.Replace(s))
+}
+
+func (c *zplCanvas) centred(y float64, _ font, size float64, s string) {
+	h := dots(size)
+	fmt.Fprintf(c.buf, "^FO0,%d^FB%d,1,0,C^A0N,%d,%d^FH^FD%s^FS\n", dots(y), dots(labelWidth), h, h, zplField.Replace(s))
+}
+
+func (c *zplCanvas) rule(y float64) {
+	fmt.Fprintf(c.buf, "^FO%d,%d^GB%d,3,3^FS\n", dots(margin), dots(y), dots(labelWidth-2*margin))
+}
+
+// code128 sizes the module width from this package's encoding so the
+// barcode fits and is centred; the printer encodes the data itself.
+func (c *zplCanvas) code128(y, height float64, data string) error {
+	modules, err := Code128(data)
+	if err != nil {
+		return err
+	}
+	module := min(max(dots(labelWidth-2*margin)/len(modules), 1), 4)
+	x := (dots(labelWidth) - module*len(modules)) / 2
+	fmt.Fprintf(c.buf, "^FO%d,%d^BY%d,3,%d^BCN,%d,N,N,N,A^FH^FD%s^FS\n", x, dots(y), module, dots(height), dots(height), zplField.Replace(data))
+	return nil
+}
+
+func (c *zplCanvas) qr(x, y, size float64, data string) error {
+	modules, err := QR([]byte(data))
+	if err != nil {
+		return err
+	}
+	mag := min(max(dots(size)/len(modules), 1), 10)
+	fmt.Fprintf(c.buf, "^FO%d,%d^BQN,2,%d^FH^FDMA,%s^FS\n", dots(x), dots(y), mag, zplField.Replace(data))
+	return nil
+}
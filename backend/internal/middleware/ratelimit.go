@@ -0,0 +1,90 @@
+package middleware
+
+import (
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// RateLimiter allows at most limit requests per key within a sliding window.
+// It is in-memory, so limits apply per server process.
+type RateLimiter struct {
+	limit  int
+	window time.Duration
+
+	mu        sync.Mutex
+	hits      map[string][]time.Time
+	lastSweep time.Time
+}
+
+// NewRateLimiter creates a RateLimiter allowing limit requests per window.
+func NewRateLimiter(limit int, window time.Duration) *RateLimiter {
+	return &RateLimiter{
+		limit:     limit,
+		window:    window,
+		hits:      make(map[string][]time.Time),
+		lastSweep: time.Now(),
+	}
+}
+
+// Allow records a request for key and reports whether it is within the limit.
+// When it is not, it also returns how long until the oldest request expires.
+func (l *RateLimiter) Allow(key string) (bool, time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	cutoff := now.Add(-l.window)
+
+	// Drop idle keys once per window so the map does not grow without bound.
+	if now.Sub(l.lastSweep) > l.window {
+		for k, ts := range l.hits {
+			if len(ts) == 0 || !ts[len(ts)-1].After(cutoff) {
+				delete(l.hits, k)
+			}
+		}
+		l.lastSweep = now
+	}
+
+	ts := l.hits[key]
+	i := 0
+	for i < len(ts) && !ts[i].After(cutoff) {
+		i++
+	}
+	ts = ts[i:]
+
+	if len(ts) >= l.limit {
+		l.hits[key] = ts
+		return false, ts[0].Sub(cutoff)
+	}
+	l.hits[key] = append(ts, now)
+	return true, 0
+}
+
+// PerIP returns middleware that rejects requests from a client IP over the
+// limit with 429 Too Many Requests and a Retry-After header.
+func (l *RateLimiter) PerIP(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if ok, retry := l.Allow(ClientIP(r)); !ok {
+			w.Header().Set("Content-Type", "application/json")
+			w.Header().Set("Retry-After", strconv.Itoa(int(retry.Seconds())+1))
+			w.WriteHeader(http.StatusTooManyRequests)
+			w.Write([]byte(`{"error":"too many requests"}`))
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// ClientIP returns the address of the connecting client. Forwarding headers
+// are ignored because they are trivially spoofed to dodge per-IP limits; a
+// trusted proxy in front should rewrite RemoteAddr instead.
+func ClientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
@@ -0,0 +1,64 @@
+package models
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Address is a postal address broken into parts. It is stored as JSONB next
+// to the free-text address column, which keeps the one-line form for display
+// and search.
+type Address struct {
+	Line1      string `json:"line1,omitempty"`
+	Line2      string `json:"line2,omitempty"`
+	City       string `json:"city,omitempty"`
+	Region     string `json:"region,omitempty"`
+	PostalCode string `json:"postal_code,omitempty"`
+	// Country is an ISO 3166-1 alpha-2 code such as "DE" or "US".
+	Country string `json:"country,omitempty"`
+}
+
+// Normalize trims every part and upper-cases the country and postal code. It
+// returns an error when the country is not a two-letter code or when nothing
+// but a country is given.
+func (a *Address) Normalize() error {
+	a.Line1 = strings.Join(strings.Fields(a.Line1), " ")
+	a.Line2 = strings.Join(strings.Fields(a.Line2), " ")
+	a.City = strings.Join(strings.Fields(a.City), " ")
+	a.Region = strings.Join(strings.Fields(a.Region), " ")
+	a.PostalCode = strings.ToUpper(strings.Join(strings.Fields(a.PostalCode), " "))
+	a.Country = strings.ToUpper(strings.TrimSpace(a.Country))
+	if a.Country != "" {
+		if len(a.Country) != 2 || a.Country[0] < 'A' || a.Country[0] > 'Z' || a.Country[1] < 'A' || a.Country[1] > 'Z' {
+			return fmt.Errorf("country must be a two-letter ISO code")
+		}
+	}
+	if a.Line1 == "" && a.Line2 == "" && a.City == "" && a.Region == "" && a.PostalCode == "" {
+		return fmt.Errorf("address is empty")
+	}
+	return nil
+}
+
+// Format renders the address on one line, e.g.
+// "Hafenstr. 12, 20457 Hamburg, DE".
+func (a *Address) Format() string {
+	var parts []string
+	for _, p := range []string{a.Line1, a.Line2, strings.TrimSpace(a.PostalCode + " " + a.City), a.Region, a.Country} {
+		if p != "" {
+			parts = append(parts, p)
+		}
+	}
+	return strings.Join(parts, ", ")
+}
+
+// Locality renders only the city and region, e.g. "Hamburg, HH", for views
+// that must not give away the street or postcode.
+func (a *Address) Locality() string {
+	var parts []string
+	for _, p := range []string{a.City, a.Region} {
+		if p != "" {
+			parts = append(parts, p)
+		}
+	}
+	return strings.Join(parts, ", ")
+}
@@ -20,6 +20,11 @@ type Client struct {
 	TotalSpent         *float64  `json:"total_spent"`
 	SatisfactionRating *float64  `json:"satisfaction_rating"`
 	Status             string    `json:"status"`
-	CreatedAt          time.Time `json:"created_at"`
-	UpdatedAt          time.Time `json:"updated_at"`
+	// AddressDetails is the structured form of Address; Latitude and
+	// Longitude place the client's site.
+	AddressDetails *Address  `json:"address_details"`
+	Latitude       *float64  `json:"latitude"`
+	Longitude      *float64  `json:"longitude"`
+	CreatedAt      time.Time `json:"created_at"`
+	UpdatedAt      time.Time `json:"updated_at"`
 }
@@ -0,0 +1,89 @@
+package models
+
+import (
+	"math"
+	"time"
+
+	"cargomax-api/internal/utils"
+
+	"github.com/google/uuid"
+)
+
+// ETA tuning. Straight-line distance is stretched by ETARoadFactor to
+// approximate the road network. The lane prior counts as ETAPriorPings pings
+// of evidence, so a shift's own pace takes over after roughly ten minutes of
+// 30-second pings.
+const (
+	ETARoadFactor      = 1.25
+	ETADefaultSpeedKmh = 55.0
+	ETAMinSpeedKmh     = 8.0
+	ETAPriorPings      = 20
+	ETASpeedWindow     = 30 * time.Minute
+	ETAStalePing       = 10 * time.Minute
+	ETALateGrace       = 15 * time.Minute
+	ETAChangeThreshold = 5 * time.Minute
+)
+
+// ShipmentETA is one prediction of when a shipment will be delivered.
+// PromisedDelivery is the shipment's estimated_delivery when the prediction
+// was made.
+type ShipmentETA struct {
+	ID                uuid.UUID  `json:"id"`
+	TenantID          uuid.UUID  `json:"tenant_id"`
+	ShipmentID        uuid.UUID  `json:"shipment_id"`
+	ShiftID           *uuid.UUID `json:"shift_id"`
+	PredictedDelivery time.Time  `json:"predicted_delivery"`
+	PromisedDelivery  *time.Time `json:"promised_delivery"`
+	PredictedLate     bool       `json:"predicted_late"`
+	RemainingKm       float64    `json:"remaining_km"`
+	SpeedKmh          float64    `json:"speed_kmh"`
+	ComputedAt        time.Time  `json:"computed_at"`
+}
+
+// BlendSpeed mixes the average speed of n recent pings with a prior pace,
+// weighting the prior as ETAPriorPings pings. A nil prior falls back to
+// ETADefaultSpeedKmh. The result never drops below ETAMinSpeedKmh, so a
+// parked truck still gets a finite ETA.
+func BlendSpeed(recentAvg float64, n int, prior *float64) float64 {
+	p := ETADefaultSpeedKmh
+	if prior != nil && *prior > 0 {
+		p = *prior
+	}
+	if n < 0 {
+		n = 0
+	}
+	speed := (recentAvg*float64(n) + p*ETAPriorPings) / float64(n+ETAPriorPings)
+	return math.Max(speed, ETAMinSpeedKmh)
+}
+
+// EstimateETA predicts the delivery time of s from the truck's position at
+// at, travelling at speedKmh. It reports false when the shipment has no
+// destination coordinates.
+func EstimateETA(s *Shipment, lat, lng float64, at time.Time, speedKmh float64) (ShipmentETA, bool) {
+	if s.DestinationLatitude == nil || s.DestinationLongitude == nil {
+		return ShipmentETA{}, false
+	}
+	km := utils.HaversineMeters(lat, lng, *s.DestinationLatitude, *s.DestinationLongitude) / 1000 * ETARoadFactor
+	speedKmh = math.Max(speedKmh, ETAMinSpeedKmh)
+	predicted := at.Add(time.Duration(km / speedKmh * float64(time.Hour))).Truncate(time.Minute)
+
+	return ShipmentETA{
+		TenantID:          s.TenantID,
+		ShipmentID:        s.ID,
+		PredictedDelivery: predicted,
+		PromisedDelivery:  s.EstimatedDelivery,
+		PredictedLate:     s.EstimatedDelivery != nil && predicted.After(s.EstimatedDelivery.Add(ETALateGrace)),
+		RemainingKm:       math.Round(km*100) / 100,
+		SpeedKmh:          math.Round(speedKmh*10) / 10,
+	}, true
+}
+
+// ETAChanged reports whether a new prediction differs enough from the
+// shipment's current one to be recorded and pushed to clients.
+func ETAChanged(s *Shipment, eta ShipmentETA) bool {
+	if s.PredictedDelivery == nil || s.PredictedLate != eta.PredictedLate {
+		return true
+	}
+	d := eta.PredictedDelivery.Sub(*s.PredictedDelivery)
+	return d >= ETAChangeThreshold || d <= -ETAChangeThreshold
+}
@@ -0,0 +1,64 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Fulfillment is the part of an order picked at one warehouse and packed into
+// one parcel. Packing it creates the shipment that carries it.
+type Fulfillment struct {
+	ID          uuid.UUID         `json:"id"`
+	TenantID    uuid.UUID         `json:"tenant_id"`
+	OrderID     uuid.UUID         `json:"order_id"`
+	WarehouseID *uuid.UUID        `json:"warehouse_id"`
+	Status      string            `json:"status"`
+	ShipmentID  *uuid.UUID        `json:"shipment_id"`
+	Weight      *float64          `json:"weight"`
+	Dimensions  *string           `json:"dimensions"`
+	CreatedBy   *uuid.UUID        `json:"created_by"`
+	PickedAt    *time.Time        `json:"picked_at"`
+	PackedAt    *time.Time        `json:"packed_at"`
+	Lines       []FulfillmentLine `json:"lines"`
+	CreatedAt   time.Time         `json:"created_at"`
+	UpdatedAt   time.Time         `json:"updated_at"`
+}
+
+// Fulfillment statuses, in order. A fulfillment can be cancelled until it
+// ships.
+const (
+	FulfillmentPicking   = "picking"
+	FulfillmentPicked    = "picked"
+	FulfillmentShipped   = "shipped"
+	FulfillmentCancelled = "cancelled"
+)
+
+// FulfillmentLine is one entry on a pick list: a quantity of an order line
+// to take from the shelf. SKU and Description are the order line's.
+type FulfillmentLine struct {
+	ID            uuid.UUID `json:"id"`
+	FulfillmentID uuid.UUID `json:"fulfillment_id"`
+	OrderLineID   uuid.UUID `json:"order_line_id"`
+	LineNumber    int       `json:"line_number"`
+	SKU           string    `json:"sku"`
+	Description   *string   `json:"description"`
+	Quantity      int       `json:"quantity"`
+}
+
+// PickRequest asks for a quantity of an order line to be picked.
+type PickRequest struct {
+	OrderLineID uuid.UUID `json:"order_line_id"`
+	Quantity    int       `json:"quantity"`
+}
+
+// Parcel describes a packed fulfillment and where its shipment goes. The
+// destination defaults to the order's client's address.
+type Parcel struct {
+	Weight             float64    `json:"weight"`
+	Dimensions         string     `json:"dimensions"`
+	Carrier            *string    `json:"carrier"`
+	Destination        *string    `json:"destination"`
+	DestinationAddress *Address   `json:"destination_address"`
+	EstimatedDelivery  *time.Time `json:"estimated_delivery"`
+}
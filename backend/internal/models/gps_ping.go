@@ -24,3 +24,12 @@ type GPSPing struct {
 	IsDelayed    bool      `json:"is_delayed"`
 	CreatedAt    time.Time `json:"created_at"`
 }
+
+// LivePosition is a driver's latest ping joined with the driver's name, the
+// truck plate and whether the driver has an open alert.
+type LivePosition struct {
+	GPSPing
+	DriverName     string `json:"driver_name"`
+	TruckPlate     string `json:"truck_plate"`
+	HasActiveAlert bool   `json:"has_active_alert"`
+}
@@ -0,0 +1,121 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Entities a file can be imported into.
+const (
+	ImportShipments = "shipments"
+	ImportOrders    = "orders"
+	ImportInventory = "inventory"
+	ImportDrivers   = "drivers"
+	ImportVehicles  = "vehicles"
+)
+
+// ImportWarehouses names warehouses, which imported rows refer to by name but
+// which are not imported themselves.
+const ImportWarehouses = "warehouses"
+
+// InventoryImportKey is what an inventory row is matched on: a SKU is
+// stocked once per warehouse.
+func InventoryImportKey(sku string, warehouseID uuid.UUID) string {
+	return sku + " @ " + warehouseID.String()
+}
+
+// Import modes say what happens to a row whose key (tracking number, order
+// number, SKU in a warehouse, employee ID or vehicle ID) already exists in the
+// tenant.
+const (
+	// ImportInsert reports the row as an error.
+	ImportInsert = "insert"
+	// ImportUpsert updates the existing record from the row's non-empty cells.
+	ImportUpsert = "upsert"
+	// ImportSkip leaves the row out and keeps the existing record.
+	ImportSkip = "skip"
+)
+
+// IsImportMode reports whether m is one of the import modes.
+func IsImportMode(m string) bool {
+	return m == ImportInsert || m == ImportUpsert || m == ImportSkip
+}
+
+// Import batch statuses. A batch is uploaded, validated by any number of dry
+// runs, then committed: at once, or queued for the background worker when the
+// file is large. A failed batch imported nothing and may be committed again.
+const (
+	ImportUploaded  = "uploaded"
+	ImportValidated = "validated"
+	ImportQueued    = "queued"
+	ImportCompleted = "completed"
+	ImportFailed    = "failed"
+)
+
+// Import limits. Files with more than ImportSyncRows rows are committed by
+// the ImportCommitJob background job rather than in the request; only the
+// first ImportMaxErrors row errors are kept on the batch.
+const (
+	ImportMaxFileBytes = 10 << 20
+	ImportMaxRows      = 20000
+	ImportSyncRows     = 500
+	ImportMaxErrors    = 200
+	ImportCommitJob    = "imports.commit"
+)
+
+// ImportColumn maps a column of the file, by its header, onto an import
+// field.
+type ImportColumn struct {
+	Field  string `json:"field"`
+	Column string `json:"column"`
+}
+
+// ImportRowError is a problem with one row of an import file. Row is the
+// row's line in the file, the header being line 1; Field is empty when the
+// problem is with the row as a whole.
+type ImportRowError struct {
+	Row     int    `json:"row"`
+	Field   string `json:"field,omitempty"`
+	Message string `json:"message"`
+}
+
+// ImportBatch is an uploaded file and the outcome of importing it. After a
+// dry run the counts are what a commit would do; after a commit they are what
+// it did.
+type ImportBatch struct {
+	ID          uuid.UUID        `json:"id"`
+	TenantID    uuid.UUID        `json:"tenant_id"`
+	Entity      string           `json:"entity"`
+	Filename    string           `json:"filename"`
+	StorageKey  string           `json:"-"`
+	Status      string           `json:"status"`
+	Mode        string           `json:"mode"`
+	Columns     []string         `json:"columns"`
+	Mapping     []ImportColumn   `json:"mapping"`
+	TotalRows   int              `json:"total_rows"`
+	Created     int              `json:"created"`
+	Updated     int              `json:"updated"`
+	Skipped     int              `json:"skipped"`
+	Failed      int              `json:"failed"`
+	Errors      []ImportRowError `json:"errors"`
+	Error       *string          `json:"error,omitempty"`
+	CreatedBy   *uuid.UUID       `json:"created_by,omitempty"`
+	CreatedAt   time.Time        `json:"created_at"`
+	UpdatedAt   time.Time        `json:"updated_at"`
+	CompletedAt *time.Time       `json:"completed_at,omitempty"`
+}
+
+// ImportRecord is one validated row ready to be written: a *Shipment,
+// *Order, *InventoryItem, *Driver or *Vehicle. Existing is set when the row
+// updates a record that is already there.
+type ImportRecord struct {
+	Row      int
+	Existing bool
+	Value    interface{}
+}
+
+// ImportCommitPayload is the payload of an ImportCommitJob.
+type ImportCommitPayload struct {
+	BatchID uuid.UUID `json:"batch_id"`
+}
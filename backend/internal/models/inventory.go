@@ -6,15 +6,21 @@ import (
 	"github.com/google/uuid"
 )
 
-// InventoryItem represents an item stored in a warehouse.
+// InventoryItem is a product's stock level in one warehouse. SKU, Name,
+// Category, UnitPrice and Weight are the product's.
 type InventoryItem struct {
 	ID          uuid.UUID `json:"id"`
 	TenantID    uuid.UUID `json:"tenant_id"`
+	ProductID   uuid.UUID `json:"product_id"`
 	WarehouseID uuid.UUID `json:"warehouse_id"`
 	SKU         string    `json:"sku"`
 	Name        *string   `json:"name"`
 	Category    *string   `json:"category"`
+	// Quantity is the stock on hand, Reserved the part of it held for open
+	// orders and InTransit what is on its way in from other warehouses.
 	Quantity    int       `json:"quantity"`
+	Reserved    int       `json:"reserved"`
+	InTransit   int       `json:"in_transit"`
 	MinQuantity int       `json:"min_quantity"`
 	UnitPrice   *float64  `json:"unit_price"`
 	Weight      *float64  `json:"weight"`
@@ -22,3 +28,8 @@ type InventoryItem struct {
 	CreatedAt   time.Time `json:"created_at"`
 	UpdatedAt   time.Time `json:"updated_at"`
 }
+
+// Available is the stock that can still be promised to orders.
+func (i *InventoryItem) Available() int {
+	return i.Quantity - i.Reserved
+}
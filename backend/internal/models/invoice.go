@@ -0,0 +1,362 @@
+package models
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Invoice kinds. A credit note reduces what is owed on the invoice it
+// credits.
+const (
+	InvoiceKindInvoice    = "invoice"
+	InvoiceKindCreditNote = "credit_note"
+)
+
+// Invoice statuses. Invoices are issued, numbered, when they are generated;
+// a void invoice keeps its number but releases its orders to be billed again.
+const (
+	InvoiceIssued        = "issued"
+	InvoicePartiallyPaid = "partially_paid"
+	InvoicePaid          = "paid"
+	InvoiceVoid          = "void"
+)
+
+// Tenant sequences that number invoices and credit notes.
+const (
+	SequenceInvoice    = "invoice"
+	SequenceCreditNote = "credit_note"
+)
+
+// Settings keys for invoicing.
+const (
+	SettingInvoicePrefix    = "invoice_prefix"
+	SettingCreditNotePrefix = "credit_note_prefix"
+	SettingInvoiceTaxes     = "invoice_taxes"
+	SettingPaymentTermsDays = "payment_terms_days"
+	SettingInvoiceCurrency  = "invoice_currency"
+)
+
+// Invoicing defaults used when a tenant has not set them.
+const (
+	DefaultInvoicePrefix    = "INV"
+	DefaultCreditNotePrefix = "CN"
+	DefaultPaymentTermsDays = 30
+	MaxPaymentTermsDays     = 365
+)
+
+// Invoice bills a client for its delivered orders over a billing period, or,
+// as a credit note, takes an amount off an earlier invoice.
+type Invoice struct {
+	ID            uuid.UUID `json:"id"`
+	TenantID      uuid.UUID `json:"tenant_id"`
+	ClientID      uuid.UUID `json:"client_id"`
+	Kind          string    `json:"kind"`
+	InvoiceNumber string    `json:"invoice_number"`
+	Status        string    `json:"status"`
+	// CreditedInvoiceID is the invoice a credit note is against.
+	CreditedInvoiceID *uuid.UUID `json:"credited_invoice_id"`
+
+	// BillToName and BillToAddress are the client's details when the
+	// invoice was issued; later edits to the client do not change them.
+	BillToName    string  `json:"bill_to_name"`
+	BillToAddress *string `json:"bill_to_address"`
+
+	PeriodStart *time.Time `json:"period_start"`
+	PeriodEnd   *time.Time `json:"period_end"`
+	IssueDate   time.Time  `json:"issue_date"`
+	DueDate     time.Time  `json:"due_date"`
+	Currency    string     `json:"currency"`
+
+	Lines []InvoiceLine `json:"lines"`
+	Taxes []InvoiceTax  `json:"taxes"`
+
+	Subtotal float64 `json:"subtotal"`
+	TaxTotal float64 `json:"tax_total"`
+	Total    float64 `json:"total"`
+	// AmountPaid and AmountCredited are what payments and credit notes
+	// have taken off an invoice so far.
+	AmountPaid     float64 `json:"amount_paid"`
+	AmountCredited float64 `json:"amount_credited"`
+
+	// Reason is why a credit note was issued or an invoice voided.
+	Reason    *string    `json:"reason"`
+	CreatedBy *uuid.UUID `json:"created_by"`
+	CreatedAt time.Time  `json:"created_at"`
+	UpdatedAt time.Time  `json:"updated_at"`
+}
+
+// BalanceDue is what the client still owes on an invoice. It is negative when
+// a credit note has left the client owed a refund.
+func (i *Invoice) BalanceDue() float64 {
+	return RoundMoney(i.Total - i.AmountPaid - i.AmountCredited)
+}
+
+// CreditLeft is how much can still be credited on an invoice: it cannot be
+// credited for more than it billed.
+func (i *Invoice) CreditLeft() float64 {
+	return RoundMoney(i.Total - i.AmountCredited)
+}
+
+// Credit takes a credit note's total off the invoice. It returns the refund
+// the credit leaves owed to the client: how much more the client has now
+// paid than it owes.
+func (i *Invoice) Credit(amount float64) (refund float64) {
+	overpaid := math.Max(-i.BalanceDue(), 0)
+	i.AmountCredited = RoundMoney(i.AmountCredited + amount)
+	return RoundMoney(math.Max(-i.BalanceDue(), 0) - overpaid)
+}
+
+// SetTotals sums the lines into the subtotal, charges each tax rate on it and
+// sets the total. Each tax is rounded to cents on its own.
+func (i *Invoice) SetTotals(rates []TaxRate) {
+	subtotal := 0.0
+	for _, l := range i.Lines {
+		subtotal += l.Amount
+	}
+	i.Subtotal = RoundMoney(subtotal)
+	i.Taxes = make([]InvoiceTax, 0, len(rates))
+	i.TaxTotal = 0
+	for _, r := range rates {
+		t := InvoiceTax{Name: r.Name, Rate: r.Rate, Amount: RoundMoney(i.Subtotal * r.Rate / 100)}
+		i.Taxes = append(i.Taxes, t)
+		i.TaxTotal += t.Amount
+	}
+	i.TaxTotal = RoundMoney(i.TaxTotal)
+	i.Total = RoundMoney(i.Subtotal + i.TaxTotal)
+}
+
+// TaxRates returns the rates an invoice was taxed at, so a credit note
+// against it is taxed alike.
+func (i *Invoice) TaxRates() []TaxRate {
+	rates := make([]TaxRate, len(i.Taxes))
+	for j, t := range i.Taxes {
+		rates[j] = TaxRate{Name: t.Name, Rate: t.Rate}
+	}
+	return rates
+}
+
+// SettleStatus sets an open invoice's status from what has been paid and
+// credited on it.
+func (i *Invoice) SettleStatus() {
+	switch {
+	case i.Status == InvoiceVoid:
+	case i.BalanceDue() <= 0:
+		i.Status = InvoicePaid
+	case i.AmountPaid > 0 || i.AmountCredited > 0:
+		i.Status = InvoicePartiallyPaid
+	default:
+		i.Status = InvoiceIssued
+	}
+}
+
+// InvoiceLine is one billed order, or an amount credited.
+type InvoiceLine struct {
+	OrderID     *uuid.UUID `json:"order_id,omitempty"`
+	Description string     `json:"description"`
+	Amount      float64    `json:"amount"`
+}
+
+// InvoiceTax is one tax charged on an invoice's subtotal.
+type InvoiceTax struct {
+	Name   string  `json:"name"`
+	Rate   float64 `json:"rate"`
+	Amount float64 `json:"amount"`
+}
+
+// Payment is money received against an invoice.
+type Payment struct {
+	ID        uuid.UUID  `json:"id"`
+	TenantID  uuid.UUID  `json:"tenant_id"`
+	InvoiceID uuid.UUID  `json:"invoice_id"`
+	ClientID  uuid.UUID  `json:"client_id"`
+	Amount    float64    `json:"amount"`
+	Method    *string    `json:"method"`
+	Reference *string    `json:"reference"`
+	PaidAt    time.Time  `json:"paid_at"`
+	CreatedBy *uuid.UUID `json:"created_by"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+// TaxRate is a tax applied to every invoice, from the invoice_taxes setting.
+type TaxRate struct {
+	Name string
+	Rate float64
+}
+
+// InvoiceSettings is how a tenant's invoices are numbered, taxed and dated.
+type InvoiceSettings struct {
+	Prefix           string
+	CreditNotePrefix string
+	Taxes            []TaxRate
+	PaymentTermsDays int
+	Currency         string
+}
+
+// DefaultInvoiceSettings are the settings used when a tenant has set none:
+// no taxes, 30 days to pay and US dollars.
+func DefaultInvoiceSettings() InvoiceSettings {
+	return InvoiceSettings{
+		Prefix:           DefaultInvoicePrefix,
+		CreditNotePrefix: DefaultCreditNotePrefix,
+		PaymentTermsDays: DefaultPaymentTermsDays,
+		Currency:         RateDefaultCurrency,
+	}
+}
+
+// FormatInvoiceNumber joins a prefix and a sequence number, e.g. INV-000042.
+func FormatInvoiceNumber(prefix string, n int64) string {
+	return fmt.Sprintf("%s-%06d", prefix, n)
+}
+
+// ParseInvoicePrefix validates an invoice_prefix or credit_note_prefix
+// setting: one to eight letters or digits, stored upper-case.
+func ParseInvoicePrefix(v string) (string, error) {
+	v = strings.ToUpper(strings.TrimSpace(v))
+	if v == "" || len(v) > 8 {
+		return "", fmt.Errorf("invoice prefix must be 1 to 8 letters or digits")
+	}
+	for _, c := range v {
+		if (c < 'A' || c > 'Z') && (c < '0' || c > '9') {
+			return "", fmt.Errorf("invoice prefix must contain only letters and digits")
+		}
+	}
+	return v, nil
+}
+
+// ParseInvoiceTaxes validates an invoice_taxes setting: comma-separated
+// name=percent pairs such as "GST=5, PST=7", each tax charged on the
+// subtotal. An empty value means no tax.
+func ParseInvoiceTaxes(v string) ([]TaxRate, error) {
+	var taxes []TaxRate
+	for _, part := range strings.Split(v, ",") {
+		if strings.TrimSpace(part) == "" {
+			continue
+		}
+		name, rate, ok := strings.Cut(part, "=")
+		name = strings.TrimSpace(name)
+		if !ok || name == "" || len(name) > 30 {
+			return nil, fmt.Errorf("invoice taxes must be name=percent pairs, e.g. VAT=20")
+		}
+		r, err := strconv.ParseFloat(strings.TrimSpace(rate), 64)
+		if err != nil || r < 0 || r > 100 {
+			return nil, fmt.Errorf("tax %s: rate must be a percentage from 0 to 100", name)
+		}
+		taxes = append(taxes, TaxRate{Name: name, Rate: r})
+	}
+	return taxes, nil
+}
+
+// FormatInvoiceTaxes is the stored form of taxes.
+func FormatInvoiceTaxes(taxes []TaxRate) string {
+	parts := make([]string, len(taxes))
+	for i, t := range taxes {
+		parts[i] = t.Name + "=" + strconv.FormatFloat(t.Rate, 'f', -1, 64)
+	}
+	return strings.Join(parts, ", ")
+}
+
+// ParsePaymentTermsDays validates a payment_terms_days setting.
+func ParsePaymentTermsDays(v string) (int, error) {
+	n, err := strconv.Atoi(strings.TrimSpace(v))
+	if err != nil || n < 0 || n > MaxPaymentTermsDays {
+		return 0, fmt.Errorf("payment terms must be a whole number of days from 0 to %d", MaxPaymentTermsDays)
+	}
+	return n, nil
+}
+
+// ParseInvoiceCurrency validates an invoice_currency setting.
+func ParseInvoiceCurrency(v string) (string, error) {
+	v = strings.ToUpper(strings.TrimSpace(v))
+	if len(v) != 3 {
+		return "", fmt.Errorf("invoice currency must be a three-letter ISO 4217 code")
+	}
+	for _, c := range v {
+		if c < 'A' || c > 'Z' {
+			return "", fmt.Errorf("invoice currency must be a three-letter ISO 4217 code")
+		}
+	}
+	return v, nil
+}
+
+// RoundMoney rounds an amount to cents.
+func RoundMoney(v float64) float64 {
+	return math.Round(v*100) / 100
+}
+
+// AgingBucket is the amount outstanding on invoices whose due date is a given
+// number of days past.
+type AgingBucket struct {
+	Label    string  `json:"label"`
+	Amount   float64 `json:"amount"`
+	Invoices int     `json:"invoices"`
+}
+
+// ClientAging is one client's outstanding invoices by age.
+type ClientAging struct {
+	ClientID    uuid.UUID     `json:"client_id"`
+	CompanyName string        `json:"company_name"`
+	Buckets     []AgingBucket `json:"buckets"`
+	Total       float64       `json:"total"`
+}
+
+// AgingReport is the tenant's accounts receivable by age as of a date.
+type AgingReport struct {
+	AsOf    time.Time     `json:"as_of"`
+	Buckets []AgingBucket `json:"buckets"`
+	Clients []ClientAging `json:"clients"`
+	Total   float64       `json:"total"`
+}
+
+// AgingBucketLabels names the aging buckets, by days past due: not yet due,
+// 1-30, 31-60, 61-90 and over 90.
+var AgingBucketLabels = []string{"current", "1-30", "31-60", "61-90", "90+"}
+
+// AgingBucketIndex returns the bucket of an invoice daysPastDue days past its
+// due date.
+func AgingBucketIndex(daysPastDue int) int {
+	switch {
+	case daysPastDue <= 0:
+		return 0
+	case daysPastDue <= 30:
+		return 1
+	case daysPastDue <= 60:
+		return 2
+	case daysPastDue <= 90:
+		return 3
+	}
+	return 4
+}
+
+// InvoiceRun is one call to bill delivered orders: every client's, or one
+// client's, for the days PeriodStart to PeriodEnd inclusive.
+type InvoiceRun struct {
+	ClientID    *uuid.UUID
+	PeriodStart time.Time
+	PeriodEnd   time.Time
+	IssueDate   time.Time
+	CreatedBy   *uuid.UUID
+}
+
+// InvoiceFilter narrows an invoice list; empty fields match everything.
+type InvoiceFilter struct {
+	ClientID *uuid.UUID
+	Kind     string
+	Status   string
+}
+
+// CreditNoteRequest describes a credit note to issue against an invoice: the
+// returned orders on it to credit in full, and an amount before tax to credit
+// besides them.
+type CreditNoteRequest struct {
+	InvoiceID uuid.UUID
+	OrderIDs  []uuid.UUID
+	Amount    float64
+	Reason    string
+	IssueDate time.Time
+	CreatedBy *uuid.UUID
+}
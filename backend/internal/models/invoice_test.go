@@ -0,0 +1,345 @@
+package models
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+// taxes renders an invoice's taxes as "name=amount" for comparison.
+func taxes(inv *Invoice) []string {
+	out := make([]string, len(inv.Taxes))
+	for i, t := range inv.Taxes {
+		out[i] = fmt.Sprintf("%s=%g", t.Name, t.Amount)
+	}
+	return out
+}
+
+func invoiceOf(amounts ...float64) *Invoice {
+	inv := &Invoice{}
+	for _, a := range amounts {
+		inv.Lines = append(inv.Lines, InvoiceLine{Amount: a})
+	}
+	return inv
+}
+
+func TestSetTotals(t *testing.T) {
+	gstPst := []TaxRate{{Name: "GST", Rate: 5}, {Name: "PST", Rate: 7}}
+	for _, tc := range []struct {
+		name     string
+		lines    []float64
+		rates    []TaxRate
+		subtotal float64
+		taxes    []string
+		taxTotal float64
+		total    float64
+	}{
+		{
+			name:     "no taxes",
+			lines:    []float64{10.1, 20.2},
+			subtotal: 30.3,
+			taxes:    []string{},
+			total:    30.3,
+		},
+		{
+			name:     "two taxes on the subtotal",
+			lines:    []float64{12.34},
+			rates:    gstPst,
+			subtotal: 12.34,
+			taxes:    []string{"GST=0.62", "PST=0.86"},
+			taxTotal: 1.48,
+			total:    13.82,
+		},
+		{
+			// 1.7 at 2% would be 0.03; each 1% rounds up on its own.
+			name:     "each tax rounded on its own",
+			lines:    []float64{1.7},
+			rates:    []TaxRate{{Name: "A", Rate: 1}, {Name: "B", Rate: 1}},
+			subtotal: 1.7,
+			taxes:    []string{"A=0.02", "B=0.02"},
+			taxTotal: 0.04,
+			total:    1.74,
+		},
+		{
+			name:     "subtotal rounded to cents",
+			lines:    []float64{10.004, 10.004},
+			rates:    []TaxRate{{Name: "VAT", Rate: 20}},
+			subtotal: 20.01,
+			taxes:    []string{"VAT=4"},
+			taxTotal: 4,
+			total:    24.01,
+		},
+		{
+			name:     "a negative line reduces the taxed subtotal",
+			lines:    []float64{100, -15},
+			rates:    []TaxRate{{Name: "VAT", Rate: 20}},
+			subtotal: 85,
+			taxes:    []string{"VAT=17"},
+			taxTotal: 17,
+			total:    102,
+		},
+		{
+			name:     "zero-rated tax is still listed",
+			lines:    []float64{40},
+			rates:    []TaxRate{{Name: "EXEMPT", Rate: 0}},
+			subtotal: 40,
+			taxes:    []string{"EXEMPT=0"},
+			total:    40,
+		},
+	} {
+		inv := invoiceOf(tc.lines...)
+		inv.SetTotals(tc.rates)
+		if inv.Subtotal != tc.subtotal {
+			t.Errorf("%s: subtotal %g, want %g", tc.name, inv.Subtotal, tc.subtotal)
+		}
+		if got := taxes(inv); !reflect.DeepEqual(got, tc.taxes) {
+			t.Errorf("%s: taxes %q, want %q", tc.name, got, tc.taxes)
+		}
+		if inv.TaxTotal != tc.taxTotal {
+			t.Errorf("%s: tax total %g, want %g", tc.name, inv.TaxTotal, tc.taxTotal)
+		}
+		if inv.Total != tc.total {
+			t.Errorf("%s: total %g, want %g", tc.name, inv.Total, tc.total)
+		}
+	}
+}
+
+func TestSetTotalsRecalculates(t *testing.T) {
+	inv := invoiceOf(50)
+	inv.SetTotals([]TaxRate{{Name: "VAT", Rate: 20}})
+	inv.SetTotals(nil)
+	if len(inv.Taxes) != 0 || inv.TaxTotal != 0 || inv.Total != 50 {
+		t.Errorf("retotalled without taxes: taxes %q, tax total %g, total %g, want none, 0, 50", taxes(inv), inv.TaxTotal, inv.Total)
+	}
+}
+
+// A credit note for everything an invoice billed is taxed at the invoice's
+// rates, so it comes to the invoice's total.
+func TestCreditNoteTaxedAtInvoiceRates(t *testing.T) {
+	inv := invoiceOf(12.34, 7.66)
+	inv.SetTotals([]TaxRate{{Name: "GST", Rate: 5}, {Name: "PST", Rate: 7}})
+	if got, want := inv.TaxRates(), []TaxRate{{Name: "GST", Rate: 5}, {Name: "PST", Rate: 7}}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("TaxRates() = %v, want %v", got, want)
+	}
+
+	note := invoiceOf(12.34, 7.66)
+	note.SetTotals(inv.TaxRates())
+	if note.Total != inv.Total || !reflect.DeepEqual(taxes(note), taxes(inv)) {
+		t.Errorf("full credit note: total %g taxes %q, want %g taxes %q", note.Total, taxes(note), inv.Total, taxes(inv))
+	}
+
+	note = invoiceOf(5)
+	note.SetTotals(inv.TaxRates())
+	if want := []string{"GST=0.25", "PST=0.35"}; note.Total != 5.6 || !reflect.DeepEqual(taxes(note), want) {
+		t.Errorf("partial credit note: total %g taxes %q, want 5.6 taxes %q", note.Total, taxes(note), want)
+	}
+}
+
+func TestRoundMoney(t *testing.T) {
+	for in, want := range map[float64]float64{
+		0:      0,
+		1.234:  1.23,
+		1.236:  1.24,
+		1.25:   1.25,
+		0.125:  0.13,
+		-0.125: -0.13,
+		-1.234: -1.23,
+		99.999: 100,
+	} {
+		if got := RoundMoney(in); got != want {
+			t.Errorf("RoundMoney(%g) = %g, want %g", in, got, want)
+		}
+	}
+}
+
+func TestSettleStatus(t *testing.T) {
+	for _, tc := range []struct {
+		name                  string
+		status                string
+		total, paid, credited float64
+		balance               float64
+		want                  string
+	}{
+		{"nothing paid", InvoiceIssued, 113, 0, 0, 113, InvoiceIssued},
+		{"part paid", InvoiceIssued, 113, 50, 0, 63, InvoicePartiallyPaid},
+		{"part credited", InvoiceIssued, 113, 0, 13, 100, InvoicePartiallyPaid},
+		{"paid and credited in full", InvoicePartiallyPaid, 113, 100, 13, 0, InvoicePaid},
+		{"overpaid by a credit", InvoicePaid, 113, 113, 13, -13, InvoicePaid},
+		{"void stays void", InvoiceVoid, 113, 0, 0, 113, InvoiceVoid},
+	} {
+		inv := &Invoice{Status: tc.status, Total: tc.total, AmountPaid: tc.paid, AmountCredited: tc.credited}
+		if got := inv.BalanceDue(); got != tc.balance {
+			t.Errorf("%s: balance due %g, want %g", tc.name, got, tc.balance)
+		}
+		inv.SettleStatus()
+		if inv.Status != tc.want {
+			t.Errorf("%s: status %q, want %q", tc.name, inv.Status, tc.want)
+		}
+	}
+}
+
+func TestCredit(t *testing.T) {
+	for _, tc := range []struct {
+		name                  string
+		total, paid, credited float64
+		credit                float64
+		left                  float64
+		refund                float64
+	}{
+		{"unpaid", 113, 0, 0, 13, 100, 0},
+		{"credit up to the balance", 113, 50, 0, 63, 50, 0},
+		{"paid in full", 113, 113, 0, 13, 100, 13},
+		{"credit past the balance", 113, 100, 0, 20, 93, 7},
+		// Only the new excess is refunded, not what an earlier credit
+		// already left owed.
+		{"already owed a refund", 113, 113, 13, 10, 90, 10},
+		{"the whole invoice", 113, 113, 0, 113, 0, 113},
+	} {
+		inv := &Invoice{Total: tc.total, AmountPaid: tc.paid, AmountCredited: tc.credited}
+		if left := inv.CreditLeft(); tc.credit > left {
+			t.Errorf("%s: credit of %g is more than the %g left", tc.name, tc.credit, left)
+			continue
+		}
+		refund := inv.Credit(tc.credit)
+		if refund != tc.refund {
+			t.Errorf("%s: refund %g, want %g", tc.name, refund, tc.refund)
+		}
+		if got := inv.CreditLeft(); got != tc.left {
+			t.Errorf("%s: %g left to credit, want %g", tc.name, got, tc.left)
+		}
+	}
+}
+
+func TestCreditLeft(t *testing.T) {
+	for _, tc := range []struct {
+		total, credited, want float64
+	}{
+		{113, 0, 113},
+		{113, 13, 100},
+		{113, 113, 0},
+		{0.3, 0.1, 0.2},
+	} {
+		inv := &Invoice{Total: tc.total, AmountCredited: tc.credited, AmountPaid: 50}
+		if got := inv.CreditLeft(); got != tc.want {
+			t.Errorf("CreditLeft of %g credited %g = %g, want %g", tc.total, tc.credited, got, tc.want)
+		}
+	}
+}
+
+func TestParseInvoiceTaxes(t *testing.T) {
+	for _, tc := range []struct {
+		in   string
+		want []TaxRate
+	}{
+		{"", nil},
+		{" , ", nil},
+		{"VAT=20", []TaxRate{{Name: "VAT", Rate: 20}}},
+		{" GST = 5 , PST=7.5,", []TaxRate{{Name: "GST", Rate: 5}, {Name: "PST", Rate: 7.5}}},
+		{"Zero=0,Full=100", []TaxRate{{Name: "Zero", Rate: 0}, {Name: "Full", Rate: 100}}},
+	} {
+		got, err := ParseInvoiceTaxes(tc.in)
+		if err != nil || !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("ParseInvoiceTaxes(%q) = %v, %v, want %v", tc.in, got, err, tc.want)
+		}
+	}
+	if got := FormatInvoiceTaxes([]TaxRate{{Name: "GST", Rate: 5}, {Name: "PST", Rate: 7.5}}); got != "GST=5, PST=7.5" {
+		t.Errorf("FormatInvoiceTaxes = %q, want %q", got, "GST=5, PST=7.5")
+	}
+	if taxes, err := ParseInvoiceTaxes(FormatInvoiceTaxes([]TaxRate{{Name: "VAT", Rate: 19.6}})); err != nil || taxes[0].Rate != 19.6 {
+		t.Errorf("VAT=19.6 round trip = %v, %v", taxes, err)
+	}
+}
+
+func TestParseInvoiceTaxesRejects(t *testing.T) {
+	for _, in := range []string{
+		"VAT",
+		"=20",
+		"VAT=",
+		"VAT=twenty",
+		"VAT=-1",
+		"VAT=100.5",
+		"VAT=20%",
+		"ABCDEFGHIJKLMNOPQRSTUVWXYZABCDE=5",
+		"GST=5,PST",
+	} {
+		if got, err := ParseInvoiceTaxes(in); err == nil {
+			t.Errorf("ParseInvoiceTaxes(%q) = %v, want an error", in, got)
+		}
+	}
+}
+
+func TestParseInvoiceSettings(t *testing.T) {
+	for _, tc := range []struct {
+		in, want string
+	}{
+		{"inv", "INV"},
+		{" cn2025 ", "CN2025"},
+		{"ABCDEFGH", "ABCDEFGH"},
+	} {
+		if got, err := ParseInvoicePrefix(tc.in); err != nil || got != tc.want {
+			t.Errorf("ParseInvoicePrefix(%q) = %q, %v, want %q", tc.in, got, err, tc.want)
+		}
+	}
+	for _, in := range []string{"", "ABCDEFGHI", "INV-", "IN V", "ÄB"} {
+		if got, err := ParseInvoicePrefix(in); err == nil {
+			t.Errorf("ParseInvoicePrefix(%q) = %q, want an error", in, got)
+		}
+	}
+
+	for in, want := range map[string]int{"0": 0, " 30 ": 30, "365": 365} {
+		if got, err := ParsePaymentTermsDays(in); err != nil || got != want {
+			t.Errorf("ParsePaymentTermsDays(%q) = %d, %v, want %d", in, got, err, want)
+		}
+	}
+	for _, in := range []string{"", "-1", "366", "thirty", "1.5"} {
+		if got, err := ParsePaymentTermsDays(in); err == nil {
+			t.Errorf("ParsePaymentTermsDays(%q) = %d, want an error", in, got)
+		}
+	}
+
+	for in, want := range map[string]string{"EUR": "EUR", " usd ": "USD", "Gbp": "GBP"} {
+		if got, err := ParseInvoiceCurrency(in); err != nil || got != want {
+			t.Errorf("ParseInvoiceCurrency(%q) = %q, %v, want %q", in, got, err, want)
+		}
+	}
+	for _, in := range []string{"", "EU", "EURO", "E1R", "€€€"} {
+		if got, err := ParseInvoiceCurrency(in); err == nil {
+			t.Errorf("ParseInvoiceCurrency(%q) = %q, want an error", in, got)
+		}
+	}
+}
+
+func TestFormatInvoiceNumber(t *testing.T) {
+	for _, tc := range []struct {
+		prefix string
+		n      int64
+		want   string
+	}{
+		{"INV", 42, "INV-000042"},
+		{"CN", 1, "CN-000001"},
+		{"INV", 1234567, "INV-1234567"},
+	} {
+		if got := FormatInvoiceNumber(tc.prefix, tc.n); got != tc.want {
+			t.Errorf("FormatInvoiceNumber(%q, %d) = %q, want %q", tc.prefix, tc.n, got, tc.want)
+		}
+	}
+}
+
+func TestAgingBucketIndex(t *testing.T) {
+	for days, want := range map[int]int{
+		-10: 0,
+		0:   0,
+		1:   1,
+		30:  1,
+		31:  2,
+		60:  2,
+		61:  3,
+		90:  3,
+		91:  4,
+		400: 4,
+	} {
+		if got := AgingBucketIndex(days); got != want {
+			t.Errorf("AgingBucketIndex(%d) = %d (%s), want %d (%s)", days, got, AgingBucketLabels[got], want, AgingBucketLabels[want])
+		}
+	}
+}
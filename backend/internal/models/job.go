@@ -0,0 +1,28 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Job is a unit of background work persisted in the jobs table. TenantID is
+// nil for system-wide jobs (e.g. the alert sweep across all active shifts).
+type Job struct {
+	ID          uuid.UUID       `json:"id"`
+	TenantID    *uuid.UUID      `json:"tenant_id,omitempty"`
+	Type        string          `json:"type"`
+	Payload     json.RawMessage `json:"payload"`
+	Status      string          `json:"status"`
+	Attempts    int             `json:"attempts"`
+	MaxAttempts int             `json:"max_attempts"`
+	RunAt       time.Time       `json:"run_at"`
+	LockedAt    *time.Time      `json:"locked_at,omitempty"`
+	LockedBy    *string         `json:"locked_by,omitempty"`
+	LastError   *string         `json:"last_error,omitempty"`
+	DedupeKey   *string         `json:"dedupe_key,omitempty"`
+	CompletedAt *time.Time      `json:"completed_at,omitempty"`
+	CreatedAt   time.Time       `json:"created_at"`
+	UpdatedAt   time.Time       `json:"updated_at"`
+}
@@ -20,6 +20,87 @@ type Order struct {
 	ScheduledDate      *time.Time `json:"scheduled_date"`
 	ReturnReason       *string    `json:"return_reason"`
 	CancellationReason *string    `json:"cancellation_reason"`
-	CreatedAt          time.Time  `json:"created_at"`
-	UpdatedAt          time.Time  `json:"updated_at"`
+
+	// ClientID is the client billed for the order; its rate card prices it.
+	ClientID *uuid.UUID `json:"client_id"`
+	// Quote is the price worked out from the rate card when the order was
+	// created without a total amount.
+	Quote *Quote `json:"quote"`
+	// InvoiceID and CreditNoteID are set by invoicing: the invoice that
+	// billed the order and the credit note that took it back off.
+	InvoiceID    *uuid.UUID `json:"invoice_id"`
+	CreditNoteID *uuid.UUID `json:"credit_note_id"`
+
+	// OrderScheduleID is the standing order the order was placed from.
+	// ScheduleFailure is why it could not move from scheduled to pending on
+	// its day; the move is retried until it succeeds.
+	OrderScheduleID *uuid.UUID `json:"order_schedule_id"`
+	ScheduleFailure *string    `json:"schedule_failure"`
+
+	// Lines are the inventory items ordered. An order with lines has its
+	// total worked out from them. They are only loaded where noted.
+	Lines []OrderLine `json:"lines,omitempty"`
+
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// Order statuses. Stock is reserved for an order's lines while it is open,
+// taken from the warehouse when it ships, released when it is cancelled and
+// put back when it is returned. A scheduled order holds no stock until it is
+// confirmed.
+const (
+	OrderScheduled  = "scheduled"
+	OrderPending    = "pending"
+	OrderProcessing = "processing"
+	OrderShipped    = "shipped"
+	OrderDelivered  = "delivered"
+	OrderCancelled  = "cancelled"
+	OrderReturned   = "returned"
+)
+
+// OrderHoldsStock reports whether an order in status keeps stock reserved
+// for its lines.
+func OrderHoldsStock(status string) bool {
+	return status == OrderPending || status == OrderProcessing
+}
+
+// OrderHasShipped reports whether an order in status has taken its stock
+// out of the warehouse.
+func OrderHasShipped(status string) bool {
+	return status == OrderShipped || status == OrderDelivered
+}
+
+// OrderLine is a quantity of one inventory item on an order.
+type OrderLine struct {
+	ID         uuid.UUID `json:"id"`
+	TenantID   uuid.UUID `json:"tenant_id"`
+	OrderID    uuid.UUID `json:"order_id"`
+	LineNumber int       `json:"line_number"`
+	// InventoryItemID is the stock the line draws on; SKU, WarehouseID and
+	// Description record what it was when ordered.
+	InventoryItemID *uuid.UUID `json:"inventory_item_id"`
+	SKU             string     `json:"sku"`
+	WarehouseID     *uuid.UUID `json:"warehouse_id"`
+	Description     *string    `json:"description"`
+	Quantity        int        `json:"quantity"`
+	UnitPrice       float64    `json:"unit_price"`
+	LineTotal       float64    `json:"line_total"`
+	// ReservedQuantity is held in stock for the line, ShippedQuantity has
+	// left the warehouse and ReturnedQuantity has come back since, restocked
+	// or, through an RMA, written off.
+	ReservedQuantity int       `json:"reserved_quantity"`
+	ShippedQuantity  int       `json:"shipped_quantity"`
+	ReturnedQuantity int       `json:"returned_quantity"`
+	CreatedAt        time.Time `json:"created_at"`
+	UpdatedAt        time.Time `json:"updated_at"`
+}
+
+// OrderLinesTotal sums the lines' totals.
+func OrderLinesTotal(lines []OrderLine) float64 {
+	total := 0.0
+	for _, l := range lines {
+		total += l.LineTotal
+	}
+	return RoundMoney(total)
 }
@@ -0,0 +1,136 @@
+package models
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// OrderSchedule is a standing order: the same order placed every Every
+// days, weeks or months from StartDate until EndDate, except on SkipDates.
+// Dates are calendar days in UTC.
+type OrderSchedule struct {
+	ID            uuid.UUID      `json:"id"`
+	TenantID      uuid.UUID      `json:"tenant_id"`
+	Name          string         `json:"name"`
+	OrderPrefix   string         `json:"order_prefix"`
+	ClientID      *uuid.UUID     `json:"client_id"`
+	CustomerName  *string        `json:"customer_name"`
+	CustomerEmail *string        `json:"customer_email"`
+	OrderType     string         `json:"order_type"`
+	Lines         []ScheduleLine `json:"lines"`
+	Frequency     string         `json:"frequency"`
+	Every         int            `json:"every"`
+	StartDate     time.Time      `json:"start_date"`
+	EndDate       *time.Time     `json:"end_date"`
+	SkipDates     []time.Time    `json:"skip_dates"`
+	// LeadDays is how many days ahead of each occurrence its order is
+	// created, as a scheduled order. NextDate is the next occurrence still
+	// to be created; nil once the schedule has run out.
+	LeadDays    int        `json:"lead_days"`
+	NextDate    *time.Time `json:"next_date"`
+	AutoFulfill bool       `json:"auto_fulfill"`
+	Active      bool       `json:"active"`
+	CreatedBy   *uuid.UUID `json:"created_by"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+}
+
+// ScheduleLine is a line of a standing order. A nil UnitPrice takes the
+// item's price when the order is created.
+type ScheduleLine struct {
+	InventoryItemID uuid.UUID `json:"inventoryItemId"`
+	SKU             string    `json:"sku"`
+	WarehouseID     uuid.UUID `json:"warehouseId"`
+	Quantity        int       `json:"quantity"`
+	UnitPrice       *float64  `json:"unitPrice,omitempty"`
+}
+
+// Schedule frequencies.
+const (
+	ScheduleDaily   = "daily"
+	ScheduleWeekly  = "weekly"
+	ScheduleMonthly = "monthly"
+)
+
+// ScheduleDateLayout is the format of schedule dates.
+const ScheduleDateLayout = "2006-01-02"
+
+// Validate checks the rule of a schedule.
+func (s *OrderSchedule) Validate() error {
+	switch s.Frequency {
+	case ScheduleDaily, ScheduleWeekly, ScheduleMonthly:
+	default:
+		return fmt.Errorf("frequency must be daily, weekly or monthly")
+	}
+	if s.Every < 1 || s.Every > 365 {
+		return fmt.Errorf("every must be from 1 to 365")
+	}
+	if s.EndDate != nil && s.EndDate.Before(s.StartDate) {
+		return fmt.Errorf("the end date is before the start date")
+	}
+	if s.LeadDays < 0 || s.LeadDays > 60 {
+		return fmt.Errorf("leadDays must be from 0 to 60")
+	}
+	if s.OrderPrefix == "" || len(s.OrderPrefix) > 30 {
+		return fmt.Errorf("orderPrefix must be 1 to 30 characters")
+	}
+	if len(s.Lines) == 0 {
+		return fmt.Errorf("a standing order needs lines")
+	}
+	return nil
+}
+
+// occurrence returns the schedule's k-th date, counting from zero. A monthly
+// schedule started on the 31st falls on the last day of shorter months.
+func (s *OrderSchedule) occurrence(k int) time.Time {
+	start := Day(s.StartDate)
+	switch s.Frequency {
+	case ScheduleDaily:
+		return start.AddDate(0, 0, k*s.Every)
+	case ScheduleWeekly:
+		return start.AddDate(0, 0, 7*k*s.Every)
+	}
+	first := time.Date(start.Year(), start.Month()+time.Month(k*s.Every), 1, 0, 0, 0, 0, time.UTC)
+	last := first.AddDate(0, 1, -1).Day()
+	return first.AddDate(0, 0, min(start.Day(), last)-1)
+}
+
+// NextOccurrence returns the first date on or after from that the schedule
+// places an order, or false once it has run out.
+func (s *OrderSchedule) NextOccurrence(from time.Time) (time.Time, bool) {
+	from = Day(from)
+	k := 0
+	if start := Day(s.StartDate); from.After(start) {
+		// Jump close to from rather than stepping through years of dates.
+		days := int(from.Sub(start).Hours() / 24)
+		switch s.Frequency {
+		case ScheduleDaily:
+			k = days / s.Every
+		case ScheduleWeekly:
+			k = days / (7 * s.Every)
+		case ScheduleMonthly:
+			k = max((days/31)/s.Every-1, 0)
+		}
+	}
+	skip := map[time.Time]bool{}
+	for _, d := range s.SkipDates {
+		skip[Day(d)] = true
+	}
+	for ; ; k++ {
+		d := s.occurrence(k)
+		if s.EndDate != nil && d.After(Day(*s.EndDate)) {
+			return time.Time{}, false
+		}
+		if !d.Before(from) && !skip[d] {
+			return d, true
+		}
+	}
+}
+
+// Day truncates t to its calendar day in UTC.
+func Day(t time.Time) time.Time {
+	t = t.UTC()
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
@@ -0,0 +1,62 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Proof of delivery limits. A driver must be within PODGeofenceMeters of the
+// destination, going by a ping no older than PODMaxPingAge, for the delivery
+// to be accepted.
+const (
+	PODGeofenceMeters = 500.0
+	PODMaxPingAge     = 5 * time.Minute
+	PODMaxPhotos      = 6
+	PODMaxFileBytes   = 5 << 20
+)
+
+// POD file kinds.
+const (
+	PODSignature = "signature"
+	PODPhoto     = "photo"
+)
+
+// PODContentTypes are the image types accepted for signatures and photos,
+// with the extension they are stored under.
+var PODContentTypes = map[string]string{
+	"image/png":  ".png",
+	"image/jpeg": ".jpg",
+	"image/webp": ".webp",
+}
+
+// ProofOfDelivery is the record a driver leaves when handing over a shipment:
+// who took it, where and when, with the signature and photos in Files.
+type ProofOfDelivery struct {
+	ID             uuid.UUID  `json:"id"`
+	TenantID       uuid.UUID  `json:"tenant_id"`
+	ShipmentID     uuid.UUID  `json:"shipment_id"`
+	DriverID       *uuid.UUID `json:"driver_id"`
+	ShiftID        *uuid.UUID `json:"shift_id"`
+	RecipientName  string     `json:"recipient_name"`
+	Latitude       float64    `json:"latitude"`
+	Longitude      float64    `json:"longitude"`
+	Accuracy       float64    `json:"accuracy"`
+	DistanceMeters float64    `json:"distance_m"`
+	CapturedAt     time.Time  `json:"captured_at"`
+	CreatedAt      time.Time  `json:"created_at"`
+	Files          []PODFile  `json:"files"`
+}
+
+// PODFile is a signature or photo attached to a proof of delivery. The bytes
+// live in file storage under StorageKey.
+type PODFile struct {
+	ID          uuid.UUID `json:"id"`
+	TenantID    uuid.UUID `json:"tenant_id"`
+	PODID       uuid.UUID `json:"pod_id"`
+	Kind        string    `json:"kind"`
+	StorageKey  string    `json:"-"`
+	ContentType string    `json:"content_type"`
+	SizeBytes   int64     `json:"size_bytes"`
+	CreatedAt   time.Time `json:"created_at"`
+}
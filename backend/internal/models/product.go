@@ -0,0 +1,34 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Product is an entry in the tenant's catalog: a SKU and what describes it.
+// Its stock is held per warehouse in inventory items; Quantity, Reserved and
+// InTransit add those up. A unit's Weight is in kg and its Volume in cubic
+// metres.
+type Product struct {
+	ID             uuid.UUID `json:"id"`
+	TenantID       uuid.UUID `json:"tenant_id"`
+	SKU            string    `json:"sku"`
+	Name           *string   `json:"name"`
+	Category       *string   `json:"category"`
+	UnitPrice      *float64  `json:"unit_price"`
+	Weight         *float64  `json:"weight"`
+	Volume         *float64  `json:"volume"`
+	UnitsPerPallet *int      `json:"units_per_pallet"`
+	Quantity       int       `json:"quantity"`
+	Reserved       int       `json:"reserved"`
+	InTransit      int       `json:"in_transit"`
+	CreatedAt      time.Time `json:"created_at"`
+	UpdatedAt      time.Time `json:"updated_at"`
+}
+
+// Available is the stock, across warehouses, that can still be promised to
+// orders.
+func (p *Product) Available() int {
+	return p.Quantity - p.Reserved
+}
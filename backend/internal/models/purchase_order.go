@@ -0,0 +1,153 @@
+package models
+
+import (
+	"math"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// SupplierProduct is an entry in the supplier catalog: a product a vendor
+// supplies, under the vendor's own SKU, at a unit cost, arriving
+// LeadTimeDays after it is ordered and sold in no fewer than
+// MinOrderQuantity.
+type SupplierProduct struct {
+	ID               uuid.UUID `json:"id"`
+	TenantID         uuid.UUID `json:"tenant_id"`
+	VendorID         uuid.UUID `json:"vendor_id"`
+	ProductID        uuid.UUID `json:"product_id"`
+	SKU              string    `json:"sku"`
+	VendorSKU        *string   `json:"vendor_sku"`
+	UnitCost         float64   `json:"unit_cost"`
+	LeadTimeDays     int       `json:"lead_time_days"`
+	MinOrderQuantity int       `json:"min_order_quantity"`
+	// Preferred marks the supplier the reorder job buys the product from.
+	Preferred bool      `json:"preferred"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// PurchaseOrder buys stock from a vendor into one of the tenant's
+// warehouses.
+type PurchaseOrder struct {
+	ID           uuid.UUID           `json:"id"`
+	TenantID     uuid.UUID           `json:"tenant_id"`
+	PONumber     string              `json:"po_number"`
+	VendorID     uuid.UUID           `json:"vendor_id"`
+	WarehouseID  uuid.UUID           `json:"warehouse_id"`
+	Status       string              `json:"status"`
+	Source       string              `json:"source"`
+	ExpectedDate *time.Time          `json:"expected_date"`
+	Notes        *string             `json:"notes"`
+	CreatedBy    *uuid.UUID          `json:"created_by"`
+	SentAt       *time.Time          `json:"sent_at"`
+	ReceivedAt   *time.Time          `json:"received_at"`
+	Lines        []PurchaseOrderLine `json:"lines"`
+	CreatedAt    time.Time           `json:"created_at"`
+	UpdatedAt    time.Time           `json:"updated_at"`
+}
+
+// PurchaseOrderLine is a quantity of one product on a purchase order, and
+// how much of it has been received.
+type PurchaseOrderLine struct {
+	ID               uuid.UUID `json:"id"`
+	PurchaseOrderID  uuid.UUID `json:"purchase_order_id"`
+	ProductID        uuid.UUID `json:"product_id"`
+	SKU              string    `json:"sku"`
+	VendorSKU        *string   `json:"vendor_sku"`
+	Quantity         int       `json:"quantity"`
+	ReceivedQuantity int       `json:"received_quantity"`
+	UnitCost         float64   `json:"unit_cost"`
+}
+
+// Total is what the purchase order costs.
+func (po *PurchaseOrder) Total() float64 {
+	var total float64
+	for _, l := range po.Lines {
+		total += float64(l.Quantity) * l.UnitCost
+	}
+	return RoundMoney(total)
+}
+
+// Purchase order statuses. A draft can be changed until it is sent to the
+// vendor; a sent order is partially received until every line has arrived
+// in full. Cancelling a partially received order closes it short.
+const (
+	PurchaseDraft             = "draft"
+	PurchaseSent              = "sent"
+	PurchasePartiallyReceived = "partially_received"
+	PurchaseReceived          = "received"
+	PurchaseCancelled         = "cancelled"
+)
+
+// IsPurchaseStatus reports whether s is a known purchase order status.
+func IsPurchaseStatus(s string) bool {
+	switch s {
+	case PurchaseDraft, PurchaseSent, PurchasePartiallyReceived, PurchaseReceived, PurchaseCancelled:
+		return true
+	}
+	return false
+}
+
+// Purchase order sources: drafted by a user or by the reorder job.
+const (
+	PurchaseManual  = "manual"
+	PurchaseReorder = "reorder"
+)
+
+// SequencePurchaseOrder numbers a tenant's purchase orders, formatted with
+// PurchaseOrderPrefix.
+const (
+	SequencePurchaseOrder = "purchase_order"
+	PurchaseOrderPrefix   = "PO"
+)
+
+// PurchaseQuantity is a quantity of a SKU to order, at UnitCost or else the
+// vendor's catalog cost, or that was received.
+type PurchaseQuantity struct {
+	SKU      string
+	Quantity int
+	UnitCost *float64
+}
+
+// ReorderPolicy is how the reorder job sizes purchase orders. Consumption
+// is what was picked over the last LookbackDays; an order covers CoverDays
+// of it beyond the supplier's lead time.
+type ReorderPolicy struct {
+	LookbackDays int
+	CoverDays    int
+}
+
+// DefaultReorderPolicy is the policy of the scheduled reorder job.
+var DefaultReorderPolicy = ReorderPolicy{LookbackDays: 30, CoverDays: 14}
+
+// ReorderLevel is a stock level as the reorder job sees it.
+type ReorderLevel struct {
+	MinQuantity int
+	// Position is the stock that is or will be on hand and unpromised:
+	// available, in transit and on open purchase orders.
+	Position int
+	// Consumed is what was picked over the policy's lookback.
+	Consumed int
+}
+
+// ReorderQuantity is how much of a stock level to order from a supplier
+// with the given lead time and minimum order quantity, or 0 when none is
+// needed. The level is reordered once its position falls to its minimum
+// plus the demand expected before a delivery could arrive, and is then
+// ordered up to that plus CoverDays of demand.
+func (p ReorderPolicy) ReorderQuantity(l ReorderLevel, leadTimeDays, minOrder int) int {
+	var daily float64
+	if p.LookbackDays > 0 {
+		daily = float64(l.Consumed) / float64(p.LookbackDays)
+	}
+	reorderPoint := l.MinQuantity + int(math.Ceil(daily*float64(leadTimeDays)))
+	if reorderPoint <= 0 || l.Position > reorderPoint {
+		return 0
+	}
+	n := reorderPoint + int(math.Ceil(daily*float64(p.CoverDays))) - l.Position
+	if n < minOrder {
+		n = minOrder
+	}
+	return n
+}
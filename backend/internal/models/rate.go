@@ -0,0 +1,107 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Surcharge kinds: a percentage of the freight charge (base, weight and
+// distance), a flat amount, or an amount per chargeable kilogram.
+const (
+	SurchargePercent = "percent"
+	SurchargeFlat    = "flat"
+	SurchargePerKg   = "per_kg"
+)
+
+// SurchargeAlways is the condition of a surcharge added to every quote, such
+// as fuel. Any other condition is an option the quote must ask for, e.g.
+// "residential" or "hazmat".
+const SurchargeAlways = "always"
+
+// Rate card defaults. The volumetric divisor is cubic centimetres per
+// kilogram, the common road and courier figure.
+const (
+	RateDefaultCurrency = "USD"
+	RateDefaultDivisor  = 5000
+)
+
+// RateCard prices shipments for a tenant. The tenant's active card with no
+// client is its default; a client's active card overrides it: its lanes are
+// tried first and its surcharges replace the default's with the same code.
+type RateCard struct {
+	ID       uuid.UUID  `json:"id"`
+	TenantID uuid.UUID  `json:"tenant_id"`
+	Name     string     `json:"name"`
+	ClientID *uuid.UUID `json:"client_id"`
+	Currency string     `json:"currency"`
+	// VolumetricDivisor converts a parcel's volume in cm³ into the weight it
+	// is charged as when that is more than its actual weight.
+	VolumetricDivisor float64     `json:"volumetric_divisor"`
+	MinCharge         float64     `json:"min_charge"`
+	Active            bool        `json:"active"`
+	Zones             []RateZone  `json:"zones"`
+	Lanes             []RateLane  `json:"lanes"`
+	Surcharges        []Surcharge `json:"surcharges"`
+	CreatedAt         time.Time   `json:"created_at"`
+	UpdatedAt         time.Time   `json:"updated_at"`
+}
+
+// RateZone groups addresses by country, region and postal code prefix. Empty
+// lists match any region or postal code.
+type RateZone struct {
+	Code           string   `json:"code"`
+	Name           string   `json:"name,omitempty"`
+	Country        string   `json:"country"`
+	Regions        []string `json:"regions,omitempty"`
+	PostalPrefixes []string `json:"postal_prefixes,omitempty"`
+}
+
+// RateLane prices shipments from one zone to another. An empty zone code
+// matches any address. The weight charge is the chargeable weight times the
+// rate of the highest break it reaches.
+type RateLane struct {
+	Origin      string        `json:"origin,omitempty"`
+	Destination string        `json:"destination,omitempty"`
+	BaseCharge  float64       `json:"base_charge"`
+	PerKm       float64       `json:"per_km,omitempty"`
+	Breaks      []WeightBreak `json:"breaks"`
+}
+
+// WeightBreak is the rate per kilogram from MinKg up to the next break.
+type WeightBreak struct {
+	MinKg float64 `json:"min_kg"`
+	PerKg float64 `json:"per_kg"`
+}
+
+// Surcharge is an extra charge on top of the freight.
+type Surcharge struct {
+	Code      string  `json:"code"`
+	Name      string  `json:"name,omitempty"`
+	Kind      string  `json:"kind"`
+	Amount    float64 `json:"amount"`
+	Condition string  `json:"condition"`
+}
+
+// Quote is an itemised price for a shipment.
+type Quote struct {
+	RateCardID         uuid.UUID   `json:"rate_card_id"`
+	RateCardName       string      `json:"rate_card_name"`
+	Currency           string      `json:"currency"`
+	OriginZone         string      `json:"origin_zone,omitempty"`
+	DestinationZone    string      `json:"destination_zone,omitempty"`
+	ActualWeightKg     float64     `json:"actual_weight_kg"`
+	VolumetricWeightKg float64     `json:"volumetric_weight_kg"`
+	ChargeableWeightKg float64     `json:"chargeable_weight_kg"`
+	DistanceKm         *float64    `json:"distance_km,omitempty"`
+	Lines              []QuoteLine `json:"lines"`
+	Total              float64     `json:"total"`
+}
+
+// QuoteLine is one charge on a quote. Code is "base", "weight", "distance",
+// "minimum" or a surcharge's code.
+type QuoteLine struct {
+	Code        string  `json:"code"`
+	Description string  `json:"description"`
+	Amount      float64 `json:"amount"`
+}
@@ -7,10 +7,22 @@ type MonthlyBreakdown struct {
 	Count int     `json:"count"`
 }
 
-// RevenueReport holds revenue report data with monthly breakdowns.
+// RevenueReport holds revenue report data with monthly breakdowns. Revenue
+// is invoiced: invoices issued in the period less credit notes, before tax.
 type RevenueReport struct {
 	TotalRevenue     float64            `json:"total_revenue"`
+	TotalTax         float64            `json:"total_tax"`
+	Collected        float64            `json:"collected"`
+	Outstanding      float64            `json:"outstanding"`
 	MonthlyBreakdown []MonthlyBreakdown `json:"monthly_breakdown"`
+	MonthlyData      []MonthlyRevenue   `json:"monthly_data"`
+}
+
+// MonthlyRevenue is one month of invoiced revenue and the orders billed.
+type MonthlyRevenue struct {
+	Month   string  `json:"month"`
+	Revenue float64 `json:"revenue"`
+	Orders  int     `json:"orders"`
 }
 
 // DeliveryReport holds delivery performance report data.
@@ -0,0 +1,147 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// RMA is a return merchandise authorization: a customer's request to send
+// back part of a shipped order, followed from approval through the return
+// shipment and inspection to a refund or credit.
+type RMA struct {
+	ID               uuid.UUID  `json:"id"`
+	TenantID         uuid.UUID  `json:"tenant_id"`
+	RMANumber        string     `json:"rma_number"`
+	OrderID          uuid.UUID  `json:"order_id"`
+	Status           string     `json:"status"`
+	Reason           string     `json:"reason"`
+	Notes            *string    `json:"notes"`
+	WarehouseID      *uuid.UUID `json:"warehouse_id"`
+	ReturnShipmentID *uuid.UUID `json:"return_shipment_id"`
+	RejectionReason  *string    `json:"rejection_reason"`
+	// Resolution is how the customer was compensated once the goods were
+	// inspected: refund, credit or none, for ResolutionAmount.
+	Resolution       *string    `json:"resolution"`
+	ResolutionAmount *float64   `json:"resolution_amount"`
+	RefundReference  *string    `json:"refund_reference"`
+	CreditNoteID     *uuid.UUID `json:"credit_note_id"`
+	CreatedBy        *uuid.UUID `json:"created_by"`
+	ApprovedAt       *time.Time `json:"approved_at"`
+	ReceivedAt       *time.Time `json:"received_at"`
+	ClosedAt         *time.Time `json:"closed_at"`
+	Lines            []RMALine  `json:"lines"`
+	CreatedAt        time.Time  `json:"created_at"`
+	UpdatedAt        time.Time  `json:"updated_at"`
+}
+
+// RMA statuses. A requested RMA is approved or rejected; an approved one
+// may come back on a return shipment, in transit, and is received when it
+// is inspected. Closing it settles the refund or credit. Until it is
+// received it can be cancelled.
+const (
+	RMARequested = "requested"
+	RMAApproved  = "approved"
+	RMARejected  = "rejected"
+	RMAInTransit = "in_transit"
+	RMAReceived  = "received"
+	RMAClosed    = "closed"
+	RMACancelled = "cancelled"
+)
+
+// RMAOpen reports whether an RMA in status still expects goods back.
+func RMAOpen(status string) bool {
+	return status == RMARequested || status == RMAApproved || status == RMAInTransit
+}
+
+// RMA resolutions.
+const (
+	RMARefund = "refund"
+	RMACredit = "credit"
+	RMANone   = "none"
+)
+
+// SequenceRMA numbers a tenant's RMAs, formatted with RMAPrefix.
+const (
+	SequenceRMA = "rma"
+	RMAPrefix   = "RMA"
+)
+
+// Condition grades given to returned goods on inspection.
+const (
+	ConditionAsNew    = "A"
+	ConditionGood     = "B"
+	ConditionDamaged  = "C"
+	ConditionUnusable = "D"
+)
+
+// IsCondition reports whether c is a known condition grade.
+func IsCondition(c string) bool {
+	switch c {
+	case ConditionAsNew, ConditionGood, ConditionDamaged, ConditionUnusable:
+		return true
+	}
+	return false
+}
+
+// Restockable reports whether goods in condition c go back on the shelf by
+// default rather than being written off.
+func Restockable(c string) bool {
+	return c == ConditionAsNew || c == ConditionGood
+}
+
+// RMALine is a quantity of an order line to be returned. SKU, Description
+// and UnitPrice are the order line's.
+type RMALine struct {
+	ID                 uuid.UUID `json:"id"`
+	RMAID              uuid.UUID `json:"rma_id"`
+	OrderLineID        uuid.UUID `json:"order_line_id"`
+	LineNumber         int       `json:"line_number"`
+	SKU                string    `json:"sku"`
+	Description        *string   `json:"description"`
+	UnitPrice          float64   `json:"unit_price"`
+	Quantity           int       `json:"quantity"`
+	ApprovedQuantity   int       `json:"approved_quantity"`
+	ReceivedQuantity   int       `json:"received_quantity"`
+	RestockedQuantity  int       `json:"restocked_quantity"`
+	WrittenOffQuantity int       `json:"written_off_quantity"`
+	Condition          *string   `json:"condition"`
+	InspectionNotes    *string   `json:"inspection_notes"`
+}
+
+// ReceivedValue is what the goods that came back were sold for: the amount
+// refunded or credited unless another is given.
+func (r *RMA) ReceivedValue() float64 {
+	var v float64
+	for _, l := range r.Lines {
+		v += float64(l.ReceivedQuantity) * l.UnitPrice
+	}
+	return RoundMoney(v)
+}
+
+// ReturnRequest asks for a quantity of an order line to be returned, or
+// approved for return.
+type ReturnRequest struct {
+	OrderLineID uuid.UUID `json:"order_line_id"`
+	Quantity    int       `json:"quantity"`
+}
+
+// Inspection records what arrived of an RMA line and in what condition.
+// Restock, when nil, follows the condition: Restockable grades go back into
+// stock and the rest are written off.
+type Inspection struct {
+	OrderLineID uuid.UUID `json:"order_line_id"`
+	Quantity    int       `json:"quantity"`
+	Condition   string    `json:"condition"`
+	Restock     *bool     `json:"restock"`
+	Notes       *string   `json:"notes"`
+}
+
+// RMAResolution settles a received RMA. Amount defaults to the RMA's
+// ReceivedValue.
+type RMAResolution struct {
+	Kind      string
+	Amount    *float64
+	Reference *string
+	CreatedBy *uuid.UUID
+}
@@ -0,0 +1,194 @@
+package models
+
+import (
+	"time"
+
+	"cargomax-api/internal/utils"
+
+	"github.com/google/uuid"
+)
+
+// Route plan statuses. Plans are edited while draft or assigned; the route
+// worker moves an assigned plan to in_progress when its driver's shift starts
+// and to completed once no stop is left pending.
+const (
+	RouteDraft      = "draft"
+	RouteAssigned   = "assigned"
+	RouteInProgress = "in_progress"
+	RouteCompleted  = "completed"
+	RouteCancelled  = "cancelled"
+)
+
+// Route stop types and statuses.
+const (
+	StopPickup   = "pickup"
+	StopDelivery = "delivery"
+	StopDepot    = "depot"
+	StopVisit    = "visit"
+
+	StopPending   = "pending"
+	StopArrived   = "arrived"
+	StopCompleted = "completed"
+	StopSkipped   = "skipped"
+)
+
+// Route defaults. A driver is at a stop while within its radius.
+const (
+	RouteStopRadiusMeters      = 200
+	RouteDefaultServiceMinutes = 10
+)
+
+// RouteDateLayout is the format of RoutePlan.PlanDate.
+const RouteDateLayout = "2006-01-02"
+
+// Route optimization defaults: proposed routes leave the depot at
+// RouteDefaultStartHour (UTC) on the plan date and are back within
+// RouteDefaultShiftHours.
+const (
+	RouteDefaultStartHour  = 8
+	RouteDefaultShiftHours = 10
+)
+
+// RoutePlan is a driver's ordered list of stops for one day.
+type RoutePlan struct {
+	ID           uuid.UUID   `json:"id"`
+	TenantID     uuid.UUID   `json:"tenant_id"`
+	PlanDate     string      `json:"plan_date"`
+	Name         string      `json:"name"`
+	WarehouseID  *uuid.UUID  `json:"warehouse_id"`
+	DriverID     *uuid.UUID  `json:"driver_id"`
+	VehicleID    *uuid.UUID  `json:"vehicle_id"`
+	ShiftID      *uuid.UUID  `json:"shift_id"`
+	Status       string      `json:"status"`
+	PlannedStart time.Time   `json:"planned_start"`
+	Notes        *string     `json:"notes"`
+	CreatedBy    *uuid.UUID  `json:"created_by"`
+	CreatedAt    time.Time   `json:"created_at"`
+	UpdatedAt    time.Time   `json:"updated_at"`
+	Stops        []RouteStop `json:"stops"`
+}
+
+// RouteStop is one stop on a route plan. Latitude/Longitude are copied from
+// the shipment, warehouse or zone the stop points at, or given explicitly.
+type RouteStop struct {
+	ID              uuid.UUID  `json:"id"`
+	TenantID        uuid.UUID  `json:"tenant_id"`
+	RouteID         uuid.UUID  `json:"route_id"`
+	Sequence        int        `json:"sequence"`
+	Type            string     `json:"type"`
+	ShipmentID      *uuid.UUID `json:"shipment_id"`
+	WarehouseID     *uuid.UUID `json:"warehouse_id"`
+	ZoneID          *uuid.UUID `json:"zone_id"`
+	Label           string     `json:"label"`
+	Latitude        float64    `json:"latitude"`
+	Longitude       float64    `json:"longitude"`
+	RadiusMeters    int        `json:"radius_meters"`
+	WindowStart     *time.Time `json:"window_start"`
+	WindowEnd       *time.Time `json:"window_end"`
+	ServiceMinutes  int        `json:"service_minutes"`
+	PlannedArrival  *time.Time `json:"planned_arrival"`
+	ActualArrival   *time.Time `json:"actual_arrival"`
+	ActualDeparture *time.Time `json:"actual_departure"`
+	Status          string     `json:"status"`
+	CreatedAt       time.Time  `json:"created_at"`
+	UpdatedAt       time.Time  `json:"updated_at"`
+}
+
+// IsRouteEditable reports whether a plan in status may still be changed.
+func IsRouteEditable(status string) bool {
+	return status == RouteDraft || status == RouteAssigned
+}
+
+// IsStopType reports whether t is a known route stop type.
+func IsStopType(t string) bool {
+	switch t {
+	case StopPickup, StopDelivery, StopDepot, StopVisit:
+		return true
+	}
+	return false
+}
+
+// TravelTime estimates the driving time between two points: the straight
+// line stretched by ETARoadFactor at ETADefaultSpeedKmh.
+func TravelTime(lat1, lng1, lat2, lng2 float64) time.Duration {
+	km := utils.HaversineMeters(lat1, lng1, lat2, lng2) / 1000 * ETARoadFactor
+	return time.Duration(km / ETADefaultSpeedKmh * float64(time.Hour)).Round(time.Minute)
+}
+
+// PlanArrivals numbers stops in order from 1 and sets each one's planned
+// arrival when driving them in that order from start. The first stop is
+// reached at start; a driver early for a window waits for it to open, then
+// spends the stop's service time. Skipped stops are passed over. It returns
+// the planned departure from the last stop.
+func PlanArrivals(start time.Time, stops []RouteStop) time.Time {
+	at := start
+	var prev *RouteStop
+	for i := range stops {
+		s := &stops[i]
+		s.Sequence = i + 1
+		if s.Status == StopSkipped {
+			s.PlannedArrival = nil
+			continue
+		}
+		if prev != nil {
+			at = at.Add(TravelTime(prev.Latitude, prev.Longitude, s.Latitude, s.Longitude))
+		}
+		arrival := at
+		s.PlannedArrival = &arrival
+		if s.WindowStart != nil && at.Before(*s.WindowStart) {
+			at = *s.WindowStart
+		}
+		at = at.Add(time.Duration(s.ServiceMinutes) * time.Minute)
+		prev = s
+	}
+	return at
+}
+
+// MissesWindow reports whether the stop is planned, or was reached, after its
+// window closed.
+func (s *RouteStop) MissesWindow() bool {
+	if s.WindowEnd == nil {
+		return false
+	}
+	at := s.PlannedArrival
+	if s.ActualArrival != nil {
+		at = s.ActualArrival
+	}
+	return at != nil && at.After(*s.WindowEnd)
+}
+
+// RouteProposal is the optimizer's suggestion for a day's deliveries from one
+// warehouse. Nothing is saved until the dispatcher accepts it.
+type RouteProposal struct {
+	PlanDate        string             `json:"plan_date"`
+	WarehouseID     uuid.UUID          `json:"warehouse_id"`
+	PlannedStart    time.Time          `json:"planned_start"`
+	ShiftHours      float64            `json:"shift_hours"`
+	TotalDistanceKm float64            `json:"total_distance_km"`
+	Routes          []ProposedRoute    `json:"routes"`
+	Unassigned      []UnroutedShipment `json:"unassigned"`
+}
+
+// ProposedRoute is the shipments one vehicle would deliver, in order.
+type ProposedRoute struct {
+	VehicleID  uuid.UUID      `json:"vehicle_id"`
+	LoadKg     float64        `json:"load_kg"`
+	CapacityKg *float64       `json:"capacity_kg"`
+	DistanceKm float64        `json:"distance_km"`
+	ReturnAt   time.Time      `json:"return_at"`
+	Stops      []ProposedStop `json:"stops"`
+}
+
+// ProposedStop is a delivery on a proposed route.
+type ProposedStop struct {
+	Sequence       int        `json:"sequence"`
+	ShipmentID     uuid.UUID  `json:"shipment_id"`
+	PlannedArrival time.Time  `json:"planned_arrival"`
+	WindowEnd      *time.Time `json:"window_end"`
+}
+
+// UnroutedShipment is a shipment the optimizer could not place, and why.
+type UnroutedShipment struct {
+	ShipmentID uuid.UUID `json:"shipment_id"`
+	Reason     string    `json:"reason"`
+}
@@ -18,3 +18,11 @@ type Shift struct {
 	CreatedAt time.Time  `json:"created_at"`
 	UpdatedAt time.Time  `json:"updated_at"`
 }
+
+// ActiveShift is an active shift joined with its driver's name and truck plate
+// for the manager dashboard.
+type ActiveShift struct {
+	Shift
+	DriverName string `json:"driver_name"`
+	TruckPlate string `json:"truck_plate"`
+}
@@ -1,7 +1,9 @@
 package models
 
 import (
+	"strings"
 	"time"
+	"unicode"
 
 	"github.com/google/uuid"
 )
@@ -22,6 +24,210 @@ type Shipment struct {
 	CustomerName      *string    `json:"customer_name"`
 	CustomerEmail     *string    `json:"customer_email"`
 	Notes             *string    `json:"notes"`
-	CreatedAt         time.Time  `json:"created_at"`
-	UpdatedAt         time.Time  `json:"updated_at"`
+
+	// DestinationLatitude/Longitude locate the drop-off for the live ETA.
+	// PredictedDelivery is the latest ETA from the driver's pings, compared
+	// against EstimatedDelivery, which is the time promised to the customer.
+	DestinationLatitude  *float64   `json:"destination_latitude"`
+	DestinationLongitude *float64   `json:"destination_longitude"`
+	PredictedDelivery    *time.Time `json:"predicted_delivery"`
+	PredictedLate        bool       `json:"predicted_late"`
+	ETAUpdatedAt         *time.Time `json:"eta_updated_at"`
+
+	// WarehouseID is the warehouse the shipment leaves from; its labels are
+	// printed there in the day's batch.
+	WarehouseID *uuid.UUID `json:"warehouse_id"`
+
+	// OriginAddress and DestinationAddress are the structured forms of
+	// Origin and Destination, when they were given.
+	OriginAddress      *Address `json:"origin_address"`
+	DestinationAddress *Address `json:"destination_address"`
+
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// Shipment statuses. Moves between them follow shipmentTransitions.
+const (
+	ShipmentPending        = "pending"
+	ShipmentProcessing     = "processing"
+	ShipmentPickedUp       = "picked_up"
+	ShipmentInTransit      = "in_transit"
+	ShipmentOutForDelivery = "out_for_delivery"
+	ShipmentDelayed        = "delayed"
+	ShipmentDelivered      = "delivered"
+	ShipmentCancelled      = "cancelled"
+	ShipmentReturned       = "returned"
+)
+
+// shipmentTransitions lists the statuses each status may move to. Cancelled
+// and returned are terminal; a delivered shipment can only come back as a
+// return.
+var shipmentTransitions = map[string][]string{
+	ShipmentPending:        {ShipmentProcessing, ShipmentPickedUp, ShipmentInTransit, ShipmentCancelled},
+	ShipmentProcessing:     {ShipmentPickedUp, ShipmentInTransit, ShipmentCancelled},
+	ShipmentPickedUp:       {ShipmentInTransit, ShipmentDelayed, ShipmentReturned},
+	ShipmentInTransit:      {ShipmentOutForDelivery, ShipmentDelayed, ShipmentDelivered, ShipmentReturned},
+	ShipmentOutForDelivery: {ShipmentDelivered, ShipmentDelayed, ShipmentInTransit, ShipmentReturned},
+	ShipmentDelayed:        {ShipmentInTransit, ShipmentOutForDelivery, ShipmentDelivered, ShipmentReturned},
+	ShipmentDelivered:      {ShipmentReturned},
+	ShipmentCancelled:      {},
+	ShipmentReturned:       {},
+}
+
+// IsShipmentStatus reports whether s is a known shipment status.
+func IsShipmentStatus(s string) bool {
+	_, ok := shipmentTransitions[s]
+	return ok
+}
+
+// CanTransitionShipment reports whether a shipment may move from one status
+// to another.
+func CanTransitionShipment(from, to string) bool {
+	for _, next := range shipmentTransitions[from] {
+		if next == to {
+			return true
+		}
+	}
+	return false
+}
+
+// IsShipmentOpen reports whether a shipment in status is still being moved,
+// i.e. it has not been delivered, cancelled or returned.
+func IsShipmentOpen(status string) bool {
+	switch status {
+	case ShipmentDelivered, ShipmentCancelled, ShipmentReturned:
+		return false
+	}
+	return true
+}
+
+// NextShipmentStatuses returns the statuses a shipment in status may move to.
+func NextShipmentStatuses(status string) []string {
+	return append([]string(nil), shipmentTransitions[status]...)
+}
+
+// ShipmentEvent is one entry on a shipment's tracking timeline. ActorType is
+// "user", "driver" or "system"; ActorID is nil for system events.
+type ShipmentEvent struct {
+	ID         uuid.UUID  `json:"id"`
+	TenantID   uuid.UUID  `json:"tenant_id"`
+	ShipmentID uuid.UUID  `json:"shipment_id"`
+	Status     string     `json:"status"`
+	Location   *string    `json:"location"`
+	Note       *string    `json:"note"`
+	ActorType  string     `json:"actor_type"`
+	ActorID    *uuid.UUID `json:"actor_id"`
+	OccurredAt time.Time  `json:"occurred_at"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+// ShipmentAssignment puts a shipment in the hands of a driver and vehicle,
+// optionally within a shift. The open assignment has a nil UnassignedAt;
+// closed ones form the shipment's assignment history.
+type ShipmentAssignment struct {
+	ID           uuid.UUID  `json:"id"`
+	TenantID     uuid.UUID  `json:"tenant_id"`
+	ShipmentID   uuid.UUID  `json:"shipment_id"`
+	DriverID     *uuid.UUID `json:"driver_id"`
+	VehicleID    *uuid.UUID `json:"vehicle_id"`
+	ShiftID      *uuid.UUID `json:"shift_id"`
+	AssignedBy   *uuid.UUID `json:"assigned_by"`
+	AssignedAt   time.Time  `json:"assigned_at"`
+	UnassignedAt *time.Time `json:"unassigned_at"`
+	CreatedAt    time.Time  `json:"created_at"`
+}
+
+// MatchesVerifier reports whether verifier proves knowledge of the shipment
+// for public tracking: either the customer's email address or its local part,
+// or the postcode at the end of the destination. Comparison ignores case and
+// spacing.
+func (s *Shipment) MatchesVerifier(verifier string) bool {
+	v := normalizeVerifier(verifier)
+	if len(v) < 3 {
+		return false
+	}
+	if s.CustomerEmail != nil && *s.CustomerEmail != "" {
+		email := normalizeVerifier(*s.CustomerEmail)
+		local, _, _ := strings.Cut(email, "@")
+		if v == email || v == local {
+			return true
+		}
+	}
+	if s.Destination != nil && strings.IndexFunc(v, unicode.IsDigit) >= 0 {
+		fields := strings.FieldsFunc(*s.Destination, func(r rune) bool { return r == ',' || unicode.IsSpace(r) })
+		// Postcodes are the final token ("60601") or the final two ("SW1A 1AA").
+		for n := 1; n <= 2 && n <= len(fields); n++ {
+			if v == normalizeVerifier(strings.Join(fields[len(fields)-n:], "")) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func normalizeVerifier(s string) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.IsSpace(r) || r == '-' {
+			return -1
+		}
+		return unicode.ToLower(r)
+	}, s)
+}
+
+// PublicTracking is the redacted view of a shipment returned to anonymous
+// consignees. It deliberately omits the tenant, customer details, internal
+// notes and who recorded each event. Origin and Destination are only the
+// city and region of the structured addresses, never the free-text address,
+// which is often a street address.
+type PublicTracking struct {
+	TrackingNumber    string                `json:"tracking_number"`
+	Status            string                `json:"status"`
+	Carrier           *string               `json:"carrier"`
+	Origin            *string               `json:"origin"`
+	Destination       *string               `json:"destination"`
+	EstimatedDelivery *time.Time            `json:"estimated_delivery"`
+	PredictedDelivery *time.Time            `json:"predicted_delivery"`
+	PredictedLate     bool                  `json:"predicted_late"`
+	ActualDelivery    *time.Time            `json:"actual_delivery"`
+	Events            []PublicTrackingEvent `json:"events"`
+}
+
+// PublicTrackingEvent is a timeline entry as shown on public tracking.
+type PublicTrackingEvent struct {
+	Status     string    `json:"status"`
+	Location   *string   `json:"location"`
+	OccurredAt time.Time `json:"occurred_at"`
+}
+
+// NewPublicTracking builds the redacted view of s and its timeline.
+func NewPublicTracking(s *Shipment, events []ShipmentEvent) *PublicTracking {
+	pt := &PublicTracking{
+		TrackingNumber:    s.TrackingNumber,
+		Status:            s.Status,
+		Carrier:           s.Carrier,
+		Origin:            publicLocality(s.OriginAddress),
+		Destination:       publicLocality(s.DestinationAddress),
+		EstimatedDelivery: s.EstimatedDelivery,
+		PredictedDelivery: s.PredictedDelivery,
+		PredictedLate:     s.PredictedLate,
+		ActualDelivery:    s.ActualDelivery,
+		Events:            make([]PublicTrackingEvent, 0, len(events)),
+	}
+	for _, e := range events {
+		pt.Events = append(pt.Events, PublicTrackingEvent{Status: e.Status, Location: e.Location, OccurredAt: e.OccurredAt})
+	}
+	return pt
+}
+
+// publicLocality is the city and region of a, or nil when there is no
+// structured address or it names neither.
+func publicLocality(a *Address) *string {
+	if a == nil {
+		return nil
+	}
+	if l := a.Locality(); l != "" {
+		return &l
+	}
+	return nil
 }
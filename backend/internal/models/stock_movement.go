@@ -0,0 +1,77 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// StockMovement is an entry in the stock ledger: a change to an item's stock
+// on hand, why it happened and the document behind it. Quantity is signed
+// and Balance is the item's quantity after the movement.
+type StockMovement struct {
+	ID              uuid.UUID  `json:"id"`
+	TenantID        uuid.UUID  `json:"tenant_id"`
+	InventoryItemID uuid.UUID  `json:"inventory_item_id"`
+	WarehouseID     *uuid.UUID `json:"warehouse_id"`
+	SKU             string     `json:"sku"`
+	Type            string     `json:"type"`
+	Quantity        int        `json:"quantity"`
+	Balance         int        `json:"balance"`
+	Reason          *string    `json:"reason"`
+	// ReferenceType and ReferenceID identify the document that moved the
+	// stock, such as an order or an RMA, and Reference is its number.
+	ReferenceType *string    `json:"reference_type"`
+	ReferenceID   *uuid.UUID `json:"reference_id"`
+	Reference     *string    `json:"reference"`
+	CreatedBy     *uuid.UUID `json:"created_by"`
+	CreatedAt     time.Time  `json:"created_at"`
+}
+
+// Stock movement types.
+const (
+	MovementReceipt    = "receipt"
+	MovementPick       = "pick"
+	MovementAdjustment = "adjustment"
+	MovementTransfer   = "transfer"
+	MovementReturn     = "return"
+	MovementWriteOff   = "write_off"
+)
+
+// IsMovementType reports whether t is a known stock movement type.
+func IsMovementType(t string) bool {
+	switch t {
+	case MovementReceipt, MovementPick, MovementAdjustment, MovementTransfer, MovementReturn, MovementWriteOff:
+		return true
+	}
+	return false
+}
+
+// StockRef is the document and reason recorded on the movements a change to
+// stock makes.
+type StockRef struct {
+	Type   string
+	ID     *uuid.UUID
+	Number string
+	Reason string
+}
+
+// StockMovementFilter narrows a stock movement listing. Zero fields match
+// everything; From is inclusive and To exclusive.
+type StockMovementFilter struct {
+	InventoryItemID *uuid.UUID
+	WarehouseID     *uuid.UUID
+	Type            string
+	From            *time.Time
+	To              *time.Time
+}
+
+// StockDiscrepancy is an item whose quantity on hand disagrees with the sum
+// of its stock movements.
+type StockDiscrepancy struct {
+	InventoryItemID uuid.UUID `json:"inventory_item_id"`
+	SKU             string    `json:"sku"`
+	WarehouseID     uuid.UUID `json:"warehouse_id"`
+	Quantity        int       `json:"quantity"`
+	LedgerQuantity  int       `json:"ledger_quantity"`
+}
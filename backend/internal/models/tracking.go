@@ -0,0 +1,115 @@
+package models
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Settings keys that shape a tenant's generated tracking numbers.
+const (
+	SettingTrackingPrefix = "tracking_prefix"
+	SettingTrackingDigits = "tracking_digits"
+)
+
+// SequenceTrackingNumber names the tenant sequence that numbers generated
+// tracking numbers.
+const SequenceTrackingNumber = "tracking_number"
+
+// Tracking number format bounds and defaults.
+const (
+	DefaultTrackingPrefix = "CM"
+	DefaultTrackingDigits = 9
+	MaxTrackingPrefix     = 6
+	MinTrackingDigits     = 6
+	MaxTrackingDigits     = 12
+)
+
+// TrackingFormat is how a tenant's tracking numbers are generated: Prefix,
+// then a serial zero-padded to Digits digits, then a Luhn check digit over
+// the serial. With the defaults serial 1234 becomes CM0000012344.
+type TrackingFormat struct {
+	Prefix string
+	Digits int
+}
+
+// DefaultTrackingFormat is the format used when a tenant has not set one.
+func DefaultTrackingFormat() TrackingFormat {
+	return TrackingFormat{Prefix: DefaultTrackingPrefix, Digits: DefaultTrackingDigits}
+}
+
+// ParseTrackingPrefix validates a tracking_prefix setting: up to six
+// letters, stored upper-case. An empty prefix gives all-digit numbers.
+func ParseTrackingPrefix(v string) (string, error) {
+	v = strings.ToUpper(strings.TrimSpace(v))
+	if len(v) > MaxTrackingPrefix {
+		return "", fmt.Errorf("tracking prefix must be at most %d letters", MaxTrackingPrefix)
+	}
+	for _, c := range v {
+		if c < 'A' || c > 'Z' {
+			return "", fmt.Errorf("tracking prefix must contain only letters A-Z")
+		}
+	}
+	return v, nil
+}
+
+// ParseTrackingDigits validates a tracking_digits setting.
+func ParseTrackingDigits(v string) (int, error) {
+	n, err := strconv.Atoi(strings.TrimSpace(v))
+	if err != nil || n < MinTrackingDigits || n > MaxTrackingDigits {
+		return 0, fmt.Errorf("tracking digits must be a whole number from %d to %d", MinTrackingDigits, MaxTrackingDigits)
+	}
+	return n, nil
+}
+
+// Format renders serial as a tracking number. It fails once serial no longer
+// fits in Digits digits.
+func (f TrackingFormat) Format(serial int64) (string, error) {
+	body := fmt.Sprintf("%0*d", f.Digits, serial)
+	if serial < 0 || len(body) > f.Digits {
+		return "", fmt.Errorf("tracking number serial %d does not fit in %d digits", serial, f.Digits)
+	}
+	return f.Prefix + body + string(LuhnDigit(body)), nil
+}
+
+// Matches reports whether tn has this format's shape: the prefix followed by
+// Digits+1 digits. It does not check the check digit.
+func (f TrackingFormat) Matches(tn string) bool {
+	body, ok := strings.CutPrefix(tn, f.Prefix)
+	if !ok || len(body) != f.Digits+1 {
+		return false
+	}
+	for _, c := range body {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// Valid reports whether tn has this format's shape and a correct check digit.
+func (f TrackingFormat) Valid(tn string) bool {
+	if !f.Matches(tn) {
+		return false
+	}
+	body := tn[len(f.Prefix):]
+	return LuhnDigit(body[:f.Digits]) == body[f.Digits]
+}
+
+// LuhnDigit returns the Luhn (mod 10) check digit for a string of digits.
+func LuhnDigit(digits string) byte {
+	sum := 0
+	double := true
+	for i := len(digits) - 1; i >= 0; i-- {
+		d := int(digits[i] - '0')
+		if double {
+			d *= 2
+			if d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+		double = !double
+	}
+	return byte('0' + (10-sum%10)%10)
+}
@@ -0,0 +1,53 @@
+package models
+
+import "testing"
+
+func TestLuhnDigit(t *testing.T) {
+	for digits, want := range map[string]byte{
+		"0":              '0',
+		"1":              '8',
+		"7992739871":     '3',
+		"49015420323751": '8', // an IMEI
+		"000001234":      '4',
+		"1234":           '4',
+		"999999999":      '9',
+	} {
+		if got := LuhnDigit(digits); got != want {
+			t.Errorf("LuhnDigit(%q) = %c, want %c", digits, got, want)
+		}
+	}
+}
+
+func TestTrackingFormat(t *testing.T) {
+	for _, tc := range []struct {
+		f      TrackingFormat
+		serial int64
+		want   string
+	}{
+		{DefaultTrackingFormat(), 1234, "CM0000012344"},
+		{DefaultTrackingFormat(), 0, "CM0000000000"},
+		{DefaultTrackingFormat(), 999999999, "CM9999999999"},
+		{TrackingFormat{Prefix: "", Digits: 6}, 42, "0000422"},
+		{TrackingFormat{Prefix: "XYZ", Digits: 12}, 7992739871, "XYZ0079927398713"},
+	} {
+		got, err := tc.f.Format(tc.serial)
+		if err != nil || got != tc.want {
+			t.Errorf("%+v.Format(%d) = %q, %v, want %q", tc.f, tc.serial, got, err, tc.want)
+			continue
+		}
+		if !tc.f.Matches(got) || !tc.f.Valid(got) {
+			t.Errorf("%+v does not accept its own %q", tc.f, got)
+		}
+	}
+	f := DefaultTrackingFormat()
+	for _, serial := range []int64{-1, 1000000000} {
+		if tn, err := f.Format(serial); err == nil {
+			t.Errorf("Format(%d) = %q, want an error", serial, tn)
+		}
+	}
+	for _, tn := range []string{"CM0000012345", "CM000001234", "XX0000012344", "CM00000A2344"} {
+		if f.Valid(tn) {
+			t.Errorf("Valid(%q)", tn)
+		}
+	}
+}
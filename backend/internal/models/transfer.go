@@ -0,0 +1,69 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// TransferOrder moves stock from one of a tenant's warehouses to another.
+type TransferOrder struct {
+	ID              uuid.UUID      `json:"id"`
+	TenantID        uuid.UUID      `json:"tenant_id"`
+	TransferNumber  string         `json:"transfer_number"`
+	FromWarehouseID uuid.UUID      `json:"from_warehouse_id"`
+	ToWarehouseID   uuid.UUID      `json:"to_warehouse_id"`
+	Status          string         `json:"status"`
+	ShipmentID      *uuid.UUID     `json:"shipment_id"`
+	Notes           *string        `json:"notes"`
+	CreatedBy       *uuid.UUID     `json:"created_by"`
+	DispatchedAt    *time.Time     `json:"dispatched_at"`
+	ReceivedAt      *time.Time     `json:"received_at"`
+	Lines           []TransferLine `json:"lines"`
+	CreatedAt       time.Time      `json:"created_at"`
+	UpdatedAt       time.Time      `json:"updated_at"`
+}
+
+// TransferLine is a quantity of one product on a transfer order, and how
+// much of it arrived.
+type TransferLine struct {
+	ID               uuid.UUID `json:"id"`
+	TransferOrderID  uuid.UUID `json:"transfer_order_id"`
+	ProductID        uuid.UUID `json:"product_id"`
+	SKU              string    `json:"sku"`
+	Quantity         int       `json:"quantity"`
+	ReceivedQuantity int       `json:"received_quantity"`
+}
+
+// Transfer order statuses. A pending transfer holds its stock at the source
+// warehouse until it is dispatched or cancelled; a dispatched one is in
+// transit until it is received at the destination.
+const (
+	TransferPending   = "pending"
+	TransferInTransit = "in_transit"
+	TransferReceived  = "received"
+	TransferCancelled = "cancelled"
+)
+
+// IsTransferStatus reports whether s is a known transfer order status.
+func IsTransferStatus(s string) bool {
+	switch s {
+	case TransferPending, TransferInTransit, TransferReceived, TransferCancelled:
+		return true
+	}
+	return false
+}
+
+// SequenceTransfer numbers a tenant's transfer orders, formatted with
+// TransferPrefix.
+const (
+	SequenceTransfer = "transfer"
+	TransferPrefix   = "TRF"
+)
+
+// TransferQuantity is a quantity of a SKU to send on a transfer order, or
+// that arrived when it is received.
+type TransferQuantity struct {
+	SKU      string
+	Quantity int
+}
@@ -20,6 +20,7 @@ type Vehicle struct {
 	NextService  *time.Time `json:"next_service"`
 	LicensePlate *string    `json:"license_plate"`
 	Year         *int       `json:"year"`
+	CapacityKg   *float64   `json:"capacity_kg"`
 	CreatedAt    time.Time  `json:"created_at"`
 	UpdatedAt    time.Time  `json:"updated_at"`
 }
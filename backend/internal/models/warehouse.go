@@ -6,7 +6,12 @@ import (
 	"github.com/google/uuid"
 )
 
-// Warehouse represents a storage warehouse facility.
+// Warehouse represents a storage warehouse facility. Its capacity is
+// measured in CapacityUnit, and UsedCapacity, the space the stock on hand
+// takes up, is kept in step with the stock ledger; it is exact, and only
+// rounded for display. The tenant's admins and managers are told when it
+// passes one of FillThresholds, percentages of capacity; FillAlertLevel is
+// the highest they were last told about.
 type Warehouse struct {
 	ID           uuid.UUID `json:"id"`
 	TenantID     uuid.UUID `json:"tenant_id"`
@@ -14,18 +19,67 @@ type Warehouse struct {
 	Location     *string   `json:"location"`
 	Address      *string   `json:"address"`
 	Capacity     int       `json:"capacity"`
-	UsedCapacity int       `json:"used_capacity"`
+	UsedCapacity float64   `json:"used_capacity"`
+	CapacityUnit string    `json:"capacity_unit"`
 	Manager      *string   `json:"manager"`
 	Phone        *string   `json:"phone"`
 	Status       string    `json:"status"`
-	CreatedAt    time.Time `json:"created_at"`
-	UpdatedAt    time.Time `json:"updated_at"`
+	Latitude     *float64  `json:"latitude"`
+	Longitude    *float64  `json:"longitude"`
+	// AddressDetails is the structured form of Address.
+	AddressDetails *Address  `json:"address_details"`
+	FillThresholds []int     `json:"fill_thresholds"`
+	FillAlertLevel int       `json:"fill_alert_level"`
+	CreatedAt      time.Time `json:"created_at"`
+	UpdatedAt      time.Time `json:"updated_at"`
 }
 
-// WarehouseCapacityStats holds aggregated capacity information.
+// Utilization is the share of the warehouse's capacity in use, as a
+// percentage.
+func (w *Warehouse) Utilization() float64 {
+	if w.Capacity <= 0 {
+		return 0
+	}
+	return w.UsedCapacity / float64(w.Capacity) * 100
+}
+
+// FillLevel is the highest of the warehouse's fill thresholds it has
+// reached, or 0 when it is below them all.
+func (w *Warehouse) FillLevel() int {
+	level, u := 0, w.Utilization()
+	for _, t := range w.FillThresholds {
+		if float64(t) <= u && t > level {
+			level = t
+		}
+	}
+	return level
+}
+
+// Warehouse capacity units.
+const (
+	CapacityPallets     = "pallets"
+	CapacityCubicMetres = "cubic_metres"
+	CapacityKg          = "kg"
+)
+
+// IsCapacityUnit reports whether s is a known capacity unit.
+func IsCapacityUnit(s string) bool {
+	switch s {
+	case CapacityPallets, CapacityCubicMetres, CapacityKg:
+		return true
+	}
+	return false
+}
+
+// DefaultFillThresholds are a new warehouse's fill thresholds.
+var DefaultFillThresholds = []int{80, 95}
+
+// WarehouseCapacityStats holds aggregated capacity information. The totals
+// add up warehouses that may measure capacity in different units;
+// Utilization is the mean of their own.
 type WarehouseCapacityStats struct {
 	TotalCapacity int     `json:"total_capacity"`
-	UsedCapacity  int     `json:"used_capacity"`
-	FreeCapacity  int     `json:"free_capacity"`
+	UsedCapacity  float64 `json:"used_capacity"`
+	FreeCapacity  float64 `json:"free_capacity"`
 	Utilization   float64 `json:"utilization"`
 }
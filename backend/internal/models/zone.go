@@ -14,8 +14,12 @@ type ApprovedZone struct {
 	Longitude    float64   `json:"longitude"`
 	RadiusMeters int       `json:"radius_meters"`
 	Type         string    `json:"type"`
-	CreatedAt    time.Time `json:"created_at"`
-	UpdatedAt    time.Time `json:"updated_at"`
+	// WarehouseID or ClientID is set on a zone created for that warehouse or
+	// client site; it follows the record's coordinates.
+	WarehouseID *uuid.UUID `json:"warehouse_id"`
+	ClientID    *uuid.UUID `json:"client_id"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
 }
 
 type AlertConfig struct {
@@ -29,3 +33,9 @@ type AlertConfig struct {
 	NotifyViaSMS             bool      `json:"notify_via_sms"`
 	UpdatedAt                time.Time `json:"updated_at"`
 }
+
+// Radii of the zones created for geocoded warehouses and client sites.
+const (
+	WarehouseZoneRadius  = 500
+	ClientSiteZoneRadius = 250
+)
@@ -0,0 +1,138 @@
+// Package pdf writes plain PDF documents: text in the standard Type 1 fonts
+// and filled rectangles on pages of any size. That is all shipping labels and
+// invoices need, so no font files are embedded and nothing outside the
+// standard library is used.
+package pdf
+
+import (
+	"bytes"
+	"compress/zlib"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Font is one of the standard fonts every PDF reader has.
+type Font int
+
+const (
+	Helvetica Font = iota
+	HelveticaBold
+	CourierBold
+)
+
+var fontNames = []string{"Helvetica", "Helvetica-Bold", "Courier-Bold"}
+
+// Document is a PDF being built, page by page.
+type Document struct {
+	pages []*Page
+}
+
+// Page is one page of a Document. Coordinates are in points (1/72 inch) from
+// the top left; PDF's own origin is the bottom left, so y is flipped on the
+// way in.
+type Page struct {
+	width, height float64
+	buf           bytes.Buffer
+}
+
+// AddPage appends a page width by height points and returns it.
+func (d *Document) AddPage(width, height float64) *Page {
+	p := &Page{width: width, height: height}
+	d.pages = append(d.pages, p)
+	return p
+}
+
+// Width returns the page width in points.
+func (p *Page) Width() float64 { return p.width }
+
+// Height returns the page height in points.
+func (p *Page) Height() float64 { return p.height }
+
+// Text sets s with its top at y.
+func (p *Page) Text(x, y float64, f Font, size float64, s string) {
+	if s == "" {
+		return
+	}
+	fmt.Fprintf(&p.buf, "BT /F%d %g Tf %.2f %.2f Td (%s) Tj ET\n", f+1, size, x, p.height-y-size*0.8, encode(s))
+}
+
+// TextRight sets s ending at x, for columns of amounts.
+func (p *Page) TextRight(x, y float64, f Font, size float64, s string) {
+	p.Text(x-TextWidth(f, size, s), y, f, size, s)
+}
+
+// Rect fills a w by h rectangle with its top left corner at x, y.
+func (p *Page) Rect(x, y, w, h float64) {
+	fmt.Fprintf(&p.buf, "%.2f %.2f %.2f %.2f re f\n", x, p.height-y-h, w, h)
+}
+
+// Write writes the document to w.
+func (d *Document) Write(w io.Writer) error {
+	if len(d.pages) == 0 {
+		return fmt.Errorf("document has no pages")
+	}
+
+	// Objects: 1 catalog, 2 page tree, then the fonts, then a page and its
+	// content stream for each page.
+	var doc bytes.Buffer
+	var offsets []int
+	obj := func(body string) {
+		offsets = append(offsets, doc.Len())
+		fmt.Fprintf(&doc, "%d 0 obj\n%s\nendobj\n", len(offsets), body)
+	}
+	firstPage := 3 + len(fontNames)
+
+	doc.WriteString("%PDF-1.4\n%\xe2\xe3\xcf\xd3\n")
+	obj("<< /Type /Catalog /Pages 2 0 R >>")
+	kids := make([]string, len(d.pages))
+	for i := range d.pages {
+		kids[i] = fmt.Sprintf("%d 0 R", firstPage+2*i)
+	}
+	obj(fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), len(d.pages)))
+	var fonts []string
+	for i, name := range fontNames {
+		obj(fmt.Sprintf("<< /Type /Font /Subtype /Type1 /BaseFont /%s /Encoding /WinAnsiEncoding >>", name))
+		fonts = append(fonts, fmt.Sprintf("/F%d %d 0 R", i+1, 3+i))
+	}
+	for i, p := range d.pages {
+		var z bytes.Buffer
+		zw := zlib.NewWriter(&z)
+		zw.Write(p.buf.Bytes())
+		zw.Close()
+		obj(fmt.Sprintf("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %g %g] /Resources << /Font << %s >> >> /Contents %d 0 R >>",
+			p.width, p.height, strings.Join(fonts, " "), firstPage+2*i+1))
+		obj(fmt.Sprintf("<< /Length %d /Filter /FlateDecode >>\nstream\n%s\nendstream", z.Len(), z.Bytes()))
+	}
+
+	xref := doc.Len()
+	fmt.Fprintf(&doc, "xref\n0 %d\n0000000000 65535 f \n", len(offsets)+1)
+	for _, off := range offsets {
+		fmt.Fprintf(&doc, "%010d 00000 n \n", off)
+	}
+	fmt.Fprintf(&doc, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(offsets)+1, xref)
+
+	_, err := w.Write(doc.Bytes())
+	return err
+}
+
+// encode encodes s for a literal string in WinAnsiEncoding: the Latin-1
+// range is kept, anything else becomes "?", and delimiters and bytes outside
+// ASCII are escaped.
+func encode(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		switch {
+		case r == '(' || r == ')' || r == '\\':
+			b.WriteByte('\\')
+			b.WriteRune(r)
+		case r >= ' ' && r < 0x7f:
+			b.WriteRune(r)
+		case r >= 0xa0 && r <= 0xff:
+			fmt.Fprintf(&b, "\\%03o", r)
+		default:
+			b.WriteByte('?')
+		}
+	}
+	return b.String()
+}
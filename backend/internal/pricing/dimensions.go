@@ -0,0 +1,60 @@
+package pricing
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// ErrBadDimensions is returned, with the text at fault, for dimensions that
+// cannot be read.
+var ErrBadDimensions = errors.New("dimensions are not length x width x height")
+
+// dimensionUnits converts each accepted unit into centimetres.
+var dimensionUnits = map[string]float64{
+	"":       1,
+	"cm":     1,
+	"mm":     0.1,
+	"m":      100,
+	"in":     2.54,
+	"inch":   2.54,
+	"inches": 2.54,
+	`"`:      2.54,
+}
+
+// ParseDimensions reads a shipment's dimensions as written by users, such as
+// "120x80x50", "120 x 80 x 50 cm", "1.2×0.8×0.5 m" or "48x40x36in", and
+// returns its length, width and height in centimetres. Without a unit the
+// numbers are centimetres. A decimal comma is read as a decimal point, so
+// "1,5x2x3 m" is 1.5 m long; a number with more than one separator, such as
+// "1,200.5", is rejected rather than guessed at.
+func ParseDimensions(s string) (l, w, h float64, err error) {
+	text := strings.ToLower(strings.TrimSpace(s))
+	unit := ""
+	for u := range dimensionUnits {
+		if u != "" && strings.HasSuffix(text, u) && len(u) > len(unit) {
+			unit = u
+		}
+	}
+	text = strings.TrimSpace(strings.TrimSuffix(text, unit))
+	text = strings.NewReplacer("×", "x", "*", "x").Replace(text)
+
+	parts := strings.Split(text, "x")
+	if len(parts) != 3 {
+		return 0, 0, 0, fmt.Errorf("%w: %q", ErrBadDimensions, s)
+	}
+	var v [3]float64
+	for i, p := range parts {
+		p = strings.TrimSpace(strings.TrimSuffix(strings.TrimSpace(p), unit))
+		if strings.Count(p, ",")+strings.Count(p, ".") > 1 {
+			return 0, 0, 0, fmt.Errorf("%w: %q has more than one decimal separator", ErrBadDimensions, p)
+		}
+		n, err := strconv.ParseFloat(strings.Replace(p, ",", ".", 1), 64)
+		if err != nil || n <= 0 {
+			return 0, 0, 0, fmt.Errorf("%w: %q", ErrBadDimensions, s)
+		}
+		v[i] = n * dimensionUnits[unit]
+	}
+	return v[0], v[1], v[2], nil
+}
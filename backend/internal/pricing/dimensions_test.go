@@ -0,0 +1,61 @@
+package pricing
+
+import (
+	"errors"
+	"math"
+	"testing"
+)
+
+func TestParseDimensions(t *testing.T) {
+	for _, tc := range []struct {
+		in      string
+		l, w, h float64
+	}{
+		{"120x80x50", 120, 80, 50},
+		{"120 x 80 x 50 cm", 120, 80, 50},
+		{"120X80X50CM", 120, 80, 50},
+		{"120*80*50", 120, 80, 50},
+		{"1200x800x500mm", 120, 80, 50},
+		{"1.2×0.8×0.5 m", 120, 80, 50},
+		{"1,2x0,8x0,5 m", 120, 80, 50},
+		{"1,5x2x3", 1.5, 2, 3},
+		{"10 in x 20 in x 5 in", 25.4, 50.8, 12.7},
+		{"48x40x36in", 121.92, 101.6, 91.44},
+		{"10x10x10 inches", 25.4, 25.4, 25.4},
+		{`10x10x10"`, 25.4, 25.4, 25.4},
+		{"  30 x 20 x 10  ", 30, 20, 10},
+	} {
+		l, w, h, err := ParseDimensions(tc.in)
+		if err != nil {
+			t.Errorf("ParseDimensions(%q): %v", tc.in, err)
+			continue
+		}
+		if !near(l, tc.l) || !near(w, tc.w) || !near(h, tc.h) {
+			t.Errorf("ParseDimensions(%q) = %g x %g x %g, want %g x %g x %g", tc.in, l, w, h, tc.l, tc.w, tc.h)
+		}
+	}
+}
+
+func TestParseDimensionsRejects(t *testing.T) {
+	for _, in := range []string{
+		"",
+		"120x80",
+		"120x80x50x10",
+		"120xx50",
+		"abcx80x50",
+		"0x80x50",
+		"-1x80x50",
+		"120x80x50 ft",
+		"1,200.5x80x50",
+		"1.2.3x80x50",
+		"1,2,3x80x50",
+	} {
+		if _, _, _, err := ParseDimensions(in); !errors.Is(err, ErrBadDimensions) {
+			t.Errorf("ParseDimensions(%q): err %v, want ErrBadDimensions", in, err)
+		}
+	}
+}
+
+func near(a, b float64) bool {
+	return math.Abs(a-b) < 1e-9
+}
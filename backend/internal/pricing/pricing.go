@@ -0,0 +1,252 @@
+// Package pricing quotes shipments from a tenant's rate cards: it places the
+// origin and destination in the cards' zones, picks the lane between them,
+// charges the chargeable weight (actual or volumetric, whichever is more) at
+// the lane's weight break, adds any distance charge and surcharges, and
+// itemises the result. It knows nothing about the database.
+package pricing
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+
+	"cargomax-api/internal/models"
+)
+
+// ErrNoRate is returned when no rate card has a lane for the shipment.
+var ErrNoRate = errors.New("no rate card lane covers this shipment")
+
+// Request is what a shipment is priced on.
+type Request struct {
+	Origin      *models.Address
+	Destination *models.Address
+	WeightKg    float64
+	// Dimensions is the shipment's free-text dimensions; see ParseDimensions.
+	Dimensions string
+	// DistanceKm is the road distance, when the endpoints are known. Lanes
+	// with a per-kilometre rate cannot price a request without it.
+	DistanceKm *float64
+	// Options ask for conditional surcharges, e.g. "residential".
+	Options []string
+}
+
+// Quote prices req. cards are tried in order, the client's card before the
+// tenant default: the lane comes from the first card with one that covers the
+// request, and a surcharge from the first card that defines its code. The
+// card the lane comes from supplies the currency, volumetric divisor and
+// minimum charge.
+func Quote(cards []*models.RateCard, req Request) (*models.Quote, error) {
+	var card *models.RateCard
+	var lane *models.RateLane
+	for _, c := range cards {
+		if lane = bestLane(c, req.Origin, req.Destination); lane != nil {
+			card = c
+			break
+		}
+	}
+	if lane == nil {
+		return nil, ErrNoRate
+	}
+
+	q := &models.Quote{
+		RateCardID:      card.ID,
+		RateCardName:    card.Name,
+		Currency:        card.Currency,
+		OriginZone:      lane.Origin,
+		DestinationZone: lane.Destination,
+		ActualWeightKg:  math.Max(req.WeightKg, 0),
+		DistanceKm:      req.DistanceKm,
+	}
+	if req.Dimensions != "" {
+		l, w, h, err := ParseDimensions(req.Dimensions)
+		if err != nil {
+			return nil, err
+		}
+		q.VolumetricWeightKg = round(l*w*h/card.VolumetricDivisor, 2)
+	}
+	// Chargeable weight is rounded up to the next tenth of a kilogram.
+	q.ChargeableWeightKg = math.Ceil(math.Max(q.ActualWeightKg, q.VolumetricWeightKg)*10-1e-9) / 10
+
+	add := func(code, desc string, amount float64) {
+		q.Lines = append(q.Lines, models.QuoteLine{Code: code, Description: desc, Amount: round(amount, 2)})
+	}
+	if lane.BaseCharge > 0 {
+		add("base", "Base charge", lane.BaseCharge)
+	}
+	if len(lane.Breaks) > 0 {
+		perKg := lane.Breaks[0].PerKg
+		for _, b := range lane.Breaks {
+			if q.ChargeableWeightKg >= b.MinKg {
+				perKg = b.PerKg
+			}
+		}
+		add("weight", fmt.Sprintf("%s kg at %s/kg", num(q.ChargeableWeightKg), num(perKg)), q.ChargeableWeightKg*perKg)
+	}
+	if lane.PerKm > 0 {
+		if req.DistanceKm == nil {
+			return nil, fmt.Errorf("the %s lane charges by distance, which needs the origin and destination coordinates", laneName(lane))
+		}
+		add("distance", fmt.Sprintf("%s km at %s/km", num(round(*req.DistanceKm, 1)), num(lane.PerKm)), round(*req.DistanceKm, 1)*lane.PerKm)
+	}
+	freight := sum(q.Lines)
+	if freight < card.MinCharge {
+		add("minimum", "Minimum charge adjustment", card.MinCharge-freight)
+		freight = card.MinCharge
+	}
+
+	surcharges, err := surchargesFor(cards, card.Currency, req.Options)
+	if err != nil {
+		return nil, err
+	}
+	for _, s := range surcharges {
+		name := s.Name
+		if name == "" {
+			name = s.Code
+		}
+		switch s.Kind {
+		case models.SurchargePercent:
+			add(s.Code, fmt.Sprintf("%s (%s%%)", name, num(s.Amount)), freight*s.Amount/100)
+		case models.SurchargePerKg:
+			add(s.Code, fmt.Sprintf("%s (%s/kg)", name, num(s.Amount)), q.ChargeableWeightKg*s.Amount)
+		default:
+			add(s.Code, name, s.Amount)
+		}
+	}
+	q.Total = round(sum(q.Lines), 2)
+	return q, nil
+}
+
+// surchargesFor returns the surcharges that apply: every "always" surcharge
+// and those options ask for, the first card defining a code winning. Cards in
+// another currency than the quote's are passed over. An option no card
+// defines is an error, so a typo is not silently free.
+func surchargesFor(cards []*models.RateCard, currency string, options []string) ([]models.Surcharge, error) {
+	var out []models.Surcharge
+	seen := map[string]bool{}
+	known := map[string]bool{}
+	want := map[string]bool{}
+	for _, o := range options {
+		want[strings.ToLower(strings.TrimSpace(o))] = true
+	}
+	for _, c := range cards {
+		if c.Currency != currency {
+			continue
+		}
+		for _, s := range c.Surcharges {
+			known[s.Condition] = true
+			if seen[s.Code] {
+				continue
+			}
+			seen[s.Code] = true
+			if s.Condition == models.SurchargeAlways || want[s.Condition] {
+				out = append(out, s)
+			}
+		}
+	}
+	for o := range want {
+		if o != "" && !known[o] {
+			return nil, fmt.Errorf("no surcharge applies to option %q", o)
+		}
+	}
+	return out, nil
+}
+
+// bestLane returns the card's lane that covers origin to destination most
+// specifically, or nil. A lane with an empty zone covers any address; between
+// covering lanes the one whose zones match more closely wins, and the first
+// listed breaks ties.
+func bestLane(c *models.RateCard, origin, dest *models.Address) *models.RateLane {
+	zones := make(map[string]*models.RateZone, len(c.Zones))
+	for i := range c.Zones {
+		zones[c.Zones[i].Code] = &c.Zones[i]
+	}
+	var best *models.RateLane
+	bestScore := -1
+	for i := range c.Lanes {
+		l := &c.Lanes[i]
+		so := zoneScore(zones[l.Origin], l.Origin, origin)
+		sd := zoneScore(zones[l.Destination], l.Destination, dest)
+		if so < 0 || sd < 0 {
+			continue
+		}
+		// The destination weighs more: lanes are mostly priced by where a
+		// shipment goes.
+		if score := sd*100 + so; score > bestScore {
+			best, bestScore = l, score
+		}
+	}
+	return best
+}
+
+// zoneScore says how closely a falls in zone z: -1 when it does not, 0 for a
+// lane end with no zone, then more for a country, a region and a longer postal
+// prefix.
+func zoneScore(z *models.RateZone, code string, a *models.Address) int {
+	if code == "" {
+		return 0
+	}
+	if z == nil || a == nil || !strings.EqualFold(z.Country, a.Country) {
+		return -1
+	}
+	score := 1
+	if len(z.Regions) > 0 {
+		ok := false
+		for _, r := range z.Regions {
+			if strings.EqualFold(r, strings.TrimSpace(a.Region)) {
+				ok = true
+			}
+		}
+		if !ok {
+			return -1
+		}
+		score++
+	}
+	if len(z.PostalPrefixes) > 0 {
+		postal := postalKey(a.PostalCode)
+		longest := -1
+		for _, p := range z.PostalPrefixes {
+			if k := postalKey(p); strings.HasPrefix(postal, k) && len(k) > longest {
+				longest = len(k)
+			}
+		}
+		if longest < 0 {
+			return -1
+		}
+		score += 2 + longest
+	}
+	return score
+}
+
+func postalKey(s string) string {
+	return strings.ToUpper(strings.Join(strings.Fields(s), ""))
+}
+
+func laneName(l *models.RateLane) string {
+	o, d := l.Origin, l.Destination
+	if o == "" {
+		o = "any"
+	}
+	if d == "" {
+		d = "any"
+	}
+	return o + " to " + d
+}
+
+func sum(lines []models.QuoteLine) float64 {
+	t := 0.0
+	for _, l := range lines {
+		t += l.Amount
+	}
+	return t
+}
+
+func round(v float64, places int) float64 {
+	p := math.Pow(10, float64(places))
+	return math.Round(v*p) / p
+}
+
+// num formats a quantity for a line description without trailing zeros.
+func num(v float64) string {
+	return fmt.Sprintf("%g", round(v, 4))
+}
@@ -0,0 +1,199 @@
+package pricing
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	"cargomax-api/internal/models"
+)
+
+// defaultCard is a tenant default card: anywhere to Germany, and a cheaper
+// base into Berlin postcodes, with a fuel surcharge on every quote.
+func defaultCard() *models.RateCard {
+	return &models.RateCard{
+		Name:              "Default",
+		Currency:          "EUR",
+		VolumetricDivisor: 5000,
+		MinCharge:         25,
+		Zones: []models.RateZone{
+			{Code: "de", Country: "DE"},
+			{Code: "de-berlin", Country: "DE", PostalPrefixes: []string{"10"}},
+		},
+		Lanes: []models.RateLane{
+			{Destination: "de", BaseCharge: 10, Breaks: []models.WeightBreak{{MinKg: 0, PerKg: 1}, {MinKg: 10, PerKg: 0.8}}},
+			{Origin: "de", Destination: "de-berlin", BaseCharge: 5, Breaks: []models.WeightBreak{{MinKg: 0, PerKg: 2}}},
+		},
+		Surcharges: []models.Surcharge{
+			{Code: "fuel", Name: "Fuel", Kind: models.SurchargePercent, Amount: 10, Condition: models.SurchargeAlways},
+			{Code: "residential", Kind: models.SurchargeFlat, Amount: 4.5, Condition: "residential"},
+			{Code: "hazmat", Kind: models.SurchargePerKg, Amount: 0.5, Condition: "hazmat"},
+		},
+	}
+}
+
+var (
+	munich  = &models.Address{City: "Munich", PostalCode: "80331", Country: "DE"}
+	hamburg = &models.Address{City: "Hamburg", PostalCode: "20095", Country: "DE"}
+	berlin  = &models.Address{City: "Berlin", PostalCode: "10115", Country: "DE"}
+	paris   = &models.Address{City: "Paris", PostalCode: "75001", Country: "FR"}
+)
+
+// lines renders a quote's lines as "code=amount" for comparison.
+func lines(q *models.Quote) []string {
+	out := make([]string, len(q.Lines))
+	for i, l := range q.Lines {
+		out[i] = fmt.Sprintf("%s=%g", l.Code, l.Amount)
+	}
+	return out
+}
+
+func TestQuote(t *testing.T) {
+	for _, tc := range []struct {
+		name       string
+		req        Request
+		chargeable float64
+		lines      []string
+		total      float64
+	}{
+		{
+			name:       "actual weight",
+			req:        Request{Origin: munich, Destination: hamburg, WeightKg: 20, Dimensions: "50x40x30"},
+			chargeable: 20,
+			lines:      []string{"base=10", "weight=16", "fuel=2.6"},
+			total:      28.6,
+		},
+		{
+			name:       "volumetric weight",
+			req:        Request{Origin: munich, Destination: hamburg, WeightKg: 5, Dimensions: "120x80x50 cm"},
+			chargeable: 96,
+			lines:      []string{"base=10", "weight=76.8", "fuel=8.68"},
+			total:      95.48,
+		},
+		{
+			name:       "minimum charge",
+			req:        Request{Origin: munich, Destination: hamburg, WeightKg: 2.01},
+			chargeable: 2.1,
+			lines:      []string{"base=10", "weight=2.1", "minimum=12.9", "fuel=2.5"},
+			total:      27.5,
+		},
+		{
+			name:       "closer zone wins",
+			req:        Request{Origin: munich, Destination: berlin, WeightKg: 10},
+			chargeable: 10,
+			lines:      []string{"base=5", "weight=20", "fuel=2.5"},
+			total:      27.5,
+		},
+		{
+			name:       "optional surcharges",
+			req:        Request{Origin: munich, Destination: hamburg, WeightKg: 20, Options: []string{" Residential", "hazmat"}},
+			chargeable: 20,
+			lines:      []string{"base=10", "weight=16", "fuel=2.6", "residential=4.5", "hazmat=10"},
+			total:      43.1,
+		},
+	} {
+		q, err := Quote([]*models.RateCard{defaultCard()}, tc.req)
+		if err != nil {
+			t.Errorf("%s: %v", tc.name, err)
+			continue
+		}
+		if q.ChargeableWeightKg != tc.chargeable {
+			t.Errorf("%s: chargeable weight %g, want %g", tc.name, q.ChargeableWeightKg, tc.chargeable)
+		}
+		if got := lines(q); !reflect.DeepEqual(got, tc.lines) {
+			t.Errorf("%s: lines %q, want %q", tc.name, got, tc.lines)
+		}
+		if q.Total != tc.total {
+			t.Errorf("%s: total %g, want %g", tc.name, q.Total, tc.total)
+		}
+	}
+}
+
+func TestQuoteZones(t *testing.T) {
+	q, err := Quote([]*models.RateCard{defaultCard()}, Request{Origin: munich, Destination: berlin, WeightKg: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if q.OriginZone != "de" || q.DestinationZone != "de-berlin" {
+		t.Errorf("zones %q to %q, want \"de\" to \"de-berlin\"", q.OriginZone, q.DestinationZone)
+	}
+	if q.Currency != "EUR" || q.RateCardName != "Default" {
+		t.Errorf("quote from %q in %s, want Default in EUR", q.RateCardName, q.Currency)
+	}
+}
+
+func TestQuoteClientCard(t *testing.T) {
+	client := &models.RateCard{
+		Name:              "Client",
+		Currency:          "EUR",
+		VolumetricDivisor: 5000,
+		Zones:             []models.RateZone{{Code: "de-berlin", Country: "DE", PostalPrefixes: []string{"10"}}},
+		Lanes:             []models.RateLane{{Destination: "de-berlin", BaseCharge: 3, Breaks: []models.WeightBreak{{MinKg: 0, PerKg: 1}}}},
+		Surcharges: []models.Surcharge{
+			{Code: "fuel", Kind: models.SurchargePercent, Amount: 5, Condition: models.SurchargeAlways},
+		},
+	}
+	cards := []*models.RateCard{client, defaultCard()}
+
+	// The client's lane and fuel rate, the default's residential surcharge.
+	q, err := Quote(cards, Request{Origin: munich, Destination: berlin, WeightKg: 10, Options: []string{"residential"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"base=3", "weight=10", "fuel=0.65", "residential=4.5"}
+	if got := lines(q); q.RateCardName != "Client" || !reflect.DeepEqual(got, want) {
+		t.Errorf("Berlin: %s lines %q, want Client lines %q", q.RateCardName, got, want)
+	}
+
+	// No client lane to Hamburg: the default prices it, still with the
+	// client's fuel rate.
+	q, err = Quote(cards, Request{Origin: munich, Destination: hamburg, WeightKg: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want = []string{"base=10", "weight=8", "minimum=7", "fuel=1.25"}
+	if got := lines(q); q.RateCardName != "Default" || !reflect.DeepEqual(got, want) {
+		t.Errorf("Hamburg: %s lines %q, want Default lines %q", q.RateCardName, got, want)
+	}
+
+	// A card in another currency lends no surcharges.
+	client.Currency = "USD"
+	q, err = Quote(cards, Request{Origin: munich, Destination: hamburg, WeightKg: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want = []string{"base=10", "weight=8", "minimum=7", "fuel=2.5"}
+	if got := lines(q); !reflect.DeepEqual(got, want) {
+		t.Errorf("USD client card: lines %q, want %q", got, want)
+	}
+}
+
+func TestQuoteDistance(t *testing.T) {
+	card := &models.RateCard{Name: "Courier", Currency: "EUR", VolumetricDivisor: 5000, Lanes: []models.RateLane{{PerKm: 1.5}}}
+	if _, err := Quote([]*models.RateCard{card}, Request{Origin: munich, Destination: paris, WeightKg: 1}); err == nil || !strings.Contains(err.Error(), "distance") {
+		t.Errorf("quote without a distance: err %v, want a distance error", err)
+	}
+	km := 12.34
+	q, err := Quote([]*models.RateCard{card}, Request{Origin: munich, Destination: paris, WeightKg: 1, DistanceKm: &km})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := lines(q), []string{"distance=18.45"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("lines %q, want %q", got, want)
+	}
+}
+
+func TestQuoteErrors(t *testing.T) {
+	cards := []*models.RateCard{defaultCard()}
+	if _, err := Quote(cards, Request{Origin: munich, Destination: paris, WeightKg: 1}); !errors.Is(err, ErrNoRate) {
+		t.Errorf("quote to France: err %v, want ErrNoRate", err)
+	}
+	if _, err := Quote(cards, Request{Origin: munich, Destination: hamburg, WeightKg: 1, Options: []string{"liftgate"}}); err == nil {
+		t.Errorf("quote with an unknown option: no error")
+	}
+	if _, err := Quote(cards, Request{Origin: munich, Destination: hamburg, WeightKg: 1, Dimensions: "big"}); !errors.Is(err, ErrBadDimensions) {
+		t.Errorf("quote with unreadable dimensions: err %v, want ErrBadDimensions", err)
+	}
+}
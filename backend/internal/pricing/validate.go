@@ -0,0 +1,147 @@
+package pricing
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"strings"
+
+	"cargomax-api/internal/models"
+)
+
+var (
+	codePattern     = regexp.MustCompile(`^[a-z0-9_-]{1,32}$`)
+	currencyPattern = regexp.MustCompile(`^[A-Z]{3}$`)
+	countryPattern  = regexp.MustCompile(`^[A-Z]{2}$`)
+)
+
+// Validate normalizes a rate card and checks that it can price shipments:
+// codes are lower-case and unique, lanes name zones of the card and are not
+// listed twice, weight breaks start at 0 kg and rise, and no rate is
+// negative.
+func Validate(c *models.RateCard) error {
+	c.Name = strings.TrimSpace(c.Name)
+	if c.Name == "" {
+		return fmt.Errorf("rate card name is required")
+	}
+	c.Currency = strings.ToUpper(strings.TrimSpace(c.Currency))
+	if c.Currency == "" {
+		c.Currency = models.RateDefaultCurrency
+	}
+	if !currencyPattern.MatchString(c.Currency) {
+		return fmt.Errorf("currency must be a three-letter ISO 4217 code")
+	}
+	if c.VolumetricDivisor == 0 {
+		c.VolumetricDivisor = models.RateDefaultDivisor
+	}
+	if !positive(c.VolumetricDivisor) {
+		return fmt.Errorf("volumetric divisor must be positive")
+	}
+	if !nonNegative(c.MinCharge) {
+		return fmt.Errorf("minimum charge cannot be negative")
+	}
+
+	zones := map[string]bool{}
+	for i := range c.Zones {
+		z := &c.Zones[i]
+		z.Code = strings.ToLower(strings.TrimSpace(z.Code))
+		z.Name = strings.TrimSpace(z.Name)
+		z.Country = strings.ToUpper(strings.TrimSpace(z.Country))
+		if !codePattern.MatchString(z.Code) {
+			return fmt.Errorf("zone code %q must be 1-32 lower-case letters, digits, - or _", z.Code)
+		}
+		if zones[z.Code] {
+			return fmt.Errorf("zone %q is defined twice", z.Code)
+		}
+		zones[z.Code] = true
+		if !countryPattern.MatchString(z.Country) {
+			return fmt.Errorf("zone %q: country must be a two-letter ISO code", z.Code)
+		}
+		z.Regions = trimAll(z.Regions, strings.TrimSpace)
+		z.PostalPrefixes = trimAll(z.PostalPrefixes, postalKey)
+	}
+
+	lanes := map[string]bool{}
+	for i := range c.Lanes {
+		l := &c.Lanes[i]
+		l.Origin = strings.ToLower(strings.TrimSpace(l.Origin))
+		l.Destination = strings.ToLower(strings.TrimSpace(l.Destination))
+		for _, code := range []string{l.Origin, l.Destination} {
+			if code != "" && !zones[code] {
+				return fmt.Errorf("lane %s: no zone %q on this card", laneName(l), code)
+			}
+		}
+		key := l.Origin + "\x00" + l.Destination
+		if lanes[key] {
+			return fmt.Errorf("lane %s is defined twice", laneName(l))
+		}
+		lanes[key] = true
+		if !nonNegative(l.BaseCharge) || !nonNegative(l.PerKm) {
+			return fmt.Errorf("lane %s: rates cannot be negative", laneName(l))
+		}
+		for j, b := range l.Breaks {
+			switch {
+			case j == 0 && b.MinKg != 0:
+				return fmt.Errorf("lane %s: the first weight break must start at 0 kg", laneName(l))
+			case j > 0 && !(b.MinKg > l.Breaks[j-1].MinKg):
+				return fmt.Errorf("lane %s: weight breaks must rise", laneName(l))
+			case !nonNegative(b.MinKg) || !nonNegative(b.PerKg):
+				return fmt.Errorf("lane %s: rates cannot be negative", laneName(l))
+			}
+		}
+		if l.Breaks == nil {
+			l.Breaks = []models.WeightBreak{}
+		}
+	}
+
+	surcharges := map[string]bool{}
+	for i := range c.Surcharges {
+		s := &c.Surcharges[i]
+		s.Code = strings.ToLower(strings.TrimSpace(s.Code))
+		s.Name = strings.TrimSpace(s.Name)
+		s.Condition = strings.ToLower(strings.TrimSpace(s.Condition))
+		if s.Condition == "" {
+			s.Condition = models.SurchargeAlways
+		}
+		if !codePattern.MatchString(s.Code) || !codePattern.MatchString(s.Condition) {
+			return fmt.Errorf("surcharge code and condition %q must be 1-32 lower-case letters, digits, - or _", s.Code)
+		}
+		if surcharges[s.Code] {
+			return fmt.Errorf("surcharge %q is defined twice", s.Code)
+		}
+		surcharges[s.Code] = true
+		switch s.Kind {
+		case models.SurchargePercent, models.SurchargeFlat, models.SurchargePerKg:
+		default:
+			return fmt.Errorf("surcharge %q: kind must be percent, flat or per_kg", s.Code)
+		}
+		if !nonNegative(s.Amount) {
+			return fmt.Errorf("surcharge %q: amount cannot be negative", s.Code)
+		}
+	}
+
+	if c.Zones == nil {
+		c.Zones = []models.RateZone{}
+	}
+	if c.Lanes == nil {
+		c.Lanes = []models.RateLane{}
+	}
+	if c.Surcharges == nil {
+		c.Surcharges = []models.Surcharge{}
+	}
+	return nil
+}
+
+// trimAll applies f to each value and drops those left empty.
+func trimAll(values []string, f func(string) string) []string {
+	var out []string
+	for _, v := range values {
+		if v = f(v); v != "" {
+			out = append(out, v)
+		}
+	}
+	return out
+}
+
+func positive(v float64) bool    { return v > 0 && !math.IsInf(v, 0) }
+func nonNegative(v float64) bool { return v >= 0 && !math.IsInf(v, 0) }
@@ -0,0 +1,132 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strconv"
+
+	"cargomax-api/internal/models"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+// fillNotifyRoles are the users told when a warehouse fills past one of its
+// thresholds.
+var fillNotifyRoles = []string{"admin", "manager"}
+
+// capacityUse is the space n units of the product p take up in the
+// warehouse w, in the warehouse's capacity unit.
+func capacityUse(n string) string {
+	return `stock_capacity_use(w.capacity_unit, ` + n + `, p.units_per_pallet, p.volume, p.weight)`
+}
+
+// shiftUsedCapacity moves a warehouse's used capacity on as the stock on
+// hand of one of its items goes from one quantity to another, and reports
+// a fill threshold it passes. The use is exact, so the running total stays
+// what refreshUsedCapacity would work out. A change that takes up no more
+// or less space, such as within a part pallet, leaves the warehouse alone.
+func shiftUsedCapacity(ctx context.Context, tx pgx.Tx, tenantID, warehouseID, itemID uuid.UUID, from, to int) error {
+	rows, err := tx.Query(ctx,
+		`UPDATE warehouses w SET used_capacity = GREATEST(w.used_capacity + `+capacityUse("$4")+` - `+capacityUse("$5")+`, 0)
+		 FROM inventory_items i JOIN products p ON p.id = i.product_id
+		 WHERE w.id = $1 AND w.tenant_id = $2 AND i.id = $3 AND `+capacityUse("$4")+` <> `+capacityUse("$5")+`
+		 RETURNING `+warehouseColumns,
+		warehouseID, tenantID, itemID, to, from,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to update used capacity: %w", err)
+	}
+	return checkFill(ctx, tx, rows)
+}
+
+// refreshUsedCapacity works out the used capacity of the given warehouses
+// afresh from their stock on hand, for when what a unit takes up changes
+// rather than the stock, and reports fill thresholds they pass.
+func refreshUsedCapacity(ctx context.Context, tx pgx.Tx, tenantID uuid.UUID, warehouseIDs []uuid.UUID) error {
+	if len(warehouseIDs) == 0 {
+		return nil
+	}
+	rows, err := tx.Query(ctx,
+		`UPDATE warehouses w SET used_capacity = COALESCE((
+			SELECT SUM(`+capacityUse("i.quantity")+`)
+			FROM inventory_items i JOIN products p ON p.id = i.product_id
+			WHERE i.warehouse_id = w.id
+		 ), 0)
+		 WHERE w.tenant_id = $1 AND w.id = ANY($2)
+		 RETURNING `+warehouseColumns,
+		tenantID, warehouseIDs,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to refresh used capacity: %w", err)
+	}
+	return checkFill(ctx, tx, rows)
+}
+
+// recheckFill checks a warehouse whose used capacity is unchanged against
+// its capacity and fill thresholds, for when those change.
+func recheckFill(ctx context.Context, tx pgx.Tx, tenantID, warehouseID uuid.UUID) error {
+	rows, err := tx.Query(ctx,
+		`SELECT `+warehouseColumns+` FROM warehouses w WHERE w.id = $1 AND w.tenant_id = $2`,
+		warehouseID, tenantID,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to read warehouse: %w", err)
+	}
+	return checkFill(ctx, tx, rows)
+}
+
+// checkFill reads the warehouses whose used capacity just changed and
+// records which fill threshold each has reached. Admins and managers are
+// told the first time one passes a threshold; one that empties below it
+// again is reported again the next time it passes it.
+func checkFill(ctx context.Context, tx pgx.Tx, rows pgx.Rows) error {
+	var changed []*models.Warehouse
+	for rows.Next() {
+		w, err := scanWarehouse(rows)
+		if err != nil {
+			rows.Close()
+			return fmt.Errorf("failed to scan warehouse: %w", err)
+		}
+		if w.FillLevel() != w.FillAlertLevel {
+			changed = append(changed, w)
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to update used capacity: %w", err)
+	}
+
+	for _, w := range changed {
+		level := w.FillLevel()
+		if _, err := tx.Exec(ctx,
+			`UPDATE warehouses SET fill_alert_level = $1 WHERE id = $2 AND tenant_id = $3`,
+			level, w.ID, w.TenantID,
+		); err != nil {
+			return fmt.Errorf("failed to update fill alert level: %w", err)
+		}
+		if level < w.FillAlertLevel {
+			continue
+		}
+		message := fmt.Sprintf("%s is %.0f%% full (%s of %d %s), past its %d%% threshold.",
+			w.Name, w.Utilization(), formatCapacity(w.UsedCapacity), w.Capacity, capacityUnitLabel(w.CapacityUnit), level)
+		if err := notifyRoles(ctx, tx, w.TenantID, fillNotifyRoles, "Warehouse filling up", message, "warehouse"); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// formatCapacity rounds a used capacity to one decimal place for display,
+// dropping a trailing ".0".
+func formatCapacity(v float64) string {
+	return strconv.FormatFloat(math.Round(v*10)/10, 'f', -1, 64)
+}
+
+func capacityUnitLabel(unit string) string {
+	if unit == models.CapacityCubicMetres {
+		return "m³"
+	}
+	return unit
+}
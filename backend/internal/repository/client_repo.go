@@ -24,9 +24,9 @@ func NewClientRepo(db *pgxpool.Pool) *ClientRepo {
 func (r *ClientRepo) Create(ctx context.Context, c *models.Client) error {
 	c.ID = uuid.New()
 	_, err := r.db.Exec(ctx,
-		`INSERT INTO clients (id, tenant_id, company_name, contact_person, email, phone, address, industry, total_shipments, total_spent, satisfaction_rating, status, created_at, updated_at)
-		 VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, NOW(), NOW())`,
-		c.ID, c.TenantID, c.CompanyName, c.ContactPerson, c.Email, c.Phone, c.Address, c.Industry, c.TotalShipments, c.TotalSpent, c.SatisfactionRating, c.Status,
+		`INSERT INTO clients (id, tenant_id, company_name, contact_person, email, phone, address, industry, total_shipments, total_spent, satisfaction_rating, status, address_details, latitude, longitude, created_at, updated_at)
+		 VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, NOW(), NOW())`,
+		c.ID, c.TenantID, c.CompanyName, c.ContactPerson, c.Email, c.Phone, c.Address, c.Industry, c.TotalShipments, c.TotalSpent, c.SatisfactionRating, c.Status, c.AddressDetails, c.Latitude, c.Longitude,
 	)
 	if err != nil {
 		return fmt.Errorf("failed to create client: %w", err)
@@ -38,10 +38,10 @@ func (r *ClientRepo) Create(ctx context.Context, c *models.Client) error {
 func (r *ClientRepo) GetByID(ctx context.Context, tenantID, id uuid.UUID) (*models.Client, error) {
 	c := &models.Client{}
 	err := r.db.QueryRow(ctx,
-		`SELECT id, tenant_id, company_name, contact_person, email, phone, address, industry, total_shipments, total_spent, satisfaction_rating, status, created_at, updated_at
+		`SELECT id, tenant_id, company_name, contact_person, email, phone, address, industry, total_shipments, total_spent, satisfaction_rating, status, address_details, latitude, longitude, created_at, updated_at
 		 FROM clients WHERE id = $1 AND tenant_id = $2`,
 		id, tenantID,
-	).Scan(&c.ID, &c.TenantID, &c.CompanyName, &c.ContactPerson, &c.Email, &c.Phone, &c.Address, &c.Industry, &c.TotalShipments, &c.TotalSpent, &c.SatisfactionRating, &c.Status, &c.CreatedAt, &c.UpdatedAt)
+	).Scan(&c.ID, &c.TenantID, &c.CompanyName, &c.ContactPerson, &c.Email, &c.Phone, &c.Address, &c.Industry, &c.TotalShipments, &c.TotalSpent, &c.SatisfactionRating, &c.Status, &c.AddressDetails, &c.Latitude, &c.Longitude, &c.CreatedAt, &c.UpdatedAt)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get client by id: %w", err)
 	}
@@ -61,7 +61,7 @@ func (r *ClientRepo) List(ctx context.Context, tenantID uuid.UUID, page, perPage
 
 	offset := (page - 1) * perPage
 	rows, err := r.db.Query(ctx,
-		`SELECT id, tenant_id, company_name, contact_person, email, phone, address, industry, total_shipments, total_spent, satisfaction_rating, status, created_at, updated_at
+		`SELECT id, tenant_id, company_name, contact_person, email, phone, address, industry, total_shipments, total_spent, satisfaction_rating, status, address_details, latitude, longitude, created_at, updated_at
 		 FROM clients WHERE tenant_id = $1 ORDER BY company_name ASC LIMIT $2 OFFSET $3`,
 		tenantID, perPage, offset,
 	)
@@ -73,7 +73,7 @@ func (r *ClientRepo) List(ctx context.Context, tenantID uuid.UUID, page, perPage
 	var clients []models.Client
 	for rows.Next() {
 		var c models.Client
-		if err := rows.Scan(&c.ID, &c.TenantID, &c.CompanyName, &c.ContactPerson, &c.Email, &c.Phone, &c.Address, &c.Industry, &c.TotalShipments, &c.TotalSpent, &c.SatisfactionRating, &c.Status, &c.CreatedAt, &c.UpdatedAt); err != nil {
+		if err := rows.Scan(&c.ID, &c.TenantID, &c.CompanyName, &c.ContactPerson, &c.Email, &c.Phone, &c.Address, &c.Industry, &c.TotalShipments, &c.TotalSpent, &c.SatisfactionRating, &c.Status, &c.AddressDetails, &c.Latitude, &c.Longitude, &c.CreatedAt, &c.UpdatedAt); err != nil {
 			return nil, 0, fmt.Errorf("failed to scan client: %w", err)
 		}
 		clients = append(clients, c)
@@ -84,9 +84,9 @@ func (r *ClientRepo) List(ctx context.Context, tenantID uuid.UUID, page, perPage
 // Update modifies an existing client.
 func (r *ClientRepo) Update(ctx context.Context, tenantID, id uuid.UUID, c *models.Client) error {
 	ct, err := r.db.Exec(ctx,
-		`UPDATE clients SET company_name = $1, contact_person = $2, email = $3, phone = $4, address = $5, industry = $6, total_shipments = $7, total_spent = $8, satisfaction_rating = $9, status = $10, updated_at = NOW()
-		 WHERE id = $11 AND tenant_id = $12`,
-		c.CompanyName, c.ContactPerson, c.Email, c.Phone, c.Address, c.Industry, c.TotalShipments, c.TotalSpent, c.SatisfactionRating, c.Status, id, tenantID,
+		`UPDATE clients SET company_name = $1, contact_person = $2, email = $3, phone = $4, address = $5, industry = $6, total_shipments = $7, total_spent = $8, satisfaction_rating = $9, status = $10, address_details = $11, latitude = $12, longitude = $13, updated_at = NOW()
+		 WHERE id = $14 AND tenant_id = $15`,
+		c.CompanyName, c.ContactPerson, c.Email, c.Phone, c.Address, c.Industry, c.TotalShipments, c.TotalSpent, c.SatisfactionRating, c.Status, c.AddressDetails, c.Latitude, c.Longitude, id, tenantID,
 	)
 	if err != nil {
 		return fmt.Errorf("failed to update client: %w", err)
@@ -115,7 +115,7 @@ func (r *ClientRepo) Delete(ctx context.Context, tenantID, id uuid.UUID) error {
 // GetTopBySpent returns the top N clients by total_spent within a tenant.
 func (r *ClientRepo) GetTopBySpent(ctx context.Context, tenantID uuid.UUID, limit int) ([]models.Client, error) {
 	rows, err := r.db.Query(ctx,
-		`SELECT id, tenant_id, company_name, contact_person, email, phone, address, industry, total_shipments, total_spent, satisfaction_rating, status, created_at, updated_at
+		`SELECT id, tenant_id, company_name, contact_person, email, phone, address, industry, total_shipments, total_spent, satisfaction_rating, status, address_details, latitude, longitude, created_at, updated_at
 		 FROM clients WHERE tenant_id = $1 AND status = 'active' ORDER BY total_spent DESC NULLS LAST LIMIT $2`,
 		tenantID, limit,
 	)
@@ -127,10 +127,51 @@ func (r *ClientRepo) GetTopBySpent(ctx context.Context, tenantID uuid.UUID, limi
 	var clients []models.Client
 	for rows.Next() {
 		var c models.Client
-		if err := rows.Scan(&c.ID, &c.TenantID, &c.CompanyName, &c.ContactPerson, &c.Email, &c.Phone, &c.Address, &c.Industry, &c.TotalShipments, &c.TotalSpent, &c.SatisfactionRating, &c.Status, &c.CreatedAt, &c.UpdatedAt); err != nil {
+		if err := rows.Scan(&c.ID, &c.TenantID, &c.CompanyName, &c.ContactPerson, &c.Email, &c.Phone, &c.Address, &c.Industry, &c.TotalShipments, &c.TotalSpent, &c.SatisfactionRating, &c.Status, &c.AddressDetails, &c.Latitude, &c.Longitude, &c.CreatedAt, &c.UpdatedAt); err != nil {
 			return nil, fmt.Errorf("failed to scan top client: %w", err)
 		}
 		clients = append(clients, c)
 	}
 	return clients, nil
 }
+
+// ListUnlocated returns the tenant's clients that have a structured address
+// but no coordinates yet.
+func (r *ClientRepo) ListUnlocated(ctx context.Context, tenantID uuid.UUID) ([]models.Client, error) {
+	rows, err := r.db.Query(ctx,
+		`SELECT id, tenant_id, company_name, contact_person, email, phone, address, industry, total_shipments, total_spent, satisfaction_rating, status, address_details, latitude, longitude, created_at, updated_at
+		 FROM clients WHERE tenant_id = $1 AND address_details IS NOT NULL AND (latitude IS NULL OR longitude IS NULL)
+		 ORDER BY company_name ASC`,
+		tenantID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list unlocated clients: %w", err)
+	}
+	defer rows.Close()
+
+	var clients []models.Client
+	for rows.Next() {
+		var c models.Client
+		if err := rows.Scan(&c.ID, &c.TenantID, &c.CompanyName, &c.ContactPerson, &c.Email, &c.Phone, &c.Address, &c.Industry, &c.TotalShipments, &c.TotalSpent, &c.SatisfactionRating, &c.Status, &c.AddressDetails, &c.Latitude, &c.Longitude, &c.CreatedAt, &c.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan client: %w", err)
+		}
+		clients = append(clients, c)
+	}
+	return clients, rows.Err()
+}
+
+// SetLocation stores the coordinates found for a client's site.
+func (r *ClientRepo) SetLocation(ctx context.Context, tenantID, id uuid.UUID, lat, lng float64) error {
+	ct, err := r.db.Exec(ctx,
+		`UPDATE clients SET latitude = $1, longitude = $2, updated_at = NOW()
+		 WHERE id = $3 AND tenant_id = $4`,
+		lat, lng, id, tenantID,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to set client location: %w", err)
+	}
+	if ct.RowsAffected() == 0 {
+		return fmt.Errorf("client not found")
+	}
+	return nil
+}
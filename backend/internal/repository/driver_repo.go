@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 
+	"cargomax-api/internal/database"
 	"cargomax-api/internal/models"
 
 	"github.com/google/uuid"
@@ -22,8 +23,12 @@ func NewDriverRepo(db *pgxpool.Pool) *DriverRepo {
 
 // Create inserts a new driver.
 func (r *DriverRepo) Create(ctx context.Context, d *models.Driver) error {
+	return insertDriver(ctx, r.db, d)
+}
+
+func insertDriver(ctx context.Context, q execer, d *models.Driver) error {
 	d.ID = uuid.New()
-	_, err := r.db.Exec(ctx,
+	_, err := q.Exec(ctx,
 		`INSERT INTO drivers (id, tenant_id, employee_id, first_name, last_name, email, phone, license_number, license_expiry, status, rating, total_deliveries, vehicle_id, created_at, updated_at)
 		 VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, NOW(), NOW())`,
 		d.ID, d.TenantID, d.EmployeeID, d.FirstName, d.LastName, d.Email, d.Phone, d.LicenseNumber, d.LicenseExpiry, d.Status, d.Rating, d.TotalDeliveries, d.VehicleID,
@@ -83,7 +88,11 @@ func (r *DriverRepo) List(ctx context.Context, tenantID uuid.UUID, page, perPage
 
 // Update modifies an existing driver.
 func (r *DriverRepo) Update(ctx context.Context, tenantID, id uuid.UUID, d *models.Driver) error {
-	_, err := r.db.Exec(ctx,
+	return updateDriver(ctx, r.db, tenantID, id, d)
+}
+
+func updateDriver(ctx context.Context, q execer, tenantID, id uuid.UUID, d *models.Driver) error {
+	_, err := q.Exec(ctx,
 		`UPDATE drivers SET employee_id = $1, first_name = $2, last_name = $3, email = $4, phone = $5, license_number = $6, license_expiry = $7, status = $8, rating = $9, total_deliveries = $10, vehicle_id = $11, updated_at = NOW()
 		 WHERE id = $12 AND tenant_id = $13`,
 		d.EmployeeID, d.FirstName, d.LastName, d.Email, d.Phone, d.LicenseNumber, d.LicenseExpiry, d.Status, d.Rating, d.TotalDeliveries, d.VehicleID, id, tenantID,
@@ -129,10 +138,11 @@ func (r *DriverRepo) GetAvailable(ctx context.Context, tenantID uuid.UUID) ([]mo
 	return drivers, nil
 }
 
-// GetByPhone retrieves a driver by phone number (for mobile login).
+// GetByPhone retrieves a driver by phone number (for mobile login). It runs on
+// the privileged connection path because the tenant is not known yet.
 func (r *DriverRepo) GetByPhone(ctx context.Context, phone string) (*models.Driver, error) {
 	d := &models.Driver{}
-	err := r.db.QueryRow(ctx,
+	err := r.db.QueryRow(database.Privileged(ctx),
 		`SELECT id, tenant_id, employee_id, first_name, last_name, email, phone, license_number, license_expiry, status, rating, total_deliveries, vehicle_id, pin_hash, created_at, updated_at
 		 FROM drivers WHERE phone = $1`,
 		phone,
@@ -0,0 +1,527 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"cargomax-api/internal/models"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// ErrFulfillmentNotFound is returned when a fulfillment does not exist in the
+// tenant.
+var ErrFulfillmentNotFound = errors.New("fulfillment not found")
+
+// FulfillmentRepo handles picking, packing and shipping orders with lines.
+type FulfillmentRepo struct {
+	db *pgxpool.Pool
+}
+
+// NewFulfillmentRepo creates a new FulfillmentRepo.
+func NewFulfillmentRepo(db *pgxpool.Pool) *FulfillmentRepo {
+	return &FulfillmentRepo{db: db}
+}
+
+const fulfillmentColumns = `id, tenant_id, order_id, warehouse_id, status, shipment_id, weight, dimensions, created_by, picked_at, packed_at, created_at, updated_at`
+
+func scanFulfillment(row pgx.Row) (*models.Fulfillment, error) {
+	f := &models.Fulfillment{}
+	err := row.Scan(&f.ID, &f.TenantID, &f.OrderID, &f.WarehouseID, &f.Status, &f.ShipmentID, &f.Weight, &f.Dimensions, &f.CreatedBy, &f.PickedAt, &f.PackedAt, &f.CreatedAt, &f.UpdatedAt)
+	return f, err
+}
+
+// CreatePickLists allocates reserved stock of a pending or processing order
+// to pick lists, one per warehouse, and moves the order to processing. picks
+// gives the quantity of each order line to pick; nil picks everything
+// reserved that no open pick list has yet. Leaving some out is how an order
+// is split across shipments.
+func (r *FulfillmentRepo) CreatePickLists(ctx context.Context, tenantID, orderID uuid.UUID, picks []models.PickRequest, createdBy *uuid.UUID) ([]models.Fulfillment, error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	status, err := lockOrderStatus(ctx, tx, tenantID, orderID)
+	if err != nil {
+		return nil, err
+	}
+	if !models.OrderHoldsStock(status) {
+		return nil, fmt.Errorf("a %s order cannot be picked", status)
+	}
+	lines, err := lockOrderLines(ctx, tx, tenantID, orderID)
+	if err != nil {
+		return nil, err
+	}
+	allocated, err := allocatedToPick(ctx, tx, tenantID, orderID)
+	if err != nil {
+		return nil, err
+	}
+
+	want := map[uuid.UUID]int{}
+	if picks == nil {
+		for _, l := range lines {
+			want[l.ID] = l.ReservedQuantity - allocated[l.ID]
+		}
+	}
+	for _, p := range picks {
+		if p.Quantity <= 0 {
+			return nil, fmt.Errorf("pick quantities must be positive")
+		}
+		want[p.OrderLineID] += p.Quantity
+	}
+
+	// One pick list per warehouse, in line order.
+	var out []models.Fulfillment
+	byWarehouse := map[uuid.UUID]int{}
+	found := 0
+	for _, l := range sortLinesByNumber(lines) {
+		n, ok := want[l.ID]
+		if !ok {
+			continue
+		}
+		found++
+		if free := l.ReservedQuantity - allocated[l.ID]; n > free {
+			return nil, fmt.Errorf("line %d (%s) has %d reserved to pick, %d wanted", l.LineNumber, l.SKU, max(free, 0), n)
+		}
+		if n <= 0 {
+			continue
+		}
+		var wh uuid.UUID
+		if l.WarehouseID != nil {
+			wh = *l.WarehouseID
+		}
+		i, ok := byWarehouse[wh]
+		if !ok {
+			f := models.Fulfillment{ID: uuid.New(), TenantID: tenantID, OrderID: orderID, WarehouseID: l.WarehouseID, Status: models.FulfillmentPicking, CreatedBy: createdBy}
+			out = append(out, f)
+			i = len(out) - 1
+			byWarehouse[wh] = i
+		}
+		out[i].Lines = append(out[i].Lines, models.FulfillmentLine{
+			ID: uuid.New(), FulfillmentID: out[i].ID, OrderLineID: l.ID, LineNumber: l.LineNumber, SKU: l.SKU, Description: l.Description, Quantity: n,
+		})
+	}
+	if picks != nil && found != len(want) {
+		return nil, fmt.Errorf("order line not found on the order")
+	}
+	if len(out) == 0 {
+		return nil, fmt.Errorf("nothing on the order is left to pick")
+	}
+
+	for i := range out {
+		f := &out[i]
+		err := tx.QueryRow(ctx,
+			`INSERT INTO fulfillments (id, tenant_id, order_id, warehouse_id, status, created_by, created_at, updated_at)
+			 VALUES ($1, $2, $3, $4, $5, $6, NOW(), NOW())
+			 RETURNING created_at, updated_at`,
+			f.ID, tenantID, orderID, f.WarehouseID, f.Status, f.CreatedBy,
+		).Scan(&f.CreatedAt, &f.UpdatedAt)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create pick list: %w", err)
+		}
+		for _, fl := range f.Lines {
+			if _, err := tx.Exec(ctx,
+				`INSERT INTO fulfillment_lines (id, tenant_id, fulfillment_id, order_line_id, quantity) VALUES ($1, $2, $3, $4, $5)`,
+				fl.ID, tenantID, f.ID, fl.OrderLineID, fl.Quantity,
+			); err != nil {
+				return nil, fmt.Errorf("failed to create pick list line: %w", err)
+			}
+		}
+	}
+	if status != models.OrderProcessing {
+		if _, err := tx.Exec(ctx,
+			`UPDATE orders SET status = $1, updated_at = NOW() WHERE id = $2 AND tenant_id = $3`,
+			models.OrderProcessing, orderID, tenantID,
+		); err != nil {
+			return nil, fmt.Errorf("failed to update order status: %w", err)
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return out, nil
+}
+
+// allocatedToPick sums, per order line, what open pick lists hold.
+func allocatedToPick(ctx context.Context, tx pgx.Tx, tenantID, orderID uuid.UUID) (map[uuid.UUID]int, error) {
+	rows, err := tx.Query(ctx,
+		`SELECT fl.order_line_id, SUM(fl.quantity)
+		 FROM fulfillment_lines fl JOIN fulfillments f ON f.id = fl.fulfillment_id
+		 WHERE f.tenant_id = $1 AND f.order_id = $2 AND f.status IN ('picking', 'picked')
+		 GROUP BY fl.order_line_id`,
+		tenantID, orderID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get picked quantities: %w", err)
+	}
+	defer rows.Close()
+
+	out := map[uuid.UUID]int{}
+	for rows.Next() {
+		var id uuid.UUID
+		var n int
+		if err := rows.Scan(&id, &n); err != nil {
+			return nil, fmt.Errorf("failed to scan picked quantity: %w", err)
+		}
+		out[id] = n
+	}
+	return out, rows.Err()
+}
+
+// sortLinesByNumber returns lines, which lockOrderLines returns in item
+// order, in line number order.
+func sortLinesByNumber(lines []models.OrderLine) []models.OrderLine {
+	out := make([]models.OrderLine, len(lines))
+	for _, l := range lines {
+		if l.LineNumber >= 1 && l.LineNumber <= len(out) {
+			out[l.LineNumber-1] = l
+		}
+	}
+	return out
+}
+
+// lockFulfillment locks a fulfillment, checking it is in one of statuses.
+func lockFulfillment(ctx context.Context, tx pgx.Tx, tenantID, id uuid.UUID, statuses ...string) (*models.Fulfillment, error) {
+	f, err := scanFulfillment(tx.QueryRow(ctx,
+		`SELECT `+fulfillmentColumns+` FROM fulfillments WHERE id = $1 AND tenant_id = $2 FOR UPDATE`,
+		id, tenantID,
+	))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrFulfillmentNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to lock fulfillment: %w", err)
+	}
+	for _, s := range statuses {
+		if f.Status == s {
+			return f, nil
+		}
+	}
+	return nil, fmt.Errorf("the fulfillment is %s", f.Status)
+}
+
+// fulfillmentOrder returns the order a fulfillment is for, so that the order
+// can be locked before the fulfillment, as CancelOrder does.
+func fulfillmentOrder(ctx context.Context, tx pgx.Tx, tenantID, id uuid.UUID) (uuid.UUID, error) {
+	var orderID uuid.UUID
+	err := tx.QueryRow(ctx,
+		`SELECT order_id FROM fulfillments WHERE id = $1 AND tenant_id = $2`,
+		id, tenantID,
+	).Scan(&orderID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return uuid.Nil, ErrFulfillmentNotFound
+	}
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("failed to get fulfillment: %w", err)
+	}
+	return orderID, nil
+}
+
+// ConfirmPick records a pick list as picked. picked gives what was actually
+// taken from the shelf for lines that came up short; the rest of their
+// quantity is left for another pick list. Nil means everything was picked.
+func (r *FulfillmentRepo) ConfirmPick(ctx context.Context, tenantID, id uuid.UUID, picked []models.PickRequest) (*models.Fulfillment, error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := lockFulfillment(ctx, tx, tenantID, id, models.FulfillmentPicking); err != nil {
+		return nil, err
+	}
+	for _, p := range picked {
+		if p.Quantity < 0 {
+			return nil, fmt.Errorf("picked quantities cannot be negative")
+		}
+		ct, err := tx.Exec(ctx,
+			`UPDATE fulfillment_lines SET quantity = $1
+			 WHERE fulfillment_id = $2 AND order_line_id = $3 AND tenant_id = $4 AND quantity >= $1`,
+			p.Quantity, id, p.OrderLineID, tenantID,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to record picked quantity: %w", err)
+		}
+		if ct.RowsAffected() == 0 {
+			return nil, fmt.Errorf("order line %s is not on the pick list, or more was picked than listed", p.OrderLineID)
+		}
+	}
+	if _, err := tx.Exec(ctx,
+		`UPDATE fulfillments SET status = $1, picked_at = NOW(), updated_at = NOW() WHERE id = $2 AND tenant_id = $3`,
+		models.FulfillmentPicked, id, tenantID,
+	); err != nil {
+		return nil, fmt.Errorf("failed to confirm pick: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return r.GetByID(ctx, tenantID, id)
+}
+
+// Pack confirms a picked fulfillment as packed into one parcel and creates
+// the shipment carrying it from s, which the caller fills in with the
+// destination, tracking number and parcel details; ev opens its timeline.
+// The picked stock leaves the warehouse. The order's first shipment is linked
+// on the order, and the order moves to shipped once every line has shipped.
+func (r *FulfillmentRepo) Pack(ctx context.Context, tenantID, id uuid.UUID, s *models.Shipment, ev *models.ShipmentEvent) (*models.Fulfillment, error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	orderID, err := fulfillmentOrder(ctx, tx, tenantID, id)
+	if err != nil {
+		return nil, err
+	}
+	status, err := lockOrderStatus(ctx, tx, tenantID, orderID)
+	if err != nil {
+		return nil, err
+	}
+	if !models.OrderHoldsStock(status) {
+		return nil, fmt.Errorf("a %s order cannot be shipped", status)
+	}
+	f, err := lockFulfillment(ctx, tx, tenantID, id, models.FulfillmentPicked)
+	if err != nil {
+		return nil, err
+	}
+	picked, err := fulfillmentLines(ctx, tx, tenantID, []uuid.UUID{id})
+	if err != nil {
+		return nil, err
+	}
+	qty := map[uuid.UUID]int{}
+	for _, fl := range picked[id] {
+		qty[fl.OrderLineID] = fl.Quantity
+	}
+
+	lines, err := lockOrderLines(ctx, tx, tenantID, orderID)
+	if err != nil {
+		return nil, err
+	}
+	ref, err := orderStockRef(ctx, tx, tenantID, orderID)
+	if err != nil {
+		return nil, err
+	}
+	shipped, complete := 0, true
+	for i := range lines {
+		l := &lines[i]
+		n := qty[l.ID]
+		if n > 0 {
+			if n > l.ReservedQuantity {
+				return nil, fmt.Errorf("%w: line %d (%s) has %d reserved, %d picked", ErrInsufficientStock, l.LineNumber, l.SKU, l.ReservedQuantity, n)
+			}
+			if l.InventoryItemID != nil {
+				if err := takeStock(ctx, tx, tenantID, *l.InventoryItemID, n, ref); err != nil {
+					return nil, err
+				}
+			}
+			l.ReservedQuantity -= n
+			l.ShippedQuantity += n
+			if err := setLineStock(ctx, tx, l); err != nil {
+				return nil, err
+			}
+			shipped += n
+		}
+		if l.ShippedQuantity < l.Quantity {
+			complete = false
+		}
+	}
+	if shipped == 0 {
+		return nil, fmt.Errorf("nothing was picked for this fulfillment")
+	}
+
+	s.TenantID = tenantID
+	s.WarehouseID = f.WarehouseID
+	if s.Status == "" {
+		s.Status = models.ShipmentPending
+	}
+	if err := insertShipment(ctx, tx, s, ev); err != nil {
+		return nil, err
+	}
+	if _, err := tx.Exec(ctx,
+		`UPDATE fulfillments SET status = $1, shipment_id = $2, weight = $3, dimensions = $4, packed_at = NOW(), updated_at = NOW()
+		 WHERE id = $5 AND tenant_id = $6`,
+		models.FulfillmentShipped, s.ID, s.Weight, s.Dimensions, id, tenantID,
+	); err != nil {
+		return nil, fmt.Errorf("failed to pack fulfillment: %w", err)
+	}
+
+	next := models.OrderProcessing
+	if complete {
+		next = models.OrderShipped
+		if err := cancelOpenFulfillments(ctx, tx, tenantID, orderID); err != nil {
+			return nil, err
+		}
+	}
+	if _, err := tx.Exec(ctx,
+		`UPDATE orders SET status = $1, shipment_id = COALESCE(shipment_id, $2), updated_at = NOW() WHERE id = $3 AND tenant_id = $4`,
+		next, s.ID, orderID, tenantID,
+	); err != nil {
+		return nil, fmt.Errorf("failed to update order status: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return r.GetByID(ctx, tenantID, id)
+}
+
+// Cancel drops a pick list that has not shipped, freeing its stock for
+// another one. The stock stays reserved for the order.
+func (r *FulfillmentRepo) Cancel(ctx context.Context, tenantID, id uuid.UUID) (*models.Fulfillment, error) {
+	ct, err := r.db.Exec(ctx,
+		`UPDATE fulfillments SET status = $1, updated_at = NOW()
+		 WHERE id = $2 AND tenant_id = $3 AND status IN ('picking', 'picked')`,
+		models.FulfillmentCancelled, id, tenantID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to cancel fulfillment: %w", err)
+	}
+	f, err := r.GetByID(ctx, tenantID, id)
+	if err != nil {
+		return nil, err
+	}
+	if ct.RowsAffected() == 0 {
+		return nil, fmt.Errorf("the fulfillment is %s", f.Status)
+	}
+	return f, nil
+}
+
+// cancelOpenFulfillments cancels an order's pick lists that have not
+// shipped, when the order no longer holds stock to pick.
+func cancelOpenFulfillments(ctx context.Context, tx pgx.Tx, tenantID, orderID uuid.UUID) error {
+	_, err := tx.Exec(ctx,
+		`UPDATE fulfillments SET status = $1, updated_at = NOW()
+		 WHERE tenant_id = $2 AND order_id = $3 AND status IN ('picking', 'picked')`,
+		models.FulfillmentCancelled, tenantID, orderID,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to cancel pick lists: %w", err)
+	}
+	return nil
+}
+
+// GetByID retrieves a fulfillment with its lines.
+func (r *FulfillmentRepo) GetByID(ctx context.Context, tenantID, id uuid.UUID) (*models.Fulfillment, error) {
+	f, err := scanFulfillment(r.db.QueryRow(ctx,
+		`SELECT `+fulfillmentColumns+` FROM fulfillments WHERE id = $1 AND tenant_id = $2`,
+		id, tenantID,
+	))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrFulfillmentNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get fulfillment: %w", err)
+	}
+	lines, err := fulfillmentLines(ctx, r.db, tenantID, []uuid.UUID{id})
+	if err != nil {
+		return nil, err
+	}
+	f.Lines = lines[id]
+	return f, nil
+}
+
+// ListByOrder returns an order's fulfillments with their lines, oldest first.
+func (r *FulfillmentRepo) ListByOrder(ctx context.Context, tenantID, orderID uuid.UUID) ([]models.Fulfillment, error) {
+	return r.list(ctx, tenantID,
+		`SELECT `+fulfillmentColumns+` FROM fulfillments WHERE tenant_id = $1 AND order_id = $2 ORDER BY created_at, id`,
+		orderID,
+	)
+}
+
+// PickLists returns the fulfillments waiting to be picked, oldest first,
+// at one warehouse or at all of them.
+func (r *FulfillmentRepo) PickLists(ctx context.Context, tenantID uuid.UUID, warehouseID *uuid.UUID) ([]models.Fulfillment, error) {
+	return r.list(ctx, tenantID,
+		`SELECT `+fulfillmentColumns+` FROM fulfillments
+		 WHERE tenant_id = $1 AND status = 'picking' AND ($2::uuid IS NULL OR warehouse_id = $2)
+		 ORDER BY created_at, id`,
+		warehouseID,
+	)
+}
+
+func (r *FulfillmentRepo) list(ctx context.Context, tenantID uuid.UUID, sql string, arg any) ([]models.Fulfillment, error) {
+	rows, err := r.db.Query(ctx, sql, tenantID, arg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list fulfillments: %w", err)
+	}
+	defer rows.Close()
+
+	out := []models.Fulfillment{}
+	var ids []uuid.UUID
+	for rows.Next() {
+		f, err := scanFulfillment(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan fulfillment: %w", err)
+		}
+		out = append(out, *f)
+		ids = append(ids, f.ID)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	lines, err := fulfillmentLines(ctx, r.db, tenantID, ids)
+	if err != nil {
+		return nil, err
+	}
+	for i := range out {
+		out[i].Lines = lines[out[i].ID]
+	}
+	return out, nil
+}
+
+// fulfillmentLines returns the lines of the given fulfillments in order line
+// order, keyed by fulfillment.
+func fulfillmentLines(ctx context.Context, q rowsQuerier, tenantID uuid.UUID, ids []uuid.UUID) (map[uuid.UUID][]models.FulfillmentLine, error) {
+	out := map[uuid.UUID][]models.FulfillmentLine{}
+	if len(ids) == 0 {
+		return out, nil
+	}
+	rows, err := q.Query(ctx,
+		`SELECT fl.id, fl.fulfillment_id, fl.order_line_id, ol.line_number, ol.sku, ol.description, fl.quantity
+		 FROM fulfillment_lines fl JOIN order_lines ol ON ol.id = fl.order_line_id
+		 WHERE fl.tenant_id = $1 AND fl.fulfillment_id = ANY($2)
+		 ORDER BY ol.line_number`,
+		tenantID, ids,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get pick list lines: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var l models.FulfillmentLine
+		if err := rows.Scan(&l.ID, &l.FulfillmentID, &l.OrderLineID, &l.LineNumber, &l.SKU, &l.Description, &l.Quantity); err != nil {
+			return nil, fmt.Errorf("failed to scan pick list line: %w", err)
+		}
+		out[l.FulfillmentID] = append(out[l.FulfillmentID], l)
+	}
+	return out, rows.Err()
+}
+
+// deliverFulfilledOrders moves shipped orders to delivered once every
+// shipment packed for them has been delivered. It runs when a shipment is
+// delivered.
+func deliverFulfilledOrders(ctx context.Context, tx pgx.Tx, tenantID, shipmentID uuid.UUID, at time.Time) error {
+	_, err := tx.Exec(ctx,
+		`UPDATE orders o SET status = 'delivered', updated_at = $3
+		 WHERE o.tenant_id = $1 AND o.status = 'shipped'
+		   AND o.id IN (SELECT order_id FROM fulfillments WHERE tenant_id = $1 AND shipment_id = $2)
+		   AND NOT EXISTS (
+		       SELECT 1 FROM fulfillments f JOIN shipments s ON s.id = f.shipment_id
+		       WHERE f.order_id = o.id AND f.status = 'shipped' AND s.status <> 'delivered')`,
+		tenantID, shipmentID, at,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to deliver orders: %w", err)
+	}
+	return nil
+}
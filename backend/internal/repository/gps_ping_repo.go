@@ -64,6 +64,22 @@ func (r *GPSPingRepo) GetLatestByDriver(ctx context.Context, tenantID, driverID
 	return p, nil
 }
 
+// RecentSpeed returns the average speed of a shift's pings recorded since
+// since, stops included, and how many pings it is based on.
+func (r *GPSPingRepo) RecentSpeed(ctx context.Context, tenantID, shiftID uuid.UUID, since time.Time) (float64, int, error) {
+	var avg float64
+	var n int
+	err := r.db.QueryRow(ctx,
+		`SELECT COALESCE(AVG(speed_kmh), 0), COUNT(*) FROM gps_pings
+		 WHERE tenant_id = $1 AND shift_id = $2 AND recorded_at >= $3`,
+		tenantID, shiftID, since,
+	).Scan(&avg, &n)
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to get recent speed: %w", err)
+	}
+	return avg, n, nil
+}
+
 // GetByShift returns all pings for a shift ordered by time.
 func (r *GPSPingRepo) GetByShift(ctx context.Context, tenantID, shiftID uuid.UUID) ([]models.GPSPing, error) {
 	rows, err := r.db.Query(ctx,
@@ -110,6 +126,42 @@ func (r *GPSPingRepo) GetLatestByTenant(ctx context.Context, tenantID uuid.UUID)
 	return pings, nil
 }
 
+// GetLivePositions returns the latest ping per driver in the last 30 minutes
+// joined with the driver name, truck plate and whether the driver has an
+// alert of alertType raised within alertWindow, as AlertRepo.HasRecentAlert
+// reports it.
+func (r *GPSPingRepo) GetLivePositions(ctx context.Context, tenantID uuid.UUID, alertType string, alertWindow time.Duration) ([]models.LivePosition, error) {
+	rows, err := r.db.Query(ctx,
+		`SELECT p.id, p.tenant_id, p.driver_id, p.truck_id, p.shift_id, p.latitude, p.longitude, p.speed_kmh, p.heading, p.accuracy, p.battery_level, p.is_moving, p.recorded_at, p.received_at, p.is_delayed, p.created_at,
+		        CONCAT_WS(' ', d.first_name, d.last_name), COALESCE(v.license_plate, ''),
+		        EXISTS(SELECT 1 FROM alerts a
+		               WHERE a.tenant_id = p.tenant_id AND a.driver_id = p.driver_id
+		                 AND a.type = $3 AND a.triggered_at > $4)
+		 FROM (
+		     SELECT DISTINCT ON (driver_id) *
+		     FROM gps_pings WHERE tenant_id = $1 AND recorded_at > $2
+		     ORDER BY driver_id, recorded_at DESC
+		 ) p
+		 LEFT JOIN drivers d ON d.id = p.driver_id AND d.tenant_id = p.tenant_id
+		 LEFT JOIN vehicles v ON v.id = p.truck_id AND v.tenant_id = p.tenant_id`,
+		tenantID, time.Now().Add(-30*time.Minute), alertType, time.Now().Add(-alertWindow),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query live positions: %w", err)
+	}
+	defer rows.Close()
+
+	var positions []models.LivePosition
+	for rows.Next() {
+		var p models.LivePosition
+		if err := rows.Scan(&p.ID, &p.TenantID, &p.DriverID, &p.TruckID, &p.ShiftID, &p.Latitude, &p.Longitude, &p.SpeedKmh, &p.Heading, &p.Accuracy, &p.BatteryLevel, &p.IsMoving, &p.RecordedAt, &p.ReceivedAt, &p.IsDelayed, &p.CreatedAt, &p.DriverName, &p.TruckPlate, &p.HasActiveAlert); err != nil {
+			return nil, fmt.Errorf("failed to scan live position: %w", err)
+		}
+		positions = append(positions, p)
+	}
+	return positions, nil
+}
+
 // CalculateShiftKm calculates total distance traveled in a shift using Haversine formula.
 func (r *GPSPingRepo) CalculateShiftKm(ctx context.Context, tenantID, shiftID uuid.UUID) (float64, error) {
 	var totalKm float64
@@ -0,0 +1,286 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"cargomax-api/internal/models"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// ErrImportNotFound is returned when an import batch does not exist within
+// the tenant.
+var ErrImportNotFound = errors.New("import not found")
+
+// importKeys names the table and unique column each import entity is matched
+// on. Warehouses are only referred to, by name, and inventory is matched on
+// SKU and warehouse.
+var importKeys = map[string]struct{ table, column string }{
+	models.ImportShipments: {"shipments", "tracking_number"},
+	models.ImportOrders:    {"orders", "order_number"},
+	models.ImportDrivers:   {"drivers", "employee_id"},
+	models.ImportVehicles:  {"vehicles", "vehicle_id"},
+}
+
+// ImportRepo handles database operations for bulk imports: the batches
+// themselves and writing their records.
+type ImportRepo struct {
+	db *pgxpool.Pool
+}
+
+// NewImportRepo creates a new ImportRepo instance.
+func NewImportRepo(db *pgxpool.Pool) *ImportRepo {
+	return &ImportRepo{db: db}
+}
+
+const importBatchColumns = `id, tenant_id, entity, filename, storage_key, status, mode, header, mapping, total_rows, created_count, updated_count, skipped_count, failed_count, errors, error, created_by, created_at, updated_at, completed_at`
+
+func scanImportBatch(row pgx.Row) (*models.ImportBatch, error) {
+	b := &models.ImportBatch{}
+	err := row.Scan(&b.ID, &b.TenantID, &b.Entity, &b.Filename, &b.StorageKey, &b.Status, &b.Mode, &b.Columns, &b.Mapping, &b.TotalRows, &b.Created, &b.Updated, &b.Skipped, &b.Failed, &b.Errors, &b.Error, &b.CreatedBy, &b.CreatedAt, &b.UpdatedAt, &b.CompletedAt)
+	return b, err
+}
+
+// nonNilJSON keeps the JSONB array columns from being written as null.
+func nonNilJSON(b *models.ImportBatch) {
+	if b.Columns == nil {
+		b.Columns = []string{}
+	}
+	if b.Mapping == nil {
+		b.Mapping = []models.ImportColumn{}
+	}
+	if b.Errors == nil {
+		b.Errors = []models.ImportRowError{}
+	}
+}
+
+// Create inserts a new import batch.
+func (r *ImportRepo) Create(ctx context.Context, b *models.ImportBatch) error {
+	nonNilJSON(b)
+	err := r.db.QueryRow(ctx,
+		`INSERT INTO import_batches (id, tenant_id, entity, filename, storage_key, status, mode, header, mapping, total_rows, created_by, created_at, updated_at)
+		 VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, NOW(), NOW())
+		 RETURNING created_at, updated_at`,
+		b.ID, b.TenantID, b.Entity, b.Filename, b.StorageKey, b.Status, b.Mode, b.Columns, b.Mapping, b.TotalRows, b.CreatedBy,
+	).Scan(&b.CreatedAt, &b.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create import batch: %w", err)
+	}
+	return nil
+}
+
+// GetByID retrieves an import batch by ID within a tenant.
+func (r *ImportRepo) GetByID(ctx context.Context, tenantID, id uuid.UUID) (*models.ImportBatch, error) {
+	b, err := scanImportBatch(r.db.QueryRow(ctx,
+		`SELECT `+importBatchColumns+` FROM import_batches WHERE id = $1 AND tenant_id = $2`,
+		id, tenantID,
+	))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrImportNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get import batch: %w", err)
+	}
+	return b, nil
+}
+
+// List returns the tenant's most recent import batches, newest first.
+func (r *ImportRepo) List(ctx context.Context, tenantID uuid.UUID, limit int) ([]models.ImportBatch, error) {
+	rows, err := r.db.Query(ctx,
+		`SELECT `+importBatchColumns+` FROM import_batches WHERE tenant_id = $1 ORDER BY created_at DESC LIMIT $2`,
+		tenantID, limit,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list import batches: %w", err)
+	}
+	defer rows.Close()
+
+	var batches []models.ImportBatch
+	for rows.Next() {
+		b, err := scanImportBatch(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan import batch: %w", err)
+		}
+		batches = append(batches, *b)
+	}
+	return batches, rows.Err()
+}
+
+// Save writes a batch's status, mapping and outcome.
+func (r *ImportRepo) Save(ctx context.Context, b *models.ImportBatch) error {
+	nonNilJSON(b)
+	err := r.db.QueryRow(ctx,
+		`UPDATE import_batches SET status = $1, mode = $2, mapping = $3, created_count = $4, updated_count = $5, skipped_count = $6, failed_count = $7, errors = $8, error = $9, completed_at = $10, updated_at = NOW()
+		 WHERE id = $11 AND tenant_id = $12
+		 RETURNING updated_at`,
+		b.Status, b.Mode, b.Mapping, b.Created, b.Updated, b.Skipped, b.Failed, b.Errors, b.Error, b.CompletedAt, b.ID, b.TenantID,
+	).Scan(&b.UpdatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return ErrImportNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("failed to save import batch: %w", err)
+	}
+	return nil
+}
+
+// Queue marks a batch queued for the background commit, unless it is queued
+// or completed already. It reports whether the batch was claimed, so a
+// double-submitted commit enqueues one job.
+func (r *ImportRepo) Queue(ctx context.Context, b *models.ImportBatch) (bool, error) {
+	nonNilJSON(b)
+	err := r.db.QueryRow(ctx,
+		`UPDATE import_batches SET status = $1, mode = $2, mapping = $3, error = NULL, updated_at = NOW()
+		 WHERE id = $4 AND tenant_id = $5 AND status NOT IN ($1, $6)
+		 RETURNING updated_at`,
+		models.ImportQueued, b.Mode, b.Mapping, b.ID, b.TenantID, models.ImportCompleted,
+	).Scan(&b.UpdatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to queue import batch: %w", err)
+	}
+	b.Status = models.ImportQueued
+	b.Error = nil
+	return true, nil
+}
+
+// KeyIDs returns the ids of the tenant's records of entity whose key is in
+// keys: tracking number, order number, SKU, employee ID or vehicle ID. For
+// models.ImportWarehouses the key is the name, compared case-insensitively
+// and returned in lower case; a name shared by several warehouses maps to
+// uuid.Nil. Inventory, stocked per warehouse, is returned under
+// models.InventoryImportKey of its SKU and warehouse.
+func (r *ImportRepo) KeyIDs(ctx context.Context, tenantID uuid.UUID, entity string, keys []string) (map[string]uuid.UUID, error) {
+	ids := make(map[string]uuid.UUID, len(keys))
+	if len(keys) == 0 {
+		return ids, nil
+	}
+
+	var rows pgx.Rows
+	var err error
+	if entity == models.ImportWarehouses {
+		lower := make([]string, len(keys))
+		for i, k := range keys {
+			lower[i] = strings.ToLower(k)
+		}
+		rows, err = r.db.Query(ctx,
+			`SELECT LOWER(name), id FROM warehouses WHERE tenant_id = $1 AND LOWER(name) = ANY($2)`,
+			tenantID, lower,
+		)
+	} else if entity == models.ImportInventory {
+		rows, err = r.db.Query(ctx,
+			`SELECT sku || ' @ ' || warehouse_id::text, id FROM inventory_items WHERE tenant_id = $1 AND sku = ANY($2)`,
+			tenantID, keys,
+		)
+	} else {
+		k, ok := importKeys[entity]
+		if !ok {
+			return nil, fmt.Errorf("unknown import entity %q", entity)
+		}
+		rows, err = r.db.Query(ctx,
+			fmt.Sprintf(`SELECT %s, id FROM %s WHERE tenant_id = $1 AND %s = ANY($2)`, k.column, k.table, k.column),
+			tenantID, keys,
+		)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up %s: %w", entity, err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var key string
+		var id uuid.UUID
+		if err := rows.Scan(&key, &id); err != nil {
+			return nil, fmt.Errorf("failed to scan %s key: %w", entity, err)
+		}
+		if _, dup := ids[key]; dup {
+			id = uuid.Nil
+		}
+		ids[key] = id
+	}
+	return ids, rows.Err()
+}
+
+// Apply writes the records of an import in one transaction, so a file is
+// imported whole or not at all. Existing records are updated by id; new
+// shipments without a tracking number get one in format f, and each new
+// shipment's timeline opens with a copy of ev. It returns how many records
+// were created and updated.
+func (r *ImportRepo) Apply(ctx context.Context, tenantID uuid.UUID, records []models.ImportRecord, f models.TrackingFormat, ev *models.ShipmentEvent) (created, updated int, err error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	for _, rec := range records {
+		if err := applyImportRecord(ctx, tx, tenantID, rec, f, ev); err != nil {
+			return 0, 0, fmt.Errorf("row %d: %w", rec.Row, err)
+		}
+		if rec.Existing {
+			updated++
+		} else {
+			created++
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return 0, 0, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return created, updated, nil
+}
+
+func applyImportRecord(ctx context.Context, tx pgx.Tx, tenantID uuid.UUID, rec models.ImportRecord, f models.TrackingFormat, ev *models.ShipmentEvent) error {
+	switch v := rec.Value.(type) {
+	case *models.Shipment:
+		v.TenantID = tenantID
+		if rec.Existing {
+			return updateShipment(ctx, tx, tenantID, v.ID, v)
+		}
+		if v.TrackingNumber == "" {
+			tn, err := nextTrackingNumber(ctx, tx, tenantID, f)
+			if err != nil {
+				return err
+			}
+			v.TrackingNumber = tn
+		}
+		var first models.ShipmentEvent
+		if ev != nil {
+			first = *ev
+		}
+		return insertShipment(ctx, tx, v, &first)
+	case *models.Order:
+		v.TenantID = tenantID
+		if rec.Existing {
+			return updateOrder(ctx, tx, tenantID, v.ID, v)
+		}
+		return insertOrder(ctx, tx, v)
+	case *models.InventoryItem:
+		v.TenantID = tenantID
+		ref := models.StockRef{Type: "import", Reason: "Bulk import"}
+		if rec.Existing {
+			return updateInventoryItem(ctx, tx, tenantID, v.ID, v, ref)
+		}
+		return insertInventoryItem(ctx, tx, v, ref)
+	case *models.Driver:
+		v.TenantID = tenantID
+		if rec.Existing {
+			return updateDriver(ctx, tx, tenantID, v.ID, v)
+		}
+		return insertDriver(ctx, tx, v)
+	case *models.Vehicle:
+		v.TenantID = tenantID
+		if rec.Existing {
+			return updateVehicle(ctx, tx, tenantID, v.ID, v)
+		}
+		return insertVehicle(ctx, tx, v)
+	}
+	return fmt.Errorf("cannot import %T", rec.Value)
+}
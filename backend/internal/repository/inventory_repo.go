@@ -2,15 +2,19 @@ package repository
 
 import (
 	"context"
+	"errors"
 	"fmt"
 
 	"cargomax-api/internal/models"
 
 	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
-// InventoryRepo handles database operations for inventory items.
+// InventoryRepo handles database operations for inventory items: the stock
+// levels of catalog products in each warehouse.
 type InventoryRepo struct {
 	db *pgxpool.Pool
 }
@@ -20,48 +24,132 @@ func NewInventoryRepo(db *pgxpool.Pool) *InventoryRepo {
 	return &InventoryRepo{db: db}
 }
 
-// Create inserts a new inventory item.
+// inventoryColumns are read from inventoryFrom: a stock level with its
+// product's description.
+const (
+	inventoryColumns = `i.id, i.tenant_id, i.product_id, i.warehouse_id, i.sku, p.name, p.category, i.quantity, i.reserved, i.in_transit, i.min_quantity, p.unit_price, p.weight, i.status, i.created_at, i.updated_at`
+	inventoryFrom    = `inventory_items i JOIN products p ON p.id = i.product_id`
+)
+
+func scanInventoryItem(row pgx.Row) (*models.InventoryItem, error) {
+	i := &models.InventoryItem{}
+	err := row.Scan(&i.ID, &i.TenantID, &i.ProductID, &i.WarehouseID, &i.SKU, &i.Name, &i.Category, &i.Quantity, &i.Reserved, &i.InTransit, &i.MinQuantity, &i.UnitPrice, &i.Weight, &i.Status, &i.CreatedAt, &i.UpdatedAt)
+	return i, err
+}
+
+// stockLevelError maps a second stock level of a product in one warehouse
+// to a readable error.
+func stockLevelError(action, sku string, err error) error {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == "23505" && pgErr.ConstraintName == "inventory_items_product_warehouse_key" {
+		return fmt.Errorf("%s is already stocked in that warehouse", sku)
+	}
+	return fmt.Errorf("failed to %s: %w", action, err)
+}
+
+// Create inserts a new inventory item, opening its ledger with the stock it
+// starts with. Its SKU is added to the catalog if it is not there yet.
 func (r *InventoryRepo) Create(ctx context.Context, i *models.InventoryItem) error {
-	i.ID = uuid.New()
-	_, err := r.db.Exec(ctx,
-		`INSERT INTO inventory_items (id, tenant_id, warehouse_id, sku, name, category, quantity, min_quantity, unit_price, weight, status, created_at, updated_at)
-		 VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, NOW(), NOW())`,
-		i.ID, i.TenantID, i.WarehouseID, i.SKU, i.Name, i.Category, i.Quantity, i.MinQuantity, i.UnitPrice, i.Weight, i.Status,
-	)
+	tx, err := r.db.Begin(ctx)
 	if err != nil {
-		return fmt.Errorf("failed to create inventory item: %w", err)
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	if err := insertInventoryItem(ctx, tx, i, models.StockRef{}); err != nil {
+		return err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
 	}
 	return nil
 }
 
+// openingBalance is the reason recorded on the movement an item's ledger
+// opens with, unless the caller gives another.
+const openingBalance = "Opening balance"
+
+func insertInventoryItem(ctx context.Context, tx pgx.Tx, i *models.InventoryItem, ref models.StockRef) error {
+	productID, err := catalogProduct(ctx, tx, i)
+	if err != nil {
+		return err
+	}
+	i.ID, i.ProductID = uuid.New(), productID
+	if _, err := tx.Exec(ctx,
+		`INSERT INTO inventory_items (id, tenant_id, product_id, warehouse_id, sku, quantity, min_quantity, status, created_at, updated_at)
+		 VALUES ($1, $2, $3, $4, $5, $6, $7, $8, NOW(), NOW())`,
+		i.ID, i.TenantID, i.ProductID, i.WarehouseID, i.SKU, i.Quantity, i.MinQuantity, i.Status,
+	); err != nil {
+		return stockLevelError("create inventory item", i.SKU, err)
+	}
+	if i.Quantity == 0 {
+		return nil
+	}
+	if ref.Reason == "" {
+		ref.Reason = openingBalance
+	}
+	return appendMovement(ctx, tx, &models.StockMovement{
+		TenantID:        i.TenantID,
+		InventoryItemID: i.ID,
+		WarehouseID:     &i.WarehouseID,
+		SKU:             i.SKU,
+		Type:            models.MovementAdjustment,
+		Quantity:        i.Quantity,
+		Balance:         i.Quantity,
+	}, ref)
+}
+
 // GetByID retrieves an inventory item by ID within a tenant.
 func (r *InventoryRepo) GetByID(ctx context.Context, tenantID, id uuid.UUID) (*models.InventoryItem, error) {
-	i := &models.InventoryItem{}
-	err := r.db.QueryRow(ctx,
-		`SELECT id, tenant_id, warehouse_id, sku, name, category, quantity, min_quantity, unit_price, weight, status, created_at, updated_at
-		 FROM inventory_items WHERE id = $1 AND tenant_id = $2`,
+	i, err := scanInventoryItem(r.db.QueryRow(ctx,
+		`SELECT `+inventoryColumns+`
+		 FROM `+inventoryFrom+` WHERE i.id = $1 AND i.tenant_id = $2`,
 		id, tenantID,
-	).Scan(&i.ID, &i.TenantID, &i.WarehouseID, &i.SKU, &i.Name, &i.Category, &i.Quantity, &i.MinQuantity, &i.UnitPrice, &i.Weight, &i.Status, &i.CreatedAt, &i.UpdatedAt)
+	))
 	if err != nil {
 		return nil, fmt.Errorf("failed to get inventory item by id: %w", err)
 	}
 	return i, nil
 }
 
-// GetBySKU retrieves an inventory item by SKU within a tenant.
-func (r *InventoryRepo) GetBySKU(ctx context.Context, tenantID uuid.UUID, sku string) (*models.InventoryItem, error) {
-	i := &models.InventoryItem{}
-	err := r.db.QueryRow(ctx,
-		`SELECT id, tenant_id, warehouse_id, sku, name, category, quantity, min_quantity, unit_price, weight, status, created_at, updated_at
-		 FROM inventory_items WHERE sku = $1 AND tenant_id = $2`,
-		sku, tenantID,
-	).Scan(&i.ID, &i.TenantID, &i.WarehouseID, &i.SKU, &i.Name, &i.Category, &i.Quantity, &i.MinQuantity, &i.UnitPrice, &i.Weight, &i.Status, &i.CreatedAt, &i.UpdatedAt)
+// GetBySKU retrieves the stock level of a SKU in a warehouse within a
+// tenant. Without a warehouse it is the one with the most stock available.
+func (r *InventoryRepo) GetBySKU(ctx context.Context, tenantID uuid.UUID, sku string, warehouseID *uuid.UUID) (*models.InventoryItem, error) {
+	i, err := scanInventoryItem(r.db.QueryRow(ctx,
+		`SELECT `+inventoryColumns+`
+		 FROM `+inventoryFrom+` WHERE i.sku = $1 AND i.tenant_id = $2 AND ($3::uuid IS NULL OR i.warehouse_id = $3)
+		 ORDER BY i.quantity - i.reserved DESC, i.created_at LIMIT 1`,
+		sku, tenantID, warehouseID,
+	))
 	if err != nil {
 		return nil, fmt.Errorf("failed to get inventory item by sku: %w", err)
 	}
 	return i, nil
 }
 
+// ListByProduct returns a product's stock levels, one per warehouse.
+func (r *InventoryRepo) ListByProduct(ctx context.Context, tenantID, productID uuid.UUID) ([]models.InventoryItem, error) {
+	rows, err := r.db.Query(ctx,
+		`SELECT `+inventoryColumns+`
+		 FROM `+inventoryFrom+` WHERE i.product_id = $1 AND i.tenant_id = $2 ORDER BY i.created_at, i.id`,
+		productID, tenantID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list stock levels: %w", err)
+	}
+	defer rows.Close()
+
+	items := []models.InventoryItem{}
+	for rows.Next() {
+		i, err := scanInventoryItem(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan inventory item: %w", err)
+		}
+		items = append(items, *i)
+	}
+	return items, rows.Err()
+}
+
 // List returns a paginated list of inventory items, optionally filtered by warehouse ID.
 func (r *InventoryRepo) List(ctx context.Context, tenantID uuid.UUID, warehouseID *uuid.UUID, page, perPage int) ([]models.InventoryItem, int, error) {
 	var total int
@@ -87,12 +175,12 @@ func (r *InventoryRepo) List(ctx context.Context, tenantID uuid.UUID, warehouseI
 	var query string
 	var args []interface{}
 	if warehouseID != nil {
-		query = `SELECT id, tenant_id, warehouse_id, sku, name, category, quantity, min_quantity, unit_price, weight, status, created_at, updated_at
-				 FROM inventory_items WHERE tenant_id = $1 AND warehouse_id = $2 ORDER BY name ASC LIMIT $3 OFFSET $4`
+		query = `SELECT ` + inventoryColumns + `
+				 FROM ` + inventoryFrom + ` WHERE i.tenant_id = $1 AND i.warehouse_id = $2 ORDER BY p.name ASC LIMIT $3 OFFSET $4`
 		args = []interface{}{tenantID, *warehouseID, perPage, offset}
 	} else {
-		query = `SELECT id, tenant_id, warehouse_id, sku, name, category, quantity, min_quantity, unit_price, weight, status, created_at, updated_at
-				 FROM inventory_items WHERE tenant_id = $1 ORDER BY name ASC LIMIT $2 OFFSET $3`
+		query = `SELECT ` + inventoryColumns + `
+				 FROM ` + inventoryFrom + ` WHERE i.tenant_id = $1 ORDER BY p.name ASC LIMIT $2 OFFSET $3`
 		args = []interface{}{tenantID, perPage, offset}
 	}
 
@@ -104,21 +192,23 @@ func (r *InventoryRepo) List(ctx context.Context, tenantID uuid.UUID, warehouseI
 
 	var items []models.InventoryItem
 	for rows.Next() {
-		var i models.InventoryItem
-		if err := rows.Scan(&i.ID, &i.TenantID, &i.WarehouseID, &i.SKU, &i.Name, &i.Category, &i.Quantity, &i.MinQuantity, &i.UnitPrice, &i.Weight, &i.Status, &i.CreatedAt, &i.UpdatedAt); err != nil {
+		i, err := scanInventoryItem(rows)
+		if err != nil {
 			return nil, 0, fmt.Errorf("failed to scan inventory item: %w", err)
 		}
-		items = append(items, i)
+		items = append(items, *i)
 	}
 	return items, total, nil
 }
 
-// GetLowStock returns inventory items where quantity is at or below min_quantity within a tenant.
-func (r *InventoryRepo) GetLowStock(ctx context.Context, tenantID uuid.UUID) ([]models.InventoryItem, error) {
+// GetLowStock returns inventory items where quantity is at or below min_quantity within a tenant,
+// optionally in one warehouse. Each warehouse's stock of a product is held to its own minimum.
+func (r *InventoryRepo) GetLowStock(ctx context.Context, tenantID uuid.UUID, warehouseID *uuid.UUID) ([]models.InventoryItem, error) {
 	rows, err := r.db.Query(ctx,
-		`SELECT id, tenant_id, warehouse_id, sku, name, category, quantity, min_quantity, unit_price, weight, status, created_at, updated_at
-		 FROM inventory_items WHERE tenant_id = $1 AND quantity <= min_quantity ORDER BY quantity ASC`,
-		tenantID,
+		`SELECT `+inventoryColumns+`
+		 FROM `+inventoryFrom+` WHERE i.tenant_id = $1 AND ($2::uuid IS NULL OR i.warehouse_id = $2) AND i.quantity <= i.min_quantity
+		 ORDER BY i.quantity ASC`,
+		tenantID, warehouseID,
 	)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get low stock items: %w", err)
@@ -127,58 +217,164 @@ func (r *InventoryRepo) GetLowStock(ctx context.Context, tenantID uuid.UUID) ([]
 
 	var items []models.InventoryItem
 	for rows.Next() {
-		var i models.InventoryItem
-		if err := rows.Scan(&i.ID, &i.TenantID, &i.WarehouseID, &i.SKU, &i.Name, &i.Category, &i.Quantity, &i.MinQuantity, &i.UnitPrice, &i.Weight, &i.Status, &i.CreatedAt, &i.UpdatedAt); err != nil {
+		i, err := scanInventoryItem(rows)
+		if err != nil {
 			return nil, fmt.Errorf("failed to scan low stock item: %w", err)
 		}
-		items = append(items, i)
+		items = append(items, *i)
 	}
 	return items, nil
 }
 
-// Update modifies an existing inventory item.
-func (r *InventoryRepo) Update(ctx context.Context, tenantID, id uuid.UUID, i *models.InventoryItem) error {
-	ct, err := r.db.Exec(ctx,
-		`UPDATE inventory_items SET warehouse_id = $1, sku = $2, name = $3, category = $4, quantity = $5, min_quantity = $6, unit_price = $7, weight = $8, status = $9, updated_at = NOW()
-		 WHERE id = $10 AND tenant_id = $11`,
-		i.WarehouseID, i.SKU, i.Name, i.Category, i.Quantity, i.MinQuantity, i.UnitPrice, i.Weight, i.Status, id, tenantID,
-	)
+// Update modifies an existing inventory item. A change to its quantity is
+// recorded as an adjustment for reason, and moving it to another warehouse
+// as a transfer of its stock out of the old one and into the new. Its SKU,
+// name, category, price and weight are its product's, so changing them
+// changes the product's stock in every warehouse.
+func (r *InventoryRepo) Update(ctx context.Context, tenantID, id uuid.UUID, i *models.InventoryItem, reason string) error {
+	tx, err := r.db.Begin(ctx)
 	if err != nil {
-		return fmt.Errorf("failed to update inventory item: %w", err)
+		return fmt.Errorf("failed to begin transaction: %w", err)
 	}
-	if ct.RowsAffected() == 0 {
+	defer tx.Rollback(ctx)
+
+	if err := updateInventoryItem(ctx, tx, tenantID, id, i, models.StockRef{Reason: reason}); err != nil {
+		return err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+func updateInventoryItem(ctx context.Context, tx pgx.Tx, tenantID, id uuid.UUID, i *models.InventoryItem, ref models.StockRef) error {
+	var quantity, inTransit int
+	var warehouseID, productID uuid.UUID
+	var volume *float64
+	var unitsPerPallet *int
+	err := tx.QueryRow(ctx,
+		`SELECT i.quantity, i.in_transit, i.warehouse_id, i.product_id, p.volume, p.units_per_pallet
+		 FROM inventory_items i JOIN products p ON p.id = i.product_id
+		 WHERE i.id = $1 AND i.tenant_id = $2 FOR UPDATE OF i`,
+		id, tenantID,
+	).Scan(&quantity, &inTransit, &warehouseID, &productID, &volume, &unitsPerPallet)
+	if errors.Is(err, pgx.ErrNoRows) {
 		return fmt.Errorf("inventory item not found")
 	}
+	if err != nil {
+		return fmt.Errorf("failed to get inventory item: %w", err)
+	}
+	if warehouseID != i.WarehouseID && inTransit > 0 {
+		return fmt.Errorf("%d of %s are in transit to this warehouse; receive them before moving it", inTransit, i.SKU)
+	}
+	i.ProductID = productID
+	if err := updateProduct(ctx, tx, &models.Product{
+		ID: productID, TenantID: tenantID, SKU: i.SKU, Name: i.Name, Category: i.Category, UnitPrice: i.UnitPrice, Weight: i.Weight,
+		Volume: volume, UnitsPerPallet: unitsPerPallet,
+	}); err != nil {
+		return err
+	}
+	if err := moveStock(ctx, tx, tenantID, id, models.MovementAdjustment, i.Quantity-quantity, ref); err != nil {
+		return err
+	}
+
+	moved := warehouseID != i.WarehouseID && i.Quantity != 0
+	transfer := func(warehouseID uuid.UUID, n, balance int) error {
+		return appendMovement(ctx, tx, &models.StockMovement{
+			TenantID:        tenantID,
+			InventoryItemID: id,
+			WarehouseID:     &warehouseID,
+			SKU:             i.SKU,
+			Type:            models.MovementTransfer,
+			Quantity:        n,
+			Balance:         balance,
+		}, ref)
+	}
+	if moved {
+		if err := transfer(warehouseID, -i.Quantity, 0); err != nil {
+			return err
+		}
+	}
+	if _, err := tx.Exec(ctx,
+		`UPDATE inventory_items SET warehouse_id = $1, min_quantity = $2, status = $3, updated_at = NOW()
+		 WHERE id = $4 AND tenant_id = $5`,
+		i.WarehouseID, i.MinQuantity, i.Status, id, tenantID,
+	); err != nil {
+		return stockLevelError("update inventory item", i.SKU, err)
+	}
+	if moved {
+		return transfer(i.WarehouseID, i.Quantity, i.Quantity)
+	}
 	return nil
 }
 
-// Delete removes an inventory item by ID within a tenant.
+// Delete removes an inventory item by ID within a tenant, freeing the space
+// its stock took up in its warehouse.
 func (r *InventoryRepo) Delete(ctx context.Context, tenantID, id uuid.UUID) error {
-	ct, err := r.db.Exec(ctx,
-		`DELETE FROM inventory_items WHERE id = $1 AND tenant_id = $2`,
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	var warehouseID uuid.UUID
+	err = tx.QueryRow(ctx,
+		`DELETE FROM inventory_items WHERE id = $1 AND tenant_id = $2 RETURNING warehouse_id`,
 		id, tenantID,
-	)
+	).Scan(&warehouseID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return fmt.Errorf("inventory item not found")
+	}
 	if err != nil {
 		return fmt.Errorf("failed to delete inventory item: %w", err)
 	}
-	if ct.RowsAffected() == 0 {
-		return fmt.Errorf("inventory item not found")
+	if err := refreshUsedCapacity(ctx, tx, tenantID, []uuid.UUID{warehouseID}); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
 	}
 	return nil
 }
 
-// Restock increases the quantity of an inventory item.
-func (r *InventoryRepo) Restock(ctx context.Context, tenantID, id uuid.UUID, quantity int) error {
-	ct, err := r.db.Exec(ctx,
-		`UPDATE inventory_items SET quantity = quantity + $1, status = CASE WHEN quantity + $1 > min_quantity THEN 'in_stock' ELSE status END, updated_at = NOW()
-		 WHERE id = $2 AND tenant_id = $3`,
-		quantity, id, tenantID,
-	)
+// Restock receives quantity units of an inventory item into stock against
+// ref.
+func (r *InventoryRepo) Restock(ctx context.Context, tenantID, id uuid.UUID, quantity int, ref models.StockRef) error {
+	return r.Adjust(ctx, tenantID, id, models.MovementReceipt, quantity, ref)
+}
+
+// Adjust changes an inventory item's stock on hand by n, recording a
+// movement of the given type against ref. It cannot take away more than is
+// on hand.
+func (r *InventoryRepo) Adjust(ctx context.Context, tenantID, id uuid.UUID, movementType string, n int, ref models.StockRef) error {
+	tx, err := r.db.Begin(ctx)
 	if err != nil {
-		return fmt.Errorf("failed to restock inventory item: %w", err)
+		return fmt.Errorf("failed to begin transaction: %w", err)
 	}
-	if ct.RowsAffected() == 0 {
-		return fmt.Errorf("inventory item not found")
+	defer tx.Rollback(ctx)
+
+	if n < 0 {
+		var onHand int
+		err := tx.QueryRow(ctx,
+			`SELECT quantity FROM inventory_items WHERE id = $1 AND tenant_id = $2 FOR UPDATE`,
+			id, tenantID,
+		).Scan(&onHand)
+		if errors.Is(err, pgx.ErrNoRows) {
+			return fmt.Errorf("inventory item not found")
+		}
+		if err != nil {
+			return fmt.Errorf("failed to get inventory item: %w", err)
+		}
+		if onHand+n < 0 {
+			return fmt.Errorf("%w: only %d on hand", ErrInsufficientStock, onHand)
+		}
+	}
+	if err := moveStock(ctx, tx, tenantID, id, movementType, n, ref); err != nil {
+		return err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
 	}
 	return nil
 }
@@ -0,0 +1,541 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"cargomax-api/internal/models"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// ErrInvoiceNotFound is returned when an invoice does not exist within the
+// tenant.
+var ErrInvoiceNotFound = errors.New("invoice not found")
+
+// InvoiceRepo handles database operations for invoices, credit notes and
+// payments.
+type InvoiceRepo struct {
+	db *pgxpool.Pool
+}
+
+// NewInvoiceRepo creates a new InvoiceRepo instance.
+func NewInvoiceRepo(db *pgxpool.Pool) *InvoiceRepo {
+	return &InvoiceRepo{db: db}
+}
+
+const invoiceColumns = `id, tenant_id, client_id, kind, invoice_number, status, credited_invoice_id, bill_to_name, bill_to_address, period_start, period_end, issue_date, due_date, currency, lines, taxes, subtotal, tax_total, total, amount_paid, amount_credited, reason, created_by, created_at, updated_at`
+
+func scanInvoice(row pgx.Row) (*models.Invoice, error) {
+	i := &models.Invoice{}
+	err := row.Scan(&i.ID, &i.TenantID, &i.ClientID, &i.Kind, &i.InvoiceNumber, &i.Status, &i.CreditedInvoiceID, &i.BillToName, &i.BillToAddress,
+		&i.PeriodStart, &i.PeriodEnd, &i.IssueDate, &i.DueDate, &i.Currency, &i.Lines, &i.Taxes,
+		&i.Subtotal, &i.TaxTotal, &i.Total, &i.AmountPaid, &i.AmountCredited, &i.Reason, &i.CreatedBy, &i.CreatedAt, &i.UpdatedAt)
+	return i, err
+}
+
+const paymentColumns = `id, tenant_id, invoice_id, client_id, amount, method, reference, paid_at, created_by, created_at`
+
+func scanPayment(row pgx.Row) (*models.Payment, error) {
+	p := &models.Payment{}
+	err := row.Scan(&p.ID, &p.TenantID, &p.InvoiceID, &p.ClientID, &p.Amount, &p.Method, &p.Reference, &p.PaidAt, &p.CreatedBy, &p.CreatedAt)
+	return p, err
+}
+
+// GetByID retrieves an invoice or credit note by ID within a tenant.
+func (r *InvoiceRepo) GetByID(ctx context.Context, tenantID, id uuid.UUID) (*models.Invoice, error) {
+	return getInvoice(ctx, r.db, tenantID, id, "")
+}
+
+// getInvoice reads an invoice, with lock appended to the query (e.g. "FOR
+// UPDATE") when it is given.
+func getInvoice(ctx context.Context, q rowQuerier, tenantID, id uuid.UUID, lock string) (*models.Invoice, error) {
+	i, err := scanInvoice(q.QueryRow(ctx,
+		`SELECT `+invoiceColumns+` FROM invoices WHERE id = $1 AND tenant_id = $2 `+lock,
+		id, tenantID,
+	))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrInvoiceNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get invoice: %w", err)
+	}
+	return i, nil
+}
+
+// List returns a page of the tenant's invoices and credit notes, newest
+// first.
+func (r *InvoiceRepo) List(ctx context.Context, tenantID uuid.UUID, f models.InvoiceFilter, page, perPage int) ([]models.Invoice, int, error) {
+	where := []string{"tenant_id = $1"}
+	args := []interface{}{tenantID}
+	if f.ClientID != nil {
+		args = append(args, *f.ClientID)
+		where = append(where, fmt.Sprintf("client_id = $%d", len(args)))
+	}
+	if f.Kind != "" {
+		args = append(args, f.Kind)
+		where = append(where, fmt.Sprintf("kind = $%d", len(args)))
+	}
+	if f.Status != "" {
+		args = append(args, f.Status)
+		where = append(where, fmt.Sprintf("status = $%d", len(args)))
+	}
+	cond := strings.Join(where, " AND ")
+
+	var total int
+	if err := r.db.QueryRow(ctx, `SELECT COUNT(*) FROM invoices WHERE `+cond, args...).Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("failed to count invoices: %w", err)
+	}
+
+	args = append(args, perPage, (page-1)*perPage)
+	rows, err := r.db.Query(ctx,
+		fmt.Sprintf(`SELECT `+invoiceColumns+` FROM invoices WHERE %s ORDER BY issue_date DESC, invoice_number DESC LIMIT $%d OFFSET $%d`, cond, len(args)-1, len(args)),
+		args...,
+	)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to list invoices: %w", err)
+	}
+	defer rows.Close()
+
+	var invoices []models.Invoice
+	for rows.Next() {
+		i, err := scanInvoice(rows)
+		if err != nil {
+			return nil, 0, fmt.Errorf("failed to scan invoice: %w", err)
+		}
+		invoices = append(invoices, *i)
+	}
+	return invoices, total, rows.Err()
+}
+
+// CreditNotes returns the credit notes issued against an invoice, oldest
+// first.
+func (r *InvoiceRepo) CreditNotes(ctx context.Context, tenantID, invoiceID uuid.UUID) ([]models.Invoice, error) {
+	rows, err := r.db.Query(ctx,
+		`SELECT `+invoiceColumns+` FROM invoices WHERE tenant_id = $1 AND credited_invoice_id = $2 ORDER BY created_at`,
+		tenantID, invoiceID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list credit notes: %w", err)
+	}
+	defer rows.Close()
+
+	notes := []models.Invoice{}
+	for rows.Next() {
+		i, err := scanInvoice(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan credit note: %w", err)
+		}
+		notes = append(notes, *i)
+	}
+	return notes, rows.Err()
+}
+
+// Payments returns the payments recorded against an invoice, oldest first.
+func (r *InvoiceRepo) Payments(ctx context.Context, tenantID, invoiceID uuid.UUID) ([]models.Payment, error) {
+	rows, err := r.db.Query(ctx,
+		`SELECT `+paymentColumns+` FROM payments WHERE tenant_id = $1 AND invoice_id = $2 ORDER BY paid_at, created_at`,
+		tenantID, invoiceID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list payments: %w", err)
+	}
+	defer rows.Close()
+
+	payments := []models.Payment{}
+	for rows.Next() {
+		p, err := scanPayment(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan payment: %w", err)
+		}
+		payments = append(payments, *p)
+	}
+	return payments, rows.Err()
+}
+
+// Generate bills the delivered orders of run's period that are not yet on an
+// invoice, one invoice per client, numbered in client order. An order counts
+// as delivered on its shipment's delivery date, or on its last update when it
+// has no delivered shipment; orders without a client or an amount are left
+// for a later run. It returns the invoices issued, none when there was
+// nothing to bill.
+func (r *InvoiceRepo) Generate(ctx context.Context, tenantID uuid.UUID, run models.InvoiceRun, s models.InvoiceSettings) ([]models.Invoice, error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	// The row locks keep a concurrent run from billing the same orders; it
+	// waits, then finds them invoiced.
+	rows, err := tx.Query(ctx,
+		`SELECT o.id, o.order_number, o.customer_name, o.client_id, o.total_amount, COALESCE(s.actual_delivery, o.updated_at) AS delivered
+		 FROM orders o
+		 LEFT JOIN shipments s ON s.id = o.shipment_id AND s.tenant_id = o.tenant_id
+		 WHERE o.tenant_id = $1 AND o.status = 'delivered' AND o.invoice_id IS NULL
+		   AND o.client_id IS NOT NULL AND o.total_amount IS NOT NULL
+		   AND ($2::uuid IS NULL OR o.client_id = $2)
+		   AND COALESCE(s.actual_delivery, o.updated_at) >= $3 AND COALESCE(s.actual_delivery, o.updated_at) < $4
+		 ORDER BY o.client_id, delivered, o.order_number
+		 FOR UPDATE OF o`,
+		tenantID, run.ClientID, run.PeriodStart, run.PeriodEnd.AddDate(0, 0, 1),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find orders to invoice: %w", err)
+	}
+	type billed struct {
+		client   uuid.UUID
+		orderIDs []uuid.UUID
+		lines    []models.InvoiceLine
+	}
+	var groups []*billed
+	for rows.Next() {
+		var id, clientID uuid.UUID
+		var number string
+		var customer *string
+		var amount float64
+		var delivered time.Time
+		if err := rows.Scan(&id, &number, &customer, &clientID, &amount, &delivered); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan order: %w", err)
+		}
+		if len(groups) == 0 || groups[len(groups)-1].client != clientID {
+			groups = append(groups, &billed{client: clientID})
+		}
+		g := groups[len(groups)-1]
+		desc := "Order " + number
+		if customer != nil && *customer != "" {
+			desc += " (" + *customer + ")"
+		}
+		desc += ", delivered " + delivered.UTC().Format("2006-01-02")
+		orderID := id
+		g.orderIDs = append(g.orderIDs, id)
+		g.lines = append(g.lines, models.InvoiceLine{OrderID: &orderID, Description: desc, Amount: amount})
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to find orders to invoice: %w", err)
+	}
+
+	invoices := []models.Invoice{}
+	for _, g := range groups {
+		start, end := run.PeriodStart, run.PeriodEnd
+		inv := models.Invoice{
+			TenantID:    tenantID,
+			ClientID:    g.client,
+			Kind:        models.InvoiceKindInvoice,
+			Status:      models.InvoiceIssued,
+			PeriodStart: &start,
+			PeriodEnd:   &end,
+			IssueDate:   run.IssueDate,
+			DueDate:     run.IssueDate.AddDate(0, 0, s.PaymentTermsDays),
+			Currency:    s.Currency,
+			Lines:       g.lines,
+			CreatedBy:   run.CreatedBy,
+		}
+		inv.SetTotals(s.Taxes)
+		if err := billTo(ctx, tx, tenantID, &inv); err != nil {
+			return nil, err
+		}
+		n, err := nextSequence(ctx, tx, tenantID, models.SequenceInvoice)
+		if err != nil {
+			return nil, err
+		}
+		inv.InvoiceNumber = models.FormatInvoiceNumber(s.Prefix, n)
+		if err := insertInvoice(ctx, tx, &inv); err != nil {
+			return nil, err
+		}
+		if _, err := tx.Exec(ctx,
+			`UPDATE orders SET invoice_id = $1 WHERE tenant_id = $2 AND id = ANY($3)`,
+			inv.ID, tenantID, g.orderIDs,
+		); err != nil {
+			return nil, fmt.Errorf("failed to mark orders invoiced: %w", err)
+		}
+		invoices = append(invoices, inv)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return invoices, nil
+}
+
+// billTo copies the client's name and address onto inv.
+func billTo(ctx context.Context, q rowQuerier, tenantID uuid.UUID, inv *models.Invoice) error {
+	err := q.QueryRow(ctx,
+		`SELECT company_name, address FROM clients WHERE id = $1 AND tenant_id = $2`,
+		inv.ClientID, tenantID,
+	).Scan(&inv.BillToName, &inv.BillToAddress)
+	if err != nil {
+		return fmt.Errorf("failed to get client to bill: %w", err)
+	}
+	return nil
+}
+
+func insertInvoice(ctx context.Context, q rowQuerier, inv *models.Invoice) error {
+	inv.ID = uuid.New()
+	err := q.QueryRow(ctx,
+		`INSERT INTO invoices (id, tenant_id, client_id, kind, invoice_number, status, credited_invoice_id, bill_to_name, bill_to_address, period_start, period_end, issue_date, due_date, currency, lines, taxes, subtotal, tax_total, total, reason, created_by, created_at, updated_at)
+		 VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19, $20, $21, NOW(), NOW())
+		 RETURNING created_at, updated_at`,
+		inv.ID, inv.TenantID, inv.ClientID, inv.Kind, inv.InvoiceNumber, inv.Status, inv.CreditedInvoiceID, inv.BillToName, inv.BillToAddress,
+		inv.PeriodStart, inv.PeriodEnd, inv.IssueDate, inv.DueDate, inv.Currency, inv.Lines, inv.Taxes, inv.Subtotal, inv.TaxTotal, inv.Total, inv.Reason, inv.CreatedBy,
+	).Scan(&inv.CreatedAt, &inv.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create invoice: %w", err)
+	}
+	return nil
+}
+
+// settle saves what has been paid and credited on inv and its status.
+func settle(ctx context.Context, q rowQuerier, inv *models.Invoice) error {
+	inv.SettleStatus()
+	err := q.QueryRow(ctx,
+		`UPDATE invoices SET amount_paid = $1, amount_credited = $2, status = $3, updated_at = NOW()
+		 WHERE id = $4 AND tenant_id = $5
+		 RETURNING updated_at`,
+		inv.AmountPaid, inv.AmountCredited, inv.Status, inv.ID, inv.TenantID,
+	).Scan(&inv.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to update invoice: %w", err)
+	}
+	return nil
+}
+
+// openInvoice locks an invoice that can still be paid or credited.
+func openInvoice(ctx context.Context, q rowQuerier, tenantID, id uuid.UUID) (*models.Invoice, error) {
+	inv, err := getInvoice(ctx, q, tenantID, id, "FOR UPDATE")
+	if err != nil {
+		return nil, err
+	}
+	if inv.Kind != models.InvoiceKindInvoice {
+		return nil, fmt.Errorf("%s is a credit note, not an invoice", inv.InvoiceNumber)
+	}
+	if inv.Status == models.InvoiceVoid {
+		return nil, fmt.Errorf("invoice %s is void", inv.InvoiceNumber)
+	}
+	return inv, nil
+}
+
+// RecordPayment records p against an invoice, which it may not overpay, and
+// adds it to the client's total_spent. It returns the updated invoice.
+func (r *InvoiceRepo) RecordPayment(ctx context.Context, tenantID, invoiceID uuid.UUID, p *models.Payment) (*models.Invoice, error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	inv, err := openInvoice(ctx, tx, tenantID, invoiceID)
+	if err != nil {
+		return nil, err
+	}
+	p.Amount = models.RoundMoney(p.Amount)
+	if !(p.Amount > 0) {
+		return nil, fmt.Errorf("payment amount must be positive")
+	}
+	if due := inv.BalanceDue(); p.Amount > due {
+		return nil, fmt.Errorf("payment of %.2f is more than the %.2f due on %s", p.Amount, math.Max(due, 0), inv.InvoiceNumber)
+	}
+
+	p.ID = uuid.New()
+	p.TenantID, p.InvoiceID, p.ClientID = tenantID, inv.ID, inv.ClientID
+	if p.PaidAt.IsZero() {
+		p.PaidAt = time.Now().UTC()
+	}
+	err = tx.QueryRow(ctx,
+		`INSERT INTO payments (id, tenant_id, invoice_id, client_id, amount, method, reference, paid_at, created_by, created_at)
+		 VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, NOW())
+		 RETURNING created_at`,
+		p.ID, p.TenantID, p.InvoiceID, p.ClientID, p.Amount, p.Method, p.Reference, p.PaidAt, p.CreatedBy,
+	).Scan(&p.CreatedAt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to record payment: %w", err)
+	}
+
+	inv.AmountPaid = models.RoundMoney(inv.AmountPaid + p.Amount)
+	if err := settle(ctx, tx, inv); err != nil {
+		return nil, err
+	}
+	if err := addClientSpent(ctx, tx, tenantID, inv.ClientID, p.Amount); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return inv, nil
+}
+
+// addClientSpent adds amount, which may be negative, to the client's
+// total_spent, never taking it below zero.
+func addClientSpent(ctx context.Context, q execer, tenantID, clientID uuid.UUID, amount float64) error {
+	_, err := q.Exec(ctx,
+		`UPDATE clients SET total_spent = GREATEST(COALESCE(total_spent, 0) + $1, 0), updated_at = NOW() WHERE id = $2 AND tenant_id = $3`,
+		amount, clientID, tenantID,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to update client total spent: %w", err)
+	}
+	return nil
+}
+
+// CreateCreditNote issues a credit note against an invoice, taxed at the
+// invoice's rates, and takes it off the invoice's balance. Credited orders
+// must be lines of the invoice, returned, and not credited before; the
+// invoice cannot be credited for more than it billed. When the credit
+// leaves the client having paid more than it now owes, the excess is owed
+// back to the client and comes off its total_spent.
+func (r *InvoiceRepo) CreateCreditNote(ctx context.Context, tenantID uuid.UUID, cn models.CreditNoteRequest, s models.InvoiceSettings) (*models.Invoice, error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	note, err := createCreditNote(ctx, tx, tenantID, cn, s)
+	if err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return note, nil
+}
+
+// createCreditNote issues a credit note within tx, as CreateCreditNote
+// describes.
+func createCreditNote(ctx context.Context, tx pgx.Tx, tenantID uuid.UUID, cn models.CreditNoteRequest, s models.InvoiceSettings) (*models.Invoice, error) {
+	inv, err := openInvoice(ctx, tx, tenantID, cn.InvoiceID)
+	if err != nil {
+		return nil, err
+	}
+
+	note := models.Invoice{
+		TenantID:          tenantID,
+		ClientID:          inv.ClientID,
+		Kind:              models.InvoiceKindCreditNote,
+		Status:            models.InvoiceIssued,
+		CreditedInvoiceID: &inv.ID,
+		IssueDate:         cn.IssueDate,
+		DueDate:           cn.IssueDate,
+		Currency:          inv.Currency,
+		Lines:             []models.InvoiceLine{},
+		Reason:            &cn.Reason,
+		CreatedBy:         cn.CreatedBy,
+	}
+	billed := map[uuid.UUID]models.InvoiceLine{}
+	for _, l := range inv.Lines {
+		if l.OrderID != nil {
+			billed[*l.OrderID] = l
+		}
+	}
+	for _, id := range cn.OrderIDs {
+		line, ok := billed[id]
+		if !ok {
+			return nil, fmt.Errorf("order %s is not billed on %s", id, inv.InvoiceNumber)
+		}
+		var number, status string
+		var credited *uuid.UUID
+		err := tx.QueryRow(ctx,
+			`SELECT order_number, status, credit_note_id FROM orders WHERE id = $1 AND tenant_id = $2 FOR UPDATE`,
+			id, tenantID,
+		).Scan(&number, &status, &credited)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get order %s: %w", id, err)
+		}
+		if status != "returned" {
+			return nil, fmt.Errorf("order %s has not been returned", number)
+		}
+		if credited != nil {
+			return nil, fmt.Errorf("order %s has already been credited", number)
+		}
+		line.Description = "Return of " + strings.TrimPrefix(line.Description, "Order ")
+		note.Lines = append(note.Lines, line)
+		delete(billed, id)
+	}
+	if cn.Amount < 0 {
+		return nil, fmt.Errorf("credit amount cannot be negative")
+	}
+	if cn.Amount > 0 {
+		note.Lines = append(note.Lines, models.InvoiceLine{Description: "Credit: " + cn.Reason, Amount: models.RoundMoney(cn.Amount)})
+	}
+	if len(note.Lines) == 0 {
+		return nil, fmt.Errorf("a credit note needs returned orders or an amount")
+	}
+	note.SetTotals(inv.TaxRates())
+	if remaining := inv.CreditLeft(); note.Total > remaining {
+		return nil, fmt.Errorf("credit of %.2f is more than the %.2f left to credit on %s", note.Total, remaining, inv.InvoiceNumber)
+	}
+
+	if err := billTo(ctx, tx, tenantID, &note); err != nil {
+		return nil, err
+	}
+	n, err := nextSequence(ctx, tx, tenantID, models.SequenceCreditNote)
+	if err != nil {
+		return nil, err
+	}
+	note.InvoiceNumber = models.FormatInvoiceNumber(s.CreditNotePrefix, n)
+	if err := insertInvoice(ctx, tx, &note); err != nil {
+		return nil, err
+	}
+	if len(cn.OrderIDs) > 0 {
+		if _, err := tx.Exec(ctx,
+			`UPDATE orders SET credit_note_id = $1 WHERE tenant_id = $2 AND id = ANY($3)`,
+			note.ID, tenantID, cn.OrderIDs,
+		); err != nil {
+			return nil, fmt.Errorf("failed to mark orders credited: %w", err)
+		}
+	}
+
+	refund := inv.Credit(note.Total)
+	if err := settle(ctx, tx, inv); err != nil {
+		return nil, err
+	}
+	if refund > 0 {
+		if err := addClientSpent(ctx, tx, tenantID, inv.ClientID, -refund); err != nil {
+			return nil, err
+		}
+	}
+	return &note, nil
+}
+
+// Void cancels an invoice nothing has been paid or credited on. It keeps its
+// number, and its orders can be billed again.
+func (r *InvoiceRepo) Void(ctx context.Context, tenantID, id uuid.UUID, reason string) (*models.Invoice, error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	inv, err := openInvoice(ctx, tx, tenantID, id)
+	if err != nil {
+		return nil, err
+	}
+	if inv.AmountPaid > 0 || inv.AmountCredited > 0 {
+		return nil, fmt.Errorf("invoice %s has payments or credit notes; issue a credit note instead", inv.InvoiceNumber)
+	}
+	inv.Status, inv.Reason = models.InvoiceVoid, &reason
+	err = tx.QueryRow(ctx,
+		`UPDATE invoices SET status = $1, reason = $2, updated_at = NOW() WHERE id = $3 AND tenant_id = $4 RETURNING updated_at`,
+		inv.Status, inv.Reason, inv.ID, tenantID,
+	).Scan(&inv.UpdatedAt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to void invoice: %w", err)
+	}
+	if _, err := tx.Exec(ctx, `UPDATE orders SET invoice_id = NULL WHERE tenant_id = $1 AND invoice_id = $2`, tenantID, inv.ID); err != nil {
+		return nil, fmt.Errorf("failed to release invoiced orders: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return inv, nil
+}
@@ -0,0 +1,180 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"cargomax-api/internal/database"
+	"cargomax-api/internal/models"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// JobRepo handles persistence for the background job queue. Queue operations
+// are cross-tenant by design (like ShiftRepo.GetAllActive) and run on the
+// privileged connection path: the scheduler serves every tenant and hands the
+// job's own tenant_id to its handler. ListByTenant stays tenant-scoped.
+type JobRepo struct {
+	db *pgxpool.Pool
+}
+
+// NewJobRepo creates a new JobRepo instance.
+func NewJobRepo(db *pgxpool.Pool) *JobRepo {
+	return &JobRepo{db: db}
+}
+
+const jobColumns = `id, tenant_id, type, payload, status, attempts, max_attempts, run_at, locked_at, locked_by, last_error, dedupe_key, completed_at, created_at, updated_at`
+
+func scanJob(row interface{ Scan(...any) error }, j *models.Job) error {
+	return row.Scan(&j.ID, &j.TenantID, &j.Type, &j.Payload, &j.Status, &j.Attempts, &j.MaxAttempts, &j.RunAt, &j.LockedAt, &j.LockedBy, &j.LastError, &j.DedupeKey, &j.CompletedAt, &j.CreatedAt, &j.UpdatedAt)
+}
+
+// Enqueue inserts a pending job. When DedupeKey is set and a job with the same
+// key already exists, nothing is inserted and false is returned.
+func (r *JobRepo) Enqueue(ctx context.Context, j *models.Job) (bool, error) {
+	j.ID = uuid.New()
+	j.Status = "pending"
+	if j.MaxAttempts <= 0 {
+		j.MaxAttempts = 5
+	}
+	if j.RunAt.IsZero() {
+		j.RunAt = time.Now()
+	}
+	if len(j.Payload) == 0 {
+		j.Payload = []byte("{}")
+	}
+	ct, err := r.db.Exec(database.Privileged(ctx),
+		`INSERT INTO jobs (id, tenant_id, type, payload, status, attempts, max_attempts, run_at, dedupe_key, created_at, updated_at)
+		 VALUES ($1, $2, $3, $4, $5, 0, $6, $7, $8, NOW(), NOW())
+		 ON CONFLICT (dedupe_key) DO NOTHING`,
+		j.ID, j.TenantID, j.Type, j.Payload, j.Status, j.MaxAttempts, j.RunAt, j.DedupeKey,
+	)
+	if err != nil {
+		return false, fmt.Errorf("failed to enqueue job: %w", err)
+	}
+	return ct.RowsAffected() > 0, nil
+}
+
+// ClaimDue atomically locks up to limit due jobs of the given types for this
+// worker. Jobs left in 'running' longer than staleAfter (e.g. after a crash)
+// are reclaimed, unless that was their last attempt: those are buried, so a
+// job that takes its worker down every time does not run forever. SKIP
+// LOCKED lets several replicas poll concurrently.
+func (r *JobRepo) ClaimDue(ctx context.Context, workerID string, types []string, limit int, staleAfter time.Duration) ([]models.Job, error) {
+	ctx = database.Privileged(ctx)
+	if _, err := r.db.Exec(ctx,
+		`UPDATE jobs SET status = 'dead', last_error = 'worker ' || COALESCE(locked_by, '') || ' stopped during the last attempt',
+			locked_at = NULL, locked_by = NULL, updated_at = NOW()
+		 WHERE type = ANY($1) AND status = 'running' AND attempts >= max_attempts
+		   AND locked_at < NOW() - make_interval(secs => $2)`,
+		types, staleAfter.Seconds(),
+	); err != nil {
+		return nil, fmt.Errorf("failed to bury stale jobs: %w", err)
+	}
+
+	rows, err := r.db.Query(ctx,
+		`UPDATE jobs SET status = 'running', locked_at = NOW(), locked_by = $1, attempts = attempts + 1, updated_at = NOW()
+		 WHERE id IN (
+			SELECT id FROM jobs
+			WHERE type = ANY($2)
+			  AND ((status = 'pending' AND run_at <= NOW())
+			    OR (status = 'running' AND attempts < max_attempts AND locked_at < NOW() - make_interval(secs => $4)))
+			ORDER BY run_at ASC
+			LIMIT $3
+			FOR UPDATE SKIP LOCKED
+		 )
+		 RETURNING `+jobColumns,
+		workerID, types, limit, staleAfter.Seconds(),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to claim jobs: %w", err)
+	}
+	defer rows.Close()
+
+	var jobs []models.Job
+	for rows.Next() {
+		var j models.Job
+		if err := scanJob(rows, &j); err != nil {
+			return nil, fmt.Errorf("failed to scan job: %w", err)
+		}
+		jobs = append(jobs, j)
+	}
+	return jobs, rows.Err()
+}
+
+// Complete marks a job as successfully finished.
+func (r *JobRepo) Complete(ctx context.Context, id uuid.UUID) error {
+	_, err := r.db.Exec(database.Privileged(ctx),
+		`UPDATE jobs SET status = 'completed', completed_at = NOW(), locked_at = NULL, locked_by = NULL, last_error = NULL, updated_at = NOW()
+		 WHERE id = $1`,
+		id,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to complete job: %w", err)
+	}
+	return nil
+}
+
+// Retry returns a failed job to 'pending' so it runs again at retryAt.
+func (r *JobRepo) Retry(ctx context.Context, id uuid.UUID, lastError string, retryAt time.Time) error {
+	_, err := r.db.Exec(database.Privileged(ctx),
+		`UPDATE jobs SET status = 'pending', run_at = $2, last_error = $3, locked_at = NULL, locked_by = NULL, updated_at = NOW()
+		 WHERE id = $1`,
+		id, retryAt, lastError,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to reschedule job: %w", err)
+	}
+	return nil
+}
+
+// Bury moves a job that exhausted its attempts to the 'dead' (dead-letter) state.
+func (r *JobRepo) Bury(ctx context.Context, id uuid.UUID, lastError string) error {
+	_, err := r.db.Exec(database.Privileged(ctx),
+		`UPDATE jobs SET status = 'dead', last_error = $2, locked_at = NULL, locked_by = NULL, updated_at = NOW()
+		 WHERE id = $1`,
+		id, lastError,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to bury job: %w", err)
+	}
+	return nil
+}
+
+// ListByTenant returns a tenant's most recent jobs, optionally filtered by status.
+func (r *JobRepo) ListByTenant(ctx context.Context, tenantID uuid.UUID, status string, limit int) ([]models.Job, error) {
+	rows, err := r.db.Query(ctx,
+		`SELECT `+jobColumns+`
+		 FROM jobs WHERE tenant_id = $1 AND ($2 = '' OR status = $2)
+		 ORDER BY created_at DESC LIMIT $3`,
+		tenantID, status, limit,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list jobs: %w", err)
+	}
+	defer rows.Close()
+
+	var jobs []models.Job
+	for rows.Next() {
+		var j models.Job
+		if err := scanJob(rows, &j); err != nil {
+			return nil, fmt.Errorf("failed to scan job: %w", err)
+		}
+		jobs = append(jobs, j)
+	}
+	return jobs, rows.Err()
+}
+
+// PruneCompleted deletes completed jobs that finished before the cutoff.
+func (r *JobRepo) PruneCompleted(ctx context.Context, before time.Time) (int64, error) {
+	ct, err := r.db.Exec(database.Privileged(ctx),
+		`DELETE FROM jobs WHERE status = 'completed' AND completed_at < $1`,
+		before,
+	)
+	if err != nil {
+		return 0, fmt.Errorf("failed to prune jobs: %w", err)
+	}
+	return ct.RowsAffected(), nil
+}
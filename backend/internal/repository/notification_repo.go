@@ -34,6 +34,27 @@ func (r *NotificationRepo) Create(ctx context.Context, n *models.Notification) e
 	return nil
 }
 
+// NotifyRoles sends the same notification to every user of a tenant with one
+// of the given roles.
+func (r *NotificationRepo) NotifyRoles(ctx context.Context, tenantID uuid.UUID, roles []string, title, message, kind string) error {
+	return notifyRoles(ctx, r.db, tenantID, roles, title, message, kind)
+}
+
+// notifyRoles is NotifyRoles on q, so the notifications can be sent in the
+// transaction that gives rise to them.
+func notifyRoles(ctx context.Context, q execer, tenantID uuid.UUID, roles []string, title, message, kind string) error {
+	_, err := q.Exec(ctx,
+		`INSERT INTO notifications (id, tenant_id, user_id, title, message, type, read, created_at)
+		 SELECT gen_random_uuid(), tenant_id, id, $2, $3, $4, FALSE, NOW()
+		 FROM users WHERE tenant_id = $1 AND role = ANY($5)`,
+		tenantID, title, message, kind, roles,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to create notifications: %w", err)
+	}
+	return nil
+}
+
 // List returns a paginated list of notifications for a user within a tenant.
 func (r *NotificationRepo) List(ctx context.Context, tenantID, userID uuid.UUID, page, perPage int) ([]models.Notification, int, error) {
 	var total int
@@ -4,10 +4,13 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"time"
 
+	"cargomax-api/internal/database"
 	"cargomax-api/internal/models"
 
 	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
@@ -24,28 +27,112 @@ func NewOrderRepo(db *pgxpool.Pool) *OrderRepo {
 	return &OrderRepo{db: db}
 }
 
-// Create inserts a new order.
+const orderColumns = `id, tenant_id, order_number, customer_name, customer_email, status, type, total_amount, shipment_id, scheduled_date, return_reason, cancellation_reason, client_id, quote, invoice_id, credit_note_id, order_schedule_id, schedule_failure, created_at, updated_at`
+
+func scanOrder(row pgx.Row) (*models.Order, error) {
+	o := &models.Order{}
+	err := row.Scan(&o.ID, &o.TenantID, &o.OrderNumber, &o.CustomerName, &o.CustomerEmail, &o.Status, &o.Type, &o.TotalAmount, &o.ShipmentID, &o.ScheduledDate, &o.ReturnReason, &o.CancellationReason, &o.ClientID, &o.Quote, &o.InvoiceID, &o.CreditNoteID, &o.OrderScheduleID, &o.ScheduleFailure, &o.CreatedAt, &o.UpdatedAt)
+	return o, err
+}
+
+// Create inserts a new order with its lines, reserving their stock unless
+// the order is scheduled. o.Lines are numbered and returned as stored.
 func (r *OrderRepo) Create(ctx context.Context, o *models.Order) error {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	if err := insertOrder(ctx, tx, o); err != nil {
+		return err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+func insertOrder(ctx context.Context, tx pgx.Tx, o *models.Order) error {
 	o.ID = uuid.New()
-	_, err := r.db.Exec(ctx,
-		`INSERT INTO orders (id, tenant_id, order_number, customer_name, customer_email, status, type, total_amount, shipment_id, scheduled_date, return_reason, cancellation_reason, created_at, updated_at)
-		 VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, NOW(), NOW())`,
-		o.ID, o.TenantID, o.OrderNumber, o.CustomerName, o.CustomerEmail, o.Status, o.Type, o.TotalAmount, o.ShipmentID, o.ScheduledDate, o.ReturnReason, o.CancellationReason,
+	if o.Status == "" {
+		o.Status = models.OrderPending
+	}
+	_, err := tx.Exec(ctx,
+		`INSERT INTO orders (id, tenant_id, order_number, customer_name, customer_email, status, type, total_amount, shipment_id, scheduled_date, return_reason, cancellation_reason, client_id, quote, order_schedule_id, created_at, updated_at)
+		 VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, NOW(), NOW())`,
+		o.ID, o.TenantID, o.OrderNumber, o.CustomerName, o.CustomerEmail, o.Status, o.Type, o.TotalAmount, o.ShipmentID, o.ScheduledDate, o.ReturnReason, o.CancellationReason, o.ClientID, o.Quote, o.OrderScheduleID,
 	)
 	if err != nil {
 		return fmt.Errorf("failed to create order: %w", err)
 	}
+	if len(o.Lines) == 0 {
+		return nil
+	}
+	if err := insertOrderLines(ctx, tx, o.TenantID, o.ID, o.Lines); err != nil {
+		return err
+	}
+	if err := applyOrderStock(ctx, tx, o.TenantID, o.ID, o.Status); err != nil {
+		return err
+	}
+	return loadOrderTotals(ctx, tx, o)
+}
+
+// insertOrderLines adds lines to an order, numbering them from one.
+func insertOrderLines(ctx context.Context, tx pgx.Tx, tenantID, orderID uuid.UUID, lines []models.OrderLine) error {
+	for i := range lines {
+		l := &lines[i]
+		l.ID = uuid.New()
+		l.TenantID = tenantID
+		l.OrderID = orderID
+		l.LineNumber = i + 1
+		l.ReservedQuantity, l.ShippedQuantity, l.ReturnedQuantity = 0, 0, 0
+		// The item must be the tenant's own; a scheduled order reserves
+		// nothing yet, so nothing else would catch a foreign one.
+		ct, err := tx.Exec(ctx,
+			`INSERT INTO order_lines (id, tenant_id, order_id, line_number, inventory_item_id, sku, warehouse_id, description, quantity, unit_price, line_total, created_at, updated_at)
+			 SELECT $1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, NOW(), NOW()
+			 WHERE $5::uuid IS NULL OR EXISTS (SELECT 1 FROM inventory_items WHERE id = $5 AND tenant_id = $2)`,
+			l.ID, tenantID, orderID, l.LineNumber, l.InventoryItemID, l.SKU, l.WarehouseID, l.Description, l.Quantity, l.UnitPrice, l.LineTotal,
+		)
+		if err != nil {
+			return fmt.Errorf("failed to create order line: %w", err)
+		}
+		if ct.RowsAffected() == 0 {
+			return fmt.Errorf("inventory item %s not found", l.SKU)
+		}
+	}
 	return nil
 }
 
+// loadOrderTotals sets an order with lines to cost the lines plus its
+// shipping quote, if any, and reloads o's total and lines.
+func loadOrderTotals(ctx context.Context, tx pgx.Tx, o *models.Order) error {
+	err := tx.QueryRow(ctx,
+		`UPDATE orders o SET total_amount = l.total + COALESCE((o.quote->>'total')::numeric, 0)
+		 FROM (SELECT SUM(line_total) AS total FROM order_lines WHERE tenant_id = $1 AND order_id = $2) l
+		 WHERE o.id = $2 AND o.tenant_id = $1 AND l.total IS NOT NULL
+		 RETURNING o.total_amount`,
+		o.TenantID, o.ID,
+	).Scan(&o.TotalAmount)
+	if errors.Is(err, pgx.ErrNoRows) {
+		o.Lines = nil
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to total order: %w", err)
+	}
+	o.Lines, err = orderLines(ctx, tx, o.TenantID, o.ID)
+	return err
+}
+
 // GetByID retrieves an order by ID within a tenant.
 func (r *OrderRepo) GetByID(ctx context.Context, tenantID, id uuid.UUID) (*models.Order, error) {
-	o := &models.Order{}
-	err := r.db.QueryRow(ctx,
-		`SELECT id, tenant_id, order_number, customer_name, customer_email, status, type, total_amount, shipment_id, scheduled_date, return_reason, cancellation_reason, created_at, updated_at
+	o, err := scanOrder(r.db.QueryRow(ctx,
+		`SELECT `+orderColumns+`
 		 FROM orders WHERE id = $1 AND tenant_id = $2`,
 		id, tenantID,
-	).Scan(&o.ID, &o.TenantID, &o.OrderNumber, &o.CustomerName, &o.CustomerEmail, &o.Status, &o.Type, &o.TotalAmount, &o.ShipmentID, &o.ScheduledDate, &o.ReturnReason, &o.CancellationReason, &o.CreatedAt, &o.UpdatedAt)
+	))
 	if err != nil {
 		return nil, fmt.Errorf("failed to get order by id: %w", err)
 	}
@@ -77,11 +164,11 @@ func (r *OrderRepo) List(ctx context.Context, tenantID uuid.UUID, status string,
 	var query string
 	var args []interface{}
 	if status != "" {
-		query = `SELECT id, tenant_id, order_number, customer_name, customer_email, status, type, total_amount, shipment_id, scheduled_date, return_reason, cancellation_reason, created_at, updated_at
+		query = `SELECT ` + orderColumns + `
 				 FROM orders WHERE tenant_id = $1 AND status = $2 ORDER BY created_at DESC LIMIT $3 OFFSET $4`
 		args = []interface{}{tenantID, status, perPage, offset}
 	} else {
-		query = `SELECT id, tenant_id, order_number, customer_name, customer_email, status, type, total_amount, shipment_id, scheduled_date, return_reason, cancellation_reason, created_at, updated_at
+		query = `SELECT ` + orderColumns + `
 				 FROM orders WHERE tenant_id = $1 ORDER BY created_at DESC LIMIT $2 OFFSET $3`
 		args = []interface{}{tenantID, perPage, offset}
 	}
@@ -94,11 +181,11 @@ func (r *OrderRepo) List(ctx context.Context, tenantID uuid.UUID, status string,
 
 	var orders []models.Order
 	for rows.Next() {
-		var o models.Order
-		if err := rows.Scan(&o.ID, &o.TenantID, &o.OrderNumber, &o.CustomerName, &o.CustomerEmail, &o.Status, &o.Type, &o.TotalAmount, &o.ShipmentID, &o.ScheduledDate, &o.ReturnReason, &o.CancellationReason, &o.CreatedAt, &o.UpdatedAt); err != nil {
+		o, err := scanOrder(rows)
+		if err != nil {
 			return nil, 0, fmt.Errorf("failed to scan order: %w", err)
 		}
-		orders = append(orders, o)
+		orders = append(orders, *o)
 	}
 	return orders, total, nil
 }
@@ -106,7 +193,7 @@ func (r *OrderRepo) List(ctx context.Context, tenantID uuid.UUID, status string,
 // GetScheduled returns orders with a scheduled_date in the future within a tenant.
 func (r *OrderRepo) GetScheduled(ctx context.Context, tenantID uuid.UUID) ([]models.Order, error) {
 	rows, err := r.db.Query(ctx,
-		`SELECT id, tenant_id, order_number, customer_name, customer_email, status, type, total_amount, shipment_id, scheduled_date, return_reason, cancellation_reason, created_at, updated_at
+		`SELECT `+orderColumns+`
 		 FROM orders WHERE tenant_id = $1 AND scheduled_date IS NOT NULL AND scheduled_date >= NOW() AND status NOT IN ('cancelled', 'returned')
 		 ORDER BY scheduled_date ASC`,
 		tenantID,
@@ -118,11 +205,11 @@ func (r *OrderRepo) GetScheduled(ctx context.Context, tenantID uuid.UUID) ([]mod
 
 	var orders []models.Order
 	for rows.Next() {
-		var o models.Order
-		if err := rows.Scan(&o.ID, &o.TenantID, &o.OrderNumber, &o.CustomerName, &o.CustomerEmail, &o.Status, &o.Type, &o.TotalAmount, &o.ShipmentID, &o.ScheduledDate, &o.ReturnReason, &o.CancellationReason, &o.CreatedAt, &o.UpdatedAt); err != nil {
+		o, err := scanOrder(rows)
+		if err != nil {
 			return nil, fmt.Errorf("failed to scan scheduled order: %w", err)
 		}
-		orders = append(orders, o)
+		orders = append(orders, *o)
 	}
 	return orders, nil
 }
@@ -130,7 +217,7 @@ func (r *OrderRepo) GetScheduled(ctx context.Context, tenantID uuid.UUID) ([]mod
 // GetReturns returns orders with status 'returned' within a tenant.
 func (r *OrderRepo) GetReturns(ctx context.Context, tenantID uuid.UUID) ([]models.Order, error) {
 	rows, err := r.db.Query(ctx,
-		`SELECT id, tenant_id, order_number, customer_name, customer_email, status, type, total_amount, shipment_id, scheduled_date, return_reason, cancellation_reason, created_at, updated_at
+		`SELECT `+orderColumns+`
 		 FROM orders WHERE tenant_id = $1 AND status = 'returned' ORDER BY updated_at DESC`,
 		tenantID,
 	)
@@ -141,19 +228,50 @@ func (r *OrderRepo) GetReturns(ctx context.Context, tenantID uuid.UUID) ([]model
 
 	var orders []models.Order
 	for rows.Next() {
-		var o models.Order
-		if err := rows.Scan(&o.ID, &o.TenantID, &o.OrderNumber, &o.CustomerName, &o.CustomerEmail, &o.Status, &o.Type, &o.TotalAmount, &o.ShipmentID, &o.ScheduledDate, &o.ReturnReason, &o.CancellationReason, &o.CreatedAt, &o.UpdatedAt); err != nil {
+		o, err := scanOrder(rows)
+		if err != nil {
 			return nil, fmt.Errorf("failed to scan returned order: %w", err)
 		}
-		orders = append(orders, o)
+		orders = append(orders, *o)
 	}
 	return orders, nil
 }
 
+// ListReturns returns a page of the orders with returns, by when they last
+// changed: those marked returned and those with an RMA. With rmaStatus, only
+// orders with an RMA in that status are listed.
+func (r *OrderRepo) ListReturns(ctx context.Context, tenantID uuid.UUID, rmaStatus string, page, perPage int) ([]models.Order, int, error) {
+	where := `tenant_id = $1 AND (($2 = '' AND status = 'returned')
+		 OR EXISTS (SELECT 1 FROM rmas WHERE rmas.order_id = orders.id AND rmas.tenant_id = $1 AND ($2 = '' OR rmas.status = $2)))`
+	var total int
+	if err := r.db.QueryRow(ctx, `SELECT COUNT(*) FROM orders WHERE `+where, tenantID, rmaStatus).Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("failed to count returned orders: %w", err)
+	}
+
+	rows, err := r.db.Query(ctx,
+		`SELECT `+orderColumns+` FROM orders WHERE `+where+` ORDER BY updated_at DESC, id LIMIT $3 OFFSET $4`,
+		tenantID, rmaStatus, perPage, (page-1)*perPage,
+	)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to get returned orders: %w", err)
+	}
+	defer rows.Close()
+
+	orders := []models.Order{}
+	for rows.Next() {
+		o, err := scanOrder(rows)
+		if err != nil {
+			return nil, 0, fmt.Errorf("failed to scan returned order: %w", err)
+		}
+		orders = append(orders, *o)
+	}
+	return orders, total, rows.Err()
+}
+
 // GetCancelled returns orders with status 'cancelled' within a tenant.
 func (r *OrderRepo) GetCancelled(ctx context.Context, tenantID uuid.UUID) ([]models.Order, error) {
 	rows, err := r.db.Query(ctx,
-		`SELECT id, tenant_id, order_number, customer_name, customer_email, status, type, total_amount, shipment_id, scheduled_date, return_reason, cancellation_reason, created_at, updated_at
+		`SELECT `+orderColumns+`
 		 FROM orders WHERE tenant_id = $1 AND status = 'cancelled' ORDER BY updated_at DESC`,
 		tenantID,
 	)
@@ -164,59 +282,261 @@ func (r *OrderRepo) GetCancelled(ctx context.Context, tenantID uuid.UUID) ([]mod
 
 	var orders []models.Order
 	for rows.Next() {
-		var o models.Order
-		if err := rows.Scan(&o.ID, &o.TenantID, &o.OrderNumber, &o.CustomerName, &o.CustomerEmail, &o.Status, &o.Type, &o.TotalAmount, &o.ShipmentID, &o.ScheduledDate, &o.ReturnReason, &o.CancellationReason, &o.CreatedAt, &o.UpdatedAt); err != nil {
+		o, err := scanOrder(rows)
+		if err != nil {
 			return nil, fmt.Errorf("failed to scan cancelled order: %w", err)
 		}
-		orders = append(orders, o)
+		orders = append(orders, *o)
 	}
 	return orders, nil
 }
 
-// Update modifies an existing order.
+// Update modifies an existing order and moves its stock with its status:
+// see applyOrderStock. When o.Lines is set they replace the order's lines,
+// which is only possible before it ships; otherwise the lines are kept. An
+// order with lines keeps its total worked out from them.
 func (r *OrderRepo) Update(ctx context.Context, tenantID, id uuid.UUID, o *models.Order) error {
-	ct, err := r.db.Exec(ctx,
-		`UPDATE orders SET order_number = $1, customer_name = $2, customer_email = $3, status = $4, type = $5, total_amount = $6, shipment_id = $7, scheduled_date = $8, return_reason = $9, cancellation_reason = $10, updated_at = NOW()
-		 WHERE id = $11 AND tenant_id = $12`,
-		o.OrderNumber, o.CustomerName, o.CustomerEmail, o.Status, o.Type, o.TotalAmount, o.ShipmentID, o.ScheduledDate, o.ReturnReason, o.CancellationReason, id, tenantID,
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	if err := updateOrder(ctx, tx, tenantID, id, o); err != nil {
+		return err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+// lockOrderStatus locks an order and returns its status.
+func lockOrderStatus(ctx context.Context, tx pgx.Tx, tenantID, id uuid.UUID) (string, error) {
+	var status string
+	err := tx.QueryRow(ctx,
+		`SELECT COALESCE(status, '') FROM orders WHERE id = $1 AND tenant_id = $2 FOR UPDATE`,
+		id, tenantID,
+	).Scan(&status)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return "", ErrOrderNotFound
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to lock order: %w", err)
+	}
+	return status, nil
+}
+
+func updateOrder(ctx context.Context, tx pgx.Tx, tenantID, id uuid.UUID, o *models.Order) error {
+	from, err := lockOrderStatus(ctx, tx, tenantID, id)
+	if err != nil {
+		return err
+	}
+	if o.Lines != nil {
+		if models.OrderHasShipped(from) || from == models.OrderCancelled || from == models.OrderReturned {
+			return fmt.Errorf("the lines of a %s order cannot be changed", from)
+		}
+		var partShipped bool
+		if err := tx.QueryRow(ctx,
+			`SELECT EXISTS (SELECT 1 FROM order_lines WHERE order_id = $1 AND tenant_id = $2 AND shipped_quantity > 0)`,
+			id, tenantID,
+		).Scan(&partShipped); err != nil {
+			return fmt.Errorf("failed to check order lines: %w", err)
+		}
+		if partShipped {
+			return fmt.Errorf("the lines of an order that has started shipping cannot be changed")
+		}
+		if err := cancelOpenFulfillments(ctx, tx, tenantID, id); err != nil {
+			return err
+		}
+		if err := releaseOrder(ctx, tx, tenantID, id); err != nil {
+			return err
+		}
+		if _, err := tx.Exec(ctx, `DELETE FROM order_lines WHERE order_id = $1 AND tenant_id = $2`, id, tenantID); err != nil {
+			return fmt.Errorf("failed to replace order lines: %w", err)
+		}
+		if err := insertOrderLines(ctx, tx, tenantID, id, o.Lines); err != nil {
+			return err
+		}
+	}
+	if o.Status == "" {
+		o.Status = from
+	}
+	if err := reopenCheck(ctx, tx, tenantID, id, from, o.Status); err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(ctx,
+		`UPDATE orders SET order_number = $1, customer_name = $2, customer_email = $3, status = $4, type = $5, total_amount = $6, shipment_id = $7, scheduled_date = $8, return_reason = $9, cancellation_reason = $10, client_id = $11, quote = $12, updated_at = NOW()
+		 WHERE id = $13 AND tenant_id = $14`,
+		o.OrderNumber, o.CustomerName, o.CustomerEmail, o.Status, o.Type, o.TotalAmount, o.ShipmentID, o.ScheduledDate, o.ReturnReason, o.CancellationReason, o.ClientID, o.Quote, id, tenantID,
 	)
 	if err != nil {
 		return fmt.Errorf("failed to update order: %w", err)
 	}
-	if ct.RowsAffected() == 0 {
-		return ErrOrderNotFound
+	if err := applyOrderStock(ctx, tx, tenantID, id, o.Status); err != nil {
+		return err
+	}
+	o.ID, o.TenantID = id, tenantID
+	return loadOrderTotals(ctx, tx, o)
+}
+
+// reopenCheck refuses to move an order with lines out of cancelled or
+// returned: its stock has been given back and is not taken again.
+func reopenCheck(ctx context.Context, tx pgx.Tx, tenantID, id uuid.UUID, from, to string) error {
+	if from == to || (from != models.OrderCancelled && from != models.OrderReturned) {
+		return nil
+	}
+	var hasLines bool
+	if err := tx.QueryRow(ctx,
+		`SELECT EXISTS (SELECT 1 FROM order_lines WHERE order_id = $1 AND tenant_id = $2)`,
+		id, tenantID,
+	).Scan(&hasLines); err != nil {
+		return fmt.Errorf("failed to check order lines: %w", err)
+	}
+	if hasLines {
+		return fmt.Errorf("a %s order cannot be reopened", from)
 	}
 	return nil
 }
 
-// CancelOrder sets an order's status to 'cancelled' with a reason.
-func (r *OrderRepo) CancelOrder(ctx context.Context, tenantID, id uuid.UUID, reason string) error {
-	ct, err := r.db.Exec(ctx,
-		`UPDATE orders SET status = 'cancelled', cancellation_reason = $1, updated_at = NOW() WHERE id = $2 AND tenant_id = $3`,
-		reason, id, tenantID,
-	)
+// setOrderStatus moves an order to status, setting column to reason, and
+// moves its stock with it.
+func (r *OrderRepo) setOrderStatus(ctx context.Context, tenantID, id uuid.UUID, status, column, reason string) error {
+	tx, err := r.db.Begin(ctx)
 	if err != nil {
-		return fmt.Errorf("failed to cancel order: %w", err)
+		return fmt.Errorf("failed to begin transaction: %w", err)
 	}
-	if ct.RowsAffected() == 0 {
-		return ErrOrderNotFound
+	defer tx.Rollback(ctx)
+
+	from, err := lockOrderStatus(ctx, tx, tenantID, id)
+	if err != nil {
+		return err
+	}
+	if err := reopenCheck(ctx, tx, tenantID, id, from, status); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(ctx,
+		`UPDATE orders SET status = $1, `+column+` = $2, updated_at = NOW() WHERE id = $3 AND tenant_id = $4`,
+		status, reason, id, tenantID,
+	); err != nil {
+		return fmt.Errorf("failed to set order status: %w", err)
+	}
+	if err := applyOrderStock(ctx, tx, tenantID, id, status); err != nil {
+		return err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
 	}
 	return nil
 }
 
-// ReturnOrder sets an order's status to 'returned' with a reason.
+// CancelOrder sets an order's status to 'cancelled' with a reason and
+// releases the stock it reserved. Stock that has shipped stays out until
+// the order is returned.
+func (r *OrderRepo) CancelOrder(ctx context.Context, tenantID, id uuid.UUID, reason string) error {
+	return r.setOrderStatus(ctx, tenantID, id, models.OrderCancelled, "cancellation_reason", reason)
+}
+
+// ReturnOrder sets an order's status to 'returned' with a reason, releasing
+// what it reserved and putting what it shipped back into stock.
 func (r *OrderRepo) ReturnOrder(ctx context.Context, tenantID, id uuid.UUID, reason string) error {
+	return r.setOrderStatus(ctx, tenantID, id, models.OrderReturned, "return_reason", reason)
+}
+
+// Confirm moves a scheduled order to pending, reserving its stock. It fails
+// with ErrInsufficientStock, leaving the order scheduled, when there is not
+// enough.
+func (r *OrderRepo) Confirm(ctx context.Context, tenantID, id uuid.UUID) error {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	from, err := lockOrderStatus(ctx, tx, tenantID, id)
+	if err != nil {
+		return err
+	}
+	if from != models.OrderScheduled {
+		return fmt.Errorf("only scheduled orders can be confirmed; this one is %s", from)
+	}
+	if _, err := tx.Exec(ctx,
+		`UPDATE orders SET status = $1, schedule_failure = NULL, updated_at = NOW() WHERE id = $2 AND tenant_id = $3`,
+		models.OrderPending, id, tenantID,
+	); err != nil {
+		return fmt.Errorf("failed to confirm order: %w", err)
+	}
+	if err := reserveOrder(ctx, tx, tenantID, id); err != nil {
+		return err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+// DueScheduled returns the scheduled orders across every tenant whose date
+// has come by now, oldest first. It runs on the privileged connection path,
+// bypassing row-level security.
+func (r *OrderRepo) DueScheduled(ctx context.Context, now time.Time) ([]models.Order, error) {
+	rows, err := r.db.Query(database.Privileged(ctx),
+		`SELECT `+orderColumns+`
+		 FROM orders WHERE status = 'scheduled' AND scheduled_date <= $1
+		 ORDER BY scheduled_date, created_at`,
+		now,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get due scheduled orders: %w", err)
+	}
+	defer rows.Close()
+
+	var orders []models.Order
+	for rows.Next() {
+		o, err := scanOrder(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan scheduled order: %w", err)
+		}
+		orders = append(orders, *o)
+	}
+	return orders, rows.Err()
+}
+
+// SetScheduleFailure records why a due scheduled order could not be
+// confirmed. It reports whether the reason changed, so a failure that
+// persists run after run is only reported once.
+func (r *OrderRepo) SetScheduleFailure(ctx context.Context, tenantID, id uuid.UUID, reason string) (bool, error) {
 	ct, err := r.db.Exec(ctx,
-		`UPDATE orders SET status = 'returned', return_reason = $1, updated_at = NOW() WHERE id = $2 AND tenant_id = $3`,
+		`UPDATE orders SET schedule_failure = $1, updated_at = NOW()
+		 WHERE id = $2 AND tenant_id = $3 AND status = 'scheduled' AND schedule_failure IS DISTINCT FROM $1`,
 		reason, id, tenantID,
 	)
 	if err != nil {
-		return fmt.Errorf("failed to return order: %w", err)
+		return false, fmt.Errorf("failed to record schedule failure: %w", err)
 	}
-	if ct.RowsAffected() == 0 {
-		return ErrOrderNotFound
+	return ct.RowsAffected() > 0, nil
+}
+
+// Lines returns an order's lines by line number.
+func (r *OrderRepo) Lines(ctx context.Context, tenantID, orderID uuid.UUID) ([]models.OrderLine, error) {
+	rows, err := r.db.Query(ctx,
+		`SELECT `+orderLineColumns+` FROM order_lines WHERE tenant_id = $1 AND order_id = $2 ORDER BY line_number`,
+		tenantID, orderID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get order lines: %w", err)
 	}
-	return nil
+	defer rows.Close()
+
+	lines := []models.OrderLine{}
+	for rows.Next() {
+		l, err := scanOrderLine(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan order line: %w", err)
+		}
+		lines = append(lines, *l)
+	}
+	return lines, rows.Err()
 }
 
 // Delete removes an order by ID within a tenant.
@@ -0,0 +1,312 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"cargomax-api/internal/database"
+	"cargomax-api/internal/models"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+var (
+	// ErrOrderScheduleNotFound is returned when a standing order does not
+	// exist within the tenant.
+	ErrOrderScheduleNotFound = errors.New("standing order not found")
+	// ErrOccurrenceSkipped is returned by Materialize when an occurrence's
+	// order could not be placed, because an item on it is gone, and was
+	// passed over.
+	ErrOccurrenceSkipped = errors.New("standing order occurrence skipped")
+)
+
+// OrderScheduleRepo handles standing orders and places their orders.
+type OrderScheduleRepo struct {
+	db *pgxpool.Pool
+}
+
+// NewOrderScheduleRepo creates a new OrderScheduleRepo instance.
+func NewOrderScheduleRepo(db *pgxpool.Pool) *OrderScheduleRepo {
+	return &OrderScheduleRepo{db: db}
+}
+
+const orderScheduleColumns = `id, tenant_id, name, order_prefix, client_id, customer_name, customer_email, order_type, lines, frequency, every, start_date, end_date, skip_dates, lead_days, next_date, auto_fulfill, active, created_by, created_at, updated_at`
+
+func scanOrderSchedule(row pgx.Row) (*models.OrderSchedule, error) {
+	s := &models.OrderSchedule{}
+	err := row.Scan(&s.ID, &s.TenantID, &s.Name, &s.OrderPrefix, &s.ClientID, &s.CustomerName, &s.CustomerEmail, &s.OrderType, &s.Lines, &s.Frequency, &s.Every, &s.StartDate, &s.EndDate, &s.SkipDates, &s.LeadDays, &s.NextDate, &s.AutoFulfill, &s.Active, &s.CreatedBy, &s.CreatedAt, &s.UpdatedAt)
+	return s, err
+}
+
+// orderScheduleError maps a clash of order prefixes to a readable error.
+func orderScheduleError(action string, err error) error {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+		return fmt.Errorf("another standing order already uses this order prefix")
+	}
+	return fmt.Errorf("failed to %s standing order: %w", action, err)
+}
+
+// setNextDate points s at its first occurrence from today on.
+func setNextDate(s *models.OrderSchedule, today time.Time) {
+	from := s.StartDate
+	if today.After(from) {
+		from = today
+	}
+	s.NextDate = nil
+	if d, ok := s.NextOccurrence(from); ok {
+		s.NextDate = &d
+	}
+}
+
+// Create inserts a standing order, due first on its first occurrence from
+// today on.
+func (r *OrderScheduleRepo) Create(ctx context.Context, s *models.OrderSchedule) error {
+	s.ID = uuid.New()
+	setNextDate(s, models.Day(time.Now()))
+	err := r.db.QueryRow(ctx,
+		`INSERT INTO order_schedules (id, tenant_id, name, order_prefix, client_id, customer_name, customer_email, order_type, lines, frequency, every, start_date, end_date, skip_dates, lead_days, next_date, auto_fulfill, active, created_by, created_at, updated_at)
+		 VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19, NOW(), NOW())
+		 RETURNING created_at, updated_at`,
+		s.ID, s.TenantID, s.Name, s.OrderPrefix, s.ClientID, s.CustomerName, s.CustomerEmail, s.OrderType, s.Lines, s.Frequency, s.Every, s.StartDate, s.EndDate, s.SkipDates, s.LeadDays, s.NextDate, s.AutoFulfill, s.Active, s.CreatedBy,
+	).Scan(&s.CreatedAt, &s.UpdatedAt)
+	if err != nil {
+		return orderScheduleError("create", err)
+	}
+	return nil
+}
+
+// GetByID retrieves a standing order within a tenant.
+func (r *OrderScheduleRepo) GetByID(ctx context.Context, tenantID, id uuid.UUID) (*models.OrderSchedule, error) {
+	s, err := scanOrderSchedule(r.db.QueryRow(ctx,
+		`SELECT `+orderScheduleColumns+` FROM order_schedules WHERE id = $1 AND tenant_id = $2`,
+		id, tenantID,
+	))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrOrderScheduleNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get standing order: %w", err)
+	}
+	return s, nil
+}
+
+// List returns the tenant's standing orders by name, optionally only one
+// client's.
+func (r *OrderScheduleRepo) List(ctx context.Context, tenantID uuid.UUID, clientID *uuid.UUID) ([]models.OrderSchedule, error) {
+	rows, err := r.db.Query(ctx,
+		`SELECT `+orderScheduleColumns+` FROM order_schedules
+		 WHERE tenant_id = $1 AND ($2::uuid IS NULL OR client_id = $2)
+		 ORDER BY name, id`,
+		tenantID, clientID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list standing orders: %w", err)
+	}
+	defer rows.Close()
+
+	out := []models.OrderSchedule{}
+	for rows.Next() {
+		s, err := scanOrderSchedule(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan standing order: %w", err)
+		}
+		out = append(out, *s)
+	}
+	return out, rows.Err()
+}
+
+// Update replaces a standing order's template and rule. Orders it has
+// already placed are left as they are; the next one is due on the new
+// rule's first occurrence from today on that has no order yet.
+func (r *OrderScheduleRepo) Update(ctx context.Context, tenantID, id uuid.UUID, s *models.OrderSchedule) error {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	var placed *time.Time
+	if err := tx.QueryRow(ctx,
+		`SELECT MAX(scheduled_date) FROM orders WHERE tenant_id = $1 AND order_schedule_id = $2`,
+		tenantID, id,
+	).Scan(&placed); err != nil {
+		return fmt.Errorf("failed to get placed orders: %w", err)
+	}
+	today := models.Day(time.Now())
+	if placed != nil && !models.Day(*placed).Before(today) {
+		today = models.Day(*placed).AddDate(0, 0, 1)
+	}
+	setNextDate(s, today)
+
+	ct, err := tx.Exec(ctx,
+		`UPDATE order_schedules SET name = $1, order_prefix = $2, client_id = $3, customer_name = $4, customer_email = $5, order_type = $6, lines = $7,
+		        frequency = $8, every = $9, start_date = $10, end_date = $11, skip_dates = $12, lead_days = $13, next_date = $14, auto_fulfill = $15, active = $16, updated_at = NOW()
+		 WHERE id = $17 AND tenant_id = $18`,
+		s.Name, s.OrderPrefix, s.ClientID, s.CustomerName, s.CustomerEmail, s.OrderType, s.Lines,
+		s.Frequency, s.Every, s.StartDate, s.EndDate, s.SkipDates, s.LeadDays, s.NextDate, s.AutoFulfill, s.Active, id, tenantID,
+	)
+	if err != nil {
+		return orderScheduleError("update", err)
+	}
+	if ct.RowsAffected() == 0 {
+		return ErrOrderScheduleNotFound
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+// Delete removes a standing order. Orders it placed are kept.
+func (r *OrderScheduleRepo) Delete(ctx context.Context, tenantID, id uuid.UUID) error {
+	ct, err := r.db.Exec(ctx,
+		`DELETE FROM order_schedules WHERE id = $1 AND tenant_id = $2`,
+		id, tenantID,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to delete standing order: %w", err)
+	}
+	if ct.RowsAffected() == 0 {
+		return ErrOrderScheduleNotFound
+	}
+	return nil
+}
+
+// DueSchedules returns the active standing orders across every tenant with
+// an order to place by today. It runs on the privileged connection path,
+// bypassing row-level security.
+func (r *OrderScheduleRepo) DueSchedules(ctx context.Context, today time.Time) ([]models.OrderSchedule, error) {
+	rows, err := r.db.Query(database.Privileged(ctx),
+		`SELECT `+orderScheduleColumns+` FROM order_schedules
+		 WHERE active AND next_date IS NOT NULL AND next_date - lead_days <= $1::date
+		 ORDER BY next_date, id`,
+		models.Day(today),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get due standing orders: %w", err)
+	}
+	defer rows.Close()
+
+	var out []models.OrderSchedule
+	for rows.Next() {
+		s, err := scanOrderSchedule(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan standing order: %w", err)
+		}
+		out = append(out, *s)
+	}
+	return out, rows.Err()
+}
+
+// Materialize places the order for a standing order's next occurrence if it
+// is due by today, as a scheduled order for that day priced at today's item
+// prices, and moves the schedule on to the occurrence after. It returns nil
+// when nothing is due. An occurrence whose order number is already taken,
+// e.g. by an earlier run, is passed over; one with an item that no longer
+// exists is passed over with ErrOccurrenceSkipped.
+func (r *OrderScheduleRepo) Materialize(ctx context.Context, tenantID, id uuid.UUID, today time.Time) (*models.Order, error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	s, err := scanOrderSchedule(tx.QueryRow(ctx,
+		`SELECT `+orderScheduleColumns+` FROM order_schedules WHERE id = $1 AND tenant_id = $2 FOR UPDATE`,
+		id, tenantID,
+	))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrOrderScheduleNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to lock standing order: %w", err)
+	}
+	if !s.Active || s.NextDate == nil || s.NextDate.AddDate(0, 0, -s.LeadDays).After(models.Day(today)) {
+		return nil, nil
+	}
+	day := models.Day(*s.NextDate)
+
+	o := &models.Order{
+		TenantID:        tenantID,
+		OrderNumber:     s.OrderPrefix + "-" + day.Format("20060102"),
+		CustomerName:    s.CustomerName,
+		CustomerEmail:   s.CustomerEmail,
+		Status:          models.OrderScheduled,
+		Type:            s.OrderType,
+		ScheduledDate:   &day,
+		ClientID:        s.ClientID,
+		OrderScheduleID: &s.ID,
+	}
+	var taken bool
+	if err := tx.QueryRow(ctx,
+		`SELECT EXISTS (SELECT 1 FROM orders WHERE tenant_id = $1 AND order_number = $2)`,
+		tenantID, o.OrderNumber,
+	).Scan(&taken); err != nil {
+		return nil, fmt.Errorf("failed to check order number: %w", err)
+	}
+
+	var skipped error
+	if !taken {
+		o.Lines, skipped = scheduleOrderLines(ctx, tx, tenantID, s.Lines)
+		if skipped == nil {
+			if err := insertOrder(ctx, tx, o); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	s.NextDate = nil
+	if next, ok := s.NextOccurrence(day.AddDate(0, 0, 1)); ok {
+		s.NextDate = &next
+	}
+	if _, err := tx.Exec(ctx,
+		`UPDATE order_schedules SET next_date = $1, updated_at = NOW() WHERE id = $2 AND tenant_id = $3`,
+		s.NextDate, id, tenantID,
+	); err != nil {
+		return nil, fmt.Errorf("failed to advance standing order: %w", err)
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	if skipped != nil {
+		return nil, fmt.Errorf("%w: %s on %s: %v", ErrOccurrenceSkipped, s.Name, day.Format(models.ScheduleDateLayout), skipped)
+	}
+	if taken {
+		return r.Materialize(ctx, tenantID, id, today)
+	}
+	return o, nil
+}
+
+// scheduleOrderLines builds order lines from a standing order's template at
+// the items' current prices and names.
+func scheduleOrderLines(ctx context.Context, tx pgx.Tx, tenantID uuid.UUID, tmpl []models.ScheduleLine) ([]models.OrderLine, error) {
+	lines := make([]models.OrderLine, 0, len(tmpl))
+	for _, t := range tmpl {
+		item, err := scanInventoryItem(tx.QueryRow(ctx,
+			`SELECT `+inventoryColumns+` FROM `+inventoryFrom+` WHERE i.id = $1 AND i.tenant_id = $2`,
+			t.InventoryItemID, tenantID,
+		))
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, fmt.Errorf("inventory item %s no longer exists", t.SKU)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to get inventory item: %w", err)
+		}
+		l := models.OrderLine{InventoryItemID: &item.ID, SKU: item.SKU, WarehouseID: &item.WarehouseID, Description: item.Name, Quantity: t.Quantity}
+		if item.UnitPrice != nil {
+			l.UnitPrice = *item.UnitPrice
+		}
+		if t.UnitPrice != nil {
+			l.UnitPrice = *t.UnitPrice
+		}
+		l.LineTotal = models.RoundMoney(float64(l.Quantity) * l.UnitPrice)
+		lines = append(lines, l)
+	}
+	return lines, nil
+}
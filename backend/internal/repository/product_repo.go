@@ -0,0 +1,243 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"cargomax-api/internal/models"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// ErrProductNotFound is returned when a product does not exist in the
+// tenant's catalog.
+var ErrProductNotFound = errors.New("product not found")
+
+// ProductRepo handles the product catalog. Stock levels of a product are
+// inventory items.
+type ProductRepo struct {
+	db *pgxpool.Pool
+}
+
+// NewProductRepo creates a new ProductRepo.
+func NewProductRepo(db *pgxpool.Pool) *ProductRepo {
+	return &ProductRepo{db: db}
+}
+
+// productColumns adds up a product's stock over its warehouses.
+const productColumns = `p.id, p.tenant_id, p.sku, p.name, p.category, p.unit_price, p.weight, p.volume, p.units_per_pallet,
+	COALESCE((SELECT SUM(i.quantity) FROM inventory_items i WHERE i.product_id = p.id), 0)::int,
+	COALESCE((SELECT SUM(i.reserved) FROM inventory_items i WHERE i.product_id = p.id), 0)::int,
+	COALESCE((SELECT SUM(i.in_transit) FROM inventory_items i WHERE i.product_id = p.id), 0)::int,
+	p.created_at, p.updated_at`
+
+func scanProduct(row pgx.Row) (*models.Product, error) {
+	p := &models.Product{}
+	err := row.Scan(&p.ID, &p.TenantID, &p.SKU, &p.Name, &p.Category, &p.UnitPrice, &p.Weight, &p.Volume, &p.UnitsPerPallet, &p.Quantity, &p.Reserved, &p.InTransit, &p.CreatedAt, &p.UpdatedAt)
+	return p, err
+}
+
+// skuError maps a clash on the catalog's unique SKU to a readable error.
+func skuError(action, sku string, err error) error {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == "23505" && pgErr.ConstraintName == "products_tenant_id_sku_key" {
+		return fmt.Errorf("SKU %s is already in the catalog", sku)
+	}
+	return fmt.Errorf("failed to %s: %w", action, err)
+}
+
+// Create adds a product to the catalog.
+func (r *ProductRepo) Create(ctx context.Context, p *models.Product) error {
+	p.ID = uuid.New()
+	err := r.db.QueryRow(ctx,
+		`INSERT INTO products (id, tenant_id, sku, name, category, unit_price, weight, volume, units_per_pallet, created_at, updated_at)
+		 VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, NOW(), NOW())
+		 RETURNING created_at, updated_at`,
+		p.ID, p.TenantID, p.SKU, p.Name, p.Category, p.UnitPrice, p.Weight, p.Volume, p.UnitsPerPallet,
+	).Scan(&p.CreatedAt, &p.UpdatedAt)
+	if err != nil {
+		return skuError("create product", p.SKU, err)
+	}
+	return nil
+}
+
+// GetByID retrieves a product by ID within a tenant.
+func (r *ProductRepo) GetByID(ctx context.Context, tenantID, id uuid.UUID) (*models.Product, error) {
+	return r.get(ctx, `p.id = $1 AND p.tenant_id = $2`, id, tenantID)
+}
+
+// GetBySKU retrieves a product by SKU within a tenant.
+func (r *ProductRepo) GetBySKU(ctx context.Context, tenantID uuid.UUID, sku string) (*models.Product, error) {
+	return r.get(ctx, `p.sku = $1 AND p.tenant_id = $2`, sku, tenantID)
+}
+
+func (r *ProductRepo) get(ctx context.Context, where string, args ...any) (*models.Product, error) {
+	p, err := scanProduct(r.db.QueryRow(ctx, `SELECT `+productColumns+` FROM products p WHERE `+where, args...))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrProductNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get product: %w", err)
+	}
+	return p, nil
+}
+
+// List returns a page of the tenant's catalog by name, optionally narrowed to
+// a category and to products whose SKU or name contains search, with the
+// total count.
+func (r *ProductRepo) List(ctx context.Context, tenantID uuid.UUID, search, category string, page, perPage int) ([]models.Product, int, error) {
+	where := `p.tenant_id = $1 AND ($2 = '' OR p.sku ILIKE '%' || $2 || '%' OR p.name ILIKE '%' || $2 || '%') AND ($3 = '' OR p.category = $3)`
+	var total int
+	if err := r.db.QueryRow(ctx, `SELECT COUNT(*) FROM products p WHERE `+where, tenantID, search, category).Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("failed to count products: %w", err)
+	}
+	rows, err := r.db.Query(ctx,
+		`SELECT `+productColumns+` FROM products p WHERE `+where+`
+		 ORDER BY p.name, p.sku LIMIT $4 OFFSET $5`,
+		tenantID, search, category, perPage, (page-1)*perPage,
+	)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to list products: %w", err)
+	}
+	defer rows.Close()
+
+	out := []models.Product{}
+	for rows.Next() {
+		p, err := scanProduct(rows)
+		if err != nil {
+			return nil, 0, fmt.Errorf("failed to scan product: %w", err)
+		}
+		out = append(out, *p)
+	}
+	return out, total, rows.Err()
+}
+
+// Update changes a product. A new SKU is copied to its stock levels, and the
+// used capacity of the warehouses holding it is worked out again for its
+// measures.
+func (r *ProductRepo) Update(ctx context.Context, tenantID, id uuid.UUID, p *models.Product) error {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	p.ID, p.TenantID = id, tenantID
+	if err := updateProduct(ctx, tx, p); err != nil {
+		return err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+func updateProduct(ctx context.Context, tx pgx.Tx, p *models.Product) error {
+	ct, err := tx.Exec(ctx,
+		`UPDATE products SET sku = $1, name = $2, category = $3, unit_price = $4, weight = $5, volume = $6, units_per_pallet = $7, updated_at = NOW()
+		 WHERE id = $8 AND tenant_id = $9`,
+		p.SKU, p.Name, p.Category, p.UnitPrice, p.Weight, p.Volume, p.UnitsPerPallet, p.ID, p.TenantID,
+	)
+	if err != nil {
+		return skuError("update product", p.SKU, err)
+	}
+	if ct.RowsAffected() == 0 {
+		return ErrProductNotFound
+	}
+	if _, err := tx.Exec(ctx,
+		`UPDATE inventory_items SET sku = $1, updated_at = NOW() WHERE product_id = $2 AND tenant_id = $3 AND sku <> $1`,
+		p.SKU, p.ID, p.TenantID,
+	); err != nil {
+		return fmt.Errorf("failed to update stock levels: %w", err)
+	}
+	warehouseIDs, err := productWarehouses(ctx, tx, p.TenantID, p.ID)
+	if err != nil {
+		return err
+	}
+	return refreshUsedCapacity(ctx, tx, p.TenantID, warehouseIDs)
+}
+
+// productWarehouses returns the warehouses that hold a product.
+func productWarehouses(ctx context.Context, q rowsQuerier, tenantID, productID uuid.UUID) ([]uuid.UUID, error) {
+	rows, err := q.Query(ctx,
+		`SELECT warehouse_id FROM inventory_items WHERE tenant_id = $1 AND product_id = $2 ORDER BY warehouse_id`,
+		tenantID, productID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get stock levels: %w", err)
+	}
+	defer rows.Close()
+
+	var out []uuid.UUID
+	for rows.Next() {
+		var id uuid.UUID
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("failed to scan stock level: %w", err)
+		}
+		out = append(out, id)
+	}
+	return out, rows.Err()
+}
+
+// Delete removes a product from the catalog along with its stock levels,
+// freeing the space they took up. A product on a transfer or purchase order
+// cannot be deleted.
+func (r *ProductRepo) Delete(ctx context.Context, tenantID, id uuid.UUID) error {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	warehouseIDs, err := productWarehouses(ctx, tx, tenantID, id)
+	if err != nil {
+		return err
+	}
+	ct, err := tx.Exec(ctx, `DELETE FROM products WHERE id = $1 AND tenant_id = $2`, id, tenantID)
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == "23503" {
+		return fmt.Errorf("the product is on a transfer or purchase order and cannot be deleted")
+	}
+	if err != nil {
+		return fmt.Errorf("failed to delete product: %w", err)
+	}
+	if ct.RowsAffected() == 0 {
+		return ErrProductNotFound
+	}
+	if err := refreshUsedCapacity(ctx, tx, tenantID, warehouseIDs); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+// catalogProduct returns the id of the tenant's product with i's SKU,
+// adding it to the catalog first when there is none. The name, category,
+// price and weight i gives replace the product's; those it leaves out keep
+// theirs.
+func catalogProduct(ctx context.Context, tx pgx.Tx, i *models.InventoryItem) (uuid.UUID, error) {
+	var id uuid.UUID
+	err := tx.QueryRow(ctx,
+		`INSERT INTO products (tenant_id, sku, name, category, unit_price, weight, created_at, updated_at)
+		 VALUES ($1, $2, $3, $4, $5, $6, NOW(), NOW())
+		 ON CONFLICT (tenant_id, sku) DO UPDATE SET
+			name = COALESCE(EXCLUDED.name, products.name),
+			category = COALESCE(EXCLUDED.category, products.category),
+			unit_price = COALESCE(EXCLUDED.unit_price, products.unit_price),
+			weight = COALESCE(EXCLUDED.weight, products.weight),
+			updated_at = NOW()
+		 RETURNING id`,
+		i.TenantID, i.SKU, i.Name, i.Category, i.UnitPrice, i.Weight,
+	).Scan(&id)
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("failed to add product to the catalog: %w", err)
+	}
+	return id, nil
+}
@@ -0,0 +1,618 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"cargomax-api/internal/database"
+	"cargomax-api/internal/models"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// ErrPurchaseOrderNotFound is returned when a purchase order does not exist
+// in the tenant.
+var ErrPurchaseOrderNotFound = errors.New("purchase order not found")
+
+// PurchaseOrderRepo handles purchase orders to vendors and the reorder job
+// that drafts them.
+type PurchaseOrderRepo struct {
+	db *pgxpool.Pool
+}
+
+// NewPurchaseOrderRepo creates a new PurchaseOrderRepo.
+func NewPurchaseOrderRepo(db *pgxpool.Pool) *PurchaseOrderRepo {
+	return &PurchaseOrderRepo{db: db}
+}
+
+const purchaseOrderColumns = `id, tenant_id, po_number, vendor_id, warehouse_id, status, source, expected_date, notes, created_by, sent_at, received_at, created_at, updated_at`
+
+func scanPurchaseOrder(row pgx.Row) (*models.PurchaseOrder, error) {
+	po := &models.PurchaseOrder{}
+	err := row.Scan(&po.ID, &po.TenantID, &po.PONumber, &po.VendorID, &po.WarehouseID, &po.Status, &po.Source, &po.ExpectedDate, &po.Notes, &po.CreatedBy, &po.SentAt, &po.ReceivedAt, &po.CreatedAt, &po.UpdatedAt)
+	return po, err
+}
+
+// purchaseStockRef refers stock movements to a purchase order.
+func purchaseStockRef(po *models.PurchaseOrder) models.StockRef {
+	return models.StockRef{Type: "purchase_order", ID: &po.ID, Number: po.PONumber}
+}
+
+// Create drafts a purchase order of quantities of SKUs from po's vendor into
+// its warehouse. A line without a unit cost takes the vendor's catalog
+// cost, and a line the vendor has in its catalog must be at least its
+// minimum order quantity.
+func (r *PurchaseOrderRepo) Create(ctx context.Context, po *models.PurchaseOrder, reqs []models.PurchaseQuantity) (*models.PurchaseOrder, error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	if err := checkPurchaseParties(ctx, tx, po); err != nil {
+		return nil, err
+	}
+	if po.Lines, err = purchaseLines(ctx, tx, po, reqs); err != nil {
+		return nil, err
+	}
+	po.Status, po.Source = models.PurchaseDraft, models.PurchaseManual
+	if err := insertPurchaseOrder(ctx, tx, po); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return r.GetByID(ctx, po.TenantID, po.ID)
+}
+
+// UpdateDraft replaces a draft purchase order's vendor, warehouse, expected
+// date, notes and lines.
+func (r *PurchaseOrderRepo) UpdateDraft(ctx context.Context, tenantID, id uuid.UUID, po *models.PurchaseOrder, reqs []models.PurchaseQuantity) (*models.PurchaseOrder, error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := lockPurchaseOrder(ctx, tx, tenantID, id, models.PurchaseDraft); err != nil {
+		return nil, err
+	}
+	po.ID, po.TenantID = id, tenantID
+	if err := checkPurchaseParties(ctx, tx, po); err != nil {
+		return nil, err
+	}
+	if po.Lines, err = purchaseLines(ctx, tx, po, reqs); err != nil {
+		return nil, err
+	}
+	if _, err := tx.Exec(ctx,
+		`UPDATE purchase_orders SET vendor_id = $1, warehouse_id = $2, expected_date = $3, notes = $4, updated_at = NOW()
+		 WHERE id = $5 AND tenant_id = $6`,
+		po.VendorID, po.WarehouseID, po.ExpectedDate, po.Notes, id, tenantID,
+	); err != nil {
+		return nil, fmt.Errorf("failed to update purchase order: %w", err)
+	}
+	if _, err := tx.Exec(ctx, `DELETE FROM purchase_order_lines WHERE purchase_order_id = $1 AND tenant_id = $2`, id, tenantID); err != nil {
+		return nil, fmt.Errorf("failed to update purchase order lines: %w", err)
+	}
+	if err := insertPurchaseLines(ctx, tx, po); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return r.GetByID(ctx, tenantID, id)
+}
+
+// checkPurchaseParties checks that a purchase order's vendor and warehouse
+// belong to its tenant.
+func checkPurchaseParties(ctx context.Context, q rowQuerier, po *models.PurchaseOrder) error {
+	var vendors, warehouses int
+	if err := q.QueryRow(ctx,
+		`SELECT (SELECT COUNT(*) FROM vendors WHERE id = $2 AND tenant_id = $1),
+			(SELECT COUNT(*) FROM warehouses WHERE id = $3 AND tenant_id = $1)`,
+		po.TenantID, po.VendorID, po.WarehouseID,
+	).Scan(&vendors, &warehouses); err != nil {
+		return fmt.Errorf("failed to get vendor: %w", err)
+	}
+	if vendors == 0 {
+		return fmt.Errorf("vendor not found in tenant")
+	}
+	if warehouses == 0 {
+		return fmt.Errorf("warehouse not found in tenant")
+	}
+	return nil
+}
+
+// purchaseLines resolves the SKUs of a purchase order's lines to products
+// and prices them from the vendor's catalog where no cost is given.
+func purchaseLines(ctx context.Context, tx pgx.Tx, po *models.PurchaseOrder, reqs []models.PurchaseQuantity) ([]models.PurchaseOrderLine, error) {
+	if len(reqs) == 0 {
+		return nil, fmt.Errorf("a purchase order needs lines to order")
+	}
+	lines := make([]models.PurchaseOrderLine, 0, len(reqs))
+	seen := map[string]bool{}
+	for _, req := range reqs {
+		if seen[req.SKU] {
+			return nil, fmt.Errorf("%s is listed twice", req.SKU)
+		}
+		seen[req.SKU] = true
+		if req.Quantity <= 0 {
+			return nil, fmt.Errorf("the quantity of %s must be positive", req.SKU)
+		}
+		if req.UnitCost != nil && *req.UnitCost < 0 {
+			return nil, fmt.Errorf("the unit cost of %s cannot be negative", req.SKU)
+		}
+		l := models.PurchaseOrderLine{SKU: req.SKU, Quantity: req.Quantity}
+		var cost *float64
+		var minOrder *int
+		err := tx.QueryRow(ctx,
+			`SELECT p.id, s.vendor_sku, s.unit_cost, s.min_order_quantity
+			 FROM products p LEFT JOIN supplier_products s ON s.product_id = p.id AND s.vendor_id = $3
+			 WHERE p.tenant_id = $1 AND p.sku = $2`,
+			po.TenantID, req.SKU, po.VendorID,
+		).Scan(&l.ProductID, &l.VendorSKU, &cost, &minOrder)
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, fmt.Errorf("%s is not in the catalog", req.SKU)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to get product: %w", err)
+		}
+		if minOrder != nil && req.Quantity < *minOrder {
+			return nil, fmt.Errorf("the vendor sells %s in no fewer than %d", req.SKU, *minOrder)
+		}
+		switch {
+		case req.UnitCost != nil:
+			l.UnitCost = *req.UnitCost
+		case cost != nil:
+			l.UnitCost = *cost
+		default:
+			return nil, fmt.Errorf("%s is not in the vendor's catalog; give its unit cost", req.SKU)
+		}
+		lines = append(lines, l)
+	}
+	return lines, nil
+}
+
+// insertPurchaseOrder numbers and inserts a purchase order with its lines.
+func insertPurchaseOrder(ctx context.Context, tx pgx.Tx, po *models.PurchaseOrder) error {
+	seq, err := nextSequence(ctx, tx, po.TenantID, models.SequencePurchaseOrder)
+	if err != nil {
+		return err
+	}
+	po.ID = uuid.New()
+	po.PONumber = models.FormatInvoiceNumber(models.PurchaseOrderPrefix, seq)
+	if _, err := tx.Exec(ctx,
+		`INSERT INTO purchase_orders (id, tenant_id, po_number, vendor_id, warehouse_id, status, source, expected_date, notes, created_by, created_at, updated_at)
+		 VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, NOW(), NOW())`,
+		po.ID, po.TenantID, po.PONumber, po.VendorID, po.WarehouseID, po.Status, po.Source, po.ExpectedDate, po.Notes, po.CreatedBy,
+	); err != nil {
+		return fmt.Errorf("failed to create purchase order: %w", err)
+	}
+	return insertPurchaseLines(ctx, tx, po)
+}
+
+func insertPurchaseLines(ctx context.Context, tx pgx.Tx, po *models.PurchaseOrder) error {
+	for _, l := range po.Lines {
+		if _, err := tx.Exec(ctx,
+			`INSERT INTO purchase_order_lines (id, tenant_id, purchase_order_id, product_id, sku, vendor_sku, quantity, unit_cost)
+			 VALUES ($1, $2, $3, $4, $5, $6, $7, $8)`,
+			uuid.New(), po.TenantID, po.ID, l.ProductID, l.SKU, l.VendorSKU, l.Quantity, l.UnitCost,
+		); err != nil {
+			return fmt.Errorf("failed to create purchase order line: %w", err)
+		}
+	}
+	return nil
+}
+
+// lockPurchaseOrder locks a purchase order, checking it is in one of
+// statuses.
+func lockPurchaseOrder(ctx context.Context, tx pgx.Tx, tenantID, id uuid.UUID, statuses ...string) (*models.PurchaseOrder, error) {
+	po, err := scanPurchaseOrder(tx.QueryRow(ctx,
+		`SELECT `+purchaseOrderColumns+` FROM purchase_orders WHERE id = $1 AND tenant_id = $2 FOR UPDATE`,
+		id, tenantID,
+	))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrPurchaseOrderNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to lock purchase order: %w", err)
+	}
+	lines, err := purchaseOrderLines(ctx, tx, tenantID, []uuid.UUID{id})
+	if err != nil {
+		return nil, err
+	}
+	po.Lines = lines[id]
+	for _, s := range statuses {
+		if po.Status == s {
+			return po, nil
+		}
+	}
+	return nil, fmt.Errorf("%s is %s", po.PONumber, po.Status)
+}
+
+// Send marks a draft as sent to the vendor. Without an expected date it is
+// expected after the longest lead time of its lines in the vendor's
+// catalog.
+func (r *PurchaseOrderRepo) Send(ctx context.Context, tenantID, id uuid.UUID) (*models.PurchaseOrder, error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := lockPurchaseOrder(ctx, tx, tenantID, id, models.PurchaseDraft); err != nil {
+		return nil, err
+	}
+	if _, err := tx.Exec(ctx,
+		`UPDATE purchase_orders po SET status = $1, sent_at = NOW(), updated_at = NOW(),
+			expected_date = COALESCE(po.expected_date, CURRENT_DATE + (
+				SELECT COALESCE(MAX(s.lead_time_days), 0) FROM purchase_order_lines l
+				JOIN supplier_products s ON s.product_id = l.product_id AND s.vendor_id = po.vendor_id
+				WHERE l.purchase_order_id = po.id))
+		 WHERE po.id = $2 AND po.tenant_id = $3`,
+		models.PurchaseSent, id, tenantID,
+	); err != nil {
+		return nil, fmt.Errorf("failed to send purchase order: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return r.GetByID(ctx, tenantID, id)
+}
+
+// Receive books a delivery against a sent purchase order into its
+// warehouse, as receipts. received gives what arrived of some SKUs; when it
+// is nil everything outstanding arrived. The order is received once every
+// line has arrived in full and partially received until then.
+func (r *PurchaseOrderRepo) Receive(ctx context.Context, tenantID, id uuid.UUID, received []models.PurchaseQuantity) (*models.PurchaseOrder, error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	po, err := lockPurchaseOrder(ctx, tx, tenantID, id, models.PurchaseSent, models.PurchasePartiallyReceived)
+	if err != nil {
+		return nil, err
+	}
+	if received != nil && len(received) == 0 {
+		return nil, fmt.Errorf("nothing was received")
+	}
+	arrived := map[string]int{}
+	if received == nil {
+		for _, l := range po.Lines {
+			arrived[l.SKU] = l.Quantity - l.ReceivedQuantity
+		}
+	}
+	outstanding := map[string]int{}
+	for _, l := range po.Lines {
+		outstanding[l.SKU] = l.Quantity - l.ReceivedQuantity
+	}
+	for _, rcv := range received {
+		left, ok := outstanding[rcv.SKU]
+		if !ok {
+			return nil, fmt.Errorf("%s is not on %s", rcv.SKU, po.PONumber)
+		}
+		if _, dup := arrived[rcv.SKU]; dup {
+			return nil, fmt.Errorf("%s is listed twice", rcv.SKU)
+		}
+		if rcv.Quantity <= 0 || rcv.Quantity > left {
+			return nil, fmt.Errorf("receive from 1 to the %d outstanding of %s", left, rcv.SKU)
+		}
+		arrived[rcv.SKU] = rcv.Quantity
+	}
+
+	ref := purchaseStockRef(po)
+	complete := true
+	for _, l := range po.Lines {
+		n := arrived[l.SKU]
+		if l.ReceivedQuantity+n < l.Quantity {
+			complete = false
+		}
+		if n == 0 {
+			continue
+		}
+		// The warehouse may not stock the product yet; it is opened.
+		var itemID uuid.UUID
+		if err := tx.QueryRow(ctx,
+			`INSERT INTO inventory_items (tenant_id, product_id, warehouse_id, sku, quantity, min_quantity, status, created_at, updated_at)
+			 VALUES ($1, $2, $3, $4, 0, 0, 'low_stock', NOW(), NOW())
+			 ON CONFLICT (product_id, warehouse_id) DO UPDATE SET updated_at = NOW()
+			 RETURNING id`,
+			tenantID, l.ProductID, po.WarehouseID, l.SKU,
+		).Scan(&itemID); err != nil {
+			return nil, fmt.Errorf("failed to get stock level: %w", err)
+		}
+		if err := moveStock(ctx, tx, tenantID, itemID, models.MovementReceipt, n, ref); err != nil {
+			return nil, err
+		}
+		if _, err := tx.Exec(ctx,
+			`UPDATE purchase_order_lines SET received_quantity = received_quantity + $1 WHERE id = $2 AND tenant_id = $3`,
+			n, l.ID, tenantID,
+		); err != nil {
+			return nil, fmt.Errorf("failed to receive purchase order line: %w", err)
+		}
+	}
+	status, set := models.PurchasePartiallyReceived, ""
+	if complete {
+		status, set = models.PurchaseReceived, "received_at = NOW()"
+	}
+	if err := setPurchaseStatus(ctx, tx, tenantID, id, status, set); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return r.GetByID(ctx, tenantID, id)
+}
+
+// Cancel withdraws a purchase order that has not been received in full. A
+// partially received order is closed short: what arrived stays in stock.
+func (r *PurchaseOrderRepo) Cancel(ctx context.Context, tenantID, id uuid.UUID) (*models.PurchaseOrder, error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := lockPurchaseOrder(ctx, tx, tenantID, id, models.PurchaseDraft, models.PurchaseSent, models.PurchasePartiallyReceived); err != nil {
+		return nil, err
+	}
+	if err := setPurchaseStatus(ctx, tx, tenantID, id, models.PurchaseCancelled, ""); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return r.GetByID(ctx, tenantID, id)
+}
+
+// setPurchaseStatus moves a purchase order to status, also running the
+// given SET clause if there is one.
+func setPurchaseStatus(ctx context.Context, tx pgx.Tx, tenantID, id uuid.UUID, status, set string) error {
+	if set != "" {
+		set += ", "
+	}
+	if _, err := tx.Exec(ctx,
+		`UPDATE purchase_orders SET status = $1, `+set+`updated_at = NOW() WHERE id = $2 AND tenant_id = $3`,
+		status, id, tenantID,
+	); err != nil {
+		return fmt.Errorf("failed to update purchase order: %w", err)
+	}
+	return nil
+}
+
+// GetByID retrieves a purchase order with its lines.
+func (r *PurchaseOrderRepo) GetByID(ctx context.Context, tenantID, id uuid.UUID) (*models.PurchaseOrder, error) {
+	po, err := scanPurchaseOrder(r.db.QueryRow(ctx,
+		`SELECT `+purchaseOrderColumns+` FROM purchase_orders WHERE id = $1 AND tenant_id = $2`,
+		id, tenantID,
+	))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrPurchaseOrderNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get purchase order: %w", err)
+	}
+	lines, err := purchaseOrderLines(ctx, r.db, tenantID, []uuid.UUID{id})
+	if err != nil {
+		return nil, err
+	}
+	po.Lines = lines[id]
+	return po, nil
+}
+
+// PurchaseOrderFilter narrows a purchase order listing. Zero fields match
+// everything.
+type PurchaseOrderFilter struct {
+	Status      string
+	Source      string
+	VendorID    *uuid.UUID
+	WarehouseID *uuid.UUID
+}
+
+// List returns a page of the tenant's purchase orders, newest first, with
+// the total count.
+func (r *PurchaseOrderRepo) List(ctx context.Context, tenantID uuid.UUID, f PurchaseOrderFilter, page, perPage int) ([]models.PurchaseOrder, int, error) {
+	where := `tenant_id = $1 AND ($2 = '' OR status = $2) AND ($3 = '' OR source = $3)
+		AND ($4::uuid IS NULL OR vendor_id = $4) AND ($5::uuid IS NULL OR warehouse_id = $5)`
+	args := []any{tenantID, f.Status, f.Source, f.VendorID, f.WarehouseID}
+	var total int
+	if err := r.db.QueryRow(ctx, `SELECT COUNT(*) FROM purchase_orders WHERE `+where, args...).Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("failed to count purchase orders: %w", err)
+	}
+	return r.list(ctx, tenantID, total,
+		`SELECT `+purchaseOrderColumns+` FROM purchase_orders WHERE `+where+`
+		 ORDER BY created_at DESC, id LIMIT $6 OFFSET $7`,
+		append(args, perPage, (page-1)*perPage)...,
+	)
+}
+
+func (r *PurchaseOrderRepo) list(ctx context.Context, tenantID uuid.UUID, total int, sql string, args ...any) ([]models.PurchaseOrder, int, error) {
+	rows, err := r.db.Query(ctx, sql, args...)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to list purchase orders: %w", err)
+	}
+	defer rows.Close()
+
+	out := []models.PurchaseOrder{}
+	var ids []uuid.UUID
+	for rows.Next() {
+		po, err := scanPurchaseOrder(rows)
+		if err != nil {
+			return nil, 0, fmt.Errorf("failed to scan purchase order: %w", err)
+		}
+		out = append(out, *po)
+		ids = append(ids, po.ID)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, err
+	}
+	lines, err := purchaseOrderLines(ctx, r.db, tenantID, ids)
+	if err != nil {
+		return nil, 0, err
+	}
+	for i := range out {
+		out[i].Lines = lines[out[i].ID]
+	}
+	return out, total, nil
+}
+
+// purchaseOrderLines returns the lines of the given purchase orders by SKU,
+// keyed by purchase order.
+func purchaseOrderLines(ctx context.Context, q rowsQuerier, tenantID uuid.UUID, ids []uuid.UUID) (map[uuid.UUID][]models.PurchaseOrderLine, error) {
+	out := map[uuid.UUID][]models.PurchaseOrderLine{}
+	if len(ids) == 0 {
+		return out, nil
+	}
+	rows, err := q.Query(ctx,
+		`SELECT id, purchase_order_id, product_id, sku, vendor_sku, quantity, received_quantity, unit_cost
+		 FROM purchase_order_lines WHERE tenant_id = $1 AND purchase_order_id = ANY($2)
+		 ORDER BY sku`,
+		tenantID, ids,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get purchase order lines: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var l models.PurchaseOrderLine
+		if err := rows.Scan(&l.ID, &l.PurchaseOrderID, &l.ProductID, &l.SKU, &l.VendorSKU, &l.Quantity, &l.ReceivedQuantity, &l.UnitCost); err != nil {
+			return nil, fmt.Errorf("failed to scan purchase order line: %w", err)
+		}
+		out[l.PurchaseOrderID] = append(out[l.PurchaseOrderID], l)
+	}
+	return out, rows.Err()
+}
+
+// reorderQuery finds the stock levels the reorder job considers: those of
+// products with a supplier, with the supplier the job would buy from, the
+// level's position counting stock on open purchase orders, and what was
+// picked from it over the lookback.
+const reorderQuery = `SELECT i.product_id, i.warehouse_id, i.sku, i.min_quantity,
+		i.quantity - i.reserved + i.in_transit + COALESCE(oo.n, 0)::int,
+		COALESCE(c.n, 0)::int,
+		sp.vendor_id, sp.vendor_sku, sp.unit_cost, sp.lead_time_days, sp.min_order_quantity
+	FROM inventory_items i
+	JOIN LATERAL (
+		SELECT s.vendor_id, s.vendor_sku, s.unit_cost, s.lead_time_days, s.min_order_quantity
+		FROM supplier_products s JOIN vendors v ON v.id = s.vendor_id
+		WHERE s.tenant_id = i.tenant_id AND s.product_id = i.product_id AND v.status IS DISTINCT FROM 'inactive'
+		ORDER BY s.preferred DESC, s.unit_cost, s.lead_time_days, s.id
+		LIMIT 1
+	) sp ON TRUE
+	LEFT JOIN LATERAL (
+		SELECT SUM(l.quantity - l.received_quantity) AS n
+		FROM purchase_order_lines l JOIN purchase_orders po ON po.id = l.purchase_order_id
+		WHERE po.tenant_id = i.tenant_id AND l.product_id = i.product_id AND po.warehouse_id = i.warehouse_id
+			AND po.status IN ('draft', 'sent', 'partially_received')
+	) oo ON TRUE
+	LEFT JOIN LATERAL (
+		SELECT -SUM(m.quantity) AS n FROM stock_movements m
+		WHERE m.inventory_item_id = i.id AND m.movement_type = 'pick' AND m.created_at >= NOW() - make_interval(days => $3)
+	) c ON TRUE
+	WHERE i.tenant_id = $1 AND ($2::uuid IS NULL OR i.warehouse_id = $2)
+	ORDER BY sp.vendor_id, i.warehouse_id, i.sku`
+
+// DraftReorders drafts purchase orders for the tenant's stock levels, in
+// one warehouse or all of them, that policy says to reorder, one per vendor
+// and warehouse. Each product is bought from its preferred supplier, or
+// else the cheapest. Stock already on open purchase orders, drafts
+// included, counts towards a level, so running it again drafts nothing new.
+func (r *PurchaseOrderRepo) DraftReorders(ctx context.Context, tenantID uuid.UUID, warehouseID *uuid.UUID, policy models.ReorderPolicy, createdBy *uuid.UUID) ([]models.PurchaseOrder, error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	// One run per tenant at a time, or two would both see nothing on order.
+	if _, err := tx.Exec(ctx, `SELECT pg_advisory_xact_lock(hashtext('purchase_orders.reorder'), hashtext($1::text))`, tenantID); err != nil {
+		return nil, fmt.Errorf("failed to lock reorder: %w", err)
+	}
+	rows, err := tx.Query(ctx, reorderQuery, tenantID, warehouseID, policy.LookbackDays)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get stock levels to reorder: %w", err)
+	}
+	var drafts []*models.PurchaseOrder
+	for rows.Next() {
+		var (
+			l             models.PurchaseOrderLine
+			level         models.ReorderLevel
+			vendorID, wID uuid.UUID
+			lead, minimum int
+		)
+		if err := rows.Scan(&l.ProductID, &wID, &l.SKU, &level.MinQuantity, &level.Position, &level.Consumed,
+			&vendorID, &l.VendorSKU, &l.UnitCost, &lead, &minimum); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan stock level: %w", err)
+		}
+		if l.Quantity = policy.ReorderQuantity(level, lead, minimum); l.Quantity == 0 {
+			continue
+		}
+		if n := len(drafts); n == 0 || drafts[n-1].VendorID != vendorID || drafts[n-1].WarehouseID != wID {
+			drafts = append(drafts, &models.PurchaseOrder{
+				TenantID:    tenantID,
+				VendorID:    vendorID,
+				WarehouseID: wID,
+				Status:      models.PurchaseDraft,
+				Source:      models.PurchaseReorder,
+				CreatedBy:   createdBy,
+			})
+		}
+		po := drafts[len(drafts)-1]
+		po.Lines = append(po.Lines, l)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to get stock levels to reorder: %w", err)
+	}
+	for _, po := range drafts {
+		if err := insertPurchaseOrder(ctx, tx, po); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	out := make([]models.PurchaseOrder, 0, len(drafts))
+	for _, d := range drafts {
+		po, err := r.GetByID(ctx, tenantID, d.ID)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, *po)
+	}
+	return out, nil
+}
+
+// ReorderTenants returns the tenants with a supplier catalog, whom the
+// reorder job runs for. It runs on the privileged connection path,
+// bypassing row-level security.
+func (r *PurchaseOrderRepo) ReorderTenants(ctx context.Context) ([]uuid.UUID, error) {
+	rows, err := r.db.Query(database.Privileged(ctx), `SELECT DISTINCT tenant_id FROM supplier_products ORDER BY tenant_id`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get tenants to reorder for: %w", err)
+	}
+	defer rows.Close()
+
+	var out []uuid.UUID
+	for rows.Next() {
+		var id uuid.UUID
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("failed to scan tenant: %w", err)
+		}
+		out = append(out, id)
+	}
+	return out, rows.Err()
+}
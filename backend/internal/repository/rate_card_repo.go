@@ -0,0 +1,161 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"cargomax-api/internal/models"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+var (
+	// ErrRateCardNotFound is returned when a rate card does not exist within
+	// the tenant.
+	ErrRateCardNotFound = errors.New("rate card not found")
+	// ErrRateCardConflict is returned when saving an active card would give
+	// the tenant, or a client, a second active card.
+	ErrRateCardConflict = errors.New("an active rate card already exists for this client; deactivate it first")
+)
+
+// RateCardRepo handles database operations for rate cards.
+type RateCardRepo struct {
+	db *pgxpool.Pool
+}
+
+// NewRateCardRepo creates a new RateCardRepo instance.
+func NewRateCardRepo(db *pgxpool.Pool) *RateCardRepo {
+	return &RateCardRepo{db: db}
+}
+
+const rateCardColumns = `id, tenant_id, name, client_id, currency, volumetric_divisor, min_charge, active, zones, lanes, surcharges, created_at, updated_at`
+
+func scanRateCard(row pgx.Row) (*models.RateCard, error) {
+	c := &models.RateCard{}
+	err := row.Scan(&c.ID, &c.TenantID, &c.Name, &c.ClientID, &c.Currency, &c.VolumetricDivisor, &c.MinCharge, &c.Active, &c.Zones, &c.Lanes, &c.Surcharges, &c.CreatedAt, &c.UpdatedAt)
+	return c, err
+}
+
+// rateCardError maps a unique violation of the one-active-card indexes to
+// ErrRateCardConflict.
+func rateCardError(action string, err error) error {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+		return ErrRateCardConflict
+	}
+	return fmt.Errorf("failed to %s rate card: %w", action, err)
+}
+
+// Create inserts a new rate card.
+func (r *RateCardRepo) Create(ctx context.Context, c *models.RateCard) error {
+	c.ID = uuid.New()
+	err := r.db.QueryRow(ctx,
+		`INSERT INTO rate_cards (id, tenant_id, name, client_id, currency, volumetric_divisor, min_charge, active, zones, lanes, surcharges, created_at, updated_at)
+		 VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, NOW(), NOW())
+		 RETURNING created_at, updated_at`,
+		c.ID, c.TenantID, c.Name, c.ClientID, c.Currency, c.VolumetricDivisor, c.MinCharge, c.Active, c.Zones, c.Lanes, c.Surcharges,
+	).Scan(&c.CreatedAt, &c.UpdatedAt)
+	if err != nil {
+		return rateCardError("create", err)
+	}
+	return nil
+}
+
+// GetByID retrieves a rate card by ID within a tenant.
+func (r *RateCardRepo) GetByID(ctx context.Context, tenantID, id uuid.UUID) (*models.RateCard, error) {
+	c, err := scanRateCard(r.db.QueryRow(ctx,
+		`SELECT `+rateCardColumns+` FROM rate_cards WHERE id = $1 AND tenant_id = $2`,
+		id, tenantID,
+	))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrRateCardNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get rate card: %w", err)
+	}
+	return c, nil
+}
+
+// List returns the tenant's rate cards, the default cards first, then by
+// name.
+func (r *RateCardRepo) List(ctx context.Context, tenantID uuid.UUID) ([]models.RateCard, error) {
+	rows, err := r.db.Query(ctx,
+		`SELECT `+rateCardColumns+` FROM rate_cards WHERE tenant_id = $1
+		 ORDER BY client_id IS NOT NULL, active DESC, name`,
+		tenantID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list rate cards: %w", err)
+	}
+	defer rows.Close()
+
+	var cards []models.RateCard
+	for rows.Next() {
+		c, err := scanRateCard(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan rate card: %w", err)
+		}
+		cards = append(cards, *c)
+	}
+	return cards, rows.Err()
+}
+
+// Update replaces a rate card's contents.
+func (r *RateCardRepo) Update(ctx context.Context, tenantID, id uuid.UUID, c *models.RateCard) error {
+	err := r.db.QueryRow(ctx,
+		`UPDATE rate_cards SET name = $1, client_id = $2, currency = $3, volumetric_divisor = $4, min_charge = $5, active = $6, zones = $7, lanes = $8, surcharges = $9, updated_at = NOW()
+		 WHERE id = $10 AND tenant_id = $11
+		 RETURNING created_at, updated_at`,
+		c.Name, c.ClientID, c.Currency, c.VolumetricDivisor, c.MinCharge, c.Active, c.Zones, c.Lanes, c.Surcharges, id, tenantID,
+	).Scan(&c.CreatedAt, &c.UpdatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return ErrRateCardNotFound
+	}
+	if err != nil {
+		return rateCardError("update", err)
+	}
+	c.ID, c.TenantID = id, tenantID
+	return nil
+}
+
+// Delete removes a rate card. Orders keep the quotes made from it.
+func (r *RateCardRepo) Delete(ctx context.Context, tenantID, id uuid.UUID) error {
+	ct, err := r.db.Exec(ctx, `DELETE FROM rate_cards WHERE id = $1 AND tenant_id = $2`, id, tenantID)
+	if err != nil {
+		return fmt.Errorf("failed to delete rate card: %w", err)
+	}
+	if ct.RowsAffected() == 0 {
+		return ErrRateCardNotFound
+	}
+	return nil
+}
+
+// ForClient returns the active cards that price a shipment for clientID, in
+// the order they are tried: the client's card, then the tenant default.
+// Either may be missing; with clientID nil only the default is returned.
+func (r *RateCardRepo) ForClient(ctx context.Context, tenantID uuid.UUID, clientID *uuid.UUID) ([]*models.RateCard, error) {
+	rows, err := r.db.Query(ctx,
+		`SELECT `+rateCardColumns+` FROM rate_cards
+		 WHERE tenant_id = $1 AND active AND (client_id IS NULL OR client_id = $2)
+		 ORDER BY client_id IS NULL`,
+		tenantID, clientID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get rate cards: %w", err)
+	}
+	defer rows.Close()
+
+	var cards []*models.RateCard
+	for rows.Next() {
+		c, err := scanRateCard(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan rate card: %w", err)
+		}
+		cards = append(cards, c)
+	}
+	return cards, rows.Err()
+}
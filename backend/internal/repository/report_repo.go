@@ -3,6 +3,7 @@ package repository
 import (
 	"context"
 	"fmt"
+	"sort"
 	"time"
 
 	"cargomax-api/internal/models"
@@ -21,29 +22,21 @@ func NewReportRepo(db *pgxpool.Pool) *ReportRepo {
 	return &ReportRepo{db: db}
 }
 
-// GetRevenueReport returns revenue report data with monthly breakdowns for the specified year.
+// GetRevenueReport returns invoiced revenue for the specified year with
+// monthly breakdowns: invoices issued less credit notes, before tax, by
+// issue month. Void invoices do not count.
 func (r *ReportRepo) GetRevenueReport(ctx context.Context, tenantID uuid.UUID, year int) (*models.RevenueReport, error) {
 	report := &models.RevenueReport{}
 	startDate := time.Date(year, 1, 1, 0, 0, 0, 0, time.UTC)
 	endDate := time.Date(year+1, 1, 1, 0, 0, 0, 0, time.UTC)
 
-	// Total revenue for the specified year
-	var totalRev *float64
-	err := r.db.QueryRow(ctx,
-		`SELECT SUM(total_amount) FROM orders WHERE tenant_id = $1 AND status NOT IN ('cancelled', 'returned') AND created_at >= $2 AND created_at < $3`,
-		tenantID, startDate, endDate,
-	).Scan(&totalRev)
-	if err != nil {
-		return nil, fmt.Errorf("failed to get total revenue: %w", err)
-	}
-	if totalRev != nil {
-		report.TotalRevenue = *totalRev
-	}
-
-	// Monthly breakdown for the specified year
 	rows, err := r.db.Query(ctx,
-		`SELECT TO_CHAR(created_at, 'YYYY-MM') AS month, COALESCE(SUM(total_amount), 0) AS value, COUNT(*) AS count
-		 FROM orders WHERE tenant_id = $1 AND status NOT IN ('cancelled', 'returned') AND created_at >= $2 AND created_at < $3
+		`SELECT TO_CHAR(issue_date, 'YYYY-MM') AS month,
+			COALESCE(SUM(CASE WHEN kind = 'invoice' THEN subtotal ELSE -subtotal END), 0) AS value,
+			COALESCE(SUM(CASE WHEN kind = 'invoice' THEN tax_total ELSE -tax_total END), 0) AS tax,
+			COUNT(*) FILTER (WHERE kind = 'invoice') AS count,
+			COALESCE(SUM(jsonb_array_length(lines)) FILTER (WHERE kind = 'invoice'), 0) AS orders
+		 FROM invoices WHERE tenant_id = $1 AND status <> 'void' AND issue_date >= $2 AND issue_date < $3
 		 GROUP BY month ORDER BY month ASC`,
 		tenantID, startDate, endDate,
 	)
@@ -54,14 +47,106 @@ func (r *ReportRepo) GetRevenueReport(ctx context.Context, tenantID uuid.UUID, y
 
 	for rows.Next() {
 		var mb models.MonthlyBreakdown
-		if err := rows.Scan(&mb.Month, &mb.Value, &mb.Count); err != nil {
+		var tax float64
+		var orders int
+		if err := rows.Scan(&mb.Month, &mb.Value, &tax, &mb.Count, &orders); err != nil {
 			return nil, fmt.Errorf("failed to scan revenue breakdown: %w", err)
 		}
 		report.MonthlyBreakdown = append(report.MonthlyBreakdown, mb)
+		report.MonthlyData = append(report.MonthlyData, models.MonthlyRevenue{Month: mb.Month, Revenue: mb.Value, Orders: orders})
+		report.TotalRevenue += mb.Value
+		report.TotalTax += tax
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to get revenue monthly breakdown: %w", err)
+	}
+	report.TotalRevenue = models.RoundMoney(report.TotalRevenue)
+	report.TotalTax = models.RoundMoney(report.TotalTax)
+
+	err = r.db.QueryRow(ctx,
+		`SELECT COALESCE(SUM(amount), 0) FROM payments WHERE tenant_id = $1 AND paid_at >= $2 AND paid_at < $3`,
+		tenantID, startDate, endDate,
+	).Scan(&report.Collected)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get payments collected: %w", err)
+	}
+	err = r.db.QueryRow(ctx,
+		`SELECT COALESCE(SUM(GREATEST(total - amount_paid - amount_credited, 0)), 0) FROM invoices
+		 WHERE tenant_id = $1 AND kind = 'invoice' AND status IN ('issued', 'partially_paid') AND issue_date >= $2 AND issue_date < $3`,
+		tenantID, startDate, endDate,
+	).Scan(&report.Outstanding)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get outstanding balance: %w", err)
 	}
 	return report, nil
 }
 
+// GetAgingReport returns what clients owe on open invoices, bucketed by how
+// many days past due each invoice is as of asOf, in total and per client,
+// the largest debtor first.
+func (r *ReportRepo) GetAgingReport(ctx context.Context, tenantID uuid.UUID, asOf time.Time) (*models.AgingReport, error) {
+	asOf = asOf.UTC().Truncate(24 * time.Hour)
+	report := &models.AgingReport{AsOf: asOf, Buckets: newAgingBuckets(), Clients: []models.ClientAging{}}
+	rows, err := r.db.Query(ctx,
+		`SELECT i.client_id, c.company_name, i.due_date, i.total - i.amount_paid - i.amount_credited
+		 FROM invoices i JOIN clients c ON c.id = i.client_id AND c.tenant_id = i.tenant_id
+		 WHERE i.tenant_id = $1 AND i.kind = 'invoice' AND i.status IN ('issued', 'partially_paid')
+		   AND i.total - i.amount_paid - i.amount_credited > 0`,
+		tenantID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get open invoices: %w", err)
+	}
+	defer rows.Close()
+
+	byClient := map[uuid.UUID]*models.ClientAging{}
+	var order []uuid.UUID
+	for rows.Next() {
+		var clientID uuid.UUID
+		var name string
+		var due time.Time
+		var balance float64
+		if err := rows.Scan(&clientID, &name, &due, &balance); err != nil {
+			return nil, fmt.Errorf("failed to scan open invoice: %w", err)
+		}
+		c := byClient[clientID]
+		if c == nil {
+			c = &models.ClientAging{ClientID: clientID, CompanyName: name, Buckets: newAgingBuckets()}
+			byClient[clientID] = c
+			order = append(order, clientID)
+		}
+		b := models.AgingBucketIndex(int(asOf.Sub(due.UTC()).Hours() / 24))
+		for _, buckets := range [][]models.AgingBucket{report.Buckets, c.Buckets} {
+			buckets[b].Amount = models.RoundMoney(buckets[b].Amount + balance)
+			buckets[b].Invoices++
+		}
+		c.Total = models.RoundMoney(c.Total + balance)
+		report.Total = models.RoundMoney(report.Total + balance)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to get open invoices: %w", err)
+	}
+
+	for _, id := range order {
+		report.Clients = append(report.Clients, *byClient[id])
+	}
+	sort.SliceStable(report.Clients, func(i, j int) bool {
+		if report.Clients[i].Total != report.Clients[j].Total {
+			return report.Clients[i].Total > report.Clients[j].Total
+		}
+		return report.Clients[i].CompanyName < report.Clients[j].CompanyName
+	})
+	return report, nil
+}
+
+func newAgingBuckets() []models.AgingBucket {
+	buckets := make([]models.AgingBucket, len(models.AgingBucketLabels))
+	for i, l := range models.AgingBucketLabels {
+		buckets[i].Label = l
+	}
+	return buckets
+}
+
 // GetDeliveryReport returns delivery performance data with monthly breakdowns for the specified year.
 func (r *ReportRepo) GetDeliveryReport(ctx context.Context, tenantID uuid.UUID, year int) (*models.DeliveryReport, error) {
 	report := &models.DeliveryReport{}
@@ -0,0 +1,627 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"cargomax-api/internal/models"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// ErrRMANotFound is returned when an RMA does not exist in the tenant.
+var ErrRMANotFound = errors.New("RMA not found")
+
+// RMARepo handles return merchandise authorizations: approving returns,
+// receiving and inspecting the goods, and refunding or crediting them.
+type RMARepo struct {
+	db *pgxpool.Pool
+}
+
+// NewRMARepo creates a new RMARepo.
+func NewRMARepo(db *pgxpool.Pool) *RMARepo {
+	return &RMARepo{db: db}
+}
+
+const rmaColumns = `id, tenant_id, rma_number, order_id, status, reason, notes, warehouse_id, return_shipment_id, rejection_reason, resolution, resolution_amount, refund_reference, credit_note_id, created_by, approved_at, received_at, closed_at, created_at, updated_at`
+
+func scanRMA(row pgx.Row) (*models.RMA, error) {
+	r := &models.RMA{}
+	err := row.Scan(&r.ID, &r.TenantID, &r.RMANumber, &r.OrderID, &r.Status, &r.Reason, &r.Notes, &r.WarehouseID, &r.ReturnShipmentID, &r.RejectionReason, &r.Resolution, &r.ResolutionAmount, &r.RefundReference, &r.CreditNoteID, &r.CreatedBy, &r.ApprovedAt, &r.ReceivedAt, &r.ClosedAt, &r.CreatedAt, &r.UpdatedAt)
+	return r, err
+}
+
+// Request opens an RMA for quantities of an order's lines. Each line can
+// have returned at most what shipped, less what has come back or is on
+// another open RMA. The goods come back to the warehouse the first line
+// shipped from.
+func (r *RMARepo) Request(ctx context.Context, tenantID, orderID uuid.UUID, reqs []models.ReturnRequest, reason string, notes *string, createdBy *uuid.UUID) (*models.RMA, error) {
+	if len(reqs) == 0 {
+		return nil, fmt.Errorf("an RMA needs lines to return")
+	}
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := lockOrderStatus(ctx, tx, tenantID, orderID); err != nil {
+		return nil, err
+	}
+	lines, err := lockOrderLines(ctx, tx, tenantID, orderID)
+	if err != nil {
+		return nil, err
+	}
+	open, err := openRMAQuantities(ctx, tx, tenantID, orderID)
+	if err != nil {
+		return nil, err
+	}
+	byID := map[uuid.UUID]*models.OrderLine{}
+	for i := range lines {
+		byID[lines[i].ID] = &lines[i]
+	}
+
+	rma := &models.RMA{ID: uuid.New(), TenantID: tenantID, OrderID: orderID, Status: models.RMARequested, Reason: reason, Notes: notes, CreatedBy: createdBy}
+	seen := map[uuid.UUID]bool{}
+	for _, req := range reqs {
+		l, ok := byID[req.OrderLineID]
+		if !ok {
+			return nil, fmt.Errorf("order line %s is not on this order", req.OrderLineID)
+		}
+		if seen[l.ID] {
+			return nil, fmt.Errorf("line %d is listed twice", l.LineNumber)
+		}
+		seen[l.ID] = true
+		if req.Quantity <= 0 {
+			return nil, fmt.Errorf("the quantity to return of line %d must be positive", l.LineNumber)
+		}
+		if left := l.ShippedQuantity - l.ReturnedQuantity - open[l.ID]; req.Quantity > left {
+			return nil, fmt.Errorf("line %d (%s) has %d that can be returned, %d asked for", l.LineNumber, l.SKU, max(left, 0), req.Quantity)
+		}
+		if rma.WarehouseID == nil {
+			rma.WarehouseID = l.WarehouseID
+		}
+	}
+
+	n, err := nextSequence(ctx, tx, tenantID, models.SequenceRMA)
+	if err != nil {
+		return nil, err
+	}
+	rma.RMANumber = models.FormatInvoiceNumber(models.RMAPrefix, n)
+	if _, err := tx.Exec(ctx,
+		`INSERT INTO rmas (id, tenant_id, rma_number, order_id, status, reason, notes, warehouse_id, created_by, created_at, updated_at)
+		 VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, NOW(), NOW())`,
+		rma.ID, tenantID, rma.RMANumber, orderID, rma.Status, reason, notes, rma.WarehouseID, createdBy,
+	); err != nil {
+		return nil, fmt.Errorf("failed to create RMA: %w", err)
+	}
+	for _, req := range reqs {
+		if _, err := tx.Exec(ctx,
+			`INSERT INTO rma_lines (id, tenant_id, rma_id, order_line_id, quantity) VALUES ($1, $2, $3, $4, $5)`,
+			uuid.New(), tenantID, rma.ID, req.OrderLineID, req.Quantity,
+		); err != nil {
+			return nil, fmt.Errorf("failed to create RMA line: %w", err)
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return r.GetByID(ctx, tenantID, rma.ID)
+}
+
+// openRMAQuantities returns, by order line, what open RMAs on an order still
+// expect back: what was asked for until it is approved, and then what was
+// approved.
+func openRMAQuantities(ctx context.Context, tx pgx.Tx, tenantID, orderID uuid.UUID) (map[uuid.UUID]int, error) {
+	rows, err := tx.Query(ctx,
+		`SELECT rl.order_line_id, SUM(CASE WHEN r.status = 'requested' THEN rl.quantity ELSE rl.approved_quantity END)
+		 FROM rma_lines rl JOIN rmas r ON r.id = rl.rma_id
+		 WHERE rl.tenant_id = $1 AND r.order_id = $2 AND r.status IN ('requested', 'approved', 'in_transit')
+		 GROUP BY rl.order_line_id`,
+		tenantID, orderID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get open RMAs: %w", err)
+	}
+	defer rows.Close()
+
+	out := map[uuid.UUID]int{}
+	for rows.Next() {
+		var id uuid.UUID
+		var n int
+		if err := rows.Scan(&id, &n); err != nil {
+			return nil, fmt.Errorf("failed to scan open RMA: %w", err)
+		}
+		out[id] = n
+	}
+	return out, rows.Err()
+}
+
+// checkNoOpenRMA stops an order being marked returned wholesale while an RMA
+// on it is still expecting goods, which would put them back in stock twice.
+func checkNoOpenRMA(ctx context.Context, tx pgx.Tx, tenantID, orderID uuid.UUID) error {
+	var number string
+	err := tx.QueryRow(ctx,
+		`SELECT rma_number FROM rmas WHERE tenant_id = $1 AND order_id = $2 AND status IN ('requested', 'approved', 'in_transit') LIMIT 1`,
+		tenantID, orderID,
+	).Scan(&number)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to check open RMAs: %w", err)
+	}
+	return fmt.Errorf("%s is open on this order; receive or cancel it instead", number)
+}
+
+// lockRMA locks the order an RMA is for and then the RMA, the order every
+// change to an order's stock takes its locks in, checking the RMA is in one
+// of statuses.
+func lockRMA(ctx context.Context, tx pgx.Tx, tenantID, id uuid.UUID, statuses ...string) (*models.RMA, error) {
+	var orderID uuid.UUID
+	err := tx.QueryRow(ctx, `SELECT order_id FROM rmas WHERE id = $1 AND tenant_id = $2`, id, tenantID).Scan(&orderID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrRMANotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get RMA: %w", err)
+	}
+	if _, err := lockOrderStatus(ctx, tx, tenantID, orderID); err != nil {
+		return nil, err
+	}
+	rma, err := scanRMA(tx.QueryRow(ctx,
+		`SELECT `+rmaColumns+` FROM rmas WHERE id = $1 AND tenant_id = $2 FOR UPDATE`,
+		id, tenantID,
+	))
+	if err != nil {
+		return nil, fmt.Errorf("failed to lock RMA: %w", err)
+	}
+	lines, err := rmaLines(ctx, tx, tenantID, []uuid.UUID{id})
+	if err != nil {
+		return nil, err
+	}
+	rma.Lines = lines[id]
+	for _, s := range statuses {
+		if rma.Status == s {
+			return rma, nil
+		}
+	}
+	return nil, fmt.Errorf("%s is %s", rma.RMANumber, rma.Status)
+}
+
+// Approve agrees which goods may come back. approved gives the quantity of
+// each line accepted, zero to refuse it; lines left out, or all of them when
+// approved is nil, are accepted as asked. An RMA with nothing accepted must
+// be rejected instead.
+func (r *RMARepo) Approve(ctx context.Context, tenantID, id uuid.UUID, approved []models.ReturnRequest) (*models.RMA, error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	rma, err := lockRMA(ctx, tx, tenantID, id, models.RMARequested)
+	if err != nil {
+		return nil, err
+	}
+	qty := map[uuid.UUID]int{}
+	for _, l := range rma.Lines {
+		qty[l.OrderLineID] = l.Quantity
+	}
+	for _, a := range approved {
+		asked, ok := qty[a.OrderLineID]
+		if !ok {
+			return nil, fmt.Errorf("order line %s is not on %s", a.OrderLineID, rma.RMANumber)
+		}
+		if a.Quantity < 0 || a.Quantity > asked {
+			return nil, fmt.Errorf("approve from 0 to the %d asked for of order line %s", asked, a.OrderLineID)
+		}
+		qty[a.OrderLineID] = a.Quantity
+	}
+	total := 0
+	for lineID, n := range qty {
+		total += n
+		if _, err := tx.Exec(ctx,
+			`UPDATE rma_lines SET approved_quantity = $1 WHERE rma_id = $2 AND order_line_id = $3 AND tenant_id = $4`,
+			n, id, lineID, tenantID,
+		); err != nil {
+			return nil, fmt.Errorf("failed to approve RMA line: %w", err)
+		}
+	}
+	if total == 0 {
+		return nil, fmt.Errorf("nothing is approved; reject the RMA instead")
+	}
+	if err := setRMAStatus(ctx, tx, tenantID, id, models.RMAApproved, "approved_at = NOW()"); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return r.GetByID(ctx, tenantID, id)
+}
+
+// Reject refuses a requested RMA.
+func (r *RMARepo) Reject(ctx context.Context, tenantID, id uuid.UUID, reason string) (*models.RMA, error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := lockRMA(ctx, tx, tenantID, id, models.RMARequested); err != nil {
+		return nil, err
+	}
+	if _, err := tx.Exec(ctx,
+		`UPDATE rmas SET status = $1, rejection_reason = $2, closed_at = NOW(), updated_at = NOW() WHERE id = $3 AND tenant_id = $4`,
+		models.RMARejected, reason, id, tenantID,
+	); err != nil {
+		return nil, fmt.Errorf("failed to reject RMA: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return r.GetByID(ctx, tenantID, id)
+}
+
+// Cancel withdraws an RMA before its goods are received.
+func (r *RMARepo) Cancel(ctx context.Context, tenantID, id uuid.UUID) (*models.RMA, error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := lockRMA(ctx, tx, tenantID, id, models.RMARequested, models.RMAApproved, models.RMAInTransit); err != nil {
+		return nil, err
+	}
+	if err := setRMAStatus(ctx, tx, tenantID, id, models.RMACancelled, "closed_at = NOW()"); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return r.GetByID(ctx, tenantID, id)
+}
+
+// ShipBack books the shipment bringing an approved RMA's goods back, from s,
+// which the caller fills in with the origin, destination and tracking
+// number; ev opens its timeline. The RMA is then in transit.
+func (r *RMARepo) ShipBack(ctx context.Context, tenantID, id uuid.UUID, s *models.Shipment, ev *models.ShipmentEvent) (*models.RMA, error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := lockRMA(ctx, tx, tenantID, id, models.RMAApproved); err != nil {
+		return nil, err
+	}
+	s.TenantID = tenantID
+	if s.Status == "" {
+		s.Status = models.ShipmentPending
+	}
+	if err := insertShipment(ctx, tx, s, ev); err != nil {
+		return nil, err
+	}
+	if _, err := tx.Exec(ctx,
+		`UPDATE rmas SET status = $1, return_shipment_id = $2, updated_at = NOW() WHERE id = $3 AND tenant_id = $4`,
+		models.RMAInTransit, s.ID, id, tenantID,
+	); err != nil {
+		return nil, fmt.Errorf("failed to book return shipment: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return r.GetByID(ctx, tenantID, id)
+}
+
+// Receive records the inspection of an RMA's goods on arrival. Each line is
+// graded; what is restocked goes back into the item's stock and what is
+// written off does not. Lines not inspected arrived with nothing. An order
+// whose every shipped unit has come back moves to returned.
+func (r *RMARepo) Receive(ctx context.Context, tenantID, id uuid.UUID, inspections []models.Inspection) (*models.RMA, error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	rma, err := lockRMA(ctx, tx, tenantID, id, models.RMAApproved, models.RMAInTransit)
+	if err != nil {
+		return nil, err
+	}
+	approved := map[uuid.UUID]int{}
+	for _, l := range rma.Lines {
+		approved[l.OrderLineID] = l.ApprovedQuantity
+	}
+	got := map[uuid.UUID]models.Inspection{}
+	for _, in := range inspections {
+		n, ok := approved[in.OrderLineID]
+		if !ok {
+			return nil, fmt.Errorf("order line %s is not on %s", in.OrderLineID, rma.RMANumber)
+		}
+		if _, dup := got[in.OrderLineID]; dup {
+			return nil, fmt.Errorf("order line %s is inspected twice", in.OrderLineID)
+		}
+		if in.Quantity < 0 || in.Quantity > n {
+			return nil, fmt.Errorf("receive from 0 to the %d approved of order line %s", n, in.OrderLineID)
+		}
+		if !models.IsCondition(in.Condition) {
+			return nil, fmt.Errorf("condition must be A, B, C or D")
+		}
+		got[in.OrderLineID] = in
+	}
+
+	lines, err := lockOrderLines(ctx, tx, tenantID, rma.OrderID)
+	if err != nil {
+		return nil, err
+	}
+	ref := models.StockRef{Type: "rma", ID: &rma.ID, Number: rma.RMANumber, Reason: rma.Reason}
+	complete := true
+	for i := range lines {
+		l := &lines[i]
+		in, ok := got[l.ID]
+		if ok && in.Quantity > 0 {
+			restock := models.Restockable(in.Condition)
+			if in.Restock != nil {
+				restock = *in.Restock
+			}
+			restocked, writtenOff := 0, in.Quantity
+			if restock {
+				restocked, writtenOff = in.Quantity, 0
+				if l.InventoryItemID != nil {
+					if err := putBackStock(ctx, tx, tenantID, *l.InventoryItemID, in.Quantity, ref); err != nil {
+						return nil, err
+					}
+				}
+			}
+			l.ReturnedQuantity += in.Quantity
+			if l.ReturnedQuantity > l.ShippedQuantity {
+				return nil, fmt.Errorf("line %d (%s) would have more returned than shipped", l.LineNumber, l.SKU)
+			}
+			if err := setLineStock(ctx, tx, l); err != nil {
+				return nil, err
+			}
+			if _, err := tx.Exec(ctx,
+				`UPDATE rma_lines SET received_quantity = $1, restocked_quantity = $2, written_off_quantity = $3, condition = $4, inspection_notes = $5
+				 WHERE rma_id = $6 AND order_line_id = $7 AND tenant_id = $8`,
+				in.Quantity, restocked, writtenOff, in.Condition, in.Notes, id, l.ID, tenantID,
+			); err != nil {
+				return nil, fmt.Errorf("failed to record inspection: %w", err)
+			}
+		} else if ok {
+			if _, err := tx.Exec(ctx,
+				`UPDATE rma_lines SET condition = $1, inspection_notes = $2 WHERE rma_id = $3 AND order_line_id = $4 AND tenant_id = $5`,
+				in.Condition, in.Notes, id, l.ID, tenantID,
+			); err != nil {
+				return nil, fmt.Errorf("failed to record inspection: %w", err)
+			}
+		}
+		if l.ReservedQuantity > 0 || l.ReturnedQuantity < l.ShippedQuantity || l.ShippedQuantity < l.Quantity {
+			complete = false
+		}
+	}
+	if err := setRMAStatus(ctx, tx, tenantID, id, models.RMAReceived, "received_at = NOW()"); err != nil {
+		return nil, err
+	}
+	if complete {
+		if _, err := tx.Exec(ctx,
+			`UPDATE orders SET status = $1, return_reason = COALESCE(return_reason, $2), updated_at = NOW()
+			 WHERE id = $3 AND tenant_id = $4 AND status IN ('shipped', 'delivered')`,
+			models.OrderReturned, rma.RMANumber+": "+rma.Reason, rma.OrderID, tenantID,
+		); err != nil {
+			return nil, fmt.Errorf("failed to mark order returned: %w", err)
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return r.GetByID(ctx, tenantID, id)
+}
+
+// Resolve settles a received RMA and closes it. A refund is recorded and
+// comes off the order's client's total_spent; a credit is issued as a credit
+// note against the invoice that billed the order, numbered with s.
+func (r *RMARepo) Resolve(ctx context.Context, tenantID, id uuid.UUID, res models.RMAResolution, s models.InvoiceSettings) (*models.RMA, error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	rma, err := lockRMA(ctx, tx, tenantID, id, models.RMAReceived)
+	if err != nil {
+		return nil, err
+	}
+	amount := rma.ReceivedValue()
+	if res.Amount != nil {
+		amount = models.RoundMoney(*res.Amount)
+	}
+	if amount < 0 {
+		return nil, fmt.Errorf("the amount cannot be negative")
+	}
+
+	var creditNoteID *uuid.UUID
+	switch res.Kind {
+	case models.RMANone:
+		amount = 0
+	case models.RMARefund:
+		if amount == 0 {
+			return nil, fmt.Errorf("nothing to refund; resolve with none instead")
+		}
+		var clientID *uuid.UUID
+		if err := tx.QueryRow(ctx, `SELECT client_id FROM orders WHERE id = $1 AND tenant_id = $2`, rma.OrderID, tenantID).Scan(&clientID); err != nil {
+			return nil, fmt.Errorf("failed to get order: %w", err)
+		}
+		if clientID != nil {
+			if err := addClientSpent(ctx, tx, tenantID, *clientID, -amount); err != nil {
+				return nil, err
+			}
+		}
+	case models.RMACredit:
+		if amount == 0 {
+			return nil, fmt.Errorf("nothing to credit; resolve with none instead")
+		}
+		var invoiceID *uuid.UUID
+		if err := tx.QueryRow(ctx, `SELECT invoice_id FROM orders WHERE id = $1 AND tenant_id = $2`, rma.OrderID, tenantID).Scan(&invoiceID); err != nil {
+			return nil, fmt.Errorf("failed to get order: %w", err)
+		}
+		if invoiceID == nil {
+			return nil, fmt.Errorf("the order has not been invoiced; refund it instead")
+		}
+		note, err := createCreditNote(ctx, tx, tenantID, models.CreditNoteRequest{
+			InvoiceID: *invoiceID,
+			Amount:    amount,
+			Reason:    rma.RMANumber + ": " + rma.Reason,
+			IssueDate: time.Now().UTC().Truncate(24 * time.Hour),
+			CreatedBy: res.CreatedBy,
+		}, s)
+		if err != nil {
+			return nil, err
+		}
+		creditNoteID = &note.ID
+	default:
+		return nil, fmt.Errorf("resolution must be refund, credit or none")
+	}
+
+	if _, err := tx.Exec(ctx,
+		`UPDATE rmas SET status = $1, resolution = $2, resolution_amount = $3, refund_reference = $4, credit_note_id = $5, closed_at = NOW(), updated_at = NOW()
+		 WHERE id = $6 AND tenant_id = $7`,
+		models.RMAClosed, res.Kind, amount, res.Reference, creditNoteID, id, tenantID,
+	); err != nil {
+		return nil, fmt.Errorf("failed to close RMA: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return r.GetByID(ctx, tenantID, id)
+}
+
+// setRMAStatus moves an RMA to status, also running the given SET clause.
+func setRMAStatus(ctx context.Context, tx pgx.Tx, tenantID, id uuid.UUID, status, set string) error {
+	if _, err := tx.Exec(ctx,
+		`UPDATE rmas SET status = $1, `+set+`, updated_at = NOW() WHERE id = $2 AND tenant_id = $3`,
+		status, id, tenantID,
+	); err != nil {
+		return fmt.Errorf("failed to update RMA: %w", err)
+	}
+	return nil
+}
+
+// GetByID retrieves an RMA with its lines.
+func (r *RMARepo) GetByID(ctx context.Context, tenantID, id uuid.UUID) (*models.RMA, error) {
+	rma, err := scanRMA(r.db.QueryRow(ctx,
+		`SELECT `+rmaColumns+` FROM rmas WHERE id = $1 AND tenant_id = $2`,
+		id, tenantID,
+	))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrRMANotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get RMA: %w", err)
+	}
+	lines, err := rmaLines(ctx, r.db, tenantID, []uuid.UUID{id})
+	if err != nil {
+		return nil, err
+	}
+	rma.Lines = lines[id]
+	return rma, nil
+}
+
+// List returns a page of the tenant's RMAs, newest first, optionally in one
+// status, with the total count.
+func (r *RMARepo) List(ctx context.Context, tenantID uuid.UUID, status string, page, perPage int) ([]models.RMA, int, error) {
+	var total int
+	if err := r.db.QueryRow(ctx,
+		`SELECT COUNT(*) FROM rmas WHERE tenant_id = $1 AND ($2 = '' OR status = $2)`,
+		tenantID, status,
+	).Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("failed to count RMAs: %w", err)
+	}
+	out, err := r.list(ctx, tenantID,
+		`SELECT `+rmaColumns+` FROM rmas WHERE tenant_id = $1 AND ($2 = '' OR status = $2)
+		 ORDER BY created_at DESC, id LIMIT $3 OFFSET $4`,
+		status, perPage, (page-1)*perPage,
+	)
+	return out, total, err
+}
+
+// ListByOrder returns an order's RMAs, oldest first.
+func (r *RMARepo) ListByOrder(ctx context.Context, tenantID, orderID uuid.UUID) ([]models.RMA, error) {
+	return r.list(ctx, tenantID,
+		`SELECT `+rmaColumns+` FROM rmas WHERE tenant_id = $1 AND order_id = $2 ORDER BY created_at, id`,
+		orderID,
+	)
+}
+
+func (r *RMARepo) list(ctx context.Context, tenantID uuid.UUID, sql string, args ...any) ([]models.RMA, error) {
+	rows, err := r.db.Query(ctx, sql, append([]any{tenantID}, args...)...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list RMAs: %w", err)
+	}
+	defer rows.Close()
+
+	out := []models.RMA{}
+	var ids []uuid.UUID
+	for rows.Next() {
+		rma, err := scanRMA(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan RMA: %w", err)
+		}
+		out = append(out, *rma)
+		ids = append(ids, rma.ID)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	lines, err := rmaLines(ctx, r.db, tenantID, ids)
+	if err != nil {
+		return nil, err
+	}
+	for i := range out {
+		out[i].Lines = lines[out[i].ID]
+	}
+	return out, nil
+}
+
+// rmaLines returns the lines of the given RMAs in order line order, keyed by
+// RMA.
+func rmaLines(ctx context.Context, q rowsQuerier, tenantID uuid.UUID, ids []uuid.UUID) (map[uuid.UUID][]models.RMALine, error) {
+	out := map[uuid.UUID][]models.RMALine{}
+	if len(ids) == 0 {
+		return out, nil
+	}
+	rows, err := q.Query(ctx,
+		`SELECT rl.id, rl.rma_id, rl.order_line_id, ol.line_number, ol.sku, ol.description, ol.unit_price,
+		        rl.quantity, rl.approved_quantity, rl.received_quantity, rl.restocked_quantity, rl.written_off_quantity, rl.condition, rl.inspection_notes
+		 FROM rma_lines rl JOIN order_lines ol ON ol.id = rl.order_line_id
+		 WHERE rl.tenant_id = $1 AND rl.rma_id = ANY($2)
+		 ORDER BY ol.line_number`,
+		tenantID, ids,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get RMA lines: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var l models.RMALine
+		if err := rows.Scan(&l.ID, &l.RMAID, &l.OrderLineID, &l.LineNumber, &l.SKU, &l.Description, &l.UnitPrice,
+			&l.Quantity, &l.ApprovedQuantity, &l.ReceivedQuantity, &l.RestockedQuantity, &l.WrittenOffQuantity, &l.Condition, &l.InspectionNotes); err != nil {
+			return nil, fmt.Errorf("failed to scan RMA line: %w", err)
+		}
+		out[l.RMAID] = append(out[l.RMAID], l)
+	}
+	return out, rows.Err()
+}
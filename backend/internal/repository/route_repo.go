@@ -0,0 +1,598 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"cargomax-api/internal/models"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// ErrRouteNotFound is returned when a route plan or stop does not exist within
+// the tenant.
+var ErrRouteNotFound = errors.New("route plan not found")
+
+// ErrRouteLocked is returned when a route plan is changed after its driver
+// has started it.
+var ErrRouteLocked = errors.New("route plan can no longer be edited")
+
+// ErrDriverBooked is returned when a driver already has an assigned or
+// running route plan on the plan's date.
+var ErrDriverBooked = errors.New("driver already has a route plan on that date")
+
+// ErrRouteConflict is returned when an accepted route proposal's shipment or
+// vehicle was put on another plan since it was proposed.
+var ErrRouteConflict = errors.New("shipment or vehicle is already on a route plan")
+
+// RouteRepo handles database operations for route plans and their stops.
+type RouteRepo struct {
+	db *pgxpool.Pool
+}
+
+// NewRouteRepo creates a new RouteRepo instance.
+func NewRouteRepo(db *pgxpool.Pool) *RouteRepo {
+	return &RouteRepo{db: db}
+}
+
+// Create inserts a draft route plan with its stops, numbering them in the
+// given order and planning their arrivals from p.PlannedStart.
+func (r *RouteRepo) Create(ctx context.Context, p *models.RoutePlan) error {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	if err := insertRoutePlan(ctx, tx, p); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+// CreateProposed inserts the draft plans of an accepted route proposal, all
+// dated date, in one transaction. It returns ErrRouteConflict when one of
+// their shipments is no longer pending and unrouted, or one of their vehicles
+// is no longer available that day.
+func (r *RouteRepo) CreateProposed(ctx context.Context, tenantID uuid.UUID, date string, plans []*models.RoutePlan) error {
+	var shipmentIDs, vehicleIDs []uuid.UUID
+	for _, p := range plans {
+		if p.VehicleID != nil {
+			vehicleIDs = append(vehicleIDs, *p.VehicleID)
+		}
+		for _, s := range p.Stops {
+			if s.ShipmentID != nil {
+				shipmentIDs = append(shipmentIDs, *s.ShipmentID)
+			}
+		}
+	}
+
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	rows, err := tx.Query(ctx,
+		`SELECT s.id FROM shipments s
+		 WHERE s.tenant_id = $1 AND s.id = ANY($2) AND s.status = 'pending' AND NOT `+routedShipment+`
+		 FOR UPDATE`,
+		tenantID, shipmentIDs,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to lock proposed shipments: %w", err)
+	}
+	n := 0
+	for rows.Next() {
+		n++
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to lock proposed shipments: %w", err)
+	}
+	if n != len(shipmentIDs) {
+		return ErrRouteConflict
+	}
+
+	var free int
+	err = tx.QueryRow(ctx,
+		`SELECT COUNT(*) FROM vehicles v
+		 WHERE v.tenant_id = $1 AND v.id = ANY($2) AND v.status = 'available' AND NOT `+bookedVehicle,
+		tenantID, vehicleIDs, date,
+	).Scan(&free)
+	if err != nil {
+		return fmt.Errorf("failed to check proposed vehicles: %w", err)
+	}
+	if free != len(vehicleIDs) {
+		return ErrRouteConflict
+	}
+
+	for _, p := range plans {
+		p.TenantID, p.PlanDate = tenantID, date
+		if err := insertRoutePlan(ctx, tx, p); err != nil {
+			return err
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+// insertRoutePlan inserts p as a draft with its stops.
+func insertRoutePlan(ctx context.Context, tx pgx.Tx, p *models.RoutePlan) error {
+	p.ID = uuid.New()
+	p.Status = models.RouteDraft
+	models.PlanArrivals(p.PlannedStart, p.Stops)
+
+	err := tx.QueryRow(ctx,
+		`INSERT INTO route_plans (id, tenant_id, plan_date, name, warehouse_id, vehicle_id, status, planned_start, notes, created_by, created_at, updated_at)
+		 VALUES ($1, $2, $3::date, $4, $5, $6, $7, $8, $9, $10, NOW(), NOW())
+		 RETURNING created_at, updated_at`,
+		p.ID, p.TenantID, p.PlanDate, p.Name, p.WarehouseID, p.VehicleID, p.Status, p.PlannedStart, p.Notes, p.CreatedBy,
+	).Scan(&p.CreatedAt, &p.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create route plan: %w", err)
+	}
+	for i := range p.Stops {
+		if err := insertRouteStop(ctx, tx, p.TenantID, p.ID, &p.Stops[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// routedShipment matches a shipment s that a live plan still means to visit.
+const routedShipment = `EXISTS (SELECT 1 FROM route_stops st JOIN route_plans rp ON rp.id = st.route_id AND rp.tenant_id = st.tenant_id
+		 WHERE st.tenant_id = s.tenant_id AND st.shipment_id = s.id AND st.status <> 'skipped' AND rp.status <> 'cancelled')`
+
+// bookedVehicle matches a vehicle v that drives a live plan on the date in $3.
+const bookedVehicle = `EXISTS (SELECT 1 FROM route_plans rp
+		 WHERE rp.tenant_id = v.tenant_id AND rp.vehicle_id = v.id AND rp.plan_date = $3::date AND rp.status <> 'cancelled')`
+
+// UnroutedShipments returns the pending shipments no live plan visits yet, in
+// promised delivery order. Without ids they are the ones promised on date
+// (UTC) or not promised at all; with ids they are those of ids that qualify,
+// whatever their date.
+func (r *RouteRepo) UnroutedShipments(ctx context.Context, tenantID uuid.UUID, date string, ids []uuid.UUID) ([]models.Shipment, error) {
+	rows, err := r.db.Query(ctx,
+		`SELECT s.id, s.tenant_id, s.tracking_number, s.origin, s.destination, s.status, s.carrier, s.weight, s.dimensions, s.estimated_delivery, s.actual_delivery, s.customer_name, s.customer_email, s.notes, s.destination_latitude, s.destination_longitude, s.predicted_delivery, s.predicted_late, s.eta_updated_at, s.warehouse_id, s.origin_address, s.destination_address, s.created_at, s.updated_at
+		 FROM shipments s
+		 WHERE s.tenant_id = $1 AND s.status = 'pending'
+		   AND CASE WHEN $3::uuid[] IS NULL
+		            THEN s.estimated_delivery IS NULL OR (s.estimated_delivery AT TIME ZONE 'UTC')::date = $2::date
+		            ELSE s.id = ANY($3) END
+		   AND NOT `+routedShipment+`
+		 ORDER BY s.estimated_delivery ASC NULLS LAST, s.created_at ASC`,
+		tenantID, date, ids,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list unrouted shipments: %w", err)
+	}
+	defer rows.Close()
+
+	var shipments []models.Shipment
+	for rows.Next() {
+		var s models.Shipment
+		if err := rows.Scan(&s.ID, &s.TenantID, &s.TrackingNumber, &s.Origin, &s.Destination, &s.Status, &s.Carrier, &s.Weight, &s.Dimensions, &s.EstimatedDelivery, &s.ActualDelivery, &s.CustomerName, &s.CustomerEmail, &s.Notes, &s.DestinationLatitude, &s.DestinationLongitude, &s.PredictedDelivery, &s.PredictedLate, &s.ETAUpdatedAt, &s.WarehouseID, &s.OriginAddress, &s.DestinationAddress, &s.CreatedAt, &s.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan shipment: %w", err)
+		}
+		shipments = append(shipments, s)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list unrouted shipments: %w", err)
+	}
+	return shipments, nil
+}
+
+// FreeVehicles returns the available vehicles that drive no live plan on
+// date, optionally only those in ids, in license plate order.
+func (r *RouteRepo) FreeVehicles(ctx context.Context, tenantID uuid.UUID, date string, ids []uuid.UUID) ([]models.Vehicle, error) {
+	rows, err := r.db.Query(ctx,
+		`SELECT v.id, v.tenant_id, v.vehicle_id, v.name, v.type, v.status, v.fuel_level, v.mileage, v.last_service, v.next_service, v.license_plate, v.year, v.capacity_kg, v.created_at, v.updated_at
+		 FROM vehicles v
+		 WHERE v.tenant_id = $1 AND v.status = 'available' AND ($2::uuid[] IS NULL OR v.id = ANY($2))
+		   AND NOT `+bookedVehicle+`
+		 ORDER BY v.license_plate ASC, v.vehicle_id ASC`,
+		tenantID, ids, date,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list free vehicles: %w", err)
+	}
+	defer rows.Close()
+
+	var vehicles []models.Vehicle
+	for rows.Next() {
+		var v models.Vehicle
+		if err := rows.Scan(&v.ID, &v.TenantID, &v.VehicleID, &v.Name, &v.Type, &v.Status, &v.FuelLevel, &v.Mileage, &v.LastService, &v.NextService, &v.LicensePlate, &v.Year, &v.CapacityKg, &v.CreatedAt, &v.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan vehicle: %w", err)
+		}
+		vehicles = append(vehicles, v)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list free vehicles: %w", err)
+	}
+	return vehicles, nil
+}
+
+// Save writes a plan's name, date, warehouse, start and notes and makes its
+// stops match p.Stops in that order: stops with a zero ID are added, stops
+// no longer listed are removed and planned arrivals are recomputed. It
+// returns ErrRouteLocked unless the plan is still editable.
+func (r *RouteRepo) Save(ctx context.Context, tenantID uuid.UUID, p *models.RoutePlan) error {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := lockEditableRoute(ctx, tx, tenantID, p.ID); err != nil {
+		return err
+	}
+	models.PlanArrivals(p.PlannedStart, p.Stops)
+
+	err = tx.QueryRow(ctx,
+		`UPDATE route_plans SET plan_date = $1::date, name = $2, warehouse_id = $3, planned_start = $4, notes = $5, updated_at = NOW()
+		 WHERE id = $6 AND tenant_id = $7
+		 RETURNING updated_at`,
+		p.PlanDate, p.Name, p.WarehouseID, p.PlannedStart, p.Notes, p.ID, tenantID,
+	).Scan(&p.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to update route plan: %w", err)
+	}
+
+	keep := make([]uuid.UUID, 0, len(p.Stops))
+	for _, s := range p.Stops {
+		if s.ID != uuid.Nil {
+			keep = append(keep, s.ID)
+		}
+	}
+	if _, err := tx.Exec(ctx,
+		`DELETE FROM route_stops WHERE tenant_id = $1 AND route_id = $2 AND id <> ALL($3)`,
+		tenantID, p.ID, keep,
+	); err != nil {
+		return fmt.Errorf("failed to remove route stops: %w", err)
+	}
+	for i := range p.Stops {
+		s := &p.Stops[i]
+		if s.ID == uuid.Nil {
+			if err := insertRouteStop(ctx, tx, tenantID, p.ID, s); err != nil {
+				return err
+			}
+			continue
+		}
+		ct, err := tx.Exec(ctx,
+			`UPDATE route_stops SET sequence = $1, type = $2, shipment_id = $3, warehouse_id = $4, zone_id = $5, label = $6, latitude = $7, longitude = $8, radius_meters = $9,
+			        window_start = $10, window_end = $11, service_minutes = $12, planned_arrival = $13, status = $14, updated_at = NOW()
+			 WHERE id = $15 AND route_id = $16 AND tenant_id = $17`,
+			s.Sequence, s.Type, s.ShipmentID, s.WarehouseID, s.ZoneID, s.Label, s.Latitude, s.Longitude, s.RadiusMeters,
+			s.WindowStart, s.WindowEnd, s.ServiceMinutes, s.PlannedArrival, s.Status, s.ID, p.ID, tenantID,
+		)
+		if err != nil {
+			return fmt.Errorf("failed to update route stop: %w", err)
+		}
+		if ct.RowsAffected() == 0 {
+			return ErrRouteNotFound
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+// lockEditableRoute locks a plan for the rest of tx and returns its status,
+// failing unless the plan exists and can still be edited.
+func lockEditableRoute(ctx context.Context, tx pgx.Tx, tenantID, id uuid.UUID) (string, error) {
+	var status string
+	err := tx.QueryRow(ctx,
+		`SELECT status FROM route_plans WHERE id = $1 AND tenant_id = $2 FOR UPDATE`,
+		id, tenantID,
+	).Scan(&status)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return "", ErrRouteNotFound
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to lock route plan: %w", err)
+	}
+	if !models.IsRouteEditable(status) {
+		return "", fmt.Errorf("%w: plan is %s", ErrRouteLocked, status)
+	}
+	return status, nil
+}
+
+func insertRouteStop(ctx context.Context, tx pgx.Tx, tenantID, routeID uuid.UUID, s *models.RouteStop) error {
+	s.ID = uuid.New()
+	s.TenantID = tenantID
+	s.RouteID = routeID
+	if s.Status == "" {
+		s.Status = models.StopPending
+	}
+	err := tx.QueryRow(ctx,
+		`INSERT INTO route_stops (id, tenant_id, route_id, sequence, type, shipment_id, warehouse_id, zone_id, label, latitude, longitude, radius_meters, window_start, window_end, service_minutes, planned_arrival, status, created_at, updated_at)
+		 VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, NOW(), NOW())
+		 RETURNING created_at, updated_at`,
+		s.ID, tenantID, routeID, s.Sequence, s.Type, s.ShipmentID, s.WarehouseID, s.ZoneID, s.Label, s.Latitude, s.Longitude, s.RadiusMeters,
+		s.WindowStart, s.WindowEnd, s.ServiceMinutes, s.PlannedArrival, s.Status,
+	).Scan(&s.CreatedAt, &s.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create route stop: %w", err)
+	}
+	return nil
+}
+
+// GetByID retrieves a route plan with its stops within a tenant.
+func (r *RouteRepo) GetByID(ctx context.Context, tenantID, id uuid.UUID) (*models.RoutePlan, error) {
+	p := &models.RoutePlan{}
+	err := r.db.QueryRow(ctx,
+		`SELECT id, tenant_id, to_char(plan_date, 'YYYY-MM-DD'), name, warehouse_id, driver_id, vehicle_id, shift_id, status, planned_start, notes, created_by, created_at, updated_at
+		 FROM route_plans WHERE id = $1 AND tenant_id = $2`,
+		id, tenantID,
+	).Scan(&p.ID, &p.TenantID, &p.PlanDate, &p.Name, &p.WarehouseID, &p.DriverID, &p.VehicleID, &p.ShiftID, &p.Status, &p.PlannedStart, &p.Notes, &p.CreatedBy, &p.CreatedAt, &p.UpdatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrRouteNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get route plan by id: %w", err)
+	}
+
+	stops, err := r.listStops(ctx, tenantID, []uuid.UUID{p.ID})
+	if err != nil {
+		return nil, err
+	}
+	p.Stops = stops[p.ID]
+	return p, nil
+}
+
+// GetByStop retrieves the route plan a stop belongs to.
+func (r *RouteRepo) GetByStop(ctx context.Context, tenantID, stopID uuid.UUID) (*models.RoutePlan, error) {
+	var routeID uuid.UUID
+	err := r.db.QueryRow(ctx,
+		`SELECT route_id FROM route_stops WHERE id = $1 AND tenant_id = $2`,
+		stopID, tenantID,
+	).Scan(&routeID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrRouteNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up route stop: %w", err)
+	}
+	return r.GetByID(ctx, tenantID, routeID)
+}
+
+// ListByDate returns the route plans of a day, optionally only one driver's,
+// with their stops, in planned start order.
+func (r *RouteRepo) ListByDate(ctx context.Context, tenantID uuid.UUID, date string, driverID *uuid.UUID) ([]models.RoutePlan, error) {
+	rows, err := r.db.Query(ctx,
+		`SELECT id, tenant_id, to_char(plan_date, 'YYYY-MM-DD'), name, warehouse_id, driver_id, vehicle_id, shift_id, status, planned_start, notes, created_by, created_at, updated_at
+		 FROM route_plans WHERE tenant_id = $1 AND plan_date = $2::date AND ($3::uuid IS NULL OR driver_id = $3)
+		 ORDER BY planned_start ASC, name ASC`,
+		tenantID, date, driverID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list route plans: %w", err)
+	}
+	defer rows.Close()
+
+	var plans []models.RoutePlan
+	var ids []uuid.UUID
+	for rows.Next() {
+		var p models.RoutePlan
+		if err := rows.Scan(&p.ID, &p.TenantID, &p.PlanDate, &p.Name, &p.WarehouseID, &p.DriverID, &p.VehicleID, &p.ShiftID, &p.Status, &p.PlannedStart, &p.Notes, &p.CreatedBy, &p.CreatedAt, &p.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan route plan: %w", err)
+		}
+		plans = append(plans, p)
+		ids = append(ids, p.ID)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list route plans: %w", err)
+	}
+	if len(plans) == 0 {
+		return plans, nil
+	}
+
+	stops, err := r.listStops(ctx, tenantID, ids)
+	if err != nil {
+		return nil, err
+	}
+	for i := range plans {
+		plans[i].Stops = stops[plans[i].ID]
+	}
+	return plans, nil
+}
+
+func (r *RouteRepo) listStops(ctx context.Context, tenantID uuid.UUID, routeIDs []uuid.UUID) (map[uuid.UUID][]models.RouteStop, error) {
+	rows, err := r.db.Query(ctx,
+		`SELECT id, tenant_id, route_id, sequence, type, shipment_id, warehouse_id, zone_id, label, latitude, longitude, radius_meters, window_start, window_end, service_minutes, planned_arrival, actual_arrival, actual_departure, status, created_at, updated_at
+		 FROM route_stops WHERE tenant_id = $1 AND route_id = ANY($2)
+		 ORDER BY route_id, sequence ASC`,
+		tenantID, routeIDs,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list route stops: %w", err)
+	}
+	defer rows.Close()
+
+	stops := make(map[uuid.UUID][]models.RouteStop, len(routeIDs))
+	for rows.Next() {
+		var s models.RouteStop
+		if err := rows.Scan(&s.ID, &s.TenantID, &s.RouteID, &s.Sequence, &s.Type, &s.ShipmentID, &s.WarehouseID, &s.ZoneID, &s.Label, &s.Latitude, &s.Longitude, &s.RadiusMeters, &s.WindowStart, &s.WindowEnd, &s.ServiceMinutes, &s.PlannedArrival, &s.ActualArrival, &s.ActualDeparture, &s.Status, &s.CreatedAt, &s.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan route stop: %w", err)
+		}
+		stops[s.RouteID] = append(stops[s.RouteID], s)
+	}
+	return stops, nil
+}
+
+// Delete removes a draft route plan. Plans that were assigned are kept for
+// their history and return ErrRouteLocked.
+func (r *RouteRepo) Delete(ctx context.Context, tenantID, id uuid.UUID) error {
+	ct, err := r.db.Exec(ctx,
+		`DELETE FROM route_plans WHERE id = $1 AND tenant_id = $2 AND status = 'draft'`,
+		id, tenantID,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to delete route plan: %w", err)
+	}
+	if ct.RowsAffected() == 0 {
+		if _, err := r.GetByID(ctx, tenantID, id); err != nil {
+			return err
+		}
+		return ErrRouteLocked
+	}
+	return nil
+}
+
+// Assign gives an editable plan to a driver and, optionally, a vehicle. A
+// driver can hold one assigned or running plan per day.
+func (r *RouteRepo) Assign(ctx context.Context, tenantID, id, driverID uuid.UUID, vehicleID *uuid.UUID) error {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := lockEditableRoute(ctx, tx, tenantID, id); err != nil {
+		return err
+	}
+
+	var driverOK, vehicleOK, booked bool
+	err = tx.QueryRow(ctx,
+		`SELECT EXISTS (SELECT 1 FROM drivers WHERE id = $1 AND tenant_id = $2),
+		        $3::uuid IS NULL OR EXISTS (SELECT 1 FROM vehicles WHERE id = $3 AND tenant_id = $2),
+		        EXISTS (SELECT 1 FROM route_plans o JOIN route_plans p ON p.id = $4 AND p.tenant_id = o.tenant_id
+		                WHERE o.tenant_id = $2 AND o.driver_id = $1 AND o.id <> p.id AND o.plan_date = p.plan_date
+		                  AND o.status IN ('assigned', 'in_progress'))`,
+		driverID, tenantID, vehicleID, id,
+	).Scan(&driverOK, &vehicleOK, &booked)
+	if err != nil {
+		return fmt.Errorf("failed to check route assignee: %w", err)
+	}
+	if !driverOK || !vehicleOK {
+		return ErrAssigneeNotFound
+	}
+	if booked {
+		return ErrDriverBooked
+	}
+
+	if _, err := tx.Exec(ctx,
+		`UPDATE route_plans SET driver_id = $1, vehicle_id = $2, status = 'assigned', updated_at = NOW()
+		 WHERE id = $3 AND tenant_id = $4`,
+		driverID, vehicleID, id, tenantID,
+	); err != nil {
+		return fmt.Errorf("failed to assign route plan: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+// GetActiveForDriver returns the driver's assigned or running plan for date,
+// or nil when there is none.
+func (r *RouteRepo) GetActiveForDriver(ctx context.Context, tenantID, driverID uuid.UUID, date string) (*models.RoutePlan, error) {
+	var id uuid.UUID
+	err := r.db.QueryRow(ctx,
+		`SELECT id FROM route_plans
+		 WHERE tenant_id = $1 AND driver_id = $2 AND plan_date = $3::date AND status IN ('assigned', 'in_progress')
+		 ORDER BY planned_start ASC LIMIT 1`,
+		tenantID, driverID, date,
+	).Scan(&id)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get driver route plan: %w", err)
+	}
+	return r.GetByID(ctx, tenantID, id)
+}
+
+// Start links a plan to the shift driving it and marks it in progress.
+func (r *RouteRepo) Start(ctx context.Context, tenantID, id, shiftID uuid.UUID) error {
+	_, err := r.db.Exec(ctx,
+		`UPDATE route_plans SET shift_id = $1, status = 'in_progress', updated_at = NOW()
+		 WHERE id = $2 AND tenant_id = $3 AND status IN ('assigned', 'in_progress')`,
+		shiftID, id, tenantID,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to start route plan: %w", err)
+	}
+	return nil
+}
+
+// RecordArrival marks a pending stop arrived at at. It reports whether the
+// stop was still pending.
+func (r *RouteRepo) RecordArrival(ctx context.Context, tenantID, stopID uuid.UUID, at time.Time) (bool, error) {
+	ct, err := r.db.Exec(ctx,
+		`UPDATE route_stops SET actual_arrival = $1, status = 'arrived', updated_at = NOW()
+		 WHERE id = $2 AND tenant_id = $3 AND status = 'pending'`,
+		at, stopID, tenantID,
+	)
+	if err != nil {
+		return false, fmt.Errorf("failed to record stop arrival: %w", err)
+	}
+	return ct.RowsAffected() > 0, nil
+}
+
+// RecordDeparture marks an arrived stop completed at at, completing its plan
+// when no stop is left to visit. It reports whether the stop was arrived.
+func (r *RouteRepo) RecordDeparture(ctx context.Context, tenantID, stopID uuid.UUID, at time.Time) (bool, error) {
+	return r.closeStop(ctx, tenantID, stopID,
+		`UPDATE route_stops SET actual_departure = $1, status = 'completed', updated_at = NOW()
+		 WHERE id = $2 AND tenant_id = $3 AND status = 'arrived'
+		 RETURNING route_id`,
+		&at,
+	)
+}
+
+// SkipStop marks a pending stop skipped, completing its plan when no stop is
+// left to visit. It reports whether the stop was pending.
+func (r *RouteRepo) SkipStop(ctx context.Context, tenantID, stopID uuid.UUID) (bool, error) {
+	return r.closeStop(ctx, tenantID, stopID,
+		`UPDATE route_stops SET status = 'skipped', planned_arrival = NULL, updated_at = NOW()
+		 WHERE id = $2 AND tenant_id = $3 AND status = 'pending' AND $1::timestamptz IS NULL
+		 RETURNING route_id`,
+		nil,
+	)
+}
+
+func (r *RouteRepo) closeStop(ctx context.Context, tenantID, stopID uuid.UUID, query string, at *time.Time) (bool, error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return false, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	var routeID uuid.UUID
+	err = tx.QueryRow(ctx, query, at, stopID, tenantID).Scan(&routeID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to close route stop: %w", err)
+	}
+	if _, err := tx.Exec(ctx,
+		`UPDATE route_plans p SET status = 'completed', updated_at = NOW()
+		 WHERE p.id = $1 AND p.tenant_id = $2 AND p.status = 'in_progress'
+		   AND NOT EXISTS (SELECT 1 FROM route_stops s WHERE s.route_id = p.id AND s.tenant_id = p.tenant_id AND s.status IN ('pending', 'arrived'))`,
+		routeID, tenantID,
+	); err != nil {
+		return false, fmt.Errorf("failed to complete route plan: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return false, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return true, nil
+}
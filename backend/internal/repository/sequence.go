@@ -0,0 +1,44 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// rowQuerier is the QueryRow method shared by *pgxpool.Pool and pgx.Tx, so a
+// helper can run either on its own or inside the caller's transaction.
+type rowQuerier interface {
+	QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
+}
+
+// rowsQuerier is the same for Query.
+type rowsQuerier interface {
+	Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error)
+}
+
+// execer is the same for Exec.
+type execer interface {
+	Exec(ctx context.Context, sql string, args ...any) (pgconn.CommandTag, error)
+}
+
+// nextSequence advances the tenant's counter called name and returns its new
+// value; the first call returns 1. The row lock taken by the upsert
+// serializes concurrent callers, and inside a transaction the number is only
+// spent if that transaction commits.
+func nextSequence(ctx context.Context, q rowQuerier, tenantID uuid.UUID, name string) (int64, error) {
+	var n int64
+	err := q.QueryRow(ctx,
+		`INSERT INTO tenant_sequences (tenant_id, name, last_value) VALUES ($1, $2, 1)
+		 ON CONFLICT (tenant_id, name) DO UPDATE SET last_value = tenant_sequences.last_value + 1
+		 RETURNING last_value`,
+		tenantID, name,
+	).Scan(&n)
+	if err != nil {
+		return 0, fmt.Errorf("failed to advance %s sequence: %w", name, err)
+	}
+	return n, nil
+}
@@ -71,3 +71,81 @@ func (r *SettingRepo) Set(ctx context.Context, s *models.Setting) error {
 	}
 	return nil
 }
+
+// TrackingFormat returns the tenant's tracking number format, falling back to
+// the defaults for any part that is not set. Stored values are validated on
+// write, so an unparsable one here is treated as unset.
+func (r *SettingRepo) TrackingFormat(ctx context.Context, tenantID uuid.UUID) (models.TrackingFormat, error) {
+	f := models.DefaultTrackingFormat()
+	rows, err := r.db.Query(ctx,
+		`SELECT key, value FROM settings WHERE tenant_id = $1 AND key = ANY($2) AND value IS NOT NULL`,
+		tenantID, []string{models.SettingTrackingPrefix, models.SettingTrackingDigits},
+	)
+	if err != nil {
+		return f, fmt.Errorf("failed to get tracking number settings: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var key, value string
+		if err := rows.Scan(&key, &value); err != nil {
+			return f, fmt.Errorf("failed to scan setting: %w", err)
+		}
+		switch key {
+		case models.SettingTrackingPrefix:
+			if v, err := models.ParseTrackingPrefix(value); err == nil {
+				f.Prefix = v
+			}
+		case models.SettingTrackingDigits:
+			if v, err := models.ParseTrackingDigits(value); err == nil {
+				f.Digits = v
+			}
+		}
+	}
+	return f, rows.Err()
+}
+
+// InvoiceSettings returns how the tenant's invoices are numbered, taxed and
+// dated, falling back to the defaults for any part that is not set. As with
+// tracking numbers, an unparsable stored value is treated as unset.
+func (r *SettingRepo) InvoiceSettings(ctx context.Context, tenantID uuid.UUID) (models.InvoiceSettings, error) {
+	s := models.DefaultInvoiceSettings()
+	rows, err := r.db.Query(ctx,
+		`SELECT key, value FROM settings WHERE tenant_id = $1 AND key = ANY($2) AND value IS NOT NULL`,
+		tenantID, []string{models.SettingInvoicePrefix, models.SettingCreditNotePrefix, models.SettingInvoiceTaxes, models.SettingPaymentTermsDays, models.SettingInvoiceCurrency},
+	)
+	if err != nil {
+		return s, fmt.Errorf("failed to get invoice settings: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var key, value string
+		if err := rows.Scan(&key, &value); err != nil {
+			return s, fmt.Errorf("failed to scan setting: %w", err)
+		}
+		switch key {
+		case models.SettingInvoicePrefix:
+			if v, err := models.ParseInvoicePrefix(value); err == nil {
+				s.Prefix = v
+			}
+		case models.SettingCreditNotePrefix:
+			if v, err := models.ParseInvoicePrefix(value); err == nil {
+				s.CreditNotePrefix = v
+			}
+		case models.SettingInvoiceTaxes:
+			if v, err := models.ParseInvoiceTaxes(value); err == nil {
+				s.Taxes = v
+			}
+		case models.SettingPaymentTermsDays:
+			if v, err := models.ParsePaymentTermsDays(value); err == nil {
+				s.PaymentTermsDays = v
+			}
+		case models.SettingInvoiceCurrency:
+			if v, err := models.ParseInvoiceCurrency(value); err == nil {
+				s.Currency = v
+			}
+		}
+	}
+	return s, rows.Err()
+}
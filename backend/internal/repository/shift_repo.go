@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"time"
 
+	"cargomax-api/internal/database"
 	"cargomax-api/internal/models"
 
 	"github.com/google/uuid"
@@ -76,8 +77,10 @@ func (r *ShiftRepo) GetActiveByDriver(ctx context.Context, tenantID, driverID uu
 	return s, nil
 }
 
+// GetAllActive returns active shifts across every tenant for the alert worker.
+// It runs on the privileged connection path, bypassing row-level security.
 func (r *ShiftRepo) GetAllActive(ctx context.Context) ([]models.Shift, error) {
-	rows, err := r.db.Query(ctx,
+	rows, err := r.db.Query(database.Privileged(ctx),
 		`SELECT id, tenant_id, driver_id, truck_id, started_at, ended_at, status, total_km, created_at, updated_at
 		 FROM shifts WHERE status = 'active'`,
 	)
@@ -97,6 +100,35 @@ func (r *ShiftRepo) GetAllActive(ctx context.Context) ([]models.Shift, error) {
 	return shifts, nil
 }
 
+// GetActiveByTenant returns a tenant's active shifts joined with the driver
+// name and truck plate, newest first.
+func (r *ShiftRepo) GetActiveByTenant(ctx context.Context, tenantID uuid.UUID) ([]models.ActiveShift, error) {
+	rows, err := r.db.Query(ctx,
+		`SELECT s.id, s.tenant_id, s.driver_id, s.truck_id, s.started_at, s.ended_at, s.status, s.total_km, s.created_at, s.updated_at,
+		        CONCAT_WS(' ', d.first_name, d.last_name), COALESCE(v.license_plate, '')
+		 FROM shifts s
+		 LEFT JOIN drivers d ON d.id = s.driver_id AND d.tenant_id = s.tenant_id
+		 LEFT JOIN vehicles v ON v.id = s.truck_id AND v.tenant_id = s.tenant_id
+		 WHERE s.tenant_id = $1 AND s.status = 'active'
+		 ORDER BY s.started_at DESC`,
+		tenantID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query active shifts: %w", err)
+	}
+	defer rows.Close()
+
+	var shifts []models.ActiveShift
+	for rows.Next() {
+		var s models.ActiveShift
+		if err := rows.Scan(&s.ID, &s.TenantID, &s.DriverID, &s.TruckID, &s.StartedAt, &s.EndedAt, &s.Status, &s.TotalKm, &s.CreatedAt, &s.UpdatedAt, &s.DriverName, &s.TruckPlate); err != nil {
+			return nil, fmt.Errorf("failed to scan active shift: %w", err)
+		}
+		shifts = append(shifts, s)
+	}
+	return shifts, nil
+}
+
 func (r *ShiftRepo) IsTruckInUse(ctx context.Context, tenantID, truckID uuid.UUID) (bool, error) {
 	var count int
 	err := r.db.QueryRow(ctx,
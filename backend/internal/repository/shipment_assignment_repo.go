@@ -0,0 +1,255 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"cargomax-api/internal/models"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+// ErrShipmentClosed is returned when assigning a shipment that has already
+// been delivered, cancelled or returned.
+var ErrShipmentClosed = errors.New("shipment is closed")
+
+// ErrAssigneeNotFound is returned when the driver or vehicle of an assignment
+// does not exist within the tenant.
+var ErrAssigneeNotFound = errors.New("driver or vehicle not found")
+
+// Assign records a as the shipment's open assignment, closing the previous one.
+// When the driver is on an active shift and no vehicle is given (or it is the
+// shift's truck), the assignment is attached to that shift and truck.
+func (r *ShipmentRepo) Assign(ctx context.Context, tenantID uuid.UUID, a *models.ShipmentAssignment) error {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	var status string
+	err = tx.QueryRow(ctx,
+		`SELECT status FROM shipments WHERE id = $1 AND tenant_id = $2 FOR UPDATE`,
+		a.ShipmentID, tenantID,
+	).Scan(&status)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return ErrShipmentNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("failed to lock shipment: %w", err)
+	}
+	if !models.IsShipmentOpen(status) {
+		return fmt.Errorf("%w: status %s", ErrShipmentClosed, status)
+	}
+
+	if a.DriverID != nil {
+		var shiftID, truckID *uuid.UUID
+		err := tx.QueryRow(ctx,
+			`SELECT s.id, s.truck_id FROM drivers d
+			 LEFT JOIN shifts s ON s.driver_id = d.id AND s.tenant_id = d.tenant_id AND s.status = 'active'
+			 WHERE d.id = $1 AND d.tenant_id = $2
+			 ORDER BY s.started_at DESC NULLS LAST LIMIT 1`,
+			*a.DriverID, tenantID,
+		).Scan(&shiftID, &truckID)
+		if errors.Is(err, pgx.ErrNoRows) {
+			return ErrAssigneeNotFound
+		}
+		if err != nil {
+			return fmt.Errorf("failed to look up driver: %w", err)
+		}
+		if shiftID != nil && (a.VehicleID == nil || *a.VehicleID == *truckID) {
+			a.ShiftID = shiftID
+			a.VehicleID = truckID
+		}
+	}
+	if a.VehicleID != nil {
+		var exists bool
+		if err := tx.QueryRow(ctx,
+			`SELECT EXISTS (SELECT 1 FROM vehicles WHERE id = $1 AND tenant_id = $2)`,
+			*a.VehicleID, tenantID,
+		).Scan(&exists); err != nil {
+			return fmt.Errorf("failed to look up vehicle: %w", err)
+		}
+		if !exists {
+			return ErrAssigneeNotFound
+		}
+	}
+
+	a.ID = uuid.New()
+	a.TenantID = tenantID
+	a.AssignedAt = time.Now()
+	a.UnassignedAt = nil
+	if _, err := tx.Exec(ctx,
+		`UPDATE shipment_assignments SET unassigned_at = $1
+		 WHERE tenant_id = $2 AND shipment_id = $3 AND unassigned_at IS NULL`,
+		a.AssignedAt, tenantID, a.ShipmentID,
+	); err != nil {
+		return fmt.Errorf("failed to close previous assignment: %w", err)
+	}
+	err = tx.QueryRow(ctx,
+		`INSERT INTO shipment_assignments (id, tenant_id, shipment_id, driver_id, vehicle_id, shift_id, assigned_by, assigned_at, created_at)
+		 VALUES ($1, $2, $3, $4, $5, $6, $7, $8, NOW())
+		 RETURNING created_at`,
+		a.ID, a.TenantID, a.ShipmentID, a.DriverID, a.VehicleID, a.ShiftID, a.AssignedBy, a.AssignedAt,
+	).Scan(&a.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create assignment: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+// Unassign closes the shipment's open assignment, if any.
+func (r *ShipmentRepo) Unassign(ctx context.Context, tenantID, shipmentID uuid.UUID) error {
+	var exists bool
+	if err := r.db.QueryRow(ctx,
+		`SELECT EXISTS (SELECT 1 FROM shipments WHERE id = $1 AND tenant_id = $2)`,
+		shipmentID, tenantID,
+	).Scan(&exists); err != nil {
+		return fmt.Errorf("failed to look up shipment: %w", err)
+	}
+	if !exists {
+		return ErrShipmentNotFound
+	}
+
+	_, err := r.db.Exec(ctx,
+		`UPDATE shipment_assignments SET unassigned_at = NOW()
+		 WHERE tenant_id = $1 AND shipment_id = $2 AND unassigned_at IS NULL`,
+		tenantID, shipmentID,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to unassign shipment: %w", err)
+	}
+	return nil
+}
+
+// AttachToShift moves every open assignment of the driver's still-open
+// shipments onto a newly started shift and its truck. The previous rows are
+// closed so the history shows each shift the shipment rode on. It returns the
+// number of shipments attached.
+func (r *ShipmentRepo) AttachToShift(ctx context.Context, tenantID, driverID, shiftID, truckID uuid.UUID) (int, error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	now := time.Now()
+	rows, err := tx.Query(ctx,
+		`UPDATE shipment_assignments a SET unassigned_at = $1
+		 FROM shipments s
+		 WHERE a.tenant_id = $2 AND a.driver_id = $3 AND a.unassigned_at IS NULL
+		   AND s.id = a.shipment_id AND s.tenant_id = a.tenant_id
+		   AND s.status NOT IN ('delivered', 'cancelled', 'returned')
+		   AND a.shift_id IS DISTINCT FROM $4
+		 RETURNING a.shipment_id, a.assigned_by`,
+		now, tenantID, driverID, shiftID,
+	)
+	if err != nil {
+		return 0, fmt.Errorf("failed to close assignments: %w", err)
+	}
+	type reopen struct {
+		shipmentID uuid.UUID
+		assignedBy *uuid.UUID
+	}
+	var moved []reopen
+	for rows.Next() {
+		var m reopen
+		if err := rows.Scan(&m.shipmentID, &m.assignedBy); err != nil {
+			rows.Close()
+			return 0, fmt.Errorf("failed to scan assignment: %w", err)
+		}
+		moved = append(moved, m)
+	}
+	rows.Close()
+
+	for _, m := range moved {
+		if _, err := tx.Exec(ctx,
+			`INSERT INTO shipment_assignments (id, tenant_id, shipment_id, driver_id, vehicle_id, shift_id, assigned_by, assigned_at, created_at)
+			 VALUES ($1, $2, $3, $4, $5, $6, $7, $8, NOW())`,
+			uuid.New(), tenantID, m.shipmentID, driverID, truckID, shiftID, m.assignedBy, now,
+		); err != nil {
+			return 0, fmt.Errorf("failed to attach assignment to shift: %w", err)
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return 0, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return len(moved), nil
+}
+
+// ListAssignmentsByShipments returns the assignment history of every shipment
+// in ids within a tenant in a single query, keyed by shipment ID and ordered
+// oldest first. The open assignment, if any, is last.
+func (r *ShipmentRepo) ListAssignmentsByShipments(ctx context.Context, tenantID uuid.UUID, ids []uuid.UUID) (map[uuid.UUID][]models.ShipmentAssignment, error) {
+	rows, err := r.db.Query(ctx,
+		`SELECT id, tenant_id, shipment_id, driver_id, vehicle_id, shift_id, assigned_by, assigned_at, unassigned_at, created_at
+		 FROM shipment_assignments WHERE tenant_id = $1 AND shipment_id = ANY($2)
+		 ORDER BY assigned_at ASC, unassigned_at ASC NULLS LAST`,
+		tenantID, ids,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list shipment assignments: %w", err)
+	}
+	defer rows.Close()
+
+	assignments := make(map[uuid.UUID][]models.ShipmentAssignment, len(ids))
+	for rows.Next() {
+		var a models.ShipmentAssignment
+		if err := rows.Scan(&a.ID, &a.TenantID, &a.ShipmentID, &a.DriverID, &a.VehicleID, &a.ShiftID, &a.AssignedBy, &a.AssignedAt, &a.UnassignedAt, &a.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan shipment assignment: %w", err)
+		}
+		assignments[a.ShipmentID] = append(assignments[a.ShipmentID], a)
+	}
+	return assignments, nil
+}
+
+// ListAssignedToDriver returns the open shipments currently assigned to a
+// driver, oldest assignment first.
+func (r *ShipmentRepo) ListAssignedToDriver(ctx context.Context, tenantID, driverID uuid.UUID) ([]models.Shipment, error) {
+	rows, err := r.db.Query(ctx,
+		`SELECT s.id, s.tenant_id, s.tracking_number, s.origin, s.destination, s.status, s.carrier, s.weight, s.dimensions, s.estimated_delivery, s.actual_delivery, s.customer_name, s.customer_email, s.notes, s.destination_latitude, s.destination_longitude, s.predicted_delivery, s.predicted_late, s.eta_updated_at, s.warehouse_id, s.origin_address, s.destination_address, s.created_at, s.updated_at
+		 FROM shipment_assignments a
+		 JOIN shipments s ON s.id = a.shipment_id AND s.tenant_id = a.tenant_id
+		 WHERE a.tenant_id = $1 AND a.driver_id = $2 AND a.unassigned_at IS NULL
+		   AND s.status NOT IN ('delivered', 'cancelled', 'returned')
+		 ORDER BY a.assigned_at ASC`,
+		tenantID, driverID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list driver shipments: %w", err)
+	}
+	defer rows.Close()
+
+	var shipments []models.Shipment
+	for rows.Next() {
+		var s models.Shipment
+		if err := rows.Scan(&s.ID, &s.TenantID, &s.TrackingNumber, &s.Origin, &s.Destination, &s.Status, &s.Carrier, &s.Weight, &s.Dimensions, &s.EstimatedDelivery, &s.ActualDelivery, &s.CustomerName, &s.CustomerEmail, &s.Notes, &s.DestinationLatitude, &s.DestinationLongitude, &s.PredictedDelivery, &s.PredictedLate, &s.ETAUpdatedAt, &s.WarehouseID, &s.OriginAddress, &s.DestinationAddress, &s.CreatedAt, &s.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan shipment: %w", err)
+		}
+		shipments = append(shipments, s)
+	}
+	return shipments, nil
+}
+
+// IsAssignedTo reports whether the shipment's open assignment belongs to the
+// driver.
+func (r *ShipmentRepo) IsAssignedTo(ctx context.Context, tenantID, shipmentID, driverID uuid.UUID) (bool, error) {
+	var assigned bool
+	err := r.db.QueryRow(ctx,
+		`SELECT EXISTS (SELECT 1 FROM shipment_assignments
+		 WHERE tenant_id = $1 AND shipment_id = $2 AND driver_id = $3 AND unassigned_at IS NULL)`,
+		tenantID, shipmentID, driverID,
+	).Scan(&assigned)
+	if err != nil {
+		return false, fmt.Errorf("failed to check shipment assignment: %w", err)
+	}
+	return assigned, nil
+}
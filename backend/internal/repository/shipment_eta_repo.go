@@ -0,0 +1,210 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"cargomax-api/internal/models"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+// ListETACandidates returns the shipments riding on a shift that are on the
+// road and have destination coordinates, i.e. those the ETA worker can predict.
+func (r *ShipmentRepo) ListETACandidates(ctx context.Context, tenantID, shiftID uuid.UUID) ([]models.Shipment, error) {
+	rows, err := r.db.Query(ctx,
+		`SELECT s.id, s.tenant_id, s.tracking_number, s.origin, s.destination, s.status, s.carrier, s.weight, s.dimensions, s.estimated_delivery, s.actual_delivery, s.customer_name, s.customer_email, s.notes, s.destination_latitude, s.destination_longitude, s.predicted_delivery, s.predicted_late, s.eta_updated_at, s.warehouse_id, s.origin_address, s.destination_address, s.created_at, s.updated_at
+		 FROM shipment_assignments a
+		 JOIN shipments s ON s.id = a.shipment_id AND s.tenant_id = a.tenant_id
+		 WHERE a.tenant_id = $1 AND a.shift_id = $2 AND a.unassigned_at IS NULL
+		   AND s.status IN ('picked_up', 'in_transit', 'out_for_delivery', 'delayed')
+		   AND s.destination_latitude IS NOT NULL AND s.destination_longitude IS NOT NULL`,
+		tenantID, shiftID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list eta candidates: %w", err)
+	}
+	defer rows.Close()
+
+	var shipments []models.Shipment
+	for rows.Next() {
+		var s models.Shipment
+		if err := rows.Scan(&s.ID, &s.TenantID, &s.TrackingNumber, &s.Origin, &s.Destination, &s.Status, &s.Carrier, &s.Weight, &s.Dimensions, &s.EstimatedDelivery, &s.ActualDelivery, &s.CustomerName, &s.CustomerEmail, &s.Notes, &s.DestinationLatitude, &s.DestinationLongitude, &s.PredictedDelivery, &s.PredictedLate, &s.ETAUpdatedAt, &s.WarehouseID, &s.OriginAddress, &s.DestinationAddress, &s.CreatedAt, &s.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan shipment: %w", err)
+		}
+		shipments = append(shipments, s)
+	}
+	return shipments, nil
+}
+
+// LaneSpeed returns the historical average speed between origin and
+// destination, or nil when the lane has no deliveries on record.
+func (r *ShipmentRepo) LaneSpeed(ctx context.Context, tenantID uuid.UUID, origin, destination *string) (*float64, error) {
+	if origin == nil || destination == nil {
+		return nil, nil
+	}
+	var speed float64
+	err := r.db.QueryRow(ctx,
+		`SELECT avg_speed_kmh FROM lane_speeds
+		 WHERE tenant_id = $1 AND origin = LOWER(BTRIM($2)) AND destination = LOWER(BTRIM($3))`,
+		tenantID, *origin, *destination,
+	).Scan(&speed)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get lane speed: %w", err)
+	}
+	return &speed, nil
+}
+
+// RecordETA stores a new prediction for a shipment. eta_updated_at is always
+// bumped; the prediction itself is only replaced, and a history row written,
+// when models.ETAChanged says it moved. It reports whether it did, so the
+// caller knows to push the change. Closed shipments are left alone.
+func (r *ShipmentRepo) RecordETA(ctx context.Context, tenantID uuid.UUID, eta *models.ShipmentETA) (bool, error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return false, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	current := models.Shipment{ID: eta.ShipmentID}
+	err = tx.QueryRow(ctx,
+		`SELECT status, predicted_delivery, predicted_late FROM shipments WHERE id = $1 AND tenant_id = $2 FOR UPDATE`,
+		eta.ShipmentID, tenantID,
+	).Scan(&current.Status, &current.PredictedDelivery, &current.PredictedLate)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return false, ErrShipmentNotFound
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to lock shipment: %w", err)
+	}
+	if !models.IsShipmentOpen(current.Status) {
+		return false, nil
+	}
+
+	if eta.ComputedAt.IsZero() {
+		eta.ComputedAt = time.Now()
+	}
+	eta.TenantID = tenantID
+	changed := models.ETAChanged(&current, *eta)
+	if !changed {
+		if _, err := tx.Exec(ctx,
+			`UPDATE shipments SET eta_updated_at = $1 WHERE id = $2 AND tenant_id = $3`,
+			eta.ComputedAt, eta.ShipmentID, tenantID,
+		); err != nil {
+			return false, fmt.Errorf("failed to touch shipment eta: %w", err)
+		}
+	} else {
+		if _, err := tx.Exec(ctx,
+			`UPDATE shipments SET predicted_delivery = $1, predicted_late = $2, eta_updated_at = $3
+			 WHERE id = $4 AND tenant_id = $5`,
+			eta.PredictedDelivery, eta.PredictedLate, eta.ComputedAt, eta.ShipmentID, tenantID,
+		); err != nil {
+			return false, fmt.Errorf("failed to update shipment eta: %w", err)
+		}
+		if err := insertShipmentETA(ctx, tx, tenantID, eta); err != nil {
+			return false, err
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return false, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return changed, nil
+}
+
+func insertShipmentETA(ctx context.Context, tx pgx.Tx, tenantID uuid.UUID, eta *models.ShipmentETA) error {
+	eta.ID = uuid.New()
+	if _, err := tx.Exec(ctx,
+		`INSERT INTO shipment_etas (id, tenant_id, shipment_id, shift_id, predicted_delivery, promised_delivery, predicted_late, remaining_km, speed_kmh, computed_at)
+		 VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)`,
+		eta.ID, tenantID, eta.ShipmentID, eta.ShiftID, eta.PredictedDelivery, eta.PromisedDelivery, eta.PredictedLate, eta.RemainingKm, eta.SpeedKmh, eta.ComputedAt,
+	); err != nil {
+		return fmt.Errorf("failed to record shipment eta: %w", err)
+	}
+	return nil
+}
+
+// ListETAsByShipments returns the prediction history of every shipment in ids
+// within a tenant in a single query, keyed by shipment ID, oldest first.
+func (r *ShipmentRepo) ListETAsByShipments(ctx context.Context, tenantID uuid.UUID, ids []uuid.UUID) (map[uuid.UUID][]models.ShipmentETA, error) {
+	rows, err := r.db.Query(ctx,
+		`SELECT id, tenant_id, shipment_id, shift_id, predicted_delivery, promised_delivery, predicted_late, remaining_km, speed_kmh, computed_at
+		 FROM shipment_etas WHERE tenant_id = $1 AND shipment_id = ANY($2)
+		 ORDER BY computed_at ASC`,
+		tenantID, ids,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list shipment etas: %w", err)
+	}
+	defer rows.Close()
+
+	etas := make(map[uuid.UUID][]models.ShipmentETA, len(ids))
+	for rows.Next() {
+		var e models.ShipmentETA
+		if err := rows.Scan(&e.ID, &e.TenantID, &e.ShipmentID, &e.ShiftID, &e.PredictedDelivery, &e.PromisedDelivery, &e.PredictedLate, &e.RemainingKm, &e.SpeedKmh, &e.ComputedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan shipment eta: %w", err)
+		}
+		etas[e.ShipmentID] = append(etas[e.ShipmentID], e)
+	}
+	return etas, nil
+}
+
+// GetAtRisk returns open shipments whose live ETA lands after the promised
+// delivery time while that time has not passed yet, soonest promise first.
+// Shipments already past it are reported by GetDelayed.
+func (r *ShipmentRepo) GetAtRisk(ctx context.Context, tenantID uuid.UUID) ([]models.Shipment, error) {
+	rows, err := r.db.Query(ctx,
+		`SELECT id, tenant_id, tracking_number, origin, destination, status, carrier, weight, dimensions, estimated_delivery, actual_delivery, customer_name, customer_email, notes, destination_latitude, destination_longitude, predicted_delivery, predicted_late, eta_updated_at, warehouse_id, origin_address, destination_address, created_at, updated_at
+		 FROM shipments WHERE tenant_id = $1 AND predicted_late
+		   AND status NOT IN ('delivered', 'cancelled', 'returned')
+		   AND estimated_delivery >= NOW()
+		 ORDER BY estimated_delivery ASC`,
+		tenantID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get at-risk shipments: %w", err)
+	}
+	defer rows.Close()
+
+	var shipments []models.Shipment
+	for rows.Next() {
+		var s models.Shipment
+		if err := rows.Scan(&s.ID, &s.TenantID, &s.TrackingNumber, &s.Origin, &s.Destination, &s.Status, &s.Carrier, &s.Weight, &s.Dimensions, &s.EstimatedDelivery, &s.ActualDelivery, &s.CustomerName, &s.CustomerEmail, &s.Notes, &s.DestinationLatitude, &s.DestinationLongitude, &s.PredictedDelivery, &s.PredictedLate, &s.ETAUpdatedAt, &s.WarehouseID, &s.OriginAddress, &s.DestinationAddress, &s.CreatedAt, &s.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan at-risk shipment: %w", err)
+		}
+		shipments = append(shipments, s)
+	}
+	return shipments, nil
+}
+
+// recordLaneSpeed folds a delivered shipment's pace into its lane's running
+// average. The pace is the mean ping speed, stops included, over every shift
+// that carried the shipment; deliveries with fewer than ten pings are skipped.
+func recordLaneSpeed(ctx context.Context, tx pgx.Tx, tenantID, shipmentID uuid.UUID, deliveredAt time.Time) error {
+	_, err := tx.Exec(ctx,
+		`INSERT INTO lane_speeds (tenant_id, origin, destination, avg_speed_kmh, samples, updated_at)
+		 SELECT s.tenant_id, LOWER(BTRIM(s.origin)), LOWER(BTRIM(s.destination)), ROUND(AVG(p.speed_kmh), 1), 1, NOW()
+		 FROM shipments s
+		 JOIN shipment_assignments a ON a.shipment_id = s.id AND a.tenant_id = s.tenant_id
+		 JOIN gps_pings p ON p.shift_id = a.shift_id AND p.tenant_id = a.tenant_id
+		   AND p.recorded_at >= a.assigned_at AND p.recorded_at <= COALESCE(a.unassigned_at, $3)
+		 WHERE s.id = $1 AND s.tenant_id = $2 AND s.origin IS NOT NULL AND s.destination IS NOT NULL
+		 GROUP BY s.tenant_id, s.origin, s.destination
+		 HAVING COUNT(p.id) >= 10
+		 ON CONFLICT (tenant_id, origin, destination) DO UPDATE SET
+		   avg_speed_kmh = ROUND((lane_speeds.avg_speed_kmh * lane_speeds.samples + EXCLUDED.avg_speed_kmh) / (lane_speeds.samples + 1), 1),
+		   samples = lane_speeds.samples + 1,
+		   updated_at = NOW()`,
+		shipmentID, tenantID, deliveredAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to record lane speed: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,130 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+
+	"cargomax-api/internal/models"
+
+	"github.com/google/uuid"
+)
+
+// Deliver moves a shipment to delivered and stores its proof of delivery in
+// one transaction, so a shipment is never delivered by a driver without one.
+// ev is appended to the timeline as in Transition; pod.Files must already be
+// in file storage.
+func (r *ShipmentRepo) Deliver(ctx context.Context, tenantID uuid.UUID, pod *models.ProofOfDelivery, ev *models.ShipmentEvent) (*models.Shipment, error) {
+	ev.Status = models.ShipmentDelivered
+	if ev.OccurredAt.IsZero() {
+		ev.OccurredAt = pod.CapturedAt
+	}
+
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	s, err := transitionShipment(ctx, tx, tenantID, pod.ShipmentID, ev)
+	if err != nil {
+		return nil, err
+	}
+
+	pod.ID = uuid.New()
+	pod.TenantID = tenantID
+	err = tx.QueryRow(ctx,
+		`INSERT INTO proof_of_delivery (id, tenant_id, shipment_id, driver_id, shift_id, recipient_name, latitude, longitude, accuracy_m, distance_m, captured_at, created_at)
+		 VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, NOW())
+		 RETURNING created_at`,
+		pod.ID, tenantID, pod.ShipmentID, pod.DriverID, pod.ShiftID, pod.RecipientName, pod.Latitude, pod.Longitude, pod.Accuracy, pod.DistanceMeters, pod.CapturedAt,
+	).Scan(&pod.CreatedAt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create proof of delivery: %w", err)
+	}
+	for i := range pod.Files {
+		f := &pod.Files[i]
+		f.ID = uuid.New()
+		f.TenantID = tenantID
+		f.PODID = pod.ID
+		err := tx.QueryRow(ctx,
+			`INSERT INTO proof_of_delivery_files (id, tenant_id, pod_id, kind, storage_key, content_type, size_bytes, created_at)
+			 VALUES ($1, $2, $3, $4, $5, $6, $7, NOW())
+			 RETURNING created_at`,
+			f.ID, tenantID, pod.ID, f.Kind, f.StorageKey, f.ContentType, f.SizeBytes,
+		).Scan(&f.CreatedAt)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create proof of delivery file: %w", err)
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return s, nil
+}
+
+// GetProofOfDelivery returns a shipment's proof of delivery with its files,
+// or nil when the shipment was not delivered with one.
+func (r *ShipmentRepo) GetProofOfDelivery(ctx context.Context, tenantID, shipmentID uuid.UUID) (*models.ProofOfDelivery, error) {
+	pods, err := r.ListProofsByShipments(ctx, tenantID, []uuid.UUID{shipmentID})
+	if err != nil || len(pods) == 0 {
+		return nil, err
+	}
+	return &pods[0], nil
+}
+
+// ListProofsByShipments returns the proofs of delivery of the shipments in ids
+// within a tenant, files included, in two queries.
+func (r *ShipmentRepo) ListProofsByShipments(ctx context.Context, tenantID uuid.UUID, ids []uuid.UUID) ([]models.ProofOfDelivery, error) {
+	rows, err := r.db.Query(ctx,
+		`SELECT id, tenant_id, shipment_id, driver_id, shift_id, recipient_name, latitude, longitude, accuracy_m, distance_m, captured_at, created_at
+		 FROM proof_of_delivery WHERE tenant_id = $1 AND shipment_id = ANY($2)`,
+		tenantID, ids,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list proofs of delivery: %w", err)
+	}
+	defer rows.Close()
+
+	var pods []models.ProofOfDelivery
+	byID := make(map[uuid.UUID]int)
+	for rows.Next() {
+		var p models.ProofOfDelivery
+		if err := rows.Scan(&p.ID, &p.TenantID, &p.ShipmentID, &p.DriverID, &p.ShiftID, &p.RecipientName, &p.Latitude, &p.Longitude, &p.Accuracy, &p.DistanceMeters, &p.CapturedAt, &p.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan proof of delivery: %w", err)
+		}
+		byID[p.ID] = len(pods)
+		pods = append(pods, p)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list proofs of delivery: %w", err)
+	}
+	if len(pods) == 0 {
+		return nil, nil
+	}
+
+	podIDs := make([]uuid.UUID, 0, len(pods))
+	for id := range byID {
+		podIDs = append(podIDs, id)
+	}
+	fileRows, err := r.db.Query(ctx,
+		`SELECT id, tenant_id, pod_id, kind, storage_key, content_type, size_bytes, created_at
+		 FROM proof_of_delivery_files WHERE tenant_id = $1 AND pod_id = ANY($2)
+		 ORDER BY created_at ASC, kind DESC`,
+		tenantID, podIDs,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list proof of delivery files: %w", err)
+	}
+	defer fileRows.Close()
+
+	for fileRows.Next() {
+		var f models.PODFile
+		if err := fileRows.Scan(&f.ID, &f.TenantID, &f.PODID, &f.Kind, &f.StorageKey, &f.ContentType, &f.SizeBytes, &f.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan proof of delivery file: %w", err)
+		}
+		p := &pods[byID[f.PODID]]
+		p.Files = append(p.Files, f)
+	}
+	return pods, nil
+}
@@ -2,14 +2,25 @@ package repository
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"time"
 
+	"cargomax-api/internal/database"
 	"cargomax-api/internal/models"
 
 	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
+// ErrShipmentNotFound is returned when a shipment does not exist within the tenant.
+var ErrShipmentNotFound = errors.New("shipment not found")
+
+// ErrIllegalShipmentTransition is returned when a status change is not allowed
+// by models.CanTransitionShipment.
+var ErrIllegalShipmentTransition = errors.New("illegal shipment status transition")
+
 // ShipmentRepo handles database operations for shipments.
 type ShipmentRepo struct {
 	db *pgxpool.Pool
@@ -20,42 +31,226 @@ func NewShipmentRepo(db *pgxpool.Pool) *ShipmentRepo {
 	return &ShipmentRepo{db: db}
 }
 
-// Create inserts a new shipment.
-func (r *ShipmentRepo) Create(ctx context.Context, s *models.Shipment) error {
+// Create inserts a new shipment and opens its timeline with an event for the
+// initial status. ev supplies the actor, location and note of that event and
+// may be nil for a system-created shipment. A shipment created as delivered
+// has actual_delivery stamped if it was not given.
+func (r *ShipmentRepo) Create(ctx context.Context, s *models.Shipment, ev *models.ShipmentEvent) error {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	if err := insertShipment(ctx, tx, s, ev); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+// insertShipment does the work of Create inside the caller's transaction.
+func insertShipment(ctx context.Context, tx pgx.Tx, s *models.Shipment, ev *models.ShipmentEvent) error {
+	if !models.IsShipmentStatus(s.Status) {
+		return fmt.Errorf("%w: unknown status %q", ErrIllegalShipmentTransition, s.Status)
+	}
+	if ev == nil {
+		ev = &models.ShipmentEvent{}
+	}
+	ev.Status = s.Status
+	if ev.OccurredAt.IsZero() {
+		ev.OccurredAt = time.Now()
+	}
+	if s.Status == models.ShipmentDelivered && s.ActualDelivery == nil {
+		s.ActualDelivery = &ev.OccurredAt
+	}
+
 	s.ID = uuid.New()
-	_, err := r.db.Exec(ctx,
-		`INSERT INTO shipments (id, tenant_id, tracking_number, origin, destination, status, carrier, weight, dimensions, estimated_delivery, actual_delivery, customer_name, customer_email, notes, created_at, updated_at)
-		 VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, NOW(), NOW())`,
-		s.ID, s.TenantID, s.TrackingNumber, s.Origin, s.Destination, s.Status, s.Carrier, s.Weight, s.Dimensions, s.EstimatedDelivery, s.ActualDelivery, s.CustomerName, s.CustomerEmail, s.Notes,
+	_, err := tx.Exec(ctx,
+		`INSERT INTO shipments (id, tenant_id, tracking_number, origin, destination, status, carrier, weight, dimensions, estimated_delivery, actual_delivery, customer_name, customer_email, notes, destination_latitude, destination_longitude, warehouse_id, origin_address, destination_address, created_at, updated_at)
+		 VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19, NOW(), NOW())`,
+		s.ID, s.TenantID, s.TrackingNumber, s.Origin, s.Destination, s.Status, s.Carrier, s.Weight, s.Dimensions, s.EstimatedDelivery, s.ActualDelivery, s.CustomerName, s.CustomerEmail, s.Notes, s.DestinationLatitude, s.DestinationLongitude, s.WarehouseID, s.OriginAddress, s.DestinationAddress,
 	)
 	if err != nil {
 		return fmt.Errorf("failed to create shipment: %w", err)
 	}
+	return insertShipmentEvent(ctx, tx, s.TenantID, s.ID, ev)
+}
+
+// Transition moves a shipment to ev.Status and appends ev to its timeline in
+// one transaction. The row is locked while the move is validated, so two
+// concurrent updates cannot both pass the check. Moving to delivered stamps
+// actual_delivery with the event time unless it is already set and feeds the
+// delivery's pace into lane_speeds, and delivers the orders it completes;
+// closing a shipment clears predicted_late.
+func (r *ShipmentRepo) Transition(ctx context.Context, tenantID, id uuid.UUID, ev *models.ShipmentEvent) (*models.Shipment, error) {
+	if ev.OccurredAt.IsZero() {
+		ev.OccurredAt = time.Now()
+	}
+
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	s, err := transitionShipment(ctx, tx, tenantID, id, ev)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return s, nil
+}
+
+// transitionShipment is Transition inside the caller's transaction.
+func transitionShipment(ctx context.Context, tx pgx.Tx, tenantID, id uuid.UUID, ev *models.ShipmentEvent) (*models.Shipment, error) {
+	var from string
+	err := tx.QueryRow(ctx,
+		`SELECT status FROM shipments WHERE id = $1 AND tenant_id = $2 FOR UPDATE`,
+		id, tenantID,
+	).Scan(&from)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrShipmentNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to lock shipment: %w", err)
+	}
+	if !models.CanTransitionShipment(from, ev.Status) {
+		return nil, fmt.Errorf("%w: %s -> %s", ErrIllegalShipmentTransition, from, ev.Status)
+	}
+
+	s := &models.Shipment{}
+	err = tx.QueryRow(ctx,
+		`UPDATE shipments SET status = $1,
+		        actual_delivery = CASE WHEN $1 = 'delivered' THEN COALESCE(actual_delivery, $2) ELSE actual_delivery END,
+		        predicted_late = predicted_late AND $1 NOT IN ('delivered', 'cancelled', 'returned'),
+		        updated_at = NOW()
+		 WHERE id = $3 AND tenant_id = $4
+		 RETURNING id, tenant_id, tracking_number, origin, destination, status, carrier, weight, dimensions, estimated_delivery, actual_delivery, customer_name, customer_email, notes, destination_latitude, destination_longitude, predicted_delivery, predicted_late, eta_updated_at, warehouse_id, origin_address, destination_address, created_at, updated_at`,
+		ev.Status, ev.OccurredAt, id, tenantID,
+	).Scan(&s.ID, &s.TenantID, &s.TrackingNumber, &s.Origin, &s.Destination, &s.Status, &s.Carrier, &s.Weight, &s.Dimensions, &s.EstimatedDelivery, &s.ActualDelivery, &s.CustomerName, &s.CustomerEmail, &s.Notes, &s.DestinationLatitude, &s.DestinationLongitude, &s.PredictedDelivery, &s.PredictedLate, &s.ETAUpdatedAt, &s.WarehouseID, &s.OriginAddress, &s.DestinationAddress, &s.CreatedAt, &s.UpdatedAt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update shipment status: %w", err)
+	}
+	if err := insertShipmentEvent(ctx, tx, tenantID, id, ev); err != nil {
+		return nil, err
+	}
+	if s.Status == models.ShipmentDelivered {
+		if err := recordLaneSpeed(ctx, tx, tenantID, id, *s.ActualDelivery); err != nil {
+			return nil, err
+		}
+		if err := deliverFulfilledOrders(ctx, tx, tenantID, id, *s.ActualDelivery); err != nil {
+			return nil, err
+		}
+	}
+	return s, nil
+}
+
+func insertShipmentEvent(ctx context.Context, tx pgx.Tx, tenantID, shipmentID uuid.UUID, ev *models.ShipmentEvent) error {
+	ev.ID = uuid.New()
+	ev.TenantID = tenantID
+	ev.ShipmentID = shipmentID
+	if ev.ActorType == "" {
+		ev.ActorType = "system"
+	}
+	err := tx.QueryRow(ctx,
+		`INSERT INTO shipment_events (id, tenant_id, shipment_id, status, location, note, actor_type, actor_id, occurred_at, created_at)
+		 VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, NOW())
+		 RETURNING created_at`,
+		ev.ID, ev.TenantID, ev.ShipmentID, ev.Status, ev.Location, ev.Note, ev.ActorType, ev.ActorID, ev.OccurredAt,
+	).Scan(&ev.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to record shipment event: %w", err)
+	}
 	return nil
 }
 
+// ListEvents returns a shipment's timeline, oldest first.
+func (r *ShipmentRepo) ListEvents(ctx context.Context, tenantID, shipmentID uuid.UUID) ([]models.ShipmentEvent, error) {
+	byShipment, err := r.ListEventsByShipments(ctx, tenantID, []uuid.UUID{shipmentID})
+	if err != nil {
+		return nil, err
+	}
+	return byShipment[shipmentID], nil
+}
+
+// ListEventsByShipments returns the timelines of every shipment in ids within a
+// tenant in a single query, keyed by shipment ID and ordered oldest first.
+func (r *ShipmentRepo) ListEventsByShipments(ctx context.Context, tenantID uuid.UUID, ids []uuid.UUID) (map[uuid.UUID][]models.ShipmentEvent, error) {
+	rows, err := r.db.Query(ctx,
+		`SELECT id, tenant_id, shipment_id, status, location, note, actor_type, actor_id, occurred_at, created_at
+		 FROM shipment_events WHERE tenant_id = $1 AND shipment_id = ANY($2)
+		 ORDER BY occurred_at ASC, created_at ASC`,
+		tenantID, ids,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list shipment events: %w", err)
+	}
+	defer rows.Close()
+
+	events := make(map[uuid.UUID][]models.ShipmentEvent, len(ids))
+	for rows.Next() {
+		var e models.ShipmentEvent
+		if err := rows.Scan(&e.ID, &e.TenantID, &e.ShipmentID, &e.Status, &e.Location, &e.Note, &e.ActorType, &e.ActorID, &e.OccurredAt, &e.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan shipment event: %w", err)
+		}
+		events[e.ShipmentID] = append(events[e.ShipmentID], e)
+	}
+	return events, nil
+}
+
 // GetByID retrieves a shipment by ID within a tenant.
 func (r *ShipmentRepo) GetByID(ctx context.Context, tenantID, id uuid.UUID) (*models.Shipment, error) {
 	s := &models.Shipment{}
 	err := r.db.QueryRow(ctx,
-		`SELECT id, tenant_id, tracking_number, origin, destination, status, carrier, weight, dimensions, estimated_delivery, actual_delivery, customer_name, customer_email, notes, created_at, updated_at
+		`SELECT id, tenant_id, tracking_number, origin, destination, status, carrier, weight, dimensions, estimated_delivery, actual_delivery, customer_name, customer_email, notes, destination_latitude, destination_longitude, predicted_delivery, predicted_late, eta_updated_at, warehouse_id, origin_address, destination_address, created_at, updated_at
 		 FROM shipments WHERE id = $1 AND tenant_id = $2`,
 		id, tenantID,
-	).Scan(&s.ID, &s.TenantID, &s.TrackingNumber, &s.Origin, &s.Destination, &s.Status, &s.Carrier, &s.Weight, &s.Dimensions, &s.EstimatedDelivery, &s.ActualDelivery, &s.CustomerName, &s.CustomerEmail, &s.Notes, &s.CreatedAt, &s.UpdatedAt)
+	).Scan(&s.ID, &s.TenantID, &s.TrackingNumber, &s.Origin, &s.Destination, &s.Status, &s.Carrier, &s.Weight, &s.Dimensions, &s.EstimatedDelivery, &s.ActualDelivery, &s.CustomerName, &s.CustomerEmail, &s.Notes, &s.DestinationLatitude, &s.DestinationLongitude, &s.PredictedDelivery, &s.PredictedLate, &s.ETAUpdatedAt, &s.WarehouseID, &s.OriginAddress, &s.DestinationAddress, &s.CreatedAt, &s.UpdatedAt)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get shipment by id: %w", err)
 	}
 	return s, nil
 }
 
+// GetByIDs retrieves every shipment in ids within a tenant in a single query.
+// Unknown ids are skipped, so the result may be shorter than ids.
+func (r *ShipmentRepo) GetByIDs(ctx context.Context, tenantID uuid.UUID, ids []uuid.UUID) ([]models.Shipment, error) {
+	rows, err := r.db.Query(ctx,
+		`SELECT id, tenant_id, tracking_number, origin, destination, status, carrier, weight, dimensions, estimated_delivery, actual_delivery, customer_name, customer_email, notes, destination_latitude, destination_longitude, predicted_delivery, predicted_late, eta_updated_at, warehouse_id, origin_address, destination_address, created_at, updated_at
+		 FROM shipments WHERE tenant_id = $1 AND id = ANY($2)`,
+		tenantID, ids,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get shipments by ids: %w", err)
+	}
+	defer rows.Close()
+
+	var shipments []models.Shipment
+	for rows.Next() {
+		var s models.Shipment
+		if err := rows.Scan(&s.ID, &s.TenantID, &s.TrackingNumber, &s.Origin, &s.Destination, &s.Status, &s.Carrier, &s.Weight, &s.Dimensions, &s.EstimatedDelivery, &s.ActualDelivery, &s.CustomerName, &s.CustomerEmail, &s.Notes, &s.DestinationLatitude, &s.DestinationLongitude, &s.PredictedDelivery, &s.PredictedLate, &s.ETAUpdatedAt, &s.WarehouseID, &s.OriginAddress, &s.DestinationAddress, &s.CreatedAt, &s.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan shipment: %w", err)
+		}
+		shipments = append(shipments, s)
+	}
+	return shipments, nil
+}
+
 // GetByTracking retrieves a shipment by tracking number within a tenant.
 func (r *ShipmentRepo) GetByTracking(ctx context.Context, tenantID uuid.UUID, trackingNumber string) (*models.Shipment, error) {
 	s := &models.Shipment{}
 	err := r.db.QueryRow(ctx,
-		`SELECT id, tenant_id, tracking_number, origin, destination, status, carrier, weight, dimensions, estimated_delivery, actual_delivery, customer_name, customer_email, notes, created_at, updated_at
+		`SELECT id, tenant_id, tracking_number, origin, destination, status, carrier, weight, dimensions, estimated_delivery, actual_delivery, customer_name, customer_email, notes, destination_latitude, destination_longitude, predicted_delivery, predicted_late, eta_updated_at, warehouse_id, origin_address, destination_address, created_at, updated_at
 		 FROM shipments WHERE tracking_number = $1 AND tenant_id = $2`,
 		trackingNumber, tenantID,
-	).Scan(&s.ID, &s.TenantID, &s.TrackingNumber, &s.Origin, &s.Destination, &s.Status, &s.Carrier, &s.Weight, &s.Dimensions, &s.EstimatedDelivery, &s.ActualDelivery, &s.CustomerName, &s.CustomerEmail, &s.Notes, &s.CreatedAt, &s.UpdatedAt)
+	).Scan(&s.ID, &s.TenantID, &s.TrackingNumber, &s.Origin, &s.Destination, &s.Status, &s.Carrier, &s.Weight, &s.Dimensions, &s.EstimatedDelivery, &s.ActualDelivery, &s.CustomerName, &s.CustomerEmail, &s.Notes, &s.DestinationLatitude, &s.DestinationLongitude, &s.PredictedDelivery, &s.PredictedLate, &s.ETAUpdatedAt, &s.WarehouseID, &s.OriginAddress, &s.DestinationAddress, &s.CreatedAt, &s.UpdatedAt)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get shipment by tracking number: %w", err)
 	}
@@ -87,11 +282,11 @@ func (r *ShipmentRepo) List(ctx context.Context, tenantID uuid.UUID, status stri
 	var query string
 	var args []interface{}
 	if status != "" {
-		query = `SELECT id, tenant_id, tracking_number, origin, destination, status, carrier, weight, dimensions, estimated_delivery, actual_delivery, customer_name, customer_email, notes, created_at, updated_at
+		query = `SELECT id, tenant_id, tracking_number, origin, destination, status, carrier, weight, dimensions, estimated_delivery, actual_delivery, customer_name, customer_email, notes, destination_latitude, destination_longitude, predicted_delivery, predicted_late, eta_updated_at, warehouse_id, origin_address, destination_address, created_at, updated_at
 				 FROM shipments WHERE tenant_id = $1 AND status = $2 ORDER BY created_at DESC LIMIT $3 OFFSET $4`
 		args = []interface{}{tenantID, status, perPage, offset}
 	} else {
-		query = `SELECT id, tenant_id, tracking_number, origin, destination, status, carrier, weight, dimensions, estimated_delivery, actual_delivery, customer_name, customer_email, notes, created_at, updated_at
+		query = `SELECT id, tenant_id, tracking_number, origin, destination, status, carrier, weight, dimensions, estimated_delivery, actual_delivery, customer_name, customer_email, notes, destination_latitude, destination_longitude, predicted_delivery, predicted_late, eta_updated_at, warehouse_id, origin_address, destination_address, created_at, updated_at
 				 FROM shipments WHERE tenant_id = $1 ORDER BY created_at DESC LIMIT $2 OFFSET $3`
 		args = []interface{}{tenantID, perPage, offset}
 	}
@@ -105,7 +300,7 @@ func (r *ShipmentRepo) List(ctx context.Context, tenantID uuid.UUID, status stri
 	var shipments []models.Shipment
 	for rows.Next() {
 		var s models.Shipment
-		if err := rows.Scan(&s.ID, &s.TenantID, &s.TrackingNumber, &s.Origin, &s.Destination, &s.Status, &s.Carrier, &s.Weight, &s.Dimensions, &s.EstimatedDelivery, &s.ActualDelivery, &s.CustomerName, &s.CustomerEmail, &s.Notes, &s.CreatedAt, &s.UpdatedAt); err != nil {
+		if err := rows.Scan(&s.ID, &s.TenantID, &s.TrackingNumber, &s.Origin, &s.Destination, &s.Status, &s.Carrier, &s.Weight, &s.Dimensions, &s.EstimatedDelivery, &s.ActualDelivery, &s.CustomerName, &s.CustomerEmail, &s.Notes, &s.DestinationLatitude, &s.DestinationLongitude, &s.PredictedDelivery, &s.PredictedLate, &s.ETAUpdatedAt, &s.WarehouseID, &s.OriginAddress, &s.DestinationAddress, &s.CreatedAt, &s.UpdatedAt); err != nil {
 			return nil, 0, fmt.Errorf("failed to scan shipment: %w", err)
 		}
 		shipments = append(shipments, s)
@@ -113,10 +308,49 @@ func (r *ShipmentRepo) List(ctx context.Context, tenantID uuid.UUID, status stri
 	return shipments, total, nil
 }
 
+// FindPublicTracking resolves a tracking number across every tenant for the
+// public tracking page and returns the redacted view of the shipment that
+// verifier matches. Tracking numbers are only unique per tenant, so the
+// verifier also disambiguates. Unknown numbers and wrong verifiers both
+// return ErrShipmentNotFound so callers cannot tell them apart.
+func (r *ShipmentRepo) FindPublicTracking(ctx context.Context, trackingNumber, verifier string) (*models.PublicTracking, error) {
+	ctx = database.Privileged(ctx)
+	rows, err := r.db.Query(ctx,
+		`SELECT id, tenant_id, tracking_number, origin, destination, status, carrier, weight, dimensions, estimated_delivery, actual_delivery, customer_name, customer_email, notes, destination_latitude, destination_longitude, predicted_delivery, predicted_late, eta_updated_at, warehouse_id, origin_address, destination_address, created_at, updated_at
+		 FROM shipments WHERE tracking_number = $1`,
+		trackingNumber,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up tracking number: %w", err)
+	}
+	defer rows.Close()
+
+	var match *models.Shipment
+	for rows.Next() {
+		var s models.Shipment
+		if err := rows.Scan(&s.ID, &s.TenantID, &s.TrackingNumber, &s.Origin, &s.Destination, &s.Status, &s.Carrier, &s.Weight, &s.Dimensions, &s.EstimatedDelivery, &s.ActualDelivery, &s.CustomerName, &s.CustomerEmail, &s.Notes, &s.DestinationLatitude, &s.DestinationLongitude, &s.PredictedDelivery, &s.PredictedLate, &s.ETAUpdatedAt, &s.WarehouseID, &s.OriginAddress, &s.DestinationAddress, &s.CreatedAt, &s.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan shipment: %w", err)
+		}
+		if match == nil && s.MatchesVerifier(verifier) {
+			match = &s
+		}
+	}
+	rows.Close()
+	if match == nil {
+		return nil, ErrShipmentNotFound
+	}
+
+	events, err := r.ListEvents(ctx, match.TenantID, match.ID)
+	if err != nil {
+		return nil, err
+	}
+	return models.NewPublicTracking(match, events), nil
+}
+
 // GetDelayed returns shipments that are past their estimated delivery date and not yet delivered.
 func (r *ShipmentRepo) GetDelayed(ctx context.Context, tenantID uuid.UUID) ([]models.Shipment, error) {
 	rows, err := r.db.Query(ctx,
-		`SELECT id, tenant_id, tracking_number, origin, destination, status, carrier, weight, dimensions, estimated_delivery, actual_delivery, customer_name, customer_email, notes, created_at, updated_at
+		`SELECT id, tenant_id, tracking_number, origin, destination, status, carrier, weight, dimensions, estimated_delivery, actual_delivery, customer_name, customer_email, notes, destination_latitude, destination_longitude, predicted_delivery, predicted_late, eta_updated_at, warehouse_id, origin_address, destination_address, created_at, updated_at
 		 FROM shipments WHERE tenant_id = $1 AND status != 'delivered' AND estimated_delivery < NOW() AND estimated_delivery IS NOT NULL
 		 ORDER BY estimated_delivery ASC`,
 		tenantID,
@@ -129,7 +363,7 @@ func (r *ShipmentRepo) GetDelayed(ctx context.Context, tenantID uuid.UUID) ([]mo
 	var shipments []models.Shipment
 	for rows.Next() {
 		var s models.Shipment
-		if err := rows.Scan(&s.ID, &s.TenantID, &s.TrackingNumber, &s.Origin, &s.Destination, &s.Status, &s.Carrier, &s.Weight, &s.Dimensions, &s.EstimatedDelivery, &s.ActualDelivery, &s.CustomerName, &s.CustomerEmail, &s.Notes, &s.CreatedAt, &s.UpdatedAt); err != nil {
+		if err := rows.Scan(&s.ID, &s.TenantID, &s.TrackingNumber, &s.Origin, &s.Destination, &s.Status, &s.Carrier, &s.Weight, &s.Dimensions, &s.EstimatedDelivery, &s.ActualDelivery, &s.CustomerName, &s.CustomerEmail, &s.Notes, &s.DestinationLatitude, &s.DestinationLongitude, &s.PredictedDelivery, &s.PredictedLate, &s.ETAUpdatedAt, &s.WarehouseID, &s.OriginAddress, &s.DestinationAddress, &s.CreatedAt, &s.UpdatedAt); err != nil {
 			return nil, fmt.Errorf("failed to scan delayed shipment: %w", err)
 		}
 		shipments = append(shipments, s)
@@ -137,12 +371,129 @@ func (r *ShipmentRepo) GetDelayed(ctx context.Context, tenantID uuid.UUID) ([]mo
 	return shipments, nil
 }
 
-// Update modifies an existing shipment.
+// maxTrackingAttempts bounds how many serials NextTrackingNumber skips over
+// when they were already used by hand-entered tracking numbers.
+const maxTrackingAttempts = 20
+
+// NextTrackingNumber generates the tenant's next tracking number in format f.
+// Serials come from the tenant's tracking_number sequence; a number already
+// taken, e.g. typed in by hand, is skipped.
+func (r *ShipmentRepo) NextTrackingNumber(ctx context.Context, tenantID uuid.UUID, f models.TrackingFormat) (string, error) {
+	return nextTrackingNumber(ctx, r.db, tenantID, f)
+}
+
+func nextTrackingNumber(ctx context.Context, q rowQuerier, tenantID uuid.UUID, f models.TrackingFormat) (string, error) {
+	for attempt := 0; attempt < maxTrackingAttempts; attempt++ {
+		serial, err := nextSequence(ctx, q, tenantID, models.SequenceTrackingNumber)
+		if err != nil {
+			return "", err
+		}
+		tn, err := f.Format(serial)
+		if err != nil {
+			return "", err
+		}
+		var taken bool
+		err = q.QueryRow(ctx,
+			`SELECT EXISTS (SELECT 1 FROM shipments WHERE tenant_id = $1 AND tracking_number = $2)`,
+			tenantID, tn,
+		).Scan(&taken)
+		if err != nil {
+			return "", fmt.Errorf("failed to check tracking number: %w", err)
+		}
+		if !taken {
+			return tn, nil
+		}
+	}
+	return "", fmt.Errorf("no free tracking number after %d attempts", maxTrackingAttempts)
+}
+
+// ListOutgoing returns the shipments leaving a warehouse on date (UTC): those
+// on a live route plan from the warehouse that day, in plan and stop order,
+// then those assigned to the warehouse and created that day that no such plan
+// carries yet. Cancelled shipments and skipped stops are left out.
+func (r *ShipmentRepo) ListOutgoing(ctx context.Context, tenantID, warehouseID uuid.UUID, date string) ([]models.Shipment, error) {
+	rows, err := r.db.Query(ctx,
+		`SELECT s.id, s.tenant_id, s.tracking_number, s.origin, s.destination, s.status, s.carrier, s.weight, s.dimensions, s.estimated_delivery, s.actual_delivery, s.customer_name, s.customer_email, s.notes, s.destination_latitude, s.destination_longitude, s.predicted_delivery, s.predicted_late, s.eta_updated_at, s.warehouse_id, s.origin_address, s.destination_address, s.created_at, s.updated_at
+		 FROM shipments s
+		 LEFT JOIN LATERAL (
+		     SELECT rp.planned_start, st.sequence
+		     FROM route_stops st
+		     JOIN route_plans rp ON rp.id = st.route_id AND rp.tenant_id = st.tenant_id
+		     WHERE st.tenant_id = s.tenant_id AND st.shipment_id = s.id AND st.status <> 'skipped'
+		       AND rp.warehouse_id = $2 AND rp.plan_date = $3::date AND rp.status <> 'cancelled'
+		     ORDER BY rp.planned_start, st.sequence
+		     LIMIT 1
+		 ) planned ON TRUE
+		 WHERE s.tenant_id = $1 AND s.status <> 'cancelled'
+		   AND (planned.sequence IS NOT NULL
+		        OR (s.warehouse_id = $2 AND (s.created_at AT TIME ZONE 'UTC')::date = $3::date))
+		 ORDER BY planned.planned_start ASC NULLS LAST, planned.sequence ASC NULLS LAST, s.created_at ASC`,
+		tenantID, warehouseID, date,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list outgoing shipments: %w", err)
+	}
+	defer rows.Close()
+
+	var shipments []models.Shipment
+	for rows.Next() {
+		var s models.Shipment
+		if err := rows.Scan(&s.ID, &s.TenantID, &s.TrackingNumber, &s.Origin, &s.Destination, &s.Status, &s.Carrier, &s.Weight, &s.Dimensions, &s.EstimatedDelivery, &s.ActualDelivery, &s.CustomerName, &s.CustomerEmail, &s.Notes, &s.DestinationLatitude, &s.DestinationLongitude, &s.PredictedDelivery, &s.PredictedLate, &s.ETAUpdatedAt, &s.WarehouseID, &s.OriginAddress, &s.DestinationAddress, &s.CreatedAt, &s.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan shipment: %w", err)
+		}
+		shipments = append(shipments, s)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list outgoing shipments: %w", err)
+	}
+	return shipments, nil
+}
+
+// Update modifies an existing shipment's details. Status is not written here;
+// it only changes through Transition so every move is validated and recorded,
+// and the predicted_* columns belong to RecordETA.
 func (r *ShipmentRepo) Update(ctx context.Context, tenantID, id uuid.UUID, s *models.Shipment) error {
-	ct, err := r.db.Exec(ctx,
-		`UPDATE shipments SET tracking_number = $1, origin = $2, destination = $3, status = $4, carrier = $5, weight = $6, dimensions = $7, estimated_delivery = $8, actual_delivery = $9, customer_name = $10, customer_email = $11, notes = $12, updated_at = NOW()
-		 WHERE id = $13 AND tenant_id = $14`,
-		s.TrackingNumber, s.Origin, s.Destination, s.Status, s.Carrier, s.Weight, s.Dimensions, s.EstimatedDelivery, s.ActualDelivery, s.CustomerName, s.CustomerEmail, s.Notes, id, tenantID,
+	return updateShipment(ctx, r.db, tenantID, id, s)
+}
+
+// UpdateWithTransition is Update together with a move to ev.Status, as
+// Transition makes it. The move, its timeline event and the detail changes
+// commit together or not at all. s takes the status and actual delivery time
+// the move leaves it with.
+func (r *ShipmentRepo) UpdateWithTransition(ctx context.Context, tenantID, id uuid.UUID, s *models.Shipment, ev *models.ShipmentEvent) error {
+	if ev.OccurredAt.IsZero() {
+		ev.OccurredAt = time.Now()
+	}
+
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	moved, err := transitionShipment(ctx, tx, tenantID, id, ev)
+	if err != nil {
+		return err
+	}
+	s.Status = moved.Status
+	if s.ActualDelivery == nil {
+		s.ActualDelivery = moved.ActualDelivery
+	}
+	if err := updateShipment(ctx, tx, tenantID, id, s); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+func updateShipment(ctx context.Context, q execer, tenantID, id uuid.UUID, s *models.Shipment) error {
+	ct, err := q.Exec(ctx,
+		`UPDATE shipments SET tracking_number = $1, origin = $2, destination = $3, carrier = $4, weight = $5, dimensions = $6, estimated_delivery = $7, actual_delivery = $8, customer_name = $9, customer_email = $10, notes = $11, destination_latitude = $12, destination_longitude = $13, warehouse_id = $14, origin_address = $15, destination_address = $16, updated_at = NOW()
+		 WHERE id = $17 AND tenant_id = $18`,
+		s.TrackingNumber, s.Origin, s.Destination, s.Carrier, s.Weight, s.Dimensions, s.EstimatedDelivery, s.ActualDelivery, s.CustomerName, s.CustomerEmail, s.Notes, s.DestinationLatitude, s.DestinationLongitude, s.WarehouseID, s.OriginAddress, s.DestinationAddress, id, tenantID,
 	)
 	if err != nil {
 		return fmt.Errorf("failed to update shipment: %w", err)
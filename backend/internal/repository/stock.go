@@ -0,0 +1,283 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"cargomax-api/internal/models"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// ErrInsufficientStock is returned when an order wants more of an item than
+// is available: on hand and not reserved for other orders.
+var ErrInsufficientStock = errors.New("insufficient stock")
+
+// stockError maps a violation of the reserved <= quantity check, which a
+// manual quantity edit below what orders hold would cause, to
+// ErrInsufficientStock.
+func stockError(action string, err error) error {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == "23514" && pgErr.ConstraintName == "inventory_items_reserved_check" {
+		return fmt.Errorf("%w: the quantity cannot be less than what open orders have reserved", ErrInsufficientStock)
+	}
+	return fmt.Errorf("failed to %s: %w", action, err)
+}
+
+// reserveStock holds n units of an item for an order. The conditional update
+// is what keeps concurrent orders from overselling: the row lock makes each
+// reservation see the ones committed before it, and it only succeeds while
+// enough stock is unreserved.
+func reserveStock(ctx context.Context, tx pgx.Tx, tenantID, itemID uuid.UUID, sku string, n int) error {
+	ct, err := tx.Exec(ctx,
+		`UPDATE inventory_items SET reserved = reserved + $1, updated_at = NOW()
+		 WHERE id = $2 AND tenant_id = $3 AND quantity - reserved >= $1`,
+		n, itemID, tenantID,
+	)
+	if err != nil {
+		return stockError("reserve stock", err)
+	}
+	if ct.RowsAffected() == 0 {
+		var available int
+		if err := tx.QueryRow(ctx,
+			`SELECT quantity - reserved FROM inventory_items WHERE id = $1 AND tenant_id = $2`,
+			itemID, tenantID,
+		).Scan(&available); err != nil {
+			return fmt.Errorf("inventory item %s not found", sku)
+		}
+		return fmt.Errorf("%w: %s has %d available, %d wanted", ErrInsufficientStock, sku, max(available, 0), n)
+	}
+	return nil
+}
+
+// releaseStock gives back n reserved units of an item.
+func releaseStock(ctx context.Context, tx pgx.Tx, tenantID, itemID uuid.UUID, n int) error {
+	_, err := tx.Exec(ctx,
+		`UPDATE inventory_items SET reserved = GREATEST(reserved - $1, 0), updated_at = NOW() WHERE id = $2 AND tenant_id = $3`,
+		n, itemID, tenantID,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to release stock: %w", err)
+	}
+	return nil
+}
+
+// takeStock removes n reserved units of an item from the warehouse as they
+// ship, recording a pick against ref.
+func takeStock(ctx context.Context, tx pgx.Tx, tenantID, itemID uuid.UUID, n int, ref models.StockRef) error {
+	_, err := tx.Exec(ctx,
+		`UPDATE inventory_items SET reserved = GREATEST(reserved - $1, 0), updated_at = NOW() WHERE id = $2 AND tenant_id = $3`,
+		n, itemID, tenantID,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to take stock: %w", err)
+	}
+	return moveStock(ctx, tx, tenantID, itemID, models.MovementPick, -n, ref)
+}
+
+// putBackStock returns n units of an item to the warehouse, recording a
+// return against ref.
+func putBackStock(ctx context.Context, tx pgx.Tx, tenantID, itemID uuid.UUID, n int, ref models.StockRef) error {
+	return moveStock(ctx, tx, tenantID, itemID, models.MovementReturn, n, ref)
+}
+
+const orderLineColumns = `id, tenant_id, order_id, line_number, inventory_item_id, sku, warehouse_id, description, quantity, unit_price, line_total, reserved_quantity, shipped_quantity, returned_quantity, created_at, updated_at`
+
+func scanOrderLine(row pgx.Row) (*models.OrderLine, error) {
+	l := &models.OrderLine{}
+	err := row.Scan(&l.ID, &l.TenantID, &l.OrderID, &l.LineNumber, &l.InventoryItemID, &l.SKU, &l.WarehouseID, &l.Description, &l.Quantity, &l.UnitPrice, &l.LineTotal, &l.ReservedQuantity, &l.ShippedQuantity, &l.ReturnedQuantity, &l.CreatedAt, &l.UpdatedAt)
+	return l, err
+}
+
+// orderLines returns an order's lines by line number.
+func orderLines(ctx context.Context, q pgx.Tx, tenantID, orderID uuid.UUID) ([]models.OrderLine, error) {
+	return queryOrderLines(ctx, q,
+		`SELECT `+orderLineColumns+` FROM order_lines WHERE tenant_id = $1 AND order_id = $2 ORDER BY line_number`,
+		tenantID, orderID,
+	)
+}
+
+// lockOrderLines locks an order's lines in inventory item order, the order
+// every stock change takes its item locks in, so that two orders sharing
+// items cannot deadlock.
+func lockOrderLines(ctx context.Context, tx pgx.Tx, tenantID, orderID uuid.UUID) ([]models.OrderLine, error) {
+	return queryOrderLines(ctx, tx,
+		`SELECT `+orderLineColumns+` FROM order_lines WHERE tenant_id = $1 AND order_id = $2
+		 ORDER BY inventory_item_id, line_number FOR UPDATE`,
+		tenantID, orderID,
+	)
+}
+
+func queryOrderLines(ctx context.Context, q pgx.Tx, sql string, args ...any) ([]models.OrderLine, error) {
+	rows, err := q.Query(ctx, sql, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get order lines: %w", err)
+	}
+	defer rows.Close()
+
+	lines := []models.OrderLine{}
+	for rows.Next() {
+		l, err := scanOrderLine(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan order line: %w", err)
+		}
+		lines = append(lines, *l)
+	}
+	return lines, rows.Err()
+}
+
+// setLineStock records a line's reserved, shipped and returned quantities.
+func setLineStock(ctx context.Context, tx pgx.Tx, l *models.OrderLine) error {
+	_, err := tx.Exec(ctx,
+		`UPDATE order_lines SET reserved_quantity = $1, shipped_quantity = $2, returned_quantity = $3, updated_at = NOW()
+		 WHERE id = $4 AND tenant_id = $5`,
+		l.ReservedQuantity, l.ShippedQuantity, l.ReturnedQuantity, l.ID, l.TenantID,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to update order line: %w", err)
+	}
+	return nil
+}
+
+// reserveOrder reserves what an order's lines still need: their quantity
+// less what is reserved or shipped already. Lines whose item has gone are
+// skipped.
+func reserveOrder(ctx context.Context, tx pgx.Tx, tenantID, orderID uuid.UUID) error {
+	lines, err := lockOrderLines(ctx, tx, tenantID, orderID)
+	if err != nil {
+		return err
+	}
+	for i := range lines {
+		l := &lines[i]
+		want := l.Quantity - l.ReservedQuantity - l.ShippedQuantity
+		if l.InventoryItemID == nil || want <= 0 {
+			continue
+		}
+		if err := reserveStock(ctx, tx, tenantID, *l.InventoryItemID, l.SKU, want); err != nil {
+			return err
+		}
+		l.ReservedQuantity += want
+		if err := setLineStock(ctx, tx, l); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// releaseOrder gives back everything an order's lines hold.
+func releaseOrder(ctx context.Context, tx pgx.Tx, tenantID, orderID uuid.UUID) error {
+	lines, err := lockOrderLines(ctx, tx, tenantID, orderID)
+	if err != nil {
+		return err
+	}
+	for i := range lines {
+		l := &lines[i]
+		if l.ReservedQuantity == 0 {
+			continue
+		}
+		if l.InventoryItemID != nil {
+			if err := releaseStock(ctx, tx, tenantID, *l.InventoryItemID, l.ReservedQuantity); err != nil {
+				return err
+			}
+		}
+		l.ReservedQuantity = 0
+		if err := setLineStock(ctx, tx, l); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// shipOrder takes an order's remaining quantities out of stock, reserving
+// first whatever is not reserved yet.
+func shipOrder(ctx context.Context, tx pgx.Tx, tenantID, orderID uuid.UUID) error {
+	if err := reserveOrder(ctx, tx, tenantID, orderID); err != nil {
+		return err
+	}
+	lines, err := lockOrderLines(ctx, tx, tenantID, orderID)
+	if err != nil {
+		return err
+	}
+	ref, err := orderStockRef(ctx, tx, tenantID, orderID)
+	if err != nil {
+		return err
+	}
+	for i := range lines {
+		l := &lines[i]
+		if l.ReservedQuantity == 0 {
+			continue
+		}
+		if l.InventoryItemID != nil {
+			if err := takeStock(ctx, tx, tenantID, *l.InventoryItemID, l.ReservedQuantity, ref); err != nil {
+				return err
+			}
+		}
+		l.ShippedQuantity += l.ReservedQuantity
+		l.ReservedQuantity = 0
+		if err := setLineStock(ctx, tx, l); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// restockOrder releases what an order holds and puts what it shipped, and
+// has not put back before, back into stock.
+func restockOrder(ctx context.Context, tx pgx.Tx, tenantID, orderID uuid.UUID) error {
+	if err := releaseOrder(ctx, tx, tenantID, orderID); err != nil {
+		return err
+	}
+	lines, err := lockOrderLines(ctx, tx, tenantID, orderID)
+	if err != nil {
+		return err
+	}
+	ref, err := orderStockRef(ctx, tx, tenantID, orderID)
+	if err != nil {
+		return err
+	}
+	for i := range lines {
+		l := &lines[i]
+		n := l.ShippedQuantity - l.ReturnedQuantity
+		if n == 0 {
+			continue
+		}
+		if l.InventoryItemID != nil {
+			if err := putBackStock(ctx, tx, tenantID, *l.InventoryItemID, n, ref); err != nil {
+				return err
+			}
+		}
+		l.ReturnedQuantity = l.ShippedQuantity
+		if err := setLineStock(ctx, tx, l); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// applyOrderStock brings an order's stock in line with its new status:
+// reserved while open, taken when shipped, released when cancelled or
+// scheduled, and put back when returned, which an open RMA on the order
+// blocks. An order that no longer holds stock has its open pick lists
+// cancelled.
+func applyOrderStock(ctx context.Context, tx pgx.Tx, tenantID, orderID uuid.UUID, status string) error {
+	if !models.OrderHoldsStock(status) {
+		if err := cancelOpenFulfillments(ctx, tx, tenantID, orderID); err != nil {
+			return err
+		}
+	}
+	switch {
+	case status == models.OrderReturned:
+		if err := checkNoOpenRMA(ctx, tx, tenantID, orderID); err != nil {
+			return err
+		}
+		return restockOrder(ctx, tx, tenantID, orderID)
+	case models.OrderHasShipped(status):
+		return shipOrder(ctx, tx, tenantID, orderID)
+	case models.OrderHoldsStock(status):
+		return reserveOrder(ctx, tx, tenantID, orderID)
+	}
+	return releaseOrder(ctx, tx, tenantID, orderID)
+}
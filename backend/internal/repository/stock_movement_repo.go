@@ -0,0 +1,222 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"cargomax-api/internal/models"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// StockMovementRepo reads the stock ledger and reconciles items with it.
+// Movements are written by the repositories that move stock, in the same
+// transaction as the change.
+type StockMovementRepo struct {
+	db *pgxpool.Pool
+}
+
+// NewStockMovementRepo creates a new StockMovementRepo.
+func NewStockMovementRepo(db *pgxpool.Pool) *StockMovementRepo {
+	return &StockMovementRepo{db: db}
+}
+
+const stockMovementColumns = `id, tenant_id, inventory_item_id, warehouse_id, sku, movement_type, quantity, balance, reason, reference_type, reference_id, reference, created_by, created_at`
+
+func scanStockMovement(row pgx.Row) (*models.StockMovement, error) {
+	m := &models.StockMovement{}
+	err := row.Scan(&m.ID, &m.TenantID, &m.InventoryItemID, &m.WarehouseID, &m.SKU, &m.Type, &m.Quantity, &m.Balance, &m.Reason, &m.ReferenceType, &m.ReferenceID, &m.Reference, &m.CreatedBy, &m.CreatedAt)
+	return m, err
+}
+
+// stockActor is the user a request moving stock is made for, or nil when
+// the change comes from a background job.
+func stockActor(ctx context.Context) *uuid.UUID {
+	if id, ok := ctx.Value(models.CtxUserID).(uuid.UUID); ok && id != uuid.Nil {
+		return &id
+	}
+	return nil
+}
+
+// moveStock changes an item's stock on hand by n and records the movement
+// against ref. Taking stock marks the item low on stock when it falls to its
+// minimum, and adding stock marks it in stock once it is above it. Stock
+// cannot fall below what orders have reserved.
+func moveStock(ctx context.Context, tx pgx.Tx, tenantID, itemID uuid.UUID, movementType string, n int, ref models.StockRef) error {
+	if n == 0 {
+		return nil
+	}
+	m := &models.StockMovement{TenantID: tenantID, InventoryItemID: itemID, Type: movementType, Quantity: n}
+	var warehouseID uuid.UUID
+	err := tx.QueryRow(ctx,
+		`UPDATE inventory_items SET quantity = quantity + $1,
+			status = CASE WHEN $1 < 0 AND quantity + $1 <= min_quantity THEN 'low_stock'
+			              WHEN $1 > 0 AND quantity + $1 > min_quantity THEN 'in_stock' ELSE status END,
+			updated_at = NOW()
+		 WHERE id = $2 AND tenant_id = $3
+		 RETURNING quantity, warehouse_id, sku`,
+		n, itemID, tenantID,
+	).Scan(&m.Balance, &warehouseID, &m.SKU)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return fmt.Errorf("inventory item not found")
+	}
+	if err != nil {
+		return stockError("move stock", err)
+	}
+	m.WarehouseID = &warehouseID
+	return appendMovement(ctx, tx, m, ref)
+}
+
+// appendMovement adds m to the ledger as it is, with the document and reason
+// from ref, and moves its warehouse's used capacity on with it. It does not
+// touch the item: callers that change stock on hand go through moveStock.
+func appendMovement(ctx context.Context, tx pgx.Tx, m *models.StockMovement, ref models.StockRef) error {
+	m.ID = uuid.New()
+	if ref.Type != "" {
+		m.ReferenceType = &ref.Type
+		m.ReferenceID = ref.ID
+	}
+	if ref.Number != "" {
+		m.Reference = &ref.Number
+	}
+	if ref.Reason != "" {
+		m.Reason = &ref.Reason
+	}
+	if m.CreatedBy == nil {
+		m.CreatedBy = stockActor(ctx)
+	}
+	_, err := tx.Exec(ctx,
+		`INSERT INTO stock_movements (id, tenant_id, inventory_item_id, warehouse_id, sku, movement_type, quantity, balance, reason, reference_type, reference_id, reference, created_by, created_at)
+		 VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, NOW())`,
+		m.ID, m.TenantID, m.InventoryItemID, m.WarehouseID, m.SKU, m.Type, m.Quantity, m.Balance, m.Reason, m.ReferenceType, m.ReferenceID, m.Reference, m.CreatedBy,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to record stock movement: %w", err)
+	}
+	if m.WarehouseID == nil {
+		return nil
+	}
+	return shiftUsedCapacity(ctx, tx, m.TenantID, *m.WarehouseID, m.InventoryItemID, m.Balance-m.Quantity, m.Balance)
+}
+
+// orderStockRef refers stock movements to an order.
+func orderStockRef(ctx context.Context, tx pgx.Tx, tenantID, orderID uuid.UUID) (models.StockRef, error) {
+	ref := models.StockRef{Type: "order", ID: &orderID}
+	if err := tx.QueryRow(ctx,
+		`SELECT COALESCE(order_number, '') FROM orders WHERE id = $1 AND tenant_id = $2`,
+		orderID, tenantID,
+	).Scan(&ref.Number); err != nil {
+		return ref, fmt.Errorf("failed to get order: %w", err)
+	}
+	return ref, nil
+}
+
+// List returns a page of the tenant's stock movements matching f, newest
+// first, with the total count.
+func (r *StockMovementRepo) List(ctx context.Context, tenantID uuid.UUID, f models.StockMovementFilter, page, perPage int) ([]models.StockMovement, int, error) {
+	where := `tenant_id = $1 AND ($2::uuid IS NULL OR inventory_item_id = $2) AND ($3::uuid IS NULL OR warehouse_id = $3)
+		AND ($4 = '' OR movement_type = $4) AND ($5::timestamptz IS NULL OR created_at >= $5) AND ($6::timestamptz IS NULL OR created_at < $6)`
+	args := []any{tenantID, f.InventoryItemID, f.WarehouseID, f.Type, f.From, f.To}
+
+	var total int
+	if err := r.db.QueryRow(ctx, `SELECT COUNT(*) FROM stock_movements WHERE `+where, args...).Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("failed to count stock movements: %w", err)
+	}
+	rows, err := r.db.Query(ctx,
+		`SELECT `+stockMovementColumns+` FROM stock_movements WHERE `+where+`
+		 ORDER BY created_at DESC, id LIMIT $7 OFFSET $8`,
+		append(args, perPage, (page-1)*perPage)...,
+	)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to list stock movements: %w", err)
+	}
+	defer rows.Close()
+
+	out := []models.StockMovement{}
+	for rows.Next() {
+		m, err := scanStockMovement(rows)
+		if err != nil {
+			return nil, 0, fmt.Errorf("failed to scan stock movement: %w", err)
+		}
+		out = append(out, *m)
+	}
+	return out, total, rows.Err()
+}
+
+const discrepancyQuery = `SELECT i.id, i.sku, i.warehouse_id, i.quantity, COALESCE(SUM(m.quantity), 0)::int
+	FROM inventory_items i LEFT JOIN stock_movements m ON m.inventory_item_id = i.id
+	WHERE i.tenant_id = $1 AND ($2::uuid IS NULL OR i.warehouse_id = $2)
+	GROUP BY i.id
+	HAVING i.quantity <> COALESCE(SUM(m.quantity), 0)
+	ORDER BY i.sku`
+
+// Discrepancies returns the items, in one warehouse or all of them, whose
+// quantity on hand is not what their movements add up to: stock changed
+// outside the ledger.
+func (r *StockMovementRepo) Discrepancies(ctx context.Context, tenantID uuid.UUID, warehouseID *uuid.UUID) ([]models.StockDiscrepancy, error) {
+	return discrepancies(ctx, r.db, tenantID, warehouseID)
+}
+
+func discrepancies(ctx context.Context, q rowsQuerier, tenantID uuid.UUID, warehouseID *uuid.UUID) ([]models.StockDiscrepancy, error) {
+	rows, err := q.Query(ctx, discrepancyQuery, tenantID, warehouseID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to reconcile stock: %w", err)
+	}
+	defer rows.Close()
+
+	out := []models.StockDiscrepancy{}
+	for rows.Next() {
+		var d models.StockDiscrepancy
+		if err := rows.Scan(&d.InventoryItemID, &d.SKU, &d.WarehouseID, &d.Quantity, &d.LedgerQuantity); err != nil {
+			return nil, fmt.Errorf("failed to scan stock discrepancy: %w", err)
+		}
+		out = append(out, d)
+	}
+	return out, rows.Err()
+}
+
+// Reconcile brings the ledger in line with the stock on hand of the items
+// Discrepancies finds, posting an adjustment for each difference, and
+// returns what it adjusted. The quantities themselves are left as they are:
+// they are what was counted.
+func (r *StockMovementRepo) Reconcile(ctx context.Context, tenantID uuid.UUID, warehouseID *uuid.UUID, reason string) ([]models.StockDiscrepancy, error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx,
+		`SELECT 1 FROM inventory_items WHERE tenant_id = $1 AND ($2::uuid IS NULL OR warehouse_id = $2) ORDER BY id FOR UPDATE`,
+		tenantID, warehouseID,
+	); err != nil {
+		return nil, fmt.Errorf("failed to lock inventory items: %w", err)
+	}
+	found, err := discrepancies(ctx, tx, tenantID, warehouseID)
+	if err != nil {
+		return nil, err
+	}
+	for _, d := range found {
+		wid := d.WarehouseID
+		m := &models.StockMovement{
+			TenantID:        tenantID,
+			InventoryItemID: d.InventoryItemID,
+			WarehouseID:     &wid,
+			SKU:             d.SKU,
+			Type:            models.MovementAdjustment,
+			Quantity:        d.Quantity - d.LedgerQuantity,
+			Balance:         d.Quantity,
+		}
+		if err := appendMovement(ctx, tx, m, models.StockRef{Reason: reason}); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return found, nil
+}
@@ -0,0 +1,212 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"cargomax-api/internal/models"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// ErrSupplierProductNotFound is returned when a supplier catalog entry does
+// not exist in the tenant.
+var ErrSupplierProductNotFound = errors.New("supplier product not found")
+
+// SupplierProductRepo handles the supplier catalog: which vendors supply
+// which products, and on what terms.
+type SupplierProductRepo struct {
+	db *pgxpool.Pool
+}
+
+// NewSupplierProductRepo creates a new SupplierProductRepo.
+func NewSupplierProductRepo(db *pgxpool.Pool) *SupplierProductRepo {
+	return &SupplierProductRepo{db: db}
+}
+
+const supplierProductColumns = `s.id, s.tenant_id, s.vendor_id, s.product_id, p.sku, s.vendor_sku, s.unit_cost, s.lead_time_days, s.min_order_quantity, s.preferred, s.created_at, s.updated_at`
+
+const supplierProductFrom = `supplier_products s JOIN products p ON p.id = s.product_id`
+
+func scanSupplierProduct(row pgx.Row) (*models.SupplierProduct, error) {
+	s := &models.SupplierProduct{}
+	err := row.Scan(&s.ID, &s.TenantID, &s.VendorID, &s.ProductID, &s.SKU, &s.VendorSKU, &s.UnitCost, &s.LeadTimeDays, &s.MinOrderQuantity, &s.Preferred, &s.CreatedAt, &s.UpdatedAt)
+	return s, err
+}
+
+// supplierProductError maps a vendor already supplying the product to a
+// readable error.
+func supplierProductError(action string, s *models.SupplierProduct, err error) error {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == "23505" && pgErr.ConstraintName == "supplier_products_vendor_id_product_id_key" {
+		return fmt.Errorf("the vendor already supplies %s", s.SKU)
+	}
+	return fmt.Errorf("failed to %s: %w", action, err)
+}
+
+// checkSupplierProduct validates the terms of a catalog entry and resolves
+// its SKU to the tenant's product and its vendor to the tenant's.
+func checkSupplierProduct(ctx context.Context, q rowQuerier, s *models.SupplierProduct) error {
+	if s.UnitCost < 0 {
+		return fmt.Errorf("unit cost cannot be negative")
+	}
+	if s.LeadTimeDays < 0 {
+		return fmt.Errorf("lead time cannot be negative")
+	}
+	if s.MinOrderQuantity <= 0 {
+		return fmt.Errorf("the minimum order quantity must be positive")
+	}
+	var n int
+	if err := q.QueryRow(ctx, `SELECT COUNT(*) FROM vendors WHERE id = $1 AND tenant_id = $2`, s.VendorID, s.TenantID).Scan(&n); err != nil {
+		return fmt.Errorf("failed to get vendor: %w", err)
+	}
+	if n == 0 {
+		return fmt.Errorf("vendor not found in tenant")
+	}
+	err := q.QueryRow(ctx, `SELECT id FROM products WHERE tenant_id = $1 AND sku = $2`, s.TenantID, s.SKU).Scan(&s.ProductID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return fmt.Errorf("%s is not in the catalog", s.SKU)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get product: %w", err)
+	}
+	return nil
+}
+
+// Create adds a product to a vendor's catalog. A preferred entry takes over
+// from the product's previous preferred supplier.
+func (r *SupplierProductRepo) Create(ctx context.Context, s *models.SupplierProduct) error {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	if err := checkSupplierProduct(ctx, tx, s); err != nil {
+		return err
+	}
+	s.ID = uuid.New()
+	if err := unprefer(ctx, tx, s); err != nil {
+		return err
+	}
+	if err := tx.QueryRow(ctx,
+		`INSERT INTO supplier_products (id, tenant_id, vendor_id, product_id, vendor_sku, unit_cost, lead_time_days, min_order_quantity, preferred, created_at, updated_at)
+		 VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, NOW(), NOW())
+		 RETURNING created_at, updated_at`,
+		s.ID, s.TenantID, s.VendorID, s.ProductID, s.VendorSKU, s.UnitCost, s.LeadTimeDays, s.MinOrderQuantity, s.Preferred,
+	).Scan(&s.CreatedAt, &s.UpdatedAt); err != nil {
+		return supplierProductError("create supplier product", s, err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+// Update changes a catalog entry's vendor, product and terms.
+func (r *SupplierProductRepo) Update(ctx context.Context, tenantID, id uuid.UUID, s *models.SupplierProduct) error {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	s.ID, s.TenantID = id, tenantID
+	if err := checkSupplierProduct(ctx, tx, s); err != nil {
+		return err
+	}
+	if err := unprefer(ctx, tx, s); err != nil {
+		return err
+	}
+	ct, err := tx.Exec(ctx,
+		`UPDATE supplier_products SET vendor_id = $1, product_id = $2, vendor_sku = $3, unit_cost = $4, lead_time_days = $5,
+			min_order_quantity = $6, preferred = $7, updated_at = NOW()
+		 WHERE id = $8 AND tenant_id = $9`,
+		s.VendorID, s.ProductID, s.VendorSKU, s.UnitCost, s.LeadTimeDays, s.MinOrderQuantity, s.Preferred, id, tenantID,
+	)
+	if err != nil {
+		return supplierProductError("update supplier product", s, err)
+	}
+	if ct.RowsAffected() == 0 {
+		return ErrSupplierProductNotFound
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+// unprefer clears the preferred flag of the product's other suppliers when
+// s is to be preferred, so a product has at most one.
+func unprefer(ctx context.Context, tx pgx.Tx, s *models.SupplierProduct) error {
+	if !s.Preferred {
+		return nil
+	}
+	if _, err := tx.Exec(ctx,
+		`UPDATE supplier_products SET preferred = FALSE, updated_at = NOW()
+		 WHERE tenant_id = $1 AND product_id = $2 AND id <> $3 AND preferred`,
+		s.TenantID, s.ProductID, s.ID,
+	); err != nil {
+		return fmt.Errorf("failed to update preferred supplier: %w", err)
+	}
+	return nil
+}
+
+// Delete removes an entry from the supplier catalog. Purchase orders
+// already placed keep their cost.
+func (r *SupplierProductRepo) Delete(ctx context.Context, tenantID, id uuid.UUID) error {
+	ct, err := r.db.Exec(ctx, `DELETE FROM supplier_products WHERE id = $1 AND tenant_id = $2`, id, tenantID)
+	if err != nil {
+		return fmt.Errorf("failed to delete supplier product: %w", err)
+	}
+	if ct.RowsAffected() == 0 {
+		return ErrSupplierProductNotFound
+	}
+	return nil
+}
+
+// GetByID retrieves a supplier catalog entry.
+func (r *SupplierProductRepo) GetByID(ctx context.Context, tenantID, id uuid.UUID) (*models.SupplierProduct, error) {
+	s, err := scanSupplierProduct(r.db.QueryRow(ctx,
+		`SELECT `+supplierProductColumns+` FROM `+supplierProductFrom+` WHERE s.id = $1 AND s.tenant_id = $2`,
+		id, tenantID,
+	))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrSupplierProductNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get supplier product: %w", err)
+	}
+	return s, nil
+}
+
+// List returns the supplier catalog, optionally of one vendor and of one
+// product, by SKU with the preferred supplier and then the cheapest first.
+func (r *SupplierProductRepo) List(ctx context.Context, tenantID uuid.UUID, vendorID, productID *uuid.UUID) ([]models.SupplierProduct, error) {
+	rows, err := r.db.Query(ctx,
+		`SELECT `+supplierProductColumns+` FROM `+supplierProductFrom+`
+		 WHERE s.tenant_id = $1 AND ($2::uuid IS NULL OR s.vendor_id = $2) AND ($3::uuid IS NULL OR s.product_id = $3)
+		 ORDER BY p.sku, s.preferred DESC, s.unit_cost, s.lead_time_days, s.id`,
+		tenantID, vendorID, productID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list supplier products: %w", err)
+	}
+	defer rows.Close()
+
+	out := []models.SupplierProduct{}
+	for rows.Next() {
+		s, err := scanSupplierProduct(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan supplier product: %w", err)
+		}
+		out = append(out, *s)
+	}
+	return out, rows.Err()
+}
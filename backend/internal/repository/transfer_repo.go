@@ -0,0 +1,433 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+
+	"cargomax-api/internal/models"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// ErrTransferNotFound is returned when a transfer order does not exist in the
+// tenant.
+var ErrTransferNotFound = errors.New("transfer order not found")
+
+// TransferRepo handles transfer orders, which move stock between a tenant's
+// warehouses.
+type TransferRepo struct {
+	db *pgxpool.Pool
+}
+
+// NewTransferRepo creates a new TransferRepo.
+func NewTransferRepo(db *pgxpool.Pool) *TransferRepo {
+	return &TransferRepo{db: db}
+}
+
+const transferColumns = `id, tenant_id, transfer_number, from_warehouse_id, to_warehouse_id, status, shipment_id, notes, created_by, dispatched_at, received_at, created_at, updated_at`
+
+func scanTransfer(row pgx.Row) (*models.TransferOrder, error) {
+	t := &models.TransferOrder{}
+	err := row.Scan(&t.ID, &t.TenantID, &t.TransferNumber, &t.FromWarehouseID, &t.ToWarehouseID, &t.Status, &t.ShipmentID, &t.Notes, &t.CreatedBy, &t.DispatchedAt, &t.ReceivedAt, &t.CreatedAt, &t.UpdatedAt)
+	return t, err
+}
+
+// transferStockRef refers stock movements to a transfer order.
+func transferStockRef(t *models.TransferOrder) models.StockRef {
+	return models.StockRef{Type: "transfer", ID: &t.ID, Number: t.TransferNumber}
+}
+
+// Create opens a transfer of quantities of SKUs from t's source warehouse to
+// its destination, holding the stock at the source until it is dispatched.
+// Each SKU must have that much available at the source.
+func (r *TransferRepo) Create(ctx context.Context, t *models.TransferOrder, reqs []models.TransferQuantity) (*models.TransferOrder, error) {
+	if len(reqs) == 0 {
+		return nil, fmt.Errorf("a transfer needs lines to send")
+	}
+	if t.FromWarehouseID == t.ToWarehouseID {
+		return nil, fmt.Errorf("a transfer must go to another warehouse")
+	}
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	var n int
+	if err := tx.QueryRow(ctx,
+		`SELECT COUNT(*) FROM warehouses WHERE tenant_id = $1 AND id IN ($2, $3)`,
+		t.TenantID, t.FromWarehouseID, t.ToWarehouseID,
+	).Scan(&n); err != nil {
+		return nil, fmt.Errorf("failed to get warehouses: %w", err)
+	}
+	if n != 2 {
+		return nil, fmt.Errorf("warehouse not found in tenant")
+	}
+
+	type source struct {
+		itemID, productID uuid.UUID
+		req               models.TransferQuantity
+	}
+	sources := make([]source, 0, len(reqs))
+	seen := map[string]bool{}
+	for _, req := range reqs {
+		if seen[req.SKU] {
+			return nil, fmt.Errorf("%s is listed twice", req.SKU)
+		}
+		seen[req.SKU] = true
+		if req.Quantity <= 0 {
+			return nil, fmt.Errorf("the quantity of %s must be positive", req.SKU)
+		}
+		s := source{req: req}
+		err := tx.QueryRow(ctx,
+			`SELECT id, product_id FROM inventory_items WHERE tenant_id = $1 AND sku = $2 AND warehouse_id = $3`,
+			t.TenantID, req.SKU, t.FromWarehouseID,
+		).Scan(&s.itemID, &s.productID)
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, fmt.Errorf("%s is not stocked in the source warehouse", req.SKU)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to get inventory item: %w", err)
+		}
+		sources = append(sources, s)
+	}
+	// Reserve in inventory item order, like orders do, so the two cannot
+	// deadlock.
+	sort.Slice(sources, func(i, j int) bool { return sources[i].itemID.String() < sources[j].itemID.String() })
+	for _, s := range sources {
+		if err := reserveStock(ctx, tx, t.TenantID, s.itemID, s.req.SKU, s.req.Quantity); err != nil {
+			return nil, err
+		}
+	}
+
+	seq, err := nextSequence(ctx, tx, t.TenantID, models.SequenceTransfer)
+	if err != nil {
+		return nil, err
+	}
+	t.ID = uuid.New()
+	t.TransferNumber = models.FormatInvoiceNumber(models.TransferPrefix, seq)
+	t.Status = models.TransferPending
+	if _, err := tx.Exec(ctx,
+		`INSERT INTO transfer_orders (id, tenant_id, transfer_number, from_warehouse_id, to_warehouse_id, status, notes, created_by, created_at, updated_at)
+		 VALUES ($1, $2, $3, $4, $5, $6, $7, $8, NOW(), NOW())`,
+		t.ID, t.TenantID, t.TransferNumber, t.FromWarehouseID, t.ToWarehouseID, t.Status, t.Notes, t.CreatedBy,
+	); err != nil {
+		return nil, fmt.Errorf("failed to create transfer order: %w", err)
+	}
+	for _, s := range sources {
+		if _, err := tx.Exec(ctx,
+			`INSERT INTO transfer_order_lines (id, tenant_id, transfer_order_id, product_id, sku, quantity) VALUES ($1, $2, $3, $4, $5, $6)`,
+			uuid.New(), t.TenantID, t.ID, s.productID, s.req.SKU, s.req.Quantity,
+		); err != nil {
+			return nil, fmt.Errorf("failed to create transfer order line: %w", err)
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return r.GetByID(ctx, t.TenantID, t.ID)
+}
+
+// lockTransfer locks a transfer order, checking it is in one of statuses.
+func lockTransfer(ctx context.Context, tx pgx.Tx, tenantID, id uuid.UUID, statuses ...string) (*models.TransferOrder, error) {
+	t, err := scanTransfer(tx.QueryRow(ctx,
+		`SELECT `+transferColumns+` FROM transfer_orders WHERE id = $1 AND tenant_id = $2 FOR UPDATE`,
+		id, tenantID,
+	))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrTransferNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to lock transfer order: %w", err)
+	}
+	lines, err := transferLines(ctx, tx, tenantID, []uuid.UUID{id})
+	if err != nil {
+		return nil, err
+	}
+	t.Lines = lines[id]
+	for _, s := range statuses {
+		if t.Status == s {
+			return t, nil
+		}
+	}
+	return nil, fmt.Errorf("%s is %s", t.TransferNumber, t.Status)
+}
+
+// sourceItem locks the stock level a transfer line is sent from.
+func sourceItem(ctx context.Context, tx pgx.Tx, t *models.TransferOrder, l models.TransferLine) (uuid.UUID, error) {
+	var id uuid.UUID
+	err := tx.QueryRow(ctx,
+		`SELECT id FROM inventory_items WHERE tenant_id = $1 AND product_id = $2 AND warehouse_id = $3 FOR UPDATE`,
+		t.TenantID, l.ProductID, t.FromWarehouseID,
+	).Scan(&id)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return uuid.Nil, fmt.Errorf("%s is no longer stocked in the source warehouse", l.SKU)
+	}
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("failed to get inventory item: %w", err)
+	}
+	return id, nil
+}
+
+// Cancel withdraws a pending transfer, giving back the stock it held.
+func (r *TransferRepo) Cancel(ctx context.Context, tenantID, id uuid.UUID) (*models.TransferOrder, error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	t, err := lockTransfer(ctx, tx, tenantID, id, models.TransferPending)
+	if err != nil {
+		return nil, err
+	}
+	for _, l := range t.Lines {
+		itemID, err := sourceItem(ctx, tx, t, l)
+		if err != nil {
+			return nil, err
+		}
+		if err := releaseStock(ctx, tx, tenantID, itemID, l.Quantity); err != nil {
+			return nil, err
+		}
+	}
+	if err := setTransferStatus(ctx, tx, tenantID, id, models.TransferCancelled, ""); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return r.GetByID(ctx, tenantID, id)
+}
+
+// Dispatch sends a pending transfer on shipment s, which the caller fills in
+// with the origin, destination and tracking number; ev opens its timeline.
+// The stock leaves the source warehouse and is counted in transit at the
+// destination, which gets a stock level for any product it did not stock.
+func (r *TransferRepo) Dispatch(ctx context.Context, tenantID, id uuid.UUID, s *models.Shipment, ev *models.ShipmentEvent) (*models.TransferOrder, error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	t, err := lockTransfer(ctx, tx, tenantID, id, models.TransferPending)
+	if err != nil {
+		return nil, err
+	}
+	s.TenantID = tenantID
+	if s.Status == "" {
+		s.Status = models.ShipmentPending
+	}
+	if err := insertShipment(ctx, tx, s, ev); err != nil {
+		return nil, err
+	}
+
+	ref := transferStockRef(t)
+	for _, l := range t.Lines {
+		itemID, err := sourceItem(ctx, tx, t, l)
+		if err != nil {
+			return nil, err
+		}
+		if err := releaseStock(ctx, tx, tenantID, itemID, l.Quantity); err != nil {
+			return nil, err
+		}
+		if err := moveStock(ctx, tx, tenantID, itemID, models.MovementTransfer, -l.Quantity, ref); err != nil {
+			return nil, err
+		}
+		if _, err := tx.Exec(ctx,
+			`INSERT INTO inventory_items (tenant_id, product_id, warehouse_id, sku, quantity, min_quantity, in_transit, status, created_at, updated_at)
+			 VALUES ($1, $2, $3, $4, 0, 0, $5, 'low_stock', NOW(), NOW())
+			 ON CONFLICT (product_id, warehouse_id) DO UPDATE SET in_transit = inventory_items.in_transit + EXCLUDED.in_transit, updated_at = NOW()`,
+			tenantID, l.ProductID, t.ToWarehouseID, l.SKU, l.Quantity,
+		); err != nil {
+			return nil, fmt.Errorf("failed to put stock in transit: %w", err)
+		}
+	}
+	if _, err := tx.Exec(ctx,
+		`UPDATE transfer_orders SET status = $1, shipment_id = $2, dispatched_at = NOW(), updated_at = NOW() WHERE id = $3 AND tenant_id = $4`,
+		models.TransferInTransit, s.ID, id, tenantID,
+	); err != nil {
+		return nil, fmt.Errorf("failed to dispatch transfer order: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return r.GetByID(ctx, tenantID, id)
+}
+
+// Receive takes a transfer in at its destination. received gives what
+// arrived of some SKUs; those left out, or all of them when received is nil,
+// arrived in full. What arrived goes into stock and the rest, lost on the
+// way, leaves the in-transit count.
+func (r *TransferRepo) Receive(ctx context.Context, tenantID, id uuid.UUID, received []models.TransferQuantity) (*models.TransferOrder, error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	t, err := lockTransfer(ctx, tx, tenantID, id, models.TransferInTransit)
+	if err != nil {
+		return nil, err
+	}
+	arrived := map[string]int{}
+	for _, l := range t.Lines {
+		arrived[l.SKU] = l.Quantity
+	}
+	for _, rcv := range received {
+		sent, ok := arrived[rcv.SKU]
+		if !ok {
+			return nil, fmt.Errorf("%s is not on %s", rcv.SKU, t.TransferNumber)
+		}
+		if rcv.Quantity < 0 || rcv.Quantity > sent {
+			return nil, fmt.Errorf("receive from 0 to the %d sent of %s", sent, rcv.SKU)
+		}
+		arrived[rcv.SKU] = rcv.Quantity
+	}
+
+	ref := transferStockRef(t)
+	for _, l := range t.Lines {
+		// The stock level in transit to may have been deleted on the way; it
+		// is opened again.
+		var itemID uuid.UUID
+		if err := tx.QueryRow(ctx,
+			`INSERT INTO inventory_items (tenant_id, product_id, warehouse_id, sku, quantity, min_quantity, in_transit, status, created_at, updated_at)
+			 VALUES ($1, $2, $3, $4, 0, 0, 0, 'low_stock', NOW(), NOW())
+			 ON CONFLICT (product_id, warehouse_id) DO UPDATE SET in_transit = GREATEST(inventory_items.in_transit - $5, 0), updated_at = NOW()
+			 RETURNING id`,
+			tenantID, l.ProductID, t.ToWarehouseID, l.SKU, l.Quantity,
+		).Scan(&itemID); err != nil {
+			return nil, fmt.Errorf("failed to take stock out of transit: %w", err)
+		}
+		n := arrived[l.SKU]
+		if err := moveStock(ctx, tx, tenantID, itemID, models.MovementTransfer, n, ref); err != nil {
+			return nil, err
+		}
+		if _, err := tx.Exec(ctx,
+			`UPDATE transfer_order_lines SET received_quantity = $1 WHERE id = $2 AND tenant_id = $3`,
+			n, l.ID, tenantID,
+		); err != nil {
+			return nil, fmt.Errorf("failed to receive transfer order line: %w", err)
+		}
+	}
+	if err := setTransferStatus(ctx, tx, tenantID, id, models.TransferReceived, "received_at = NOW()"); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return r.GetByID(ctx, tenantID, id)
+}
+
+// setTransferStatus moves a transfer order to status, also running the
+// given SET clause if there is one.
+func setTransferStatus(ctx context.Context, tx pgx.Tx, tenantID, id uuid.UUID, status, set string) error {
+	if set != "" {
+		set += ", "
+	}
+	if _, err := tx.Exec(ctx,
+		`UPDATE transfer_orders SET status = $1, `+set+`updated_at = NOW() WHERE id = $2 AND tenant_id = $3`,
+		status, id, tenantID,
+	); err != nil {
+		return fmt.Errorf("failed to update transfer order: %w", err)
+	}
+	return nil
+}
+
+// GetByID retrieves a transfer order with its lines.
+func (r *TransferRepo) GetByID(ctx context.Context, tenantID, id uuid.UUID) (*models.TransferOrder, error) {
+	t, err := scanTransfer(r.db.QueryRow(ctx,
+		`SELECT `+transferColumns+` FROM transfer_orders WHERE id = $1 AND tenant_id = $2`,
+		id, tenantID,
+	))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrTransferNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get transfer order: %w", err)
+	}
+	lines, err := transferLines(ctx, r.db, tenantID, []uuid.UUID{id})
+	if err != nil {
+		return nil, err
+	}
+	t.Lines = lines[id]
+	return t, nil
+}
+
+// List returns a page of the tenant's transfer orders, newest first,
+// optionally in one status and to or from one warehouse, with the total
+// count.
+func (r *TransferRepo) List(ctx context.Context, tenantID uuid.UUID, status string, warehouseID *uuid.UUID, page, perPage int) ([]models.TransferOrder, int, error) {
+	where := `tenant_id = $1 AND ($2 = '' OR status = $2) AND ($3::uuid IS NULL OR from_warehouse_id = $3 OR to_warehouse_id = $3)`
+	var total int
+	if err := r.db.QueryRow(ctx, `SELECT COUNT(*) FROM transfer_orders WHERE `+where, tenantID, status, warehouseID).Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("failed to count transfer orders: %w", err)
+	}
+	rows, err := r.db.Query(ctx,
+		`SELECT `+transferColumns+` FROM transfer_orders WHERE `+where+`
+		 ORDER BY created_at DESC, id LIMIT $4 OFFSET $5`,
+		tenantID, status, warehouseID, perPage, (page-1)*perPage,
+	)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to list transfer orders: %w", err)
+	}
+	defer rows.Close()
+
+	out := []models.TransferOrder{}
+	var ids []uuid.UUID
+	for rows.Next() {
+		t, err := scanTransfer(rows)
+		if err != nil {
+			return nil, 0, fmt.Errorf("failed to scan transfer order: %w", err)
+		}
+		out = append(out, *t)
+		ids = append(ids, t.ID)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, err
+	}
+	lines, err := transferLines(ctx, r.db, tenantID, ids)
+	if err != nil {
+		return nil, 0, err
+	}
+	for i := range out {
+		out[i].Lines = lines[out[i].ID]
+	}
+	return out, total, nil
+}
+
+// transferLines returns the lines of the given transfer orders by SKU, keyed
+// by transfer order.
+func transferLines(ctx context.Context, q rowsQuerier, tenantID uuid.UUID, ids []uuid.UUID) (map[uuid.UUID][]models.TransferLine, error) {
+	out := map[uuid.UUID][]models.TransferLine{}
+	if len(ids) == 0 {
+		return out, nil
+	}
+	rows, err := q.Query(ctx,
+		`SELECT id, transfer_order_id, product_id, sku, quantity, received_quantity
+		 FROM transfer_order_lines WHERE tenant_id = $1 AND transfer_order_id = ANY($2)
+		 ORDER BY sku`,
+		tenantID, ids,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get transfer order lines: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var l models.TransferLine
+		if err := rows.Scan(&l.ID, &l.TransferOrderID, &l.ProductID, &l.SKU, &l.Quantity, &l.ReceivedQuantity); err != nil {
+			return nil, fmt.Errorf("failed to scan transfer order line: %w", err)
+		}
+		out[l.TransferOrderID] = append(out[l.TransferOrderID], l)
+	}
+	return out, rows.Err()
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 
+	"cargomax-api/internal/database"
 	"cargomax-api/internal/models"
 
 	"github.com/google/uuid"
@@ -141,9 +142,10 @@ func (r *UserRepo) List(ctx context.Context, tenantID uuid.UUID, page, perPage i
 }
 
 // GetByEmailGlobal retrieves a user by email across all tenants (used for login).
+// It runs on the privileged connection path, bypassing row-level security.
 func (r *UserRepo) GetByEmailGlobal(ctx context.Context, email string) (*models.User, error) {
 	u := &models.User{}
-	err := r.db.QueryRow(ctx,
+	err := r.db.QueryRow(database.Privileged(ctx),
 		`SELECT id, tenant_id, email, password_hash, first_name, last_name, role, email_verified, email_verify_token, avatar_url, created_at, updated_at
 		 FROM users WHERE email = $1`,
 		email,
@@ -155,9 +157,10 @@ func (r *UserRepo) GetByEmailGlobal(ctx context.Context, email string) (*models.
 }
 
 // GetByVerifyTokenAnyTenant retrieves a user by verification token across all tenants.
+// It runs on the privileged connection path, bypassing row-level security.
 func (r *UserRepo) GetByVerifyTokenAnyTenant(ctx context.Context, token string) (*models.User, error) {
 	u := &models.User{}
-	err := r.db.QueryRow(ctx,
+	err := r.db.QueryRow(database.Privileged(ctx),
 		`SELECT id, tenant_id, email, password_hash, first_name, last_name, role, email_verified, email_verify_token, avatar_url, created_at, updated_at
 		 FROM users WHERE email_verify_token = $1`,
 		token,
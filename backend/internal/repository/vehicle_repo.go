@@ -22,11 +22,15 @@ func NewVehicleRepo(db *pgxpool.Pool) *VehicleRepo {
 
 // Create inserts a new vehicle.
 func (r *VehicleRepo) Create(ctx context.Context, v *models.Vehicle) error {
+	return insertVehicle(ctx, r.db, v)
+}
+
+func insertVehicle(ctx context.Context, q execer, v *models.Vehicle) error {
 	v.ID = uuid.New()
-	_, err := r.db.Exec(ctx,
-		`INSERT INTO vehicles (id, tenant_id, vehicle_id, name, type, status, fuel_level, mileage, last_service, next_service, license_plate, year, created_at, updated_at)
-		 VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, NOW(), NOW())`,
-		v.ID, v.TenantID, v.VehicleID, v.Name, v.Type, v.Status, v.FuelLevel, v.Mileage, v.LastService, v.NextService, v.LicensePlate, v.Year,
+	_, err := q.Exec(ctx,
+		`INSERT INTO vehicles (id, tenant_id, vehicle_id, name, type, status, fuel_level, mileage, last_service, next_service, license_plate, year, capacity_kg, created_at, updated_at)
+		 VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, NOW(), NOW())`,
+		v.ID, v.TenantID, v.VehicleID, v.Name, v.Type, v.Status, v.FuelLevel, v.Mileage, v.LastService, v.NextService, v.LicensePlate, v.Year, v.CapacityKg,
 	)
 	if err != nil {
 		return fmt.Errorf("failed to create vehicle: %w", err)
@@ -38,16 +42,40 @@ func (r *VehicleRepo) Create(ctx context.Context, v *models.Vehicle) error {
 func (r *VehicleRepo) GetByID(ctx context.Context, tenantID, id uuid.UUID) (*models.Vehicle, error) {
 	v := &models.Vehicle{}
 	err := r.db.QueryRow(ctx,
-		`SELECT id, tenant_id, vehicle_id, name, type, status, fuel_level, mileage, last_service, next_service, license_plate, year, created_at, updated_at
+		`SELECT id, tenant_id, vehicle_id, name, type, status, fuel_level, mileage, last_service, next_service, license_plate, year, capacity_kg, created_at, updated_at
 		 FROM vehicles WHERE id = $1 AND tenant_id = $2`,
 		id, tenantID,
-	).Scan(&v.ID, &v.TenantID, &v.VehicleID, &v.Name, &v.Type, &v.Status, &v.FuelLevel, &v.Mileage, &v.LastService, &v.NextService, &v.LicensePlate, &v.Year, &v.CreatedAt, &v.UpdatedAt)
+	).Scan(&v.ID, &v.TenantID, &v.VehicleID, &v.Name, &v.Type, &v.Status, &v.FuelLevel, &v.Mileage, &v.LastService, &v.NextService, &v.LicensePlate, &v.Year, &v.CapacityKg, &v.CreatedAt, &v.UpdatedAt)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get vehicle by id: %w", err)
 	}
 	return v, nil
 }
 
+// GetByIDs retrieves every vehicle in ids within a tenant in a single query.
+// Unknown ids are skipped, so the result may be shorter than ids.
+func (r *VehicleRepo) GetByIDs(ctx context.Context, tenantID uuid.UUID, ids []uuid.UUID) ([]models.Vehicle, error) {
+	rows, err := r.db.Query(ctx,
+		`SELECT id, tenant_id, vehicle_id, name, type, status, fuel_level, mileage, last_service, next_service, license_plate, year, capacity_kg, created_at, updated_at
+		 FROM vehicles WHERE tenant_id = $1 AND id = ANY($2)`,
+		tenantID, ids,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get vehicles by ids: %w", err)
+	}
+	defer rows.Close()
+
+	var vehicles []models.Vehicle
+	for rows.Next() {
+		var v models.Vehicle
+		if err := rows.Scan(&v.ID, &v.TenantID, &v.VehicleID, &v.Name, &v.Type, &v.Status, &v.FuelLevel, &v.Mileage, &v.LastService, &v.NextService, &v.LicensePlate, &v.Year, &v.CapacityKg, &v.CreatedAt, &v.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan vehicle: %w", err)
+		}
+		vehicles = append(vehicles, v)
+	}
+	return vehicles, nil
+}
+
 // List returns a paginated list of vehicles, optionally filtered by status.
 func (r *VehicleRepo) List(ctx context.Context, tenantID uuid.UUID, status string, page, perPage int) ([]models.Vehicle, int, error) {
 	var total int
@@ -73,11 +101,11 @@ func (r *VehicleRepo) List(ctx context.Context, tenantID uuid.UUID, status strin
 	var query string
 	var args []interface{}
 	if status != "" {
-		query = `SELECT id, tenant_id, vehicle_id, name, type, status, fuel_level, mileage, last_service, next_service, license_plate, year, created_at, updated_at
+		query = `SELECT id, tenant_id, vehicle_id, name, type, status, fuel_level, mileage, last_service, next_service, license_plate, year, capacity_kg, created_at, updated_at
 				 FROM vehicles WHERE tenant_id = $1 AND status = $2 ORDER BY created_at DESC LIMIT $3 OFFSET $4`
 		args = []interface{}{tenantID, status, perPage, offset}
 	} else {
-		query = `SELECT id, tenant_id, vehicle_id, name, type, status, fuel_level, mileage, last_service, next_service, license_plate, year, created_at, updated_at
+		query = `SELECT id, tenant_id, vehicle_id, name, type, status, fuel_level, mileage, last_service, next_service, license_plate, year, capacity_kg, created_at, updated_at
 				 FROM vehicles WHERE tenant_id = $1 ORDER BY created_at DESC LIMIT $2 OFFSET $3`
 		args = []interface{}{tenantID, perPage, offset}
 	}
@@ -91,7 +119,7 @@ func (r *VehicleRepo) List(ctx context.Context, tenantID uuid.UUID, status strin
 	var vehicles []models.Vehicle
 	for rows.Next() {
 		var v models.Vehicle
-		if err := rows.Scan(&v.ID, &v.TenantID, &v.VehicleID, &v.Name, &v.Type, &v.Status, &v.FuelLevel, &v.Mileage, &v.LastService, &v.NextService, &v.LicensePlate, &v.Year, &v.CreatedAt, &v.UpdatedAt); err != nil {
+		if err := rows.Scan(&v.ID, &v.TenantID, &v.VehicleID, &v.Name, &v.Type, &v.Status, &v.FuelLevel, &v.Mileage, &v.LastService, &v.NextService, &v.LicensePlate, &v.Year, &v.CapacityKg, &v.CreatedAt, &v.UpdatedAt); err != nil {
 			return nil, 0, fmt.Errorf("failed to scan vehicle: %w", err)
 		}
 		vehicles = append(vehicles, v)
@@ -101,10 +129,14 @@ func (r *VehicleRepo) List(ctx context.Context, tenantID uuid.UUID, status strin
 
 // Update modifies an existing vehicle.
 func (r *VehicleRepo) Update(ctx context.Context, tenantID, id uuid.UUID, v *models.Vehicle) error {
-	_, err := r.db.Exec(ctx,
-		`UPDATE vehicles SET vehicle_id = $1, name = $2, type = $3, status = $4, fuel_level = $5, mileage = $6, last_service = $7, next_service = $8, license_plate = $9, year = $10, updated_at = NOW()
-		 WHERE id = $11 AND tenant_id = $12`,
-		v.VehicleID, v.Name, v.Type, v.Status, v.FuelLevel, v.Mileage, v.LastService, v.NextService, v.LicensePlate, v.Year, id, tenantID,
+	return updateVehicle(ctx, r.db, tenantID, id, v)
+}
+
+func updateVehicle(ctx context.Context, q execer, tenantID, id uuid.UUID, v *models.Vehicle) error {
+	_, err := q.Exec(ctx,
+		`UPDATE vehicles SET vehicle_id = $1, name = $2, type = $3, status = $4, fuel_level = $5, mileage = $6, last_service = $7, next_service = $8, license_plate = $9, year = $10, capacity_kg = $11, updated_at = NOW()
+		 WHERE id = $12 AND tenant_id = $13`,
+		v.VehicleID, v.Name, v.Type, v.Status, v.FuelLevel, v.Mileage, v.LastService, v.NextService, v.LicensePlate, v.Year, v.CapacityKg, id, tenantID,
 	)
 	if err != nil {
 		return fmt.Errorf("failed to update vehicle: %w", err)
@@ -150,7 +182,7 @@ func (r *VehicleRepo) CountByStatus(ctx context.Context, tenantID uuid.UUID) (ma
 // GetActive returns all active vehicles for a tenant.
 func (r *VehicleRepo) GetActive(ctx context.Context, tenantID uuid.UUID) ([]models.Vehicle, error) {
 	rows, err := r.db.Query(ctx,
-		`SELECT id, tenant_id, vehicle_id, name, type, status, fuel_level, mileage, last_service, next_service, license_plate, year, created_at, updated_at
+		`SELECT id, tenant_id, vehicle_id, name, type, status, fuel_level, mileage, last_service, next_service, license_plate, year, capacity_kg, created_at, updated_at
 		 FROM vehicles WHERE tenant_id = $1 AND status = 'available' ORDER BY license_plate ASC`,
 		tenantID,
 	)
@@ -162,7 +194,7 @@ func (r *VehicleRepo) GetActive(ctx context.Context, tenantID uuid.UUID) ([]mode
 	var vehicles []models.Vehicle
 	for rows.Next() {
 		var v models.Vehicle
-		if err := rows.Scan(&v.ID, &v.TenantID, &v.VehicleID, &v.Name, &v.Type, &v.Status, &v.FuelLevel, &v.Mileage, &v.LastService, &v.NextService, &v.LicensePlate, &v.Year, &v.CreatedAt, &v.UpdatedAt); err != nil {
+		if err := rows.Scan(&v.ID, &v.TenantID, &v.VehicleID, &v.Name, &v.Type, &v.Status, &v.FuelLevel, &v.Mileage, &v.LastService, &v.NextService, &v.LicensePlate, &v.Year, &v.CapacityKg, &v.CreatedAt, &v.UpdatedAt); err != nil {
 			return nil, fmt.Errorf("failed to scan vehicle: %w", err)
 		}
 		vehicles = append(vehicles, v)
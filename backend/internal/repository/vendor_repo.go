@@ -2,11 +2,13 @@ package repository
 
 import (
 	"context"
+	"errors"
 	"fmt"
 
 	"cargomax-api/internal/models"
 
 	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
@@ -97,12 +99,17 @@ func (r *VendorRepo) Update(ctx context.Context, tenantID, id uuid.UUID, v *mode
 	return nil
 }
 
-// Delete removes a vendor by ID within a tenant.
+// Delete removes a vendor by ID within a tenant. A vendor with purchase
+// orders cannot be deleted.
 func (r *VendorRepo) Delete(ctx context.Context, tenantID, id uuid.UUID) error {
 	ct, err := r.db.Exec(ctx,
 		`DELETE FROM vendors WHERE id = $1 AND tenant_id = $2`,
 		id, tenantID,
 	)
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == "23503" {
+		return fmt.Errorf("the vendor has purchase orders and cannot be deleted")
+	}
 	if err != nil {
 		return fmt.Errorf("failed to delete vendor: %w", err)
 	}
@@ -2,11 +2,13 @@ package repository
 
 import (
 	"context"
+	"errors"
 	"fmt"
 
 	"cargomax-api/internal/models"
 
 	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
@@ -20,13 +22,37 @@ func NewWarehouseRepo(db *pgxpool.Pool) *WarehouseRepo {
 	return &WarehouseRepo{db: db}
 }
 
-// Create inserts a new warehouse.
+const warehouseColumns = `w.id, w.tenant_id, w.name, w.location, w.address, w.capacity, w.used_capacity, w.capacity_unit, w.manager, w.phone, w.status,
+	w.latitude, w.longitude, w.address_details, w.fill_thresholds, w.fill_alert_level, w.created_at, w.updated_at`
+
+func scanWarehouse(row pgx.Row) (*models.Warehouse, error) {
+	w := &models.Warehouse{}
+	err := row.Scan(&w.ID, &w.TenantID, &w.Name, &w.Location, &w.Address, &w.Capacity, &w.UsedCapacity, &w.CapacityUnit, &w.Manager, &w.Phone, &w.Status,
+		&w.Latitude, &w.Longitude, &w.AddressDetails, &w.FillThresholds, &w.FillAlertLevel, &w.CreatedAt, &w.UpdatedAt)
+	return w, err
+}
+
+// warehouseDefaults measures a warehouse's capacity in pallets and gives it
+// the default fill thresholds unless it says otherwise.
+func warehouseDefaults(w *models.Warehouse) {
+	if w.CapacityUnit == "" {
+		w.CapacityUnit = models.CapacityPallets
+	}
+	if w.FillThresholds == nil {
+		w.FillThresholds = models.DefaultFillThresholds
+	}
+}
+
+// Create inserts a new warehouse. It holds no stock yet, so none of its
+// capacity is used.
 func (r *WarehouseRepo) Create(ctx context.Context, w *models.Warehouse) error {
 	w.ID = uuid.New()
+	w.UsedCapacity = 0
+	warehouseDefaults(w)
 	_, err := r.db.Exec(ctx,
-		`INSERT INTO warehouses (id, tenant_id, name, location, address, capacity, used_capacity, manager, phone, status, created_at, updated_at)
-		 VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, NOW(), NOW())`,
-		w.ID, w.TenantID, w.Name, w.Location, w.Address, w.Capacity, w.UsedCapacity, w.Manager, w.Phone, w.Status,
+		`INSERT INTO warehouses (id, tenant_id, name, location, address, capacity, capacity_unit, manager, phone, status, latitude, longitude, address_details, fill_thresholds, created_at, updated_at)
+		 VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, NOW(), NOW())`,
+		w.ID, w.TenantID, w.Name, w.Location, w.Address, w.Capacity, w.CapacityUnit, w.Manager, w.Phone, w.Status, w.Latitude, w.Longitude, w.AddressDetails, w.FillThresholds,
 	)
 	if err != nil {
 		return fmt.Errorf("failed to create warehouse: %w", err)
@@ -36,18 +62,41 @@ func (r *WarehouseRepo) Create(ctx context.Context, w *models.Warehouse) error {
 
 // GetByID retrieves a warehouse by ID within a tenant.
 func (r *WarehouseRepo) GetByID(ctx context.Context, tenantID, id uuid.UUID) (*models.Warehouse, error) {
-	w := &models.Warehouse{}
-	err := r.db.QueryRow(ctx,
-		`SELECT id, tenant_id, name, location, address, capacity, used_capacity, manager, phone, status, created_at, updated_at
-		 FROM warehouses WHERE id = $1 AND tenant_id = $2`,
+	w, err := scanWarehouse(r.db.QueryRow(ctx,
+		`SELECT `+warehouseColumns+`
+		 FROM warehouses w WHERE w.id = $1 AND w.tenant_id = $2`,
 		id, tenantID,
-	).Scan(&w.ID, &w.TenantID, &w.Name, &w.Location, &w.Address, &w.Capacity, &w.UsedCapacity, &w.Manager, &w.Phone, &w.Status, &w.CreatedAt, &w.UpdatedAt)
+	))
 	if err != nil {
 		return nil, fmt.Errorf("failed to get warehouse by id: %w", err)
 	}
 	return w, nil
 }
 
+// GetByIDs retrieves every warehouse in ids within a tenant in a single query.
+// Unknown ids are skipped, so the result may be shorter than ids.
+func (r *WarehouseRepo) GetByIDs(ctx context.Context, tenantID uuid.UUID, ids []uuid.UUID) ([]models.Warehouse, error) {
+	rows, err := r.db.Query(ctx,
+		`SELECT `+warehouseColumns+`
+		 FROM warehouses w WHERE w.tenant_id = $1 AND w.id = ANY($2)`,
+		tenantID, ids,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get warehouses by ids: %w", err)
+	}
+	defer rows.Close()
+
+	var warehouses []models.Warehouse
+	for rows.Next() {
+		w, err := scanWarehouse(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan warehouse: %w", err)
+		}
+		warehouses = append(warehouses, *w)
+	}
+	return warehouses, nil
+}
+
 // List returns a paginated list of warehouses within a tenant.
 func (r *WarehouseRepo) List(ctx context.Context, tenantID uuid.UUID, page, perPage int) ([]models.Warehouse, int, error) {
 	var total int
@@ -61,8 +110,8 @@ func (r *WarehouseRepo) List(ctx context.Context, tenantID uuid.UUID, page, perP
 
 	offset := (page - 1) * perPage
 	rows, err := r.db.Query(ctx,
-		`SELECT id, tenant_id, name, location, address, capacity, used_capacity, manager, phone, status, created_at, updated_at
-		 FROM warehouses WHERE tenant_id = $1 ORDER BY name ASC LIMIT $2 OFFSET $3`,
+		`SELECT `+warehouseColumns+`
+		 FROM warehouses w WHERE w.tenant_id = $1 ORDER BY w.name ASC LIMIT $2 OFFSET $3`,
 		tenantID, perPage, offset,
 	)
 	if err != nil {
@@ -72,28 +121,54 @@ func (r *WarehouseRepo) List(ctx context.Context, tenantID uuid.UUID, page, perP
 
 	var warehouses []models.Warehouse
 	for rows.Next() {
-		var w models.Warehouse
-		if err := rows.Scan(&w.ID, &w.TenantID, &w.Name, &w.Location, &w.Address, &w.Capacity, &w.UsedCapacity, &w.Manager, &w.Phone, &w.Status, &w.CreatedAt, &w.UpdatedAt); err != nil {
+		w, err := scanWarehouse(rows)
+		if err != nil {
 			return nil, 0, fmt.Errorf("failed to scan warehouse: %w", err)
 		}
-		warehouses = append(warehouses, w)
+		warehouses = append(warehouses, *w)
 	}
 	return warehouses, total, nil
 }
 
-// Update modifies an existing warehouse.
+// Update modifies an existing warehouse. An empty capacity unit or nil fill
+// thresholds keep the stored ones. When the unit changes, its used capacity
+// is worked out again in the new unit; either way it is checked against the
+// capacity and fill thresholds.
 func (r *WarehouseRepo) Update(ctx context.Context, tenantID, id uuid.UUID, w *models.Warehouse) error {
-	ct, err := r.db.Exec(ctx,
-		`UPDATE warehouses SET name = $1, location = $2, address = $3, capacity = $4, used_capacity = $5, manager = $6, phone = $7, status = $8, updated_at = NOW()
-		 WHERE id = $9 AND tenant_id = $10`,
-		w.Name, w.Location, w.Address, w.Capacity, w.UsedCapacity, w.Manager, w.Phone, w.Status, id, tenantID,
-	)
+	tx, err := r.db.Begin(ctx)
 	if err != nil {
-		return fmt.Errorf("failed to update warehouse: %w", err)
+		return fmt.Errorf("failed to begin transaction: %w", err)
 	}
-	if ct.RowsAffected() == 0 {
+	defer tx.Rollback(ctx)
+
+	var unitChanged bool
+	err = tx.QueryRow(ctx,
+		`UPDATE warehouses w SET name = $1, location = $2, address = $3, capacity = $4, capacity_unit = COALESCE(NULLIF($5, ''), w.capacity_unit),
+			manager = $6, phone = $7, status = $8, latitude = $9, longitude = $10, address_details = $11,
+			fill_thresholds = COALESCE($12, w.fill_thresholds), updated_at = NOW()
+		 FROM (SELECT id, capacity_unit FROM warehouses WHERE id = $13 AND tenant_id = $14 FOR UPDATE) old
+		 WHERE w.id = old.id
+		 RETURNING w.capacity_unit <> old.capacity_unit`,
+		w.Name, w.Location, w.Address, w.Capacity, w.CapacityUnit, w.Manager, w.Phone, w.Status, w.Latitude, w.Longitude, w.AddressDetails, w.FillThresholds, id, tenantID,
+	).Scan(&unitChanged)
+	if errors.Is(err, pgx.ErrNoRows) {
 		return fmt.Errorf("warehouse not found")
 	}
+	if err != nil {
+		return fmt.Errorf("failed to update warehouse: %w", err)
+	}
+	if unitChanged {
+		err = refreshUsedCapacity(ctx, tx, tenantID, []uuid.UUID{id})
+	} else {
+		err = recheckFill(ctx, tx, tenantID, id)
+	}
+	if err != nil {
+		return err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
 	return nil
 }
 
@@ -112,20 +187,61 @@ func (r *WarehouseRepo) Delete(ctx context.Context, tenantID, id uuid.UUID) erro
 	return nil
 }
 
-// GetCapacityStats returns aggregated capacity statistics for all warehouses within a tenant.
+// GetCapacityStats returns aggregated capacity statistics for all active
+// warehouses within a tenant. Utilization is the mean fill of those with a
+// capacity, as they may measure it in different units.
 func (r *WarehouseRepo) GetCapacityStats(ctx context.Context, tenantID uuid.UUID) (*models.WarehouseCapacityStats, error) {
 	stats := &models.WarehouseCapacityStats{}
 	err := r.db.QueryRow(ctx,
-		`SELECT COALESCE(SUM(capacity), 0), COALESCE(SUM(used_capacity), 0)
+		`SELECT COALESCE(SUM(capacity), 0), COALESCE(SUM(used_capacity), 0),
+			COALESCE(AVG(used_capacity * 100.0 / capacity) FILTER (WHERE capacity > 0), 0)::float8
 		 FROM warehouses WHERE tenant_id = $1 AND status = 'active'`,
 		tenantID,
-	).Scan(&stats.TotalCapacity, &stats.UsedCapacity)
+	).Scan(&stats.TotalCapacity, &stats.UsedCapacity, &stats.Utilization)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get capacity stats: %w", err)
 	}
-	stats.FreeCapacity = stats.TotalCapacity - stats.UsedCapacity
-	if stats.TotalCapacity > 0 {
-		stats.Utilization = float64(stats.UsedCapacity) / float64(stats.TotalCapacity) * 100
-	}
+	stats.FreeCapacity = float64(stats.TotalCapacity) - stats.UsedCapacity
 	return stats, nil
 }
+
+// ListUnlocated returns the tenant's warehouses that have a structured
+// address but no coordinates yet.
+func (r *WarehouseRepo) ListUnlocated(ctx context.Context, tenantID uuid.UUID) ([]models.Warehouse, error) {
+	rows, err := r.db.Query(ctx,
+		`SELECT `+warehouseColumns+`
+		 FROM warehouses w WHERE w.tenant_id = $1 AND w.address_details IS NOT NULL AND (w.latitude IS NULL OR w.longitude IS NULL)
+		 ORDER BY w.name ASC`,
+		tenantID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list unlocated warehouses: %w", err)
+	}
+	defer rows.Close()
+
+	var warehouses []models.Warehouse
+	for rows.Next() {
+		w, err := scanWarehouse(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan warehouse: %w", err)
+		}
+		warehouses = append(warehouses, *w)
+	}
+	return warehouses, rows.Err()
+}
+
+// SetLocation stores the coordinates found for a warehouse.
+func (r *WarehouseRepo) SetLocation(ctx context.Context, tenantID, id uuid.UUID, lat, lng float64) error {
+	ct, err := r.db.Exec(ctx,
+		`UPDATE warehouses SET latitude = $1, longitude = $2, updated_at = NOW()
+		 WHERE id = $3 AND tenant_id = $4`,
+		lat, lng, id, tenantID,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to set warehouse location: %w", err)
+	}
+	if ct.RowsAffected() == 0 {
+		return fmt.Errorf("warehouse not found")
+	}
+	return nil
+}
@@ -20,7 +20,7 @@ func NewZoneRepo(db *pgxpool.Pool) *ZoneRepo {
 
 func (r *ZoneRepo) GetByTenant(ctx context.Context, tenantID uuid.UUID) ([]models.ApprovedZone, error) {
 	rows, err := r.db.Query(ctx,
-		`SELECT id, tenant_id, label, latitude, longitude, radius_meters, type, created_at, updated_at
+		`SELECT id, tenant_id, label, latitude, longitude, radius_meters, type, warehouse_id, client_id, created_at, updated_at
 		 FROM approved_zones WHERE tenant_id = $1`,
 		tenantID,
 	)
@@ -32,7 +32,7 @@ func (r *ZoneRepo) GetByTenant(ctx context.Context, tenantID uuid.UUID) ([]model
 	var zones []models.ApprovedZone
 	for rows.Next() {
 		var z models.ApprovedZone
-		if err := rows.Scan(&z.ID, &z.TenantID, &z.Label, &z.Latitude, &z.Longitude, &z.RadiusMeters, &z.Type, &z.CreatedAt, &z.UpdatedAt); err != nil {
+		if err := rows.Scan(&z.ID, &z.TenantID, &z.Label, &z.Latitude, &z.Longitude, &z.RadiusMeters, &z.Type, &z.WarehouseID, &z.ClientID, &z.CreatedAt, &z.UpdatedAt); err != nil {
 			return nil, fmt.Errorf("failed to scan zone: %w", err)
 		}
 		zones = append(zones, z)
@@ -96,10 +96,10 @@ func (r *ZoneRepo) Delete(ctx context.Context, tenantID, id uuid.UUID) error {
 func (r *ZoneRepo) GetByID(ctx context.Context, tenantID, id uuid.UUID) (*models.ApprovedZone, error) {
 	z := &models.ApprovedZone{}
 	err := r.db.QueryRow(ctx,
-		`SELECT id, tenant_id, label, latitude, longitude, radius_meters, type, created_at, updated_at
+		`SELECT id, tenant_id, label, latitude, longitude, radius_meters, type, warehouse_id, client_id, created_at, updated_at
 		 FROM approved_zones WHERE tenant_id = $1 AND id = $2`,
 		tenantID, id,
-	).Scan(&z.ID, &z.TenantID, &z.Label, &z.Latitude, &z.Longitude, &z.RadiusMeters, &z.Type, &z.CreatedAt, &z.UpdatedAt)
+	).Scan(&z.ID, &z.TenantID, &z.Label, &z.Latitude, &z.Longitude, &z.RadiusMeters, &z.Type, &z.WarehouseID, &z.ClientID, &z.CreatedAt, &z.UpdatedAt)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get zone by id: %w", err)
 	}
@@ -120,3 +120,89 @@ func (r *ZoneRepo) CreateOrUpdateAlertConfig(ctx context.Context, c *models.Aler
 	}
 	return nil
 }
+
+// syncWarehouseZonesSQL creates or moves the zone of every located warehouse
+// matched by $1 (tenant) and $2 (one warehouse, or NULL for all). A radius a
+// manager has since changed is kept.
+const syncWarehouseZonesSQL = `INSERT INTO approved_zones (id, tenant_id, label, latitude, longitude, radius_meters, type, warehouse_id, created_at, updated_at)
+	SELECT gen_random_uuid(), tenant_id, name, latitude, longitude, $3, 'warehouse', id, NOW(), NOW()
+	FROM warehouses
+	WHERE tenant_id = $1 AND ($2::uuid IS NULL OR id = $2) AND latitude IS NOT NULL AND longitude IS NOT NULL
+	ON CONFLICT (warehouse_id) WHERE warehouse_id IS NOT NULL
+	DO UPDATE SET label = EXCLUDED.label, latitude = EXCLUDED.latitude, longitude = EXCLUDED.longitude, updated_at = NOW()`
+
+const dropWarehouseZonesSQL = `DELETE FROM approved_zones z USING warehouses w
+	WHERE z.tenant_id = $1 AND z.warehouse_id = w.id AND w.tenant_id = $1 AND ($2::uuid IS NULL OR w.id = $2)
+	  AND (w.latitude IS NULL OR w.longitude IS NULL)`
+
+// syncClientZonesSQL is syncWarehouseZonesSQL for client sites.
+const syncClientZonesSQL = `INSERT INTO approved_zones (id, tenant_id, label, latitude, longitude, radius_meters, type, client_id, created_at, updated_at)
+	SELECT gen_random_uuid(), tenant_id, company_name, latitude, longitude, $3, 'client_site', id, NOW(), NOW()
+	FROM clients
+	WHERE tenant_id = $1 AND ($2::uuid IS NULL OR id = $2) AND latitude IS NOT NULL AND longitude IS NOT NULL
+	ON CONFLICT (client_id) WHERE client_id IS NOT NULL
+	DO UPDATE SET label = EXCLUDED.label, latitude = EXCLUDED.latitude, longitude = EXCLUDED.longitude, updated_at = NOW()`
+
+const dropClientZonesSQL = `DELETE FROM approved_zones z USING clients c
+	WHERE z.tenant_id = $1 AND z.client_id = c.id AND c.tenant_id = $1 AND ($2::uuid IS NULL OR c.id = $2)
+	  AND (c.latitude IS NULL OR c.longitude IS NULL)`
+
+// SyncWarehouseZone creates the warehouse's approved zone, or moves and
+// relabels it, from the warehouse's coordinates. A warehouse without
+// coordinates loses its zone.
+func (r *ZoneRepo) SyncWarehouseZone(ctx context.Context, tenantID, warehouseID uuid.UUID) error {
+	return r.syncLinked(ctx, tenantID, &warehouseID, nil)
+}
+
+// SyncClientZone is SyncWarehouseZone for a client's site.
+func (r *ZoneRepo) SyncClientZone(ctx context.Context, tenantID, clientID uuid.UUID) error {
+	return r.syncLinked(ctx, tenantID, nil, &clientID)
+}
+
+// SyncLinkedZones brings the zones of every warehouse and client of a tenant
+// in line with their coordinates and returns how many zones it created or
+// updated.
+func (r *ZoneRepo) SyncLinkedZones(ctx context.Context, tenantID uuid.UUID) (int, error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	var synced int64
+	for _, q := range []struct {
+		sync, drop string
+		radius     int
+	}{
+		{syncWarehouseZonesSQL, dropWarehouseZonesSQL, models.WarehouseZoneRadius},
+		{syncClientZonesSQL, dropClientZonesSQL, models.ClientSiteZoneRadius},
+	} {
+		ct, err := tx.Exec(ctx, q.sync, tenantID, nil, q.radius)
+		if err != nil {
+			return 0, fmt.Errorf("failed to sync zones: %w", err)
+		}
+		synced += ct.RowsAffected()
+		if _, err := tx.Exec(ctx, q.drop, tenantID, nil); err != nil {
+			return 0, fmt.Errorf("failed to remove zones: %w", err)
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return 0, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return int(synced), nil
+}
+
+func (r *ZoneRepo) syncLinked(ctx context.Context, tenantID uuid.UUID, warehouseID, clientID *uuid.UUID) error {
+	sync, drop, radius, id := syncWarehouseZonesSQL, dropWarehouseZonesSQL, models.WarehouseZoneRadius, warehouseID
+	if clientID != nil {
+		sync, drop, radius, id = syncClientZonesSQL, dropClientZonesSQL, models.ClientSiteZoneRadius, clientID
+	}
+	if _, err := r.db.Exec(ctx, sync, tenantID, id, radius); err != nil {
+		return fmt.Errorf("failed to sync zone: %w", err)
+	}
+	if _, err := r.db.Exec(ctx, drop, tenantID, id); err != nil {
+		return fmt.Errorf("failed to remove zone: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,113 @@
+package rest
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"path/filepath"
+	"strings"
+
+	"cargomax-api/internal/imports"
+	"cargomax-api/internal/models"
+
+	"github.com/google/uuid"
+)
+
+// UploadImport handles POST /api/v1/manager/imports
+// Multipart form: entity (shipments, orders, inventory, drivers or vehicles)
+// and file (.csv or .xlsx, at most 10 MB and 20,000 rows). The file is parsed
+// and stored, and a batch is created with a column mapping suggested from the
+// header. Nothing is imported yet: the batch is validated and committed with
+// the validateImport and commitImport GraphQL mutations.
+func (h *ManagerHandler) UploadImport(w http.ResponseWriter, r *http.Request) {
+	tenantID := r.Context().Value(models.CtxTenantID).(uuid.UUID)
+	userID := r.Context().Value(models.CtxUserID).(uuid.UUID)
+
+	r.Body = http.MaxBytesReader(w, r.Body, models.ImportMaxFileBytes+1<<20)
+	if err := r.ParseMultipartForm(1 << 20); err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			jsonError(w, "upload too large", http.StatusRequestEntityTooLarge)
+			return
+		}
+		jsonError(w, "invalid multipart form", http.StatusBadRequest)
+		return
+	}
+	defer r.MultipartForm.RemoveAll()
+
+	entity := strings.TrimSpace(r.FormValue("entity"))
+	spec, ok := imports.SpecFor(entity)
+	if !ok {
+		jsonError(w, "entity must be shipments, orders, inventory, drivers or vehicles", http.StatusBadRequest)
+		return
+	}
+	files := r.MultipartForm.File["file"]
+	if len(files) != 1 {
+		jsonError(w, "exactly one file is required", http.StatusBadRequest)
+		return
+	}
+	fh := files[0]
+	if fh.Size > models.ImportMaxFileBytes {
+		jsonError(w, fmt.Sprintf("file exceeds %d MB", models.ImportMaxFileBytes>>20), http.StatusRequestEntityTooLarge)
+		return
+	}
+	f, err := fh.Open()
+	if err != nil {
+		jsonError(w, "file could not be read", http.StatusBadRequest)
+		return
+	}
+	data, err := io.ReadAll(f)
+	f.Close()
+	if err != nil {
+		jsonError(w, "file could not be read", http.StatusBadRequest)
+		return
+	}
+
+	table, err := imports.Parse(fh.Filename, data)
+	if err != nil {
+		jsonError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if len(table.Rows) == 0 {
+		jsonError(w, "file has no data rows", http.StatusBadRequest)
+		return
+	}
+	if len(table.Rows) > models.ImportMaxRows {
+		jsonError(w, fmt.Sprintf("file has more than %d rows", models.ImportMaxRows), http.StatusBadRequest)
+		return
+	}
+
+	id := uuid.New()
+	key := fmt.Sprintf("%s/imports/%s%s", tenantID, id, strings.ToLower(filepath.Ext(fh.Filename)))
+	if _, err := h.Storage.Put(r.Context(), key, bytes.NewReader(data)); err != nil {
+		log.Printf("manager: failed to store import file: %v", err)
+		jsonError(w, "failed to store file", http.StatusInternalServerError)
+		return
+	}
+
+	batch := &models.ImportBatch{
+		ID:         id,
+		TenantID:   tenantID,
+		Entity:     entity,
+		Filename:   filepath.Base(fh.Filename),
+		StorageKey: key,
+		Status:     models.ImportUploaded,
+		Mode:       models.ImportInsert,
+		Columns:    table.Header,
+		Mapping:    spec.Suggest(table.Header),
+		TotalRows:  len(table.Rows),
+		CreatedBy:  &userID,
+	}
+	if err := h.ImportRepo.Create(r.Context(), batch); err != nil {
+		log.Printf("manager: failed to create import batch: %v", err)
+		if err := h.Storage.Delete(r.Context(), key); err != nil {
+			log.Printf("manager: failed to delete orphaned import file %s: %v", key, err)
+		}
+		jsonError(w, "failed to create import", http.StatusInternalServerError)
+		return
+	}
+	jsonResponse(w, http.StatusCreated, batch)
+}
@@ -0,0 +1,66 @@
+package rest
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+
+	"cargomax-api/internal/invoicing"
+	"cargomax-api/internal/models"
+	"cargomax-api/internal/repository"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+)
+
+// GetInvoicePDF handles GET /api/v1/manager/invoices/{id}/pdf
+// Returns an invoice or credit note as an A4 PDF, issued by the tenant.
+func (h *ManagerHandler) GetInvoicePDF(w http.ResponseWriter, r *http.Request) {
+	tenantID := r.Context().Value(models.CtxTenantID).(uuid.UUID)
+
+	invoiceID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		jsonError(w, "invalid invoice id", http.StatusBadRequest)
+		return
+	}
+	inv, err := h.InvoiceRepo.GetByID(r.Context(), tenantID, invoiceID)
+	if errors.Is(err, repository.ErrInvoiceNotFound) {
+		jsonError(w, "invoice not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		log.Printf("manager: failed to get invoice: %v", err)
+		jsonError(w, "failed to get invoice", http.StatusInternalServerError)
+		return
+	}
+	issuer, err := h.labelSender(r, tenantID)
+	if err != nil {
+		log.Printf("manager: failed to get tenant: %v", err)
+		jsonError(w, "failed to get tenant", http.StatusInternalServerError)
+		return
+	}
+
+	doc := invoicing.Document{Invoice: inv, Issuer: issuer}
+	if inv.CreditedInvoiceID != nil {
+		credited, err := h.InvoiceRepo.GetByID(r.Context(), tenantID, *inv.CreditedInvoiceID)
+		if err != nil {
+			log.Printf("manager: failed to get credited invoice: %v", err)
+			jsonError(w, "failed to get credited invoice", http.StatusInternalServerError)
+			return
+		}
+		doc.CreditedNumber = credited.InvoiceNumber
+	}
+
+	var buf bytes.Buffer
+	if err := invoicing.PDF(&buf, doc); err != nil {
+		log.Printf("manager: failed to render invoice: %v", err)
+		jsonError(w, "failed to render invoice", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/pdf")
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s.pdf"`, inv.InvoiceNumber))
+	w.WriteHeader(http.StatusOK)
+	w.Write(buf.Bytes())
+}
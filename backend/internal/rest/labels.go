@@ -0,0 +1,151 @@
+package rest
+
+import (
+	"bytes"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"cargomax-api/internal/labels"
+	"cargomax-api/internal/models"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+)
+
+// labelFormat reads the format query parameter: "pdf" (the default) or
+// "zpl".
+func labelFormat(r *http.Request) (string, bool) {
+	switch f := r.URL.Query().Get("format"); f {
+	case "", "pdf":
+		return "pdf", true
+	case "zpl":
+		return "zpl", true
+	}
+	return "", false
+}
+
+// writeLabels renders ls in format and sends it as a download named name,
+// keeping only characters that are safe in a file name.
+func writeLabels(w http.ResponseWriter, format, name string, ls []labels.Label) {
+	name = strings.Map(func(r rune) rune {
+		if r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '-' || r == '_' {
+			return r
+		}
+		return '_'
+	}, name)
+	var buf bytes.Buffer
+	var err error
+	contentType := "application/pdf"
+	if format == "zpl" {
+		contentType = "application/zpl"
+		err = labels.ZPL(&buf, ls)
+	} else {
+		err = labels.PDF(&buf, ls)
+	}
+	if err != nil {
+		log.Printf("manager: failed to render labels: %v", err)
+		jsonError(w, "failed to render labels", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s.%s"`, name, format))
+	w.WriteHeader(http.StatusOK)
+	w.Write(buf.Bytes())
+}
+
+// labelSender is the shipper printed on the tenant's labels.
+func (h *ManagerHandler) labelSender(r *http.Request, tenantID uuid.UUID) (string, error) {
+	tenant, err := h.TenantRepo.GetByID(r.Context(), tenantID)
+	if err != nil {
+		return "", err
+	}
+	return tenant.Name, nil
+}
+
+// GetShipmentLabel handles GET /api/v1/manager/shipments/{id}/label
+// Returns the shipment's 4x6 label as a PDF, or as ZPL with ?format=zpl.
+func (h *ManagerHandler) GetShipmentLabel(w http.ResponseWriter, r *http.Request) {
+	tenantID := r.Context().Value(models.CtxTenantID).(uuid.UUID)
+
+	shipmentID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		jsonError(w, "invalid shipment id", http.StatusBadRequest)
+		return
+	}
+	format, ok := labelFormat(r)
+	if !ok {
+		jsonError(w, "format must be pdf or zpl", http.StatusBadRequest)
+		return
+	}
+
+	shipment, err := h.ShipmentRepo.GetByID(r.Context(), tenantID, shipmentID)
+	if err != nil {
+		jsonError(w, "shipment not found", http.StatusNotFound)
+		return
+	}
+	sender, err := h.labelSender(r, tenantID)
+	if err != nil {
+		log.Printf("manager: failed to load tenant for label: %v", err)
+		jsonError(w, "failed to render labels", http.StatusInternalServerError)
+		return
+	}
+
+	writeLabels(w, format, "label-"+shipment.TrackingNumber, []labels.Label{labels.ForShipment(shipment, sender, time.Now().UTC())})
+}
+
+// GetWarehouseLabels handles GET /api/v1/manager/warehouses/{id}/labels
+// Query params: date (YYYY-MM-DD, UTC, default today), format (pdf or zpl).
+// Returns the labels of every shipment leaving the warehouse that day in one
+// document, one page per shipment, in loading order: routed shipments by
+// route and stop, then the rest by creation time.
+func (h *ManagerHandler) GetWarehouseLabels(w http.ResponseWriter, r *http.Request) {
+	tenantID := r.Context().Value(models.CtxTenantID).(uuid.UUID)
+
+	warehouseID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		jsonError(w, "invalid warehouse id", http.StatusBadRequest)
+		return
+	}
+	format, ok := labelFormat(r)
+	if !ok {
+		jsonError(w, "format must be pdf or zpl", http.StatusBadRequest)
+		return
+	}
+	day := time.Now().UTC().Truncate(24 * time.Hour)
+	if v := r.URL.Query().Get("date"); v != "" {
+		if day, err = time.Parse("2006-01-02", v); err != nil {
+			jsonError(w, "date must be YYYY-MM-DD", http.StatusBadRequest)
+			return
+		}
+	}
+
+	if _, err := h.WarehouseRepo.GetByID(r.Context(), tenantID, warehouseID); err != nil {
+		jsonError(w, "warehouse not found", http.StatusNotFound)
+		return
+	}
+	shipments, err := h.ShipmentRepo.ListOutgoing(r.Context(), tenantID, warehouseID, day.Format("2006-01-02"))
+	if err != nil {
+		log.Printf("manager: failed to list outgoing shipments: %v", err)
+		jsonError(w, "failed to list outgoing shipments", http.StatusInternalServerError)
+		return
+	}
+	if len(shipments) == 0 {
+		jsonError(w, "no shipments leave this warehouse on that date", http.StatusNotFound)
+		return
+	}
+	sender, err := h.labelSender(r, tenantID)
+	if err != nil {
+		log.Printf("manager: failed to load tenant for label: %v", err)
+		jsonError(w, "failed to render labels", http.StatusInternalServerError)
+		return
+	}
+
+	ls := make([]labels.Label, len(shipments))
+	for i := range shipments {
+		ls[i] = labels.ForShipment(&shipments[i], sender, day)
+	}
+	writeLabels(w, format, "labels-"+day.Format("2006-01-02"), ls)
+}
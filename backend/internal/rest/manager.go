@@ -13,6 +13,7 @@ import (
 	"cargomax-api/internal/config"
 	"cargomax-api/internal/models"
 	"cargomax-api/internal/repository"
+	"cargomax-api/internal/storage"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/google/uuid"
@@ -28,10 +29,19 @@ type ManagerHandler struct {
 	PingRepo    *repository.GPSPingRepo
 	AlertRepo   *repository.AlertRepo
 	ZoneRepo    *repository.ZoneRepo
+
+	ShipmentRepo  *repository.ShipmentRepo
+	WarehouseRepo *repository.WarehouseRepo
+	TenantRepo    *repository.TenantRepo
+	ImportRepo    *repository.ImportRepo
+	InvoiceRepo   *repository.InvoiceRepo
+
+	// Storage holds uploaded import files.
+	Storage storage.Store
 }
 
 // NewManagerHandler constructs a ManagerHandler with all required dependencies.
-func NewManagerHandler(cfg *config.Config, driverRepo *repository.DriverRepo, vehicleRepo *repository.VehicleRepo, shiftRepo *repository.ShiftRepo, pingRepo *repository.GPSPingRepo, alertRepo *repository.AlertRepo, zoneRepo *repository.ZoneRepo) *ManagerHandler {
+func NewManagerHandler(cfg *config.Config, driverRepo *repository.DriverRepo, vehicleRepo *repository.VehicleRepo, shiftRepo *repository.ShiftRepo, pingRepo *repository.GPSPingRepo, alertRepo *repository.AlertRepo, zoneRepo *repository.ZoneRepo, shipmentRepo *repository.ShipmentRepo, warehouseRepo *repository.WarehouseRepo, tenantRepo *repository.TenantRepo, importRepo *repository.ImportRepo, invoiceRepo *repository.InvoiceRepo, store storage.Store) *ManagerHandler {
 	return &ManagerHandler{
 		Config:      cfg,
 		DriverRepo:  driverRepo,
@@ -40,6 +50,14 @@ func NewManagerHandler(cfg *config.Config, driverRepo *repository.DriverRepo, ve
 		PingRepo:    pingRepo,
 		AlertRepo:   alertRepo,
 		ZoneRepo:    zoneRepo,
+
+		ShipmentRepo:  shipmentRepo,
+		WarehouseRepo: warehouseRepo,
+		TenantRepo:    tenantRepo,
+		ImportRepo:    importRepo,
+		InvoiceRepo:   invoiceRepo,
+
+		Storage: store,
 	}
 }
 
@@ -69,6 +87,16 @@ func (h *ManagerHandler) Routes() chi.Router {
 	r.Put("/zones/{id}", h.UpdateZone)
 	r.Delete("/zones/{id}", h.DeleteZone)
 
+	// Shipping labels
+	r.Get("/shipments/{id}/label", h.GetShipmentLabel)
+	r.Get("/warehouses/{id}/labels", h.GetWarehouseLabels)
+
+	// Bulk imports; validated and committed through GraphQL
+	r.Post("/imports", h.UploadImport)
+
+	// Invoices; issued and paid through GraphQL
+	r.Get("/invoices/{id}/pdf", h.GetInvoicePDF)
+
 	return r
 }
 
@@ -129,9 +157,10 @@ func (h *ManagerHandler) managerAuthMiddleware(next http.Handler) http.Handler {
 func (h *ManagerHandler) GetLivePositions(w http.ResponseWriter, r *http.Request) {
 	tenantID := r.Context().Value(models.CtxTenantID).(uuid.UUID)
 
-	pings, err := h.PingRepo.GetLatestByTenant(r.Context(), tenantID)
+	// Driver names, truck plates and alert state come back joined in one query.
+	pings, err := h.PingRepo.GetLivePositions(r.Context(), tenantID, "", 30*time.Minute)
 	if err != nil {
-		log.Printf("manager: failed to get latest pings: %v", err)
+		log.Printf("manager: failed to get live positions: %v", err)
 		jsonError(w, "failed to fetch live positions", http.StatusInternalServerError)
 		return
 	}
@@ -146,28 +175,6 @@ func (h *ManagerHandler) GetLivePositions(w http.ResponseWriter, r *http.Request
 	now := time.Now()
 	positions := make([]map[string]interface{}, 0, len(pings))
 	for _, p := range pings {
-		// Resolve driver name.
-		driverName := ""
-		driver, _ := h.DriverRepo.GetByID(r.Context(), tenantID, p.DriverID)
-		if driver != nil {
-			if driver.FirstName != nil {
-				driverName = *driver.FirstName
-			}
-			if driver.LastName != nil {
-				if driverName != "" {
-					driverName += " "
-				}
-				driverName += *driver.LastName
-			}
-		}
-
-		// Resolve truck plate.
-		truckPlate := ""
-		truck, _ := h.VehicleRepo.GetByID(r.Context(), tenantID, p.TruckID)
-		if truck != nil && truck.LicensePlate != nil {
-			truckPlate = *truck.LicensePlate
-		}
-
 		// Determine status colour.
 		age := now.Sub(p.RecordedAt)
 		status := "green"
@@ -181,16 +188,15 @@ func (h *ManagerHandler) GetLivePositions(w http.ResponseWriter, r *http.Request
 		}
 
 		// Override with red if there is an active alert for this driver.
-		hasAlert, _ := h.AlertRepo.HasRecentAlert(r.Context(), tenantID, p.DriverID, "", 30)
-		if hasAlert {
+		if p.HasActiveAlert {
 			status = "red"
 		}
 
 		positions = append(positions, map[string]interface{}{
 			"driver_id":     p.DriverID,
-			"driver_name":   driverName,
+			"driver_name":   p.DriverName,
 			"truck_id":      p.TruckID,
-			"truck_plate":   truckPlate,
+			"truck_plate":   p.TruckPlate,
 			"shift_id":      p.ShiftID,
 			"latitude":      p.Latitude,
 			"longitude":     p.Longitude,
@@ -214,48 +220,21 @@ func (h *ManagerHandler) GetLivePositions(w http.ResponseWriter, r *http.Request
 func (h *ManagerHandler) GetActiveShifts(w http.ResponseWriter, r *http.Request) {
 	tenantID := r.Context().Value(models.CtxTenantID).(uuid.UUID)
 
-	allShifts, err := h.ShiftRepo.GetAllActive(r.Context())
+	active, err := h.ShiftRepo.GetActiveByTenant(r.Context(), tenantID)
 	if err != nil {
 		log.Printf("manager: failed to get active shifts: %v", err)
 		jsonError(w, "failed to fetch active shifts", http.StatusInternalServerError)
 		return
 	}
 
-	// Filter to this tenant only (GetAllActive is cross-tenant by design for the worker).
-	shifts := make([]map[string]interface{}, 0)
-	for _, s := range allShifts {
-		if s.TenantID != tenantID {
-			continue
-		}
-
-		// Resolve driver name.
-		driverName := ""
-		driver, _ := h.DriverRepo.GetByID(r.Context(), tenantID, s.DriverID)
-		if driver != nil {
-			if driver.FirstName != nil {
-				driverName = *driver.FirstName
-			}
-			if driver.LastName != nil {
-				if driverName != "" {
-					driverName += " "
-				}
-				driverName += *driver.LastName
-			}
-		}
-
-		// Resolve truck plate.
-		truckPlate := ""
-		truck, _ := h.VehicleRepo.GetByID(r.Context(), tenantID, s.TruckID)
-		if truck != nil && truck.LicensePlate != nil {
-			truckPlate = *truck.LicensePlate
-		}
-
+	shifts := make([]map[string]interface{}, 0, len(active))
+	for _, s := range active {
 		shifts = append(shifts, map[string]interface{}{
 			"shift_id":    s.ID,
 			"driver_id":   s.DriverID,
-			"driver_name": driverName,
+			"driver_name": s.DriverName,
 			"truck_id":    s.TruckID,
-			"truck_plate": truckPlate,
+			"truck_plate": s.TruckPlate,
 			"started_at":  s.StartedAt,
 			"total_km":    s.TotalKm,
 		})
@@ -0,0 +1,234 @@
+package rest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"math"
+	"mime/multipart"
+	"net/http"
+	"strings"
+	"time"
+
+	"cargomax-api/internal/models"
+	"cargomax-api/internal/repository"
+	"cargomax-api/internal/utils"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+)
+
+// podMaxRequestBytes caps a proof-of-delivery upload: a signature, the
+// maximum number of photos and some room for the form fields.
+const podMaxRequestBytes = (models.PODMaxPhotos+1)*models.PODMaxFileBytes + 1<<20
+
+// SubmitProofOfDelivery handles POST /api/v1/driver/shipments/{id}/pod
+// Multipart form: recipient_name, signature (one image), photos (optional
+// images), location and note (optional). The position and time are taken from
+// the driver's latest ping on the active shift, and the delivery is refused
+// when that position is outside the destination geofence. On success the
+// shipment moves to delivered; this is the only way a driver delivers a load.
+func (h *TrackingHandler) SubmitProofOfDelivery(w http.ResponseWriter, r *http.Request) {
+	tenantID := r.Context().Value(models.CtxTenantID).(uuid.UUID)
+	driverID := r.Context().Value(models.CtxUserID).(uuid.UUID)
+
+	shipmentID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		jsonError(w, "invalid shipment id", http.StatusBadRequest)
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, podMaxRequestBytes)
+	if err := r.ParseMultipartForm(1 << 20); err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			jsonError(w, "upload too large", http.StatusRequestEntityTooLarge)
+			return
+		}
+		jsonError(w, "invalid multipart form", http.StatusBadRequest)
+		return
+	}
+	defer r.MultipartForm.RemoveAll()
+
+	recipient := strings.TrimSpace(r.FormValue("recipient_name"))
+	if recipient == "" || len(recipient) > 255 {
+		jsonError(w, "recipient_name is required (max 255 characters)", http.StatusBadRequest)
+		return
+	}
+	signatures := r.MultipartForm.File["signature"]
+	if len(signatures) != 1 {
+		jsonError(w, "exactly one signature image is required", http.StatusBadRequest)
+		return
+	}
+	photos := r.MultipartForm.File["photos"]
+	if len(photos) > models.PODMaxPhotos {
+		jsonError(w, fmt.Sprintf("at most %d photos are allowed", models.PODMaxPhotos), http.StatusBadRequest)
+		return
+	}
+
+	// files[i] is stored from headers[i], the signature first.
+	var files []models.PODFile
+	var headers []*multipart.FileHeader
+	for _, u := range []struct {
+		kind    string
+		headers []*multipart.FileHeader
+	}{{models.PODSignature, signatures}, {models.PODPhoto, photos}} {
+		for _, fh := range u.headers {
+			contentType, err := sniffPODImage(fh)
+			if err != nil {
+				jsonError(w, fmt.Sprintf("%s %q: %v", u.kind, fh.Filename, err), http.StatusBadRequest)
+				return
+			}
+			files = append(files, models.PODFile{Kind: u.kind, ContentType: contentType, SizeBytes: fh.Size})
+			headers = append(headers, fh)
+		}
+	}
+
+	// Drivers may only deliver loads currently assigned to them.
+	assigned, err := h.ShipmentRepo.IsAssignedTo(r.Context(), tenantID, shipmentID, driverID)
+	if err != nil {
+		jsonError(w, "failed to check assignment", http.StatusInternalServerError)
+		return
+	}
+	if !assigned {
+		jsonError(w, "shipment not found", http.StatusNotFound)
+		return
+	}
+
+	shift, err := h.ShiftRepo.GetActiveByDriver(r.Context(), tenantID, driverID)
+	if err != nil {
+		jsonError(w, "no active shift", http.StatusConflict)
+		return
+	}
+	ping, err := h.PingRepo.GetLatestByDriver(r.Context(), tenantID, driverID)
+	if err != nil || ping.ShiftID != shift.ID || time.Since(ping.RecordedAt) > models.PODMaxPingAge {
+		jsonError(w, "no recent GPS position on the active shift", http.StatusConflict)
+		return
+	}
+
+	shipment, err := h.ShipmentRepo.GetByID(r.Context(), tenantID, shipmentID)
+	if err != nil {
+		log.Printf("tracking: failed to get shipment for proof of delivery: %v", err)
+		jsonError(w, "failed to get shipment", http.StatusInternalServerError)
+		return
+	}
+	if !models.CanTransitionShipment(shipment.Status, models.ShipmentDelivered) {
+		jsonError(w, fmt.Sprintf("%v: %s -> %s", repository.ErrIllegalShipmentTransition, shipment.Status, models.ShipmentDelivered), http.StatusConflict)
+		return
+	}
+	if shipment.DestinationLatitude == nil || shipment.DestinationLongitude == nil {
+		jsonError(w, "shipment has no destination coordinates to check the delivery against", http.StatusConflict)
+		return
+	}
+	distance := utils.HaversineMeters(ping.Latitude, ping.Longitude, *shipment.DestinationLatitude, *shipment.DestinationLongitude)
+	if distance > models.PODGeofenceMeters {
+		jsonResponse(w, http.StatusUnprocessableEntity, map[string]interface{}{
+			"error":          "too far from the delivery address",
+			"distance_m":     math.Round(distance),
+			"max_distance_m": models.PODGeofenceMeters,
+		})
+		return
+	}
+
+	for i := range files {
+		f := &files[i]
+		f.StorageKey = fmt.Sprintf("%s/pod/%s/%s%s", tenantID, shipmentID, uuid.New(), models.PODContentTypes[f.ContentType])
+		if err := h.storePODFile(r.Context(), f.StorageKey, headers[i]); err != nil {
+			log.Printf("tracking: failed to store proof of delivery file: %v", err)
+			h.deletePODFiles(files[:i])
+			jsonError(w, "failed to store proof of delivery", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	pod := &models.ProofOfDelivery{
+		ShipmentID:     shipmentID,
+		DriverID:       &driverID,
+		ShiftID:        &shift.ID,
+		RecipientName:  recipient,
+		Latitude:       ping.Latitude,
+		Longitude:      ping.Longitude,
+		Accuracy:       ping.Accuracy,
+		DistanceMeters: math.Round(distance*10) / 10,
+		CapturedAt:     ping.RecordedAt,
+		Files:          files,
+	}
+	note := "Received by " + recipient
+	if n := strings.TrimSpace(r.FormValue("note")); n != "" {
+		note += ": " + n
+	}
+	ev := &models.ShipmentEvent{ActorType: "driver", ActorID: &driverID, Note: &note}
+	if loc := strings.TrimSpace(r.FormValue("location")); loc != "" {
+		ev.Location = &loc
+	}
+
+	shipment, err = h.ShipmentRepo.Deliver(r.Context(), tenantID, pod, ev)
+	if err != nil {
+		h.deletePODFiles(files)
+		if errors.Is(err, repository.ErrIllegalShipmentTransition) {
+			jsonError(w, err.Error(), http.StatusConflict)
+			return
+		}
+		log.Printf("tracking: failed to record proof of delivery: %v", err)
+		jsonError(w, "failed to record proof of delivery", http.StatusInternalServerError)
+		return
+	}
+
+	jsonResponse(w, http.StatusCreated, map[string]interface{}{
+		"id":                shipment.ID,
+		"status":            shipment.Status,
+		"actual_delivery":   shipment.ActualDelivery,
+		"event_id":          ev.ID,
+		"proof_of_delivery": pod,
+	})
+}
+
+// sniffPODImage checks an uploaded signature or photo is an accepted image
+// within the size limit and returns its content type, judged from the bytes
+// rather than the client's header.
+func sniffPODImage(fh *multipart.FileHeader) (string, error) {
+	if fh.Size == 0 {
+		return "", errors.New("file is empty")
+	}
+	if fh.Size > models.PODMaxFileBytes {
+		return "", fmt.Errorf("file exceeds %d MB", models.PODMaxFileBytes>>20)
+	}
+	f, err := fh.Open()
+	if err != nil {
+		return "", errors.New("file could not be read")
+	}
+	defer f.Close()
+
+	head := make([]byte, 512)
+	n, _ := io.ReadFull(f, head)
+	contentType := http.DetectContentType(head[:n])
+	if _, ok := models.PODContentTypes[contentType]; !ok {
+		return "", errors.New("must be a PNG, JPEG or WebP image")
+	}
+	return contentType, nil
+}
+
+func (h *TrackingHandler) storePODFile(ctx context.Context, key string, fh *multipart.FileHeader) error {
+	f, err := fh.Open()
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = h.Storage.Put(ctx, key, f)
+	return err
+}
+
+// deletePODFiles removes files stored for a delivery that was not recorded.
+// It runs detached from the request, which may already be cancelled.
+func (h *TrackingHandler) deletePODFiles(files []models.PODFile) {
+	for _, f := range files {
+		if f.StorageKey == "" {
+			continue
+		}
+		if err := h.Storage.Delete(context.Background(), f.StorageKey); err != nil {
+			log.Printf("tracking: failed to delete orphaned proof of delivery file %s: %v", f.StorageKey, err)
+		}
+	}
+}
@@ -0,0 +1,73 @@
+package rest
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+
+	"cargomax-api/internal/middleware"
+	"cargomax-api/internal/repository"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// PublicTrackingHandler serves the unauthenticated tracking API used by end
+// customers holding a tracking number. It never reveals which tenant owns a
+// shipment and is throttled per client IP to stop enumeration.
+type PublicTrackingHandler struct {
+	ShipmentRepo *repository.ShipmentRepo
+	Limiter      *middleware.RateLimiter
+}
+
+// NewPublicTrackingHandler constructs a PublicTrackingHandler.
+func NewPublicTrackingHandler(shipmentRepo *repository.ShipmentRepo, limiter *middleware.RateLimiter) *PublicTrackingHandler {
+	return &PublicTrackingHandler{
+		ShipmentRepo: shipmentRepo,
+		Limiter:      limiter,
+	}
+}
+
+// Routes returns a chi.Router with the public tracking endpoints.
+func (h *PublicTrackingHandler) Routes() chi.Router {
+	r := chi.NewRouter()
+	r.Use(h.Limiter.PerIP)
+	r.Post("/track", h.Track)
+	return r
+}
+
+// Track handles POST /api/v1/public/track
+// Body: {"tracking_number": "SHP-1001", "verifier": "60601"}
+//
+// The verifier is the destination postcode or the customer's email address
+// (or the part before the @). It is taken from the body rather than the query
+// string so it does not end up in access logs.
+func (h *PublicTrackingHandler) Track(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		TrackingNumber string `json:"tracking_number"`
+		Verifier       string `json:"verifier"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		jsonError(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+	req.TrackingNumber = strings.TrimSpace(req.TrackingNumber)
+	if req.TrackingNumber == "" || strings.TrimSpace(req.Verifier) == "" {
+		jsonError(w, "tracking_number and verifier are required", http.StatusBadRequest)
+		return
+	}
+
+	tracking, err := h.ShipmentRepo.FindPublicTracking(r.Context(), req.TrackingNumber, req.Verifier)
+	if errors.Is(err, repository.ErrShipmentNotFound) {
+		jsonError(w, "no shipment matches that tracking number and verifier", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		log.Printf("public: failed to look up tracking number: %v", err)
+		jsonError(w, "failed to look up shipment", http.StatusInternalServerError)
+		return
+	}
+
+	jsonResponse(w, http.StatusOK, tracking)
+}
@@ -3,6 +3,7 @@ package rest
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"log"
 	"net/http"
 	"strings"
@@ -13,6 +14,7 @@ import (
 	"cargomax-api/internal/config"
 	"cargomax-api/internal/models"
 	"cargomax-api/internal/repository"
+	"cargomax-api/internal/storage"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/google/uuid"
@@ -20,31 +22,35 @@ import (
 )
 
 type TrackingHandler struct {
-	Config      *config.Config
-	DriverRepo  *repository.DriverRepo
-	VehicleRepo *repository.VehicleRepo
-	ShiftRepo   *repository.ShiftRepo
-	PingRepo    *repository.GPSPingRepo
-	AlertRepo   *repository.AlertRepo
-	ZoneRepo    *repository.ZoneRepo
-	WSHub       *Hub
+	Config       *config.Config
+	DriverRepo   *repository.DriverRepo
+	VehicleRepo  *repository.VehicleRepo
+	ShiftRepo    *repository.ShiftRepo
+	PingRepo     *repository.GPSPingRepo
+	AlertRepo    *repository.AlertRepo
+	ZoneRepo     *repository.ZoneRepo
+	ShipmentRepo *repository.ShipmentRepo
+	Storage      storage.Store
+	WSHub        *Hub
 
 	// Rate limiting: last ping time per driver
 	pingRateMu sync.Mutex
 	pingRates  map[uuid.UUID]time.Time
 }
 
-func NewTrackingHandler(cfg *config.Config, driverRepo *repository.DriverRepo, vehicleRepo *repository.VehicleRepo, shiftRepo *repository.ShiftRepo, pingRepo *repository.GPSPingRepo, alertRepo *repository.AlertRepo, zoneRepo *repository.ZoneRepo, hub *Hub) *TrackingHandler {
+func NewTrackingHandler(cfg *config.Config, driverRepo *repository.DriverRepo, vehicleRepo *repository.VehicleRepo, shiftRepo *repository.ShiftRepo, pingRepo *repository.GPSPingRepo, alertRepo *repository.AlertRepo, zoneRepo *repository.ZoneRepo, shipmentRepo *repository.ShipmentRepo, store storage.Store, hub *Hub) *TrackingHandler {
 	return &TrackingHandler{
-		Config:      cfg,
-		DriverRepo:  driverRepo,
-		VehicleRepo: vehicleRepo,
-		ShiftRepo:   shiftRepo,
-		PingRepo:    pingRepo,
-		AlertRepo:   alertRepo,
-		ZoneRepo:    zoneRepo,
-		WSHub:       hub,
-		pingRates:   make(map[uuid.UUID]time.Time),
+		Config:       cfg,
+		DriverRepo:   driverRepo,
+		VehicleRepo:  vehicleRepo,
+		ShiftRepo:    shiftRepo,
+		PingRepo:     pingRepo,
+		AlertRepo:    alertRepo,
+		ZoneRepo:     zoneRepo,
+		ShipmentRepo: shipmentRepo,
+		Storage:      store,
+		WSHub:        hub,
+		pingRates:    make(map[uuid.UUID]time.Time),
 	}
 }
 
@@ -63,6 +69,9 @@ func (h *TrackingHandler) Routes() chi.Router {
 		r.Post("/tracking/ping", h.ReceivePings)
 		r.Post("/auth/refresh-token", h.RefreshToken)
 		r.Get("/driver/active-shift", h.GetActiveShift)
+		r.Get("/driver/shipments", h.GetDriverShipments)
+		r.Post("/driver/shipments/{id}/status", h.UpdateDriverShipmentStatus)
+		r.Post("/driver/shipments/{id}/pod", h.SubmitProofOfDelivery)
 	})
 
 	return r
@@ -252,9 +261,16 @@ func (h *TrackingHandler) StartShift(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Loads assigned to the driver now ride on this shift's truck.
+	attached, err := h.ShipmentRepo.AttachToShift(r.Context(), tenantID, driverID, shift.ID, truckUUID)
+	if err != nil {
+		log.Printf("tracking: failed to attach shipments to shift %s: %v", shift.ID, err)
+	}
+
 	jsonResponse(w, http.StatusOK, map[string]interface{}{
-		"shift_id":   shift.ID,
-		"started_at": shift.StartedAt,
+		"shift_id":           shift.ID,
+		"started_at":         shift.StartedAt,
+		"shipments_attached": attached,
 	})
 }
 
@@ -476,6 +492,121 @@ func (h *TrackingHandler) GetActiveShift(w http.ResponseWriter, r *http.Request)
 	})
 }
 
+// driverShipmentStatuses are the statuses a driver may move their own loads
+// to from the mobile app; everything else is set from the dashboard. Drivers
+// deliver through SubmitProofOfDelivery instead.
+var driverShipmentStatuses = map[string]bool{
+	models.ShipmentPickedUp:       true,
+	models.ShipmentInTransit:      true,
+	models.ShipmentOutForDelivery: true,
+	models.ShipmentDelayed:        true,
+}
+
+// GetDriverShipments handles GET /api/v1/driver/shipments
+// Returns the open shipments assigned to the driver, the statuses each can be
+// moved to from the app and whether it can be delivered with a proof of
+// delivery.
+func (h *TrackingHandler) GetDriverShipments(w http.ResponseWriter, r *http.Request) {
+	tenantID := r.Context().Value(models.CtxTenantID).(uuid.UUID)
+	driverID := r.Context().Value(models.CtxUserID).(uuid.UUID)
+
+	shipments, err := h.ShipmentRepo.ListAssignedToDriver(r.Context(), tenantID, driverID)
+	if err != nil {
+		log.Printf("tracking: failed to list driver shipments: %v", err)
+		jsonError(w, "failed to list shipments", http.StatusInternalServerError)
+		return
+	}
+
+	result := make([]map[string]interface{}, 0, len(shipments))
+	for _, s := range shipments {
+		next := []string{}
+		for _, status := range models.NextShipmentStatuses(s.Status) {
+			if driverShipmentStatuses[status] {
+				next = append(next, status)
+			}
+		}
+		result = append(result, map[string]interface{}{
+			"id":                 s.ID,
+			"tracking_number":    s.TrackingNumber,
+			"status":             s.Status,
+			"origin":             s.Origin,
+			"destination":        s.Destination,
+			"customer_name":      s.CustomerName,
+			"weight":             s.Weight,
+			"dimensions":         s.Dimensions,
+			"estimated_delivery": s.EstimatedDelivery,
+			"notes":              s.Notes,
+			"next_statuses":      next,
+			"can_deliver":        models.CanTransitionShipment(s.Status, models.ShipmentDelivered),
+		})
+	}
+
+	jsonResponse(w, http.StatusOK, map[string]interface{}{"shipments": result})
+}
+
+// UpdateDriverShipmentStatus handles POST /api/v1/driver/shipments/{id}/status
+// Body: {"status": "picked_up", "location": "Dock 4", "note": "2 pallets"}
+func (h *TrackingHandler) UpdateDriverShipmentStatus(w http.ResponseWriter, r *http.Request) {
+	tenantID := r.Context().Value(models.CtxTenantID).(uuid.UUID)
+	driverID := r.Context().Value(models.CtxUserID).(uuid.UUID)
+
+	shipmentID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		jsonError(w, "invalid shipment id", http.StatusBadRequest)
+		return
+	}
+
+	var req struct {
+		Status   string `json:"status"`
+		Location string `json:"location"`
+		Note     string `json:"note"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		jsonError(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+	if !driverShipmentStatuses[req.Status] {
+		jsonError(w, "status not allowed from the driver app", http.StatusBadRequest)
+		return
+	}
+
+	// Drivers may only update loads currently assigned to them.
+	assigned, err := h.ShipmentRepo.IsAssignedTo(r.Context(), tenantID, shipmentID, driverID)
+	if err != nil {
+		jsonError(w, "failed to check assignment", http.StatusInternalServerError)
+		return
+	}
+	if !assigned {
+		jsonError(w, "shipment not found", http.StatusNotFound)
+		return
+	}
+
+	ev := &models.ShipmentEvent{Status: req.Status, ActorType: "driver", ActorID: &driverID}
+	if req.Location != "" {
+		ev.Location = &req.Location
+	}
+	if req.Note != "" {
+		ev.Note = &req.Note
+	}
+	shipment, err := h.ShipmentRepo.Transition(r.Context(), tenantID, shipmentID, ev)
+	if errors.Is(err, repository.ErrIllegalShipmentTransition) {
+		jsonError(w, err.Error(), http.StatusConflict)
+		return
+	}
+	if err != nil {
+		log.Printf("tracking: failed to update shipment status: %v", err)
+		jsonError(w, "failed to update shipment status", http.StatusInternalServerError)
+		return
+	}
+
+	jsonResponse(w, http.StatusOK, map[string]interface{}{
+		"id":              shipment.ID,
+		"status":          shipment.Status,
+		"actual_delivery": shipment.ActualDelivery,
+		"event_id":        ev.ID,
+	})
+}
+
 func jsonResponse(w http.ResponseWriter, status int, data interface{}) {
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(status)
@@ -117,6 +117,32 @@ func (h *Hub) BroadcastAlert(tenantID uuid.UUID, data interface{}) {
 	h.broadcast <- broadcastMsg{tenantID: tenantID, msgType: "alert", data: msg}
 }
 
+// BroadcastETA pushes a shipment's changed ETA to the tenant's tracking
+// subscribers as an "eta" message alongside the driver positions.
+func (h *Hub) BroadcastETA(tenantID uuid.UUID, data interface{}) {
+	msg, err := json.Marshal(map[string]interface{}{
+		"type": "eta",
+		"data": data,
+	})
+	if err != nil {
+		return
+	}
+	h.broadcast <- broadcastMsg{tenantID: tenantID, msgType: "tracking", data: msg}
+}
+
+// BroadcastRoute pushes a route stop arrival or departure to the tenant's
+// tracking subscribers as a "route" message for the dispatch board.
+func (h *Hub) BroadcastRoute(tenantID uuid.UUID, data interface{}) {
+	msg, err := json.Marshal(map[string]interface{}{
+		"type": "route",
+		"data": data,
+	})
+	if err != nil {
+		return
+	}
+	h.broadcast <- broadcastMsg{tenantID: tenantID, msgType: "tracking", data: msg}
+}
+
 // HandleTrackingWS handles WS /ws/tracking/live
 func (h *Hub) HandleTrackingWS(w http.ResponseWriter, r *http.Request) {
 	tenantID := h.authenticateWS(w, r)
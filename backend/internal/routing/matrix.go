@@ -0,0 +1,91 @@
+// Package routing plans delivery routes for a fleet: given a depot, a set of
+// jobs with weights and time windows and the vehicles available, it proposes
+// which vehicle serves which jobs and in what order.
+package routing
+
+import (
+	"fmt"
+	"time"
+
+	"cargomax-api/internal/utils"
+)
+
+// Matrix gives the travel between two locations by index. Index 0 is the
+// depot and job k is at index k+1.
+type Matrix interface {
+	// Distance returns the road distance from i to j in meters.
+	Distance(i, j int) float64
+	// Duration returns the driving time from i to j.
+	Duration(i, j int) time.Duration
+}
+
+// Point is a location in degrees.
+type Point struct {
+	Latitude  float64
+	Longitude float64
+}
+
+// table is a precomputed matrix driven at a constant speed.
+type table struct {
+	meters   [][]float64
+	speedKmh float64
+}
+
+func (t *table) Distance(i, j int) float64 { return t.meters[i][j] }
+
+func (t *table) Duration(i, j int) time.Duration {
+	return time.Duration(t.meters[i][j] / 1000 / t.speedKmh * float64(time.Hour)).Round(time.Minute)
+}
+
+// HaversineMatrix measures the straight line between points stretched by
+// roadFactor and drives it at speedKmh.
+func HaversineMatrix(points []Point, roadFactor, speedKmh float64) Matrix {
+	meters := make([][]float64, len(points))
+	for i, a := range points {
+		meters[i] = make([]float64, len(points))
+		for j, b := range points {
+			if i != j {
+				meters[i][j] = utils.HaversineMeters(a.Latitude, a.Longitude, b.Latitude, b.Longitude) * roadFactor
+			}
+		}
+	}
+	return &table{meters: meters, speedKmh: speedKmh}
+}
+
+// TableMatrix uses road distances in kilometers supplied by the caller, for
+// example from a routing service, driven at speedKmh. km must be square with
+// n rows and non-negative entries.
+func TableMatrix(km [][]float64, n int, speedKmh float64) (Matrix, error) {
+	if len(km) != n {
+		return nil, fmt.Errorf("distance matrix must have %d rows, got %d", n, len(km))
+	}
+	meters := make([][]float64, n)
+	for i, row := range km {
+		if len(row) != n {
+			return nil, fmt.Errorf("distance matrix row %d must have %d entries, got %d", i, n, len(row))
+		}
+		meters[i] = make([]float64, n)
+		for j, d := range row {
+			if d < 0 {
+				return nil, fmt.Errorf("distance matrix entry [%d][%d] is negative", i, j)
+			}
+			meters[i][j] = d * 1000
+		}
+	}
+	return &table{meters: meters, speedKmh: speedKmh}, nil
+}
+
+// subset is a matrix restricted to some of its locations.
+type subset struct {
+	m   Matrix
+	idx []int
+}
+
+func (s *subset) Distance(i, j int) float64       { return s.m.Distance(s.idx[i], s.idx[j]) }
+func (s *subset) Duration(i, j int) time.Duration { return s.m.Duration(s.idx[i], s.idx[j]) }
+
+// Subset is m restricted to the locations in idx, renumbered in that order:
+// location i of the result is location idx[i] of m.
+func Subset(m Matrix, idx []int) Matrix {
+	return &subset{m: m, idx: idx}
+}
@@ -0,0 +1,343 @@
+package routing
+
+import (
+	"math"
+	"sort"
+	"time"
+)
+
+// Reasons a job is left out of every route.
+const (
+	ReasonOverweight = "heavier than any vehicle's capacity"
+	ReasonWindow     = "delivery window closes before it can be reached"
+	ReasonShift      = "cannot be reached and returned from within the shift"
+	ReasonNoRoom     = "no vehicle has room left"
+)
+
+// maxPasses bounds the local search; each pass is quadratic in the number of
+// jobs, and it normally settles in a handful.
+const maxPasses = 50
+
+// Vehicle is a vehicle available for the day. A CapacityKg of zero means the
+// load is not limited.
+type Vehicle struct {
+	CapacityKg float64
+}
+
+// Job is a delivery to make. A driver early for WindowStart waits for it; a
+// job reached after WindowEnd is infeasible.
+type Job struct {
+	WeightKg    float64
+	Service     time.Duration
+	WindowStart *time.Time
+	WindowEnd   *time.Time
+}
+
+// Problem is one day's routing for a depot. Every route leaves the depot at
+// Start and must be back within ShiftLength (zero for no limit).
+type Problem struct {
+	Start       time.Time
+	ShiftLength time.Duration
+	Vehicles    []Vehicle
+	Jobs        []Job
+	Matrix      Matrix
+}
+
+// Route is the jobs one vehicle serves, in driving order, with the planned
+// arrival at each and the return to the depot.
+type Route struct {
+	Vehicle  int
+	Jobs     []int
+	Arrivals []time.Time
+	Return   time.Time
+	LoadKg   float64
+	Meters   float64
+}
+
+// Unassigned is a job no route could take.
+type Unassigned struct {
+	Job    int
+	Reason string
+}
+
+// Solution is the proposed routes, one per vehicle used, in vehicle order.
+type Solution struct {
+	Routes     []Route
+	Unassigned []Unassigned
+	Meters     float64
+}
+
+// Solve builds routes by nearest-neighbour seeding, one vehicle at a time
+// starting with the largest, inserts what is left at its cheapest feasible
+// position and then improves the routes with 2-opt, relocate and swap moves
+// until none shortens the total distance. Every route respects its vehicle's
+// capacity, the jobs' windows and the shift length.
+func Solve(p Problem) Solution {
+	s := &solver{p: p, routes: make([][]int, len(p.Vehicles))}
+
+	var open []int
+	var out []Unassigned
+	for j := range p.Jobs {
+		if reason := s.reject(j); reason != "" {
+			out = append(out, Unassigned{Job: j, Reason: reason})
+			continue
+		}
+		open = append(open, j)
+	}
+
+	open = s.seed(open)
+	open = s.insert(open)
+	for pass := 0; pass < maxPasses; pass++ {
+		if !s.twoOpt() && !s.relocate() && !s.swap() {
+			break
+		}
+		open = s.insert(open)
+	}
+	for _, j := range open {
+		out = append(out, Unassigned{Job: j, Reason: ReasonNoRoom})
+	}
+	sort.Slice(out, func(a, b int) bool { return out[a].Job < out[b].Job })
+
+	sol := Solution{Unassigned: out}
+	for v, jobs := range s.routes {
+		if len(jobs) == 0 {
+			continue
+		}
+		rt, _ := s.evaluate(v, jobs)
+		sol.Routes = append(sol.Routes, rt)
+		sol.Meters += rt.Meters
+	}
+	return sol
+}
+
+type solver struct {
+	p      Problem
+	routes [][]int
+}
+
+// evaluate drives jobs in order on vehicle v and reports whether that is
+// feasible.
+func (s *solver) evaluate(v int, jobs []int) (Route, bool) {
+	rt := Route{Vehicle: v, Jobs: append([]int(nil), jobs...), Arrivals: make([]time.Time, len(jobs))}
+	at, prev := s.p.Start, 0
+	ok := true
+	for i, j := range jobs {
+		job := s.p.Jobs[j]
+		rt.LoadKg += job.WeightKg
+		rt.Meters += s.p.Matrix.Distance(prev, j+1)
+		at = at.Add(s.p.Matrix.Duration(prev, j+1))
+		rt.Arrivals[i] = at
+		if job.WindowEnd != nil && at.After(*job.WindowEnd) {
+			ok = false
+		}
+		if job.WindowStart != nil && at.Before(*job.WindowStart) {
+			at = *job.WindowStart
+		}
+		at = at.Add(job.Service)
+		prev = j + 1
+	}
+	rt.Meters += s.p.Matrix.Distance(prev, 0)
+	rt.Return = at.Add(s.p.Matrix.Duration(prev, 0))
+
+	if c := s.p.Vehicles[v].CapacityKg; c > 0 && rt.LoadKg > c {
+		ok = false
+	}
+	if s.p.ShiftLength > 0 && rt.Return.Sub(s.p.Start) > s.p.ShiftLength {
+		ok = false
+	}
+	return rt, ok
+}
+
+// cost is the distance of jobs on vehicle v, or +Inf when infeasible.
+func (s *solver) cost(v int, jobs []int) float64 {
+	if len(jobs) == 0 {
+		return 0
+	}
+	rt, ok := s.evaluate(v, jobs)
+	if !ok {
+		return math.Inf(1)
+	}
+	return rt.Meters
+}
+
+// reject explains why job j cannot be served even alone on the best-suited
+// vehicle, or returns "" when it can.
+func (s *solver) reject(j int) string {
+	fits := false
+	for v, veh := range s.p.Vehicles {
+		if veh.CapacityKg > 0 && s.p.Jobs[j].WeightKg > veh.CapacityKg {
+			continue
+		}
+		fits = true
+		if _, ok := s.evaluate(v, []int{j}); ok {
+			return ""
+		}
+	}
+	if !fits {
+		return ReasonOverweight
+	}
+	job := s.p.Jobs[j]
+	if job.WindowEnd != nil && s.p.Start.Add(s.p.Matrix.Duration(0, j+1)).After(*job.WindowEnd) {
+		return ReasonWindow
+	}
+	return ReasonShift
+}
+
+// seed fills vehicles largest first, each by repeatedly driving to the
+// nearest open job it can still take. It returns the jobs left open.
+func (s *solver) seed(open []int) []int {
+	order := make([]int, len(s.p.Vehicles))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool {
+		ca, cb := s.p.Vehicles[order[a]].CapacityKg, s.p.Vehicles[order[b]].CapacityKg
+		return ca == 0 && cb != 0 || cb != 0 && ca > cb
+	})
+
+	for _, v := range order {
+		for len(open) > 0 {
+			prev := 0
+			if n := len(s.routes[v]); n > 0 {
+				prev = s.routes[v][n-1] + 1
+			}
+			best, bestDist := -1, math.Inf(1)
+			for i, j := range open {
+				d := s.p.Matrix.Distance(prev, j+1)
+				if d >= bestDist {
+					continue
+				}
+				if _, ok := s.evaluate(v, append(s.routes[v][:len(s.routes[v]):len(s.routes[v])], j)); ok {
+					best, bestDist = i, d
+				}
+			}
+			if best < 0 {
+				break
+			}
+			s.routes[v] = append(s.routes[v], open[best])
+			open = append(open[:best], open[best+1:]...)
+		}
+	}
+	return open
+}
+
+// insert places each open job at the feasible position, on any vehicle,
+// that adds the least distance. It returns the jobs that fit nowhere.
+func (s *solver) insert(open []int) []int {
+	var left []int
+	for _, j := range open {
+		bestV, bestPos, bestAdd := -1, 0, math.Inf(1)
+		for v, jobs := range s.routes {
+			base := s.cost(v, jobs)
+			for pos := 0; pos <= len(jobs); pos++ {
+				add := s.cost(v, insertAt(jobs, pos, j)) - base
+				if add < bestAdd {
+					bestV, bestPos, bestAdd = v, pos, add
+				}
+			}
+		}
+		if bestV < 0 {
+			left = append(left, j)
+			continue
+		}
+		s.routes[bestV] = insertAt(s.routes[bestV], bestPos, j)
+	}
+	return left
+}
+
+// twoOpt reverses the first segment of a route whose reversal shortens it.
+func (s *solver) twoOpt() bool {
+	for v, jobs := range s.routes {
+		base := s.cost(v, jobs)
+		for i := 0; i < len(jobs)-1; i++ {
+			for k := i + 1; k < len(jobs); k++ {
+				next := append([]int(nil), jobs...)
+				for a, b := i, k; a < b; a, b = a+1, b-1 {
+					next[a], next[b] = next[b], next[a]
+				}
+				if improves(s.cost(v, next), base) {
+					s.routes[v] = next
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+// relocate moves the first job whose move to another position, on the same
+// or another vehicle, shortens the total.
+func (s *solver) relocate() bool {
+	for a, from := range s.routes {
+		baseA := s.cost(a, from)
+		for i, j := range from {
+			rest := removeAt(from, i)
+			restCost := s.cost(a, rest)
+			for b, to := range s.routes {
+				if a == b {
+					for pos := 0; pos <= len(rest); pos++ {
+						if pos == i {
+							continue
+						}
+						next := insertAt(rest, pos, j)
+						if improves(s.cost(a, next), baseA) {
+							s.routes[a] = next
+							return true
+						}
+					}
+					continue
+				}
+				baseB := s.cost(b, to)
+				for pos := 0; pos <= len(to); pos++ {
+					next := insertAt(to, pos, j)
+					if improves(restCost+s.cost(b, next), baseA+baseB) {
+						s.routes[a], s.routes[b] = rest, next
+						return true
+					}
+				}
+			}
+		}
+	}
+	return false
+}
+
+// swap exchanges the first pair of jobs on two vehicles whose exchange
+// shortens the total.
+func (s *solver) swap() bool {
+	for a := range s.routes {
+		for b := a + 1; b < len(s.routes); b++ {
+			base := s.cost(a, s.routes[a]) + s.cost(b, s.routes[b])
+			for i := range s.routes[a] {
+				for k := range s.routes[b] {
+					na := append([]int(nil), s.routes[a]...)
+					nb := append([]int(nil), s.routes[b]...)
+					na[i], nb[k] = nb[k], na[i]
+					if improves(s.cost(a, na)+s.cost(b, nb), base) {
+						s.routes[a], s.routes[b] = na, nb
+						return true
+					}
+				}
+			}
+		}
+	}
+	return false
+}
+
+// improves reports whether next is shorter than base by more than a meter,
+// so rounding cannot make the search cycle.
+func improves(next, base float64) bool {
+	return next < base-1
+}
+
+func insertAt(jobs []int, pos, j int) []int {
+	out := make([]int, 0, len(jobs)+1)
+	out = append(out, jobs[:pos]...)
+	out = append(out, j)
+	return append(out, jobs[pos:]...)
+}
+
+func removeAt(jobs []int, i int) []int {
+	out := make([]int, 0, len(jobs)-1)
+	out = append(out, jobs[:i]...)
+	return append(out, jobs[i+1:]...)
+}
@@ -0,0 +1,243 @@
+package routing
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+// start is the shift start in every fixture.
+var start = time.Date(2025, 3, 4, 8, 0, 0, 0, time.UTC)
+
+// plane is a matrix over points in km, the depot first, driven at 6 km/h so
+// each km takes ten minutes and rounding to the minute stays small.
+func plane(t *testing.T, points ...[2]float64) Matrix {
+	t.Helper()
+	km := make([][]float64, len(points))
+	for i, a := range points {
+		km[i] = make([]float64, len(points))
+		for j, b := range points {
+			km[i][j] = math.Hypot(a[0]-b[0], a[1]-b[1])
+		}
+	}
+	m, err := TableMatrix(km, len(points), 6)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return m
+}
+
+// line is plane with every point on the x axis.
+func line(t *testing.T, xs ...float64) Matrix {
+	t.Helper()
+	points := make([][2]float64, len(xs))
+	for i, x := range xs {
+		points[i] = [2]float64{x, 0}
+	}
+	return plane(t, points...)
+}
+
+func by(d time.Duration) *time.Time {
+	t := start.Add(d)
+	return &t
+}
+
+func TestSolveRejects(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		p    Problem
+		want map[int]string
+	}{
+		{
+			name: "too heavy for every vehicle",
+			p: Problem{Vehicles: []Vehicle{{CapacityKg: 100}, {CapacityKg: 120}},
+				Jobs: []Job{{WeightKg: 150}, {WeightKg: 90}}, Matrix: line(t, 0, 1, 2)},
+			want: map[int]string{0: ReasonOverweight},
+		},
+		{
+			name: "an unlimited vehicle takes any weight",
+			p:    Problem{Vehicles: []Vehicle{{CapacityKg: 100}, {}}, Jobs: []Job{{WeightKg: 150}}, Matrix: line(t, 0, 1)},
+			want: map[int]string{},
+		},
+		{
+			name: "window closes before the drive there",
+			p:    Problem{Vehicles: []Vehicle{{}}, Jobs: []Job{{WindowEnd: by(20 * time.Minute)}, {WindowEnd: by(time.Hour)}}, Matrix: line(t, 0, 3, 3)},
+			want: map[int]string{0: ReasonWindow},
+		},
+		{
+			name: "out and back takes longer than the shift",
+			p:    Problem{ShiftLength: time.Hour, Vehicles: []Vehicle{{}}, Jobs: []Job{{}, {}}, Matrix: line(t, 0, 4, 2)},
+			want: map[int]string{0: ReasonShift},
+		},
+		{
+			name: "service time counts against the shift",
+			p:    Problem{ShiftLength: time.Hour, Vehicles: []Vehicle{{}}, Jobs: []Job{{Service: 25 * time.Minute}}, Matrix: line(t, 0, 2)},
+			want: map[int]string{0: ReasonShift},
+		},
+		{
+			name: "waiting for a window counts against the shift",
+			p: Problem{ShiftLength: time.Hour, Vehicles: []Vehicle{{}},
+				Jobs: []Job{{WindowStart: by(55 * time.Minute), WindowEnd: by(2 * time.Hour)}}, Matrix: line(t, 0, 1)},
+			want: map[int]string{0: ReasonShift},
+		},
+		{
+			name: "each fits alone but not together",
+			p:    Problem{Vehicles: []Vehicle{{CapacityKg: 100}}, Jobs: []Job{{WeightKg: 60}, {WeightKg: 60}}, Matrix: line(t, 0, 1, 2)},
+			want: map[int]string{1: ReasonNoRoom},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			tc.p.Start = start
+			sol := Solve(tc.p)
+			got := map[int]string{}
+			for _, u := range sol.Unassigned {
+				got[u.Job] = u.Reason
+			}
+			if len(got) != len(tc.want) {
+				t.Fatalf("unassigned %v, want %v", got, tc.want)
+			}
+			for j, reason := range tc.want {
+				if got[j] != reason {
+					t.Errorf("job %d: %q, want %q", j, got[j], reason)
+				}
+			}
+			checkSolution(t, tc.p, sol)
+		})
+	}
+}
+
+// checkSolution asserts every job is routed once or left out, and every
+// route keeps to its vehicle's capacity, the windows and the shift.
+func checkSolution(t *testing.T, p Problem, sol Solution) {
+	t.Helper()
+	seen := map[int]int{}
+	for _, u := range sol.Unassigned {
+		seen[u.Job]++
+	}
+	meters := 0.0
+	for _, rt := range sol.Routes {
+		load := 0.0
+		for i, j := range rt.Jobs {
+			seen[j]++
+			load += p.Jobs[j].WeightKg
+			if end := p.Jobs[j].WindowEnd; end != nil && rt.Arrivals[i].After(*end) {
+				t.Errorf("vehicle %d reaches job %d at %s, after its window", rt.Vehicle, j, rt.Arrivals[i].Format("15:04"))
+			}
+		}
+		if c := p.Vehicles[rt.Vehicle].CapacityKg; c > 0 && load > c {
+			t.Errorf("vehicle %d carries %v kg of its %v", rt.Vehicle, load, c)
+		}
+		if load != rt.LoadKg {
+			t.Errorf("vehicle %d reports %v kg, carries %v", rt.Vehicle, rt.LoadKg, load)
+		}
+		if p.ShiftLength > 0 && rt.Return.Sub(p.Start) > p.ShiftLength {
+			t.Errorf("vehicle %d is back after %s", rt.Vehicle, rt.Return.Sub(p.Start))
+		}
+		meters += rt.Meters
+	}
+	for j := range p.Jobs {
+		if seen[j] != 1 {
+			t.Errorf("job %d appears %d times", j, seen[j])
+		}
+	}
+	if math.Abs(meters-sol.Meters) > 0.001 {
+		t.Errorf("routes add up to %v m, solution says %v", meters, sol.Meters)
+	}
+}
+
+func TestSolveFeasibleAndSettled(t *testing.T) {
+	// Two clusters either side of the depot, with windows and weights that
+	// keep the vehicles from simply taking a side each.
+	p := Problem{
+		Start:       start,
+		ShiftLength: 4 * time.Hour,
+		Vehicles:    []Vehicle{{CapacityKg: 50}, {CapacityKg: 80}, {}},
+		Jobs: []Job{
+			{WeightKg: 20, Service: 5 * time.Minute},
+			{WeightKg: 30, WindowEnd: by(40 * time.Minute)},
+			{WeightKg: 25, WindowStart: by(time.Hour), WindowEnd: by(2 * time.Hour)},
+			{WeightKg: 10},
+			{WeightKg: 40, Service: 10 * time.Minute},
+			{WeightKg: 15, WindowEnd: by(30 * time.Minute)},
+			{WeightKg: 35},
+			{WeightKg: 5, WindowStart: by(90 * time.Minute)},
+		},
+		Matrix: plane(t, [2]float64{0, 0},
+			[2]float64{2, 1}, [2]float64{3, 0}, [2]float64{2, -2}, [2]float64{4, 1},
+			[2]float64{-2, 1}, [2]float64{-1, -1}, [2]float64{-3, 2}, [2]float64{-4, 0}),
+	}
+	sol := Solve(p)
+	checkSolution(t, p, sol)
+	if len(sol.Unassigned) != 0 {
+		t.Errorf("left out %v", sol.Unassigned)
+	}
+
+	// The local search has nothing left to improve.
+	s := &solver{p: p, routes: make([][]int, len(p.Vehicles))}
+	for _, rt := range sol.Routes {
+		s.routes[rt.Vehicle] = rt.Jobs
+	}
+	if s.twoOpt() || s.relocate() || s.swap() {
+		t.Error("the solution can still be shortened")
+	}
+}
+
+func TestTwoOpt(t *testing.T) {
+	// A unit square: visiting its corners out of order crosses the route.
+	m := plane(t, [2]float64{0, 0}, [2]float64{0, 1}, [2]float64{1, 1}, [2]float64{1, 0})
+	p := Problem{Start: start, Vehicles: []Vehicle{{}}, Jobs: make([]Job, 3), Matrix: m}
+	s := &solver{p: p, routes: [][]int{{0, 2, 1}}}
+	if !s.twoOpt() {
+		t.Fatal("twoOpt left a crossing route")
+	}
+	if c := s.cost(0, s.routes[0]); math.Abs(c-4000) > 1 {
+		t.Errorf("route %v is %v m, want 4000", s.routes[0], c)
+	}
+
+	// When job 2 must be reached within 25 minutes, uncrossing the route
+	// would miss it.
+	p.Jobs[2].WindowEnd = by(25 * time.Minute)
+	s = &solver{p: p, routes: [][]int{{0, 2, 1}}}
+	if s.twoOpt() {
+		t.Errorf("twoOpt made route %v, which misses a window", s.routes[0])
+	}
+}
+
+func TestRelocate(t *testing.T) {
+	m := line(t, 0, 5, 6, -5)
+	p := Problem{Start: start, Vehicles: []Vehicle{{}, {}}, Jobs: []Job{{WeightKg: 8}, {WeightKg: 8}, {WeightKg: 1}}, Matrix: m}
+	s := &solver{p: p, routes: [][]int{{0, 2}, {1}}}
+	if !s.relocate() {
+		t.Fatal("relocate left a job on the far side")
+	}
+	if total := s.cost(0, s.routes[0]) + s.cost(1, s.routes[1]); total >= 32000 {
+		t.Errorf("routes %v are %v m, want under 32000", s.routes, total)
+	}
+
+	// With room for a single heavy job each, there is no better place for
+	// any of them.
+	p.Vehicles = []Vehicle{{CapacityKg: 10}, {CapacityKg: 10}}
+	s = &solver{p: p, routes: [][]int{{0, 2}, {1}}}
+	if s.relocate() {
+		t.Errorf("relocate made routes %v, which overload a vehicle", s.routes)
+	}
+}
+
+func TestSwap(t *testing.T) {
+	m := line(t, 0, 5, -6, -5, 6)
+	p := Problem{Start: start, Vehicles: []Vehicle{{}, {}}, Jobs: []Job{{WeightKg: 5}, {WeightKg: 1}, {WeightKg: 6}, {WeightKg: 6}}, Matrix: m}
+	s := &solver{p: p, routes: [][]int{{0, 1}, {2, 3}}}
+	if !s.swap() {
+		t.Fatal("swap left both routes crossing the depot")
+	}
+	if total := s.cost(0, s.routes[0]) + s.cost(1, s.routes[1]); total >= 44000 {
+		t.Errorf("routes %v are %v m, want under 44000", s.routes, total)
+	}
+
+	// The first vehicle is full, and every exchange would overload it.
+	p.Vehicles[0].CapacityKg = 6
+	s = &solver{p: p, routes: [][]int{{0, 1}, {2, 3}}}
+	if s.swap() {
+		t.Errorf("swap made routes %v, which overload a vehicle", s.routes)
+	}
+}
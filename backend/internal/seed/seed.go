@@ -6,6 +6,8 @@ import (
 	"log"
 	"time"
 
+	"cargomax-api/internal/database"
+
 	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5/pgxpool"
 	"golang.org/x/crypto/bcrypt"
@@ -14,7 +16,8 @@ import (
 // SeedData populates the database with realistic demo data for two tenants.
 // All inserts use ON CONFLICT DO NOTHING so the function is idempotent.
 func SeedData(pool *pgxpool.Pool) error {
-	ctx := context.Background()
+	// Seeding writes rows for several tenants, so it bypasses row-level security.
+	ctx := database.Privileged(context.Background())
 
 	log.Println("Seeding database with demo data...")
 
@@ -291,6 +294,32 @@ func SeedData(pool *pgxpool.Pool) error {
 		"St. Louis, MO", "Pittsburgh, PA",
 	}
 
+	// Approximate city-centre coordinates, used as the destination of the
+	// live ETA.
+	cityCoords := map[string][2]float64{
+		"Los Angeles, CA": {34.0522, -118.2437}, "New York, NY": {40.7128, -74.0060},
+		"Chicago, IL": {41.8781, -87.6298}, "Houston, TX": {29.7604, -95.3698},
+		"Phoenix, AZ": {33.4484, -112.0740}, "Philadelphia, PA": {39.9526, -75.1652},
+		"San Antonio, TX": {29.4241, -98.4936}, "San Diego, CA": {32.7157, -117.1611},
+		"Dallas, TX": {32.7767, -96.7970}, "San Jose, CA": {37.3382, -121.8863},
+		"Austin, TX": {30.2672, -97.7431}, "Jacksonville, FL": {30.3322, -81.6557},
+		"Fort Worth, TX": {32.7555, -97.3308}, "Columbus, OH": {39.9612, -82.9988},
+		"Charlotte, NC": {35.2271, -80.8431}, "Indianapolis, IN": {39.7684, -86.1581},
+		"San Francisco, CA": {37.7749, -122.4194}, "Seattle, WA": {47.6062, -122.3321},
+		"Denver, CO": {39.7392, -104.9903}, "Nashville, TN": {36.1627, -86.7816},
+		"Oklahoma City, OK": {35.4676, -97.5164}, "Portland, OR": {45.5152, -122.6784},
+		"Las Vegas, NV": {36.1699, -115.1398}, "Memphis, TN": {35.1495, -90.0490},
+		"Louisville, KY": {38.2527, -85.7585}, "Baltimore, MD": {39.2904, -76.6122},
+		"Milwaukee, WI": {43.0389, -87.9065}, "Albuquerque, NM": {35.0844, -106.6504},
+		"Tucson, AZ": {32.2226, -110.9747}, "Fresno, CA": {36.7378, -119.7871},
+		"Sacramento, CA": {38.5816, -121.4944}, "Mesa, AZ": {33.4152, -111.8315},
+		"Kansas City, MO": {39.0997, -94.5786}, "Atlanta, GA": {33.7490, -84.3880},
+		"Omaha, NE": {41.2565, -95.9345}, "Miami, FL": {25.7617, -80.1918},
+		"Minneapolis, MN": {44.9778, -93.2650}, "Raleigh, NC": {35.7796, -78.6382},
+		"Cleveland, OH": {41.4993, -81.6944}, "Tampa, FL": {27.9506, -82.4572},
+		"St. Louis, MO": {38.6270, -90.1994}, "Pittsburgh, PA": {40.4406, -79.9959},
+	}
+
 	carriers := []string{
 		"Acme Fleet", "FastFreight Express", "Continental Hauling", "Pacific Cargo Lines",
 		"Midwest Logistics", "Southern Transport Co", "Atlantic Freight", "Mountain Express",
@@ -380,18 +409,89 @@ func SeedData(pool *pgxpool.Pool) error {
 		}
 	}
 
-	shipmentSQL := `INSERT INTO shipments (id, tenant_id, tracking_number, origin, destination, status, carrier, weight, dimensions, estimated_delivery, actual_delivery, customer_name, customer_email, notes, created_at, updated_at)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16)
+	shipmentSQL := `INSERT INTO shipments (id, tenant_id, tracking_number, origin, destination, status, carrier, weight, dimensions, estimated_delivery, actual_delivery, customer_name, customer_email, notes, destination_latitude, destination_longitude, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18)
 		ON CONFLICT (tenant_id, tracking_number) DO NOTHING`
 
 	allShipments := append(acmeShipments, betaShipments...)
 	for _, s := range allShipments {
 		createdAt := now.Add(-time.Duration(5+int(s.weight)%10) * 24 * time.Hour)
-		if _, err := pool.Exec(ctx, shipmentSQL, s.id, s.tenantID, s.tracking, s.origin, s.destination, s.status, s.carrier, s.weight, s.dimensions, s.estDelivery, s.actDelivery, s.custName, s.custEmail, s.notes, createdAt, now); err != nil {
+		dest := cityCoords[s.destination]
+		if _, err := pool.Exec(ctx, shipmentSQL, s.id, s.tenantID, s.tracking, s.origin, s.destination, s.status, s.carrier, s.weight, s.dimensions, s.estDelivery, s.actDelivery, s.custName, s.custEmail, s.notes, dest[0], dest[1], createdAt, now); err != nil {
 			return fmt.Errorf("seed shipments (%s): %w", s.tracking, err)
 		}
 	}
 
+	// Give every shipment a plausible tracking timeline ending in its status.
+	// Selecting from shipments skips rows the insert above did not create.
+	eventSQL := `INSERT INTO shipment_events (tenant_id, shipment_id, status, location, actor_type, occurred_at, created_at)
+		SELECT tenant_id, id, $2, $3, 'system', $4, $4 FROM shipments WHERE id = $1`
+
+	for _, s := range allShipments {
+		createdAt := now.Add(-time.Duration(5+int(s.weight)%10) * 24 * time.Hour)
+		var path []string
+		switch s.status {
+		case "in_transit":
+			path = []string{"pending", "picked_up", "in_transit"}
+		case "delayed":
+			path = []string{"pending", "picked_up", "in_transit", "delayed"}
+		case "delivered":
+			path = []string{"pending", "picked_up", "in_transit", "out_for_delivery", "delivered"}
+		default:
+			path = []string{"pending"}
+		}
+		for i, status := range path {
+			at := createdAt.Add(time.Duration(i*18) * time.Hour)
+			location := s.origin
+			if status == "out_for_delivery" || status == "delivered" {
+				location = s.destination
+			}
+			if status == "delivered" && s.actDelivery != nil {
+				at = *s.actDelivery
+			}
+			if _, err := pool.Exec(ctx, eventSQL, s.id, status, location, at); err != nil {
+				return fmt.Errorf("seed shipment events (%s): %w", s.tracking, err)
+			}
+		}
+	}
+
+	// Lane history for the delivered shipments gives the live ETA a prior
+	// pace before a shift has reported enough pings of its own.
+	laneSQL := `INSERT INTO lane_speeds (tenant_id, origin, destination, avg_speed_kmh, samples, updated_at)
+		VALUES ($1, LOWER($2), LOWER($3), $4, $5, $6)
+		ON CONFLICT (tenant_id, origin, destination) DO NOTHING`
+
+	for i, s := range allShipments {
+		if s.status != "delivered" {
+			continue
+		}
+		if _, err := pool.Exec(ctx, laneSQL, s.tenantID, s.origin, s.destination, 52.0+float64(i%7)*2.5, 3+i%5, now); err != nil {
+			return fmt.Errorf("seed lane speeds (%s): %w", s.tracking, err)
+		}
+	}
+
+	// Put every shipment that is on the road in the hands of a driver and the
+	// vehicle that driver is assigned to.
+	assignmentSQL := `INSERT INTO shipment_assignments (tenant_id, shipment_id, driver_id, vehicle_id, assigned_at)
+		SELECT tenant_id, id, $2, $3, $4 FROM shipments WHERE id = $1`
+
+	onTheRoad := 0
+	for _, s := range allShipments {
+		if s.status != "in_transit" && s.status != "delayed" {
+			continue
+		}
+		drivers := acmeDrivers[:20]
+		if s.tenantID == betaTenantID {
+			drivers = betaDrivers
+		}
+		d := drivers[onTheRoad%len(drivers)]
+		onTheRoad++
+		assignedAt := now.Add(-time.Duration(5+int(s.weight)%10) * 24 * time.Hour).Add(12 * time.Hour)
+		if _, err := pool.Exec(ctx, assignmentSQL, s.id, d.id, d.vehicleID, assignedAt); err != nil {
+			return fmt.Errorf("seed shipment assignments (%s): %w", s.tracking, err)
+		}
+	}
+
 	// ---------------------------------------------------------------
 	// 6. MAINTENANCE RECORDS — Acme (20)
 	// ---------------------------------------------------------------
@@ -437,41 +537,42 @@ func SeedData(pool *pgxpool.Pool) error {
 		location string
 		address  string
 		capacity int
-		used     int
 		manager  string
 		phone    string
 		status   string
 	}
 
 	acmeWarehouses := []seedWarehouse{
-		{uuid.New(), acmeTenantID, "LA Distribution Center", "Los Angeles, CA", "1200 Alameda St, Los Angeles, CA 90012", 50000, 38500, "Robert Chang", "(213) 555-0101", "active"},
-		{uuid.New(), acmeTenantID, "Chicago Hub", "Chicago, IL", "4500 W Roosevelt Rd, Chicago, IL 60624", 45000, 31200, "Maria Santos", "(312) 555-0202", "active"},
-		{uuid.New(), acmeTenantID, "Dallas Mega Warehouse", "Dallas, TX", "8900 Stemmons Fwy, Dallas, TX 75247", 60000, 42000, "Tom Bradley", "(214) 555-0303", "active"},
-		{uuid.New(), acmeTenantID, "Atlanta Fulfillment", "Atlanta, GA", "2100 Donald Lee Hollowell Pkwy, Atlanta, GA 30318", 35000, 28700, "Keisha Williams", "(404) 555-0404", "active"},
-		{uuid.New(), acmeTenantID, "Seattle Cold Storage", "Seattle, WA", "3700 E Marginal Way S, Seattle, WA 98134", 25000, 19800, "Derek Olson", "(206) 555-0505", "active"},
-		{uuid.New(), acmeTenantID, "Miami Port Warehouse", "Miami, FL", "1000 Port Blvd, Miami, FL 33132", 40000, 35600, "Carlos Mendez", "(305) 555-0606", "active"},
-		{uuid.New(), acmeTenantID, "Denver Transit Hub", "Denver, CO", "5600 E 56th Ave, Commerce City, CO 80022", 30000, 18900, "Angela Torres", "(720) 555-0707", "active"},
-		{uuid.New(), acmeTenantID, "NYC Metro Facility", "Newark, NJ", "100 Port St, Newark, NJ 07114", 55000, 48200, "Frank DeLuca", "(973) 555-0808", "active"},
+		{uuid.New(), acmeTenantID, "LA Distribution Center", "Los Angeles, CA", "1200 Alameda St, Los Angeles, CA 90012", 50000, "Robert Chang", "(213) 555-0101", "active"},
+		{uuid.New(), acmeTenantID, "Chicago Hub", "Chicago, IL", "4500 W Roosevelt Rd, Chicago, IL 60624", 45000, "Maria Santos", "(312) 555-0202", "active"},
+		{uuid.New(), acmeTenantID, "Dallas Mega Warehouse", "Dallas, TX", "8900 Stemmons Fwy, Dallas, TX 75247", 60000, "Tom Bradley", "(214) 555-0303", "active"},
+		{uuid.New(), acmeTenantID, "Atlanta Fulfillment", "Atlanta, GA", "2100 Donald Lee Hollowell Pkwy, Atlanta, GA 30318", 35000, "Keisha Williams", "(404) 555-0404", "active"},
+		{uuid.New(), acmeTenantID, "Seattle Cold Storage", "Seattle, WA", "3700 E Marginal Way S, Seattle, WA 98134", 25000, "Derek Olson", "(206) 555-0505", "active"},
+		{uuid.New(), acmeTenantID, "Miami Port Warehouse", "Miami, FL", "1000 Port Blvd, Miami, FL 33132", 40000, "Carlos Mendez", "(305) 555-0606", "active"},
+		{uuid.New(), acmeTenantID, "Denver Transit Hub", "Denver, CO", "5600 E 56th Ave, Commerce City, CO 80022", 30000, "Angela Torres", "(720) 555-0707", "active"},
+		{uuid.New(), acmeTenantID, "NYC Metro Facility", "Newark, NJ", "100 Port St, Newark, NJ 07114", 55000, "Frank DeLuca", "(973) 555-0808", "active"},
 	}
 
 	betaWarehouses := []seedWarehouse{
-		{uuid.New(), betaTenantID, "Beta Central Depot", "Houston, TX", "7200 Navigation Blvd, Houston, TX 77011", 15000, 8500, "Marco Reyes", "(713) 555-0901", "active"},
-		{uuid.New(), betaTenantID, "Beta East Hub", "Charlotte, NC", "4800 Statesville Ave, Charlotte, NC 28269", 12000, 6200, "Diana Foster", "(704) 555-0902", "active"},
+		{uuid.New(), betaTenantID, "Beta Central Depot", "Houston, TX", "7200 Navigation Blvd, Houston, TX 77011", 15000, "Marco Reyes", "(713) 555-0901", "active"},
+		{uuid.New(), betaTenantID, "Beta East Hub", "Charlotte, NC", "4800 Statesville Ave, Charlotte, NC 28269", 12000, "Diana Foster", "(704) 555-0902", "active"},
 	}
 
-	warehouseSQL := `INSERT INTO warehouses (id, tenant_id, name, location, address, capacity, used_capacity, manager, phone, status, created_at, updated_at)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
+	// Capacity is measured by weight, which every seeded product has; used
+	// capacity is worked out once the stock is in.
+	warehouseSQL := `INSERT INTO warehouses (id, tenant_id, name, location, address, capacity, capacity_unit, manager, phone, status, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, 'kg', $7, $8, $9, $10, $11)
 		ON CONFLICT DO NOTHING`
 
 	allWarehouses := append(acmeWarehouses, betaWarehouses...)
 	for _, w := range allWarehouses {
-		if _, err := pool.Exec(ctx, warehouseSQL, w.id, w.tenantID, w.name, w.location, w.address, w.capacity, w.used, w.manager, w.phone, w.status, now.Add(-150*24*time.Hour), now); err != nil {
+		if _, err := pool.Exec(ctx, warehouseSQL, w.id, w.tenantID, w.name, w.location, w.address, w.capacity, w.manager, w.phone, w.status, now.Add(-150*24*time.Hour), now); err != nil {
 			return fmt.Errorf("seed warehouses (%s): %w", w.name, err)
 		}
 	}
 
 	// ---------------------------------------------------------------
-	// 8. INVENTORY ITEMS — Acme (50)
+	// 8. PRODUCTS AND INVENTORY — Acme (50 products, 60 stock levels)
 	// ---------------------------------------------------------------
 	type seedInventory struct {
 		sku      string
@@ -537,17 +638,51 @@ func SeedData(pool *pgxpool.Pool) error {
 		{"SKU-050", "Warehouse Management Tablet", "Technology", 20, 5, 499.99, 0.6, "in_stock"},
 	}
 
-	inventorySQL := `INSERT INTO inventory_items (id, tenant_id, warehouse_id, sku, name, category, quantity, min_quantity, unit_price, weight, status, created_at, updated_at)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)
-		ON CONFLICT (tenant_id, sku) DO NOTHING`
+	productSQL := `INSERT INTO products (tenant_id, sku, name, category, unit_price, weight, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		ON CONFLICT (tenant_id, sku) DO UPDATE SET updated_at = products.updated_at
+		RETURNING id`
 
+	inventorySQL := `INSERT INTO inventory_items (id, tenant_id, product_id, warehouse_id, sku, quantity, min_quantity, status, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+		ON CONFLICT (product_id, warehouse_id) DO NOTHING`
+
+	// Each product is stocked in one warehouse; the first ten are also
+	// stocked in the next one, at half the quantity.
 	for i, item := range inventoryItems {
-		wh := acmeWarehouses[i%len(acmeWarehouses)]
-		if _, err := pool.Exec(ctx, inventorySQL, uuid.New(), acmeTenantID, wh.id, item.sku, item.name, item.category, item.quantity, item.minQty, item.price, item.weight, item.status, now.Add(-90*24*time.Hour), now); err != nil {
-			return fmt.Errorf("seed inventory (%s): %w", item.sku, err)
+		var productID uuid.UUID
+		if err := pool.QueryRow(ctx, productSQL, acmeTenantID, item.sku, item.name, item.category, item.price, item.weight, now.Add(-90*24*time.Hour), now).Scan(&productID); err != nil {
+			return fmt.Errorf("seed product (%s): %w", item.sku, err)
+		}
+		whs := []seedWarehouse{acmeWarehouses[i%len(acmeWarehouses)]}
+		if i < 10 {
+			whs = append(whs, acmeWarehouses[(i+1)%len(acmeWarehouses)])
+		}
+		for j, wh := range whs {
+			quantity := item.quantity / (j + 1)
+			if _, err := pool.Exec(ctx, inventorySQL, uuid.New(), acmeTenantID, productID, wh.id, item.sku, quantity, item.minQty, item.status, now.Add(-90*24*time.Hour), now); err != nil {
+				return fmt.Errorf("seed inventory (%s): %w", item.sku, err)
+			}
 		}
 	}
 
+	// Opening balances, so each item's ledger adds up to its stock on hand.
+	if _, err := pool.Exec(ctx, `INSERT INTO stock_movements (tenant_id, inventory_item_id, warehouse_id, sku, movement_type, quantity, balance, reason, created_at)
+		SELECT i.tenant_id, i.id, i.warehouse_id, i.sku, 'adjustment', i.quantity, i.quantity, 'Opening balance', i.created_at
+		FROM inventory_items i
+		WHERE i.tenant_id = $1 AND i.quantity <> 0
+		  AND NOT EXISTS (SELECT 1 FROM stock_movements m WHERE m.inventory_item_id = i.id)`, acmeTenantID); err != nil {
+		return fmt.Errorf("seed stock movements: %w", err)
+	}
+	if _, err := pool.Exec(ctx, `UPDATE warehouses w SET used_capacity = COALESCE((
+			SELECT SUM(stock_capacity_use(w.capacity_unit, i.quantity, p.units_per_pallet, p.volume, p.weight))
+			FROM inventory_items i JOIN products p ON p.id = i.product_id
+			WHERE i.warehouse_id = w.id
+		), 0)
+		WHERE w.tenant_id = ANY($1)`, []uuid.UUID{acmeTenantID, betaTenantID}); err != nil {
+		return fmt.Errorf("seed used capacity: %w", err)
+	}
+
 	// ---------------------------------------------------------------
 	// 9. ORDERS — Acme (156) + Beta (20)
 	// ---------------------------------------------------------------
@@ -874,7 +1009,7 @@ func SeedData(pool *pgxpool.Pool) error {
 		{acmeManager.id, "vendor.update", "vendor", `{"name":"Pacific Fuel Supply","rating":4.5}`, "192.168.1.15"},
 		{acmeDispatcher.id, "vehicle.update", "vehicle", `{"vehicle_id":"TRK-005","status":"maintenance"}`, "192.168.1.20"},
 		{acmeDispatcher.id, "maintenance.create", "maintenance", `{"vehicle":"TRK-005","type":"Oil Change"}`, "192.168.1.20"},
-		{acmeAdmin.id, "warehouse.update", "warehouse", `{"name":"LA Distribution Center","used_capacity":38500}`, "192.168.1.10"},
+		{acmeAdmin.id, "warehouse.update", "warehouse", `{"name":"LA Distribution Center","capacity":50000}`, "192.168.1.10"},
 		{acmeManager.id, "shipment.create", "shipment", `{"tracking":"SHP-1020","destination":"Seattle, WA"}`, "192.168.1.15"},
 		{acmeDispatcher.id, "driver.update", "driver", `{"employee_id":"DRV-005","status":"on_trip"}`, "192.168.1.20"},
 		{acmeManager.id, "order.update", "order", `{"order_number":"ORD-1010","status":"shipped"}`, "192.168.1.15"},
@@ -5,6 +5,7 @@ import (
 	"log"
 	"time"
 
+	"cargomax-api/internal/jobs"
 	"cargomax-api/internal/models"
 	"cargomax-api/internal/repository"
 	"cargomax-api/internal/rest"
@@ -31,21 +32,14 @@ func NewAlertWorker(shiftRepo *repository.ShiftRepo, pingRepo *repository.GPSPin
 	}
 }
 
-func (w *AlertWorker) Start(ctx context.Context) {
-	ticker := time.NewTicker(30 * time.Second)
-	defer ticker.Stop()
-
-	log.Println("Alert worker started (checking every 30s)")
-
-	for {
-		select {
-		case <-ctx.Done():
-			log.Println("Alert worker stopped")
-			return
-		case <-ticker.C:
-			w.checkAlerts(ctx)
-		}
-	}
+// Register wires the alert check into the job scheduler: the "alerts.check"
+// handler scans every active shift and a cron entry enqueues it every 30s.
+func (w *AlertWorker) Register(s *jobs.Scheduler) error {
+	jobs.Register(s, "alerts.check", func(ctx context.Context, _ *uuid.UUID, _ struct{}) error {
+		w.checkAlerts(ctx)
+		return nil
+	})
+	return s.Cron("alerts.check", "@every 30s", "alerts.check", struct{}{})
 }
 
 func (w *AlertWorker) checkAlerts(ctx context.Context) {
@@ -56,6 +50,10 @@ func (w *AlertWorker) checkAlerts(ctx context.Context) {
 	}
 
 	for _, shift := range shifts {
+		// The scan above is cross-tenant; everything below runs under the
+		// shift's own tenant so row-level security applies.
+		ctx := context.WithValue(ctx, models.CtxTenantID, shift.TenantID)
+
 		// Get alert config for this tenant
 		config, err := w.ZoneRepo.GetAlertConfig(ctx, shift.TenantID)
 		if err != nil {
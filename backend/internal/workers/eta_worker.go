@@ -0,0 +1,114 @@
+package workers
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"cargomax-api/internal/jobs"
+	"cargomax-api/internal/models"
+	"cargomax-api/internal/repository"
+	"cargomax-api/internal/rest"
+
+	"github.com/google/uuid"
+)
+
+// ETAWorker keeps the live ETA of every shipment on an active shift. Each
+// run takes the shift's latest ping, the distance left to the shipment's
+// destination and the shift's recent pace blended with the lane's historical
+// speed, and records the prediction when it moves.
+type ETAWorker struct {
+	ShiftRepo    *repository.ShiftRepo
+	PingRepo     *repository.GPSPingRepo
+	ShipmentRepo *repository.ShipmentRepo
+	WSHub        *rest.Hub
+}
+
+func NewETAWorker(shiftRepo *repository.ShiftRepo, pingRepo *repository.GPSPingRepo, shipmentRepo *repository.ShipmentRepo, hub *rest.Hub) *ETAWorker {
+	return &ETAWorker{
+		ShiftRepo:    shiftRepo,
+		PingRepo:     pingRepo,
+		ShipmentRepo: shipmentRepo,
+		WSHub:        hub,
+	}
+}
+
+// Register wires the ETA refresh into the job scheduler: the "eta.refresh"
+// handler scans every active shift and a cron entry enqueues it every minute.
+func (w *ETAWorker) Register(s *jobs.Scheduler) error {
+	jobs.Register(s, "eta.refresh", func(ctx context.Context, _ *uuid.UUID, _ struct{}) error {
+		w.refresh(ctx, time.Now())
+		return nil
+	})
+	return s.Cron("eta.refresh", "@every 1m", "eta.refresh", struct{}{})
+}
+
+func (w *ETAWorker) refresh(ctx context.Context, now time.Time) {
+	shifts, err := w.ShiftRepo.GetAllActive(ctx)
+	if err != nil {
+		log.Printf("eta worker: failed to get active shifts: %v", err)
+		return
+	}
+
+	for _, shift := range shifts {
+		// As in the alert worker, the shift scan is cross-tenant and the rest
+		// runs under the shift's own tenant.
+		ctx := context.WithValue(ctx, models.CtxTenantID, shift.TenantID)
+		if err := w.RefreshShift(ctx, shift.TenantID, shift.ID, shift.DriverID, now); err != nil {
+			log.Printf("eta worker: shift %s: %v", shift.ID, err)
+		}
+	}
+}
+
+// RefreshShift predicts the ETA of every shipment riding on a shift as of now
+// and pushes the ones that changed to the tenant's tracking subscribers. A
+// shift without a ping in the last models.ETAStalePing is skipped, as its
+// position is no longer live.
+func (w *ETAWorker) RefreshShift(ctx context.Context, tenantID, shiftID, driverID uuid.UUID, now time.Time) error {
+	shipments, err := w.ShipmentRepo.ListETACandidates(ctx, tenantID, shiftID)
+	if err != nil || len(shipments) == 0 {
+		return err
+	}
+	ping, err := w.PingRepo.GetLatestByDriver(ctx, tenantID, driverID)
+	if err != nil || ping.ShiftID != shiftID || now.Sub(ping.RecordedAt) > models.ETAStalePing {
+		return nil
+	}
+	recent, n, err := w.PingRepo.RecentSpeed(ctx, tenantID, shiftID, now.Add(-models.ETASpeedWindow))
+	if err != nil {
+		return err
+	}
+
+	for i := range shipments {
+		s := &shipments[i]
+		prior, err := w.ShipmentRepo.LaneSpeed(ctx, tenantID, s.Origin, s.Destination)
+		if err != nil {
+			return err
+		}
+		// The prediction starts from the ping, not from now: a truck that
+		// last reported a minute ago has been driving since.
+		eta, ok := models.EstimateETA(s, ping.Latitude, ping.Longitude, ping.RecordedAt, models.BlendSpeed(recent, n, prior))
+		if !ok {
+			continue
+		}
+		eta.ShiftID = &shiftID
+		eta.ComputedAt = now
+
+		changed, err := w.ShipmentRepo.RecordETA(ctx, tenantID, &eta)
+		if err != nil {
+			return err
+		}
+		if changed && w.WSHub != nil {
+			w.WSHub.BroadcastETA(tenantID, map[string]interface{}{
+				"shipment_id":        eta.ShipmentID,
+				"tracking_number":    s.TrackingNumber,
+				"predicted_delivery": eta.PredictedDelivery,
+				"promised_delivery":  eta.PromisedDelivery,
+				"predicted_late":     eta.PredictedLate,
+				"remaining_km":       eta.RemainingKm,
+				"speed_kmh":          eta.SpeedKmh,
+				"computed_at":        eta.ComputedAt,
+			})
+		}
+	}
+	return nil
+}
@@ -0,0 +1,39 @@
+package workers
+
+import (
+	"context"
+	"fmt"
+
+	"cargomax-api/internal/jobs"
+	"cargomax-api/internal/models"
+
+	"github.com/google/uuid"
+)
+
+// ImportCommitter commits a queued import batch. The GraphQL resolver
+// implements it, so a background commit validates rows exactly as a
+// synchronous one does.
+type ImportCommitter interface {
+	CommitImport(ctx context.Context, tenantID, batchID uuid.UUID) error
+}
+
+// ImportWorker commits imports too large to commit in the request.
+type ImportWorker struct {
+	Importer ImportCommitter
+}
+
+func NewImportWorker(importer ImportCommitter) *ImportWorker {
+	return &ImportWorker{Importer: importer}
+}
+
+// Register wires the "imports.commit" handler into the job scheduler. Jobs
+// are enqueued by the commitImport mutation, one per batch.
+func (w *ImportWorker) Register(s *jobs.Scheduler) error {
+	jobs.Register(s, models.ImportCommitJob, func(ctx context.Context, tenantID *uuid.UUID, p models.ImportCommitPayload) error {
+		if tenantID == nil {
+			return fmt.Errorf("import %s: job has no tenant", p.BatchID)
+		}
+		return w.Importer.CommitImport(ctx, *tenantID, p.BatchID)
+	})
+	return nil
+}
@@ -0,0 +1,148 @@
+package workers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"cargomax-api/internal/jobs"
+	"cargomax-api/internal/models"
+	"cargomax-api/internal/repository"
+
+	"github.com/google/uuid"
+)
+
+// maxOccurrencesPerRun caps how many orders one run places for a single
+// standing order, so a schedule that was paused for months catches up over
+// several runs rather than in one burst.
+const maxOccurrencesPerRun = 31
+
+// scheduleNotifyRoles are the users told when a standing order needs a hand.
+var scheduleNotifyRoles = []string{"admin", "manager"}
+
+// OrderScheduleWorker runs standing orders. Each run places the orders that
+// standing orders have coming up, as scheduled orders, and confirms the
+// scheduled orders whose date has come, reserving their stock and, for
+// standing orders set to, raising their pick lists. An order short of stock
+// stays scheduled, is retried on the next run, and the tenant's admins and
+// managers are told.
+type OrderScheduleWorker struct {
+	ScheduleRepo     *repository.OrderScheduleRepo
+	OrderRepo        *repository.OrderRepo
+	FulfillmentRepo  *repository.FulfillmentRepo
+	NotificationRepo *repository.NotificationRepo
+}
+
+func NewOrderScheduleWorker(scheduleRepo *repository.OrderScheduleRepo, orderRepo *repository.OrderRepo, fulfillmentRepo *repository.FulfillmentRepo, notificationRepo *repository.NotificationRepo) *OrderScheduleWorker {
+	return &OrderScheduleWorker{
+		ScheduleRepo:     scheduleRepo,
+		OrderRepo:        orderRepo,
+		FulfillmentRepo:  fulfillmentRepo,
+		NotificationRepo: notificationRepo,
+	}
+}
+
+// Register wires standing orders into the job scheduler: the
+// "orders.schedule" handler does a run and a cron entry enqueues it every
+// five minutes.
+func (w *OrderScheduleWorker) Register(s *jobs.Scheduler) error {
+	jobs.Register(s, "orders.schedule", func(ctx context.Context, _ *uuid.UUID, _ struct{}) error {
+		w.Run(ctx, time.Now())
+		return nil
+	})
+	return s.Cron("orders.schedule", "@every 5m", "orders.schedule", struct{}{})
+}
+
+// Run places every standing order's due orders and then confirms every
+// scheduled order due by now.
+func (w *OrderScheduleWorker) Run(ctx context.Context, now time.Time) {
+	schedules, err := w.ScheduleRepo.DueSchedules(ctx, now)
+	if err != nil {
+		log.Printf("order schedule worker: failed to get due standing orders: %v", err)
+		return
+	}
+	for _, s := range schedules {
+		// The scans are cross-tenant; the rest runs under the standing
+		// order's or order's own tenant.
+		ctx := context.WithValue(ctx, models.CtxTenantID, s.TenantID)
+		w.Materialize(ctx, s, now)
+	}
+
+	orders, err := w.OrderRepo.DueScheduled(ctx, now)
+	if err != nil {
+		log.Printf("order schedule worker: failed to get due orders: %v", err)
+		return
+	}
+	for _, o := range orders {
+		ctx := context.WithValue(ctx, models.CtxTenantID, o.TenantID)
+		if err := w.Promote(ctx, &o); err != nil {
+			log.Printf("order schedule worker: order %s: %v", o.ID, err)
+		}
+	}
+}
+
+// Materialize places a standing order's orders due by now, up to
+// maxOccurrencesPerRun of them.
+func (w *OrderScheduleWorker) Materialize(ctx context.Context, s models.OrderSchedule, now time.Time) {
+	for i := 0; i < maxOccurrencesPerRun; i++ {
+		o, err := w.ScheduleRepo.Materialize(ctx, s.TenantID, s.ID, now)
+		if errors.Is(err, repository.ErrOccurrenceSkipped) {
+			log.Printf("order schedule worker: standing order %s: %v", s.ID, err)
+			w.notify(ctx, s.TenantID, "Standing order skipped", err.Error())
+			continue
+		}
+		if err != nil {
+			log.Printf("order schedule worker: standing order %s: %v", s.ID, err)
+			return
+		}
+		if o == nil {
+			return
+		}
+	}
+}
+
+// Promote confirms a due scheduled order. When it is short of stock the
+// reason is recorded on the order, which stays scheduled, and reported the
+// first time it is seen. An order placed by a standing order set to auto
+// fulfil has its pick lists raised.
+func (w *OrderScheduleWorker) Promote(ctx context.Context, o *models.Order) error {
+	err := w.OrderRepo.Confirm(ctx, o.TenantID, o.ID)
+	if errors.Is(err, repository.ErrInsufficientStock) {
+		changed, ferr := w.OrderRepo.SetScheduleFailure(ctx, o.TenantID, o.ID, err.Error())
+		if ferr != nil {
+			return ferr
+		}
+		if changed {
+			w.notify(ctx, o.TenantID, "Scheduled order short of stock",
+				fmt.Sprintf("Order %s could not be confirmed: %v. It will be retried.", o.OrderNumber, err))
+		}
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if o.OrderScheduleID == nil {
+		return nil
+	}
+	s, err := w.ScheduleRepo.GetByID(ctx, o.TenantID, *o.OrderScheduleID)
+	if errors.Is(err, repository.ErrOrderScheduleNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if s.AutoFulfill {
+		if _, err := w.FulfillmentRepo.CreatePickLists(ctx, o.TenantID, o.ID, nil, nil); err != nil {
+			return fmt.Errorf("failed to raise pick lists: %w", err)
+		}
+	}
+	return nil
+}
+
+func (w *OrderScheduleWorker) notify(ctx context.Context, tenantID uuid.UUID, title, message string) {
+	if err := w.NotificationRepo.NotifyRoles(ctx, tenantID, scheduleNotifyRoles, title, message, "order"); err != nil {
+		log.Printf("order schedule worker: %v", err)
+	}
+}
@@ -0,0 +1,81 @@
+package workers
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+
+	"cargomax-api/internal/jobs"
+	"cargomax-api/internal/models"
+	"cargomax-api/internal/repository"
+
+	"github.com/google/uuid"
+)
+
+// reorderNotifyRoles are the users told when the reorder job drafts
+// purchase orders for them to review and send.
+var reorderNotifyRoles = []string{"admin", "manager"}
+
+// ReorderWorker runs the reorder job. Each run drafts purchase orders, under
+// DefaultReorderPolicy, for every stock level of a tenant with a supplier
+// catalog that has fallen to its reorder point. Drafts are never sent by the
+// job; the tenant's admins and managers are told there are some to review.
+type ReorderWorker struct {
+	PurchaseOrderRepo *repository.PurchaseOrderRepo
+	NotificationRepo  *repository.NotificationRepo
+}
+
+func NewReorderWorker(purchaseOrderRepo *repository.PurchaseOrderRepo, notificationRepo *repository.NotificationRepo) *ReorderWorker {
+	return &ReorderWorker{
+		PurchaseOrderRepo: purchaseOrderRepo,
+		NotificationRepo:  notificationRepo,
+	}
+}
+
+// Register wires the reorder job into the job scheduler: the
+// "purchases.reorder" handler does a run and a cron entry enqueues it once
+// a day.
+func (w *ReorderWorker) Register(s *jobs.Scheduler) error {
+	jobs.Register(s, "purchases.reorder", func(ctx context.Context, _ *uuid.UUID, _ struct{}) error {
+		w.Run(ctx)
+		return nil
+	})
+	return s.Cron("purchases.reorder", "@daily", "purchases.reorder", struct{}{})
+}
+
+// Run drafts every tenant's due purchase orders.
+func (w *ReorderWorker) Run(ctx context.Context) {
+	tenants, err := w.PurchaseOrderRepo.ReorderTenants(ctx)
+	if err != nil {
+		log.Printf("reorder worker: %v", err)
+		return
+	}
+	for _, tenantID := range tenants {
+		// The scan is cross-tenant; drafting runs under the tenant.
+		ctx := context.WithValue(ctx, models.CtxTenantID, tenantID)
+		if err := w.Reorder(ctx, tenantID); err != nil {
+			log.Printf("reorder worker: tenant %s: %v", tenantID, err)
+		}
+	}
+}
+
+// Reorder drafts a tenant's due purchase orders and reports them.
+func (w *ReorderWorker) Reorder(ctx context.Context, tenantID uuid.UUID) error {
+	drafts, err := w.PurchaseOrderRepo.DraftReorders(ctx, tenantID, nil, models.DefaultReorderPolicy, nil)
+	if err != nil {
+		return err
+	}
+	if len(drafts) == 0 {
+		return nil
+	}
+	numbers := make([]string, len(drafts))
+	for i, po := range drafts {
+		numbers[i] = po.PONumber
+	}
+	message := fmt.Sprintf("Stock has fallen to its reorder point. Drafted %s for review.", strings.Join(numbers, ", "))
+	if err := w.NotificationRepo.NotifyRoles(ctx, tenantID, reorderNotifyRoles, "Purchase orders drafted", message, "inventory"); err != nil {
+		log.Printf("reorder worker: %v", err)
+	}
+	return nil
+}
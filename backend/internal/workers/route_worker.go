@@ -0,0 +1,143 @@
+package workers
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"cargomax-api/internal/jobs"
+	"cargomax-api/internal/models"
+	"cargomax-api/internal/repository"
+	"cargomax-api/internal/rest"
+	"cargomax-api/internal/utils"
+
+	"github.com/google/uuid"
+)
+
+// RouteWorker follows drivers along their route plans. When a shift starts,
+// the driver's plan for that day is linked to it and goes in progress; after
+// that each run checks the shift's latest ping against the stops' radii and
+// records the actual arrival and departure times.
+type RouteWorker struct {
+	ShiftRepo *repository.ShiftRepo
+	PingRepo  *repository.GPSPingRepo
+	RouteRepo *repository.RouteRepo
+	WSHub     *rest.Hub
+}
+
+func NewRouteWorker(shiftRepo *repository.ShiftRepo, pingRepo *repository.GPSPingRepo, routeRepo *repository.RouteRepo, hub *rest.Hub) *RouteWorker {
+	return &RouteWorker{
+		ShiftRepo: shiftRepo,
+		PingRepo:  pingRepo,
+		RouteRepo: routeRepo,
+		WSHub:     hub,
+	}
+}
+
+// Register wires stop detection into the job scheduler: the "routes.track"
+// handler scans every active shift and a cron entry enqueues it every minute.
+func (w *RouteWorker) Register(s *jobs.Scheduler) error {
+	jobs.Register(s, "routes.track", func(ctx context.Context, _ *uuid.UUID, _ struct{}) error {
+		w.track(ctx, time.Now())
+		return nil
+	})
+	return s.Cron("routes.track", "@every 1m", "routes.track", struct{}{})
+}
+
+func (w *RouteWorker) track(ctx context.Context, now time.Time) {
+	shifts, err := w.ShiftRepo.GetAllActive(ctx)
+	if err != nil {
+		log.Printf("route worker: failed to get active shifts: %v", err)
+		return
+	}
+
+	for _, shift := range shifts {
+		ctx := context.WithValue(ctx, models.CtxTenantID, shift.TenantID)
+		if err := w.TrackShift(ctx, &shift, now); err != nil {
+			log.Printf("route worker: shift %s: %v", shift.ID, err)
+		}
+	}
+}
+
+// TrackShift advances the route plan driven on a shift as of now. The plan is
+// the driver's assigned plan dated the shift's start day (UTC). A stop is
+// reached when the latest ping is within its radius and left once a later
+// ping is outside it; stops are reached in any order, one per run. Nothing is
+// recorded from a ping older than models.ETAStalePing.
+func (w *RouteWorker) TrackShift(ctx context.Context, shift *models.Shift, now time.Time) error {
+	plan, err := w.RouteRepo.GetActiveForDriver(ctx, shift.TenantID, shift.DriverID, shift.StartedAt.UTC().Format(models.RouteDateLayout))
+	if err != nil || plan == nil {
+		return err
+	}
+	if plan.Status == models.RouteAssigned || plan.ShiftID == nil || *plan.ShiftID != shift.ID {
+		if err := w.RouteRepo.Start(ctx, shift.TenantID, plan.ID, shift.ID); err != nil {
+			return err
+		}
+	}
+
+	ping, err := w.PingRepo.GetLatestByDriver(ctx, shift.TenantID, shift.DriverID)
+	if err != nil || ping.ShiftID != shift.ID || now.Sub(ping.RecordedAt) > models.ETAStalePing {
+		return nil
+	}
+
+	atStop := false
+	for i := range plan.Stops {
+		s := &plan.Stops[i]
+		if s.Status != models.StopArrived {
+			continue
+		}
+		if utils.HaversineMeters(ping.Latitude, ping.Longitude, s.Latitude, s.Longitude) <= float64(s.RadiusMeters) {
+			atStop = true
+			continue
+		}
+		left, err := w.RouteRepo.RecordDeparture(ctx, shift.TenantID, s.ID, ping.RecordedAt)
+		if err != nil {
+			return err
+		}
+		if left {
+			s.Status, s.ActualDeparture = models.StopCompleted, &ping.RecordedAt
+			w.broadcast(plan, s)
+		}
+	}
+	if atStop {
+		return nil
+	}
+
+	for i := range plan.Stops {
+		s := &plan.Stops[i]
+		if s.Status != models.StopPending {
+			continue
+		}
+		if utils.HaversineMeters(ping.Latitude, ping.Longitude, s.Latitude, s.Longitude) > float64(s.RadiusMeters) {
+			continue
+		}
+		arrived, err := w.RouteRepo.RecordArrival(ctx, shift.TenantID, s.ID, ping.RecordedAt)
+		if err != nil {
+			return err
+		}
+		if arrived {
+			s.Status, s.ActualArrival = models.StopArrived, &ping.RecordedAt
+			w.broadcast(plan, s)
+		}
+		break
+	}
+	return nil
+}
+
+func (w *RouteWorker) broadcast(plan *models.RoutePlan, s *models.RouteStop) {
+	if w.WSHub == nil {
+		return
+	}
+	w.WSHub.BroadcastRoute(plan.TenantID, map[string]interface{}{
+		"route_id":         plan.ID,
+		"driver_id":        plan.DriverID,
+		"stop_id":          s.ID,
+		"sequence":         s.Sequence,
+		"label":            s.Label,
+		"status":           s.Status,
+		"planned_arrival":  s.PlannedArrival,
+		"actual_arrival":   s.ActualArrival,
+		"actual_departure": s.ActualDeparture,
+		"misses_window":    s.MissesWindow(),
+	})
+}
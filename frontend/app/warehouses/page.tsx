@@ -34,7 +34,7 @@ export default function WarehousesPage() {
                 <div className="flex items-center gap-2"><Phone className="h-3.5 w-3.5" />{w.phone}</div>
               </div>
               <div className="mt-4">
-                <div className="flex justify-between text-xs mb-1"><span className="text-gray-500">Capacity</span><span className="font-medium">{w.usedCapacity?.toLocaleString()} / {w.capacity?.toLocaleString()}</span></div>
+                <div className="flex justify-between text-xs mb-1"><span className="text-gray-500">Capacity</span><span className="font-medium">{w.usedCapacity?.toLocaleString(undefined, { maximumFractionDigits: 1 })} / {w.capacity?.toLocaleString()}</span></div>
                 <div className="h-2 bg-gray-100 rounded-full"><div className={`h-2 rounded-full ${pct > 85 ? "bg-red-500" : pct > 60 ? "bg-amber-500" : "bg-green-500"}`} style={{ width: `${pct}%` }} /></div>
                 <p className="text-xs text-gray-400 mt-1">{pct}% utilized</p>
               </div>